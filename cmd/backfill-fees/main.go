@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/tx_graph"
+)
+
+// backfill-fees fills in transaction_inputs.value, transactions.total_fee and blocks.total_fees
+// for blocks indexed before the tx_graph module resolved input values
+func main() {
+	var (
+		configPath string
+		fromHeight int64
+		toHeight   int64
+		chunkSize  int64
+	)
+
+	flag.StringVar(&configPath, "config", "configs/config.yaml", "Path to config file")
+	flag.Int64Var(&fromHeight, "from", 0, "First block height to backfill")
+	flag.Int64Var(&toHeight, "to", -1, "Last block height to backfill (-1 = last indexed block)")
+	flag.Int64Var(&chunkSize, "chunk", 1000, "Blocks backfilled per database transaction")
+	flag.Parse()
+
+	if chunkSize <= 0 {
+		log.Fatal("-chunk must be greater than 0")
+	}
+
+	config.InitConfig(configPath)
+	if !config.IsModuleEnabled("TX_GRAPH") {
+		log.Fatal("The tx_graph module is disabled in the configuration")
+	}
+
+	if err := postgres.InitPostgres(); err != nil {
+		log.Fatalf("Failed to initialize PostgreSQL: %v", err)
+	}
+	defer postgres.ClosePostgres()
+
+	if toHeight < 0 {
+		lastBlock, err := postgres.GetLastIndexedBlock()
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		toHeight = lastBlock
+	}
+
+	// An interrupt lets the chunk in progress commit before stopping
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+
+	log.Printf("Backfilling input values and fees for blocks %d-%d", fromHeight, toHeight)
+	var total tx_graph.FeeBackfillResult
+	for start := fromHeight; start <= toHeight; start += chunkSize {
+		select {
+		case <-interrupt:
+			log.Printf("Interrupt signal received, stopping before block %d", start)
+			toHeight = start - 1
+			continue
+		default:
+		}
+
+		end := min(start+chunkSize-1, toHeight)
+		result, err := tx_graph.BackfillFees(context.Background(), start, end)
+		if err != nil {
+			log.Fatalf("Backfill stopped at block %d: %v", start, err)
+		}
+		total.InputsResolved += result.InputsResolved
+		total.FeesUpdated += result.FeesUpdated
+		total.BlocksUpdated += result.BlocksUpdated
+		total.ShieldedSkipped += result.ShieldedSkipped
+		log.Printf("Blocks %d-%d: %d inputs resolved, %d fees updated, %d block totals updated",
+			start, end, result.InputsResolved, result.FeesUpdated, result.BlocksUpdated)
+	}
+
+	log.Printf("Backfill done: %d inputs resolved, %d fees updated, %d block totals updated",
+		total.InputsResolved, total.FeesUpdated, total.BlocksUpdated)
+	if total.ShieldedSkipped > 0 {
+		log.Printf("%d shielded transactions have no fee yet; re-index their blocks to compute it from the node's value balances",
+			total.ShieldedSkipped)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/indexer"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/provider"
+)
+
+// backfill indexes a single module over blocks that are already indexed, fetching them again from
+// the node, so a module enabled on a synced database gets its history without a full re-index
+func main() {
+	var (
+		configPath string
+		module     string
+		fromHeight int64
+		toHeight   int64
+	)
+
+	flag.StringVar(&configPath, "config", "configs/config.yaml", "Path to config file")
+	flag.StringVar(&module, "module", "", "Module to backfill: "+strings.Join(indexer.BackfillModules(), ", "))
+	flag.Int64Var(&fromHeight, "from", 0, "First block height to backfill")
+	flag.Int64Var(&toHeight, "to", -1, "Last block height to backfill (-1 = last indexed block)")
+	flag.Parse()
+
+	if module == "" {
+		log.Fatal("-module is required")
+	}
+	module = strings.ToUpper(module)
+
+	config.InitConfig(configPath)
+	if err := indexer.ValidateBackfillModule(module); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	if err := postgres.InitPostgres(); err != nil {
+		log.Fatalf("Failed to initialize PostgreSQL: %v", err)
+	}
+	defer postgres.ClosePostgres()
+
+	rpcClient, err := provider.InitClient()
+	if err != nil {
+		log.Fatalf("Failed to initialize provider: %v", err)
+	}
+
+	lastBlock, err := postgres.GetLastIndexedBlock()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if toHeight < 0 || toHeight > lastBlock {
+		toHeight = lastBlock
+	}
+
+	// An interrupt lets the block in progress commit before stopping
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+
+	batchSize := int64(config.Conf.Indexer.BatchSize)
+	log.Printf("Backfilling module %s for blocks %d-%d", module, fromHeight, toHeight)
+	var indexed, skipped int64
+	for start := fromHeight; start <= toHeight; start += batchSize {
+		end := min(start+batchSize-1, toHeight)
+		// Blocks buried below the finality depth are inserted without conflict updates, as in a sync
+		postgres.SetBackfilling(lastBlock-end > int64(config.Conf.Indexer.FinalityDepth))
+		rpcClient.PrefetchBlocks(start, end)
+
+		var batchIndexed int64
+		for height := start; height <= end; height++ {
+			select {
+			case <-interrupt:
+				log.Printf("Interrupt signal received, stopping before block %d", height)
+				log.Printf("Backfill stopped: %d blocks indexed, %d already had %s rows", indexed, skipped, module)
+				return
+			default:
+			}
+
+			done, err := indexer.BackfillModule(module, height, rpcClient)
+			if err != nil {
+				log.Fatalf("Backfill stopped at block %d: %v", height, err)
+			}
+			if done {
+				batchIndexed++
+			} else {
+				skipped++
+			}
+		}
+		indexed += batchIndexed
+		log.Printf("Blocks %d-%d: %d indexed", start, end, batchIndexed)
+	}
+
+	log.Printf("Backfill done: %d blocks indexed, %d already had %s rows", indexed, skipped, module)
+}
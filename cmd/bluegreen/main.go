@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/bluegreen"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+
+	// Import core schemas and modules so the target schema gets every table before the copy
+	_ "github.com/keep-starknet-strange/ztarknet/zindex/internal/accounts"
+	_ "github.com/keep-starknet-strange/ztarknet/zindex/internal/alerts"
+	_ "github.com/keep-starknet-strange/ztarknet/zindex/internal/annotations"
+	_ "github.com/keep-starknet-strange/ztarknet/zindex/internal/apikeys"
+	_ "github.com/keep-starknet-strange/ztarknet/zindex/internal/blocks"
+	_ "github.com/keep-starknet-strange/ztarknet/zindex/internal/changefeed"
+	_ "github.com/keep-starknet-strange/ztarknet/zindex/internal/index_log"
+	_ "github.com/keep-starknet-strange/ztarknet/zindex/internal/jobs"
+	_ "github.com/keep-starknet-strange/ztarknet/zindex/internal/mempool"
+	_ "github.com/keep-starknet-strange/ztarknet/zindex/internal/reorg"
+	_ "github.com/keep-starknet-strange/ztarknet/zindex/internal/retry_queue"
+	_ "github.com/keep-starknet-strange/ztarknet/zindex/internal/snapshots"
+	_ "github.com/keep-starknet-strange/ztarknet/zindex/internal/starks"
+	_ "github.com/keep-starknet-strange/ztarknet/zindex/internal/tx_graph"
+	_ "github.com/keep-starknet-strange/ztarknet/zindex/internal/tze_graph"
+	_ "github.com/keep-starknet-strange/ztarknet/zindex/internal/usage"
+	_ "github.com/keep-starknet-strange/ztarknet/zindex/internal/webhooks"
+)
+
+const usageText = `Usage: bluegreen [--config path] <command> [flags]
+
+The config is the new (green) deployment's, whose database.schema is the target schema.
+
+Commands:
+  copy --from SCHEMA     create the tables of database.schema and fill them from SCHEMA
+  status                 show the active schema
+  activate [--schema S]  make S (default: database.schema) the schema that serves the API
+`
+
+// bluegreen prepares and switches a blue/green schema upgrade
+// Run copy while the green deployment is stopped; the blue deployment keeps serving meanwhile
+func main() {
+	var configPath string
+	flag.StringVar(&configPath, "config", "configs/config.yaml", "Path to config file")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usageText) }
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	config.InitConfig(configPath)
+	if err := postgres.InitPostgres(); err != nil {
+		log.Fatalf("Failed to initialize PostgreSQL: %v", err)
+	}
+	defer postgres.ClosePostgres()
+
+	command, args := flag.Arg(0), flag.Args()[1:]
+	switch command {
+	case "copy":
+		copySchema(args)
+	case "status":
+		showStatus()
+	case "activate":
+		activate(args)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func copySchema(args []string) {
+	flags := flag.NewFlagSet("copy", flag.ExitOnError)
+	from := flags.String("from", "public", "Schema of the live (blue) deployment")
+	flags.Parse(args)
+
+	log.Printf("Copying schema %s into %s", *from, postgres.Schema())
+	copies, err := bluegreen.CopyFrom(context.Background(), *from)
+	if err != nil {
+		log.Fatalf("Copy failed, schema %s is unchanged: %v", postgres.Schema(), err)
+	}
+
+	for _, copied := range copies {
+		switch {
+		case copied.Skipped:
+			fmt.Printf("%-32s  new table, left empty\n", copied.Table)
+		case len(copied.NewColumns) > 0:
+			fmt.Printf("%-32s  %d rows, new columns left at defaults: %s\n", copied.Table, copied.Rows, strings.Join(copied.NewColumns, ", "))
+		default:
+			fmt.Printf("%-32s  %d rows\n", copied.Table, copied.Rows)
+		}
+	}
+	log.Printf("Copy done; start the new deployment on schema %s to resume indexing from the copied tip", postgres.Schema())
+}
+
+func showStatus() {
+	if !bluegreen.Enabled() {
+		log.Fatal("api.blue_green.enabled is not set in this config")
+	}
+	if err := bluegreen.Refresh(context.Background()); err != nil {
+		log.Fatalf("%v", err)
+	}
+	status := bluegreen.GetStatus()
+	fmt.Printf("active schema: %s", status.ActiveSchema)
+	if status.SwitchedAt != nil {
+		fmt.Printf(" (since %s)", status.SwitchedAt.UTC().Format("2006-01-02 15:04:05"))
+	}
+	fmt.Printf("\nthis config:   %s\n", status.Schema)
+}
+
+func activate(args []string) {
+	flags := flag.NewFlagSet("activate", flag.ExitOnError)
+	schema := flags.String("schema", postgres.Schema(), "Schema to activate")
+	flags.Parse(args)
+
+	if !bluegreen.Enabled() {
+		log.Fatal("api.blue_green.enabled is not set in this config")
+	}
+	if err := bluegreen.Activate(context.Background(), *schema); err != nil {
+		log.Fatalf("%v", err)
+	}
+	log.Printf("Schema %s is active; deployments pick the switch up within api.blue_green.poll_interval seconds", *schema)
+}
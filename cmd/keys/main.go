@@ -0,0 +1,119 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/apikeys"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+)
+
+const usageText = `Usage: keys [--config path] <command> [flags]
+
+Commands:
+  create --name NAME [--scope read|admin] [--rps N --burst N]   create a key and print it once
+  revoke --key-id ID                                            revoke a key
+  list [--all]                                                  list active keys, or every key with --all
+`
+
+// keys creates, revokes and lists the API keys checked under api.auth
+// It talks to the database directly, so the first admin key can be created before the API
+// accepts any admin request
+func main() {
+	var configPath string
+	flag.StringVar(&configPath, "config", "configs/config.yaml", "Path to config file")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usageText) }
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	config.InitConfig(configPath)
+	if err := postgres.InitPostgres(); err != nil {
+		log.Fatalf("Failed to initialize PostgreSQL: %v", err)
+	}
+	defer postgres.ClosePostgres()
+
+	command, args := flag.Arg(0), flag.Args()[1:]
+	switch command {
+	case "create":
+		createKey(args)
+	case "revoke":
+		revokeKey(args)
+	case "list":
+		listKeys(args)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func createKey(args []string) {
+	flags := flag.NewFlagSet("create", flag.ExitOnError)
+	name := flags.String("name", "", "Name identifying the key's owner")
+	scope := flags.String("scope", apikeys.ScopeRead, "Key scope: read or admin")
+	rps := flags.Float64("rps", 0, "Requests per second of the key's own rate limit (0 = api.rate_limit applies)")
+	burst := flags.Int("burst", 0, "Burst of the key's own rate limit")
+	flags.Parse(args)
+
+	key := apikeys.Key{Name: *name, Scope: *scope, RequestsPerSecond: *rps, Burst: *burst}
+	if err := apikeys.Validate(&key); err != nil {
+		log.Fatalf("Invalid key: %v", err)
+	}
+
+	created, err := apikeys.CreateKey(key)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	log.Printf("Created %s key %s (key_id %s); it is not shown again:", created.Scope, created.Name, created.KeyID)
+	fmt.Println(created.Key)
+}
+
+func revokeKey(args []string) {
+	flags := flag.NewFlagSet("revoke", flag.ExitOnError)
+	keyID := flags.String("key-id", "", "key_id of the key to revoke")
+	flags.Parse(args)
+
+	if *keyID == "" {
+		log.Fatal("-key-id is required")
+	}
+
+	revoked, err := apikeys.RevokeKey(*keyID)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if !revoked {
+		log.Fatalf("No active key has key_id %s", *keyID)
+	}
+	log.Printf("Revoked key %s; running API servers reject it once their cache expires (api.auth.cache_ttl)", *keyID)
+}
+
+func listKeys(args []string) {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	all := flags.Bool("all", false, "Include revoked keys")
+	flags.Parse(args)
+
+	keys, err := apikeys.GetKeys(*all)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	fmt.Printf("%-16s  %-5s  %-11s  %-20s  %s\n", "KEY_ID", "SCOPE", "RATE", "CREATED", "NAME")
+	for _, key := range keys {
+		rate := "default"
+		if key.HasRateLimit() {
+			rate = fmt.Sprintf("%g/s,%d", key.RequestsPerSecond, key.Burst)
+		}
+		name := key.Name
+		if key.RevokedAt != nil {
+			name += " (revoked " + key.RevokedAt.UTC().Format("2006-01-02") + ")"
+		}
+		fmt.Printf("%-16s  %-5s  %-11s  %-20s  %s\n", key.KeyID, key.Scope, rate, key.CreatedAt.UTC().Format("2006-01-02 15:04:05"), name)
+	}
+}
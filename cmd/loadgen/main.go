@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+)
+
+func main() {
+	var (
+		target      string
+		accessLog   string
+		concurrency int
+		duration    time.Duration
+		maxRequests int
+		rate        float64
+		timeout     time.Duration
+	)
+
+	flag.StringVar(&target, "target", "http://localhost:8080", "Base URL of the zIndex API under test")
+	flag.StringVar(&accessLog, "log", "", "Access log to replay (one path or Common/Combined Log Format line per request); empty generates a synthetic query mix")
+	flag.IntVar(&concurrency, "concurrency", 8, "Number of concurrent clients")
+	flag.DurationVar(&duration, "duration", 30*time.Second, "How long to generate load")
+	flag.IntVar(&maxRequests, "requests", 0, "Stop after this many requests (0 = run for the full duration)")
+	flag.Float64Var(&rate, "rate", 0, "Target requests per second across all clients (0 = as fast as possible)")
+	flag.DurationVar(&timeout, "timeout", 10*time.Second, "Per-request timeout")
+	flag.Parse()
+
+	if concurrency <= 0 {
+		log.Fatal("-concurrency must be greater than 0")
+	}
+	target = strings.TrimRight(target, "/")
+	client := &http.Client{Timeout: timeout}
+
+	var source requestSource
+	var err error
+	if accessLog != "" {
+		source, err = newReplaySource(accessLog)
+	} else {
+		source, err = newSyntheticSource(client, target)
+	}
+	if err != nil {
+		log.Fatalf("Failed to prepare requests: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		log.Println("Interrupt signal received, stopping load...")
+		cancel()
+	}()
+
+	log.Printf("Sending %s load to %s with %d clients for %s", source.name(), target, concurrency, duration)
+	results := run(ctx, client, target, source, concurrency, maxRequests, rate)
+	results.print(os.Stdout)
+}
+
+// run issues requests from source until ctx is done or maxRequests have been sent
+func run(ctx context.Context, client *http.Client, target string, source requestSource, concurrency, maxRequests int, rate float64) *report {
+	paths := make(chan string)
+	go func() {
+		defer close(paths)
+
+		var ticker *time.Ticker
+		if rate > 0 {
+			ticker = time.NewTicker(time.Duration(float64(time.Second) / rate))
+			defer ticker.Stop()
+		}
+
+		for sent := 0; maxRequests == 0 || sent < maxRequests; sent++ {
+			if ticker != nil {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+
+			path, ok := source.next()
+			if !ok {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case paths <- path:
+			}
+		}
+	}()
+
+	results := newReport()
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				s := send(ctx, client, target+path, path)
+				// Requests cut off by the end of the run are not failures of the server
+				if s.err != nil && ctx.Err() != nil {
+					continue
+				}
+				results.add(s)
+			}
+		}()
+	}
+
+	wg.Wait()
+	results.finish()
+	return results
+}
+
+// send performs one GET and measures its latency up to the end of the response body
+func send(ctx context.Context, client *http.Client, url, path string) sample {
+	s := sample{endpoint: endpointOf(path)}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		s.err = err
+		return s
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		s.latency = time.Since(start)
+		s.err = err
+		return s
+	}
+	_, err = drain(resp)
+	s.latency = time.Since(start)
+	s.status = resp.StatusCode
+	if err != nil {
+		s.err = fmt.Errorf("failed to read response: %w", err)
+	}
+	return s
+}
+
+// endpointOf strips the query string so latencies are grouped per route
+func endpointOf(path string) string {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		return path[:i]
+	}
+	return path
+}
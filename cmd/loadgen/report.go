@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// sample is the outcome of one request
+type sample struct {
+	endpoint string
+	status   int
+	latency  time.Duration
+	err      error
+}
+
+// endpointStats collects the latencies and outcomes of one endpoint
+type endpointStats struct {
+	latencies []time.Duration
+	errors    int
+	statuses  map[int]int
+}
+
+func newEndpointStats() *endpointStats {
+	return &endpointStats{statuses: map[int]int{}}
+}
+
+func (e *endpointStats) add(s sample) {
+	e.latencies = append(e.latencies, s.latency)
+	if s.err != nil {
+		e.errors++
+		return
+	}
+	e.statuses[s.status]++
+}
+
+// failures counts transport errors and 5xx responses
+func (e *endpointStats) failures() int {
+	failed := e.errors
+	for status, count := range e.statuses {
+		if status >= 500 {
+			failed += count
+		}
+	}
+	return failed
+}
+
+// percentile returns the latency below which p percent of requests completed
+// latencies must be sorted
+func percentile(latencies []time.Duration, p float64) time.Duration {
+	if len(latencies) == 0 {
+		return 0
+	}
+	i := int(float64(len(latencies)-1) * p / 100)
+	return latencies[i]
+}
+
+// report aggregates samples overall and per endpoint
+type report struct {
+	mu        sync.Mutex
+	startedAt time.Time
+	elapsed   time.Duration
+	total     *endpointStats
+	endpoints map[string]*endpointStats
+	firstErr  error
+}
+
+func newReport() *report {
+	return &report{
+		startedAt: time.Now(),
+		total:     newEndpointStats(),
+		endpoints: map[string]*endpointStats{},
+	}
+}
+
+func (r *report) add(s sample) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.total.add(s)
+	e, ok := r.endpoints[s.endpoint]
+	if !ok {
+		e = newEndpointStats()
+		r.endpoints[s.endpoint] = e
+	}
+	e.add(s)
+	if s.err != nil && r.firstErr == nil {
+		r.firstErr = s.err
+	}
+}
+
+// finish records the run duration and sorts latencies for percentile lookups
+func (r *report) finish() {
+	r.elapsed = time.Since(r.startedAt)
+	sortLatencies(r.total.latencies)
+	for _, e := range r.endpoints {
+		sortLatencies(e.latencies)
+	}
+}
+
+func sortLatencies(latencies []time.Duration) {
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+}
+
+func (r *report) print(out io.Writer) {
+	requests := len(r.total.latencies)
+	fmt.Fprintf(out, "\nRequests:   %d in %s (%.1f req/s)\n", requests, r.elapsed.Round(time.Millisecond), float64(requests)/r.elapsed.Seconds())
+	fmt.Fprintf(out, "Failures:   %d (transport errors and 5xx responses)\n", r.total.failures())
+	if r.firstErr != nil {
+		fmt.Fprintf(out, "First error: %v\n", r.firstErr)
+	}
+
+	statuses := make([]int, 0, len(r.total.statuses))
+	for status := range r.total.statuses {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+	fmt.Fprint(out, "Statuses:  ")
+	for _, status := range statuses {
+		fmt.Fprintf(out, " %d=%d", status, r.total.statuses[status])
+	}
+	if r.total.errors > 0 {
+		fmt.Fprintf(out, " error=%d", r.total.errors)
+	}
+	fmt.Fprintln(out)
+
+	endpoints := make([]string, 0, len(r.endpoints))
+	for endpoint := range r.endpoints {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Slice(endpoints, func(i, j int) bool {
+		return len(r.endpoints[endpoints[i]].latencies) > len(r.endpoints[endpoints[j]].latencies)
+	})
+
+	fmt.Fprintln(out)
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "endpoint\trequests\tfailures\tp50\tp90\tp99\tmax\t")
+	printRow(w, "all", r.total)
+	for _, endpoint := range endpoints {
+		printRow(w, endpoint, r.endpoints[endpoint])
+	}
+	w.Flush()
+}
+
+func printRow(w io.Writer, name string, e *endpointStats) {
+	fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%s\t%s\t\n", name, len(e.latencies), e.failures(),
+		formatLatency(percentile(e.latencies, 50)),
+		formatLatency(percentile(e.latencies, 90)),
+		formatLatency(percentile(e.latencies, 99)),
+		formatLatency(percentile(e.latencies, 100)),
+	)
+}
+
+func formatLatency(d time.Duration) string {
+	return d.Round(100 * time.Microsecond).String()
+}
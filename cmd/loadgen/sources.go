@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// requestSource yields the request paths (with query strings) to send
+type requestSource interface {
+	name() string
+	next() (string, bool)
+}
+
+// replaySource cycles through the GET requests of a recorded access log
+type replaySource struct {
+	mu    sync.Mutex
+	paths []string
+	pos   int
+}
+
+// newReplaySource reads an access log holding either a bare request path per line or
+// Common/Combined Log Format lines ("GET /api/v1/... HTTP/1.1" in the request field)
+// Non-GET requests are skipped so a replay never repeats admin actions
+func newReplaySource(path string) (*replaySource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open access log: %w", err)
+	}
+	defer file.Close()
+
+	source := &replaySource{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if p, ok := parseLogLine(scanner.Text()); ok {
+			source.paths = append(source.paths, p)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read access log: %w", err)
+	}
+	if len(source.paths) == 0 {
+		return nil, fmt.Errorf("no GET requests found in %s", path)
+	}
+
+	return source, nil
+}
+
+// parseLogLine extracts the request path of a GET request from one access log line
+func parseLogLine(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", false
+	}
+	if strings.HasPrefix(line, "/") {
+		return strings.Fields(line)[0], true
+	}
+
+	// Log formats quote the request line: "GET /path HTTP/1.1"
+	request := line
+	if start := strings.IndexByte(line, '"'); start >= 0 {
+		end := strings.IndexByte(line[start+1:], '"')
+		if end < 0 {
+			return "", false
+		}
+		request = line[start+1 : start+1+end]
+	}
+
+	fields := strings.Fields(request)
+	if len(fields) < 2 || fields[0] != http.MethodGet || !strings.HasPrefix(fields[1], "/") {
+		return "", false
+	}
+	return fields[1], true
+}
+
+func (s *replaySource) name() string {
+	return fmt.Sprintf("replayed (%d recorded requests)", len(s.paths))
+}
+
+func (s *replaySource) next() (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.paths[s.pos]
+	s.pos = (s.pos + 1) % len(s.paths)
+	return p, true
+}
+
+// syntheticQuery is one kind of explorer query in the synthetic mix
+type syntheticQuery struct {
+	weight int
+	path   func(rng *rand.Rand, tip int64) string
+}
+
+// syntheticMix approximates explorer traffic: mostly recent and per-block lookups, with a
+// share of module queries. Queries against disabled modules are reported as 501s
+var syntheticMix = []syntheticQuery{
+	{6, func(_ *rand.Rand, _ int64) string { return "/api/v1/blocks/recent?limit=10" }},
+	{6, func(rng *rand.Rand, tip int64) string {
+		return fmt.Sprintf("/api/v1/blocks/block?height=%d", randomHeight(rng, tip))
+	}},
+	{4, func(rng *rand.Rand, tip int64) string {
+		return fmt.Sprintf("/api/v1/tx-graph/transactions/by-block?block_height=%d", randomHeight(rng, tip))
+	}},
+	{4, func(_ *rand.Rand, _ int64) string { return "/api/v1/tx-graph/transactions/recent?limit=20" }},
+	{2, func(_ *rand.Rand, _ int64) string { return "/api/v1/tze-graph/outputs/all-unspent?limit=20" }},
+	{2, func(_ *rand.Rand, _ int64) string { return "/api/v1/starks/proofs/recent?limit=20" }},
+	{2, func(_ *rand.Rand, _ int64) string { return "/api/v1/starks/facts/recent?limit=20" }},
+	{2, func(_ *rand.Rand, _ int64) string { return "/api/v1/accounts/top-balances?limit=20" }},
+	{1, func(_ *rand.Rand, _ int64) string { return "/api/v1/stats/activity" }},
+	{1, func(_ *rand.Rand, _ int64) string { return "/status" }},
+}
+
+// randomHeight favours recent blocks, which explorers query far more often than old ones
+func randomHeight(rng *rand.Rand, tip int64) int64 {
+	if tip <= 0 {
+		return 0
+	}
+	if rng.Intn(4) > 0 {
+		recent := int64(100)
+		if tip < recent {
+			recent = tip
+		}
+		return tip - rng.Int63n(recent+1)
+	}
+	return rng.Int63n(tip + 1)
+}
+
+// syntheticSource draws weighted random queries from syntheticMix
+type syntheticSource struct {
+	mu          sync.Mutex
+	rng         *rand.Rand
+	tip         int64
+	totalWeight int
+}
+
+// newSyntheticSource looks up the latest indexed height so block queries hit indexed blocks
+func newSyntheticSource(client *http.Client, target string) (*syntheticSource, error) {
+	tip, err := latestHeight(client, target)
+	if err != nil {
+		return nil, err
+	}
+
+	source := &syntheticSource{rng: rand.New(rand.NewSource(rand.Int63())), tip: tip}
+	for _, q := range syntheticMix {
+		source.totalWeight += q.weight
+	}
+	return source, nil
+}
+
+// latestHeight reads the newest indexed block from /blocks/recent, which unlike /blocks/latest
+// answers even while the instance is still syncing
+func latestHeight(client *http.Client, target string) (int64, error) {
+	resp, err := client.Get(target + "/api/v1/blocks/recent?limit=1")
+	if err != nil {
+		return 0, fmt.Errorf("failed to get latest block: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("failed to get latest block: status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Data []struct {
+			Height int64 `json:"height"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, fmt.Errorf("failed to decode latest block: %w", err)
+	}
+	if len(body.Data) == 0 {
+		return 0, fmt.Errorf("no blocks indexed yet")
+	}
+	return body.Data[0].Height, nil
+}
+
+func (s *syntheticSource) name() string {
+	return fmt.Sprintf("synthetic (tip %d)", s.tip)
+}
+
+func (s *syntheticSource) next() (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pick := s.rng.Intn(s.totalWeight)
+	for _, q := range syntheticMix {
+		if pick < q.weight {
+			return q.path(s.rng, s.tip), true
+		}
+		pick -= q.weight
+	}
+	return syntheticMix[0].path(s.rng, s.tip), true
+}
+
+// drain reads and discards the response body so latency covers the full transfer
+func drain(resp *http.Response) (int64, error) {
+	defer resp.Body.Close()
+	return io.Copy(io.Discard, resp.Body)
+}
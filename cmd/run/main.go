@@ -2,27 +2,48 @@ package main
 
 import (
 	"flag"
-	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"syscall"
 
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/alerts"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/bluegreen"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/jobs"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/provider"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/webhooks"
 	"github.com/keep-starknet-strange/ztarknet/zindex/routes"
 
 	// Import core schemas to register their initialization functions
+	_ "github.com/keep-starknet-strange/ztarknet/zindex/internal/annotations"
+	_ "github.com/keep-starknet-strange/ztarknet/zindex/internal/apikeys"
 	_ "github.com/keep-starknet-strange/ztarknet/zindex/internal/blocks"
+	_ "github.com/keep-starknet-strange/ztarknet/zindex/internal/changefeed"
+	_ "github.com/keep-starknet-strange/ztarknet/zindex/internal/index_log"
+	_ "github.com/keep-starknet-strange/ztarknet/zindex/internal/retry_queue"
+	_ "github.com/keep-starknet-strange/ztarknet/zindex/internal/snapshots"
+	_ "github.com/keep-starknet-strange/ztarknet/zindex/internal/usage"
+
+	// Import maintenance to register its bulk change hook
+	_ "github.com/keep-starknet-strange/ztarknet/zindex/internal/maintenance"
 
 	// Import modules to register their schema initialization functions
 	_ "github.com/keep-starknet-strange/ztarknet/zindex/internal/accounts"
+	_ "github.com/keep-starknet-strange/ztarknet/zindex/internal/mempool"
 	_ "github.com/keep-starknet-strange/ztarknet/zindex/internal/starks"
 	_ "github.com/keep-starknet-strange/ztarknet/zindex/internal/tx_graph"
 	_ "github.com/keep-starknet-strange/ztarknet/zindex/internal/tze_graph"
 )
 
 func main() {
+	os.Exit(run())
+}
+
+// run starts every component and blocks until one fails for good or an interrupt arrives
+// It returns the process exit code so deferred cleanup runs before exiting
+func run() int {
 	var (
 		configPath string
 		rpcURL     string
@@ -48,6 +69,24 @@ func main() {
 		log.Fatalf("Failed to initialize PostgreSQL: %v", err)
 	}
 	defer postgres.ClosePostgres()
+	routeAPIReads()
+
+	// Read the active schema before serving, so a standby deployment never answers as the live one
+	if err := bluegreen.Start(); err != nil {
+		log.Fatalf("Failed to start blue/green schema tracking: %v", err)
+	}
+
+	// Webhooks start before the indexer and stop after it, so every event it publishes is queued
+	webhooks.Start()
+	defer webhooks.Stop()
+
+	// Background jobs run until every component that enqueues them has stopped
+	jobs.Start()
+	defer jobs.Stop()
+
+	// Alerts are evaluated after each indexed block and stop before webhooks, so the last ones are queued
+	alerts.Start()
+	defer alerts.Stop()
 
 	log.Println("Initializing Zcash provider...")
 	if err := provider.InitProvider(startBlock); err != nil {
@@ -56,20 +95,31 @@ func main() {
 	defer provider.CloseProvider()
 
 	log.Printf("Starting API server on %s:%s...", config.Conf.Api.Host, config.Conf.Api.Port)
-	go routes.StartServer(config.Conf.Api.Host, config.Conf.Api.Port)
+	server := routes.NewServer(config.Conf.Api.Host, config.Conf.Api.Port)
+	apiErrors := make(chan error, 1)
+	go serveAPI(server, apiErrors)
+	// Deferred calls run in reverse: the API drains first, then the indexer stops, then the pool closes
+	defer shutdownAPI(server)
+
+	dbErrors := make(chan error, 1)
+	go watchDatabase(dbErrors)
 
 	interrupt := make(chan os.Signal, 1)
-	signal.Notify(interrupt, os.Interrupt)
-
-	for {
-		select {
-		case <-interrupt:
-			log.Println("Interrupt signal received, shutting down...")
-			return
-		case err := <-provider.ErrorChannel:
-			log.Printf("Provider error: %v", err)
-			fmt.Println("Critical error occurred, shutting down...")
-			return
-		}
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+
+	var reason shutdown
+	select {
+	case <-interrupt:
+		log.Println("Shutdown signal received, shutting down...")
+		reason = shutdown{component: componentSignal}
+	case err := <-provider.ErrorChannel:
+		reason = indexerShutdown(err)
+	case err := <-apiErrors:
+		reason = shutdown{component: componentAPI, err: err}
+	case err := <-dbErrors:
+		reason = shutdown{component: componentDatabase, err: err}
 	}
+
+	reason.log()
+	return reason.exitCode()
 }
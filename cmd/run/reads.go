@@ -0,0 +1,31 @@
+package main
+
+import (
+	"log"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/accounts"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/blocks"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/mempool"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/starks"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/tx_graph"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/tze_graph"
+)
+
+// routeAPIReads sends the modules' lookups through database.statement_timeouts.api_read when it is set
+// Left unset, lookups run straight on the pool under database.statement_timeout
+func routeAPIReads() {
+	if config.Conf.Database.StatementTimeouts.APIRead == 0 || postgres.DB == nil {
+		return
+	}
+
+	apiReads := postgres.NewTimeoutQuerier(postgres.QueryAPIRead)
+	blocks.SetReadDB(apiReads)
+	accounts.SetReadDB(apiReads)
+	tx_graph.SetReadDB(apiReads)
+	tze_graph.SetReadDB(apiReads)
+	starks.SetReadDB(apiReads)
+	mempool.SetReadDB(apiReads)
+	log.Printf("API lookups limited to a %ds statement timeout", config.Conf.Database.StatementTimeouts.APIRead)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/usage"
+)
+
+const (
+	// apiMaxRestarts is how many times in a row the API listener is restarted before giving up
+	apiMaxRestarts = 5
+	// apiRestartDelay is the pause before the first API restart; each further attempt waits one more delay
+	apiRestartDelay = 5 * time.Second
+	// apiStableAfter resets the API restart count once the listener has served this long
+	apiStableAfter = time.Minute
+
+	// dbCheckInterval is how often the database connection is pinged
+	dbCheckInterval = 15 * time.Second
+	// dbMaxOutage is how long the database may stay unreachable before the process exits
+	// pgxpool reconnects on its own, so shorter outages are only logged
+	dbMaxOutage = 5 * time.Minute
+)
+
+// Components named in the shutdown reason
+const (
+	componentSignal   = "signal"
+	componentIndexer  = "indexer"
+	componentAPI      = "api"
+	componentDatabase = "database"
+)
+
+// shutdown is why the process is exiting
+type shutdown struct {
+	component string
+	err       error
+}
+
+// exitCode is 0 for a requested shutdown and 1 for a failure
+func (s shutdown) exitCode() int {
+	if s.component == componentSignal {
+		return 0
+	}
+	return 1
+}
+
+// log writes the shutdown reason as one key=value line that log pipelines can parse
+func (s shutdown) log() {
+	reason := "interrupt"
+	if s.err != nil {
+		reason = s.err.Error()
+	}
+	log.Printf("shutdown component=%s exit_code=%d reason=%q", s.component, s.exitCode(), reason)
+}
+
+// serveAPI runs the API server, restarting its listener with a growing pause when it fails
+// It reports an error once apiMaxRestarts restarts in a row have failed
+func serveAPI(server *http.Server, errs chan<- error) {
+	restarts := 0
+	for {
+		started := time.Now()
+		err := server.ListenAndServe()
+		if errors.Is(err, http.ErrServerClosed) {
+			return
+		}
+		if time.Since(started) > apiStableAfter {
+			restarts = 0
+		}
+		if restarts >= apiMaxRestarts {
+			errs <- fmt.Errorf("API server failed after %d restarts: %w", restarts, err)
+			return
+		}
+
+		restarts++
+		delay := time.Duration(restarts) * apiRestartDelay
+		log.Printf("API server failed: %v; restarting in %s (attempt %d/%d)", err, delay, restarts, apiMaxRestarts)
+		time.Sleep(delay)
+	}
+}
+
+// shutdownAPI stops accepting connections and waits up to api.shutdown_timeout for in-flight
+// requests to finish, then writes the usage counts they recorded while the database is still open
+func shutdownAPI(server *http.Server) {
+	timeout := time.Duration(config.Conf.Api.ShutdownTimeout) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	log.Printf("Draining API requests (up to %s)...", timeout)
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("API requests did not finish within %s, closing connections: %v", timeout, err)
+		server.Close()
+	}
+
+	if usage.Enabled() {
+		if err := usage.Flush(context.Background()); err != nil {
+			log.Printf("%v", err)
+		}
+	}
+}
+
+// watchDatabase pings the database and reports an error once it has been unreachable for dbMaxOutage
+func watchDatabase(errs chan<- error) {
+	if postgres.DB == nil {
+		return
+	}
+
+	ticker := time.NewTicker(dbCheckInterval)
+	defer ticker.Stop()
+
+	var downSince time.Time
+	for range ticker.C {
+		err := pingDatabase()
+		switch {
+		case err == nil && !downSince.IsZero():
+			log.Printf("Database reachable again after %s", time.Since(downSince).Round(time.Second))
+			downSince = time.Time{}
+		case err != nil && downSince.IsZero():
+			downSince = time.Now()
+			log.Printf("Database unreachable, exiting if it is not back within %s: %v", dbMaxOutage, err)
+		case err != nil && time.Since(downSince) > dbMaxOutage:
+			errs <- fmt.Errorf("database unreachable for %s: %w", time.Since(downSince).Round(time.Second), err)
+			return
+		}
+	}
+}
+
+func pingDatabase() error {
+	if postgres.DB == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), dbCheckInterval)
+	defer cancel()
+	return postgres.DB.Ping(ctx)
+}
+
+// indexerShutdown attributes an indexer failure to the database when it is unreachable,
+// since the indexer only stops after its own retries and supervisor have given up
+func indexerShutdown(err error) shutdown {
+	if pingErr := pingDatabase(); pingErr != nil {
+		return shutdown{component: componentDatabase, err: fmt.Errorf("%w (database unreachable: %v)", err, pingErr)}
+	}
+	return shutdown{component: componentIndexer, err: err}
+}
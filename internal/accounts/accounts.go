@@ -17,11 +17,61 @@ type DBTX interface {
 	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
 }
 
+// readDB serves account lookups; nil falls back to postgres.DB
+var readDB postgres.Querier
+
+// SetReadDB routes account lookups through q, e.g. a read replica
+// pool or a mock in tests. Passing nil restores the global postgres.DB
+func SetReadDB(q postgres.Querier) {
+	readDB = q
+}
+
 func init() {
 	// Register this module's schema initialization with the postgres package
 	postgres.RegisterModuleSchema("ACCOUNTS", InitSchema)
 }
 
+// redundantIndexes are created by default but dropped when database.drop_redundant_indexes
+// is enabled, since address is the leading column of the primary key and idx_account_txs_address_block
+var redundantIndexes = []postgres.RedundantIndex{
+	{
+		Name:       "idx_account_txs_address",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_account_txs_address ON account_transactions(address);`,
+	},
+}
+
+// secondaryIndexes serve account queries
+// idx_account_txs_txid is required because per-block row counting scopes account_transactions by txid
+var secondaryIndexes = []postgres.SecondaryIndex{
+	// Indexes for accounts
+	{
+		Name:       "idx_accounts_balance",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_accounts_balance ON accounts(balance);`,
+	},
+	{
+		Name:       "idx_accounts_first_seen_at",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_accounts_first_seen_at ON accounts(first_seen_at);`,
+	},
+	// Indexes for account transactions
+	{
+		Name:       "idx_account_txs_txid",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_account_txs_txid ON account_transactions(txid);`,
+		Required:   true,
+	},
+	{
+		Name:       "idx_account_txs_block_height",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_account_txs_block_height ON account_transactions(block_height);`,
+	},
+	{
+		Name:       "idx_account_txs_type",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_account_txs_type ON account_transactions(type);`,
+	},
+	{
+		Name:       "idx_account_txs_address_block",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_account_txs_address_block ON account_transactions(address, block_height DESC);`,
+	},
+}
+
 // InitSchema creates the account tables and indexes
 func InitSchema() error {
 	schema := `
@@ -37,22 +87,14 @@ func InitSchema() error {
 			address VARCHAR(255) NOT NULL,
 			txid VARCHAR(64) NOT NULL,
 			block_height BIGINT NOT NULL,
-			type VARCHAR(10) NOT NULL,
+			type VARCHAR(20) NOT NULL,
 			balance_change BIGINT NOT NULL DEFAULT 0,
 			PRIMARY KEY (address, txid),
 			FOREIGN KEY (address) REFERENCES accounts(address) ON DELETE CASCADE
 		);
 
-		-- Indexes for accounts
-		CREATE INDEX IF NOT EXISTS idx_accounts_balance ON accounts(balance);
-		CREATE INDEX IF NOT EXISTS idx_accounts_first_seen_at ON accounts(first_seen_at);
-
-		-- Indexes for account transactions
-		CREATE INDEX IF NOT EXISTS idx_account_txs_address ON account_transactions(address);
-		CREATE INDEX IF NOT EXISTS idx_account_txs_txid ON account_transactions(txid);
-		CREATE INDEX IF NOT EXISTS idx_account_txs_block_height ON account_transactions(block_height);
-		CREATE INDEX IF NOT EXISTS idx_account_txs_type ON account_transactions(type);
-		CREATE INDEX IF NOT EXISTS idx_account_txs_address_block ON account_transactions(address, block_height DESC);
+		-- Widened from VARCHAR(10) to fit coinbase_reward
+		ALTER TABLE account_transactions ALTER COLUMN type TYPE VARCHAR(20);
 	`
 
 	_, err := postgres.DB.Exec(context.Background(), schema)
@@ -60,12 +102,21 @@ func InitSchema() error {
 		return fmt.Errorf("failed to create account schema: %w", err)
 	}
 
+	if err := postgres.InitSecondaryIndexes(secondaryIndexes); err != nil {
+		return fmt.Errorf("failed to init accounts indexes: %w", err)
+	}
+
+	if err := postgres.InitRedundantIndexes(redundantIndexes); err != nil {
+		return fmt.Errorf("failed to init accounts redundant indexes: %w", err)
+	}
+
 	return nil
 }
 
 // GetAccount retrieves an account by its address
 func GetAccount(address string) (*Account, error) {
-	account, err := postgres.PostgresQueryOne[Account](
+	account, err := postgres.PostgresQueryOneCtx[Account](
+		context.Background(), readDB,
 		`SELECT address, balance, first_seen_at
 		 FROM accounts WHERE address = $1`,
 		address,
@@ -83,7 +134,8 @@ func GetAccount(address string) (*Account, error) {
 
 // GetAccounts retrieves accounts with pagination
 func GetAccounts(limit, offset int) ([]Account, error) {
-	accounts, err := postgres.PostgresQuery[Account](
+	accounts, err := postgres.PostgresQueryCtx[Account](
+		context.Background(), readDB,
 		`SELECT address, balance, first_seen_at
 		 FROM accounts
 		 ORDER BY balance DESC, first_seen_at DESC
@@ -99,7 +151,8 @@ func GetAccounts(limit, offset int) ([]Account, error) {
 
 // GetAccountsByBalanceRange retrieves accounts within a balance range
 func GetAccountsByBalanceRange(minBalance, maxBalance int64, limit, offset int) ([]Account, error) {
-	accounts, err := postgres.PostgresQuery[Account](
+	accounts, err := postgres.PostgresQueryCtx[Account](
+		context.Background(), readDB,
 		`SELECT address, balance, first_seen_at
 		 FROM accounts
 		 WHERE balance >= $1 AND balance <= $2
@@ -116,7 +169,8 @@ func GetAccountsByBalanceRange(minBalance, maxBalance int64, limit, offset int)
 
 // GetTopAccountsByBalance retrieves accounts with highest balances
 func GetTopAccountsByBalance(limit int) ([]Account, error) {
-	accounts, err := postgres.PostgresQuery[Account](
+	accounts, err := postgres.PostgresQueryCtx[Account](
+		context.Background(), readDB,
 		`SELECT address, balance, first_seen_at
 		 FROM accounts
 		 ORDER BY balance DESC
@@ -131,14 +185,17 @@ func GetTopAccountsByBalance(limit int) ([]Account, error) {
 }
 
 // GetAccountTransactions retrieves all transactions for an account
-func GetAccountTransactions(address string, limit, offset int) ([]AccountTransaction, error) {
-	txs, err := postgres.PostgresQuery[AccountTransaction](
+// Transactions that did not change the balance, e.g. zero-value outputs, are left out unless includeZero is set
+// Only transactions whose signed balance_change lies within [minChange, maxChange] are returned
+func GetAccountTransactions(address string, includeZero bool, minChange, maxChange int64, limit, offset int) ([]AccountTransaction, error) {
+	txs, err := postgres.PostgresQueryCtx[AccountTransaction](
+		context.Background(), readDB,
 		`SELECT address, txid, block_height, type, balance_change
 		 FROM account_transactions
-		 WHERE address = $1
+		 WHERE address = $1 AND ($2 OR balance_change <> 0) AND balance_change BETWEEN $3 AND $4
 		 ORDER BY block_height DESC
-		 LIMIT $2 OFFSET $3`,
-		address, limit, offset,
+		 LIMIT $5 OFFSET $6`,
+		address, includeZero, minChange, maxChange, limit, offset,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get account transactions: %w", err)
@@ -148,14 +205,15 @@ func GetAccountTransactions(address string, limit, offset int) ([]AccountTransac
 }
 
 // GetAccountTransactionsByType retrieves transactions for an account filtered by type
-func GetAccountTransactionsByType(address string, txType string, limit, offset int) ([]AccountTransaction, error) {
-	txs, err := postgres.PostgresQuery[AccountTransaction](
+func GetAccountTransactionsByType(address string, txType string, includeZero bool, minChange, maxChange int64, limit, offset int) ([]AccountTransaction, error) {
+	txs, err := postgres.PostgresQueryCtx[AccountTransaction](
+		context.Background(), readDB,
 		`SELECT address, txid, block_height, type, balance_change
 		 FROM account_transactions
-		 WHERE address = $1 AND type = $2
+		 WHERE address = $1 AND type = $2 AND ($3 OR balance_change <> 0) AND balance_change BETWEEN $4 AND $5
 		 ORDER BY block_height DESC
-		 LIMIT $3 OFFSET $4`,
-		address, txType, limit, offset,
+		 LIMIT $6 OFFSET $7`,
+		address, txType, includeZero, minChange, maxChange, limit, offset,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get account transactions by type: %w", err)
@@ -165,24 +223,26 @@ func GetAccountTransactionsByType(address string, txType string, limit, offset i
 }
 
 // GetAccountReceivingTransactions retrieves receiving transactions for an account
-func GetAccountReceivingTransactions(address string, limit, offset int) ([]AccountTransaction, error) {
-	return GetAccountTransactionsByType(address, string(TxTypeReceive), limit, offset)
+func GetAccountReceivingTransactions(address string, includeZero bool, minChange, maxChange int64, limit, offset int) ([]AccountTransaction, error) {
+	return GetAccountTransactionsByType(address, string(TxTypeReceive), includeZero, minChange, maxChange, limit, offset)
 }
 
 // GetAccountSendingTransactions retrieves sending transactions for an account
-func GetAccountSendingTransactions(address string, limit, offset int) ([]AccountTransaction, error) {
-	return GetAccountTransactionsByType(address, string(TxTypeSend), limit, offset)
+func GetAccountSendingTransactions(address string, includeZero bool, minChange, maxChange int64, limit, offset int) ([]AccountTransaction, error) {
+	return GetAccountTransactionsByType(address, string(TxTypeSend), includeZero, minChange, maxChange, limit, offset)
 }
 
 // GetAccountTransactionsByBlockRange retrieves transactions for an account within a block range
-func GetAccountTransactionsByBlockRange(address string, fromBlock, toBlock int64, limit, offset int) ([]AccountTransaction, error) {
-	txs, err := postgres.PostgresQuery[AccountTransaction](
+func GetAccountTransactionsByBlockRange(address string, fromBlock, toBlock int64, includeZero bool, minChange, maxChange int64, limit, offset int) ([]AccountTransaction, error) {
+	txs, err := postgres.PostgresQueryCtx[AccountTransaction](
+		context.Background(), readDB,
 		`SELECT address, txid, block_height, type, balance_change
 		 FROM account_transactions
-		 WHERE address = $1 AND block_height >= $2 AND block_height <= $3
+		 WHERE address = $1 AND block_height >= $2 AND block_height <= $3 AND ($4 OR balance_change <> 0)
+		   AND balance_change BETWEEN $5 AND $6
 		 ORDER BY block_height DESC
-		 LIMIT $4 OFFSET $5`,
-		address, fromBlock, toBlock, limit, offset,
+		 LIMIT $7 OFFSET $8`,
+		address, fromBlock, toBlock, includeZero, minChange, maxChange, limit, offset,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get account transactions by block range: %w", err)
@@ -197,7 +257,8 @@ func GetAccountTransactionCount(address string) (int64, error) {
 		Count int64 `db:"count"`
 	}
 
-	res, err := postgres.PostgresQueryOne[result](
+	res, err := postgres.PostgresQueryOneCtx[result](
+		context.Background(), readDB,
 		`SELECT COUNT(*) as count FROM account_transactions WHERE address = $1`,
 		address,
 	)
@@ -210,7 +271,8 @@ func GetAccountTransactionCount(address string) (int64, error) {
 
 // GetAccountTransaction retrieves a specific transaction for an account
 func GetAccountTransaction(address, txid string) (*AccountTransaction, error) {
-	tx, err := postgres.PostgresQueryOne[AccountTransaction](
+	tx, err := postgres.PostgresQueryOneCtx[AccountTransaction](
+		context.Background(), readDB,
 		`SELECT address, txid, block_height, type, balance_change
 		 FROM account_transactions
 		 WHERE address = $1 AND txid = $2`,
@@ -229,7 +291,8 @@ func GetAccountTransaction(address, txid string) (*AccountTransaction, error) {
 
 // GetTransactionAccounts retrieves all accounts associated with a transaction
 func GetTransactionAccounts(txid string) ([]AccountTransaction, error) {
-	txs, err := postgres.PostgresQuery[AccountTransaction](
+	txs, err := postgres.PostgresQueryCtx[AccountTransaction](
+		context.Background(), readDB,
 		`SELECT address, txid, block_height, type, balance_change
 		 FROM account_transactions
 		 WHERE txid = $1
@@ -245,7 +308,8 @@ func GetTransactionAccounts(txid string) ([]AccountTransaction, error) {
 
 // GetRecentActiveAccounts retrieves accounts with recent transaction activity
 func GetRecentActiveAccounts(limit int) ([]Account, error) {
-	accounts, err := postgres.PostgresQuery[Account](
+	accounts, err := postgres.PostgresQueryCtx[Account](
+		context.Background(), readDB,
 		`SELECT a.address, a.balance, a.first_seen_at
 		 FROM accounts a
 		 WHERE a.address IN (
@@ -296,12 +360,7 @@ func StoreAccountTransaction(postgresTx DBTX, address string, txid string, block
 
 // CountAccounts returns the total count of accounts
 func CountAccounts() (int64, error) {
-	var count int64
-	err := postgres.DB.QueryRow(context.Background(), `SELECT COUNT(*) FROM accounts`).Scan(&count)
-	if err != nil {
-		return 0, fmt.Errorf("failed to count accounts: %w", err)
-	}
-	return count, nil
+	return postgres.CountRows(context.Background(), readDB, "accounts")
 }
 
 // CountAccountTransactions returns the total count of account transactions with optional filters
@@ -319,12 +378,12 @@ func CountAccountTransactions(address string, txType string) (int64, error) {
 		query = `SELECT COUNT(*) FROM account_transactions WHERE type = $1`
 		args = []interface{}{txType}
 	} else {
-		query = `SELECT COUNT(*) FROM account_transactions`
-		args = []interface{}{}
+		// Unfiltered counts come from the maintained row counter
+		return postgres.CountRows(context.Background(), readDB, "account_transactions")
 	}
 
 	var count int64
-	err := postgres.DB.QueryRow(context.Background(), query, args...).Scan(&count)
+	err := postgres.ReadQuerier(readDB).QueryRow(context.Background(), query, args...).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count account transactions: %w", err)
 	}
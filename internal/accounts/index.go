@@ -3,124 +3,238 @@ package accounts
 import (
 	"context"
 	"fmt"
-	"log"
+	"math"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/logging"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/types"
 )
 
 // IndexAccounts indexes account-related data from a Zcash block
 // This function extracts and stores account balances, transactions, and related data
-// All account updates in a block are indexed atomically in a single database transaction
-func IndexAccounts(block *types.ZcashBlock) error {
+// Account updates are written through postgresTx, the block's transaction shared by every module
+func IndexAccounts(postgresTx pgx.Tx, block *types.ZcashBlock) error {
 	// Check if accounts module is enabled
 	if !config.IsModuleEnabled("ACCOUNTS") {
 		return nil
 	}
 
-	log.Printf("Indexing accounts for block %d (hash: %s, %d transactions)",
+	logging.Blockf(logging.ModuleAccounts, "Indexing accounts for block %d (hash: %s, %d transactions)",
 		block.Height, block.Hash, len(block.Tx))
 
 	ctx := context.Background()
 
-	// Begin a database transaction for the entire block
-	postgresTx, err := postgres.DB.Begin(ctx)
+	// Resolve what each address spent, from the sender addresses tx_graph recorded for the block
+	sent, err := resolveSentAmounts(ctx, postgresTx, block)
 	if err != nil {
-		return fmt.Errorf("failed to begin database transaction for block %d: %w", block.Height, err)
+		return err
 	}
-	defer postgresTx.Rollback(ctx)
 
 	// Track balance changes for each address in this block
 	balanceChanges := make(map[string]int64)
 
 	// Process each transaction in the block
 	for _, tx := range block.Tx {
-		if err := indexAccountTransaction(postgresTx, block, &tx, balanceChanges); err != nil {
+		if err := indexAccountTransaction(block, &tx, sent[tx.TxID], balanceChanges); err != nil {
 			return fmt.Errorf("failed to index accounts for transaction %s in block %d: %w",
 				tx.TxID, block.Height, err)
 		}
 	}
 
-	// Update account balances first (this creates accounts if they don't exist)
-	for address, change := range balanceChanges {
-		if err := updateAccountBalance(postgresTx, address, change); err != nil {
-			return fmt.Errorf("failed to update balance for account %s in block %d: %w",
-				address, block.Height, err)
+	// Store accounts and account transactions, keeping the row counters in step
+	addresses := make([]string, 0, len(balanceChanges))
+	for address := range balanceChanges {
+		addresses = append(addresses, address)
+	}
+	txids := make([]string, len(block.Tx))
+	for i, tx := range block.Tx {
+		txids[i] = tx.TxID
+	}
+	scopes := []postgres.RowScope{
+		{Table: "accounts", Column: "address", Keys: addresses},
+		{Table: "account_transactions", Column: "txid", Keys: txids},
+	}
+	err = postgres.TrackRowCounts(ctx, postgresTx, scopes, func() error {
+		// Update account balances first (this creates accounts if they don't exist)
+		for address, change := range balanceChanges {
+			if err := updateAccountBalance(postgresTx, address, change); err != nil {
+				return fmt.Errorf("failed to update balance for account %s in block %d: %w",
+					address, block.Height, err)
+			}
+		}
+		postgres.QueueManifestKeys(postgresTx, "ACCOUNTS", "accounts", "update", addresses...)
+
+		// Now store account transactions (accounts exist now, so FK constraint satisfied)
+		for _, tx := range block.Tx {
+			if err := storeAccountTransactionsForTx(postgresTx, block, &tx, sent[tx.TxID]); err != nil {
+				return fmt.Errorf("failed to store account transactions for tx %s in block %d: %w",
+					tx.TxID, block.Height, err)
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	logging.Blockf(logging.ModuleAccounts, "Successfully indexed accounts for block %d (%d addresses affected)",
+		block.Height, len(balanceChanges))
+	return nil
+}
+
+// resolveSentAmounts returns, by txid and address, the value each transparent address spent in
+// the block's transactions
+// Sender addresses are read from inputs_addresses, which tx_graph fills earlier in the same
+// database transaction; without TX_GRAPH, or for outputs tx_graph has no addresses for (e.g. ones
+// indexed before it recorded them), spends cannot be attributed and balances are not debited
+func resolveSentAmounts(ctx context.Context, postgresTx DBTX, block *types.ZcashBlock) (map[string]map[string]int64, error) {
+	sent := make(map[string]map[string]int64)
+	if !config.IsModuleEnabled("TX_GRAPH") {
+		return sent, nil
 	}
 
-	// Now store account transactions (accounts exist now, so FK constraint satisfied)
+	var txids []string
 	for _, tx := range block.Tx {
-		if err := storeAccountTransactionsForTx(postgresTx, block, &tx); err != nil {
-			return fmt.Errorf("failed to store account transactions for tx %s in block %d: %w",
-				tx.TxID, block.Height, err)
+		if !tx.IsCoinbase() {
+			txids = append(txids, tx.TxID)
 		}
 	}
+	if len(txids) == 0 {
+		return sent, nil
+	}
 
-	// Commit the transaction
-	if err := postgresTx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit database transaction for block %d: %w", block.Height, err)
+	rows, err := postgresTx.Query(ctx, `
+		SELECT txid, address, SUM(value)
+		FROM inputs_addresses
+		WHERE txid = ANY($1)
+		GROUP BY txid, address
+	`, txids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve sender addresses of block %d: %w", block.Height, err)
 	}
+	defer rows.Close()
 
-	log.Printf("Successfully indexed accounts for block %d (%d addresses affected)",
-		block.Height, len(balanceChanges))
-	return nil
+	for rows.Next() {
+		var txid, address string
+		var value int64
+		if err := rows.Scan(&txid, &address, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan sender address of block %d: %w", block.Height, err)
+		}
+		if sent[txid] == nil {
+			sent[txid] = make(map[string]int64)
+		}
+		sent[txid][address] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to resolve sender addresses of block %d: %w", block.Height, err)
+	}
+
+	return sent, nil
 }
 
 // indexAccountTransaction processes a single transaction and tracks balance changes
+// sent holds the value each address spent in the transaction
 // Note: This does NOT store account_transactions - that happens after accounts are created
-func indexAccountTransaction(postgresTx DBTX, block *types.ZcashBlock, tx *types.ZcashTransaction, balanceChanges map[string]int64) error {
-	// Skip coinbase transactions for input processing (they have no spender address)
-	if !tx.IsCoinbase() {
-		// TODO: Process inputs - these represent sending transactions (spending from addresses)
-		// Note: In Zcash, transparent inputs (Vin) don't directly contain the sender's address.
-		// The address must be looked up from the previous output (prevTxid:prevVout) being spent.
-		//
-		// Recommended implementation approach:
-		// 1. Query transaction_outputs table from tx_graph module to get the scriptPubKey/addresses
-		//    for each (vin.TxID, vin.Vout) pair
-		// 2. For each sender address found:
-		//    - Store account transaction with type = "send"
-		//    - Subtract vout.ValueZat from balanceChanges[address]
-		// 3. This requires cross-module data access (accounts depends on tx_graph)
-		//    - Consider indexing order: ensure tx_graph runs before accounts
-		//    - Or implement a post-processing step after both modules complete
-	}
-
-	// Process outputs - track balance changes for receiving transactions
-	for _, vout := range tx.Vout {
-		// Extract addresses from the output script
-		if vout.ScriptPubKey != nil && len(vout.ScriptPubKey.Addresses) > 0 {
-			for _, address := range vout.ScriptPubKey.Addresses {
-				// Track balance change (positive for receiving)
-				balanceChanges[address] += vout.ValueZat
-			}
+func indexAccountTransaction(block *types.ZcashBlock, tx *types.ZcashTransaction, sent map[string]int64, balanceChanges map[string]int64) error {
+	// Credit receivers and debit senders
+	for address, change := range transparentChanges(tx, sent) {
+		balanceChanges[address] += change
+	}
+
+	// Track value moving into or out of the shielded pools as the transaction's counterparty
+	if config.Conf.Modules.Accounts.TrackShielded {
+		for pool, change := range shieldedPoolChanges(tx) {
+			balanceChanges[pool] += change
 		}
 	}
 
 	return nil
 }
 
+// transparentChanges returns the net change in zatoshis of each transparent address in a
+// transaction: the outputs it receives minus the previous outputs it spends
+// An address that spends and receives change in the same transaction has a single net change,
+// which is also what its account transaction records
+func transparentChanges(tx *types.ZcashTransaction, sent map[string]int64) map[string]int64 {
+	changes := make(map[string]int64)
+	outputAddresses := tx.Parsed().OutputAddresses
+	for i, vout := range tx.Vout {
+		for _, address := range outputAddresses[i] {
+			changes[address] += vout.ValueZat
+		}
+	}
+	for address, value := range sent {
+		changes[address] -= value
+	}
+	return changes
+}
+
+// shieldedPoolChanges returns the net value in zatoshis each shielded pool gains (positive)
+// or loses (negative) in a transaction; pools the transaction does not touch are omitted
+func shieldedPoolChanges(tx *types.ZcashTransaction) map[string]int64 {
+	changes := make(map[string]int64)
+
+	// vpub_old enters the Sprout pool, vpub_new leaves it
+	sprout := int64(0)
+	for _, js := range tx.VJoinSplit {
+		sprout += int64(math.Round(js.VPubOld*1e8)) - int64(math.Round(js.VPubNew*1e8))
+	}
+	if sprout != 0 {
+		changes[ShieldedSproutAddress] = sprout
+	}
+
+	// A positive value balance is value leaving the pool into the transparent part of the transaction
+	if tx.ValueBalanceZat != 0 {
+		changes[ShieldedSaplingAddress] = -tx.ValueBalanceZat
+	}
+	if tx.Orchard != nil && tx.Orchard.ValueBalanceZat != 0 {
+		changes[ShieldedOrchardAddress] = -tx.Orchard.ValueBalanceZat
+	}
+
+	return changes
+}
+
 // storeAccountTransactionsForTx stores account transaction records for a single transaction
 // This should be called AFTER accounts are created to satisfy foreign key constraints
-func storeAccountTransactionsForTx(postgresTx DBTX, block *types.ZcashBlock, tx *types.ZcashTransaction) error {
-	// Process outputs - record receiving transactions
-	for _, vout := range tx.Vout {
-		if vout.ScriptPubKey != nil && len(vout.ScriptPubKey.Addresses) > 0 {
-			for _, address := range vout.ScriptPubKey.Addresses {
-				err := StoreAccountTransaction(
-					postgresTx,
-					address,
-					tx.TxID,
-					block.Height,
-					string(TxTypeReceive),
-					int64(vout.Value), // positive value for receiving
-				)
-				if err != nil {
-					return fmt.Errorf("failed to store receiving transaction for address %s: %w", address, err)
-				}
+func storeAccountTransactionsForTx(postgresTx DBTX, block *types.ZcashBlock, tx *types.ZcashTransaction, sent map[string]int64) error {
+	// Record one row per address with its net change: a send when the address lost value,
+	// otherwise a receive, tagging miner income apart from transfers
+	receiveType := TxTypeReceive
+	if tx.IsCoinbase() {
+		receiveType = TxTypeCoinbaseReward
+	}
+	for address, change := range transparentChanges(tx, sent) {
+		txType := receiveType
+		if change < 0 {
+			txType = TxTypeSend
+		}
+		err := StoreAccountTransaction(
+			postgresTx,
+			address,
+			tx.TxID,
+			block.Height,
+			string(txType),
+			change, // positive for receiving, negative for sending
+		)
+		if err != nil {
+			return fmt.Errorf("failed to store %s transaction for address %s: %w", txType, address, err)
+		}
+		postgres.QueueManifestKeys(postgresTx, "ACCOUNTS", "account_transactions", "insert", address+":"+tx.TxID)
+	}
+
+	// Record the shielded pool side of t2z/z2t flows
+	if config.Conf.Modules.Accounts.TrackShielded {
+		for pool, change := range shieldedPoolChanges(tx) {
+			txType := TxTypeReceive
+			if change < 0 {
+				txType = TxTypeSend
+			}
+			if err := StoreAccountTransaction(postgresTx, pool, tx.TxID, block.Height, string(txType), change); err != nil {
+				return fmt.Errorf("failed to store shielded pool transaction for %s: %w", pool, err)
 			}
+			postgres.QueueManifestKeys(postgresTx, "ACCOUNTS", "account_transactions", "insert", pool+":"+tx.TxID)
 		}
 	}
 
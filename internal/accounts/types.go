@@ -14,7 +14,7 @@ type AccountTransaction struct {
 	Address       string `json:"address" db:"address"`
 	TxID          string `json:"txid" db:"txid"`
 	BlockHeight   int64  `json:"block_height" db:"block_height"`
-	Type          string `json:"type" db:"type"`                     // receive, send
+	Type          string `json:"type" db:"type"`                     // receive, send, coinbase_reward
 	BalanceChange int64  `json:"balance_change" db:"balance_change"` // positive for receive, negative for send
 }
 
@@ -22,6 +22,16 @@ type AccountTransaction struct {
 type AccountTransactionType string
 
 const (
-	TxTypeReceive AccountTransactionType = "receive" // receiving transaction (funds coming in)
-	TxTypeSend    AccountTransactionType = "send"    // sending transaction (funds going out)
+	TxTypeReceive        AccountTransactionType = "receive"         // receiving transaction (funds coming in)
+	TxTypeSend           AccountTransactionType = "send"            // sending transaction (funds going out)
+	TxTypeCoinbaseReward AccountTransactionType = "coinbase_reward" // block reward or fees paid to a coinbase output
+)
+
+// Shielded pool pseudo-accounts, tracked when modules.accounts.track_shielded is enabled
+// Each aggregates the value entering (receive) and leaving (send) its pool from transparent transactions
+// The ':' keeps them from colliding with transparent addresses
+const (
+	ShieldedSproutAddress  = "shielded:sprout"
+	ShieldedSaplingAddress = "shielded:sapling"
+	ShieldedOrchardAddress = "shielded:orchard"
 )
@@ -0,0 +1,182 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+)
+
+func init() {
+	// Register this as a core schema (always initialized)
+	postgres.RegisterCoreSchema("alerts", InitSchema)
+}
+
+// InitSchema creates the alert rule and alert tables
+// Alerts are kept across reorgs; they record what was observed when the block was indexed
+func InitSchema() error {
+	schema := `
+		CREATE TABLE IF NOT EXISTS alert_rules (
+			name VARCHAR(64) PRIMARY KEY,
+			kind VARCHAR(32) NOT NULL,
+			threshold BIGINT NOT NULL DEFAULT 0,
+			verifier_id VARCHAR(128) NOT NULL DEFAULT '',
+			program_hash VARCHAR(128) NOT NULL DEFAULT '',
+			blocks BIGINT NOT NULL DEFAULT 0,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS alerts (
+			id BIGSERIAL PRIMARY KEY,
+			rule_name VARCHAR(64) NOT NULL,
+			kind VARCHAR(32) NOT NULL,
+			subject VARCHAR(128) NOT NULL,  -- txid, verifier_id or program hash
+			value BIGINT NOT NULL,
+			message TEXT NOT NULL,
+			status VARCHAR(16) NOT NULL,  -- firing, resolved or triggered
+			block_height BIGINT NOT NULL,
+			fired_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			resolved_height BIGINT,
+			resolved_at TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_alerts_firing ON alerts(rule_name, subject) WHERE status = 'firing';
+		CREATE INDEX IF NOT EXISTS idx_alerts_rule ON alerts(rule_name, id);
+	`
+
+	_, err := postgres.DB.Exec(context.Background(), schema)
+	if err != nil {
+		return fmt.Errorf("failed to create alerts schema: %w", err)
+	}
+
+	return nil
+}
+
+// Enabled reports whether alert rules are evaluated
+func Enabled() bool {
+	return config.Conf.Alerts.Enabled
+}
+
+// IsStatus reports whether status is an alert status
+func IsStatus(status string) bool {
+	return status == StatusFiring || status == StatusResolved || status == StatusTriggered
+}
+
+// ValidateRule checks a rule added through the API; names of configuration rules are taken
+func ValidateRule(rule *Rule) error {
+	if _, ok := config.Conf.Alerts.Rules[rule.Name]; ok {
+		return fmt.Errorf("rule %s is defined in the configuration", rule.Name)
+	}
+	return config.ValidateAlertRule(rule.Name, config.AlertRuleConfig{
+		Kind:        rule.Kind,
+		Threshold:   rule.Threshold,
+		VerifierID:  rule.VerifierID,
+		ProgramHash: rule.ProgramHash,
+		Blocks:      rule.Blocks,
+	})
+}
+
+// GetRules returns the configuration rules followed by the rules added through the API, each by name
+func GetRules(ctx context.Context) ([]Rule, error) {
+	rules := make([]Rule, 0, len(config.Conf.Alerts.Rules))
+	for name, rule := range config.Conf.Alerts.Rules {
+		rules = append(rules, Rule{
+			Name:        name,
+			Kind:        rule.Kind,
+			Threshold:   rule.Threshold,
+			VerifierID:  rule.VerifierID,
+			ProgramHash: rule.ProgramHash,
+			Blocks:      rule.Blocks,
+			Source:      SourceConfig,
+		})
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Name < rules[j].Name })
+
+	apiRules, err := postgres.PostgresQueryCtx[Rule](ctx, nil,
+		`SELECT name, kind, threshold, verifier_id, program_hash, blocks, 'api' AS source, created_at
+		 FROM alert_rules
+		 ORDER BY name`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert rules: %w", err)
+	}
+
+	return append(rules, apiRules...), nil
+}
+
+// CreateRule stores a rule added through the API; the rule must have been validated
+// Returns nil when a rule with the same name already exists
+func CreateRule(rule Rule) (*Rule, error) {
+	created, err := postgres.PostgresQueryOneCtx[Rule](context.Background(), nil,
+		`INSERT INTO alert_rules (name, kind, threshold, verifier_id, program_hash, blocks)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 ON CONFLICT (name) DO NOTHING
+		 RETURNING name, kind, threshold, verifier_id, program_hash, blocks, 'api' AS source, created_at`,
+		rule.Name, rule.Kind, rule.Threshold, rule.VerifierID, rule.ProgramHash, rule.Blocks,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create alert rule %s: %w", rule.Name, err)
+	}
+
+	return created, nil
+}
+
+// DeleteRule removes a rule added through the API and resolves its firing alerts
+// Returns false when no such rule exists
+func DeleteRule(name string) (bool, error) {
+	ctx := context.Background()
+	tx, err := postgres.DB.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `DELETE FROM alert_rules WHERE name = $1`, name)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete alert rule %s: %w", name, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	_, err = tx.Exec(ctx,
+		`UPDATE alerts SET status = 'resolved', resolved_at = CURRENT_TIMESTAMP
+		 WHERE rule_name = $1 AND status = 'firing'`,
+		name,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve alerts of rule %s: %w", name, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit alert rule deletion: %w", err)
+	}
+
+	return true, nil
+}
+
+const alertColumns = `id, rule_name, kind, subject, value, message, status, block_height, fired_at, resolved_height, resolved_at`
+
+// GetAlerts lists alerts newest first, optionally filtered by rule and status
+func GetAlerts(ruleName, status string, limit, offset int) ([]Alert, error) {
+	alerts, err := postgres.PostgresQueryCtx[Alert](context.Background(), nil,
+		`SELECT `+alertColumns+`
+		 FROM alerts
+		 WHERE ($1 = '' OR rule_name = $1) AND ($2 = '' OR status = $2)
+		 ORDER BY id DESC
+		 LIMIT $3 OFFSET $4`,
+		ruleName, status, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alerts: %w", err)
+	}
+
+	return alerts, nil
+}
@@ -0,0 +1,251 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/events"
+)
+
+// eventBuffer is how many events may wait before the event bus drops the alert subscriber;
+// blocks published before it resubscribes are not evaluated
+const eventBuffer = 1024
+
+var (
+	stopChan chan struct{}
+	loopDone chan struct{}
+)
+
+// Start evaluates every rule after each indexed block until Stop is called
+// It does nothing unless alerts are enabled
+func Start() {
+	if !Enabled() {
+		return
+	}
+
+	stopChan = make(chan struct{})
+	loopDone = make(chan struct{})
+	go func() {
+		defer close(loopDone)
+		evaluateLoop(stopChan)
+	}()
+}
+
+// Stop ends evaluation, waiting for a block being evaluated
+func Stop() {
+	if stopChan != nil {
+		close(stopChan)
+		<-loopDone
+		stopChan = nil
+	}
+}
+
+func evaluateLoop(stop chan struct{}) {
+	sub := events.Subscribe(eventBuffer)
+	for {
+		select {
+		case <-stop:
+			events.Unsubscribe(sub)
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				log.Printf("Alert event subscriber fell behind, resubscribing; blocks published meanwhile are not evaluated")
+				sub = events.Subscribe(eventBuffer)
+				continue
+			}
+			if event.Topic != events.TopicBlocks {
+				continue
+			}
+			Evaluate(context.Background(), event.BlockHeight)
+		}
+	}
+}
+
+// Evaluate checks every rule against the state after the block at height was indexed
+// A rule that fails to evaluate is logged and skipped, so it does not hold back the others
+func Evaluate(ctx context.Context, height int64) {
+	rules, err := GetRules(ctx)
+	if err != nil {
+		log.Printf("%v", err)
+		return
+	}
+
+	for _, rule := range rules {
+		var err error
+		switch rule.Kind {
+		case KindProofSizeAbove:
+			err = evaluateProofSize(ctx, rule, height)
+		case KindVerifierBalanceBelow:
+			err = evaluateVerifierBalance(ctx, rule, height)
+		case KindNoFactForProgram:
+			err = evaluateProgramFacts(ctx, rule, height)
+		}
+		if err != nil {
+			log.Printf("Failed to evaluate alert rule %s at block %d: %v", rule.Name, height, err)
+		}
+	}
+}
+
+// evaluateProofSize triggers an alert for each proof above the threshold submitted in the block
+func evaluateProofSize(ctx context.Context, rule Rule, height int64) error {
+	type largeProof struct {
+		VerifierID string `db:"verifier_id"`
+		Txid       string `db:"txid"`
+		ProofSize  int64  `db:"proof_size"`
+	}
+	proofs, err := postgres.PostgresQueryCtx[largeProof](ctx, nil,
+		`SELECT verifier_id, txid, proof_size
+		 FROM stark_proofs
+		 WHERE block_height = $1 AND proof_size > $2 AND ($3 = '' OR verifier_id = $3)
+		 ORDER BY txid`,
+		height, rule.Threshold, rule.VerifierID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to get proofs: %w", err)
+	}
+
+	for _, proof := range proofs {
+		message := fmt.Sprintf("proof of %d bytes for verifier %s exceeds %d bytes", proof.ProofSize, proof.VerifierID, rule.Threshold)
+		if err := fire(ctx, rule, proof.Txid, proof.ProofSize, message, StatusTriggered, height); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// evaluateVerifierBalance fires for each verifier whose balance dropped below the threshold
+// and resolves the alert once the balance is back at or above it
+func evaluateVerifierBalance(ctx context.Context, rule Rule, height int64) error {
+	type verifierBalance struct {
+		VerifierID string `db:"verifier_id"`
+		Balance    int64  `db:"balance"`
+	}
+	balances, err := postgres.PostgresQueryCtx[verifierBalance](ctx, nil,
+		`SELECT verifier_id, balance
+		 FROM verifiers
+		 WHERE $1 = '' OR verifier_id = $1`,
+		rule.VerifierID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to get verifier balances: %w", err)
+	}
+
+	firing, err := firingAlerts(ctx, rule.Name)
+	if err != nil {
+		return err
+	}
+
+	for _, verifier := range balances {
+		alert, isFiring := firing[verifier.VerifierID]
+		below := verifier.Balance < rule.Threshold
+		switch {
+		case below && !isFiring:
+			message := fmt.Sprintf("verifier %s balance %d is below %d", verifier.VerifierID, verifier.Balance, rule.Threshold)
+			err = fire(ctx, rule, verifier.VerifierID, verifier.Balance, message, StatusFiring, height)
+		case !below && isFiring:
+			err = resolve(ctx, alert, height)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// evaluateProgramFacts fires once more than rule.Blocks blocks have passed without a fact for
+// the program and resolves the alert when a new fact is indexed
+func evaluateProgramFacts(ctx context.Context, rule Rule, height int64) error {
+	programHash := strings.ToLower(rule.ProgramHash)
+	lastFact, err := postgres.PostgresQueryOneCtx[int64](ctx, nil,
+		`SELECT COALESCE(MAX(block_height), -1) FROM ztarknet_facts WHERE program_hash = $1`,
+		programHash,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to get last fact height: %w", err)
+	}
+
+	firing, err := firingAlerts(ctx, rule.Name)
+	if err != nil {
+		return err
+	}
+
+	alert, isFiring := firing[programHash]
+	blocksSince := height - *lastFact
+	stale := blocksSince > rule.Blocks
+	switch {
+	case stale && !isFiring:
+		message := fmt.Sprintf("no fact for program %s in the last %d blocks", programHash, blocksSince)
+		if *lastFact < 0 {
+			message = fmt.Sprintf("no fact for program %s has been indexed", programHash)
+		}
+		return fire(ctx, rule, programHash, blocksSince, message, StatusFiring, height)
+	case !stale && isFiring:
+		return resolve(ctx, alert, height)
+	}
+	return nil
+}
+
+// firingAlerts returns the open alerts of a rule by subject
+func firingAlerts(ctx context.Context, ruleName string) (map[string]Alert, error) {
+	alerts, err := postgres.PostgresQueryCtx[Alert](ctx, nil,
+		`SELECT `+alertColumns+`
+		 FROM alerts
+		 WHERE rule_name = $1 AND status = 'firing'`,
+		ruleName,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get firing alerts: %w", err)
+	}
+
+	bySubject := make(map[string]Alert, len(alerts))
+	for _, alert := range alerts {
+		bySubject[alert.Subject] = alert
+	}
+	return bySubject, nil
+}
+
+// fire records an alert and publishes it on the alerts topic
+func fire(ctx context.Context, rule Rule, subject string, value int64, message, status string, height int64) error {
+	alert, err := postgres.PostgresQueryOneCtx[Alert](ctx, nil,
+		`INSERT INTO alerts (rule_name, kind, subject, value, message, status, block_height)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 RETURNING `+alertColumns,
+		rule.Name, rule.Kind, subject, value, message, status, height,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record alert for %s: %w", subject, err)
+	}
+
+	log.Printf("ALERT %s: %s (block %d)", rule.Name, message, height)
+	publish(alert, height)
+	return nil
+}
+
+// resolve closes a firing alert and publishes the resolved alert
+func resolve(ctx context.Context, alert Alert, height int64) error {
+	resolved, err := postgres.PostgresQueryOneCtx[Alert](ctx, nil,
+		`UPDATE alerts SET status = 'resolved', resolved_height = $2, resolved_at = CURRENT_TIMESTAMP
+		 WHERE id = $1
+		 RETURNING `+alertColumns,
+		alert.ID, height,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to resolve alert %d: %w", alert.ID, err)
+	}
+
+	log.Printf("Alert %s resolved for %s (block %d)", alert.RuleName, alert.Subject, height)
+	publish(resolved, height)
+	return nil
+}
+
+// publish notifies event subscribers at the height the alert fired or resolved at
+func publish(alert *Alert, height int64) {
+	events.Publish(events.Event{
+		Topic:       events.TopicAlerts,
+		BlockHeight: height,
+		Data:        alert,
+	})
+}
@@ -0,0 +1,52 @@
+package alerts
+
+import "time"
+
+// Rule kinds
+const (
+	KindProofSizeAbove       = "proof_size_above"       // a STARK proof larger than threshold bytes
+	KindVerifierBalanceBelow = "verifier_balance_below" // a verifier balance under threshold zatoshis
+	KindNoFactForProgram     = "no_fact_for_program"    // no Ztarknet fact for program_hash in the last blocks blocks
+)
+
+// Where a rule is defined; configuration rules cannot be changed through the API
+const (
+	SourceConfig = "config"
+	SourceApi    = "api"
+)
+
+// Alert statuses
+// proof_size_above alerts are about a single proof, so they are triggered rather than fire and resolve
+const (
+	StatusFiring    = "firing"
+	StatusResolved  = "resolved"
+	StatusTriggered = "triggered"
+)
+
+// Rule is a named alert condition
+type Rule struct {
+	Name        string     `json:"name" db:"name"`
+	Kind        string     `json:"kind" db:"kind"`
+	Threshold   int64      `json:"threshold,omitempty" db:"threshold"`
+	VerifierID  string     `json:"verifier_id,omitempty" db:"verifier_id"`
+	ProgramHash string     `json:"program_hash,omitempty" db:"program_hash"`
+	Blocks      int64      `json:"blocks,omitempty" db:"blocks"`
+	Source      string     `json:"source" db:"source"`
+	CreatedAt   *time.Time `json:"created_at,omitempty" db:"created_at"` // api rules only
+}
+
+// Alert is a rule firing about one subject
+// The event published on the alerts topic carries the alert as its data
+type Alert struct {
+	ID             int64      `json:"id" db:"id"`
+	RuleName       string     `json:"rule_name" db:"rule_name"`
+	Kind           string     `json:"kind" db:"kind"`
+	Subject        string     `json:"subject" db:"subject"` // txid, verifier_id or program hash
+	Value          int64      `json:"value" db:"value"`     // proof size, verifier balance or blocks since the program's last fact
+	Message        string     `json:"message" db:"message"`
+	Status         string     `json:"status" db:"status"`
+	BlockHeight    int64      `json:"block_height" db:"block_height"`
+	FiredAt        time.Time  `json:"fired_at" db:"fired_at"`
+	ResolvedHeight *int64     `json:"resolved_height,omitempty" db:"resolved_height"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty" db:"resolved_at"`
+}
@@ -0,0 +1,143 @@
+package annotations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+)
+
+// Limits on annotation fields
+const (
+	MaxEntityIDLength = 128
+	MaxKeyLength      = 64
+	MaxValueLength    = 4096
+)
+
+func init() {
+	// Register this as a core schema (always initialized)
+	postgres.RegisterCoreSchema("annotations", InitSchema)
+}
+
+// InitSchema creates the annotations table
+// Annotations are kept apart from indexed data, so reorgs and re-indexing never touch them
+func InitSchema() error {
+	schema := `
+		CREATE TABLE IF NOT EXISTS annotations (
+			entity_type VARCHAR(16) NOT NULL,  -- block, tx, verifier or address
+			entity_id VARCHAR(128) NOT NULL,
+			key VARCHAR(64) NOT NULL,
+			value TEXT NOT NULL DEFAULT '',
+			author VARCHAR(64) NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (entity_type, entity_id, key)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_annotations_type_key ON annotations(entity_type, key);
+	`
+
+	_, err := postgres.DB.Exec(context.Background(), schema)
+	if err != nil {
+		return fmt.Errorf("failed to create annotations schema: %w", err)
+	}
+
+	return nil
+}
+
+// IsEntityType reports whether entityType is one of the annotatable entity types
+func IsEntityType(entityType string) bool {
+	switch entityType {
+	case EntityBlock, EntityTx, EntityVerifier, EntityAddress:
+		return true
+	}
+	return false
+}
+
+// Validate checks an annotation's entity type and field lengths
+func Validate(a *Annotation) error {
+	if !IsEntityType(a.EntityType) {
+		return fmt.Errorf("entity_type must be one of %s, %s, %s, %s", EntityBlock, EntityTx, EntityVerifier, EntityAddress)
+	}
+	if a.EntityID == "" || len(a.EntityID) > MaxEntityIDLength {
+		return fmt.Errorf("entity_id must be 1-%d characters", MaxEntityIDLength)
+	}
+	if a.Key == "" || len(a.Key) > MaxKeyLength {
+		return fmt.Errorf("key must be 1-%d characters", MaxKeyLength)
+	}
+	if len(a.Value) > MaxValueLength {
+		return fmt.Errorf("value must be at most %d characters", MaxValueLength)
+	}
+	return nil
+}
+
+// SetAnnotation creates an annotation or replaces the value of an existing one
+func SetAnnotation(a Annotation) (*Annotation, error) {
+	annotation, err := postgres.PostgresQueryOneCtx[Annotation](
+		context.Background(), nil,
+		`INSERT INTO annotations (entity_type, entity_id, key, value, author)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (entity_type, entity_id, key) DO UPDATE SET
+			value = EXCLUDED.value,
+			author = EXCLUDED.author,
+			updated_at = CURRENT_TIMESTAMP
+		 RETURNING entity_type, entity_id, key, value, author, created_at, updated_at`,
+		a.EntityType, a.EntityID, a.Key, a.Value, a.Author,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set annotation %s on %s %s: %w", a.Key, a.EntityType, a.EntityID, err)
+	}
+
+	return annotation, nil
+}
+
+// DeleteAnnotation removes one annotation, or every annotation of the entity when key is empty
+// Returns the number of annotations removed
+func DeleteAnnotation(entityType, entityID, key string) (int64, error) {
+	tag, err := postgres.DB.Exec(context.Background(),
+		`DELETE FROM annotations
+		 WHERE entity_type = $1 AND entity_id = $2 AND ($3 = '' OR key = $3)`,
+		entityType, entityID, key,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete annotations on %s %s: %w", entityType, entityID, err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// GetAnnotations retrieves the annotations of one entity ordered by key
+func GetAnnotations(entityType, entityID string) ([]Annotation, error) {
+	annotations, err := postgres.PostgresQueryCtx[Annotation](
+		context.Background(), nil,
+		`SELECT entity_type, entity_id, key, value, author, created_at, updated_at
+		 FROM annotations
+		 WHERE entity_type = $1 AND entity_id = $2
+		 ORDER BY key`,
+		entityType, entityID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get annotations on %s %s: %w", entityType, entityID, err)
+	}
+
+	return annotations, nil
+}
+
+// GetAnnotationsByKey retrieves the annotations with a given key across entities of one type,
+// most recently updated first, with pagination
+func GetAnnotationsByKey(entityType, key string, limit, offset int) ([]Annotation, error) {
+	annotations, err := postgres.PostgresQueryCtx[Annotation](
+		context.Background(), nil,
+		`SELECT entity_type, entity_id, key, value, author, created_at, updated_at
+		 FROM annotations
+		 WHERE entity_type = $1 AND key = $2
+		 ORDER BY updated_at DESC, entity_id
+		 LIMIT $3 OFFSET $4`,
+		entityType, key, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s annotations by key %s: %w", entityType, key, err)
+	}
+
+	return annotations, nil
+}
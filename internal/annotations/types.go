@@ -0,0 +1,23 @@
+package annotations
+
+import "time"
+
+// Entity types that can be annotated
+const (
+	EntityBlock    = "block"    // entity_id is the block hash
+	EntityTx       = "tx"       // entity_id is the txid
+	EntityVerifier = "verifier" // entity_id is the verifier_id
+	EntityAddress  = "address"  // entity_id is the transparent address
+)
+
+// Annotation is a note or tag attached to an indexed entity
+// A tag is an annotation with an empty value
+type Annotation struct {
+	EntityType string    `json:"entity_type" db:"entity_type"`
+	EntityID   string    `json:"entity_id" db:"entity_id"`
+	Key        string    `json:"key" db:"key"`
+	Value      string    `json:"value" db:"value"`
+	Author     string    `json:"author" db:"author"` // usage key id of the API key that last set it
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,199 @@
+package apikeys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/usage"
+)
+
+// Limits on key fields
+const (
+	MaxNameLength = 64
+
+	// keyPrefix marks zindex API keys so they are recognisable in configs and secret scanners
+	keyPrefix = "zk_"
+	// maxCachedKeys bounds the lookup cache, which also remembers unknown keys
+	maxCachedKeys = 10000
+)
+
+func init() {
+	// Register this as a core schema (always initialized)
+	postgres.RegisterCoreSchema("api_keys", InitSchema)
+}
+
+// InitSchema creates the api_keys table
+// Only the SHA-256 of each key is stored, so a database dump does not leak usable keys
+func InitSchema() error {
+	schema := `
+		CREATE TABLE IF NOT EXISTS api_keys (
+			key_id VARCHAR(16) PRIMARY KEY,  -- usage.KeyID of the key
+			key_hash VARCHAR(64) NOT NULL UNIQUE,  -- SHA-256 of the key
+			name VARCHAR(64) NOT NULL,
+			scope VARCHAR(16) NOT NULL,  -- read or admin
+			requests_per_second DOUBLE PRECISION NOT NULL DEFAULT 0,  -- 0 = api.rate_limit applies
+			burst INT NOT NULL DEFAULT 0,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			revoked_at TIMESTAMPTZ
+		);
+	`
+
+	_, err := postgres.DB.Exec(context.Background(), schema)
+	if err != nil {
+		return fmt.Errorf("failed to create api_keys schema: %w", err)
+	}
+
+	return nil
+}
+
+// Enabled reports whether API keys are checked
+func Enabled() bool {
+	return config.Conf.Api.Auth.Enabled
+}
+
+// IsScope reports whether scope is a key scope
+func IsScope(scope string) bool {
+	return scope == ScopeRead || scope == ScopeAdmin
+}
+
+// Validate checks a key's name, scope and rate limit
+func Validate(k *Key) error {
+	if k.Name == "" || len(k.Name) > MaxNameLength {
+		return fmt.Errorf("name must be 1-%d characters", MaxNameLength)
+	}
+	if !IsScope(k.Scope) {
+		return fmt.Errorf("scope must be %s or %s", ScopeRead, ScopeAdmin)
+	}
+	if k.RequestsPerSecond < 0 || k.Burst < 0 {
+		return fmt.Errorf("requests_per_second and burst must be non-negative")
+	}
+	if (k.RequestsPerSecond > 0) != (k.Burst > 0) {
+		return fmt.Errorf("requests_per_second and burst must be set together")
+	}
+	return nil
+}
+
+// hashKey is the key_hash column of a raw API key
+func hashKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateKey stores a key with a newly generated secret, which is returned in Key.Key this one time
+func CreateKey(k Key) (*Key, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	apiKey := keyPrefix + hex.EncodeToString(secret)
+
+	created, err := postgres.PostgresQueryOneCtx[Key](
+		context.Background(), nil,
+		`INSERT INTO api_keys (key_id, key_hash, name, scope, requests_per_second, burst)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING key_id, name, scope, requests_per_second, burst, created_at, revoked_at`,
+		usage.KeyID(apiKey), hashKey(apiKey), k.Name, k.Scope, k.RequestsPerSecond, k.Burst,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API key %s: %w", k.Name, err)
+	}
+
+	created.Key = apiKey
+	return created, nil
+}
+
+// GetKeys lists keys oldest first, including revoked ones when includeRevoked is set
+func GetKeys(includeRevoked bool) ([]Key, error) {
+	keys, err := postgres.PostgresQueryCtx[Key](
+		context.Background(), nil,
+		`SELECT key_id, name, scope, requests_per_second, burst, created_at, revoked_at
+		 FROM api_keys
+		 WHERE $1 OR revoked_at IS NULL
+		 ORDER BY created_at, key_id`,
+		includeRevoked,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// RevokeKey marks a key revoked so it is rejected from now on
+// Returns false when no active key has the id
+// Other API processes keep accepting the key until their cached lookup expires (api.auth.cache_ttl)
+func RevokeKey(keyID string) (bool, error) {
+	tag, err := postgres.DB.Exec(context.Background(),
+		`UPDATE api_keys SET revoked_at = CURRENT_TIMESTAMP
+		 WHERE key_id = $1 AND revoked_at IS NULL`,
+		keyID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke API key %s: %w", keyID, err)
+	}
+
+	clearCache()
+	return tag.RowsAffected() > 0, nil
+}
+
+type cacheEntry struct {
+	key     *Key // nil for an unknown or revoked key
+	expires time.Time
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = map[string]cacheEntry{}
+)
+
+func clearCache() {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	cache = map[string]cacheEntry{}
+}
+
+// Authenticate returns the active key matching a raw API key, or nil when it is unknown or revoked
+// Lookups are cached for api.auth.cache_ttl seconds so requests do not each query the database
+func Authenticate(ctx context.Context, apiKey string) (*Key, error) {
+	hash := hashKey(apiKey)
+	now := time.Now()
+
+	cacheMu.Lock()
+	entry, ok := cache[hash]
+	cacheMu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.key, nil
+	}
+
+	key, err := postgres.PostgresQueryOneCtx[Key](
+		ctx, nil,
+		`SELECT key_id, name, scope, requests_per_second, burst, created_at, revoked_at
+		 FROM api_keys
+		 WHERE key_hash = $1 AND revoked_at IS NULL`,
+		hash,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		key, err = nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up API key: %w", err)
+	}
+
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if len(cache) >= maxCachedKeys {
+		cache = map[string]cacheEntry{}
+	}
+	cache[hash] = cacheEntry{key: key, expires: now.Add(time.Duration(config.Conf.Api.Auth.CacheTTL) * time.Second)}
+
+	return key, nil
+}
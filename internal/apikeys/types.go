@@ -0,0 +1,32 @@
+package apikeys
+
+import "time"
+
+// Key scopes; an admin key can also call every read endpoint
+const (
+	ScopeRead  = "read"
+	ScopeAdmin = "admin"
+)
+
+// Key is an API key as stored in the api_keys table
+// The key itself is only returned when it is created
+type Key struct {
+	KeyID             string     `json:"key_id" db:"key_id"` // usage key id, shared with the api_usage reports
+	Name              string     `json:"name" db:"name"`
+	Scope             string     `json:"scope" db:"scope"`
+	RequestsPerSecond float64    `json:"requests_per_second" db:"requests_per_second"` // 0 = api.rate_limit applies
+	Burst             int        `json:"burst" db:"burst"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	RevokedAt         *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	Key               string     `json:"key,omitempty" db:"-"`
+}
+
+// HasScope reports whether the key may call endpoints requiring scope
+func (k *Key) HasScope(scope string) bool {
+	return k.Scope == ScopeAdmin || k.Scope == scope
+}
+
+// HasRateLimit reports whether the key has its own rate limit instead of api.rate_limit
+func (k *Key) HasRateLimit() bool {
+	return k.RequestsPerSecond > 0
+}
@@ -5,14 +5,48 @@ import (
 	"fmt"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
 )
 
+// DBTX is an interface that both pgxpool.Pool and pgx.Tx implement
+type DBTX interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// readDB serves block lookups; nil falls back to postgres.DB
+var readDB postgres.Querier
+
+// SetReadDB routes block lookups through q, e.g. a read replica
+// pool or a mock in tests. Passing nil restores the global postgres.DB
+func SetReadDB(q postgres.Querier) {
+	readDB = q
+}
+
 func init() {
 	// Register this as a core schema (always initialized)
 	postgres.RegisterCoreSchema("blocks", InitSchema)
 }
 
+// redundantIndexes are created by default but dropped when database.drop_redundant_indexes
+// is enabled, since hash is covered by the UNIQUE constraint
+var redundantIndexes = []postgres.RedundantIndex{
+	{
+		Name:       "idx_blocks_hash",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_blocks_hash ON blocks(hash);`,
+	},
+}
+
+// secondaryIndexes serve block lookups by time (see postgres.SecondaryIndex for deferral)
+var secondaryIndexes = []postgres.SecondaryIndex{
+	{
+		Name:       "idx_blocks_timestamp",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_blocks_timestamp ON blocks(timestamp);`,
+	},
+}
+
 // InitSchema creates the blocks table and indexes
 // This is part of the core schema and is always initialized
 func InitSchema() error {
@@ -27,11 +61,24 @@ func InitSchema() error {
 			nonce VARCHAR(64),
 			version INT,
 			tx_count INT DEFAULT 0,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			total_output_zat BIGINT NOT NULL DEFAULT 0,
+			total_fees BIGINT NOT NULL DEFAULT 0,
+			tze_tx_count INT NOT NULL DEFAULT 0,
+			stark_tx_count INT NOT NULL DEFAULT 0,
+			size BIGINT NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			indexed_at TIMESTAMPTZ,  -- when every module finished indexing the block
+			rpc_source VARCHAR(255)  -- redacted RPC endpoint the block was last indexed from
 		);
 
-		CREATE INDEX IF NOT EXISTS idx_blocks_hash ON blocks(hash);
-		CREATE INDEX IF NOT EXISTS idx_blocks_timestamp ON blocks(timestamp);
+		-- Columns added after the initial schema
+		ALTER TABLE blocks ADD COLUMN IF NOT EXISTS total_output_zat BIGINT NOT NULL DEFAULT 0;
+		ALTER TABLE blocks ADD COLUMN IF NOT EXISTS total_fees BIGINT NOT NULL DEFAULT 0;
+		ALTER TABLE blocks ADD COLUMN IF NOT EXISTS tze_tx_count INT NOT NULL DEFAULT 0;
+		ALTER TABLE blocks ADD COLUMN IF NOT EXISTS stark_tx_count INT NOT NULL DEFAULT 0;
+		ALTER TABLE blocks ADD COLUMN IF NOT EXISTS size BIGINT NOT NULL DEFAULT 0;
+		ALTER TABLE blocks ADD COLUMN IF NOT EXISTS indexed_at TIMESTAMPTZ;
+		ALTER TABLE blocks ADD COLUMN IF NOT EXISTS rpc_source VARCHAR(255);
 	`
 
 	_, err := postgres.DB.Exec(context.Background(), schema)
@@ -39,20 +86,94 @@ func InitSchema() error {
 		return fmt.Errorf("failed to create blocks schema: %w", err)
 	}
 
+	if err := postgres.InitSecondaryIndexes(secondaryIndexes); err != nil {
+		return fmt.Errorf("failed to init blocks indexes: %w", err)
+	}
+
+	if err := postgres.InitRedundantIndexes(redundantIndexes); err != nil {
+		return fmt.Errorf("failed to init blocks redundant indexes: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateBlockTotalFees sets total_fees on a block from the fees of its indexed transactions
+// Called by the tx_graph module, which owns transaction fee data, inside its block transaction
+func UpdateBlockTotalFees(postgresTx DBTX, height int64) error {
+	if postgresTx == nil {
+		postgresTx = postgres.DB
+	}
+
+	_, err := postgresTx.Exec(context.Background(),
+		`UPDATE blocks
+		 SET total_fees = (SELECT COALESCE(SUM(total_fee), 0) FROM transactions WHERE block_height = $1)
+		 WHERE height = $1`,
+		height,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update total fees for block %d: %w", height, err)
+	}
+
+	return nil
+}
+
+// UpdateBlockTotalFeesRange recomputes total_fees for every block between fromHeight and toHeight
+// Returns the number of blocks whose total changed
+func UpdateBlockTotalFeesRange(postgresTx DBTX, fromHeight, toHeight int64) (int64, error) {
+	if postgresTx == nil {
+		postgresTx = postgres.DB
+	}
+
+	tag, err := postgresTx.Exec(context.Background(),
+		`UPDATE blocks b
+		 SET total_fees = f.fees
+		 FROM (
+			SELECT block_height, COALESCE(SUM(total_fee), 0) AS fees
+			FROM transactions
+			WHERE block_height BETWEEN $1 AND $2
+			GROUP BY block_height
+		 ) f
+		 WHERE b.height = f.block_height AND b.total_fees <> f.fees`,
+		fromHeight, toHeight,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update total fees for blocks %d-%d: %w", fromHeight, toHeight, err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// MarkBlockIndexed records when a block finished indexing in every module and the RPC endpoint it came from
+// Re-indexing a stored block keeps the first time, so latency reflects when the block first became queryable,
+// but replaces the source, since the stored rows now come from that endpoint
+// It runs last in the block's transaction, so clock_timestamp() rather than the transaction start is used
+func MarkBlockIndexed(postgresTx DBTX, height int64, rpcSource string) error {
+	_, err := postgresTx.Exec(context.Background(),
+		`UPDATE blocks
+		 SET indexed_at = COALESCE(indexed_at, clock_timestamp()), rpc_source = NULLIF($2, '')
+		 WHERE height = $1`,
+		height, rpcSource,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to mark block %d indexed: %w", height, err)
+	}
+
 	return nil
 }
 
 // StoreBlock inserts or updates a block in the database
-func StoreBlock(height int64, hash string, prevHash string, merkleRoot string, timestamp int64, difficulty float64, nonce string, version int, txCount int) error {
+// If postgresTx is provided, it will be used; otherwise a standalone query is executed
+func StoreBlock(postgresTx DBTX, height int64, hash string, prevHash string, merkleRoot string, timestamp int64, difficulty float64, nonce string, version int, size int64, txCount int, totalOutputZat int64, tzeTxCount int, starkTxCount int) error {
 	ctx := context.Background()
 
 	// Convert difficulty to string for storage
 	difficultyStr := fmt.Sprintf("%f", difficulty)
 
 	query := `
-		INSERT INTO blocks (height, hash, prev_hash, merkle_root, timestamp, difficulty, nonce, version, tx_count)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-		ON CONFLICT (height) DO UPDATE SET
+		INSERT INTO blocks (height, hash, prev_hash, merkle_root, timestamp, difficulty, nonce, version, tx_count,
+		                    total_output_zat, tze_tx_count, stark_tx_count, size)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (height) ` + postgres.OnConflictImmutable(`
 			hash = EXCLUDED.hash,
 			prev_hash = EXCLUDED.prev_hash,
 			merkle_root = EXCLUDED.merkle_root,
@@ -60,10 +181,19 @@ func StoreBlock(height int64, hash string, prevHash string, merkleRoot string, t
 			difficulty = EXCLUDED.difficulty,
 			nonce = EXCLUDED.nonce,
 			version = EXCLUDED.version,
-			tx_count = EXCLUDED.tx_count
-	`
+			tx_count = EXCLUDED.tx_count,
+			total_output_zat = EXCLUDED.total_output_zat,
+			tze_tx_count = EXCLUDED.tze_tx_count,
+			stark_tx_count = EXCLUDED.stark_tx_count,
+			size = EXCLUDED.size
+	`)
+
+	if postgresTx == nil {
+		postgresTx = postgres.DB
+	}
 
-	_, err := postgres.DB.Exec(ctx, query, height, hash, prevHash, merkleRoot, timestamp, difficultyStr, nonce, version, txCount)
+	_, err := postgresTx.Exec(ctx, query, height, hash, prevHash, merkleRoot, timestamp, difficultyStr, nonce, version, txCount,
+		totalOutputZat, tzeTxCount, starkTxCount, size)
 	if err != nil {
 		return fmt.Errorf("failed to store block %d: %w", height, err)
 	}
@@ -73,8 +203,11 @@ func StoreBlock(height int64, hash string, prevHash string, merkleRoot string, t
 
 // GetBlock retrieves a block by its height
 func GetBlock(height int64) (*Block, error) {
-	block, err := postgres.PostgresQueryOne[Block](
-		`SELECT height, hash, prev_hash, merkle_root, timestamp, difficulty, nonce, version, tx_count, created_at
+	block, err := postgres.PostgresQueryOneCtx[Block](
+		context.Background(), readDB,
+		`SELECT height, hash, prev_hash, merkle_root, timestamp, difficulty, nonce, version, tx_count,
+		        total_output_zat, total_fees, tze_tx_count, stark_tx_count, size, created_at,
+		        indexed_at, EXTRACT(EPOCH FROM indexed_at)::DOUBLE PRECISION - timestamp AS index_latency, rpc_source
 		 FROM blocks WHERE height = $1`,
 		height,
 	)
@@ -86,13 +219,20 @@ func GetBlock(height int64) (*Block, error) {
 		return nil, fmt.Errorf("failed to get block: %w", err)
 	}
 
+	if err := markBlockFinal(block); err != nil {
+		return nil, err
+	}
+
 	return block, nil
 }
 
 // GetBlockByHash retrieves a block by its hash
 func GetBlockByHash(hash string) (*Block, error) {
-	block, err := postgres.PostgresQueryOne[Block](
-		`SELECT height, hash, prev_hash, merkle_root, timestamp, difficulty, nonce, version, tx_count, created_at
+	block, err := postgres.PostgresQueryOneCtx[Block](
+		context.Background(), readDB,
+		`SELECT height, hash, prev_hash, merkle_root, timestamp, difficulty, nonce, version, tx_count,
+		        total_output_zat, total_fees, tze_tx_count, stark_tx_count, size, created_at,
+		        indexed_at, EXTRACT(EPOCH FROM indexed_at)::DOUBLE PRECISION - timestamp AS index_latency, rpc_source
 		 FROM blocks WHERE hash = $1`,
 		hash,
 	)
@@ -104,13 +244,20 @@ func GetBlockByHash(hash string) (*Block, error) {
 		return nil, fmt.Errorf("failed to get block by hash: %w", err)
 	}
 
+	if err := markBlockFinal(block); err != nil {
+		return nil, err
+	}
+
 	return block, nil
 }
 
 // GetBlocks retrieves blocks with pagination
 func GetBlocks(limit, offset int) ([]Block, error) {
-	blocks, err := postgres.PostgresQuery[Block](
-		`SELECT height, hash, prev_hash, merkle_root, timestamp, difficulty, nonce, version, tx_count, created_at
+	blocks, err := postgres.PostgresQueryCtx[Block](
+		context.Background(), readDB,
+		`SELECT height, hash, prev_hash, merkle_root, timestamp, difficulty, nonce, version, tx_count,
+		        total_output_zat, total_fees, tze_tx_count, stark_tx_count, size, created_at,
+		        indexed_at, EXTRACT(EPOCH FROM indexed_at)::DOUBLE PRECISION - timestamp AS index_latency, rpc_source
 		 FROM blocks
 		 ORDER BY height DESC
 		 LIMIT $1 OFFSET $2`,
@@ -120,13 +267,20 @@ func GetBlocks(limit, offset int) ([]Block, error) {
 		return nil, fmt.Errorf("failed to get blocks: %w", err)
 	}
 
+	if err := markBlocksFinal(blocks); err != nil {
+		return nil, err
+	}
+
 	return blocks, nil
 }
 
 // GetBlocksByRange retrieves blocks within a height range
 func GetBlocksByRange(fromHeight, toHeight int64, limit, offset int) ([]Block, error) {
-	blocks, err := postgres.PostgresQuery[Block](
-		`SELECT height, hash, prev_hash, merkle_root, timestamp, difficulty, nonce, version, tx_count, created_at
+	blocks, err := postgres.PostgresQueryCtx[Block](
+		context.Background(), readDB,
+		`SELECT height, hash, prev_hash, merkle_root, timestamp, difficulty, nonce, version, tx_count,
+		        total_output_zat, total_fees, tze_tx_count, stark_tx_count, size, created_at,
+		        indexed_at, EXTRACT(EPOCH FROM indexed_at)::DOUBLE PRECISION - timestamp AS index_latency, rpc_source
 		 FROM blocks
 		 WHERE height >= $1 AND height <= $2
 		 ORDER BY height DESC
@@ -137,13 +291,20 @@ func GetBlocksByRange(fromHeight, toHeight int64, limit, offset int) ([]Block, e
 		return nil, fmt.Errorf("failed to get blocks by range: %w", err)
 	}
 
+	if err := markBlocksFinal(blocks); err != nil {
+		return nil, err
+	}
+
 	return blocks, nil
 }
 
 // GetBlocksByTimestampRange retrieves blocks within a timestamp range
 func GetBlocksByTimestampRange(fromTimestamp, toTimestamp int64, limit, offset int) ([]Block, error) {
-	blocks, err := postgres.PostgresQuery[Block](
-		`SELECT height, hash, prev_hash, merkle_root, timestamp, difficulty, nonce, version, tx_count, created_at
+	blocks, err := postgres.PostgresQueryCtx[Block](
+		context.Background(), readDB,
+		`SELECT height, hash, prev_hash, merkle_root, timestamp, difficulty, nonce, version, tx_count,
+		        total_output_zat, total_fees, tze_tx_count, stark_tx_count, size, created_at,
+		        indexed_at, EXTRACT(EPOCH FROM indexed_at)::DOUBLE PRECISION - timestamp AS index_latency, rpc_source
 		 FROM blocks
 		 WHERE timestamp >= $1 AND timestamp <= $2
 		 ORDER BY timestamp DESC
@@ -154,13 +315,20 @@ func GetBlocksByTimestampRange(fromTimestamp, toTimestamp int64, limit, offset i
 		return nil, fmt.Errorf("failed to get blocks by timestamp range: %w", err)
 	}
 
+	if err := markBlocksFinal(blocks); err != nil {
+		return nil, err
+	}
+
 	return blocks, nil
 }
 
 // GetRecentBlocks retrieves the most recent blocks
 func GetRecentBlocks(limit int) ([]Block, error) {
-	blocks, err := postgres.PostgresQuery[Block](
-		`SELECT height, hash, prev_hash, merkle_root, timestamp, difficulty, nonce, version, tx_count, created_at
+	blocks, err := postgres.PostgresQueryCtx[Block](
+		context.Background(), readDB,
+		`SELECT height, hash, prev_hash, merkle_root, timestamp, difficulty, nonce, version, tx_count,
+		        total_output_zat, total_fees, tze_tx_count, stark_tx_count, size, created_at,
+		        indexed_at, EXTRACT(EPOCH FROM indexed_at)::DOUBLE PRECISION - timestamp AS index_latency, rpc_source
 		 FROM blocks
 		 ORDER BY height DESC
 		 LIMIT $1`,
@@ -170,40 +338,97 @@ func GetRecentBlocks(limit int) ([]Block, error) {
 		return nil, fmt.Errorf("failed to get recent blocks: %w", err)
 	}
 
+	if err := markBlocksFinal(blocks); err != nil {
+		return nil, err
+	}
+
 	return blocks, nil
 }
 
 // GetBlockCount returns the total number of blocks
 func GetBlockCount() (int64, error) {
-	type result struct {
-		Count int64 `db:"count"`
-	}
+	return postgres.CountRows(context.Background(), readDB, "blocks")
+}
 
-	res, err := postgres.PostgresQueryOne[result](
-		`SELECT COUNT(*) as count FROM blocks`,
+// GetLatestBlock retrieves the most recent block
+func GetLatestBlock() (*Block, error) {
+	block, err := postgres.PostgresQueryOneCtx[Block](
+		context.Background(), readDB,
+		`SELECT height, hash, prev_hash, merkle_root, timestamp, difficulty, nonce, version, tx_count,
+		        total_output_zat, total_fees, tze_tx_count, stark_tx_count, size, created_at,
+		        indexed_at, EXTRACT(EPOCH FROM indexed_at)::DOUBLE PRECISION - timestamp AS index_latency, rpc_source
+		 FROM blocks
+		 ORDER BY height DESC
+		 LIMIT 1`,
 	)
+
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
 	if err != nil {
-		return 0, fmt.Errorf("failed to get block count: %w", err)
+		return nil, fmt.Errorf("failed to get latest block: %w", err)
+	}
+
+	if err := markBlockFinal(block); err != nil {
+		return nil, err
 	}
 
-	return res.Count, nil
+	return block, nil
 }
 
-// GetLatestBlock retrieves the most recent block
-func GetLatestBlock() (*Block, error) {
-	block, err := postgres.PostgresQueryOne[Block](
-		`SELECT height, hash, prev_hash, merkle_root, timestamp, difficulty, nonce, version, tx_count, created_at
+// GetLatestFinalizedBlock retrieves the highest indexed block that has reached the configured finality depth
+func GetLatestFinalizedBlock() (*Block, error) {
+	finalizedHeight, err := postgres.GetFinalizedHeight()
+	if err != nil {
+		return nil, err
+	}
+	if finalizedHeight < 0 {
+		return nil, nil
+	}
+
+	block, err := postgres.PostgresQueryOneCtx[Block](
+		context.Background(), readDB,
+		`SELECT height, hash, prev_hash, merkle_root, timestamp, difficulty, nonce, version, tx_count,
+		        total_output_zat, total_fees, tze_tx_count, stark_tx_count, size, created_at,
+		        indexed_at, EXTRACT(EPOCH FROM indexed_at)::DOUBLE PRECISION - timestamp AS index_latency, rpc_source
 		 FROM blocks
+		 WHERE height <= $1
 		 ORDER BY height DESC
 		 LIMIT 1`,
+		finalizedHeight,
 	)
 
 	if err == pgx.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get latest block: %w", err)
+		return nil, fmt.Errorf("failed to get latest finalized block: %w", err)
 	}
 
+	block.Final = true
 	return block, nil
 }
+
+// markBlocksFinal sets the Final flag on blocks at or below the finalized height
+func markBlocksFinal(blocks []Block) error {
+	finalizedHeight, err := postgres.GetFinalizedHeight()
+	if err != nil {
+		return err
+	}
+
+	for i := range blocks {
+		blocks[i].Final = blocks[i].Height <= finalizedHeight
+	}
+	return nil
+}
+
+// markBlockFinal sets the Final flag on a single block
+func markBlockFinal(block *Block) error {
+	finalizedHeight, err := postgres.GetFinalizedHeight()
+	if err != nil {
+		return err
+	}
+
+	block.Final = block.Height <= finalizedHeight
+	return nil
+}
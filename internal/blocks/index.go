@@ -1,36 +1,80 @@
 package blocks
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"strconv"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/logging"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/types"
 )
 
 // IndexBlocks indexes core block data
 // This function stores essential block information and is always executed (core module)
-func IndexBlocks(block *types.ZcashBlock) error {
+// The block row is written through postgresTx, the block's transaction shared by every module
+func IndexBlocks(postgresTx pgx.Tx, block *types.ZcashBlock) error {
 	// Note: Blocks module is a core module and is always enabled
 	// No need to check if it's enabled in config
 
-	log.Printf("Indexing block data %d (hash: %s)", block.Height, block.Hash)
-
-	// Store the block using the existing storage function
-	err := StoreBlock(
-		block.Height,
-		block.Hash,
-		block.PreviousBlockHash,
-		block.MerkleRoot,
-		block.Time,
-		block.Difficulty,
-		block.Nonce,
-		block.Version,
-		len(block.Tx),
-	)
+	logging.Blockf(logging.ModuleBlocks, "Indexing block data %d (hash: %s)", block.Height, block.Hash)
+
+	totalOutputZat, tzeTxCount, starkTxCount := summarizeTransactions(block)
+
+	ctx := context.Background()
+
+	// Store the block and count it in the blocks row counter
+	scopes := []postgres.RowScope{{Table: "blocks", Column: "height", Keys: []int64{block.Height}}}
+	err := postgres.TrackRowCounts(ctx, postgresTx, scopes, func() error {
+		return StoreBlock(
+			postgresTx,
+			block.Height,
+			block.Hash,
+			block.PreviousBlockHash,
+			block.MerkleRoot,
+			block.Time,
+			block.Difficulty,
+			block.Nonce,
+			block.Version,
+			block.Size,
+			len(block.Tx),
+			totalOutputZat,
+			tzeTxCount,
+			starkTxCount,
+		)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to store block %d: %w", block.Height, err)
 	}
+	postgres.QueueManifestKeys(postgresTx, "BLOCKS", "blocks", "insert", strconv.FormatInt(block.Height, 10))
 
-	log.Printf("Successfully indexed block data %d", block.Height)
+	logging.Blockf(logging.ModuleBlocks, "Successfully indexed block data %d", block.Height)
 	return nil
 }
+
+// summarizeTransactions computes the per-block transaction summaries stored on the blocks table:
+// the total transparent output value in zatoshis, the number of TZE transactions and
+// the number of stark_verify TZE transactions
+func summarizeTransactions(block *types.ZcashBlock) (int64, int, int) {
+	totalOutputZat := int64(0)
+	tzeTxCount := 0
+	starkTxCount := 0
+
+	for _, tx := range block.Tx {
+		for _, vout := range tx.Vout {
+			totalOutputZat += vout.ValueZat
+		}
+
+		extensionID, ok := tx.TZEExtensionID()
+		if !ok {
+			continue
+		}
+		tzeTxCount++
+		if extensionID == 1 { // stark_verify
+			starkTxCount++
+		}
+	}
+
+	return totalOutputZat, tzeTxCount, starkTxCount
+}
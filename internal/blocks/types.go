@@ -4,14 +4,23 @@ import "time"
 
 // Block represents a block in the blockchain
 type Block struct {
-	Height     int64     `db:"height" json:"height"`
-	Hash       string    `db:"hash" json:"hash"`
-	PrevHash   string    `db:"prev_hash" json:"prev_hash"`
-	MerkleRoot string    `db:"merkle_root" json:"merkle_root"`
-	Timestamp  int64     `db:"timestamp" json:"timestamp"`
-	Difficulty string    `db:"difficulty" json:"difficulty"`
-	Nonce      string    `db:"nonce" json:"nonce"`
-	Version    int       `db:"version" json:"version"`
-	TxCount    int       `db:"tx_count" json:"tx_count"`
-	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+	Height         int64      `db:"height" json:"height"`
+	Hash           string     `db:"hash" json:"hash"`
+	PrevHash       string     `db:"prev_hash" json:"prev_hash"`
+	MerkleRoot     string     `db:"merkle_root" json:"merkle_root"`
+	Timestamp      int64      `db:"timestamp" json:"timestamp"`
+	Difficulty     string     `db:"difficulty" json:"difficulty"`
+	Nonce          string     `db:"nonce" json:"nonce"`
+	Version        int        `db:"version" json:"version"`
+	TxCount        int        `db:"tx_count" json:"tx_count"`
+	TotalOutputZat int64      `db:"total_output_zat" json:"total_output_zat"` // transparent outputs, in zatoshis
+	TotalFees      int64      `db:"total_fees" json:"total_fees"`             // maintained by tx_graph
+	TzeTxCount     int        `db:"tze_tx_count" json:"tze_tx_count"`
+	StarkTxCount   int        `db:"stark_tx_count" json:"stark_tx_count"`
+	Size           int64      `db:"size" json:"size"` // serialized block size in bytes
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+	IndexedAt      *time.Time `db:"indexed_at" json:"indexed_at"`       // null for blocks indexed before it was recorded
+	IndexLatency   *float64   `db:"index_latency" json:"index_latency"` // seconds from the block timestamp to indexed_at
+	RpcSource      *string    `db:"rpc_source" json:"rpc_source"`       // RPC endpoint the block was indexed from, credentials removed
+	Final          bool       `db:"-" json:"final"`
 }
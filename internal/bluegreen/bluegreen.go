@@ -0,0 +1,188 @@
+package bluegreen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+)
+
+// ErrNotIndexed is returned when activating a schema that holds no zindex tables
+var ErrNotIndexed = errors.New("schema has no zindex tables (indexer_state is missing)")
+
+func init() {
+	// Register this as a core schema (always initialized)
+	postgres.RegisterCoreSchema("blue_green", InitSchema)
+}
+
+// InitSchema creates public.zindex_active_schema, the one row naming the schema that serves the API
+// It is kept in public rather than database.schema so the blue and green deployments read the same row
+func InitSchema() error {
+	if !Enabled() {
+		return nil
+	}
+
+	schema := `
+		CREATE TABLE IF NOT EXISTS public.zindex_active_schema (
+			id INT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+			schema_name VARCHAR(63) NOT NULL,
+			switched_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+	`
+
+	_, err := postgres.DB.Exec(context.Background(), schema)
+	if err != nil {
+		return fmt.Errorf("failed to create zindex_active_schema: %w", err)
+	}
+
+	return nil
+}
+
+// Enabled reports whether the API is gated on the active schema
+func Enabled() bool {
+	return config.Conf.Api.BlueGreen.Enabled
+}
+
+var (
+	mu         sync.RWMutex
+	active     string
+	switchedAt time.Time
+	checkedAt  time.Time
+)
+
+// Serving reports whether this deployment serves the API: always without api.blue_green, otherwise
+// while database.schema is the active schema
+func Serving() bool {
+	if !Enabled() {
+		return true
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	return active == postgres.Schema()
+}
+
+// GetStatus returns the blue/green state of this deployment as of the last poll
+func GetStatus() Status {
+	status := Status{Schema: postgres.Schema(), Serving: Serving()}
+	if !Enabled() {
+		status.ActiveSchema = status.Schema
+		return status
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+	status.ActiveSchema = active
+	if !switchedAt.IsZero() {
+		at := switchedAt
+		status.SwitchedAt = &at
+	}
+	if !checkedAt.IsZero() {
+		at := checkedAt
+		status.CheckedAt = &at
+	}
+	return status
+}
+
+// Start claims the active schema for this deployment when none is set yet, so the first deployment
+// to enable blue/green keeps serving, then re-reads it every api.blue_green.poll_interval seconds
+func Start() error {
+	if !Enabled() {
+		return nil
+	}
+
+	ctx := context.Background()
+	_, err := postgres.DB.Exec(ctx,
+		`INSERT INTO public.zindex_active_schema (id, schema_name) VALUES (1, $1)
+		 ON CONFLICT (id) DO NOTHING`,
+		postgres.Schema(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to claim the active schema: %w", err)
+	}
+	if err := Refresh(ctx); err != nil {
+		return err
+	}
+
+	status := GetStatus()
+	if status.Serving {
+		log.Printf("Blue/green: schema %s is active, serving the API", status.Schema)
+	} else {
+		log.Printf("Blue/green: schema %s is active, schema %s is on standby", status.ActiveSchema, status.Schema)
+	}
+
+	interval := time.Duration(config.Conf.Api.BlueGreen.PollInterval) * time.Second
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := Refresh(context.Background()); err != nil {
+				log.Printf("%v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// Refresh reads the active schema, logging when this deployment starts or stops serving
+func Refresh(ctx context.Context) error {
+	var schema string
+	var at time.Time
+	err := postgres.DB.QueryRow(ctx,
+		`SELECT schema_name, switched_at FROM public.zindex_active_schema WHERE id = 1`,
+	).Scan(&schema, &at)
+	if err != nil {
+		return fmt.Errorf("failed to read the active schema: %w", err)
+	}
+
+	own := postgres.Schema()
+	mu.Lock()
+	defer mu.Unlock()
+	if active != "" && active != schema {
+		switch own {
+		case schema:
+			log.Printf("Blue/green: switched to schema %s, serving the API", own)
+		case active:
+			log.Printf("Blue/green: switched to schema %s, schema %s is on standby", schema, own)
+		}
+	}
+	active = schema
+	switchedAt = at
+	checkedAt = time.Now()
+	return nil
+}
+
+// Activate makes schema the active schema, so its deployment serves the API and the others stand by
+// from their next poll. The schema must hold zindex tables
+func Activate(ctx context.Context, schema string) error {
+	if !config.SchemaNamePattern.MatchString(schema) {
+		return fmt.Errorf("invalid schema name %q", schema)
+	}
+
+	var indexed bool
+	err := postgres.DB.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`,
+		pgx.Identifier{schema, "indexer_state"}.Sanitize(),
+	).Scan(&indexed)
+	if err != nil {
+		return fmt.Errorf("failed to check schema %s: %w", schema, err)
+	}
+	if !indexed {
+		return ErrNotIndexed
+	}
+
+	_, err = postgres.DB.Exec(ctx,
+		`INSERT INTO public.zindex_active_schema (id, schema_name) VALUES (1, $1)
+		 ON CONFLICT (id) DO UPDATE SET schema_name = EXCLUDED.schema_name, switched_at = CURRENT_TIMESTAMP`,
+		schema,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to activate schema %s: %w", schema, err)
+	}
+
+	return Refresh(ctx)
+}
@@ -0,0 +1,216 @@
+package bluegreen
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+)
+
+// CopyFrom fills the tables of database.schema with the rows of the same tables in source, so a new
+// version can start from the live index instead of indexing the chain again
+// Tables are emptied first and copied in one repeatable read transaction, parents before the tables
+// referencing them, so every table is read from the same snapshot of source while it is being indexed.
+// Columns are matched by name: columns only in the new schema keep their defaults, for the new
+// version's backfill or re-index to fill, and columns only in source are dropped
+func CopyFrom(ctx context.Context, source string) ([]TableCopy, error) {
+	target := postgres.Schema()
+	if !config.SchemaNamePattern.MatchString(source) {
+		return nil, fmt.Errorf("invalid schema name %q", source)
+	}
+	if source == target {
+		return nil, fmt.Errorf("source schema %s is database.schema itself", source)
+	}
+
+	tables, err := copyOrder(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	postgresTx, err := postgres.BeginTxWithTimeout(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead}, postgres.QueryAdminMaintenance)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin copy transaction: %w", err)
+	}
+	defer postgresTx.Rollback(ctx)
+
+	qualified := make([]string, len(tables))
+	for i, table := range tables {
+		qualified[i] = pgx.Identifier{target, table}.Sanitize()
+	}
+	if len(qualified) > 0 {
+		if _, err := postgresTx.Exec(ctx, "TRUNCATE "+strings.Join(qualified, ", ")); err != nil {
+			return nil, fmt.Errorf("failed to empty the tables of schema %s: %w", target, err)
+		}
+	}
+
+	copies := make([]TableCopy, 0, len(tables))
+	for _, table := range tables {
+		copied, err := copyTable(ctx, postgresTx, source, target, table)
+		if err != nil {
+			return nil, err
+		}
+		copies = append(copies, copied)
+		if copied.Skipped {
+			log.Printf("Skipped %s: not in schema %s", table, source)
+		} else {
+			log.Printf("Copied %d rows of %s", copied.Rows, table)
+		}
+	}
+
+	if err := postgresTx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit copy from schema %s: %w", source, err)
+	}
+
+	return copies, nil
+}
+
+// copyTable copies the columns table has in both schemas and moves its serial sequences past the copied ids
+func copyTable(ctx context.Context, postgresTx pgx.Tx, source, target, table string) (TableCopy, error) {
+	copied := TableCopy{Table: table}
+
+	targetColumns, err := tableColumns(ctx, postgresTx, target, table)
+	if err != nil {
+		return copied, err
+	}
+	sourceColumns, err := tableColumns(ctx, postgresTx, source, table)
+	if err != nil {
+		return copied, err
+	}
+	if len(sourceColumns) == 0 {
+		copied.Skipped = true
+		return copied, nil
+	}
+	inSource := make(map[string]bool, len(sourceColumns))
+	for _, column := range sourceColumns {
+		inSource[column.name] = true
+	}
+
+	var columns, serials []string
+	for _, column := range targetColumns {
+		if !inSource[column.name] {
+			copied.NewColumns = append(copied.NewColumns, column.name)
+			continue
+		}
+		columns = append(columns, pgx.Identifier{column.name}.Sanitize())
+		if column.serial {
+			serials = append(serials, column.name)
+		}
+	}
+	if len(columns) == 0 {
+		return copied, nil
+	}
+
+	list := strings.Join(columns, ", ")
+	tag, err := postgresTx.Exec(ctx, fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s",
+		pgx.Identifier{target, table}.Sanitize(), list, list, pgx.Identifier{source, table}.Sanitize()))
+	if err != nil {
+		return copied, fmt.Errorf("failed to copy %s from schema %s: %w", table, source, err)
+	}
+	copied.Rows = tag.RowsAffected()
+
+	for _, column := range serials {
+		_, err := postgresTx.Exec(ctx, fmt.Sprintf(
+			"SELECT setval(pg_get_serial_sequence($1, $2), COALESCE(MAX(%s), 0) + 1, false) FROM %s",
+			pgx.Identifier{column}.Sanitize(), pgx.Identifier{target, table}.Sanitize()),
+			pgx.Identifier{target, table}.Sanitize(), column,
+		)
+		if err != nil {
+			return copied, fmt.Errorf("failed to advance the sequence of %s.%s: %w", table, column, err)
+		}
+	}
+
+	return copied, nil
+}
+
+type tableColumn struct {
+	name   string
+	serial bool // filled from a sequence, e.g. BIGSERIAL
+}
+
+// tableColumns lists the writable columns of a table in order; empty when the table does not exist
+func tableColumns(ctx context.Context, postgresTx pgx.Tx, schema, table string) ([]tableColumn, error) {
+	rows, err := postgresTx.Query(ctx, `
+		SELECT column_name, COALESCE(column_default LIKE 'nextval(%', false)
+		FROM information_schema.columns
+		WHERE table_schema = $1 AND table_name = $2 AND is_generated = 'NEVER'
+		ORDER BY ordinal_position
+	`, schema, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the columns of %s.%s: %w", schema, table, err)
+	}
+	defer rows.Close()
+
+	var columns []tableColumn
+	for rows.Next() {
+		var column tableColumn
+		if err := rows.Scan(&column.name, &column.serial); err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
+
+// copyOrder lists the tables of schema with every table after the tables its foreign keys reference
+func copyOrder(ctx context.Context, schema string) ([]string, error) {
+	rows, err := postgres.DB.Query(ctx, `
+		SELECT table_name FROM information_schema.tables
+		WHERE table_schema = $1 AND table_type = 'BASE TABLE' AND table_name <> 'zindex_active_schema'
+		ORDER BY table_name
+	`, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the tables of schema %s: %w", schema, err)
+	}
+	tables, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the tables of schema %s: %w", schema, err)
+	}
+
+	rows, err = postgres.DB.Query(ctx, `
+		SELECT child.relname, parent.relname
+		FROM pg_constraint c
+		JOIN pg_class child ON child.oid = c.conrelid
+		JOIN pg_class parent ON parent.oid = c.confrelid
+		JOIN pg_namespace n ON n.oid = child.relnamespace
+		WHERE c.contype = 'f' AND n.nspname = $1
+		  AND parent.relnamespace = child.relnamespace AND child.oid <> parent.oid
+	`, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the foreign keys of schema %s: %w", schema, err)
+	}
+	parents := make(map[string][]string)
+	for rows.Next() {
+		var child, parent string
+		if err := rows.Scan(&child, &parent); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		parents[child] = append(parents[child], parent)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ordered := make([]string, 0, len(tables))
+	visited := make(map[string]bool)
+	var visit func(table string)
+	visit = func(table string) {
+		if visited[table] {
+			return
+		}
+		visited[table] = true
+		for _, parent := range parents[table] {
+			visit(parent)
+		}
+		ordered = append(ordered, table)
+	}
+	for _, table := range tables {
+		visit(table)
+	}
+	return ordered, nil
+}
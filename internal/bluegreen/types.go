@@ -0,0 +1,22 @@
+package bluegreen
+
+import "time"
+
+// Status is the blue/green state of this deployment
+type Status struct {
+	Schema       string     `json:"schema"`        // database.schema of this deployment
+	ActiveSchema string     `json:"active_schema"` // schema whose deployment serves the API
+	Serving      bool       `json:"serving"`
+	SwitchedAt   *time.Time `json:"switched_at,omitempty"`
+	CheckedAt    *time.Time `json:"checked_at,omitempty"` // last successful read of the active schema
+}
+
+// TableCopy is the outcome of copying one table from the source schema
+type TableCopy struct {
+	Table string `json:"table"`
+	Rows  int64  `json:"rows"`
+	// NewColumns exist only in the target schema and are left at their defaults
+	NewColumns []string `json:"new_columns,omitempty"`
+	// Skipped is set for tables the source schema does not have, left empty for the new version to fill
+	Skipped bool `json:"skipped,omitempty"`
+}
@@ -0,0 +1,85 @@
+package chainparams
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/provider"
+)
+
+var (
+	mu      sync.Mutex
+	current *Params
+)
+
+// ForNetwork returns the default consensus parameters of a network, as in zcashd's chainparams
+// The lockbox is funded from NU6 activation until the end of the lockbox funding streams
+// Regtest has no slow start and activates Blossom and NU6 only when the node is started with -nuparams
+func ForNetwork(network string) (*Params, error) {
+	switch network {
+	case NetworkMain:
+		blossom, lockboxStart, lockboxEnd := int64(653600), int64(2726400), int64(4406400)
+		return &Params{Network: network, SlowStartInterval: 20000, PreBlossomHalvingInterval: 840000, BlossomHeight: &blossom,
+			LockboxStartHeight: &lockboxStart, LockboxEndHeight: &lockboxEnd}, nil
+	case NetworkTest:
+		blossom, lockboxStart, lockboxEnd := int64(584000), int64(2976000), int64(3396000)
+		return &Params{Network: network, SlowStartInterval: 20000, PreBlossomHalvingInterval: 840000, BlossomHeight: &blossom,
+			LockboxStartHeight: &lockboxStart, LockboxEndHeight: &lockboxEnd}, nil
+	case NetworkRegtest:
+		return &Params{Network: network, SlowStartInterval: 0, PreBlossomHalvingInterval: 144}, nil
+	default:
+		return nil, fmt.Errorf("unknown network %q, set chain.network to main, test or regtest", network)
+	}
+}
+
+// Get returns the consensus parameters of the indexed network with the chain overrides applied
+// Without chain.network the network is asked from the node on first use; a failed lookup is
+// retried on the next call
+func Get() (*Params, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if current != nil {
+		return current, nil
+	}
+
+	chain := config.Conf.Chain
+	network := chain.Network
+	if network == "" {
+		var err error
+		network, err = provider.GetChain()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the network from the node: %w", err)
+		}
+	}
+
+	params, err := ForNetwork(network)
+	if err != nil {
+		return nil, err
+	}
+	if chain.SlowStartInterval != nil {
+		params.SlowStartInterval = *chain.SlowStartInterval
+	}
+	if chain.PreBlossomHalvingInterval != nil {
+		params.PreBlossomHalvingInterval = *chain.PreBlossomHalvingInterval
+	}
+	if chain.BlossomHeight != nil {
+		blossom := *chain.BlossomHeight
+		params.BlossomHeight = &blossom
+	}
+	if chain.LockboxStartHeight != nil {
+		lockboxStart := *chain.LockboxStartHeight
+		params.LockboxStartHeight = &lockboxStart
+	}
+	if chain.LockboxEndHeight != nil {
+		lockboxEnd := *chain.LockboxEndHeight
+		params.LockboxEndHeight = &lockboxEnd
+	}
+
+	log.Printf("Using %s chain parameters (slow start %d, pre-Blossom halving interval %d)",
+		params.Network, params.SlowStartInterval, params.PreBlossomHalvingInterval)
+	current = params
+	return current, nil
+}
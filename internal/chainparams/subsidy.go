@@ -0,0 +1,86 @@
+package chainparams
+
+// SlowStartShift is the height the subsidy would have started at without the slow start ramp
+func (p *Params) SlowStartShift() int64 {
+	return p.SlowStartInterval / 2
+}
+
+// PostBlossomHalvingInterval is the halving interval in blocks once Blossom is active
+func (p *Params) PostBlossomHalvingInterval() int64 {
+	return p.PreBlossomHalvingInterval * BlossomPoWTargetSpacingRatio
+}
+
+// BlossomActive reports whether Blossom is active at height
+func (p *Params) BlossomActive(height int64) bool {
+	return p.BlossomHeight != nil && height >= *p.BlossomHeight
+}
+
+// TargetSpacing is the target block time in seconds at height
+func (p *Params) TargetSpacing(height int64) int64 {
+	if p.BlossomActive(height) {
+		return PostBlossomTargetSpacing
+	}
+	return PreBlossomTargetSpacing
+}
+
+// Halving returns the number of halvings that apply at height (ZIP 208)
+// After Blossom the pre-Blossom part of the chain counts at the post-Blossom rate, so the
+// halving count is computed in units of the post-Blossom interval
+func (p *Params) Halving(height int64) int64 {
+	if !p.BlossomActive(height) {
+		return (height - p.SlowStartShift()) / p.PreBlossomHalvingInterval
+	}
+	blossom := *p.BlossomHeight
+	scaledHalvings := (blossom-p.SlowStartShift())*BlossomPoWTargetSpacingRatio + (height - blossom)
+	return scaledHalvings / p.PostBlossomHalvingInterval()
+}
+
+// BlockSubsidy returns the new coins a block at height may create, in zatoshis (ZIP 208)
+// It includes the funding stream and lockbox shares, which are not paid to the miner, see LockboxShare
+func (p *Params) BlockSubsidy(height int64) int64 {
+	if height < p.SlowStartShift() {
+		return MaxBlockSubsidy / p.SlowStartInterval * height
+	}
+	if height < p.SlowStartInterval {
+		return MaxBlockSubsidy / p.SlowStartInterval * (height + 1)
+	}
+
+	halvings := p.Halving(height)
+	if halvings >= maxHalvings {
+		return 0
+	}
+	if p.BlossomActive(height) {
+		return (MaxBlockSubsidy / BlossomPoWTargetSpacingRatio) >> halvings
+	}
+	return MaxBlockSubsidy >> halvings
+}
+
+// LockboxShare returns the part of the subsidy at height deferred to the lockbox, in zatoshis (ZIP 1015)
+// It is part of BlockSubsidy but not paid out by the coinbase
+func (p *Params) LockboxShare(height int64) int64 {
+	if p.LockboxStartHeight == nil || height < *p.LockboxStartHeight {
+		return 0
+	}
+	if p.LockboxEndHeight != nil && height >= *p.LockboxEndHeight {
+		return 0
+	}
+	return p.BlockSubsidy(height) * LockboxNumerator / LockboxDenominator
+}
+
+// HalvingHeight returns the first height at which n halvings apply
+func (p *Params) HalvingHeight(n int64) int64 {
+	height := p.SlowStartShift() + n*p.PreBlossomHalvingInterval
+	if p.BlossomHeight == nil || height < *p.BlossomHeight {
+		return height
+	}
+	blossom := *p.BlossomHeight
+	return blossom + n*p.PostBlossomHalvingInterval() - (blossom-p.SlowStartShift())*BlossomPoWTargetSpacingRatio
+}
+
+// NextHalving returns the height of the first halving after height, or false once the subsidy is 0
+func (p *Params) NextHalving(height int64) (int64, bool) {
+	if p.BlockSubsidy(height) == 0 && height >= p.SlowStartInterval {
+		return 0, false
+	}
+	return p.HalvingHeight(p.Halving(height) + 1), true
+}
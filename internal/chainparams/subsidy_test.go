@@ -0,0 +1,135 @@
+package chainparams
+
+import "testing"
+
+func mustNetwork(t *testing.T, network string) *Params {
+	t.Helper()
+	params, err := ForNetwork(network)
+	if err != nil {
+		t.Fatalf("ForNetwork(%q): %v", network, err)
+	}
+	return params
+}
+
+func TestBlockSubsidy(t *testing.T) {
+	main := mustNetwork(t, NetworkMain)
+	regtest := mustNetwork(t, NetworkRegtest)
+
+	tests := []struct {
+		name   string
+		params *Params
+		height int64
+		want   int64
+	}{
+		{"genesis", main, 0, 0},
+		{"slow start first block", main, 1, 62500},
+		{"slow start before shift", main, 9999, 624937500},
+		{"slow start after shift", main, 10000, 625062500},
+		{"slow start last block", main, 19999, 1250000000},
+		{"full subsidy", main, 20000, 1250000000},
+		{"last pre-blossom block", main, 653599, 1250000000},
+		{"blossom", main, 653600, 625000000},
+		{"before first halving", main, 1046399, 625000000},
+		{"first halving", main, 1046400, 312500000},
+		{"second halving", main, 2726400, 156250000},
+		{"third halving", main, 4406400, 78125000},
+		{"regtest genesis", regtest, 0, 1250000000},
+		{"regtest first halving", regtest, 144, 625000000},
+		{"regtest subsidy exhausted", regtest, 144 * 64, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.params.BlockSubsidy(tt.height); got != tt.want {
+				t.Errorf("BlockSubsidy(%d) = %d, want %d", tt.height, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLockboxShare(t *testing.T) {
+	main := mustNetwork(t, NetworkMain)
+	test := mustNetwork(t, NetworkTest)
+	regtest := mustNetwork(t, NetworkRegtest)
+
+	tests := []struct {
+		name   string
+		params *Params
+		height int64
+		want   int64
+	}{
+		{"before nu6", main, 2726399, 0},
+		{"nu6 activation", main, 2726400, 18750000},
+		{"last lockbox block", main, 4406399, 18750000},
+		{"lockbox end", main, 4406400, 0},
+		{"testnet before nu6", test, 2975999, 0},
+		{"testnet nu6 activation", test, 2976000, 18750000},
+		{"testnet lockbox end", test, 3396000, 0},
+		{"regtest has no lockbox", regtest, 1000, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.params.LockboxShare(tt.height); got != tt.want {
+				t.Errorf("LockboxShare(%d) = %d, want %d", tt.height, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHalvingHeight(t *testing.T) {
+	main := mustNetwork(t, NetworkMain)
+	regtest := mustNetwork(t, NetworkRegtest)
+
+	tests := []struct {
+		name   string
+		params *Params
+		n      int64
+		want   int64
+	}{
+		{"main first", main, 1, 1046400},
+		{"main second", main, 2, 2726400},
+		{"main third", main, 3, 4406400},
+		{"regtest first", regtest, 1, 144},
+		{"regtest second", regtest, 2, 288},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.params.HalvingHeight(tt.n)
+			if got != tt.want {
+				t.Fatalf("HalvingHeight(%d) = %d, want %d", tt.n, got, tt.want)
+			}
+			if halvings := tt.params.Halving(got); halvings != tt.n {
+				t.Errorf("Halving(%d) = %d, want %d", got, halvings, tt.n)
+			}
+			if halvings := tt.params.Halving(got - 1); halvings != tt.n-1 {
+				t.Errorf("Halving(%d) = %d, want %d", got-1, halvings, tt.n-1)
+			}
+		})
+	}
+}
+
+func TestNextHalving(t *testing.T) {
+	main := mustNetwork(t, NetworkMain)
+	regtest := mustNetwork(t, NetworkRegtest)
+
+	tests := []struct {
+		name   string
+		params *Params
+		height int64
+		want   int64
+		wantOk bool
+	}{
+		{"slow start", main, 100, 1046400, true},
+		{"after blossom", main, 2000000, 2726400, true},
+		{"at a halving", main, 2726400, 4406400, true},
+		{"regtest", regtest, 10, 144, true},
+		{"subsidy exhausted", regtest, 144 * 64, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.params.NextHalving(tt.height)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("NextHalving(%d) = %d, %v, want %d, %v", tt.height, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
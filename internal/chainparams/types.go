@@ -0,0 +1,34 @@
+package chainparams
+
+// Networks reported by getblockchaininfo
+const (
+	NetworkMain    = "main"
+	NetworkTest    = "test"
+	NetworkRegtest = "regtest"
+)
+
+// Consensus constants shared by every network (ZIP 208)
+const (
+	MaxBlockSubsidy = 1_250_000_000 // 12.5 ZEC in zatoshis, the subsidy before the first halving
+	// BlossomPoWTargetSpacingRatio is the factor Blossom shortened the block target spacing by
+	BlossomPoWTargetSpacingRatio = 2
+	PreBlossomTargetSpacing      = 150 // seconds
+	PostBlossomTargetSpacing     = 75  // seconds
+	// maxHalvings is the halving count from which the subsidy is 0
+	maxHalvings = 64
+	// LockboxNumerator / LockboxDenominator of the subsidy is deferred to the lockbox (ZIP 1015)
+	LockboxNumerator   = 12
+	LockboxDenominator = 100
+)
+
+// Params are the consensus parameters that determine the block subsidy of a network
+type Params struct {
+	Network                   string `json:"network"`
+	SlowStartInterval         int64  `json:"slow_start_interval"`
+	PreBlossomHalvingInterval int64  `json:"pre_blossom_halving_interval"`
+	BlossomHeight             *int64 `json:"blossom_height"` // null when Blossom never activates
+	// Blocks from LockboxStartHeight up to LockboxEndHeight (exclusive) defer part of their subsidy
+	// to the lockbox instead of paying it out in the coinbase; null start when there is no lockbox
+	LockboxStartHeight *int64 `json:"lockbox_start_height"`
+	LockboxEndHeight   *int64 `json:"lockbox_end_height"` // null when the lockbox has no end
+}
@@ -0,0 +1,117 @@
+package changefeed
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/types"
+)
+
+func init() {
+	// Register this as a core schema (always initialized)
+	postgres.RegisterCoreSchema("change_feed", InitSchema)
+}
+
+// InitSchema creates the change_feed and block_manifests tables and indexes
+// Change feed rows are appended when blocks are indexed and when they are rolled back during a reorg
+// The BIGSERIAL id doubles as the consumer cursor, so it is strictly increasing
+func InitSchema() error {
+	schema := `
+		CREATE TABLE IF NOT EXISTS change_feed (
+			id BIGSERIAL PRIMARY KEY,
+			change_type VARCHAR(20) NOT NULL,  -- block_added, block_removed, fact_added, fact_removed
+			block_height BIGINT NOT NULL,
+			block_hash VARCHAR(64),
+			verifier_id VARCHAR(80),
+			txid VARCHAR(64),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_change_feed_block_height ON change_feed(block_height);
+
+		-- Per-block manifests of row keys touched by each module, used to replay reorg removals
+		CREATE TABLE IF NOT EXISTS block_manifests (
+			block_height BIGINT NOT NULL,
+			block_hash VARCHAR(64) NOT NULL,
+			module VARCHAR(20) NOT NULL,
+			table_name VARCHAR(50) NOT NULL,
+			action VARCHAR(10) NOT NULL,  -- insert, update, spend
+			row_key VARCHAR(160) NOT NULL,
+			PRIMARY KEY (block_height, table_name, action, row_key)
+		);
+	`
+
+	_, err := postgres.DB.Exec(context.Background(), schema)
+	if err != nil {
+		return fmt.Errorf("failed to create change_feed schema: %w", err)
+	}
+
+	return nil
+}
+
+// RecordBlock captures the block manifest and appends a block_added entry for an indexed block,
+// followed by fact_added entries for every Ztarknet fact in that block
+// It runs in the block's transaction, so feed consumers never see a block whose rows are not committed
+func RecordBlock(tx pgx.Tx, block *types.ZcashBlock) error {
+	if err := recordBlockManifest(tx, block.Height, block.Hash); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(context.Background(),
+		`INSERT INTO change_feed (change_type, block_height, block_hash)
+		 VALUES ($1, $2, $3)`,
+		ChangeBlockAdded, block.Height, block.Hash,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record block_added change: %w", err)
+	}
+
+	_, err = tx.Exec(context.Background(),
+		`INSERT INTO change_feed (change_type, block_height, block_hash, verifier_id, txid)
+		 SELECT $1, block_height, block_hash, split_part(row_key, '/', 1), split_part(row_key, '/', 2)
+		 FROM block_manifests
+		 WHERE block_height = $2 AND table_name = 'ztarknet_facts'
+		 ORDER BY row_key`,
+		ChangeFactAdded, block.Height,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record fact_added changes: %w", err)
+	}
+
+	if err := pruneBlockManifests(tx, block.Height); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetChanges retrieves change feed entries after the given cursor in cursor order
+func GetChanges(cursor int64, limit int) (*ChangesPage, error) {
+	changes, err := postgres.PostgresQueryCtx[Change](
+		context.Background(), nil,
+		`SELECT id, change_type, block_height, block_hash, verifier_id, txid, created_at
+		 FROM change_feed
+		 WHERE id > $1
+		 ORDER BY id
+		 LIMIT $2`,
+		cursor, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get changes: %w", err)
+	}
+
+	page := &ChangesPage{
+		Changes:    changes,
+		NextCursor: cursor,
+	}
+	if len(changes) > 0 {
+		page.NextCursor = changes[len(changes)-1].Cursor
+	}
+	if page.Changes == nil {
+		page.Changes = []Change{}
+	}
+
+	return page, nil
+}
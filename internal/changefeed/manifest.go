@@ -0,0 +1,134 @@
+package changefeed
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+)
+
+// recordBlockManifest stores the row keys each enabled module queued while writing the block
+// through postgresTx, so the manifest is committed with the rows it describes
+// Row keys per table, as the modules queue them with postgres.QueueManifestKeys:
+//   - blocks: height
+//   - transactions: txid
+//   - transaction_outputs, tze_outputs: txid:vout
+//   - transaction_inputs, tze_inputs: txid:vin
+//   - inputs_addresses: txid:vin:address
+//   - account_transactions: address:txid
+//   - accounts: address
+//   - stark_proofs, ztarknet_facts: verifier_id/txid (verifier_id itself contains ':')
+func recordBlockManifest(postgresTx pgx.Tx, height int64, hash string) error {
+	// Clear any manifest left over from a previous attempt at this height
+	_, err := postgresTx.Exec(context.Background(),
+		`DELETE FROM block_manifests WHERE block_height = $1`,
+		height,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to clear block manifest: %w", err)
+	}
+
+	keys := postgres.TakeManifest(postgresTx)
+	if len(keys) == 0 {
+		return nil
+	}
+	modules := make([]string, len(keys))
+	tables := make([]string, len(keys))
+	actions := make([]string, len(keys))
+	rowKeys := make([]string, len(keys))
+	for i, key := range keys {
+		modules[i], tables[i], actions[i], rowKeys[i] = key.Module, key.Table, key.Action, key.RowKey
+	}
+
+	_, err = postgresTx.Exec(context.Background(),
+		`INSERT INTO block_manifests (block_height, block_hash, module, table_name, action, row_key)
+		 SELECT DISTINCT $1::BIGINT, $2::VARCHAR, k.module, k.table_name, k.action, k.row_key
+		 FROM unnest($3::TEXT[], $4::TEXT[], $5::TEXT[], $6::TEXT[]) AS k(module, table_name, action, row_key)`,
+		height, hash, modules, tables, actions, rowKeys,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record block manifest: %w", err)
+	}
+
+	return nil
+}
+
+// RecordLateRows records the manifest keys queued through postgresTx for rows written after the block
+// at height was indexed, such as by a retry, an import or a backfill, in that block's manifest
+// Ztarknet facts the manifest did not list yet are appended to the change feed as fact_added, so
+// consumers see them and a rollback of the block replays their removal
+func RecordLateRows(postgresTx pgx.Tx, height int64) error {
+	keys := postgres.TakeManifest(postgresTx)
+	if len(keys) == 0 {
+		return nil
+	}
+	modules := make([]string, len(keys))
+	tables := make([]string, len(keys))
+	actions := make([]string, len(keys))
+	rowKeys := make([]string, len(keys))
+	for i, key := range keys {
+		modules[i], tables[i], actions[i], rowKeys[i] = key.Module, key.Table, key.Action, key.RowKey
+	}
+
+	// Keys are recorded under the hash of the block stored at height, and only new ones are announced
+	_, err := postgresTx.Exec(context.Background(),
+		`WITH recorded AS (
+			INSERT INTO block_manifests (block_height, block_hash, module, table_name, action, row_key)
+			SELECT DISTINCT b.height, b.hash, k.module, k.table_name, k.action, k.row_key
+			FROM unnest($2::TEXT[], $3::TEXT[], $4::TEXT[], $5::TEXT[]) AS k(module, table_name, action, row_key),
+			     blocks b
+			WHERE b.height = $1
+			ON CONFLICT DO NOTHING
+			RETURNING block_height, block_hash, table_name, row_key
+		 )
+		 INSERT INTO change_feed (change_type, block_height, block_hash, verifier_id, txid)
+		 SELECT $6, block_height, block_hash, split_part(row_key, '/', 1), split_part(row_key, '/', 2)
+		 FROM recorded
+		 WHERE table_name = 'ztarknet_facts'
+		 ORDER BY row_key`,
+		height, modules, tables, actions, rowKeys, ChangeFactAdded,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record late rows of block %d: %w", height, err)
+	}
+
+	return nil
+}
+
+// pruneBlockManifests removes manifests older than the configured retention window
+// Manifests are only needed while their block can still be rolled back by a reorg
+func pruneBlockManifests(postgresTx pgx.Tx, height int64) error {
+	retention := config.Conf.Indexer.ManifestRetention
+	if retention <= 0 {
+		return nil
+	}
+
+	_, err := postgresTx.Exec(context.Background(),
+		`DELETE FROM block_manifests WHERE block_height <= $1`,
+		height-int64(retention),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to prune block manifests: %w", err)
+	}
+
+	return nil
+}
+
+// GetBlockManifest retrieves the manifest entries recorded for a block height
+func GetBlockManifest(height int64) ([]ManifestEntry, error) {
+	entries, err := postgres.PostgresQueryCtx[ManifestEntry](
+		context.Background(), nil,
+		`SELECT block_height, block_hash, module, table_name, action, row_key
+		 FROM block_manifests
+		 WHERE block_height = $1
+		 ORDER BY module, table_name, action, row_key`,
+		height,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block manifest: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,40 @@
+package changefeed
+
+import "time"
+
+// ChangeType identifies the kind of change recorded in the change feed
+type ChangeType string
+
+const (
+	ChangeBlockAdded   ChangeType = "block_added"
+	ChangeBlockRemoved ChangeType = "block_removed"
+	ChangeFactAdded    ChangeType = "fact_added"
+	ChangeFactRemoved  ChangeType = "fact_removed"
+)
+
+// Change represents a single ordered entry in the change feed
+type Change struct {
+	Cursor      int64     `json:"cursor" db:"id"`
+	ChangeType  string    `json:"change_type" db:"change_type"` // block_added, block_removed, fact_added, fact_removed
+	BlockHeight int64     `json:"block_height" db:"block_height"`
+	BlockHash   *string   `json:"block_hash,omitempty" db:"block_hash"`
+	VerifierID  *string   `json:"verifier_id,omitempty" db:"verifier_id"` // fact changes only
+	TxID        *string   `json:"txid,omitempty" db:"txid"`               // fact changes only
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// ChangesPage is a page of the change feed along with the cursor to resume from
+type ChangesPage struct {
+	Changes    []Change `json:"changes"`
+	NextCursor int64    `json:"next_cursor"`
+}
+
+// ManifestEntry is a single row key a module wrote or updated while indexing a block
+type ManifestEntry struct {
+	BlockHeight int64  `json:"block_height" db:"block_height"`
+	BlockHash   string `json:"block_hash" db:"block_hash"`
+	Module      string `json:"module" db:"module"`
+	TableName   string `json:"table_name" db:"table_name"`
+	Action      string `json:"action" db:"action"` // insert, update, spend
+	RowKey      string `json:"row_key" db:"row_key"`
+}
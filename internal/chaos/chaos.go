@@ -0,0 +1,110 @@
+//go:build chaos
+
+package chaos
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Enabled reports whether this binary was built with fault injection
+const Enabled = true
+
+// KillExitCode is the exit status of a kill point, so restart loops can tell it from a real failure
+const KillExitCode = 86
+
+var (
+	rpcTimeoutRate = rate("ZINDEX_CHAOS_RPC_TIMEOUT")
+	dbErrorRate    = rate("ZINDEX_CHAOS_DB_ERROR")
+	killRate       = rate("ZINDEX_CHAOS_KILL")
+	points         = pointSet(os.Getenv("ZINDEX_CHAOS_POINTS"))
+
+	mu     sync.Mutex
+	source = rand.New(rand.NewSource(seed()))
+)
+
+func init() {
+	log.Printf("CHAOS build: rpc_timeout=%g db_error=%g kill=%g points=%q",
+		rpcTimeoutRate, dbErrorRate, killRate, os.Getenv("ZINDEX_CHAOS_POINTS"))
+}
+
+func rate(name string) float64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0
+	}
+	r, err := strconv.ParseFloat(value, 64)
+	if err != nil || r < 0 || r > 1 {
+		log.Fatalf("%s must be a probability between 0 and 1, got %q", name, value)
+	}
+	return r
+}
+
+func seed() int64 {
+	value := os.Getenv("ZINDEX_CHAOS_SEED")
+	if value == "" {
+		s := time.Now().UnixNano()
+		log.Printf("CHAOS seed %d (set ZINDEX_CHAOS_SEED to replay)", s)
+		return s
+	}
+	s, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Fatalf("ZINDEX_CHAOS_SEED must be an integer, got %q", value)
+	}
+	return s
+}
+
+func pointSet(list string) map[string]bool {
+	if list == "" {
+		return nil
+	}
+	set := make(map[string]bool)
+	for _, point := range strings.Split(list, ",") {
+		set[strings.TrimSpace(point)] = true
+	}
+	return set
+}
+
+// roll reports whether a fault with probability p fires at point
+func roll(point string, p float64) bool {
+	if p == 0 || (points != nil && !points[point]) {
+		return false
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	return source.Float64() < p
+}
+
+// RPCTimeout returns an error for an RPC attempt that should fail as if the node timed out
+func RPCTimeout(method string) error {
+	if !roll("rpc", rpcTimeoutRate) {
+		return nil
+	}
+	log.Printf("CHAOS: injecting RPC timeout for %s", method)
+	return fmt.Errorf("chaos: %s: %w", method, os.ErrDeadlineExceeded)
+}
+
+// DBError returns an error for a database step that should fail at point
+func DBError(point string) error {
+	if !roll(point, dbErrorRate) {
+		return nil
+	}
+	log.Printf("CHAOS: injecting database error at %s", point)
+	return fmt.Errorf("chaos: injected database error at %s", point)
+}
+
+// KillPoint exits the process without running deferred calls or shutdown hooks, like a crash at point
+// An open database transaction is aborted by Postgres when the connection drops
+func KillPoint(point string) {
+	if !roll(point, killRate) {
+		return
+	}
+	log.Printf("CHAOS: killing process at %s", point)
+	os.Exit(KillExitCode)
+}
@@ -0,0 +1,13 @@
+// Package chaos injects faults into the indexer for recovery testing
+// It is only compiled in with `go build -tags chaos`; regular builds get the no-op hooks of off.go
+// Faults are configured through environment variables, each a probability between 0 and 1:
+//
+//	ZINDEX_CHAOS_RPC_TIMEOUT  an RPC attempt fails as if it timed out
+//	ZINDEX_CHAOS_DB_ERROR     a database step of block indexing or rollback fails
+//	ZINDEX_CHAOS_KILL         the process exits at a kill point between module writes and commits
+//	ZINDEX_CHAOS_POINTS       comma-separated points to restrict DB errors and kills to (default: all)
+//	ZINDEX_CHAOS_SEED         seed of the fault sequence, to replay a run (default: time-based)
+//
+// Points are the module names (blocks, tx_graph, accounts, tze_graph, starks), commit, changefeed,
+// state and rollback. A DB error fails the step at the point; a kill exits right after it.
+package chaos
@@ -0,0 +1,15 @@
+//go:build !chaos
+
+package chaos
+
+// Enabled reports whether this binary was built with fault injection
+const Enabled = false
+
+// RPCTimeout never fails without the chaos build tag
+func RPCTimeout(method string) error { return nil }
+
+// DBError never fails without the chaos build tag
+func DBError(point string) error { return nil }
+
+// KillPoint does nothing without the chaos build tag
+func KillPoint(point string) {}
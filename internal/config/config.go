@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"log"
+	"net/netip"
 	"os"
 	"regexp"
 	"strings"
@@ -14,10 +15,18 @@ var Conf Config
 
 type Config struct {
 	Rpc      RpcConfig      `yaml:"rpc"`
+	Chain    ChainConfig    `yaml:"chain"`
 	Api      ApiConfig      `yaml:"api"`
 	Database DatabaseConfig `yaml:"database"`
 	Indexer  IndexerConfig  `yaml:"indexer"`
 	Modules  ModulesConfig  `yaml:"modules"`
+	Logging  LoggingConfig  `yaml:"logging"`
+	// Reports are operator-defined SQL reports served at /api/v1/reports/{name}
+	Reports map[string]ReportConfig `yaml:"reports"`
+	// Alerts are operator-defined conditions on indexed data, checked after every block
+	Alerts AlertsConfig `yaml:"alerts"`
+	// Jobs runs fee backfills, pruning, counter reconciliation and webhook digests from a Postgres queue
+	Jobs JobsConfig `yaml:"jobs"`
 }
 
 type RpcConfig struct {
@@ -25,19 +34,67 @@ type RpcConfig struct {
 	Timeout       int    `yaml:"timeout"`
 	RetryAttempts int    `yaml:"retry_attempts"`
 	RetryDelay    int    `yaml:"retry_delay"`
+	DevMode       bool   `yaml:"dev_mode"` // regtest only: allow mining blocks through the admin API
+	// Basic auth credentials (rpcuser/rpcpassword); ${VAR} substitution keeps them out of the file
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// CookieFile is the node's .cookie auth file, re-read when the node rejects the credentials
+	CookieFile string       `yaml:"cookie_file"`
+	TLS        RpcTLSConfig `yaml:"tls"`
+	// MethodTimeouts overrides timeout (seconds) for individual RPC methods
+	MethodTimeouts map[string]int `yaml:"method_timeouts"`
+}
+
+// RpcTLSConfig customizes TLS for https RPC endpoints
+type RpcTLSConfig struct {
+	CAFile             string `yaml:"ca_file"`   // PEM CA bundle trusted instead of the system roots
+	CertFile           string `yaml:"cert_file"` // PEM client certificate for mutual TLS
+	KeyFile            string `yaml:"key_file"`
+	ServerName         string `yaml:"server_name"` // overrides the name the server certificate is checked against
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+// ChainConfig selects the consensus parameters of the indexed network, used for computed data
+// such as the emission schedule. Unset overrides keep the network's defaults
+type ChainConfig struct {
+	Network                   string `yaml:"network"` // main, test or regtest; empty asks the node
+	SlowStartInterval         *int64 `yaml:"slow_start_interval"`
+	PreBlossomHalvingInterval *int64 `yaml:"pre_blossom_halving_interval"`
+	BlossomHeight             *int64 `yaml:"blossom_height"`
+	LockboxStartHeight        *int64 `yaml:"lockbox_start_height"`
+	LockboxEndHeight          *int64 `yaml:"lockbox_end_height"`
 }
 
 type ApiConfig struct {
-	Host           string           `yaml:"host"`
-	Port           string           `yaml:"port"`
-	Production     bool             `yaml:"production"`
-	Admin          bool             `yaml:"admin"`
-	Cors           CorsConfig       `yaml:"cors"`
-	ReadTimeout    int              `yaml:"read_timeout"`
-	WriteTimeout   int              `yaml:"write_timeout"`
-	IdleTimeout    int              `yaml:"idle_timeout"`
-	MaxHeaderBytes int              `yaml:"max_header_bytes"`
-	Pagination     PaginationConfig `yaml:"pagination"`
+	Host           string             `yaml:"host"`
+	Port           string             `yaml:"port"`
+	Production     bool               `yaml:"production"`
+	Admin          bool               `yaml:"admin"`
+	AdminCidrs     []string           `yaml:"admin_allowed_cidrs"`
+	Cors           CorsConfig         `yaml:"cors"`
+	ReadTimeout    int                `yaml:"read_timeout"`
+	WriteTimeout   int                `yaml:"write_timeout"`
+	IdleTimeout    int                `yaml:"idle_timeout"`
+	MaxHeaderBytes int                `yaml:"max_header_bytes"`
+	Pagination     PaginationConfig   `yaml:"pagination"`
+	DegradedMode   DegradedModeConfig `yaml:"degraded_mode"`
+	Usage          UsageConfig        `yaml:"usage"`
+	Websocket      WebsocketConfig    `yaml:"websocket"`
+	Metrics        bool               `yaml:"metrics"` // serve Prometheus metrics at /metrics
+	// ExcludeZeroValueOutputs hides zero-value outputs from output and account listings unless include_zero=true
+	ExcludeZeroValueOutputs bool `yaml:"exclude_zero_value_outputs"`
+	// ShutdownTimeout is how many seconds in-flight requests get to finish on shutdown
+	ShutdownTimeout int `yaml:"shutdown_timeout"`
+	// Webhooks deliver indexer events to subscribed URLs
+	Webhooks WebhooksConfig `yaml:"webhooks"`
+	// LoadShedding rejects low-priority requests of endpoint groups running over their latency budget
+	LoadShedding LoadSheddingConfig `yaml:"load_shedding"`
+	// RateLimit caps the request rate of each API key, or client IP without one, with token buckets
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	// Auth checks API keys stored in the api_keys table
+	Auth AuthConfig `yaml:"auth"`
+	// BlueGreen serves the API only while database.schema is the active schema, for schema upgrades
+	BlueGreen BlueGreenConfig `yaml:"blue_green"`
 }
 
 type PaginationConfig struct {
@@ -46,6 +103,72 @@ type PaginationConfig struct {
 	MaxOffset    int `yaml:"max_offset"`
 }
 
+type UsageConfig struct {
+	Enabled       bool `yaml:"enabled"`
+	FlushInterval int  `yaml:"flush_interval"`
+}
+
+type WebsocketConfig struct {
+	Enabled        bool `yaml:"enabled"`
+	MaxConnections int  `yaml:"max_connections"`
+}
+
+// WebhooksConfig controls delivery of signed event payloads to webhook subscriptions
+type WebhooksConfig struct {
+	Enabled     bool `yaml:"enabled"`
+	Timeout     int  `yaml:"timeout"`      // seconds a delivery request may take
+	MaxAttempts int  `yaml:"max_attempts"` // attempts before a delivery is marked failed
+	RetryDelay  int  `yaml:"retry_delay"`  // seconds before the first retry, doubling each attempt
+	// MaxDigestEvents bounds the events listed in one digest delivery; the counts cover every event
+	MaxDigestEvents int `yaml:"max_digest_events"`
+}
+
+// LoadSheddingConfig sets per endpoint group p95 latency budgets
+// A group is the path segment after /api/v1/, e.g. blocks, tx-graph or starks
+type LoadSheddingConfig struct {
+	Enabled     bool           `yaml:"enabled"`
+	Window      int            `yaml:"window"`       // seconds of requests the rolling p95 covers
+	MinSamples  int            `yaml:"min_samples"`  // requests in the window before a group can shed
+	RetryAfter  int            `yaml:"retry_after"`  // seconds sent in the Retry-After header
+	Budgets     map[string]int `yaml:"budgets"`      // p95 budget in milliseconds per group
+	LowPriority []string       `yaml:"low_priority"` // path prefixes shed first; empty sheds the whole group
+}
+
+// RateLimitConfig sets the token bucket every client draws API requests from
+// Routes overrides the rate for path prefixes; the longest matching prefix gets its own bucket
+type RateLimitConfig struct {
+	Enabled           bool                            `yaml:"enabled"`
+	RequestsPerSecond float64                         `yaml:"requests_per_second"` // bucket refill rate
+	Burst             int                             `yaml:"burst"`               // bucket size
+	Routes            map[string]RateLimitRouteConfig `yaml:"routes"`
+}
+
+// RateLimitRouteConfig is the rate of one path prefix
+type RateLimitRouteConfig struct {
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+	Burst             int     `yaml:"burst"`
+}
+
+// AuthConfig controls API key authentication
+// With Enabled, admin routes also need a key with the admin scope
+type AuthConfig struct {
+	Enabled    bool `yaml:"enabled"`
+	RequireKey bool `yaml:"require_key"` // reject /api/v1/ requests without a key instead of serving them anonymously
+	CacheTTL   int  `yaml:"cache_ttl"`   // seconds a key lookup is reused before the database is read again
+}
+
+// BlueGreenConfig lets deployments on different database schemas share one database, with only
+// the deployment on the active schema serving API requests
+type BlueGreenConfig struct {
+	Enabled      bool `yaml:"enabled"`
+	PollInterval int  `yaml:"poll_interval"` // seconds between reads of the active schema
+}
+
+type DegradedModeConfig struct {
+	LagThreshold int64 `yaml:"lag_threshold"`
+	RejectStale  bool  `yaml:"reject_stale"`
+}
+
 type CorsConfig struct {
 	AllowedOrigins []string `yaml:"allowed_origins"`
 	AllowedMethods []string `yaml:"allowed_methods"`
@@ -58,20 +181,72 @@ type DatabaseConfig struct {
 	User               string `yaml:"user"`
 	Password           string `yaml:"password"`
 	DBName             string `yaml:"dbname"`
+	Schema             string `yaml:"schema"` // schema holding zindex tables (empty = the server's search_path, usually public)
 	SSLMode            string `yaml:"sslmode"`
 	MaxConnections     int    `yaml:"max_connections"`
 	MaxIdleConnections int    `yaml:"max_idle_connections"`
 	ConnectionLifetime int    `yaml:"connection_lifetime"`
 	ConnectTimeout     int    `yaml:"connect_timeout"`
 	StatementTimeout   int    `yaml:"statement_timeout"`
+
+	DropRedundantIndexes bool              `yaml:"drop_redundant_indexes"`
+	DeferIndexesUntilTip int               `yaml:"defer_indexes_until_tip"`
+	ImmutableOnConflict  string            `yaml:"immutable_on_conflict"`
+	Maintenance          MaintenanceConfig `yaml:"maintenance"`
+	// Statement timeouts per query class, on top of StatementTimeout
+	StatementTimeouts StatementTimeoutsConfig `yaml:"statement_timeouts"`
+}
+
+// StatementTimeoutsConfig sets the statement timeout of each query class in seconds, applied with SET LOCAL
+type StatementTimeoutsConfig struct {
+	APIRead          int `yaml:"api_read"`          // module lookups served by the API (0 = statement_timeout)
+	IndexerWrite     int `yaml:"indexer_write"`     // block indexing and reorg rollbacks (0 = statement_timeout)
+	AdminMaintenance int `yaml:"admin_maintenance"` // ANALYZE/VACUUM, counter reconciliation, index builds (0 = no limit)
+}
+
+type MaintenanceConfig struct {
+	AutoAnalyze      bool  `yaml:"auto_analyze"`
+	AutoVacuum       bool  `yaml:"auto_vacuum"`
+	BulkRowThreshold int64 `yaml:"bulk_row_threshold"`
 }
 
 type IndexerConfig struct {
-	BatchSize           int   `yaml:"batch_size"`
-	PollInterval        int   `yaml:"poll_interval"`
-	StartBlock          int64 `yaml:"start_block"`
-	EnableReorgHandling bool  `yaml:"enable_reorg_handling"`
-	MaxReorgDepth       int   `yaml:"max_reorg_depth"`
+	BatchSize                int              `yaml:"batch_size"`
+	PollInterval             int              `yaml:"poll_interval"`
+	StartBlock               int64            `yaml:"start_block"`
+	EnableReorgHandling      bool             `yaml:"enable_reorg_handling"`
+	MaxReorgDepth            int              `yaml:"max_reorg_depth"`
+	FinalityDepth            int              `yaml:"finality_depth"`
+	ManifestRetention        int              `yaml:"manifest_retention"`
+	WedgeTimeout             int              `yaml:"wedge_timeout"`
+	UtxoSnapshotInterval     int              `yaml:"utxo_snapshot_interval"`
+	Lenient                  bool             `yaml:"lenient"`
+	MerkleCheck              string           `yaml:"merkle_check"`
+	CounterReconcileInterval int              `yaml:"counter_reconcile_interval"`
+	Supervisor               SupervisorConfig `yaml:"supervisor"`
+	// Witnesses larger than this (bytes) are hashed without being decoded; 0 = no limit
+	MaxWitnessSize int `yaml:"max_witness_size"`
+	// BatchRpc fetches each batch's block hashes and blocks with one JSON-RPC batch request each
+	BatchRpc bool `yaml:"batch_rpc"`
+	// Notify wakes the loop when the node announces a block instead of waiting for the next poll
+	Notify NotifyConfig `yaml:"notify"`
+}
+
+// NotifyConfig selects the block notifications pushed by the node
+type NotifyConfig struct {
+	ZmqUrl   string `yaml:"zmq_url"`   // -zmqpubhashblock/-zmqpubrawblock endpoint, tcp://host:port (empty = disabled)
+	ZmqTopic string `yaml:"zmq_topic"` // hashblock or rawblock
+	Http     bool   `yaml:"http"`      // accept POST /api/v1/admin/notify/block, e.g. from -blocknotify
+	// FallbackPollInterval is the seconds between polls while the ZMQ socket is connected
+	FallbackPollInterval int `yaml:"fallback_poll_interval"`
+}
+
+// SupervisorConfig controls what the indexer does when a block keeps failing after its retries
+type SupervisorConfig struct {
+	Enabled        bool `yaml:"enabled"`
+	InitialBackoff int  `yaml:"initial_backoff"`
+	MaxBackoff     int  `yaml:"max_backoff"`
+	MaxRestarts    int  `yaml:"max_restarts"`
 }
 
 type ModulesConfig struct {
@@ -79,6 +254,7 @@ type ModulesConfig struct {
 	TzeGraph TzeGraphConfig `yaml:"tze_graph"`
 	Starks   StarksConfig   `yaml:"starks"`
 	Accounts AccountsConfig `yaml:"accounts"`
+	Mempool  MempoolConfig  `yaml:"mempool"`
 }
 
 type TxGraphConfig struct {
@@ -89,15 +265,101 @@ type TxGraphConfig struct {
 type TzeGraphConfig struct {
 	Enabled             bool `yaml:"enabled"`
 	MaxPreconditionSize int  `yaml:"max_precondition_size"`
+	MaxGraphDepth       int  `yaml:"max_graph_depth"`
 }
 
 type StarksConfig struct {
-	Enabled       bool `yaml:"enabled"`
-	IndexZtarknet bool `yaml:"index_ztarknet"`
+	Enabled          bool   `yaml:"enabled"`
+	IndexZtarknet    bool   `yaml:"index_ztarknet"`
+	ExportSigningKey string `yaml:"export_signing_key"`
+	// FormatActivations maps a stark_verify format version to the block height it takes effect at
+	// Version 1 applies from genesis until the first listed activation
+	FormatActivations map[int]int64 `yaml:"format_activations"`
+	// Program hashes of the canonical Ztarknet deployment (bootloader and OS); facts proving
+	// other programs are recorded as anomalies. Empty skips the check
+	ExpectedProgramHash      string `yaml:"expected_program_hash"`
+	ExpectedInnerProgramHash string `yaml:"expected_inner_program_hash"`
+	// CanonicalVerifierID is the verifier of the canonical Ztarknet rollup, unless an admin designates another
+	CanonicalVerifierID string `yaml:"canonical_verifier_id"`
+	// CostModel estimates the verification cost of each stark_verify proof as it is indexed
+	CostModel CostModelConfig `yaml:"cost_model"`
+}
+
+// CostModelConfig selects the stark_verify verification cost model and its weights
+// An empty model leaves proofs unpriced
+type CostModelConfig struct {
+	Model        string  `yaml:"model"`          // linear, or empty to disable
+	Base         float64 `yaml:"base"`           // cost of every proof
+	PerProofByte float64 `yaml:"per_proof_byte"` // cost per byte of proof data
+	Pedersen     float64 `yaml:"pedersen"`       // added when the witness sets with_pedersen
 }
 
 type AccountsConfig struct {
-	Enabled bool `yaml:"enabled"`
+	Enabled       bool `yaml:"enabled"`
+	TrackShielded bool `yaml:"track_shielded"`
+}
+
+// MempoolConfig controls tracking of unconfirmed transactions
+type MempoolConfig struct {
+	Enabled         bool `yaml:"enabled"`
+	PollInterval    int  `yaml:"poll_interval"`    // seconds between getrawmempool polls
+	Retention       int  `yaml:"retention"`        // hours confirmed and evicted entries are kept
+	MaxTransactions int  `yaml:"max_transactions"` // new transactions fetched per poll
+}
+
+// ReportConfig is a read-only SQL report template
+// The query references its parameters as $1, $2, ... in the order they are listed
+type ReportConfig struct {
+	Description string              `yaml:"description"`
+	Query       string              `yaml:"query"`
+	Params      []ReportParamConfig `yaml:"params"`
+	MaxRows     int                 `yaml:"max_rows"` // rows returned before the result is truncated
+	Timeout     int                 `yaml:"timeout"`  // seconds, 0 uses database.statement_timeout
+}
+
+// ReportParamConfig is a query parameter a report accepts; any other parameter is rejected
+type ReportParamConfig struct {
+	Name     string `yaml:"name"`
+	Type     string `yaml:"type"` // int, string, bool or hex
+	Required bool   `yaml:"required"`
+	Default  string `yaml:"default"`
+}
+
+// AlertsConfig enables alert rules; besides the rules listed here, rules can be added through the admin API
+type AlertsConfig struct {
+	Enabled bool                       `yaml:"enabled"`
+	Rules   map[string]AlertRuleConfig `yaml:"rules"`
+}
+
+// AlertRuleConfig is a condition checked after every indexed block
+// The fields that apply depend on the kind:
+//   - proof_size_above: threshold (bytes), verifier_id optional
+//   - verifier_balance_below: threshold (zatoshis), verifier_id optional
+//   - no_fact_for_program: program_hash and blocks
+type AlertRuleConfig struct {
+	Kind        string `yaml:"kind" json:"kind"`
+	Threshold   int64  `yaml:"threshold" json:"threshold"`
+	VerifierID  string `yaml:"verifier_id" json:"verifier_id"`
+	ProgramHash string `yaml:"program_hash" json:"program_hash"`
+	Blocks      int64  `yaml:"blocks" json:"blocks"`
+}
+
+// JobsConfig sizes the background job queue
+// While it is disabled, periodic work runs on in-process timers and nothing can be enqueued
+type JobsConfig struct {
+	Enabled      bool `yaml:"enabled"`
+	Workers      int  `yaml:"workers"`       // jobs run concurrently by this instance
+	PollInterval int  `yaml:"poll_interval"` // seconds between checks for due jobs
+	MaxAttempts  int  `yaml:"max_attempts"`  // attempts before a job is marked failed
+	RetryDelay   int  `yaml:"retry_delay"`   // seconds before the first retry, doubling each attempt
+	Lease        int  `yaml:"lease"`         // seconds a silent worker keeps a job before another takes it over
+	Retention    int  `yaml:"retention"`     // hours finished jobs are kept (0 = forever)
+}
+
+type LoggingConfig struct {
+	Level        string            `yaml:"level"`
+	Modules      map[string]string `yaml:"modules"`
+	BatchSummary bool              `yaml:"batch_summary"`
 }
 
 func InitConfig(configPath string) {
@@ -160,6 +422,8 @@ func IsModuleEnabled(moduleName string) bool {
 		return Conf.Modules.Starks.Enabled
 	case "ACCOUNTS":
 		return Conf.Modules.Accounts.Enabled
+	case "MEMPOOL":
+		return Conf.Modules.Mempool.Enabled
 	default:
 		return false
 	}
@@ -183,6 +447,52 @@ func validateConfig() error {
 	if Conf.Rpc.RetryDelay < 0 {
 		return fmt.Errorf("rpc.retry_delay must be non-negative")
 	}
+	if Conf.Rpc.DevMode && Conf.Api.Production {
+		return fmt.Errorf("rpc.dev_mode cannot be enabled with api.production")
+	}
+	if Conf.Rpc.Password != "" && Conf.Rpc.Username == "" {
+		return fmt.Errorf("rpc.password requires rpc.username")
+	}
+	if Conf.Rpc.CookieFile != "" && Conf.Rpc.Username != "" {
+		return fmt.Errorf("rpc.cookie_file and rpc.username cannot both be set")
+	}
+	for method, timeout := range Conf.Rpc.MethodTimeouts {
+		if timeout <= 0 {
+			return fmt.Errorf("rpc.method_timeouts.%s must be greater than 0", method)
+		}
+	}
+	rpcTLS := Conf.Rpc.TLS
+	if (rpcTLS.CertFile == "") != (rpcTLS.KeyFile == "") {
+		return fmt.Errorf("rpc.tls.cert_file and rpc.tls.key_file must be set together")
+	}
+	if rpcTLS != (RpcTLSConfig{}) && !strings.HasPrefix(Conf.Rpc.Url, "https://") {
+		return fmt.Errorf("rpc.tls requires an https:// rpc.url")
+	}
+	if rpcTLS.InsecureSkipVerify && Conf.Api.Production {
+		return fmt.Errorf("rpc.tls.insecure_skip_verify cannot be enabled with api.production")
+	}
+
+	// Validate chain parameters
+	switch Conf.Chain.Network {
+	case "", "main", "test", "regtest":
+	default:
+		return fmt.Errorf("chain.network must be main, test or regtest (or empty to ask the node)")
+	}
+	if interval := Conf.Chain.SlowStartInterval; interval != nil && *interval < 0 {
+		return fmt.Errorf("chain.slow_start_interval must be non-negative")
+	}
+	if interval := Conf.Chain.PreBlossomHalvingInterval; interval != nil && *interval <= 0 {
+		return fmt.Errorf("chain.pre_blossom_halving_interval must be greater than 0")
+	}
+	if height := Conf.Chain.BlossomHeight; height != nil && *height < 0 {
+		return fmt.Errorf("chain.blossom_height must be non-negative")
+	}
+	if height := Conf.Chain.LockboxStartHeight; height != nil && *height < 0 {
+		return fmt.Errorf("chain.lockbox_start_height must be non-negative")
+	}
+	if end, start := Conf.Chain.LockboxEndHeight, Conf.Chain.LockboxStartHeight; end != nil && start != nil && *end <= *start {
+		return fmt.Errorf("chain.lockbox_end_height must be greater than chain.lockbox_start_height")
+	}
 
 	// Validate API configuration
 	if Conf.Api.Host == "" {
@@ -203,6 +513,9 @@ func validateConfig() error {
 	if Conf.Api.MaxHeaderBytes <= 0 {
 		return fmt.Errorf("api.max_header_bytes must be greater than 0")
 	}
+	if Conf.Api.ShutdownTimeout <= 0 {
+		return fmt.Errorf("api.shutdown_timeout must be greater than 0")
+	}
 
 	// Validate pagination configuration
 	if Conf.Api.Pagination.DefaultLimit <= 0 {
@@ -218,6 +531,101 @@ func validateConfig() error {
 		return fmt.Errorf("api.pagination.max_offset must be non-negative")
 	}
 
+	// Validate admin allowlist
+	for _, cidr := range Conf.Api.AdminCidrs {
+		if _, err := netip.ParsePrefix(cidr); err != nil {
+			return fmt.Errorf("api.admin_allowed_cidrs: invalid CIDR %q: %w", cidr, err)
+		}
+	}
+
+	// Validate degraded mode configuration
+	if Conf.Api.DegradedMode.LagThreshold < 0 {
+		return fmt.Errorf("api.degraded_mode.lag_threshold must be non-negative")
+	}
+
+	// Validate usage tracking configuration
+	if Conf.Api.Usage.Enabled && Conf.Api.Usage.FlushInterval <= 0 {
+		return fmt.Errorf("api.usage.flush_interval must be greater than 0 when usage tracking is enabled")
+	}
+
+	// Validate WebSocket configuration
+	if Conf.Api.Websocket.Enabled && Conf.Api.Websocket.MaxConnections <= 0 {
+		return fmt.Errorf("api.websocket.max_connections must be greater than 0")
+	}
+
+	// Validate webhook configuration
+	if Conf.Api.Webhooks.Enabled {
+		if Conf.Api.Webhooks.Timeout <= 0 {
+			return fmt.Errorf("api.webhooks.timeout must be greater than 0")
+		}
+		if Conf.Api.Webhooks.MaxAttempts <= 0 {
+			return fmt.Errorf("api.webhooks.max_attempts must be greater than 0")
+		}
+		if Conf.Api.Webhooks.RetryDelay <= 0 {
+			return fmt.Errorf("api.webhooks.retry_delay must be greater than 0")
+		}
+		if Conf.Api.Webhooks.MaxDigestEvents <= 0 {
+			return fmt.Errorf("api.webhooks.max_digest_events must be greater than 0")
+		}
+	}
+
+	// Validate rate limiting configuration
+	if rateLimit := Conf.Api.RateLimit; rateLimit.Enabled {
+		if rateLimit.RequestsPerSecond <= 0 {
+			return fmt.Errorf("api.rate_limit.requests_per_second must be greater than 0")
+		}
+		if rateLimit.Burst < 1 {
+			return fmt.Errorf("api.rate_limit.burst must be at least 1")
+		}
+		for prefix, route := range rateLimit.Routes {
+			if !strings.HasPrefix(prefix, "/api/v1/") {
+				return fmt.Errorf("api.rate_limit.routes: %q must start with /api/v1/", prefix)
+			}
+			if route.RequestsPerSecond <= 0 {
+				return fmt.Errorf("api.rate_limit.routes.%s.requests_per_second must be greater than 0", prefix)
+			}
+			if route.Burst < 1 {
+				return fmt.Errorf("api.rate_limit.routes.%s.burst must be at least 1", prefix)
+			}
+		}
+	}
+
+	// Validate API key authentication configuration
+	if Conf.Api.Auth.Enabled && Conf.Api.Auth.CacheTTL < 0 {
+		return fmt.Errorf("api.auth.cache_ttl must be non-negative")
+	}
+
+	// Validate blue/green configuration
+	if Conf.Api.BlueGreen.Enabled && Conf.Api.BlueGreen.PollInterval <= 0 {
+		return fmt.Errorf("api.blue_green.poll_interval must be greater than 0")
+	}
+
+	// Validate load shedding configuration
+	if shedding := Conf.Api.LoadShedding; shedding.Enabled {
+		if shedding.Window <= 0 {
+			return fmt.Errorf("api.load_shedding.window must be greater than 0")
+		}
+		if shedding.MinSamples < 0 {
+			return fmt.Errorf("api.load_shedding.min_samples must be non-negative")
+		}
+		if shedding.RetryAfter <= 0 {
+			return fmt.Errorf("api.load_shedding.retry_after must be greater than 0")
+		}
+		for group, budget := range shedding.Budgets {
+			if group == "admin" {
+				return fmt.Errorf("api.load_shedding.budgets: admin routes are never shed")
+			}
+			if budget <= 0 {
+				return fmt.Errorf("api.load_shedding.budgets.%s must be greater than 0", group)
+			}
+		}
+		for _, prefix := range shedding.LowPriority {
+			if !strings.HasPrefix(prefix, "/api/v1/") {
+				return fmt.Errorf("api.load_shedding.low_priority: %q must start with /api/v1/", prefix)
+			}
+		}
+	}
+
 	// Validate CORS configuration (if provided)
 	validMethods := map[string]bool{
 		"GET": true, "POST": true, "PUT": true, "PATCH": true,
@@ -240,6 +648,9 @@ func validateConfig() error {
 		if Conf.Database.DBName == "" {
 			return fmt.Errorf("database.dbname is required when database connection is enabled")
 		}
+		if Conf.Database.Schema != "" && !SchemaNamePattern.MatchString(Conf.Database.Schema) {
+			return fmt.Errorf("database.schema must be a lowercase identifier (letters, digits and underscores)")
+		}
 
 		// Validate SSL mode
 		validSSLModes := map[string]bool{
@@ -275,6 +686,27 @@ func validateConfig() error {
 		if Conf.Database.StatementTimeout <= 0 {
 			return fmt.Errorf("database.statement_timeout must be greater than 0")
 		}
+
+		if Conf.Database.DeferIndexesUntilTip < 0 {
+			return fmt.Errorf("database.defer_indexes_until_tip must be non-negative")
+		}
+		if Conf.Database.ImmutableOnConflict == "" {
+			Conf.Database.ImmutableOnConflict = "update"
+		}
+		if Conf.Database.ImmutableOnConflict != "update" && Conf.Database.ImmutableOnConflict != "skip" {
+			return fmt.Errorf("database.immutable_on_conflict must be one of: update, skip")
+		}
+
+		// Validate per-class statement timeouts
+		timeouts := Conf.Database.StatementTimeouts
+		if timeouts.APIRead < 0 || timeouts.IndexerWrite < 0 || timeouts.AdminMaintenance < 0 {
+			return fmt.Errorf("database.statement_timeouts must be non-negative")
+		}
+
+		// Validate maintenance settings
+		if Conf.Database.Maintenance.AutoAnalyze && Conf.Database.Maintenance.BulkRowThreshold <= 0 {
+			return fmt.Errorf("database.maintenance.bulk_row_threshold must be greater than 0 when auto_analyze is enabled")
+		}
 	}
 
 	// Validate Indexer configuration
@@ -284,12 +716,61 @@ func validateConfig() error {
 	if Conf.Indexer.PollInterval <= 0 {
 		return fmt.Errorf("indexer.poll_interval must be greater than 0")
 	}
+	if notify := Conf.Indexer.Notify; notify.ZmqUrl != "" {
+		if !strings.HasPrefix(notify.ZmqUrl, "tcp://") {
+			return fmt.Errorf("indexer.notify.zmq_url must start with tcp://")
+		}
+		if notify.ZmqTopic != "hashblock" && notify.ZmqTopic != "rawblock" {
+			return fmt.Errorf("indexer.notify.zmq_topic must be hashblock or rawblock")
+		}
+		if notify.FallbackPollInterval < Conf.Indexer.PollInterval {
+			return fmt.Errorf("indexer.notify.fallback_poll_interval must be at least indexer.poll_interval")
+		}
+	}
 	if Conf.Indexer.StartBlock < 0 {
 		return fmt.Errorf("indexer.start_block must be non-negative")
 	}
 	if Conf.Indexer.MaxReorgDepth < 0 {
 		return fmt.Errorf("indexer.max_reorg_depth must be non-negative")
 	}
+	if Conf.Indexer.FinalityDepth < 0 {
+		return fmt.Errorf("indexer.finality_depth must be non-negative")
+	}
+	if Conf.Indexer.MerkleCheck == "" {
+		Conf.Indexer.MerkleCheck = "reject"
+	}
+	if Conf.Indexer.MerkleCheck != "reject" && Conf.Indexer.MerkleCheck != "flag" && Conf.Indexer.MerkleCheck != "off" {
+		return fmt.Errorf("indexer.merkle_check must be one of: reject, flag, off")
+	}
+	if Conf.Indexer.ManifestRetention < 0 {
+		return fmt.Errorf("indexer.manifest_retention must be non-negative")
+	}
+	if Conf.Indexer.ManifestRetention > 0 && Conf.Indexer.ManifestRetention < Conf.Indexer.MaxReorgDepth {
+		return fmt.Errorf("indexer.manifest_retention must be 0 (keep all) or at least max_reorg_depth")
+	}
+	if Conf.Indexer.WedgeTimeout < 0 {
+		return fmt.Errorf("indexer.wedge_timeout must be non-negative")
+	}
+	if Conf.Indexer.UtxoSnapshotInterval < 0 {
+		return fmt.Errorf("indexer.utxo_snapshot_interval must be non-negative")
+	}
+	if Conf.Indexer.CounterReconcileInterval < 0 {
+		return fmt.Errorf("indexer.counter_reconcile_interval must be non-negative")
+	}
+	if Conf.Indexer.MaxWitnessSize < 0 {
+		return fmt.Errorf("indexer.max_witness_size must be non-negative")
+	}
+	if Conf.Indexer.Supervisor.Enabled {
+		if Conf.Indexer.Supervisor.InitialBackoff <= 0 {
+			return fmt.Errorf("indexer.supervisor.initial_backoff must be greater than 0")
+		}
+		if Conf.Indexer.Supervisor.MaxBackoff < Conf.Indexer.Supervisor.InitialBackoff {
+			return fmt.Errorf("indexer.supervisor.max_backoff must be at least initial_backoff")
+		}
+		if Conf.Indexer.Supervisor.MaxRestarts < 0 {
+			return fmt.Errorf("indexer.supervisor.max_restarts must be non-negative")
+		}
+	}
 
 	// Validate Module configurations
 	if Conf.Modules.TxGraph.Enabled {
@@ -302,7 +783,213 @@ func validateConfig() error {
 		if Conf.Modules.TzeGraph.MaxPreconditionSize <= 0 {
 			return fmt.Errorf("modules.tze_graph.max_precondition_size must be greater than 0")
 		}
+		if Conf.Modules.TzeGraph.MaxGraphDepth <= 0 {
+			return fmt.Errorf("modules.tze_graph.max_graph_depth must be greater than 0")
+		}
+	}
+
+	if key := Conf.Modules.Starks.ExportSigningKey; key != "" {
+		if !regexp.MustCompile(`^[0-9a-fA-F]{64}$`).MatchString(key) {
+			return fmt.Errorf("modules.starks.export_signing_key must be a 64-character hex Ed25519 seed")
+		}
+	}
+	for name, hash := range map[string]*string{
+		"expected_program_hash":       &Conf.Modules.Starks.ExpectedProgramHash,
+		"expected_inner_program_hash": &Conf.Modules.Starks.ExpectedInnerProgramHash,
+	} {
+		if *hash == "" {
+			continue
+		}
+		if !regexp.MustCompile(`^[0-9a-fA-F]{64}$`).MatchString(*hash) {
+			return fmt.Errorf("modules.starks.%s must be 64 hex characters", name)
+		}
+		if !Conf.Modules.Starks.IndexZtarknet {
+			return fmt.Errorf("modules.starks.%s requires modules.starks.index_ztarknet", name)
+		}
+		// Facts store hashes in lowercase
+		*hash = strings.ToLower(*hash)
+	}
+	if len(Conf.Modules.Starks.CanonicalVerifierID) > 80 {
+		return fmt.Errorf("modules.starks.canonical_verifier_id must be a verifier id (txid:vout)")
+	}
+	if model := Conf.Modules.Starks.CostModel; model.Model != "" {
+		if model.Model != "linear" {
+			return fmt.Errorf("modules.starks.cost_model.model must be linear or empty, got %q", model.Model)
+		}
+		if model.Base < 0 || model.PerProofByte < 0 || model.Pedersen < 0 {
+			return fmt.Errorf("modules.starks.cost_model weights must not be negative")
+		}
+	}
+	for version, height := range Conf.Modules.Starks.FormatActivations {
+		if version <= 1 {
+			return fmt.Errorf("modules.starks.format_activations: version %d is invalid, version 1 applies from genesis", version)
+		}
+		if height < 0 {
+			return fmt.Errorf("modules.starks.format_activations.%d must be a non-negative block height", version)
+		}
+	}
+
+	if Conf.Modules.Mempool.Enabled {
+		if Conf.Modules.Mempool.PollInterval <= 0 {
+			return fmt.Errorf("modules.mempool.poll_interval must be greater than 0")
+		}
+		if Conf.Modules.Mempool.Retention <= 0 {
+			return fmt.Errorf("modules.mempool.retention must be greater than 0")
+		}
+		if Conf.Modules.Mempool.MaxTransactions <= 0 {
+			return fmt.Errorf("modules.mempool.max_transactions must be greater than 0")
+		}
 	}
 
+	// Validate report templates
+	if err := validateReports(); err != nil {
+		return err
+	}
+
+	// Validate alert rules
+	if Conf.Alerts.Enabled {
+		if !Conf.Modules.Starks.Enabled {
+			return fmt.Errorf("alerts require the starks module to be enabled")
+		}
+		for name, rule := range Conf.Alerts.Rules {
+			if err := ValidateAlertRule(name, rule); err != nil {
+				return fmt.Errorf("alerts.rules.%s: %w", name, err)
+			}
+		}
+	}
+
+	// Validate job queue configuration
+	if Conf.Jobs.Enabled {
+		if Conf.Jobs.Workers <= 0 {
+			return fmt.Errorf("jobs.workers must be greater than 0")
+		}
+		if Conf.Jobs.PollInterval <= 0 {
+			return fmt.Errorf("jobs.poll_interval must be greater than 0")
+		}
+		if Conf.Jobs.MaxAttempts <= 0 {
+			return fmt.Errorf("jobs.max_attempts must be greater than 0")
+		}
+		if Conf.Jobs.RetryDelay <= 0 {
+			return fmt.Errorf("jobs.retry_delay must be greater than 0")
+		}
+		if Conf.Jobs.Lease < 2 {
+			return fmt.Errorf("jobs.lease must be at least 2")
+		}
+		if Conf.Jobs.Retention < 0 {
+			return fmt.Errorf("jobs.retention must be 0 or greater")
+		}
+	}
+
+	// Validate Logging configuration
+	validLevels := map[string]bool{"": true, "debug": true, "info": true, "warn": true, "error": true}
+	if !validLevels[strings.ToLower(Conf.Logging.Level)] {
+		return fmt.Errorf("logging.level must be one of: debug, info, warn, error")
+	}
+	validLogModules := map[string]bool{
+		"INDEXER": true, "BLOCKS": true, "TX_GRAPH": true, "TZE_GRAPH": true, "ACCOUNTS": true, "STARKS": true, "MEMPOOL": true,
+	}
+	for module, level := range Conf.Logging.Modules {
+		if !validLogModules[strings.ToUpper(module)] {
+			return fmt.Errorf("logging.modules contains invalid module: %s", module)
+		}
+		if !validLevels[strings.ToLower(level)] || level == "" {
+			return fmt.Errorf("logging.modules.%s must be one of: debug, info, warn, error", module)
+		}
+	}
+
+	return nil
+}
+
+// SchemaNamePattern matches the schema names accepted for database.schema and blue/green switches
+var SchemaNamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
+var (
+	reportNamePattern        = regexp.MustCompile(`^[a-z0-9_-]+$`)
+	reportParamNamePattern   = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+	reportPlaceholderPattern = regexp.MustCompile(`\$([0-9]+)`)
+)
+
+// ValidateAlertRule checks an alert rule's name and the fields its kind requires
+// It is shared by rules from the configuration and rules added through the admin API
+func ValidateAlertRule(name string, rule AlertRuleConfig) error {
+	if len(name) > 64 || !reportNamePattern.MatchString(name) {
+		return fmt.Errorf("name must be 1-64 lowercase letters, digits, _ and -")
+	}
+	if len(rule.VerifierID) > 128 || len(rule.ProgramHash) > 128 {
+		return fmt.Errorf("verifier_id and program_hash must be at most 128 characters")
+	}
+
+	switch rule.Kind {
+	case "proof_size_above", "verifier_balance_below":
+		if rule.Threshold <= 0 {
+			return fmt.Errorf("threshold must be greater than 0")
+		}
+		if rule.ProgramHash != "" || rule.Blocks != 0 {
+			return fmt.Errorf("program_hash and blocks do not apply to %s", rule.Kind)
+		}
+	case "no_fact_for_program":
+		if rule.ProgramHash == "" {
+			return fmt.Errorf("program_hash is required")
+		}
+		if rule.Blocks <= 0 {
+			return fmt.Errorf("blocks must be greater than 0")
+		}
+		if rule.Threshold != 0 || rule.VerifierID != "" {
+			return fmt.Errorf("threshold and verifier_id do not apply to %s", rule.Kind)
+		}
+		if !Conf.Modules.Starks.IndexZtarknet {
+			return fmt.Errorf("no_fact_for_program requires modules.starks.index_ztarknet")
+		}
+	default:
+		return fmt.Errorf("kind must be one of: proof_size_above, verifier_balance_below, no_fact_for_program")
+	}
+	return nil
+}
+
+// validateReports checks each report template and defaults max_rows to api.pagination.max_limit
+func validateReports() error {
+	validTypes := map[string]bool{"int": true, "string": true, "bool": true, "hex": true}
+	for name, report := range Conf.Reports {
+		if !reportNamePattern.MatchString(name) {
+			return fmt.Errorf("reports.%s: name must contain only lowercase letters, digits, _ and -", name)
+		}
+		report.Query = strings.TrimRight(strings.TrimSpace(report.Query), "; \t\n")
+		if report.Query == "" {
+			return fmt.Errorf("reports.%s.query is required", name)
+		}
+		if report.MaxRows < 0 {
+			return fmt.Errorf("reports.%s.max_rows must be non-negative", name)
+		}
+		if report.MaxRows == 0 {
+			report.MaxRows = Conf.Api.Pagination.MaxLimit
+		}
+		if report.Timeout < 0 {
+			return fmt.Errorf("reports.%s.timeout must be non-negative", name)
+		}
+
+		seen := map[string]bool{}
+		for _, param := range report.Params {
+			if !reportParamNamePattern.MatchString(param.Name) || param.Name == "format" {
+				return fmt.Errorf("reports.%s.params: invalid parameter name %q", name, param.Name)
+			}
+			if seen[param.Name] {
+				return fmt.Errorf("reports.%s.params: duplicate parameter %s", name, param.Name)
+			}
+			seen[param.Name] = true
+			if !validTypes[param.Type] {
+				return fmt.Errorf("reports.%s.params.%s.type must be one of: int, string, bool, hex", name, param.Name)
+			}
+		}
+
+		for _, match := range reportPlaceholderPattern.FindAllStringSubmatch(report.Query, -1) {
+			var index int
+			fmt.Sscanf(match[1], "%d", &index)
+			if index < 1 || index > len(report.Params) {
+				return fmt.Errorf("reports.%s.query references $%d but only %d params are declared", name, index, len(report.Params))
+			}
+		}
+
+		Conf.Reports[name] = report
+	}
 	return nil
 }
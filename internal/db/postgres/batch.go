@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Execer runs a statement, satisfied by *pgxpool.Pool, pgx.Tx and the modules' DBTX interfaces
+type Execer interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+}
+
+type batchSender interface {
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+// WriteBatch collects the row writes of a block so they reach the database in one round-trip
+// instead of one per row. Statements keep the order they were queued in, so an output queued
+// before the input spending it is in place when the input's UPDATE runs
+// Writes are upserts rather than COPY so re-indexing and immutable_on_conflict behave as before
+type WriteBatch struct {
+	batch  pgx.Batch
+	labels []string // what each statement does, for errors
+}
+
+// Queue adds a statement; label completes "failed to ..." if it errors, e.g. "store output abc:0"
+func (b *WriteBatch) Queue(label, sql string, args ...interface{}) {
+	b.batch.Queue(sql, args...)
+	b.labels = append(b.labels, label)
+}
+
+// Len returns the number of queued statements
+func (b *WriteBatch) Len() int {
+	return len(b.labels)
+}
+
+// Send runs the queued statements through db, stopping at the first that fails
+// A single statement, or a db that cannot send batches, is executed statement by statement
+func (b *WriteBatch) Send(ctx context.Context, db Execer) error {
+	if b.Len() == 0 {
+		return nil
+	}
+
+	sender, ok := db.(batchSender)
+	if !ok || b.Len() == 1 {
+		for i, query := range b.batch.QueuedQueries {
+			if _, err := db.Exec(ctx, query.SQL, query.Arguments...); err != nil {
+				return fmt.Errorf("failed to %s: %w", b.labels[i], err)
+			}
+		}
+		return nil
+	}
+
+	results := sender.SendBatch(ctx, &b.batch)
+	for _, label := range b.labels {
+		if _, err := results.Exec(); err != nil {
+			results.Close()
+			return fmt.Errorf("failed to %s: %w", label, err)
+		}
+	}
+	if err := results.Close(); err != nil {
+		return fmt.Errorf("failed to send batch of %d statements: %w", b.Len(), err)
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package postgres
+
+import (
+	"sync/atomic"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+)
+
+// skipImmutableConflicts is set by the indexer while it backfills final blocks with
+// database.immutable_on_conflict set to "skip"
+var skipImmutableConflicts atomic.Bool
+
+// SetBackfilling records whether the blocks being indexed are deep enough below the tip that
+// their rows can no longer change, enabling the "skip" conflict mode for immutable tables
+func SetBackfilling(backfilling bool) {
+	skipImmutableConflicts.Store(backfilling && config.Conf.Database.ImmutableOnConflict == "skip")
+}
+
+// OnConflictImmutable returns the conflict action for an insert into a table whose rows never
+// change once written. assignments is the SET list used when rows are updated, and is dropped in
+// favour of DO NOTHING while backfilling in "skip" mode to avoid rewriting identical rows
+// Rows that truly mutate (spent flags, balances) must keep their own DO UPDATE clause
+func OnConflictImmutable(assignments string) string {
+	if skipImmutableConflicts.Load() {
+		return "DO NOTHING"
+	}
+	return "DO UPDATE SET" + assignments
+}
@@ -0,0 +1,245 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CountedTables are the tables whose row counts are maintained in the counters table
+// Counters are only seeded for tables that exist, i.e. for enabled modules
+var CountedTables = []string{
+	"blocks",
+	"transactions", "transaction_outputs", "transaction_inputs",
+	"tze_inputs", "tze_outputs",
+	"accounts", "account_transactions",
+	"verifiers", "stark_proofs", "ztarknet_facts",
+}
+
+// RowScope selects the rows of a table that indexing one block can add or remove
+type RowScope struct {
+	Table  string
+	Column string
+	Keys   any // []string or []int64 matching Column
+}
+
+// initCounters creates the counters table and seeds a counter for every counted table that
+// exists but has none yet, so later per-block deltas start from a real count
+func initCounters() error {
+	_, err := DB.Exec(context.Background(), `
+		CREATE TABLE IF NOT EXISTS counters (
+			table_name VARCHAR(50) PRIMARY KEY,
+			row_count BIGINT NOT NULL DEFAULT 0,
+			reconciled_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create counters schema: %w", err)
+	}
+
+	rows, err := DB.Query(context.Background(), `
+		SELECT t.name
+		FROM unnest($1::TEXT[]) AS t(name)
+		WHERE to_regclass(t.name) IS NOT NULL
+		  AND NOT EXISTS (SELECT 1 FROM counters c WHERE c.table_name = t.name)
+	`, CountedTables)
+	if err != nil {
+		return fmt.Errorf("failed to find unseeded counters: %w", err)
+	}
+	missing, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return fmt.Errorf("failed to find unseeded counters: %w", err)
+	}
+
+	for _, table := range missing {
+		if _, err := reconcileCounter(context.Background(), table); err != nil {
+			return err
+		}
+		log.Printf("Seeded row counter for %s", table)
+	}
+
+	return nil
+}
+
+// countScopes counts the rows in each scope in a single round trip
+func countScopes(ctx context.Context, q Querier, scopes []RowScope) ([]int64, error) {
+	if len(scopes) == 0 {
+		return nil, nil
+	}
+	selects := make([]string, len(scopes))
+	args := make([]any, len(scopes))
+	for i, scope := range scopes {
+		selects[i] = fmt.Sprintf("(SELECT COUNT(*) FROM %s WHERE %s = ANY($%d))", scope.Table, scope.Column, i+1)
+		args[i] = scope.Keys
+	}
+
+	counts := make([]int64, len(scopes))
+	dest := make([]any, len(scopes))
+	for i := range counts {
+		dest[i] = &counts[i]
+	}
+
+	err := q.QueryRow(ctx, "SELECT "+strings.Join(selects, ", "), args...).Scan(dest...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count rows: %w", err)
+	}
+	return counts, nil
+}
+
+// TrackRowCounts runs fn, which writes a block's rows through postgresTx, and adds the change in
+// the number of rows in each scope to the counters in the same transaction
+// Counting before and after keeps counters exact when a block is re-indexed over existing rows
+func TrackRowCounts(ctx context.Context, postgresTx pgx.Tx, scopes []RowScope, fn func() error) error {
+	before, err := countScopes(ctx, postgresTx, scopes)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(); err != nil {
+		return err
+	}
+
+	after, err := countScopes(ctx, postgresTx, scopes)
+	if err != nil {
+		return err
+	}
+
+	deltas := make(map[string]int64)
+	for i, scope := range scopes {
+		deltas[scope.Table] += after[i] - before[i]
+	}
+	return AddRowCounts(ctx, postgresTx, deltas)
+}
+
+// AddRowCounts applies row count deltas to existing counters
+// Tables without a counter are skipped; they are seeded from a real count on the next startup
+func AddRowCounts(ctx context.Context, postgresTx pgx.Tx, deltas map[string]int64) error {
+	tables := make([]string, 0, len(deltas))
+	values := make([]int64, 0, len(deltas))
+	for table, delta := range deltas {
+		if delta != 0 {
+			tables = append(tables, table)
+			values = append(values, delta)
+		}
+	}
+	if len(tables) == 0 {
+		return nil
+	}
+
+	_, err := postgresTx.Exec(ctx, `
+		UPDATE counters c
+		SET row_count = c.row_count + d.delta,
+		    updated_at = CURRENT_TIMESTAMP
+		FROM unnest($1::TEXT[], $2::BIGINT[]) AS d(table_name, delta)
+		WHERE c.table_name = d.table_name
+	`, tables, values)
+	if err != nil {
+		return fmt.Errorf("failed to update row counters: %w", err)
+	}
+	return nil
+}
+
+// CountRows returns the row count of a table from its counter, falling back to COUNT(*)
+// when the table has no counter yet
+func CountRows(ctx context.Context, q Querier, table string) (int64, error) {
+	var count int64
+	err := ReadQuerier(q).QueryRow(ctx, `SELECT row_count FROM counters WHERE table_name = $1`, table).Scan(&count)
+	if err == pgx.ErrNoRows {
+		err = ReadQuerier(q).QueryRow(ctx, "SELECT COUNT(*) FROM "+table).Scan(&count)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to count %s: %w", table, err)
+	}
+	return count, nil
+}
+
+// GetRowCounts returns every maintained counter keyed by table name
+func GetRowCounts(ctx context.Context, q Querier) (map[string]int64, error) {
+	rows, err := ReadQuerier(q).Query(ctx, `SELECT table_name, row_count FROM counters ORDER BY table_name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get row counters: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int64)
+	for rows.Next() {
+		var table string
+		var count int64
+		if err := rows.Scan(&table, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan row counter: %w", err)
+		}
+		counts[table] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read row counters: %w", err)
+	}
+	return counts, nil
+}
+
+// ReconcileCounters recounts every maintained counter and corrects any drift
+func ReconcileCounters(ctx context.Context) error {
+	counts, err := GetRowCounts(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for table, stored := range counts {
+		actual, err := reconcileCounter(ctx, table)
+		if err != nil {
+			return err
+		}
+		if actual != stored {
+			log.Printf("Row counter for %s drifted by %d (counter %d, actual %d), corrected", table, stored-actual, stored, actual)
+		}
+	}
+	return nil
+}
+
+// reconcileCounter replaces a table's counter with its COUNT(*)
+// The counter row is locked before counting, so indexing transactions that commit rows while
+// the count runs block on their counter update and apply it on top of the new value
+func reconcileCounter(ctx context.Context, table string) (int64, error) {
+	tx, err := DB.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Full counts of large tables can exceed the configured statement timeout
+	if err := SetLocalStatementTimeout(ctx, tx, QueryAdminMaintenance); err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO counters (table_name) VALUES ($1) ON CONFLICT (table_name) DO NOTHING
+	`, table)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create counter for %s: %w", table, err)
+	}
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM counters WHERE table_name = $1 FOR UPDATE`, table); err != nil {
+		return 0, fmt.Errorf("failed to lock counter for %s: %w", table, err)
+	}
+
+	var count int64
+	if err := tx.QueryRow(ctx, "SELECT COUNT(*) FROM "+table).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count %s: %w", table, err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE counters
+		SET row_count = $2, reconciled_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE table_name = $1
+	`, table, count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update counter for %s: %w", table, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit counter for %s: %w", table, err)
+	}
+	return count, nil
+}
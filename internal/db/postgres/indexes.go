@@ -0,0 +1,153 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+)
+
+// SecondaryIndex is an index that serves API lookups rather than the indexer itself
+// When database.defer_indexes_until_tip is set, secondary indexes are skipped at startup and
+// built concurrently once the indexer is close to the node tip (see BuildDeferredIndexes)
+type SecondaryIndex struct {
+	Name       string
+	Definition string // CREATE INDEX IF NOT EXISTS statement
+	Required   bool   // queried while indexing blocks, so never deferred
+}
+
+var (
+	deferredIndexesMu sync.Mutex
+	deferredIndexes   []SecondaryIndex
+	buildingIndexes   bool
+)
+
+// IndexDeferralEnabled reports whether secondary indexes are deferred until the indexer nears the tip
+func IndexDeferralEnabled() bool {
+	return config.Conf.Database.DeferIndexesUntilTip > 0
+}
+
+// InitSecondaryIndexes creates the given indexes, or queues them for BuildDeferredIndexes
+// when index deferral is enabled
+// Queued indexes that already exist are no-ops when built, so deferral is safe on an existing database
+func InitSecondaryIndexes(indexes []SecondaryIndex) error {
+	for _, index := range indexes {
+		if IndexDeferralEnabled() && !index.Required {
+			deferIndex(index)
+			continue
+		}
+		if _, err := DB.Exec(context.Background(), index.Definition); err != nil {
+			return fmt.Errorf("failed to create index %s: %w", index.Name, err)
+		}
+	}
+	return nil
+}
+
+func deferIndex(index SecondaryIndex) {
+	deferredIndexesMu.Lock()
+	defer deferredIndexesMu.Unlock()
+	deferredIndexes = append(deferredIndexes, index)
+}
+
+// HasDeferredIndexes reports whether deferred indexes are waiting to be built
+func HasDeferredIndexes() bool {
+	deferredIndexesMu.Lock()
+	defer deferredIndexesMu.Unlock()
+	return len(deferredIndexes) > 0 && !buildingIndexes
+}
+
+// BuildDeferredIndexes builds every deferred index with CREATE INDEX CONCURRENTLY, so indexing
+// and API reads continue while they build. Only one build runs at a time; indexes that fail to
+// build are queued again for the next call
+func BuildDeferredIndexes(ctx context.Context) error {
+	deferredIndexesMu.Lock()
+	if buildingIndexes || len(deferredIndexes) == 0 {
+		deferredIndexesMu.Unlock()
+		return nil
+	}
+	pending := deferredIndexes
+	deferredIndexes = nil
+	buildingIndexes = true
+	deferredIndexesMu.Unlock()
+
+	var failed []SecondaryIndex
+	defer func() {
+		deferredIndexesMu.Lock()
+		deferredIndexes = append(failed, deferredIndexes...)
+		buildingIndexes = false
+		deferredIndexesMu.Unlock()
+	}()
+
+	conn, err := DB.Acquire(ctx)
+	if err != nil {
+		failed = pending
+		return fmt.Errorf("failed to acquire connection for index builds: %w", err)
+	}
+	defer conn.Release()
+
+	// Index builds on large tables can exceed the configured statement timeout
+	resetTimeout, err := SetSessionStatementTimeout(ctx, conn, QueryAdminMaintenance)
+	if err != nil {
+		failed = pending
+		return err
+	}
+	defer resetTimeout()
+
+	log.Printf("Building %d deferred indexes", len(pending))
+	var lastErr error
+	for i, index := range pending {
+		if err := buildIndexConcurrently(ctx, conn, index); err != nil {
+			log.Printf("Failed to build deferred index %s: %v", index.Name, err)
+			failed = append(failed, index)
+			lastErr = err
+			if ctx.Err() != nil {
+				failed = append(failed, pending[i+1:]...)
+				break
+			}
+			continue
+		}
+		log.Printf("Built deferred index %s", index.Name)
+	}
+
+	if lastErr != nil {
+		return fmt.Errorf("failed to build %d deferred indexes: %w", len(failed), lastErr)
+	}
+	log.Println("All deferred indexes built")
+	return nil
+}
+
+// buildIndexConcurrently builds one index without blocking writes to its table
+func buildIndexConcurrently(ctx context.Context, conn *pgxpool.Conn, index SecondaryIndex) error {
+	statement := strings.Replace(index.Definition, "CREATE INDEX IF NOT EXISTS", "CREATE INDEX CONCURRENTLY IF NOT EXISTS", 1)
+	if statement == index.Definition {
+		return fmt.Errorf("index %s is not a CREATE INDEX IF NOT EXISTS statement", index.Name)
+	}
+
+	// An interrupted concurrent build leaves an invalid index behind, which IF NOT EXISTS would keep
+	// to_regclass resolves the name through search_path, so an index of another schema is not matched
+	var valid bool
+	err := conn.QueryRow(ctx, `
+		SELECT i.indisvalid
+		FROM pg_index i
+		WHERE i.indexrelid = to_regclass($1)
+	`, index.Name).Scan(&valid)
+	if err != nil && err != pgx.ErrNoRows {
+		return fmt.Errorf("failed to check index %s: %w", index.Name, err)
+	}
+	if err == nil && !valid {
+		log.Printf("Dropping invalid index %s left by an interrupted build", index.Name)
+		if _, err := conn.Exec(ctx, "DROP INDEX CONCURRENTLY IF EXISTS "+index.Name); err != nil {
+			return fmt.Errorf("failed to drop invalid index %s: %w", index.Name, err)
+		}
+	}
+
+	if _, err := conn.Exec(ctx, statement); err != nil {
+		return fmt.Errorf("failed to build index %s: %w", index.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package postgres
+
+import "sync"
+
+// ManifestKey is a row a module wrote or updated while indexing a block, as recorded in block_manifests
+type ManifestKey struct {
+	Module string
+	Table  string
+	Action string // insert, update or spend
+	RowKey string
+}
+
+var (
+	manifestMu sync.Mutex
+	// manifests holds the keys queued through each transaction that collects a block manifest
+	manifests = make(map[Execer][]ManifestKey)
+)
+
+// CollectManifest starts collecting the manifest keys modules queue through postgresTx
+// Keys queued through any other connection or transaction, e.g. by a module backfill, are dropped
+func CollectManifest(postgresTx Execer) {
+	manifestMu.Lock()
+	defer manifestMu.Unlock()
+	manifests[postgresTx] = nil
+}
+
+// TakeManifest returns the keys queued through postgresTx and stops collecting them
+func TakeManifest(postgresTx Execer) []ManifestKey {
+	manifestMu.Lock()
+	defer manifestMu.Unlock()
+	keys := manifests[postgresTx]
+	delete(manifests, postgresTx)
+	return keys
+}
+
+// QueueManifestKeys queues the keys of rows a module wrote or updated through postgresTx for the
+// block manifest, in the same transaction as the rows themselves
+func QueueManifestKeys(postgresTx Execer, module, table, action string, rowKeys ...string) {
+	manifestMu.Lock()
+	defer manifestMu.Unlock()
+	keys, ok := manifests[postgresTx]
+	if !ok {
+		return
+	}
+	for _, rowKey := range rowKeys {
+		keys = append(keys, ManifestKey{Module: module, Table: table, Action: action, RowKey: rowKey})
+	}
+	manifests[postgresTx] = keys
+}
+
+// MergeManifest moves the keys queued through a released savepoint to its parent transaction
+func MergeManifest(savepoint, postgresTx Execer) {
+	keys := TakeManifest(savepoint)
+	manifestMu.Lock()
+	defer manifestMu.Unlock()
+	if parent, ok := manifests[postgresTx]; ok {
+		manifests[postgresTx] = append(parent, keys...)
+	}
+}
@@ -0,0 +1,33 @@
+package postgres
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/metrics"
+)
+
+// Connection pool statistics, read from the pool on every scrape
+func init() {
+	poolStat := func(register func(string, string, func() float64), name, help string, value func(*pgxpool.Stat) float64) {
+		register(name, help, func() float64 {
+			if DB == nil {
+				return 0
+			}
+			return value(DB.Stat())
+		})
+	}
+
+	poolStat(metrics.NewGaugeFunc, "zindex_db_pool_max_conns", "Maximum size of the database connection pool",
+		func(s *pgxpool.Stat) float64 { return float64(s.MaxConns()) })
+	poolStat(metrics.NewGaugeFunc, "zindex_db_pool_total_conns", "Open database connections",
+		func(s *pgxpool.Stat) float64 { return float64(s.TotalConns()) })
+	poolStat(metrics.NewGaugeFunc, "zindex_db_pool_acquired_conns", "Database connections in use",
+		func(s *pgxpool.Stat) float64 { return float64(s.AcquiredConns()) })
+	poolStat(metrics.NewGaugeFunc, "zindex_db_pool_idle_conns", "Idle database connections",
+		func(s *pgxpool.Stat) float64 { return float64(s.IdleConns()) })
+	poolStat(metrics.NewCounterFunc, "zindex_db_pool_acquires_total", "Connections acquired from the pool",
+		func(s *pgxpool.Stat) float64 { return float64(s.AcquireCount()) })
+	poolStat(metrics.NewCounterFunc, "zindex_db_pool_empty_acquires_total", "Acquires that waited because the pool had no idle connection",
+		func(s *pgxpool.Stat) float64 { return float64(s.EmptyAcquireCount()) })
+	poolStat(metrics.NewCounterFunc, "zindex_db_pool_acquire_wait_seconds_total", "Time spent waiting to acquire connections",
+		func(s *pgxpool.Stat) float64 { return s.AcquireDuration().Seconds() })
+}
@@ -8,12 +8,29 @@ import (
 	"time"
 
 	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/chaos"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
 )
 
 var DB *pgxpool.Pool
 
+// Querier is the read side of a database handle, satisfied by *pgxpool.Pool,
+// pgx.Tx and test doubles
+type Querier interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// ReadQuerier returns q, or the global DB when q is nil
+func ReadQuerier(q Querier) Querier {
+	if q == nil {
+		return DB
+	}
+	return q
+}
+
 // SchemaInitFunc is a function type for module schema initialization
 type SchemaInitFunc func() error
 
@@ -33,6 +50,51 @@ func RegisterCoreSchema(name string, initFunc SchemaInitFunc) {
 	registeredCoreSchemas[name] = initFunc
 }
 
+// RedundantIndex is an index whose lookups are already served by a primary key,
+// unique constraint or composite index with the same leading column
+type RedundantIndex struct {
+	Name       string
+	Definition string // CREATE INDEX IF NOT EXISTS statement
+}
+
+// InitRedundantIndexes creates the given redundant indexes, or drops them when
+// database.drop_redundant_indexes is enabled to cut write amplification during sync
+// Created indexes are deferred like any other secondary index when index deferral is enabled
+func InitRedundantIndexes(indexes []RedundantIndex) error {
+	for _, index := range indexes {
+		if !config.Conf.Database.DropRedundantIndexes {
+			err := InitSecondaryIndexes([]SecondaryIndex{{Name: index.Name, Definition: index.Definition}})
+			if err != nil {
+				return fmt.Errorf("failed to apply redundant index %s: %w", index.Name, err)
+			}
+			continue
+		}
+		if _, err := DB.Exec(context.Background(), "DROP INDEX IF EXISTS "+index.Name); err != nil {
+			return fmt.Errorf("failed to apply redundant index %s: %w", index.Name, err)
+		}
+	}
+	return nil
+}
+
+// BulkChangeHook is called after a bulk delete or load with the reason, the affected
+// tables (nil means all tables) and the number of rows touched
+type BulkChangeHook func(reason string, tables []string, rows int64)
+
+// registeredBulkChangeHooks holds the hooks notified after bulk deletes and loads
+var registeredBulkChangeHooks []BulkChangeHook
+
+// RegisterBulkChangeHook registers a hook notified after bulk deletes and loads
+func RegisterBulkChangeHook(hook BulkChangeHook) {
+	registeredBulkChangeHooks = append(registeredBulkChangeHooks, hook)
+}
+
+// NotifyBulkChange notifies all registered hooks of a bulk delete or load
+func NotifyBulkChange(reason string, tables []string, rows int64) {
+	for _, hook := range registeredBulkChangeHooks {
+		hook(reason, tables, rows)
+	}
+}
+
 func InitPostgres() error {
 	if !config.ShouldConnectPostgres() {
 		log.Println("PostgreSQL connection disabled in config")
@@ -55,6 +117,11 @@ func InitPostgres() error {
 		return fmt.Errorf("failed to parse database config: %w", err)
 	}
 
+	// Keep zindex tables in database.schema, e.g. the green schema of a blue/green upgrade
+	if cfg.Schema != "" {
+		poolConfig.ConnConfig.RuntimeParams["search_path"] = cfg.Schema
+	}
+
 	// Configure connection pool settings
 	poolConfig.MaxConns = int32(cfg.MaxConnections)
 	poolConfig.MinConns = int32(cfg.MaxIdleConnections)
@@ -83,6 +150,13 @@ func InitPostgres() error {
 	DB = pool
 	log.Println("PostgreSQL connected successfully")
 
+	if cfg.Schema != "" {
+		if _, err := DB.Exec(ctx, "CREATE SCHEMA IF NOT EXISTS "+pgx.Identifier{cfg.Schema}.Sanitize()); err != nil {
+			return fmt.Errorf("failed to create schema %s: %w", cfg.Schema, err)
+		}
+		log.Printf("Using database schema %s", cfg.Schema)
+	}
+
 	if err := initSchema(); err != nil {
 		return fmt.Errorf("failed to initialize schema: %w", err)
 	}
@@ -90,6 +164,14 @@ func InitPostgres() error {
 	return nil
 }
 
+// Schema returns the schema holding zindex tables, database.schema or public when it is unset
+func Schema() string {
+	if config.Conf.Database.Schema != "" {
+		return config.Conf.Database.Schema
+	}
+	return "public"
+}
+
 func ClosePostgres() {
 	if DB != nil {
 		log.Println("Closing PostgreSQL connection...")
@@ -141,6 +223,11 @@ func initSchema() error {
 		return fmt.Errorf("failed to initialize module schemas: %w", err)
 	}
 
+	// Row counters are seeded from the tables created above
+	if err := initCounters(); err != nil {
+		return fmt.Errorf("failed to initialize row counters: %w", err)
+	}
+
 	return nil
 }
 
@@ -183,6 +270,16 @@ func GetLastIndexedBlock() (int64, error) {
 	return lastBlock, nil
 }
 
+// GetFinalizedHeight returns the highest block height buried at least indexer.finality_depth
+// blocks below the last indexed block. A negative value means no block is final yet.
+func GetFinalizedHeight() (int64, error) {
+	lastBlock, err := GetLastIndexedBlock()
+	if err != nil {
+		return 0, err
+	}
+	return lastBlock - int64(config.Conf.Indexer.FinalityDepth), nil
+}
+
 // GetLastIndexedHash returns the hash of the last indexed block
 func GetLastIndexedHash() (string, error) {
 	var hash string
@@ -206,7 +303,7 @@ func GetBlockHashAtHeight(height int64) (string, error) {
 // RollbackToHeight removes all data after the specified height
 // This handles all module tables in the correct order to maintain referential integrity
 func RollbackToHeight(ctx context.Context, rollbackHeight int64) error {
-	tx, err := DB.Begin(ctx)
+	tx, err := BeginWithTimeout(ctx, QueryIndexerWrite)
 	if err != nil {
 		return fmt.Errorf("failed to begin rollback transaction: %w", err)
 	}
@@ -214,8 +311,40 @@ func RollbackToHeight(ctx context.Context, rollbackHeight int64) error {
 
 	log.Printf("Starting rollback to height %d", rollbackHeight)
 
-	// Step 1: Unspend transaction outputs that were spent after rollback height
+	// Rows touched across all tables, reported to bulk change hooks after commit
+	var rowsAffected int64
+
+	// Rows removed per counted table, subtracted from the row counters before commit
+	removed := make(map[string]int64)
+
+	// Step 1: Replay the block manifests of removed blocks into the change feed before their rows are deleted
+	// Entries are ordered from the tip downwards so consumers can undo them in cursor order
 	result, err := tx.Exec(ctx, `
+		INSERT INTO change_feed (change_type, block_height, block_hash, verifier_id, txid)
+		SELECT CASE table_name WHEN 'ztarknet_facts' THEN 'fact_removed' ELSE 'block_removed' END,
+		       block_height, block_hash,
+		       CASE table_name WHEN 'ztarknet_facts' THEN split_part(row_key, '/', 1) END,
+		       CASE table_name WHEN 'ztarknet_facts' THEN split_part(row_key, '/', 2) END
+		FROM block_manifests
+		WHERE block_height > $1 AND table_name IN ('ztarknet_facts', 'blocks')
+		ORDER BY block_height DESC, (table_name = 'blocks'), row_key
+	`, rollbackHeight)
+	if err != nil {
+		return fmt.Errorf("failed to record removals in change feed: %w", err)
+	}
+	log.Printf("Recorded %d change feed removals", result.RowsAffected())
+
+	result, err = tx.Exec(ctx, `
+		DELETE FROM block_manifests WHERE block_height > $1
+	`, rollbackHeight)
+	if err != nil {
+		return fmt.Errorf("failed to delete block manifests: %w", err)
+	}
+	log.Printf("Deleted %d block manifest entries", result.RowsAffected())
+	rowsAffected += result.RowsAffected()
+
+	// Step 2: Unspend transaction outputs that were spent after rollback height
+	result, err = tx.Exec(ctx, `
 		UPDATE transaction_outputs
 		SET spent_by_txid = NULL,
 		    spent_by_vin = NULL,
@@ -226,8 +355,9 @@ func RollbackToHeight(ctx context.Context, rollbackHeight int64) error {
 		return fmt.Errorf("failed to unspend transaction outputs: %w", err)
 	}
 	log.Printf("Unspent %d transaction outputs", result.RowsAffected())
+	rowsAffected += result.RowsAffected()
 
-	// Step 2: Unspend TZE outputs that were spent after rollback height
+	// Step 3: Unspend TZE outputs that were spent after rollback height
 	result, err = tx.Exec(ctx, `
 		UPDATE tze_outputs
 		SET spent_by_txid = NULL,
@@ -239,8 +369,9 @@ func RollbackToHeight(ctx context.Context, rollbackHeight int64) error {
 		return fmt.Errorf("failed to unspend TZE outputs: %w", err)
 	}
 	log.Printf("Unspent %d TZE outputs", result.RowsAffected())
+	rowsAffected += result.RowsAffected()
 
-	// Step 3: Recalculate account balances for affected accounts
+	// Step 4: Recalculate account balances for affected accounts
 	result, err = tx.Exec(ctx, `
 		UPDATE accounts a
 		SET balance = COALESCE((
@@ -259,8 +390,9 @@ func RollbackToHeight(ctx context.Context, rollbackHeight int64) error {
 		return fmt.Errorf("failed to recalculate account balances: %w", err)
 	}
 	log.Printf("Recalculated %d account balances", result.RowsAffected())
+	rowsAffected += result.RowsAffected()
 
-	// Step 4: Delete account transactions after rollback height
+	// Step 5: Delete account transactions after rollback height
 	result, err = tx.Exec(ctx, `
 		DELETE FROM account_transactions WHERE block_height > $1
 	`, rollbackHeight)
@@ -268,8 +400,10 @@ func RollbackToHeight(ctx context.Context, rollbackHeight int64) error {
 		return fmt.Errorf("failed to delete account transactions: %w", err)
 	}
 	log.Printf("Deleted %d account transactions", result.RowsAffected())
+	rowsAffected += result.RowsAffected()
+	removed["account_transactions"] -= result.RowsAffected()
 
-	// Step 5: Delete orphaned accounts (accounts with no remaining transactions)
+	// Step 6: Delete orphaned accounts (accounts with no remaining transactions)
 	result, err = tx.Exec(ctx, `
 		DELETE FROM accounts
 		WHERE address NOT IN (
@@ -280,8 +414,10 @@ func RollbackToHeight(ctx context.Context, rollbackHeight int64) error {
 		return fmt.Errorf("failed to delete orphaned accounts: %w", err)
 	}
 	log.Printf("Deleted %d orphaned accounts", result.RowsAffected())
+	rowsAffected += result.RowsAffected()
+	removed["accounts"] -= result.RowsAffected()
 
-	// Step 6: Delete TZE inputs for transactions after rollback height
+	// Step 7: Delete TZE inputs for transactions after rollback height
 	result, err = tx.Exec(ctx, `
 		DELETE FROM tze_inputs WHERE txid IN (
 			SELECT txid FROM transactions WHERE block_height > $1
@@ -291,8 +427,10 @@ func RollbackToHeight(ctx context.Context, rollbackHeight int64) error {
 		return fmt.Errorf("failed to delete TZE inputs: %w", err)
 	}
 	log.Printf("Deleted %d TZE inputs", result.RowsAffected())
+	rowsAffected += result.RowsAffected()
+	removed["tze_inputs"] -= result.RowsAffected()
 
-	// Step 7: Delete TZE outputs for transactions after rollback height
+	// Step 8: Delete TZE outputs for transactions after rollback height
 	result, err = tx.Exec(ctx, `
 		DELETE FROM tze_outputs WHERE txid IN (
 			SELECT txid FROM transactions WHERE block_height > $1
@@ -302,8 +440,23 @@ func RollbackToHeight(ctx context.Context, rollbackHeight int64) error {
 		return fmt.Errorf("failed to delete TZE outputs: %w", err)
 	}
 	log.Printf("Deleted %d TZE outputs", result.RowsAffected())
+	rowsAffected += result.RowsAffected()
+	removed["tze_outputs"] -= result.RowsAffected()
+
+	// Count the inputs and outputs that step 9 removes through CASCADE, for the row counters
+	var cascadedOutputs, cascadedInputs int64
+	err = tx.QueryRow(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM transaction_outputs o JOIN transactions t ON t.txid = o.txid WHERE t.block_height > $1),
+			(SELECT COUNT(*) FROM transaction_inputs i JOIN transactions t ON t.txid = i.txid WHERE t.block_height > $1)
+	`, rollbackHeight).Scan(&cascadedOutputs, &cascadedInputs)
+	if err != nil {
+		return fmt.Errorf("failed to count transaction inputs and outputs: %w", err)
+	}
+	removed["transaction_outputs"] -= cascadedOutputs
+	removed["transaction_inputs"] -= cascadedInputs
 
-	// Step 8: Delete transactions after rollback height (CASCADE deletes inputs/outputs)
+	// Step 9: Delete transactions after rollback height (CASCADE deletes inputs/outputs/input addresses)
 	result, err = tx.Exec(ctx, `
 		DELETE FROM transactions WHERE block_height > $1
 	`, rollbackHeight)
@@ -311,8 +464,10 @@ func RollbackToHeight(ctx context.Context, rollbackHeight int64) error {
 		return fmt.Errorf("failed to delete transactions: %w", err)
 	}
 	log.Printf("Deleted %d transactions", result.RowsAffected())
+	rowsAffected += result.RowsAffected()
+	removed["transactions"] -= result.RowsAffected()
 
-	// Step 9: Delete STARK proofs after rollback height
+	// Step 10: Delete STARK proofs after rollback height
 	result, err = tx.Exec(ctx, `
 		DELETE FROM stark_proofs WHERE block_height > $1
 	`, rollbackHeight)
@@ -320,8 +475,10 @@ func RollbackToHeight(ctx context.Context, rollbackHeight int64) error {
 		return fmt.Errorf("failed to delete STARK proofs: %w", err)
 	}
 	log.Printf("Deleted %d STARK proofs", result.RowsAffected())
+	rowsAffected += result.RowsAffected()
+	removed["stark_proofs"] -= result.RowsAffected()
 
-	// Step 10: Delete Ztarknet facts after rollback height
+	// Step 11: Delete Ztarknet facts after rollback height
 	result, err = tx.Exec(ctx, `
 		DELETE FROM ztarknet_facts WHERE block_height > $1
 	`, rollbackHeight)
@@ -329,8 +486,10 @@ func RollbackToHeight(ctx context.Context, rollbackHeight int64) error {
 		return fmt.Errorf("failed to delete Ztarknet facts: %w", err)
 	}
 	log.Printf("Deleted %d Ztarknet facts", result.RowsAffected())
+	rowsAffected += result.RowsAffected()
+	removed["ztarknet_facts"] -= result.RowsAffected()
 
-	// Step 11: Delete orphaned verifiers (verifiers with no remaining proofs/facts)
+	// Step 12: Delete orphaned verifiers (verifiers with no remaining proofs/facts)
 	result, err = tx.Exec(ctx, `
 		DELETE FROM verifiers
 		WHERE verifier_id NOT IN (
@@ -343,8 +502,30 @@ func RollbackToHeight(ctx context.Context, rollbackHeight int64) error {
 		return fmt.Errorf("failed to delete orphaned verifiers: %w", err)
 	}
 	log.Printf("Deleted %d orphaned verifiers", result.RowsAffected())
+	rowsAffected += result.RowsAffected()
+	removed["verifiers"] -= result.RowsAffected()
+
+	// Step 13: Delete UTXO snapshots after rollback height
+	result, err = tx.Exec(ctx, `
+		DELETE FROM utxo_snapshots WHERE block_height > $1
+	`, rollbackHeight)
+	if err != nil {
+		return fmt.Errorf("failed to delete UTXO snapshots: %w", err)
+	}
+	log.Printf("Deleted %d UTXO snapshots", result.RowsAffected())
+	rowsAffected += result.RowsAffected()
+
+	// Step 14: Delete retry queue entries for transactions after rollback height
+	result, err = tx.Exec(ctx, `
+		DELETE FROM failed_items WHERE block_height > $1
+	`, rollbackHeight)
+	if err != nil {
+		return fmt.Errorf("failed to delete failed items: %w", err)
+	}
+	log.Printf("Deleted %d failed items", result.RowsAffected())
+	rowsAffected += result.RowsAffected()
 
-	// Step 12: Delete blocks after rollback height
+	// Step 15: Delete blocks after rollback height
 	result, err = tx.Exec(ctx, `
 		DELETE FROM blocks WHERE height > $1
 	`, rollbackHeight)
@@ -352,8 +533,10 @@ func RollbackToHeight(ctx context.Context, rollbackHeight int64) error {
 		return fmt.Errorf("failed to delete blocks: %w", err)
 	}
 	log.Printf("Deleted %d blocks", result.RowsAffected())
+	rowsAffected += result.RowsAffected()
+	removed["blocks"] -= result.RowsAffected()
 
-	// Step 13: Update indexer state to rollback height
+	// Step 16: Update indexer state to rollback height
 	_, err = tx.Exec(ctx, `
 		UPDATE indexer_state
 		SET last_indexed_block = $1,
@@ -365,17 +548,85 @@ func RollbackToHeight(ctx context.Context, rollbackHeight int64) error {
 		return fmt.Errorf("failed to update indexer state: %w", err)
 	}
 
+	if err := AddRowCounts(ctx, tx, removed); err != nil {
+		return err
+	}
+
+	if err := chaos.DBError("rollback"); err != nil {
+		return err
+	}
+
 	// Commit the transaction
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit rollback transaction: %w", err)
 	}
+	chaos.KillPoint("rollback")
 
 	log.Printf("Successfully rolled back to height %d", rollbackHeight)
+
+	NotifyBulkChange("rollback", rollbackTables(), rowsAffected)
 	return nil
 }
 
-func UpdateLastIndexedBlock(height int64, hash string) error {
-	_, err := DB.Exec(
+// rollbackCounts counts the rows RollbackToHeight deletes from each table, for dry runs
+// Rows removed through CASCADE are counted with the table they belong to
+var rollbackCounts = []struct {
+	table string
+	query string
+}{
+	{"blocks", `SELECT COUNT(*) FROM blocks WHERE height > $1`},
+	{"block_manifests", `SELECT COUNT(*) FROM block_manifests WHERE block_height > $1`},
+	{"transactions", `SELECT COUNT(*) FROM transactions WHERE block_height > $1`},
+	{"transaction_inputs", `SELECT COUNT(*) FROM transaction_inputs i JOIN transactions t ON t.txid = i.txid WHERE t.block_height > $1`},
+	{"transaction_outputs", `SELECT COUNT(*) FROM transaction_outputs o JOIN transactions t ON t.txid = o.txid WHERE t.block_height > $1`},
+	{"tze_inputs", `SELECT COUNT(*) FROM tze_inputs WHERE txid IN (SELECT txid FROM transactions WHERE block_height > $1)`},
+	{"tze_outputs", `SELECT COUNT(*) FROM tze_outputs WHERE txid IN (SELECT txid FROM transactions WHERE block_height > $1)`},
+	{"account_transactions", `SELECT COUNT(*) FROM account_transactions WHERE block_height > $1`},
+	{"accounts", `SELECT COUNT(*) FROM accounts a
+		WHERE NOT EXISTS (SELECT 1 FROM account_transactions at WHERE at.address = a.address AND at.block_height <= $1)`},
+	{"stark_proofs", `SELECT COUNT(*) FROM stark_proofs WHERE block_height > $1`},
+	{"ztarknet_facts", `SELECT COUNT(*) FROM ztarknet_facts WHERE block_height > $1`},
+	{"verifiers", `SELECT COUNT(*) FROM verifiers v
+		WHERE NOT EXISTS (SELECT 1 FROM stark_proofs p WHERE p.verifier_id = v.verifier_id AND p.block_height <= $1)
+		  AND NOT EXISTS (SELECT 1 FROM ztarknet_facts f WHERE f.verifier_id = v.verifier_id AND f.block_height <= $1)`},
+	{"utxo_snapshots", `SELECT COUNT(*) FROM utxo_snapshots WHERE block_height > $1`},
+	{"failed_items", `SELECT COUNT(*) FROM failed_items WHERE block_height > $1`},
+}
+
+// rollbackTables lists the tables RollbackToHeight writes, including the change feed it appends to
+// and the tables emptied through CASCADE
+func rollbackTables() []string {
+	tables := []string{"change_feed", "inputs_addresses", "fact_segments", "fact_anomalies"}
+	for _, c := range rollbackCounts {
+		tables = append(tables, c.table)
+	}
+	return tables
+}
+
+// CountRollbackRows returns how many rows RollbackToHeight would delete from each table, without deleting them
+func CountRollbackRows(ctx context.Context, rollbackHeight int64) (map[string]int64, error) {
+	tx, err := DB.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin rollback count transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	counts := make(map[string]int64, len(rollbackCounts))
+	for _, c := range rollbackCounts {
+		var count int64
+		if err := tx.QueryRow(ctx, c.query, rollbackHeight).Scan(&count); err != nil {
+			return nil, fmt.Errorf("failed to count %s rows above height %d: %w", c.table, rollbackHeight, err)
+		}
+		counts[c.table] = count
+	}
+
+	return counts, nil
+}
+
+// UpdateLastIndexedBlock advances the indexer state inside the block's transaction, so the state
+// can never trail or lead the rows committed for the block
+func UpdateLastIndexedBlock(postgresTx pgx.Tx, height int64, hash string) error {
+	_, err := postgresTx.Exec(
 		context.Background(),
 		"UPDATE indexer_state SET last_indexed_block = $1, last_indexed_hash = $2, updated_at = CURRENT_TIMESTAMP WHERE id = 1",
 		height, hash,
@@ -396,9 +647,24 @@ func UpdateLastIndexedBlock(height int64, hash string) error {
 //	Returns:
 //	  []RowType - Slice of RowType structs with the query result.
 //	  error - Error if the query fails.
+//
+// Deprecated: use PostgresQueryCtx, which takes a context and a querier.
 func PostgresQuery[RowType any](query string, args ...interface{}) ([]RowType, error) {
+	return PostgresQueryCtx[RowType](context.Background(), nil, query, args...)
+}
+
+// Same as PostgresQuery, but only returns the first row.
+//
+// Deprecated: use PostgresQueryOneCtx, which takes a context and a querier.
+func PostgresQueryOne[RowType any](query string, args ...interface{}) (*RowType, error) {
+	return PostgresQueryOneCtx[RowType](context.Background(), nil, query, args...)
+}
+
+// PostgresQueryCtx runs query against q (nil uses the global DB) under ctx and scans
+// every row into RowType.
+func PostgresQueryCtx[RowType any](ctx context.Context, q Querier, query string, args ...interface{}) ([]RowType, error) {
 	var result []RowType
-	err := pgxscan.Select(context.Background(), DB, &result, query, args...)
+	err := pgxscan.Select(ctx, ReadQuerier(q), &result, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -406,10 +672,10 @@ func PostgresQuery[RowType any](query string, args ...interface{}) ([]RowType, e
 	return result, nil
 }
 
-// Same as PostgresQuery, but only returns the first row.
-func PostgresQueryOne[RowType any](query string, args ...interface{}) (*RowType, error) {
+// Same as PostgresQueryCtx, but only returns the first row.
+func PostgresQueryOneCtx[RowType any](ctx context.Context, q Querier, query string, args ...interface{}) (*RowType, error) {
 	var result RowType
-	err := pgxscan.Get(context.Background(), DB, &result, query, args...)
+	err := pgxscan.Get(ctx, ReadQuerier(q), &result, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,176 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+)
+
+// QueryClass groups queries that share a statement timeout (database.statement_timeouts)
+type QueryClass string
+
+const (
+	QueryAPIRead          QueryClass = "api_read"          // module lookups served by the API
+	QueryIndexerWrite     QueryClass = "indexer_write"     // block indexing and reorg rollbacks
+	QueryAdminMaintenance QueryClass = "admin_maintenance" // ANALYZE/VACUUM, counter reconciliation and index builds
+)
+
+// StatementTimeout returns the statement timeout of a query class in milliseconds (0 = no limit)
+// api_read and indexer_write fall back to database.statement_timeout when unset
+func StatementTimeout(class QueryClass) int {
+	timeouts := config.Conf.Database.StatementTimeouts
+	seconds := config.Conf.Database.StatementTimeout
+	switch class {
+	case QueryAPIRead:
+		if timeouts.APIRead > 0 {
+			seconds = timeouts.APIRead
+		}
+	case QueryIndexerWrite:
+		if timeouts.IndexerWrite > 0 {
+			seconds = timeouts.IndexerWrite
+		}
+	case QueryAdminMaintenance:
+		seconds = timeouts.AdminMaintenance
+	}
+	return seconds * 1000
+}
+
+// SetLocalStatementTimeout applies the statement timeout of class to the rest of postgresTx
+// Nothing is sent when it matches the connection's database.statement_timeout
+func SetLocalStatementTimeout(ctx context.Context, postgresTx pgx.Tx, class QueryClass) error {
+	timeout := StatementTimeout(class)
+	if timeout == config.Conf.Database.StatementTimeout*1000 {
+		return nil
+	}
+	if _, err := postgresTx.Exec(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", timeout)); err != nil {
+		return fmt.Errorf("failed to set %s statement timeout: %w", class, err)
+	}
+	return nil
+}
+
+// SetSessionStatementTimeout applies the statement timeout of class to a connection outside a
+// transaction, e.g. for VACUUM or CREATE INDEX CONCURRENTLY; the returned func resets it
+func SetSessionStatementTimeout(ctx context.Context, conn Execer, class QueryClass) (func(), error) {
+	if _, err := conn.Exec(ctx, fmt.Sprintf("SET statement_timeout = %d", StatementTimeout(class))); err != nil {
+		return func() {}, fmt.Errorf("failed to set %s statement timeout: %w", class, err)
+	}
+	return func() { conn.Exec(context.Background(), "RESET statement_timeout") }, nil
+}
+
+// BeginWithTimeout begins a transaction on the global DB whose statements run under the
+// statement timeout of class
+func BeginWithTimeout(ctx context.Context, class QueryClass) (pgx.Tx, error) {
+	return BeginTxWithTimeout(ctx, pgx.TxOptions{}, class)
+}
+
+// BeginTxWithTimeout is BeginWithTimeout with transaction options, such as an isolation level
+func BeginTxWithTimeout(ctx context.Context, txOptions pgx.TxOptions, class QueryClass) (pgx.Tx, error) {
+	postgresTx, err := DB.BeginTx(ctx, txOptions)
+	if err != nil {
+		return nil, err
+	}
+	if err := SetLocalStatementTimeout(ctx, postgresTx, class); err != nil {
+		postgresTx.Rollback(ctx)
+		return nil, err
+	}
+	return postgresTx, nil
+}
+
+// timeoutQuerier runs each query in its own read-only transaction under the statement timeout
+// of a query class, so lookups can be held to a tighter (or looser) limit than the pool default
+type timeoutQuerier struct {
+	class QueryClass
+}
+
+// NewTimeoutQuerier returns a Querier on the global DB whose queries run under the statement
+// timeout of class, for use with the modules' SetReadDB
+// Each query costs a BEGIN and ROLLBACK round-trip on top of the query itself
+func NewTimeoutQuerier(class QueryClass) Querier {
+	return &timeoutQuerier{class: class}
+}
+
+func (q *timeoutQuerier) begin(ctx context.Context) (pgx.Tx, error) {
+	postgresTx, err := DB.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin read transaction: %w", err)
+	}
+	if err := SetLocalStatementTimeout(ctx, postgresTx, q.class); err != nil {
+		postgresTx.Rollback(ctx)
+		return nil, err
+	}
+	return postgresTx, nil
+}
+
+func (q *timeoutQuerier) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	postgresTx, err := q.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := postgresTx.Query(ctx, sql, args...)
+	if err != nil {
+		postgresTx.Rollback(ctx)
+		return nil, err
+	}
+	return &timeoutRows{Rows: rows, ctx: ctx, tx: postgresTx}, nil
+}
+
+func (q *timeoutQuerier) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	postgresTx, err := q.begin(ctx)
+	if err != nil {
+		return errRow{err: err}
+	}
+	return &timeoutRow{row: postgresTx.QueryRow(ctx, sql, args...), ctx: ctx, tx: postgresTx}
+}
+
+// timeoutRows ends its transaction once the rows are read or closed
+type timeoutRows struct {
+	pgx.Rows
+	ctx  context.Context
+	tx   pgx.Tx
+	done bool
+}
+
+func (r *timeoutRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.finish()
+	return false
+}
+
+func (r *timeoutRows) Close() {
+	r.Rows.Close()
+	r.finish()
+}
+
+func (r *timeoutRows) finish() {
+	if r.done {
+		return
+	}
+	r.done = true
+	r.Rows.Close()
+	r.tx.Rollback(r.ctx)
+}
+
+// timeoutRow ends its transaction once the row is scanned
+type timeoutRow struct {
+	row pgx.Row
+	ctx context.Context
+	tx  pgx.Tx
+}
+
+func (r *timeoutRow) Scan(dest ...any) error {
+	defer r.tx.Rollback(r.ctx)
+	return r.row.Scan(dest...)
+}
+
+// errRow is a pgx.Row that fails to scan with err
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(dest ...any) error {
+	return r.err
+}
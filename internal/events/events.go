@@ -0,0 +1,60 @@
+package events
+
+import (
+	"log"
+	"sync"
+)
+
+// Subscription receives every published event on C until it is closed
+// C is closed when the subscriber is dropped for falling behind or unsubscribes
+type Subscription struct {
+	C chan Event
+}
+
+var (
+	mu          sync.Mutex
+	subscribers = map[*Subscription]struct{}{}
+)
+
+// Subscribe registers a subscriber with room for buffer pending events
+func Subscribe(buffer int) *Subscription {
+	sub := &Subscription{C: make(chan Event, buffer)}
+
+	mu.Lock()
+	defer mu.Unlock()
+	subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Unsubscribe removes a subscriber and closes its channel; calling it again is a no-op
+func Unsubscribe(sub *Subscription) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := subscribers[sub]; ok {
+		delete(subscribers, sub)
+		close(sub.C)
+	}
+}
+
+// HasSubscribers reports whether anyone is listening, so the indexer can skip building events
+func HasSubscribers() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return len(subscribers) > 0
+}
+
+// Publish delivers an event to every subscriber without blocking the indexer
+// A subscriber whose buffer is full is dropped rather than given a gap in its stream
+func Publish(event Event) {
+	mu.Lock()
+	defer mu.Unlock()
+	for sub := range subscribers {
+		select {
+		case sub.C <- event:
+		default:
+			log.Printf("Dropping event subscriber: %d events pending", len(sub.C))
+			delete(subscribers, sub)
+			close(sub.C)
+		}
+	}
+}
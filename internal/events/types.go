@@ -0,0 +1,51 @@
+package events
+
+// Topics events are published under
+const (
+	TopicBlocks      = "blocks"       // every indexed block
+	TopicTzeOutputs  = "tze_outputs"  // TZE outputs created in an indexed block (TZE_GRAPH)
+	TopicStarkProofs = "stark_proofs" // STARK proofs submitted in an indexed block (STARKS)
+	TopicFacts       = "facts"        // Ztarknet facts proven in an indexed block (STARKS with index_ztarknet)
+	TopicReorgs      = "reorgs"       // chain reorganizations; events at or above the height are void
+	TopicAlerts      = "alerts"       // alert rules firing or resolving (alerts.enabled)
+	TopicAnomalies   = "anomalies"    // facts not matching the expected program hashes (STARKS with expected hashes)
+	TopicWedges      = "wedges"       // the indexing loop wedging and recovering (indexer.wedge_timeout)
+)
+
+// Topics lists every topic clients can subscribe to
+var Topics = []string{TopicBlocks, TopicTzeOutputs, TopicStarkProofs, TopicFacts, TopicReorgs, TopicAlerts, TopicAnomalies, TopicWedges}
+
+// Event is a notification published by the indexer once a block is committed
+// Blocks publish one event for the block and one per TZE output, STARK proof and fact it added
+type Event struct {
+	Topic       string      `json:"topic"`
+	BlockHeight int64       `json:"block_height"` // for reorgs, the height indexing restarts from
+	BlockHash   string      `json:"block_hash,omitempty"`
+	VerifierID  string      `json:"verifier_id,omitempty"` // stark_proofs, facts and anomalies only
+	Data        interface{} `json:"data"`
+}
+
+// BlockEvent is the data of a blocks event
+type BlockEvent struct {
+	Height     int64  `json:"height"`
+	Hash       string `json:"hash"`
+	PrevHash   string `json:"prev_hash"`
+	Timestamp  int64  `json:"timestamp"`
+	TxCount    int    `json:"tx_count"`
+	TzeTxCount int    `json:"tze_tx_count"`
+}
+
+// ReorgEvent is the data of a reorgs event
+type ReorgEvent struct {
+	FromHeight int64 `json:"from_height"` // last height indexed before the reorg
+	NewHeight  int64 `json:"new_height"`  // height indexing restarts from
+}
+
+// WedgeEvent is the data of a wedges event
+type WedgeEvent struct {
+	Wedged         bool    `json:"wedged"`          // false once the loop recovered
+	Height         int64   `json:"height"`          // block the loop is stuck on, or the first block indexed again
+	ChainHeight    int64   `json:"chain_height"`    // node tip when the wedge was detected
+	StalledSeconds float64 `json:"stalled_seconds"` // time without progress
+	LastError      string  `json:"last_error,omitempty"`
+}
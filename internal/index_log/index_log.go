@@ -0,0 +1,91 @@
+package index_log
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+)
+
+// Stages at which an entry can be recorded
+const (
+	StageValidate = "validate"
+	StageParse    = "parse"
+	StageMerkle   = "merkle"
+)
+
+func init() {
+	// Register this as a core schema (always initialized)
+	postgres.RegisterCoreSchema("index_log", InitSchema)
+}
+
+// InitSchema creates the index_log table
+// Entries are history, so they are kept across rollbacks and re-indexing
+func InitSchema() error {
+	schema := `
+		CREATE TABLE IF NOT EXISTS index_log (
+			id BIGSERIAL PRIMARY KEY,
+			block_height BIGINT NOT NULL,
+			block_hash VARCHAR(64) NOT NULL,
+			stage VARCHAR(20) NOT NULL,
+			message TEXT NOT NULL,
+			rpc_source VARCHAR(255),  -- redacted RPC endpoint that served the payload
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		-- Columns added after the initial schema
+		ALTER TABLE index_log ADD COLUMN IF NOT EXISTS rpc_source VARCHAR(255);
+
+		CREATE INDEX IF NOT EXISTS idx_index_log_block_height ON index_log(block_height);
+	`
+
+	_, err := postgres.DB.Exec(context.Background(), schema)
+	if err != nil {
+		return fmt.Errorf("failed to create index_log schema: %w", err)
+	}
+
+	return nil
+}
+
+// Record appends an entry for a block
+// It writes outside any block transaction so the entry survives the block failing
+func Record(height int64, hash string, rpcSource string, stage string, message string) error {
+	_, err := postgres.DB.Exec(context.Background(), `
+		INSERT INTO index_log (block_height, block_hash, stage, message, rpc_source)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+	`, height, hash, stage, message, rpcSource)
+	if err != nil {
+		return fmt.Errorf("failed to record index log entry for block %d: %w", height, err)
+	}
+	return nil
+}
+
+// GetEntries retrieves entries, optionally filtered by stage, newest first
+func GetEntries(stage string, limit, offset int) ([]Entry, error) {
+	entries, err := postgres.PostgresQueryCtx[Entry](
+		context.Background(), nil,
+		`SELECT id, block_height, block_hash, stage, message, rpc_source, created_at
+		 FROM index_log
+		 WHERE $1 = '' OR stage = $1
+		 ORDER BY id DESC
+		 LIMIT $2 OFFSET $3`,
+		stage, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get index log entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// CountEntries returns the number of entries, optionally filtered by stage
+func CountEntries(stage string) (int64, error) {
+	var count int64
+	err := postgres.DB.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM index_log WHERE $1 = '' OR stage = $1`, stage,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count index log entries: %w", err)
+	}
+	return count, nil
+}
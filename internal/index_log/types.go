@@ -0,0 +1,14 @@
+package index_log
+
+import "time"
+
+// Entry is a problem the indexer hit with a block, such as an RPC payload that failed validation
+type Entry struct {
+	ID          int64     `json:"id" db:"id"`
+	BlockHeight int64     `json:"block_height" db:"block_height"`
+	BlockHash   string    `json:"block_hash" db:"block_hash"`
+	Stage       string    `json:"stage" db:"stage"` // validate, parse, merkle
+	Message     string    `json:"message" db:"message"`
+	RpcSource   *string   `json:"rpc_source" db:"rpc_source"` // RPC endpoint that served the payload
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,177 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/accounts"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/changefeed"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/starks"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/tx_graph"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/types"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/tze_graph"
+)
+
+// backfillModule is a module that can be indexed on its own over blocks the indexer already stored
+type backfillModule struct {
+	index func(postgresTx pgx.Tx, block *types.ZcashBlock) error
+	// indexed reports whether the module already has rows for the block, which is then skipped
+	indexed func(ctx context.Context, block *types.ZcashBlock) (bool, error)
+	// requires lists the modules whose rows for the same block this one reads while indexing
+	requires []string
+}
+
+var backfillModules = map[string]backfillModule{
+	"TX_GRAPH": {
+		index: tx_graph.IndexTxGraph,
+		indexed: func(ctx context.Context, block *types.ZcashBlock) (bool, error) {
+			return rowsExist(ctx, `SELECT EXISTS (SELECT 1 FROM transactions WHERE block_height = $1)`, block.Height)
+		},
+	},
+	"ACCOUNTS": {
+		index: accounts.IndexAccounts,
+		indexed: func(ctx context.Context, block *types.ZcashBlock) (bool, error) {
+			return rowsExist(ctx, `SELECT EXISTS (SELECT 1 FROM account_transactions WHERE block_height = $1)`, block.Height)
+		},
+		// Senders are debited from the input addresses tx_graph resolved
+		requires: []string{"TX_GRAPH"},
+	},
+	"TZE_GRAPH": {
+		index: tze_graph.IndexTzeGraph,
+		indexed: func(ctx context.Context, block *types.ZcashBlock) (bool, error) {
+			return rowsExist(ctx,
+				`SELECT EXISTS (SELECT 1 FROM tze_outputs WHERE txid = ANY($1))
+				     OR EXISTS (SELECT 1 FROM tze_inputs WHERE txid = ANY($1))`,
+				blockTxids(block))
+		},
+	},
+	"STARKS": {
+		index: starks.IndexStarks,
+		indexed: func(ctx context.Context, block *types.ZcashBlock) (bool, error) {
+			return rowsExist(ctx,
+				`SELECT EXISTS (SELECT 1 FROM stark_proofs WHERE block_height = $1)
+				     OR EXISTS (SELECT 1 FROM ztarknet_facts WHERE block_height = $1)`,
+				block.Height)
+		},
+	},
+}
+
+func rowsExist(ctx context.Context, query string, arg any) (bool, error) {
+	var exists bool
+	if err := postgres.DB.QueryRow(ctx, query, arg).Scan(&exists); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+func blockTxids(block *types.ZcashBlock) []string {
+	txids := make([]string, len(block.Tx))
+	for i, tx := range block.Tx {
+		txids[i] = tx.TxID
+	}
+	return txids
+}
+
+// BackfillModules lists the modules BackfillModule can index on their own, sorted by name
+func BackfillModules() []string {
+	modules := make([]string, 0, len(backfillModules))
+	for module := range backfillModules {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+	return modules
+}
+
+// ValidateBackfillModule checks that a module can be backfilled with the current configuration:
+// it must be enabled, and so must the modules whose rows it reads
+func ValidateBackfillModule(module string) error {
+	m, ok := backfillModules[module]
+	if !ok {
+		return fmt.Errorf("module %s cannot be backfilled, expected one of %v", module, BackfillModules())
+	}
+	if !config.IsModuleEnabled(module) {
+		return fmt.Errorf("module %s is disabled in the configuration", module)
+	}
+	for _, required := range m.requires {
+		if !config.IsModuleEnabled(required) {
+			return fmt.Errorf("module %s reads %s rows, enable %s as well", module, required, required)
+		}
+	}
+	return nil
+}
+
+// BackfillModule fetches an already indexed block again and indexes it in a single module
+// The rows it writes join the block's manifest and new Ztarknet facts the change feed, in the same
+// transaction, so a rollback removes them; the blocks table, the other modules' tables and the indexer
+// state are left untouched. Returns false when the module already has rows for the block, which is skipped
+func BackfillModule(module string, height int64, rpcClient RpcClient) (bool, error) {
+	m, ok := backfillModules[module]
+	if !ok {
+		return false, fmt.Errorf("module %s cannot be backfilled", module)
+	}
+
+	blockHash, err := rpcClient.GetBlockHash(height)
+	if err != nil {
+		return false, fmt.Errorf("failed to get block hash for height %d: %w", height, err)
+	}
+
+	// Only blocks on the indexed chain are backfilled; after a reorg the indexer has to catch up first
+	storedHash, err := postgres.GetBlockHashAtHeight(height)
+	if err != nil {
+		return false, err
+	}
+	if storedHash != blockHash {
+		return false, fmt.Errorf("block %d is %s on the node but %s in the database, let the indexer handle the reorg first",
+			height, blockHash, storedHash)
+	}
+
+	rawBlock, err := rpcClient.GetBlock(blockHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to get block %s: %w", blockHash, err)
+	}
+	if err := validateRawBlock(rawBlock); err != nil {
+		return false, fmt.Errorf("failed to validate block %d: %w", height, err)
+	}
+	block, err := parseBlock(rawBlock)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse block %d: %w", height, err)
+	}
+	if block.Hash != blockHash || block.Height != height {
+		return false, fmt.Errorf("node returned block %d %s for block %d %s", block.Height, block.Hash, height, blockHash)
+	}
+
+	ctx := context.Background()
+	indexed, err := m.indexed(ctx, block)
+	if err != nil {
+		return false, fmt.Errorf("failed to check %s rows of block %d: %w", module, height, err)
+	}
+	if indexed {
+		return false, nil
+	}
+
+	postgresTx, err := postgres.BeginWithTimeout(ctx, postgres.QueryIndexerWrite)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin database transaction for block %d: %w", height, err)
+	}
+	defer postgresTx.Rollback(ctx)
+	postgres.CollectManifest(postgresTx)
+	defer postgres.TakeManifest(postgresTx)
+
+	block.Parse()
+	if err := indexModule(strings.ToLower(module), func() error { return m.index(postgresTx, block) }); err != nil {
+		return false, fmt.Errorf("failed to backfill block %d: %w", height, err)
+	}
+	if err := changefeed.RecordLateRows(postgresTx, height); err != nil {
+		return false, fmt.Errorf("failed to record backfilled rows of block %d: %w", height, err)
+	}
+	if err := postgresTx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit database transaction for block %d: %w", height, err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,515 @@
+//go:build chaos
+
+package indexer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/chaos"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+)
+
+// These tests fail or kill each step of indexing a block in a child process, through the hooks of
+// the chaos package, then check that recovering leaves the database as a run without faults does
+// They index a synthetic chain into the zindex_chaos_test schema, dropped before each test, of the
+// database configured by ZINDEX_TEST_CONFIG (relative to the repository root); without it they are
+// skipped. Faults are set per child process, so ZINDEX_CHAOS_* must not be set:
+//
+//	ZINDEX_TEST_CONFIG=configs/config.yaml go test -tags chaos ./internal/indexer/
+
+const (
+	chaosTestSchema = "zindex_chaos_test"
+	chaosTestTip    = 5                        // height of the last block of the synthetic chain
+	chaosStepEnv    = "ZINDEX_CHAOS_TEST_STEP" // set in child processes to the step they run
+
+	chaosSubsidy     = 625000000
+	chaosFee         = 10000
+	chaosGenesisTime = 1700000000
+)
+
+// chaosPoints are the steps of indexing a block that can fail or be killed, in the order they run
+var chaosPoints = []string{"blocks", "tx_graph", "accounts", "tze_graph", "starks", "changefeed", "state", "commit"}
+
+// chaosFaults maps each fault to its environment variable and the exit status of a faulted step
+var chaosFaults = []struct {
+	name     string
+	env      string
+	exitCode int
+}{
+	{"db_error", "ZINDEX_CHAOS_DB_ERROR", 1},
+	{"kill", "ZINDEX_CHAOS_KILL", chaos.KillExitCode},
+}
+
+func TestMain(m *testing.M) {
+	if step := os.Getenv(chaosStepEnv); step != "" {
+		os.Exit(runChaosStep(step))
+	}
+	os.Exit(m.Run())
+}
+
+// TestChaosIndexFaults fails and kills every step of indexing the tip block, then restarts as the
+// indexer does: interrupted blocks are rolled back and the tip is indexed again
+func TestChaosIndexFaults(t *testing.T) {
+	reference := chaosReference(t)
+	for _, point := range chaosPoints {
+		for _, fault := range chaosFaults {
+			t.Run(point+"/"+fault.name, func(t *testing.T) {
+				chain := resetChaosDB(t)
+				indexChaosChain(t, chain, 0, chaosTestTip-1)
+
+				runChaosChild(t, fmt.Sprintf("index:%d", chaosTestTip), fault.env, point, fault.exitCode)
+
+				// Only a kill after the commit leaves the block indexed
+				committed := fault.name == "kill" && point == "commit"
+				recoverChaosDB(t)
+				if committed {
+					compareChaosState(t, "after restart", readChaosState(t), reference[chaosTestTip])
+					return
+				}
+				compareChaosState(t, "after restart", readChaosState(t), reference[chaosTestTip-1])
+
+				indexChaosChain(t, chain, chaosTestTip, chaosTestTip)
+				compareChaosState(t, "after indexing the tip again", readChaosState(t), reference[chaosTestTip])
+			})
+		}
+	}
+}
+
+// TestChaosRollbackFaults fails and kills a rollback, then indexes the rolled back blocks again
+func TestChaosRollbackFaults(t *testing.T) {
+	reference := chaosReference(t)
+	const rollbackHeight = chaosTestTip - 2
+
+	chain := resetChaosDB(t)
+	indexChaosChain(t, chain, 0, chaosTestTip)
+	step := fmt.Sprintf("rollback:%d", rollbackHeight)
+
+	// A failed rollback leaves every block in place
+	runChaosChild(t, step, "ZINDEX_CHAOS_DB_ERROR", "rollback", 1)
+	compareChaosState(t, "after failed rollback", readChaosState(t), reference[chaosTestTip])
+	if removed := countChaosRemovals(t); removed != 0 {
+		t.Fatalf("failed rollback left %d block_removed entries in the change feed, want 0", removed)
+	}
+
+	// A kill after the rollback's commit leaves it applied
+	runChaosChild(t, step, "ZINDEX_CHAOS_KILL", "rollback", chaos.KillExitCode)
+	recoverChaosDB(t)
+	compareChaosState(t, "after rollback", readChaosState(t), reference[rollbackHeight])
+	if removed := countChaosRemovals(t); removed != chaosTestTip-rollbackHeight {
+		t.Fatalf("rollback appended %d block_removed entries to the change feed, want %d", removed, chaosTestTip-rollbackHeight)
+	}
+
+	indexChaosChain(t, chain, rollbackHeight+1, chaosTestTip)
+	compareChaosState(t, "after indexing the rolled back blocks again", readChaosState(t), reference[chaosTestTip])
+}
+
+// TestChaosRecoverInterruptedBlocks rolls back a block committed above the last indexed block, as
+// left by versions that updated indexer_state after the block's own commit
+func TestChaosRecoverInterruptedBlocks(t *testing.T) {
+	reference := chaosReference(t)
+
+	chain := resetChaosDB(t)
+	indexChaosChain(t, chain, 0, chaosTestTip)
+	_, err := postgres.DB.Exec(context.Background(), `
+		UPDATE indexer_state
+		SET last_indexed_block = $1, last_indexed_hash = (SELECT hash FROM blocks WHERE height = $1)
+		WHERE id = 1
+	`, chaosTestTip-1)
+	if err != nil {
+		t.Fatalf("failed to move indexer state back: %v", err)
+	}
+
+	recoverChaosDB(t)
+	compareChaosState(t, "after recovery", readChaosState(t), reference[chaosTestTip-1])
+
+	indexChaosChain(t, chain, chaosTestTip, chaosTestTip)
+	compareChaosState(t, "after indexing the tip again", readChaosState(t), reference[chaosTestTip])
+}
+
+// chaosReferenceStates holds the state after each block of the synthetic chain indexed without faults
+var chaosReferenceStates []chaosState
+
+func chaosReference(t *testing.T) []chaosState {
+	t.Helper()
+	if chaosReferenceStates != nil {
+		return chaosReferenceStates
+	}
+	chain := resetChaosDB(t)
+	states := make([]chaosState, 0, chaosTestTip+1)
+	for height := int64(0); height <= chaosTestTip; height++ {
+		indexChaosChain(t, chain, height, height)
+		states = append(states, readChaosState(t))
+	}
+	chaosReferenceStates = states
+	return states
+}
+
+// loadChaosConfig loads the ZINDEX_TEST_CONFIG config with every indexing module enabled, and
+// reports false when it is not set
+func loadChaosConfig() bool {
+	path := os.Getenv("ZINDEX_TEST_CONFIG")
+	if path == "" {
+		return false
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join("..", "..", path)
+	}
+	config.InitConfig(path)
+	config.Conf.Database.Schema = chaosTestSchema
+	config.Conf.Indexer.MerkleCheck = "reject"
+	config.Conf.Modules.TxGraph.Enabled = true
+	config.Conf.Modules.Accounts.Enabled = true
+	config.Conf.Modules.TzeGraph.Enabled = true
+	config.Conf.Modules.Starks.Enabled = true
+	config.Conf.Modules.Mempool.Enabled = false
+	return true
+}
+
+// resetChaosDB drops the test schema and creates it again, and returns the chain to index into it
+func resetChaosDB(t *testing.T) *chaosChain {
+	t.Helper()
+	if !loadChaosConfig() {
+		t.Skip("ZINDEX_TEST_CONFIG is not set")
+	}
+	for _, env := range os.Environ() {
+		if strings.HasPrefix(env, "ZINDEX_CHAOS_") {
+			t.Fatalf("%s is set; the chaos tests set faults in their child processes only", strings.SplitN(env, "=", 2)[0])
+		}
+	}
+
+	if postgres.DB == nil {
+		if err := postgres.InitPostgres(); err != nil {
+			t.Fatalf("failed to connect to the test database: %v", err)
+		}
+	}
+	if _, err := postgres.DB.Exec(context.Background(), "DROP SCHEMA IF EXISTS "+pgx.Identifier{chaosTestSchema}.Sanitize()+" CASCADE"); err != nil {
+		t.Fatalf("failed to drop schema %s: %v", chaosTestSchema, err)
+	}
+	postgres.ClosePostgres()
+	if err := postgres.InitPostgres(); err != nil {
+		t.Fatalf("failed to initialize schema %s: %v", chaosTestSchema, err)
+	}
+	return newChaosChain()
+}
+
+// recoverChaosDB does what Start does before resuming: roll back blocks above the last indexed block
+func recoverChaosDB(t *testing.T) {
+	t.Helper()
+	lastBlock, err := postgres.GetLastIndexedBlock()
+	if err != nil {
+		t.Fatalf("failed to get last indexed block: %v", err)
+	}
+	if err := recoverInterruptedBlocks(lastBlock); err != nil {
+		t.Fatalf("failed to recover interrupted blocks: %v", err)
+	}
+}
+
+func indexChaosChain(t *testing.T, chain *chaosChain, fromHeight, toHeight int64) {
+	t.Helper()
+	for height := fromHeight; height <= toHeight; height++ {
+		if err := IndexBlock(height, chain); err != nil {
+			t.Fatalf("failed to index block %d: %v", height, err)
+		}
+	}
+}
+
+// runChaosChild runs a step in a child process with fault env firing at point, and checks its exit status
+func runChaosChild(t *testing.T, step, env, point string, wantExit int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(),
+		chaosStepEnv+"="+step,
+		env+"=1",
+		"ZINDEX_CHAOS_POINTS="+point,
+		"ZINDEX_CHAOS_SEED=1",
+	)
+	output, err := cmd.CombinedOutput()
+	exitCode := 0
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	} else if err != nil {
+		t.Fatalf("failed to run %s: %v", step, err)
+	}
+	if exitCode != wantExit {
+		t.Fatalf("%s with %s at %s exited with %d, want %d\n%s", step, env, point, exitCode, wantExit, output)
+	}
+}
+
+// runChaosStep runs the step of a child process, index:<height> or rollback:<height>, and returns
+// its exit status; faults exit from inside the step
+func runChaosStep(step string) int {
+	if !loadChaosConfig() {
+		log.Printf("ZINDEX_TEST_CONFIG is not set")
+		return 2
+	}
+	if err := postgres.InitPostgres(); err != nil {
+		log.Printf("Failed to initialize PostgreSQL: %v", err)
+		return 2
+	}
+	defer postgres.ClosePostgres()
+
+	action, arg, _ := strings.Cut(step, ":")
+	height, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		log.Printf("Invalid step %q: %v", step, err)
+		return 2
+	}
+	switch action {
+	case "index":
+		err = IndexBlock(height, newChaosChain())
+	case "rollback":
+		err = postgres.RollbackToHeight(context.Background(), height)
+	default:
+		err = fmt.Errorf("unknown step %q", step)
+	}
+	if err != nil {
+		log.Printf("Step %s failed: %v", step, err)
+		return 1
+	}
+	return 0
+}
+
+// chaosState is what indexing the synthetic chain leaves in the database, without timestamps or
+// change feed cursors
+type chaosState struct {
+	LastIndexed    int64
+	Blocks         []string         // height/hash
+	Balances       map[string]int64 // by address
+	AccountTxs     []string         // address/txid/type/balance_change
+	Unspent        []string         // txid:vout
+	Counters       map[string]int64 // by table
+	Feed           []string         // change feed entries left once removals cancel what they remove
+	BlockManifests []string
+}
+
+func readChaosState(t *testing.T) chaosState {
+	t.Helper()
+	lastIndexed, err := postgres.GetLastIndexedBlock()
+	if err != nil {
+		t.Fatalf("failed to get last indexed block: %v", err)
+	}
+	state := chaosState{
+		LastIndexed: lastIndexed,
+		Blocks:      queryChaosStrings(t, `SELECT height || '/' || hash FROM blocks ORDER BY height`),
+		Balances:    make(map[string]int64),
+		AccountTxs: queryChaosStrings(t, `
+			SELECT address || '/' || txid || '/' || type || '/' || balance_change
+			FROM account_transactions ORDER BY 1`),
+		Unspent: queryChaosStrings(t, `
+			SELECT txid || ':' || vout FROM transaction_outputs WHERE spent_by_txid IS NULL ORDER BY 1`),
+		Counters: make(map[string]int64),
+		BlockManifests: queryChaosStrings(t, `
+			SELECT block_height || '/' || block_hash || '/' || module || '/' || table_name || '/' || action || '/' || row_key
+			FROM block_manifests ORDER BY 1`),
+	}
+
+	for _, balance := range queryChaosStrings(t, `SELECT address || '/' || balance FROM accounts`) {
+		address, value, _ := strings.Cut(balance, "/")
+		state.Balances[address], _ = strconv.ParseInt(value, 10, 64)
+	}
+
+	// Counters must match the tables they count, whatever was interrupted
+	for _, counter := range queryChaosStrings(t, `SELECT table_name || '/' || row_count FROM counters`) {
+		table, value, _ := strings.Cut(counter, "/")
+		rowCount, _ := strconv.ParseInt(value, 10, 64)
+		var actual int64
+		if err := postgres.DB.QueryRow(context.Background(), "SELECT COUNT(*) FROM "+pgx.Identifier{table}.Sanitize()).Scan(&actual); err != nil {
+			t.Fatalf("failed to count rows of %s: %v", table, err)
+		}
+		if rowCount != actual {
+			t.Errorf("counter for %s is %d, the table has %d rows", table, rowCount, actual)
+		}
+		state.Counters[table] = rowCount
+	}
+
+	// Replay the change feed as a consumer does, a removal cancelling the entry it removes
+	entries := queryChaosStrings(t, `
+		SELECT change_type || ' ' || block_height || '/' || COALESCE(block_hash, '') || '/' ||
+		       COALESCE(verifier_id, '') || '/' || COALESCE(txid, '')
+		FROM change_feed ORDER BY id`)
+	for _, entry := range entries {
+		changeType, key, _ := strings.Cut(entry, " ")
+		if !strings.HasSuffix(changeType, "_removed") {
+			state.Feed = append(state.Feed, entry)
+			continue
+		}
+		added := strings.TrimSuffix(changeType, "_removed") + "_added " + key
+		i := len(state.Feed) - 1
+		for i >= 0 && state.Feed[i] != added {
+			i--
+		}
+		if i < 0 {
+			t.Errorf("change feed entry %q removes an entry that was never added", entry)
+			continue
+		}
+		state.Feed = append(state.Feed[:i], state.Feed[i+1:]...)
+	}
+	return state
+}
+
+func compareChaosState(t *testing.T, when string, got, want chaosState) {
+	t.Helper()
+	gotValue, wantValue := reflect.ValueOf(got), reflect.ValueOf(want)
+	for i := 0; i < gotValue.NumField(); i++ {
+		if !reflect.DeepEqual(gotValue.Field(i).Interface(), wantValue.Field(i).Interface()) {
+			t.Errorf("%s: %s is %v, want %v", when, gotValue.Type().Field(i).Name,
+				gotValue.Field(i).Interface(), wantValue.Field(i).Interface())
+		}
+	}
+}
+
+func countChaosRemovals(t *testing.T) int64 {
+	t.Helper()
+	var count int64
+	err := postgres.DB.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM change_feed WHERE change_type = 'block_removed'`).Scan(&count)
+	if err != nil {
+		t.Fatalf("failed to count block_removed entries: %v", err)
+	}
+	return count
+}
+
+func queryChaosStrings(t *testing.T, query string) []string {
+	t.Helper()
+	rows, err := postgres.DB.Query(context.Background(), query)
+	if err != nil {
+		t.Fatalf("failed to query %q: %v", query, err)
+	}
+	values, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		t.Fatalf("failed to query %q: %v", query, err)
+	}
+	return values
+}
+
+// chaosChain is an RpcClient serving a synthetic chain: every block's coinbase pays a miner, and
+// from block 1 a transaction splits the previous block's coinbase between two addresses
+type chaosChain struct {
+	hashes []string
+	blocks map[string]map[string]interface{}
+}
+
+func newChaosChain() *chaosChain {
+	chain := &chaosChain{blocks: make(map[string]map[string]interface{})}
+	for height := int64(0); height <= chaosTestTip; height++ {
+		txs := []interface{}{chaosCoinbase(height)}
+		txids := []string{chaosHash("coinbase", height)}
+		if height > 0 {
+			txs = append(txs, chaosSpend(height))
+			txids = append(txids, chaosHash("spend", height))
+		}
+		root, err := merkleRoot(txids)
+		if err != nil {
+			panic(err)
+		}
+
+		hash := chaosHash("block", height)
+		block := map[string]interface{}{
+			"hash":       hash,
+			"height":     float64(height),
+			"version":    float64(4),
+			"size":       float64(1000),
+			"merkleroot": root,
+			"time":       float64(chaosGenesisTime + height*75),
+			"bits":       "200f0f0f",
+			"difficulty": float64(1),
+			"tx":         txs,
+		}
+		if height > 0 {
+			block["previousblockhash"] = chain.hashes[height-1]
+		}
+		chain.hashes = append(chain.hashes, hash)
+		chain.blocks[hash] = block
+	}
+	return chain
+}
+
+func (c *chaosChain) GetBlockHash(height int64) (string, error) {
+	if height < 0 || height >= int64(len(c.hashes)) {
+		return "", fmt.Errorf("block height %d out of range", height)
+	}
+	return c.hashes[height], nil
+}
+
+func (c *chaosChain) GetBlock(hash string) (map[string]interface{}, error) {
+	block, ok := c.blocks[hash]
+	if !ok {
+		return nil, fmt.Errorf("block %s not found", hash)
+	}
+	return block, nil
+}
+
+func (c *chaosChain) GetBlockCount() (int64, error) { return int64(len(c.hashes)) - 1, nil }
+
+func (c *chaosChain) BlockSource(hash string) string { return "chaos-test" }
+
+func (c *chaosChain) PrefetchBlocks(fromHeight, toHeight int64) {}
+
+func chaosHash(kind string, height int64) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s-%d", kind, height)))
+	return hex.EncodeToString(sum[:])
+}
+
+func chaosCoinbase(height int64) map[string]interface{} {
+	return map[string]interface{}{
+		"txid":     chaosHash("coinbase", height),
+		"version":  float64(4),
+		"locktime": float64(0),
+		"vin": []interface{}{
+			map[string]interface{}{"coinbase": fmt.Sprintf("%02x", height+1), "sequence": float64(4294967295)},
+		},
+		"vout": []interface{}{chaosOutput(0, chaosSubsidy, "tmChaosMiner")},
+	}
+}
+
+func chaosSpend(height int64) map[string]interface{} {
+	alice := int64(chaosSubsidy/2 + height)
+	return map[string]interface{}{
+		"txid":     chaosHash("spend", height),
+		"version":  float64(4),
+		"locktime": float64(0),
+		"vin": []interface{}{
+			map[string]interface{}{
+				"txid":      chaosHash("coinbase", height-1),
+				"vout":      float64(0),
+				"scriptSig": map[string]interface{}{"asm": "", "hex": ""},
+				"sequence":  float64(4294967295),
+			},
+		},
+		"vout": []interface{}{
+			chaosOutput(0, alice, "tmChaosAlice"),
+			chaosOutput(1, chaosSubsidy-alice-chaosFee, "tmChaosBob"),
+		},
+	}
+}
+
+// chaosOutput is a P2PKH output to address, whose script hashes the address
+func chaosOutput(n int, valueZat int64, address string) map[string]interface{} {
+	pubKeyHash := sha256.Sum256([]byte(address))
+	return map[string]interface{}{
+		"n":        float64(n),
+		"value":    float64(valueZat) / 1e8,
+		"valueZat": float64(valueZat),
+		"scriptPubKey": map[string]interface{}{
+			"asm":       "",
+			"hex":       "76a914" + hex.EncodeToString(pubKeyHash[:20]) + "88ac",
+			"type":      "pubkeyhash",
+			"addresses": []interface{}{address},
+		},
+	}
+}
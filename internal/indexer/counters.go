@@ -0,0 +1,50 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/jobs"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/logging"
+)
+
+// KindReconcileCounters is the periodic job that recounts the row counters when jobs.enabled is set
+const KindReconcileCounters = "reconcile_counters"
+
+func init() {
+	jobs.RegisterPeriodic(KindReconcileCounters, counterReconcileInterval, func(ctx context.Context, _ json.RawMessage) (any, error) {
+		return nil, postgres.ReconcileCounters(ctx)
+	})
+}
+
+func counterReconcileInterval() time.Duration {
+	return time.Duration(config.Conf.Indexer.CounterReconcileInterval) * time.Minute
+}
+
+// runCounterReconciler periodically recounts the row counters maintained during indexing
+// Counters are exact for indexing and rollbacks; this corrects drift from writes made outside
+// the indexing loop, such as retried items or manual SQL
+func runCounterReconciler() {
+	// The job queue runs the reconcile_counters job instead, once across every instance
+	interval := counterReconcileInterval()
+	if interval <= 0 || jobs.Enabled() {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopChan:
+			return
+		case <-ticker.C:
+			if err := postgres.ReconcileCounters(context.Background()); err != nil {
+				logging.Errorf(logging.ModuleIndexer, "Failed to reconcile row counters: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package indexer
+
+import (
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/events"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/logging"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/starks"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/types"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/tze_graph"
+)
+
+// publishBlockEvents notifies event subscribers of a committed block and the rows it added
+// Module rows are read back from the database, so nothing is built while nobody is subscribed;
+// a failed read is logged and skipped, since events are a convenience on top of the API
+func publishBlockEvents(block *types.ZcashBlock) {
+	if !events.HasSubscribers() {
+		return
+	}
+
+	tzeTxCount := 0
+	for _, tx := range block.Tx {
+		if tx.IsTZETransaction() {
+			tzeTxCount++
+		}
+	}
+	events.Publish(events.Event{
+		Topic:       events.TopicBlocks,
+		BlockHeight: block.Height,
+		BlockHash:   block.Hash,
+		Data: events.BlockEvent{
+			Height:     block.Height,
+			Hash:       block.Hash,
+			PrevHash:   block.PreviousBlockHash,
+			Timestamp:  block.Time,
+			TxCount:    len(block.Tx),
+			TzeTxCount: tzeTxCount,
+		},
+	})
+
+	if config.IsModuleEnabled("TZE_GRAPH") {
+		for _, tx := range block.Tx {
+			if !tx.HasTZEOutputs() {
+				continue
+			}
+			outputs, err := tze_graph.GetTzeOutputs(tx.TxID)
+			if err != nil {
+				logging.Errorf(logging.ModuleIndexer, "Failed to read TZE outputs of %s for events: %v", tx.TxID, err)
+				continue
+			}
+			for _, output := range outputs {
+				publishBlockEvent(block, events.TopicTzeOutputs, "", output)
+			}
+		}
+	}
+
+	if config.IsModuleEnabled("STARKS") {
+		proofs, err := starks.GetStarkProofsByBlock(block.Height, "")
+		if err != nil {
+			logging.Errorf(logging.ModuleIndexer, "Failed to read STARK proofs of block %d for events: %v", block.Height, err)
+		}
+		for _, proof := range proofs {
+			publishBlockEvent(block, events.TopicStarkProofs, proof.VerifierID, proof)
+		}
+	}
+
+	if starks.ShouldIndexZtarknet() {
+		facts, err := starks.GetZtarknetFactsByBlock(block.Height, "")
+		if err != nil {
+			logging.Errorf(logging.ModuleIndexer, "Failed to read Ztarknet facts of block %d for events: %v", block.Height, err)
+		}
+		for _, fact := range facts {
+			publishBlockEvent(block, events.TopicFacts, fact.VerifierID, fact)
+		}
+	}
+
+	if starks.ShouldIndexZtarknet() && starks.ExpectedProgramsConfigured() {
+		anomalies, err := starks.GetFactAnomaliesByBlock(block.Height)
+		if err != nil {
+			logging.Errorf(logging.ModuleIndexer, "Failed to read fact anomalies of block %d for events: %v", block.Height, err)
+		}
+		for _, anomaly := range anomalies {
+			publishBlockEvent(block, events.TopicAnomalies, anomaly.VerifierID, anomaly)
+		}
+	}
+}
+
+func publishBlockEvent(block *types.ZcashBlock, topic, verifierID string, data interface{}) {
+	events.Publish(events.Event{
+		Topic:       topic,
+		BlockHeight: block.Height,
+		BlockHash:   block.Hash,
+		VerifierID:  verifierID,
+		Data:        data,
+	})
+}
+
+// publishReorgEvent tells subscribers that events from newHeight up were rolled back
+func publishReorgEvent(fromHeight, newHeight int64) {
+	events.Publish(events.Event{
+		Topic:       events.TopicReorgs,
+		BlockHeight: newHeight,
+		Data:        events.ReorgEvent{FromHeight: fromHeight, NewHeight: newHeight},
+	})
+}
@@ -7,11 +7,17 @@ import (
 	"log"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/accounts"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/blocks"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/changefeed"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/chaos"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/index_log"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/logging"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/reorg"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/snapshots"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/starks"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/tx_graph"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/types"
@@ -23,22 +29,31 @@ type RpcClient interface {
 	GetBlockHash(height int64) (string, error)
 	GetBlock(hash string) (map[string]interface{}, error)
 	GetBlockCount() (int64, error)
+	// BlockSource returns the RPC endpoint that served a fetched block, for provenance
+	BlockSource(hash string) string
+	// PrefetchBlocks fetches the blocks of a height range ahead of IndexBlock in as few round trips
+	// as the node allows; blocks it could not fetch are fetched one by one as before
+	PrefetchBlocks(fromHeight, toHeight int64)
 }
 
 const (
 	// maxIndexRetries is the maximum number of times to retry indexing a block after rollback
 	maxIndexRetries = 3
+	// stopTimeout bounds how long Stop waits for the block being indexed
+	stopTimeout = 30 * time.Second
 )
 
 var (
 	stopChan     chan struct{}
 	errorChannel chan error
+	// loopDone is closed once the indexing loop has returned, so shutdown can wait for the block in flight
+	loopDone chan struct{}
 )
 
 // IndexBlock fetches and indexes a single block at the specified height
 // This is the main entry point for indexing a block and coordinates all module indexing
 func IndexBlock(height int64, rpcClient RpcClient) error {
-	log.Printf("Indexing block at height %d", height)
+	logging.Blockf(logging.ModuleIndexer, "Indexing block at height %d", height)
 
 	// Fetch block hash
 	blockHash, err := rpcClient.GetBlockHash(height)
@@ -51,12 +66,34 @@ func IndexBlock(height int64, rpcClient RpcClient) error {
 	if err != nil {
 		return fmt.Errorf("failed to get block %s: %w", blockHash, err)
 	}
+	rpcSource := rpcClient.BlockSource(blockHash)
+
+	// Validate the payload before parsing so node-version incompatibilities fail loudly
+	if err := validateRawBlock(rawBlock); err != nil {
+		recordIndexLog(height, blockHash, rpcSource, index_log.StageValidate, err)
+		return fmt.Errorf("failed to validate block %d: %w", height, err)
+	}
 
 	// Parse block into ZcashBlock structure
 	block, err := parseBlock(rawBlock)
 	if err != nil {
+		recordIndexLog(height, blockHash, rpcSource, index_log.StageParse, err)
 		return fmt.Errorf("failed to parse block %d: %w", height, err)
 	}
+	if block.Hash != blockHash {
+		err := fmt.Errorf("block hash mismatch: requested %s, got %s", blockHash, block.Hash)
+		recordIndexLog(height, blockHash, rpcSource, index_log.StageValidate, err)
+		return err
+	}
+
+	// Recompute the merkle root so a body that does not match its header is caught before it is stored
+	if err := checkMerkleRoot(block); err != nil {
+		recordIndexLog(height, blockHash, rpcSource, index_log.StageMerkle, err)
+		if config.Conf.Indexer.MerkleCheck == "reject" {
+			return fmt.Errorf("failed to verify block %d: %w", height, err)
+		}
+		logging.Warnf(logging.ModuleIndexer, "Indexing block %d despite merkle mismatch (indexer.merkle_check: flag): %v", height, err)
+	}
 
 	// Verify block height matches expected height
 	if block.Height != height {
@@ -70,18 +107,77 @@ func IndexBlock(height int64, rpcClient RpcClient) error {
 		return err // This may be a ReorgError which will be handled by the indexing loop
 	}
 
-	// Index block data in each enabled module
+	// Index block data in each enabled module inside one database transaction, so a module
+	// failing leaves nothing of the block committed by the modules before it
 	// Order matters: blocks should be indexed first, then modules that depend on blocks
-	if err := indexModules(block); err != nil {
+	ctx := context.Background()
+	postgresTx, err := postgres.BeginWithTimeout(ctx, postgres.QueryIndexerWrite)
+	if err != nil {
+		return fmt.Errorf("failed to begin database transaction for block %d: %w", height, err)
+	}
+	defer postgresTx.Rollback(ctx)
+
+	// Modules queue the keys of the rows they write for the block manifest, which the change feed
+	// records before the commit; the keys of a failed attempt are dropped with its transaction
+	postgres.CollectManifest(postgresTx)
+	defer postgres.TakeManifest(postgresTx)
+
+	if err := indexModules(postgresTx, block); err != nil {
 		return fmt.Errorf("failed to index modules for block %d: %w", height, err)
 	}
 
+	// The change feed, the indexer state and the block stamp are written in the same transaction,
+	// so a block is either committed with all of them or not at all
+
+	// Record the block manifest and append the block and its facts to the consumer change feed
+	if err := chaos.DBError("changefeed"); err != nil {
+		return fmt.Errorf("failed to record change feed for block %d: %w", height, err)
+	}
+	if err := changefeed.RecordBlock(postgresTx, block); err != nil {
+		return fmt.Errorf("failed to record change feed for block %d: %w", height, err)
+	}
+	chaos.KillPoint("changefeed")
+
 	// Update indexer state with the new last indexed block
-	if err := postgres.UpdateLastIndexedBlock(height, blockHash); err != nil {
+	if err := chaos.DBError("state"); err != nil {
+		return fmt.Errorf("failed to update last indexed block: %w", err)
+	}
+	if err := postgres.UpdateLastIndexedBlock(postgresTx, height, blockHash); err != nil {
 		return fmt.Errorf("failed to update last indexed block: %w", err)
 	}
+	chaos.KillPoint("state")
+
+	// Stamp the block so /stats/indexing-latency can measure block time to queryable, and record
+	// which node served it so anomalies can be traced back to that node
+	if err := blocks.MarkBlockIndexed(postgresTx, height, rpcSource); err != nil {
+		return err
+	}
+
+	if err := chaos.DBError("commit"); err != nil {
+		return fmt.Errorf("failed to commit database transaction for block %d: %w", height, err)
+	}
+	if err := postgresTx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit database transaction for block %d: %w", height, err)
+	}
+	chaos.KillPoint("commit")
+
+	blocksIndexedTotal.Inc()
+
+	// Notify WebSocket subscribers now that the block is committed
+	publishBlockEvents(block)
 
-	log.Printf("Successfully indexed block %d: %s", height, blockHash)
+	// Settle the block's transactions tracked by the mempool module
+	confirmMempool(block)
+
+	// Hash the UTXO sets at checkpoint heights in the background so other indexers can cross-check ours
+	snapshots.MaybeSnapshot(height)
+
+	// Report the load so maintenance can refresh planner statistics during long syncs
+	postgres.NotifyBulkChange("index", indexedTables(), int64(len(block.Tx)))
+
+	summary.add(len(block.Tx))
+
+	logging.Blockf(logging.ModuleIndexer, "Successfully indexed block %d: %s", height, blockHash)
 	return nil
 }
 
@@ -102,38 +198,73 @@ func parseBlock(rawBlock map[string]interface{}) (*types.ZcashBlock, error) {
 	return &block, nil
 }
 
+// recordIndexLog stores a block payload problem in the index_log
+// Recording is best effort; the block's own error is what stops the indexer
+func recordIndexLog(height int64, blockHash string, rpcSource string, stage string, cause error) {
+	if err := index_log.Record(height, blockHash, rpcSource, stage, cause.Error()); err != nil {
+		logging.Errorf(logging.ModuleIndexer, "%v", err)
+	}
+}
+
 // indexModules calls the indexing function for each enabled module
-// This function orchestrates the indexing across all modules
-func indexModules(block *types.ZcashBlock) error {
+// This function orchestrates the indexing across all modules, which all write through postgresTx
+func indexModules(postgresTx pgx.Tx, block *types.ZcashBlock) error {
+	// Classify scripts and extract addresses once, for every module to share
+	block.Parse()
+
 	// Always index blocks (core module)
-	if err := blocks.IndexBlocks(block); err != nil {
-		return fmt.Errorf("failed to index blocks module: %w", err)
+	if err := indexModule("blocks", func() error { return blocks.IndexBlocks(postgresTx, block) }); err != nil {
+		return err
 	}
 
-	// Index accounts module (if enabled)
-	if err := accounts.IndexAccounts(block); err != nil {
-		return fmt.Errorf("failed to index accounts module: %w", err)
+	// Index transaction graph module (if enabled)
+	if err := indexModule("tx_graph", func() error { return tx_graph.IndexTxGraph(postgresTx, block) }); err != nil {
+		return err
 	}
 
-	// Index transaction graph module (if enabled)
-	if err := tx_graph.IndexTxGraph(block); err != nil {
-		return fmt.Errorf("failed to index tx_graph module: %w", err)
+	// Index accounts module (if enabled)
+	// Runs after tx_graph, whose input addresses it reads to debit senders
+	if err := indexModule("accounts", func() error { return accounts.IndexAccounts(postgresTx, block) }); err != nil {
+		return err
 	}
 
 	// Index TZE graph module (if enabled)
-	if err := tze_graph.IndexTzeGraph(block); err != nil {
-		return fmt.Errorf("failed to index tze_graph module: %w", err)
+	if err := indexModule("tze_graph", func() error { return tze_graph.IndexTzeGraph(postgresTx, block) }); err != nil {
+		return err
 	}
 
 	// Index STARK module (if enabled)
 	// This includes both STARK proofs and Ztarknet-specific data
-	if err := starks.IndexStarks(block); err != nil {
-		return fmt.Errorf("failed to index starks module: %w", err)
+	if err := indexModule("starks", func() error { return starks.IndexStarks(postgresTx, block) }); err != nil {
+		return err
 	}
 
 	return nil
 }
 
+// moduleTables lists the tables each optional module writes while indexing a block
+var moduleTables = []struct {
+	module string
+	tables []string
+}{
+	{"TX_GRAPH", []string{"transactions", "transaction_outputs", "transaction_inputs", "inputs_addresses"}},
+	{"ACCOUNTS", []string{"accounts", "account_transactions"}},
+	{"TZE_GRAPH", []string{"tze_outputs", "tze_inputs"}},
+	{"STARKS", []string{"verifiers", "stark_proofs", "ztarknet_facts", "fact_segments", "fact_anomalies"}},
+	{"MEMPOOL", []string{"mempool_transactions"}}, // confirmed transactions are settled after the commit
+}
+
+// indexedTables returns the tables written for every block with the modules enabled in the configuration
+func indexedTables() []string {
+	tables := []string{"blocks", "change_feed", "block_manifests"}
+	for _, m := range moduleTables {
+		if config.IsModuleEnabled(m.module) {
+			tables = append(tables, m.tables...)
+		}
+	}
+	return tables
+}
+
 // GetLastIndexedBlock retrieves the last successfully indexed block height
 // This is used to resume indexing from the correct position
 func GetLastIndexedBlock() (int64, error) {
@@ -146,6 +277,7 @@ func GetLastIndexedBlock() (int64, error) {
 func Start(startBlock int64, rpcClient RpcClient) (chan struct{}, chan error) {
 	stopChan = make(chan struct{})
 	errorChannel = make(chan error, 1)
+	loopDone = make(chan struct{})
 
 	// Determine starting block height
 	var indexStartBlock int64
@@ -158,22 +290,49 @@ func Start(startBlock int64, rpcClient RpcClient) (chan struct{}, chan error) {
 			log.Printf("Failed to get last indexed block, starting from config: %v", err)
 			indexStartBlock = config.Conf.Indexer.StartBlock
 		} else {
+			// Indexing on top of a half-written block would leave its rows behind for good
+			if err := recoverInterruptedBlocks(lastBlock); err != nil {
+				log.Printf("Failed to roll back blocks above the last indexed block %d, not starting indexer: %v", lastBlock, err)
+				errorChannel <- fmt.Errorf("failed to roll back blocks above the last indexed block %d: %w", lastBlock, err)
+				close(loopDone)
+				return stopChan, errorChannel
+			}
 			indexStartBlock = lastBlock + 1
 			log.Printf("Resuming indexer from block: %d", indexStartBlock)
 		}
 	}
 
 	// Start indexing loop in goroutine
-	go startIndexingLoop(indexStartBlock, rpcClient)
+	go func() {
+		defer close(loopDone)
+		startIndexingLoop(indexStartBlock, rpcClient)
+	}()
+
+	// Start watchdog to detect a loop that stopped making progress
+	go runWatchdog(rpcClient)
+
+	// Start periodic reconciliation of the row counters
+	go runCounterReconciler()
+
+	// Subscribe to the node's block notifications, if configured
+	go runZmqNotifier()
 
 	return stopChan, errorChannel
 }
 
 // Stop signals the indexing loop to stop
+// The block being indexed is finished first, waiting at most stopTimeout
 func Stop() {
 	if stopChan != nil {
 		log.Println("Stopping indexer...")
 		close(stopChan)
+
+		select {
+		case <-loopDone:
+			log.Println("Indexer stopped")
+		case <-time.After(stopTimeout):
+			log.Printf("Indexer did not stop within %s, closing anyway", stopTimeout)
+		}
 	}
 }
 
@@ -185,11 +344,18 @@ func startIndexingLoop(startBlock int64, rpcClient RpcClient) {
 
 	log.Printf("Starting indexing loop from block %d", currentBlock)
 
+	state.setRunning(true, currentBlock)
+	defer state.setRunning(false, currentBlock)
+
 	for {
 		select {
 		case <-stopChan:
 			log.Println("Indexing stopped")
 			return
+		case req := <-rewindChan:
+			// Admin rollbacks and re-indexes are applied between blocks
+			currentBlock = applyRewind(req, currentBlock)
+			retryCount = 0
 		default:
 			// Get current blockchain height
 			blockCount, err := rpcClient.GetBlockCount()
@@ -198,10 +364,12 @@ func startIndexingLoop(startBlock int64, rpcClient RpcClient) {
 				time.Sleep(pollInterval)
 				continue
 			}
+			state.setChainHeight(blockCount)
+			maybeBuildDeferredIndexes(currentBlock, blockCount)
 
-			// Wait if we're caught up
+			// Wait if we're caught up, until the node announces a block or the next poll
 			if currentBlock > blockCount {
-				time.Sleep(pollInterval)
+				waitForBlock()
 				continue
 			}
 
@@ -211,35 +379,58 @@ func startIndexingLoop(startBlock int64, rpcClient RpcClient) {
 				batchEnd = blockCount
 			}
 
-			log.Printf("Indexing blocks %d to %d (chain height: %d)", currentBlock, batchEnd, blockCount)
+			logging.Blockf(logging.ModuleIndexer, "Indexing blocks %d to %d (chain height: %d)", currentBlock, batchEnd, blockCount)
+			summary.reset(currentBlock)
+			state.setBatch(currentBlock, batchEnd)
+
+			// Blocks buried deeper than the finality depth will not change, so their rows are immutable
+			postgres.SetBackfilling(blockCount-batchEnd > int64(config.Conf.Indexer.FinalityDepth))
+
+			// Fetch the whole batch up front; a batch cut short by a reorg or error is fetched again
+			rpcClient.PrefetchBlocks(currentBlock, batchEnd)
 
 			// Track if we need to restart from a different height (reorg or error)
 			batchCompleted := true
 
 			// Index batch of blocks
+		batch:
 			for height := currentBlock; height <= batchEnd; height++ {
 				select {
 				case <-stopChan:
 					return
+				case req := <-rewindChan:
+					currentBlock = applyRewind(req, height)
+					retryCount = 0
+					batchCompleted = false
+					break batch
 				default:
 					if err := IndexBlock(height, rpcClient); err != nil {
 						// Check if this is a reorg error - if so, restart from the new height
 						if reorgErr := reorg.GetReorgError(err); reorgErr != nil {
 							log.Printf("Reorg handled: %s", reorgErr.Error())
+							publishReorgEvent(height, reorgErr.NewStartHeight)
+							revertMempool(reorgErr.NewStartHeight)
 							currentBlock = reorgErr.NewStartHeight
 							retryCount = 0        // Reset retry count after reorg
 							batchCompleted = false // Don't advance past the batch
-							break                 // Exit the inner loop to restart from new height
+							break batch            // Exit the inner loop to restart from new height
 						}
 
 						// Non-reorg error - attempt rollback and retry
 						log.Printf("Error indexing block %d: %v", height, err)
 						retryCount++
+						state.recordError(height, retryCount, err)
 
 						if retryCount > maxIndexRetries {
-							log.Printf("Max retries (%d) exceeded for block %d, stopping indexer", maxIndexRetries, height)
-							errorChannel <- fmt.Errorf("max retries exceeded for block %d: %w", height, err)
-							return
+							log.Printf("Max retries (%d) exceeded for block %d", maxIndexRetries, height)
+							if !superviseFailure(height, fmt.Errorf("max retries exceeded for block %d: %w", height, err)) {
+								return
+							}
+							// Resumed by the supervisor: retry the block with a fresh set of retries
+							retryCount = 0
+							currentBlock = height
+							batchCompleted = false
+							break batch
 						}
 
 						// Rollback to previous block and retry
@@ -253,30 +444,36 @@ func startIndexingLoop(startBlock int64, rpcClient RpcClient) {
 						ctx := context.Background()
 						if rollbackErr := postgres.RollbackToHeight(ctx, rollbackHeight); rollbackErr != nil {
 							log.Printf("Failed to rollback to height %d: %v", rollbackHeight, rollbackErr)
-							errorChannel <- fmt.Errorf("failed to rollback after indexing error: %w", rollbackErr)
-							return
+							if !superviseFailure(height, fmt.Errorf("failed to rollback after indexing error: %w", rollbackErr)) {
+								return
+							}
+							retryCount = 0
+							currentBlock = height
+							batchCompleted = false
+							break batch
 						}
 
 						// Set current block to retry from the rollback height + 1
 						currentBlock = rollbackHeight + 1
 						batchCompleted = false // Don't advance past the batch
-						break                  // Exit inner loop to restart from the rollback point
+						break batch            // Exit inner loop to restart from the rollback point
 					}
 
 					// Success - reset retry count
 					retryCount = 0
+					state.recordSuccess(height)
 				}
 			}
 
+			// In quiet sync mode this replaces the per-block lines of the batch
+			if logging.SummaryMode() {
+				summary.log(blockCount)
+			}
+
 			// Only advance to next batch if we completed the current one without reorg or error
 			if batchCompleted {
 				currentBlock = batchEnd + 1
 			}
-
-			// Sleep if we're caught up
-			if currentBlock > blockCount {
-				time.Sleep(pollInterval)
-			}
 		}
 	}
 }
@@ -0,0 +1,28 @@
+package indexer
+
+import (
+	"context"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/logging"
+)
+
+// maybeBuildDeferredIndexes starts building the secondary indexes skipped at startup once the
+// indexer is within database.defer_indexes_until_tip blocks of the chain height
+// The build runs in the background so indexing continues while the indexes are created
+func maybeBuildDeferredIndexes(currentBlock, chainHeight int64) {
+	if !postgres.HasDeferredIndexes() {
+		return
+	}
+	if chainHeight-currentBlock > int64(config.Conf.Database.DeferIndexesUntilTip) {
+		return
+	}
+
+	logging.Infof(logging.ModuleIndexer, "Within %d blocks of the tip, building deferred indexes", chainHeight-currentBlock)
+	go func() {
+		if err := postgres.BuildDeferredIndexes(context.Background()); err != nil {
+			logging.Errorf(logging.ModuleIndexer, "Failed to build deferred indexes: %v", err)
+		}
+	}()
+}
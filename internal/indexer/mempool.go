@@ -0,0 +1,22 @@
+package indexer
+
+import (
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/logging"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/mempool"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/types"
+)
+
+// confirmMempool marks the block's transactions confirmed in the mempool module
+// The block is already committed, so a failure only leaves the entries to the next poll
+func confirmMempool(block *types.ZcashBlock) {
+	if err := mempool.ConfirmBlock(block); err != nil {
+		logging.Warnf(logging.ModuleMempool, "%v", err)
+	}
+}
+
+// revertMempool returns transactions of rolled back blocks to pending after a reorg
+func revertMempool(newStartHeight int64) {
+	if err := mempool.RevertConfirmations(newStartHeight); err != nil {
+		logging.Warnf(logging.ModuleMempool, "%v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package indexer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/types"
+)
+
+// checkMerkleRoot compares the header's merkleroot with the root recomputed from the block's txids
+// A mismatch means the node returned transactions that are not the ones the header commits to,
+// through a node bug or tampering on the RPC connection. Disabled by indexer.merkle_check: off
+func checkMerkleRoot(block *types.ZcashBlock) error {
+	if config.Conf.Indexer.MerkleCheck == "off" {
+		return nil
+	}
+
+	txids := make([]string, 0, len(block.Tx))
+	for _, tx := range block.Tx {
+		txids = append(txids, tx.TxID)
+	}
+
+	root, err := merkleRoot(txids)
+	if err != nil {
+		return err
+	}
+	if root != block.MerkleRoot {
+		return fmt.Errorf("merkle root mismatch: header has %s, %d txids hash to %s", block.MerkleRoot, len(txids), root)
+	}
+	return nil
+}
+
+// merkleRoot computes a block's merkle root from its txids as Zcash block headers commit to it:
+// a tree of double SHA-256 hashes over the txids in internal (reversed) byte order, where a level
+// with an odd number of hashes pairs its last hash with itself. Txids and the root are display hex
+func merkleRoot(txids []string) (string, error) {
+	if len(txids) == 0 {
+		return "", fmt.Errorf("cannot compute the merkle root of a block without transactions")
+	}
+
+	level := make([][]byte, len(txids))
+	for i, txid := range txids {
+		hash, err := hex.DecodeString(txid)
+		if err != nil || len(hash) != sha256.Size {
+			return "", fmt.Errorf("invalid txid %q at index %d", txid, i)
+		}
+		level[i] = reverseBytes(hash)
+	}
+
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+		next := make([][]byte, 0, len(level)/2)
+		for i := 0; i < len(level); i += 2 {
+			first := sha256.Sum256(append(append(make([]byte, 0, 2*sha256.Size), level[i]...), level[i+1]...))
+			second := sha256.Sum256(first[:])
+			next = append(next, second[:])
+		}
+		level = next
+	}
+
+	return hex.EncodeToString(reverseBytes(level[0])), nil
+}
+
+// reverseBytes returns a reversed copy, converting between display and internal byte order
+func reverseBytes(b []byte) []byte {
+	reversed := make([]byte, len(b))
+	for i := range b {
+		reversed[i] = b[len(b)-1-i]
+	}
+	return reversed
+}
@@ -0,0 +1,73 @@
+package indexer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/chaos"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/metrics"
+)
+
+var (
+	blocksIndexedTotal = metrics.NewCounter("zindex_blocks_indexed_total",
+		"Blocks indexed and committed")
+	moduleIndexDuration = metrics.NewHistogram("zindex_module_index_duration_seconds",
+		"Time spent indexing one block in each module", nil, "module")
+	moduleIndexErrors = metrics.NewCounter("zindex_module_index_errors_total",
+		"Blocks a module failed to index", "module")
+)
+
+func init() {
+	metrics.NewGaugeFunc("zindex_chain_height", "Chain height last reported by the node", func() float64 {
+		state.mu.RLock()
+		defer state.mu.RUnlock()
+		return float64(state.chainHeight)
+	})
+	metrics.NewGaugeFunc("zindex_indexed_height", "Height of the last indexed block", func() float64 {
+		state.mu.RLock()
+		defer state.mu.RUnlock()
+		return float64(state.lastIndexedHeight)
+	})
+	metrics.NewGaugeFunc("zindex_indexer_wedged", "1 while the indexing loop is wedged, 0 otherwise", func() float64 {
+		if GetLoopStatus().Wedged {
+			return 1
+		}
+		return 0
+	})
+	metrics.NewCounterFunc("zindex_indexer_wedge_alerts_total", "Times the indexing loop was reported as wedged", func() float64 {
+		return float64(GetLoopStatus().WedgeAlerts)
+	})
+	metrics.NewGaugeFunc("zindex_indexer_retry_count", "Failed attempts at the block being indexed", func() float64 {
+		return float64(GetLoopStatus().RetryCount)
+	})
+	metrics.NewGaugeFunc("zindex_indexer_last_error_timestamp_seconds", "Unix time of the last indexing error, 0 if none", func() float64 {
+		if lastErrorAt := GetLoopStatus().LastErrorAt; lastErrorAt != nil {
+			return float64(lastErrorAt.Unix())
+		}
+		return 0
+	})
+	metrics.NewGaugeFunc("zindex_indexer_seconds_since_last_block", "Seconds since the indexing loop last indexed a block, or started", func() float64 {
+		return GetLoopStatus().SecondsSinceLastSuccess
+	})
+}
+
+// indexModule runs one module's indexer for a block, recording its duration and failures
+// Disabled modules return straight away and are left out of the histogram
+func indexModule(module string, index func() error) error {
+	start := time.Now()
+	err := index()
+	if err == nil {
+		err = chaos.DBError(module)
+	}
+	if module == "blocks" || config.IsModuleEnabled(strings.ToUpper(module)) {
+		moduleIndexDuration.ObserveSince(start, module)
+	}
+	if err != nil {
+		moduleIndexErrors.Inc(module)
+		return fmt.Errorf("failed to index %s module: %w", module, err)
+	}
+	chaos.KillPoint(module)
+	return nil
+}
@@ -0,0 +1,202 @@
+package indexer
+
+import (
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/zmq"
+)
+
+const (
+	// zmqDialTimeout bounds connecting to the node's ZMQ endpoint and the handshake
+	zmqDialTimeout = 10 * time.Second
+	// zmqMaxBackoff caps the doubling delay between reconnection attempts
+	zmqMaxBackoff = 30 * time.Second
+)
+
+// NotifyStatus describes the block notifications received from the node
+type NotifyStatus struct {
+	ZmqUrl          string     `json:"zmq_url,omitempty"`
+	ZmqConnected    bool       `json:"zmq_connected"`
+	Http            bool       `json:"http"`
+	Notifications   int64      `json:"notifications"`
+	LastHash        string     `json:"last_hash,omitempty"`
+	LastSource      string     `json:"last_source,omitempty"` // zmq or http
+	LastNotifiedAt  *time.Time `json:"last_notified_at,omitempty"`
+	LastZmqError    string     `json:"last_zmq_error,omitempty"`
+	PollIntervalSec float64    `json:"poll_interval_seconds"` // current wait between polls when caught up
+}
+
+var (
+	// blockNotifyChan wakes the indexing loop when it is waiting for the next block
+	blockNotifyChan = make(chan struct{}, 1)
+
+	notifyMu       sync.Mutex
+	zmqConnected   bool
+	notifications  int64
+	lastHash       string
+	lastSource     string
+	lastNotifiedAt time.Time
+	lastZmqError   string
+)
+
+// NotifyNewBlock wakes the indexing loop as soon as the node announces a block
+// The block itself is still read over RPC, so a notification that turns out stale or
+// duplicated only costs one getblockcount
+func NotifyNewBlock(hash, source string) {
+	notifyMu.Lock()
+	notifications++
+	lastHash = hash
+	lastSource = source
+	lastNotifiedAt = time.Now()
+	notifyMu.Unlock()
+
+	wakeIndexer()
+}
+
+func wakeIndexer() {
+	select {
+	case blockNotifyChan <- struct{}{}:
+	default:
+	}
+}
+
+// GetNotifyStatus returns the state of block notifications, or nil when none are configured
+func GetNotifyStatus() *NotifyStatus {
+	notify := config.Conf.Indexer.Notify
+	if notify.ZmqUrl == "" && !notify.Http {
+		return nil
+	}
+
+	notifyMu.Lock()
+	defer notifyMu.Unlock()
+	status := &NotifyStatus{
+		ZmqUrl:          notify.ZmqUrl,
+		ZmqConnected:    zmqConnected,
+		Http:            notify.Http,
+		Notifications:   notifications,
+		LastHash:        lastHash,
+		LastSource:      lastSource,
+		LastZmqError:    lastZmqError,
+		PollIntervalSec: idlePollIntervalLocked().Seconds(),
+	}
+	if !lastNotifiedAt.IsZero() {
+		t := lastNotifiedAt
+		status.LastNotifiedAt = &t
+	}
+	return status
+}
+
+// idlePollInterval is how long a caught-up loop waits before polling the node again
+// While the ZMQ socket is connected notifications drive indexing, and polling only guards
+// against a missed notification; once it drops the loop polls every indexer.poll_interval
+func idlePollInterval() time.Duration {
+	notifyMu.Lock()
+	defer notifyMu.Unlock()
+	return idlePollIntervalLocked()
+}
+
+func idlePollIntervalLocked() time.Duration {
+	if zmqConnected {
+		return time.Duration(config.Conf.Indexer.Notify.FallbackPollInterval) * time.Second
+	}
+	return time.Duration(config.Conf.Indexer.PollInterval) * time.Second
+}
+
+// waitForBlock blocks until a block notification arrives, the idle poll interval passes or the
+// indexer stops
+func waitForBlock() {
+	timer := time.NewTimer(idlePollInterval())
+	defer timer.Stop()
+	select {
+	case <-blockNotifyChan:
+	case <-timer.C:
+	case <-stopChan:
+	}
+}
+
+func setZmqState(connected bool, err error) {
+	notifyMu.Lock()
+	defer notifyMu.Unlock()
+	zmqConnected = connected
+	if err != nil {
+		lastZmqError = err.Error()
+	}
+}
+
+// runZmqNotifier subscribes to the node's hashblock or rawblock notifications, reconnecting with
+// backoff whenever the socket drops, until the indexer stops
+func runZmqNotifier() {
+	notify := config.Conf.Indexer.Notify
+	if notify.ZmqUrl == "" {
+		return
+	}
+
+	backoff := time.Second
+	for {
+		sub, err := zmq.Dial(notify.ZmqUrl, zmqDialTimeout, notify.ZmqTopic)
+		if err != nil {
+			setZmqState(false, err)
+			log.Printf("Block notifications unavailable, polling every %ds: %v", config.Conf.Indexer.PollInterval, err)
+			select {
+			case <-stopChan:
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, zmqMaxBackoff)
+			continue
+		}
+
+		log.Printf("Subscribed to %s notifications at %s", notify.ZmqTopic, notify.ZmqUrl)
+		setZmqState(true, nil)
+		backoff = time.Second
+		// Catch up on anything announced while the socket was down
+		wakeIndexer()
+
+		err = receiveNotifications(sub)
+		setZmqState(false, err)
+		select {
+		case <-stopChan:
+			return
+		default:
+			log.Printf("Block notification socket dropped, polling every %ds until it reconnects: %v", config.Conf.Indexer.PollInterval, err)
+		}
+	}
+}
+
+// receiveNotifications wakes the indexing loop for every message until the socket fails or the
+// indexer stops, which closes the socket
+func receiveNotifications(sub *zmq.Subscriber) error {
+	closed := make(chan struct{})
+	defer close(closed)
+	go func() {
+		select {
+		case <-stopChan:
+		case <-closed:
+		}
+		sub.Close()
+	}()
+
+	for {
+		frames, err := sub.Receive()
+		if err != nil {
+			return err
+		}
+		if len(frames) < 2 {
+			continue
+		}
+		NotifyNewBlock(notificationHash(string(frames[0]), frames[1]), "zmq")
+	}
+}
+
+// notificationHash returns the block hash of a hashblock notification, displayed like RPC hashes
+// rawblock bodies are whole serialized blocks, which are not decoded here
+func notificationHash(topic string, body []byte) string {
+	if topic != "hashblock" || len(body) != 32 {
+		return ""
+	}
+	return hex.EncodeToString(body)
+}
@@ -0,0 +1,133 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+)
+
+// rewindTimeout is how long Rewind waits for the indexing loop to reach a point between blocks
+const rewindTimeout = 2 * time.Minute
+
+// ReindexStatus tracks the re-indexing of a block range requested through Reindex
+type ReindexStatus struct {
+	From        int64      `json:"from"`
+	To          int64      `json:"to"`
+	RequestedAt time.Time  `json:"requested_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"` // set once block To is indexed again
+}
+
+// rewindRequest asks the indexing loop to roll back to height before indexing another block
+type rewindRequest struct {
+	height  int64
+	reindex *ReindexStatus // range to track, nil for a plain rollback
+	done    chan error
+}
+
+var rewindChan = make(chan rewindRequest)
+
+// Rewind rolls the index back to height; the indexing loop then indexes the blocks above it again
+// While the loop runs, the rollback is handed to it between blocks so it never races a block in flight
+func Rewind(height int64) error {
+	return requestRewind(rewindRequest{height: height})
+}
+
+// Reindex rolls the index back below from, so blocks from..to are indexed again from the node
+// Every block above from is removed with them, so the loop re-indexes up to the tip, not only to to
+func Reindex(from, to int64) error {
+	return requestRewind(rewindRequest{
+		height:  from - 1,
+		reindex: &ReindexStatus{From: from, To: to, RequestedAt: time.Now()},
+	})
+}
+
+func requestRewind(req rewindRequest) error {
+	req.done = make(chan error, 1)
+
+	if !state.isRunning() {
+		if err := postgres.RollbackToHeight(context.Background(), req.height); err != nil {
+			return err
+		}
+		state.setReindex(req.reindex)
+		return nil
+	}
+
+	select {
+	case rewindChan <- req:
+	case <-time.After(rewindTimeout):
+		return fmt.Errorf("indexing loop did not pick up the rollback within %s", rewindTimeout)
+	}
+	return <-req.done
+}
+
+// recoverInterruptedBlocks rolls back blocks committed above lastBlock, which versions that updated
+// indexer_state after the block's commit left behind when the process stopped in between
+// Indexing them again on top of their own rows would apply their balance changes twice, so Start
+// does not run the loop when this fails
+func recoverInterruptedBlocks(lastBlock int64) error {
+	ctx := context.Background()
+	var maxHeight int64
+	if err := postgres.DB.QueryRow(ctx, "SELECT COALESCE(MAX(height), -1) FROM blocks").Scan(&maxHeight); err != nil {
+		return fmt.Errorf("failed to get highest stored block: %w", err)
+	}
+	if maxHeight <= lastBlock {
+		return nil
+	}
+
+	log.Printf("Blocks %d to %d were committed after the last indexed block %d, rolling them back before resuming",
+		lastBlock+1, maxHeight, lastBlock)
+	return postgres.RollbackToHeight(ctx, lastBlock)
+}
+
+// applyRewind performs a requested rollback from the indexing loop and returns the next block to index,
+// which stays next when the rollback fails
+func applyRewind(req rewindRequest, next int64) int64 {
+	if err := postgres.RollbackToHeight(context.Background(), req.height); err != nil {
+		req.done <- err
+		return next
+	}
+	state.setReindex(req.reindex)
+	req.done <- nil
+
+	log.Printf("Rolled back to block %d on admin request, indexing again from block %d", req.height, req.height+1)
+	publishReorgEvent(next, req.height+1)
+	revertMempool(req.height + 1)
+	return req.height + 1
+}
+
+func (s *loopState) isRunning() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.running
+}
+
+func (s *loopState) setReindex(reindex *ReindexStatus) {
+	if reindex == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reindex = reindex
+}
+
+// reindexStatus returns a copy of the last requested re-index, nil if there was none
+func (s *loopState) reindexStatus() *ReindexStatus {
+	if s.reindex == nil {
+		return nil
+	}
+	status := *s.reindex
+	return &status
+}
+
+// markReindexed completes the tracked re-index once its last block is indexed (caller must hold the lock)
+func (s *loopState) markReindexed(height int64) {
+	if s.reindex == nil || s.reindex.CompletedAt != nil || height < s.reindex.To {
+		return
+	}
+	now := time.Now()
+	s.reindex.CompletedAt = &now
+	log.Printf("Re-indexed blocks %d to %d in %s", s.reindex.From, s.reindex.To, now.Sub(s.reindex.RequestedAt).Round(time.Second))
+}
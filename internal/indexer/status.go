@@ -0,0 +1,245 @@
+package indexer
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/events"
+)
+
+const (
+	// watchdogInterval is how often the watchdog checks the indexing loop for progress
+	watchdogInterval = 30 * time.Second
+)
+
+// LoopStatus is a snapshot of the indexing loop's internal state
+type LoopStatus struct {
+	Running                 bool          `json:"running"`
+	CurrentHeight           int64         `json:"current_height"`
+	BatchStart              int64         `json:"batch_start"`
+	BatchEnd                int64         `json:"batch_end"`
+	ChainHeight             int64         `json:"chain_height"`
+	LastIndexedHeight       int64         `json:"last_indexed_height"`
+	RetryCount              int           `json:"retry_count"`
+	LastError               string        `json:"last_error,omitempty"`
+	LastErrorAt             *time.Time    `json:"last_error_at,omitempty"`
+	LastSuccessAt           *time.Time    `json:"last_success_at,omitempty"`
+	SecondsSinceLastSuccess float64       `json:"seconds_since_last_success"`
+	Wedged                  bool          `json:"wedged"`
+	WedgedSince             *time.Time    `json:"wedged_since,omitempty"`
+	WedgeAlerts             int64         `json:"wedge_alerts"`
+	CircuitBreaker          BreakerStatus `json:"circuit_breaker"`
+	// Reindex is the last re-index requested through the admin API
+	Reindex *ReindexStatus `json:"reindex,omitempty"`
+	// Notifications reports the node's block notifications when indexer.notify is configured
+	Notifications *NotifyStatus `json:"notifications,omitempty"`
+}
+
+// loopState holds the mutable loop state shared between the indexing loop, the watchdog and the API
+type loopState struct {
+	mu sync.RWMutex
+
+	running           bool
+	startedAt         time.Time
+	currentHeight     int64
+	batchStart        int64
+	batchEnd          int64
+	chainHeight       int64
+	lastIndexedHeight int64
+	retryCount        int
+	lastError         string
+	lastErrorAt       time.Time
+	lastSuccessAt     time.Time
+	wedged            bool
+	wedgedSince       time.Time
+	wedgeAlerts       int64
+	breaker           BreakerStatus
+	reindex           *ReindexStatus
+}
+
+var state = &loopState{breaker: BreakerStatus{State: BreakerClosed}}
+
+// GetLoopStatus returns a snapshot of the indexing loop state
+func GetLoopStatus() LoopStatus {
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+
+	status := LoopStatus{
+		Running:           state.running,
+		CurrentHeight:     state.currentHeight,
+		BatchStart:        state.batchStart,
+		BatchEnd:          state.batchEnd,
+		ChainHeight:       state.chainHeight,
+		LastIndexedHeight: state.lastIndexedHeight,
+		RetryCount:        state.retryCount,
+		LastError:         state.lastError,
+		Wedged:            state.wedged,
+		WedgeAlerts:       state.wedgeAlerts,
+		CircuitBreaker:    state.breaker,
+		Reindex:           state.reindexStatus(),
+		Notifications:     GetNotifyStatus(),
+	}
+	if !state.lastErrorAt.IsZero() {
+		lastErrorAt := state.lastErrorAt
+		status.LastErrorAt = &lastErrorAt
+	}
+	if !state.lastSuccessAt.IsZero() {
+		lastSuccessAt := state.lastSuccessAt
+		status.LastSuccessAt = &lastSuccessAt
+	}
+	if state.wedged {
+		wedgedSince := state.wedgedSince
+		status.WedgedSince = &wedgedSince
+	}
+	status.SecondsSinceLastSuccess = time.Since(state.progressBaseline()).Seconds()
+
+	return status
+}
+
+// SyncLag returns how many blocks the indexer trails the node tip by
+// ok is false until the loop is running and has seen the chain height
+func SyncLag() (lag int64, ok bool) {
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+
+	if !state.running || state.chainHeight == 0 {
+		return 0, false
+	}
+
+	// currentHeight is the next block to index, so it also covers blocks indexed before a restart
+	indexed := state.lastIndexedHeight
+	if state.currentHeight-1 > indexed {
+		indexed = state.currentHeight - 1
+	}
+
+	lag = state.chainHeight - indexed
+	if lag < 0 {
+		lag = 0
+	}
+	return lag, true
+}
+
+// progressBaseline returns the last time the loop made progress (caller must hold the lock)
+func (s *loopState) progressBaseline() time.Time {
+	if s.lastSuccessAt.IsZero() {
+		return s.startedAt
+	}
+	return s.lastSuccessAt
+}
+
+func (s *loopState) setRunning(running bool, startHeight int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = running
+	if running {
+		s.startedAt = time.Now()
+		s.currentHeight = startHeight
+	}
+}
+
+func (s *loopState) setChainHeight(height int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chainHeight = height
+}
+
+func (s *loopState) setBatch(start, end int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batchStart = start
+	s.batchEnd = end
+	s.currentHeight = start
+}
+
+func (s *loopState) recordSuccess(height int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastIndexedHeight = height
+	s.currentHeight = height + 1
+	s.retryCount = 0
+	if s.wedged {
+		log.Printf("Indexing loop recovered at block %d after being wedged since %s", height, s.wedgedSince.Format(time.RFC3339))
+		s.wedged = false
+		events.Publish(events.Event{
+			Topic:       events.TopicWedges,
+			BlockHeight: height,
+			Data: events.WedgeEvent{
+				Height:         height,
+				ChainHeight:    s.chainHeight,
+				StalledSeconds: time.Since(s.progressBaseline()).Seconds(),
+			},
+		})
+	}
+	s.lastSuccessAt = time.Now()
+	s.closeBreaker(height)
+	s.markReindexed(height)
+}
+
+func (s *loopState) recordError(height int64, retryCount int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.currentHeight = height
+	s.retryCount = retryCount
+	s.lastError = err.Error()
+	s.lastErrorAt = time.Now()
+}
+
+// runWatchdog periodically checks whether the loop has stopped making progress
+// while the node tip keeps advancing, and raises an alert when it has
+func runWatchdog(rpcClient RpcClient) {
+	timeout := time.Duration(config.Conf.Indexer.WedgeTimeout) * time.Minute
+	if timeout <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(watchdogInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopChan:
+			return
+		case <-ticker.C:
+			// Query the node directly, the loop may be stuck inside an RPC call
+			tip, err := rpcClient.GetBlockCount()
+			if err != nil {
+				continue
+			}
+			state.checkWedged(tip, timeout)
+		}
+	}
+}
+
+// checkWedged marks the loop as wedged and publishes a wedges event once per wedge episode
+func (s *loopState) checkWedged(tip int64, timeout time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running || s.wedged || tip < s.currentHeight {
+		return
+	}
+
+	stalledFor := time.Since(s.progressBaseline())
+	if stalledFor < timeout {
+		return
+	}
+
+	s.wedged = true
+	s.wedgedSince = time.Now()
+	s.wedgeAlerts++
+	log.Printf("ALERT: indexing loop wedged at block %d for %s while node tip is at %d (last error: %s)",
+		s.currentHeight, stalledFor.Round(time.Second), tip, s.lastError)
+	events.Publish(events.Event{
+		Topic:       events.TopicWedges,
+		BlockHeight: s.currentHeight,
+		Data: events.WedgeEvent{
+			Wedged:         true,
+			Height:         s.currentHeight,
+			ChainHeight:    tip,
+			StalledSeconds: stalledFor.Seconds(),
+			LastError:      s.lastError,
+		},
+	})
+}
@@ -0,0 +1,42 @@
+package indexer
+
+import (
+	"time"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/logging"
+)
+
+// batchSummary accumulates what the current batch indexed, for the quiet sync summary line
+// It is only touched by the indexing loop goroutine
+type batchSummary struct {
+	startedAt    time.Time
+	fromHeight   int64
+	blocks       int64
+	transactions int64
+}
+
+var summary = &batchSummary{}
+
+func (b *batchSummary) reset(fromHeight int64) {
+	b.startedAt = time.Now()
+	b.fromHeight = fromHeight
+	b.blocks = 0
+	b.transactions = 0
+}
+
+func (b *batchSummary) add(txCount int) {
+	b.blocks++
+	b.transactions += int64(txCount)
+}
+
+// log emits one line with the aggregate counts of the batch
+func (b *batchSummary) log(chainHeight int64) {
+	if b.blocks == 0 {
+		return
+	}
+
+	elapsed := time.Since(b.startedAt)
+	logging.Infof(logging.ModuleIndexer, "Indexed blocks %d to %d (chain height: %d): %d blocks, %d transactions in %s (%.1f blocks/s)",
+		b.fromHeight, b.fromHeight+b.blocks-1, chainHeight, b.blocks, b.transactions,
+		elapsed.Round(time.Millisecond), float64(b.blocks)/elapsed.Seconds())
+}
@@ -0,0 +1,117 @@
+package indexer
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+)
+
+// Circuit breaker states reported in /status
+const (
+	BreakerClosed   = "closed"    // indexing normally
+	BreakerOpen     = "open"      // paused after a block failed its retries, waiting out the backoff
+	BreakerHalfOpen = "half_open" // resumed; closes on the next indexed block, re-opens on the next failure
+)
+
+// BreakerStatus is the state of the indexer supervisor's circuit breaker
+type BreakerStatus struct {
+	State          string     `json:"state"`
+	Trips          int        `json:"trips"` // consecutive trips since the last indexed block
+	TotalTrips     int64      `json:"total_trips"`
+	BackoffSeconds float64    `json:"backoff_seconds,omitempty"`
+	OpenedAt       *time.Time `json:"opened_at,omitempty"`
+	ResumeAt       *time.Time `json:"resume_at,omitempty"`
+	LastTripError  string     `json:"last_trip_error,omitempty"`
+}
+
+// superviseFailure handles a block failure the loop cannot retry any further
+// Without indexer.supervisor, or once max_restarts consecutive resumes have failed, the error is
+// sent on errorChannel and false is returned so the loop exits as before
+// Otherwise the circuit breaker opens, the loop sleeps for the backoff and true is returned to resume
+func superviseFailure(height int64, err error) bool {
+	supervisor := config.Conf.Indexer.Supervisor
+	if !supervisor.Enabled {
+		log.Printf("Stopping indexer at block %d: %v", height, err)
+		errorChannel <- err
+		return false
+	}
+
+	trips := state.breakerTrips()
+	if supervisor.MaxRestarts > 0 && trips >= supervisor.MaxRestarts {
+		log.Printf("ALERT: indexer still failing at block %d after %d resume attempts, stopping indexer: %v", height, trips, err)
+		errorChannel <- fmt.Errorf("%w (gave up after %d resume attempts)", err, trips)
+		return false
+	}
+
+	backoff := supervisorBackoff(trips)
+	state.openBreaker(backoff, err)
+	log.Printf("ALERT: indexer circuit breaker open at block %d (trip %d), resuming in %s: %v", height, trips+1, backoff, err)
+
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	select {
+	case <-stopChan:
+		return false
+	case <-timer.C:
+	}
+
+	state.halfOpenBreaker()
+	log.Printf("Indexer circuit breaker half-open, resuming at block %d", height)
+	return true
+}
+
+// supervisorBackoff doubles initial_backoff for every consecutive trip, up to max_backoff
+func supervisorBackoff(trips int) time.Duration {
+	supervisor := config.Conf.Indexer.Supervisor
+	backoff := time.Duration(supervisor.InitialBackoff) * time.Second
+	maxBackoff := time.Duration(supervisor.MaxBackoff) * time.Second
+	for i := 0; i < trips && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff
+}
+
+func (s *loopState) breakerTrips() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.breaker.Trips
+}
+
+func (s *loopState) openBreaker(backoff time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	resumeAt := now.Add(backoff)
+	s.breaker.State = BreakerOpen
+	s.breaker.Trips++
+	s.breaker.TotalTrips++
+	s.breaker.BackoffSeconds = backoff.Seconds()
+	s.breaker.OpenedAt = &now
+	s.breaker.ResumeAt = &resumeAt
+	s.breaker.LastTripError = err.Error()
+}
+
+func (s *loopState) halfOpenBreaker() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.breaker.State = BreakerHalfOpen
+	s.breaker.ResumeAt = nil
+}
+
+// closeBreaker resets the breaker once a block is indexed (caller must hold the lock)
+func (s *loopState) closeBreaker(height int64) {
+	if s.breaker.State == BreakerClosed {
+		return
+	}
+	log.Printf("Indexer circuit breaker closed at block %d after %d trips", height, s.breaker.Trips)
+	s.breaker.State = BreakerClosed
+	s.breaker.Trips = 0
+	s.breaker.BackoffSeconds = 0
+	s.breaker.OpenedAt = nil
+	s.breaker.ResumeAt = nil
+}
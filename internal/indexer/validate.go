@@ -0,0 +1,164 @@
+package indexer
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// maxReportedProblems caps how many problems a single validation error lists
+const maxReportedProblems = 20
+
+// blockValidator collects every problem found in a raw getblock payload, so a node-version
+// incompatibility is reported in full on the first block rather than one field at a time
+type blockValidator struct {
+	problems []string
+}
+
+func (v *blockValidator) addf(format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+// validateRawBlock checks the fields parseBlock and the modules rely on before the payload is
+// unmarshalled, since a generic unmarshal turns missing or mistyped fields into zero values
+func validateRawBlock(rawBlock map[string]interface{}) error {
+	v := &blockValidator{}
+
+	v.hash(rawBlock, "hash", "block")
+	v.hash(rawBlock, "merkleroot", "block")
+	height, ok := v.integer(rawBlock, "height", "block")
+	v.integer(rawBlock, "time", "block")
+	if ok && height > 0 {
+		v.hash(rawBlock, "previousblockhash", "block")
+	}
+
+	txs, ok := v.array(rawBlock, "tx", "block")
+	if ok && len(txs) == 0 {
+		v.addf("block: tx is empty, expected at least the coinbase transaction")
+	}
+	for i, rawTx := range txs {
+		tx, isObject := rawTx.(map[string]interface{})
+		if !isObject {
+			v.addf("tx[%d]: expected an object, got %s", i, jsonType(rawTx))
+			continue
+		}
+		v.transaction(tx, fmt.Sprintf("tx[%d]", i))
+	}
+
+	if len(v.problems) == 0 {
+		return nil
+	}
+	problems := v.problems
+	if len(problems) > maxReportedProblems {
+		problems = append(problems[:maxReportedProblems:maxReportedProblems],
+			fmt.Sprintf("... and %d more", len(v.problems)-maxReportedProblems))
+	}
+	return fmt.Errorf("invalid block payload: %s", strings.Join(problems, "; "))
+}
+
+// transaction validates a verbose transaction object from getblock verbosity 2
+func (v *blockValidator) transaction(tx map[string]interface{}, path string) {
+	v.hash(tx, "txid", path)
+
+	vins, _ := v.array(tx, "vin", path)
+	for i, rawVin := range vins {
+		vinPath := fmt.Sprintf("%s.vin[%d]", path, i)
+		vin, isObject := rawVin.(map[string]interface{})
+		if !isObject {
+			v.addf("%s: expected an object, got %s", vinPath, jsonType(rawVin))
+			continue
+		}
+		if _, isCoinbase := vin["coinbase"]; isCoinbase {
+			continue
+		}
+		v.hash(vin, "txid", vinPath)
+		v.integer(vin, "vout", vinPath)
+	}
+
+	vouts, _ := v.array(tx, "vout", path)
+	for i, rawVout := range vouts {
+		voutPath := fmt.Sprintf("%s.vout[%d]", path, i)
+		vout, isObject := rawVout.(map[string]interface{})
+		if !isObject {
+			v.addf("%s: expected an object, got %s", voutPath, jsonType(rawVout))
+			continue
+		}
+		v.integer(vout, "n", voutPath)
+		v.integer(vout, "valueZat", voutPath)
+		if _, isObject := vout["scriptPubKey"].(map[string]interface{}); !isObject {
+			v.addf("%s: scriptPubKey is %s, expected an object", voutPath, describe(vout["scriptPubKey"]))
+		}
+	}
+}
+
+// hash requires obj[key] to be a 64-character hex string
+func (v *blockValidator) hash(obj map[string]interface{}, key, path string) {
+	s, ok := obj[key].(string)
+	if !ok {
+		v.addf("%s: %s is %s, expected a hex string", path, key, describe(obj[key]))
+		return
+	}
+	if len(s) != 64 {
+		v.addf("%s: %s %q has length %d, expected 64", path, key, s, len(s))
+		return
+	}
+	if _, err := hex.DecodeString(s); err != nil {
+		v.addf("%s: %s %q is not valid hex", path, key, s)
+	}
+}
+
+// integer requires obj[key] to be a finite, non-negative whole number
+func (v *blockValidator) integer(obj map[string]interface{}, key, path string) (int64, bool) {
+	f, ok := obj[key].(float64)
+	if !ok {
+		v.addf("%s: %s is %s, expected a number", path, key, describe(obj[key]))
+		return 0, false
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) || f != math.Trunc(f) {
+		v.addf("%s: %s is %v, expected a whole number", path, key, f)
+		return 0, false
+	}
+	if f < 0 {
+		v.addf("%s: %s is %v, expected a non-negative number", path, key, f)
+		return 0, false
+	}
+	return int64(f), true
+}
+
+// array requires obj[key] to be a JSON array
+func (v *blockValidator) array(obj map[string]interface{}, key, path string) ([]interface{}, bool) {
+	a, ok := obj[key].([]interface{})
+	if !ok {
+		v.addf("%s: %s is %s, expected an array", path, key, describe(obj[key]))
+		return nil, false
+	}
+	return a, true
+}
+
+// describe names a decoded JSON value for error messages, distinguishing absent from null
+func describe(value interface{}) string {
+	if value == nil {
+		return "missing or null"
+	}
+	return "a " + jsonType(value)
+}
+
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
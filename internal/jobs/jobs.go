@@ -0,0 +1,260 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+)
+
+func init() {
+	// Register this as a core schema (always initialized)
+	postgres.RegisterCoreSchema("jobs", InitSchema)
+
+	RegisterPeriodic(KindPruneJobs, pruneInterval, pruneFinished)
+}
+
+// InitSchema creates the jobs table
+// A periodic kind keeps at most one queued or running job, enforced by the partial unique index
+// on unique_key; finished jobs keep their row until jobs.retention expires
+func InitSchema() error {
+	schema := `
+		CREATE TABLE IF NOT EXISTS jobs (
+			id BIGSERIAL PRIMARY KEY,
+			kind VARCHAR(64) NOT NULL,
+			payload JSONB NOT NULL DEFAULT '{}',
+			status VARCHAR(16) NOT NULL DEFAULT 'queued',
+			attempts INTEGER NOT NULL DEFAULT 0,
+			max_attempts INTEGER NOT NULL,
+			run_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			locked_until TIMESTAMP,
+			last_error TEXT,
+			result JSONB,
+			unique_key VARCHAR(64),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			started_at TIMESTAMP,
+			finished_at TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_jobs_due ON jobs(run_at) WHERE status IN ('queued', 'running');
+		CREATE INDEX IF NOT EXISTS idx_jobs_kind_status ON jobs(kind, status);
+		CREATE INDEX IF NOT EXISTS idx_jobs_finished_at ON jobs(finished_at) WHERE finished_at IS NOT NULL;
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_jobs_active_unique_key ON jobs(unique_key)
+			WHERE status IN ('queued', 'running');
+	`
+
+	_, err := postgres.DB.Exec(context.Background(), schema)
+	if err != nil {
+		return fmt.Errorf("failed to create jobs schema: %w", err)
+	}
+
+	return nil
+}
+
+// KindPruneJobs deletes finished jobs older than jobs.retention
+const KindPruneJobs = "prune_jobs"
+
+// registration is a job kind's handler, and for periodic kinds how often it runs
+type registration struct {
+	handler  Handler
+	interval func() time.Duration
+}
+
+var registrations = make(map[string]registration)
+
+// Register registers the handler of jobs enqueued with Enqueue
+// Subsystems call this from init(), the same way they register their schemas
+func Register(kind string, handler Handler) {
+	registrations[kind] = registration{handler: handler}
+}
+
+// RegisterPeriodic registers a kind the queue schedules itself, interval after its last run
+// interval is read when jobs are scheduled, once the configuration is loaded; 0 or less disables it
+func RegisterPeriodic(kind string, interval func() time.Duration, handler Handler) {
+	registrations[kind] = registration{handler: handler, interval: interval}
+}
+
+// Enabled reports whether background work runs through the job queue (jobs.enabled)
+func Enabled() bool {
+	return config.Conf.Jobs.Enabled
+}
+
+// IsStatus reports whether status is a job status
+func IsStatus(status string) bool {
+	return status == StatusQueued || status == StatusRunning || status == StatusSucceeded || status == StatusFailed
+}
+
+// IsKind reports whether a handler is registered for kind
+func IsKind(kind string) bool {
+	_, ok := registrations[kind]
+	return ok
+}
+
+// IsPeriodic reports whether kind is scheduled by the queue rather than enqueued on demand
+func IsPeriodic(kind string) bool {
+	return registrations[kind].interval != nil
+}
+
+// Kinds lists the registered job kinds, sorted by name
+func Kinds() []KindInfo {
+	kinds := make([]KindInfo, 0, len(registrations))
+	for kind, reg := range registrations {
+		info := KindInfo{Kind: kind, Periodic: reg.interval != nil}
+		if info.Periodic {
+			info.Interval = int64(max(reg.interval(), 0) / time.Second)
+		}
+		kinds = append(kinds, info)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i].Kind < kinds[j].Kind })
+	return kinds
+}
+
+// permanentError marks a job error that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps an error a handler returns for a job that cannot succeed, such as an invalid
+// payload, so the job fails without using its remaining attempts
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+const jobColumns = `id, kind, payload, status, attempts, max_attempts, run_at, last_error, result,
+	unique_key, created_at, started_at, finished_at`
+
+// Enqueue queues a job of a registered kind to run as soon as a worker is free
+// payload is encoded as JSON; nil enqueues an empty object
+func Enqueue(ctx context.Context, kind string, payload any) (*Job, error) {
+	if !IsKind(kind) {
+		return nil, fmt.Errorf("unknown job kind %q", kind)
+	}
+
+	data := []byte("{}")
+	if payload != nil {
+		var err error
+		data, err = json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode payload of %s job: %w", kind, err)
+		}
+	}
+
+	job, err := postgres.PostgresQueryOneCtx[Job](ctx, nil,
+		`INSERT INTO jobs (kind, payload, max_attempts)
+		 VALUES ($1, $2, $3)
+		 RETURNING `+jobColumns,
+		kind, data, config.Conf.Jobs.MaxAttempts,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enqueue %s job: %w", kind, err)
+	}
+
+	wake()
+	return job, nil
+}
+
+// GetJobs lists jobs newest first, optionally filtered by kind and status
+func GetJobs(kind, status string, limit, offset int) ([]Job, error) {
+	jobs, err := postgres.PostgresQueryCtx[Job](
+		context.Background(), nil,
+		`SELECT `+jobColumns+`
+		 FROM jobs
+		 WHERE ($1 = '' OR kind = $1) AND ($2 = '' OR status = $2)
+		 ORDER BY id DESC
+		 LIMIT $3 OFFSET $4`,
+		kind, status, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// GetJob returns a job, or nil when it does not exist
+func GetJob(id int64) (*Job, error) {
+	jobs, err := postgres.PostgresQueryCtx[Job](
+		context.Background(), nil,
+		`SELECT `+jobColumns+` FROM jobs WHERE id = $1`,
+		id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job %d: %w", id, err)
+	}
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+
+	return &jobs[0], nil
+}
+
+// CountJobs returns the number of jobs per kind and status
+func CountJobs() ([]StatusCount, error) {
+	counts, err := postgres.PostgresQueryCtx[StatusCount](
+		context.Background(), nil,
+		`SELECT kind, status, COUNT(*) AS count
+		 FROM jobs
+		 GROUP BY kind, status
+		 ORDER BY kind, status`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count jobs: %w", err)
+	}
+
+	return counts, nil
+}
+
+// Retry queues a failed job again with a fresh attempt budget, keeping its payload and last error
+// Returns nil when the job is not failed, or is periodic and another run of it is already queued
+func Retry(id int64) (*Job, error) {
+	jobs, err := postgres.PostgresQueryCtx[Job](
+		context.Background(), nil,
+		`UPDATE jobs
+		 SET status = 'queued', attempts = 0, run_at = CURRENT_TIMESTAMP, locked_until = NULL,
+		     max_attempts = GREATEST(max_attempts, $2), finished_at = NULL
+		 WHERE id = $1 AND status = 'failed'
+		   AND (unique_key IS NULL OR NOT EXISTS (
+		       SELECT 1 FROM jobs active
+		       WHERE active.unique_key = jobs.unique_key AND active.status IN ('queued', 'running')))
+		 RETURNING `+jobColumns,
+		id, config.Conf.Jobs.MaxAttempts,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retry job %d: %w", id, err)
+	}
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+
+	wake()
+	return &jobs[0], nil
+}
+
+func pruneInterval() time.Duration {
+	if config.Conf.Jobs.Retention <= 0 {
+		return 0
+	}
+	return time.Hour
+}
+
+// pruneFinished deletes succeeded and failed jobs that finished more than jobs.retention hours ago
+func pruneFinished(ctx context.Context, _ json.RawMessage) (any, error) {
+	tag, err := postgres.DB.Exec(ctx,
+		`DELETE FROM jobs
+		 WHERE status IN ('succeeded', 'failed')
+		   AND finished_at < CURRENT_TIMESTAMP - make_interval(hours => $1)`,
+		config.Conf.Jobs.Retention,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prune finished jobs: %w", err)
+	}
+
+	return map[string]int64{"deleted": tag.RowsAffected()}, nil
+}
@@ -0,0 +1,50 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Job statuses
+const (
+	StatusQueued    = "queued"    // waiting for run_at and a free worker
+	StatusRunning   = "running"   // claimed by a worker that holds its lease
+	StatusSucceeded = "succeeded" // the handler returned without error
+	StatusFailed    = "failed"    // max_attempts reached; can be retried
+)
+
+// Handler runs one job. payload is the JSON the job was enqueued with, and the returned value is
+// stored as the job's result. ctx is cancelled when the queue stops; the job then runs again later
+type Handler func(ctx context.Context, payload json.RawMessage) (any, error)
+
+// Job is one unit of background work
+type Job struct {
+	ID          int64           `json:"id" db:"id"`
+	Kind        string          `json:"kind" db:"kind"`
+	Payload     json.RawMessage `json:"payload" db:"payload"`
+	Status      string          `json:"status" db:"status"`
+	Attempts    int             `json:"attempts" db:"attempts"`
+	MaxAttempts int             `json:"max_attempts" db:"max_attempts"`
+	RunAt       time.Time       `json:"run_at" db:"run_at"` // earliest time of the next attempt
+	LastError   *string         `json:"last_error" db:"last_error"`
+	Result      json.RawMessage `json:"result" db:"result"`
+	UniqueKey   *string         `json:"unique_key" db:"unique_key"` // periodic jobs only
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	StartedAt   *time.Time      `json:"started_at" db:"started_at"`
+	FinishedAt  *time.Time      `json:"finished_at" db:"finished_at"`
+}
+
+// KindInfo describes a registered job kind
+type KindInfo struct {
+	Kind     string `json:"kind"`
+	Periodic bool   `json:"periodic"`
+	Interval int64  `json:"interval,omitempty"` // seconds between runs of a periodic kind; 0 while disabled
+}
+
+// StatusCount is the number of jobs of a kind in a status
+type StatusCount struct {
+	Kind   string `json:"kind" db:"kind"`
+	Status string `json:"status" db:"status"`
+	Count  int64  `json:"count" db:"count"`
+}
@@ -0,0 +1,350 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/bluegreen"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+)
+
+const (
+	// maxBackoffDoublings caps the retry delay at retry_delay * 2^maxBackoffDoublings
+	maxBackoffDoublings = 10
+	// maxErrorLength bounds the error kept for a failed attempt
+	maxErrorLength = 1024
+)
+
+var (
+	stopChan chan struct{}
+	// loopsDone is closed once the workers and the scheduler have returned
+	loopsDone chan struct{}
+	// cancelRunning cancels the context of the jobs running when Stop is called
+	cancelRunning context.CancelFunc
+	// wakeChan prompts an idle worker to look for due jobs right away
+	wakeChan = make(chan struct{}, 1)
+)
+
+// Start runs jobs.workers workers and the scheduler of periodic kinds until Stop is called
+// It does nothing unless jobs.enabled is set
+func Start() {
+	if !Enabled() {
+		return
+	}
+
+	stopChan = make(chan struct{})
+	loopsDone = make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelRunning = cancel
+
+	var wg sync.WaitGroup
+	for i := 0; i < config.Conf.Jobs.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			workLoop(ctx, stopChan)
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		scheduleLoop(stopChan)
+	}()
+	go func() {
+		wg.Wait()
+		close(loopsDone)
+	}()
+
+	log.Printf("Job queue started with %d workers", config.Conf.Jobs.Workers)
+}
+
+// Stop cancels the running jobs and waits for the workers to return
+// Interrupted jobs are queued again without using up an attempt, and resume on the next start
+func Stop() {
+	if stopChan != nil {
+		close(stopChan)
+		cancelRunning()
+		<-loopsDone
+		stopChan = nil
+	}
+}
+
+func wake() {
+	select {
+	case wakeChan <- struct{}{}:
+	default:
+	}
+}
+
+func pollInterval() time.Duration {
+	return time.Duration(config.Conf.Jobs.PollInterval) * time.Second
+}
+
+func workLoop(ctx context.Context, stop chan struct{}) {
+	ticker := time.NewTicker(pollInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		case <-wakeChan:
+		}
+		// A standby blue/green deployment leaves the queue to the live one
+		if !bluegreen.Serving() {
+			continue
+		}
+
+		// Keep running jobs until none is due, so a backlog is not paced by the ticker
+		for {
+			job, err := claim(ctx)
+			if err != nil {
+				log.Printf("Failed to claim a job: %v", err)
+				break
+			}
+			if job == nil {
+				break
+			}
+			run(ctx, job)
+
+			select {
+			case <-stop:
+				return
+			default:
+			}
+		}
+	}
+}
+
+// claim takes the oldest due job, or one whose worker let its lease expire, and leases it
+// Returns nil when no job is due
+func claim(ctx context.Context) (*Job, error) {
+	jobs, err := postgres.PostgresQueryCtx[Job](ctx, nil,
+		`WITH due AS (
+			SELECT id FROM jobs
+			WHERE (status = 'queued' AND run_at <= CURRENT_TIMESTAMP)
+			   OR (status = 'running' AND locked_until < CURRENT_TIMESTAMP AND attempts < max_attempts)
+			ORDER BY run_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE jobs j
+		SET status = 'running', attempts = j.attempts + 1, started_at = CURRENT_TIMESTAMP,
+		    locked_until = CURRENT_TIMESTAMP + make_interval(secs => $1)
+		FROM due
+		WHERE j.id = due.id
+		RETURNING j.id, j.kind, j.payload, j.status, j.attempts, j.max_attempts, j.run_at, j.last_error,
+		          j.result, j.unique_key, j.created_at, j.started_at, j.finished_at`,
+		config.Conf.Jobs.Lease,
+	)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim due job: %w", err)
+	}
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+	return &jobs[0], nil
+}
+
+// run executes a claimed job, renewing its lease while the handler runs, and records the outcome
+func run(ctx context.Context, job *Job) {
+	reg, ok := registrations[job.Kind]
+	if !ok {
+		finish(job, nil, fmt.Errorf("no handler registered for job kind %q", job.Kind), false)
+		return
+	}
+
+	renewed := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		renewLease(job, done)
+	}()
+
+	result, err := reg.handler(ctx, job.Payload)
+	close(done)
+	<-renewed
+
+	if err != nil && ctx.Err() != nil {
+		release(job)
+		return
+	}
+	var permanent *permanentError
+	finish(job, result, err, !errors.As(err, &permanent))
+}
+
+// renewLease extends a running job's lease every half lease until done is closed, so a long job
+// is not taken over by another worker
+func renewLease(job *Job, done chan struct{}) {
+	lease := time.Duration(config.Conf.Jobs.Lease) * time.Second
+	ticker := time.NewTicker(lease / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		_, err := postgres.DB.Exec(context.Background(),
+			`UPDATE jobs SET locked_until = CURRENT_TIMESTAMP + make_interval(secs => $2)
+			 WHERE id = $1 AND status = 'running' AND attempts = $3`,
+			job.ID, config.Conf.Jobs.Lease, job.Attempts,
+		)
+		if err != nil {
+			log.Printf("Failed to renew lease of job %d: %v", job.ID, err)
+		}
+	}
+}
+
+// finish moves a job to succeeded, failed, or its next retry
+// With retry unset the job fails whatever attempts it has left
+// Only the attempt this worker claimed is updated: if the lease lapsed and another worker claimed
+// the job again, or it was failed as abandoned, the stale outcome is dropped
+func finish(job *Job, result any, jobErr error, retry bool) {
+	ctx := context.Background()
+
+	if jobErr == nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			jobErr = fmt.Errorf("failed to encode job result: %w", err)
+		} else {
+			tag, err := postgres.DB.Exec(ctx,
+				`UPDATE jobs
+				 SET status = 'succeeded', result = $2, last_error = NULL, locked_until = NULL,
+				     finished_at = CURRENT_TIMESTAMP
+				 WHERE id = $1 AND status = 'running' AND attempts = $3`,
+				job.ID, data, job.Attempts,
+			)
+			if err != nil {
+				log.Printf("Failed to record success of job %d: %v", job.ID, err)
+			} else if tag.RowsAffected() == 0 {
+				log.Printf("Dropped the result of job %d attempt %d, the job is no longer held by this worker", job.ID, job.Attempts)
+			}
+			return
+		}
+	}
+
+	message := jobErr.Error()
+	if len(message) > maxErrorLength {
+		message = message[:maxErrorLength]
+	}
+
+	status := StatusQueued
+	if !retry || job.Attempts >= job.MaxAttempts {
+		status = StatusFailed
+	}
+	backoff := config.Conf.Jobs.RetryDelay << min(job.Attempts-1, maxBackoffDoublings)
+
+	tag, err := postgres.DB.Exec(ctx,
+		`UPDATE jobs
+		 SET status = $2, last_error = $3, locked_until = NULL,
+		     run_at = CASE WHEN $2 = 'queued' THEN CURRENT_TIMESTAMP + make_interval(secs => $4) ELSE run_at END,
+		     finished_at = CASE WHEN $2 = 'failed' THEN CURRENT_TIMESTAMP END
+		 WHERE id = $1 AND status = 'running' AND attempts = $5`,
+		job.ID, status, message, backoff, job.Attempts,
+	)
+	if err != nil {
+		log.Printf("Failed to record failure of job %d: %v", job.ID, err)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		log.Printf("Dropped the failure of job %d attempt %d, the job is no longer held by this worker", job.ID, job.Attempts)
+		return
+	}
+
+	if status == StatusFailed {
+		log.Printf("Job %d (%s) failed after %d attempts: %s", job.ID, job.Kind, job.Attempts, message)
+	}
+}
+
+// release queues a job interrupted by Stop again, giving back the attempt it was claimed with
+func release(job *Job) {
+	_, err := postgres.DB.Exec(context.Background(),
+		`UPDATE jobs
+		 SET status = 'queued', attempts = GREATEST(attempts - 1, 0), locked_until = NULL,
+		     run_at = CURRENT_TIMESTAMP
+		 WHERE id = $1 AND status = 'running' AND attempts = $2`,
+		job.ID, job.Attempts,
+	)
+	if err != nil {
+		log.Printf("Failed to release interrupted job %d: %v", job.ID, err)
+	}
+}
+
+// scheduleLoop keeps one run of every enabled periodic kind queued, and fails running jobs whose
+// worker disappeared after their last attempt
+func scheduleLoop(stop chan struct{}) {
+	ticker := time.NewTicker(pollInterval())
+	defer ticker.Stop()
+	for {
+		if bluegreen.Serving() {
+			if err := schedulePeriodic(context.Background()); err != nil {
+				log.Printf("%v", err)
+			}
+			if err := failAbandoned(context.Background()); err != nil {
+				log.Printf("%v", err)
+			}
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// schedulePeriodic queues the next run of each periodic kind that has none queued or running,
+// interval after the last run finished. The unique index makes this safe across instances
+func schedulePeriodic(ctx context.Context) error {
+	var errs []error
+	for kind, reg := range registrations {
+		if reg.interval == nil {
+			continue
+		}
+		interval := reg.interval()
+		if interval <= 0 {
+			continue
+		}
+
+		tag, err := postgres.DB.Exec(ctx,
+			`INSERT INTO jobs (kind, max_attempts, unique_key, run_at)
+			 SELECT $1, 1, $1, COALESCE(
+			     (SELECT MAX(finished_at) FROM jobs WHERE unique_key = $1) + make_interval(secs => $2),
+			     CURRENT_TIMESTAMP)
+			 ON CONFLICT (unique_key) WHERE status IN ('queued', 'running') DO NOTHING`,
+			kind, interval.Seconds(),
+		)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to schedule %s job: %w", kind, err))
+			continue
+		}
+		if tag.RowsAffected() > 0 {
+			wake()
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// failAbandoned fails running jobs whose lease expired with no attempts left, which claim skips
+func failAbandoned(ctx context.Context) error {
+	_, err := postgres.DB.Exec(ctx,
+		`UPDATE jobs
+		 SET status = 'failed', locked_until = NULL, finished_at = CURRENT_TIMESTAMP,
+		     last_error = 'worker stopped responding during the last attempt'
+		 WHERE status = 'running' AND locked_until < CURRENT_TIMESTAMP AND attempts >= max_attempts`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to fail abandoned jobs: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"log"
+	"strings"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+)
+
+// Level is a log verbosity level; messages below a module's level are dropped
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// Module names accepted as logging.modules keys (case-insensitive)
+const (
+	ModuleIndexer  = "INDEXER"
+	ModuleBlocks   = "BLOCKS"
+	ModuleTxGraph  = "TX_GRAPH"
+	ModuleTzeGraph = "TZE_GRAPH"
+	ModuleAccounts = "ACCOUNTS"
+	ModuleStarks   = "STARKS"
+	ModuleMempool  = "MEMPOOL"
+)
+
+// ParseLevel converts a config level name to a Level; unknown or empty names mean info
+func ParseLevel(name string) Level {
+	switch strings.ToLower(name) {
+	case "debug":
+		return LevelDebug
+	case "warn":
+		return LevelWarn
+	case "error":
+		return LevelError
+	default:
+		return LevelInfo
+	}
+}
+
+// ModuleLevel returns the effective level of a module: its logging.modules override, else logging.level
+func ModuleLevel(module string) Level {
+	for name, level := range config.Conf.Logging.Modules {
+		if strings.EqualFold(name, module) {
+			return ParseLevel(level)
+		}
+	}
+	return ParseLevel(config.Conf.Logging.Level)
+}
+
+// Enabled reports whether a module logs messages at level
+func Enabled(module string, level Level) bool {
+	return level >= ModuleLevel(module)
+}
+
+// SummaryMode reports whether per-block progress lines are replaced by one summary line per batch
+func SummaryMode() bool {
+	return config.Conf.Logging.BatchSummary
+}
+
+func Debugf(module string, format string, args ...interface{}) {
+	logf(module, LevelDebug, format, args...)
+}
+
+func Infof(module string, format string, args ...interface{}) {
+	logf(module, LevelInfo, format, args...)
+}
+
+func Warnf(module string, format string, args ...interface{}) {
+	logf(module, LevelWarn, format, args...)
+}
+
+func Errorf(module string, format string, args ...interface{}) {
+	logf(module, LevelError, format, args...)
+}
+
+// Blockf logs a per-block progress line at info, or at debug in summary mode
+// where the indexer's batch summary takes its place
+func Blockf(module string, format string, args ...interface{}) {
+	if SummaryMode() {
+		logf(module, LevelDebug, format, args...)
+		return
+	}
+	logf(module, LevelInfo, format, args...)
+}
+
+func logf(module string, level Level, format string, args ...interface{}) {
+	if !Enabled(module, level) {
+		return
+	}
+	log.Printf(format, args...)
+}
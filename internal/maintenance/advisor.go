@@ -0,0 +1,72 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+)
+
+// GetIndexReport inspects index usage statistics and, when the pg_stat_statements
+// extension is installed, the slowest statements by mean execution time
+func GetIndexReport(slowQueryLimit int) (*IndexReport, error) {
+	// Primary keys and unique indexes enforce constraints, so they are never reported as unused
+	unused, err := postgres.PostgresQueryCtx[UnusedIndex](
+		context.Background(), nil,
+		`SELECT s.relname AS table_name, s.indexrelname AS index_name, s.idx_scan AS scans,
+		        pg_relation_size(s.indexrelid) AS size_bytes
+		 FROM pg_stat_user_indexes s
+		 JOIN pg_index i ON i.indexrelid = s.indexrelid
+		 WHERE s.idx_scan = 0
+		   AND NOT i.indisprimary
+		   AND NOT i.indisunique
+		   AND s.relname = ANY($1)
+		 ORDER BY pg_relation_size(s.indexrelid) DESC, s.relname, s.indexrelname`,
+		ManagedTables,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unused indexes: %w", err)
+	}
+
+	report := &IndexReport{
+		UnusedIndexes: unused,
+		SlowQueries:   []SlowQuery{},
+	}
+	if report.UnusedIndexes == nil {
+		report.UnusedIndexes = []UnusedIndex{}
+	}
+
+	var installed bool
+	err = postgres.DB.QueryRow(context.Background(),
+		`SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_stat_statements')`,
+	).Scan(&installed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check pg_stat_statements: %w", err)
+	}
+	if !installed {
+		report.Note = "pg_stat_statements extension is not installed; slow query report unavailable"
+		return report, nil
+	}
+
+	slow, err := postgres.PostgresQueryCtx[SlowQuery](
+		context.Background(), nil,
+		`SELECT query, calls, mean_exec_time AS mean_time_ms, total_exec_time AS total_time_ms, rows
+		 FROM pg_stat_statements
+		 WHERE dbid = (SELECT oid FROM pg_database WHERE datname = current_database())
+		 ORDER BY mean_exec_time DESC
+		 LIMIT $1`,
+		slowQueryLimit,
+	)
+	if err != nil {
+		// The extension may be installed but not preloaded, or predate PostgreSQL 13 column names
+		report.Note = fmt.Sprintf("pg_stat_statements query failed: %v", err)
+		return report, nil
+	}
+
+	report.PgStatStatementsAvailable = true
+	if slow != nil {
+		report.SlowQueries = slow
+	}
+
+	return report, nil
+}
@@ -0,0 +1,181 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+)
+
+// ManagedTables lists every table zindex writes to and may run maintenance on
+// A table added to a schema must be listed here, and in the indexer's tables if blocks write it
+var ManagedTables = []string{
+	"blocks",
+	"indexer_state",
+	"counters",
+	"change_feed",
+	"block_manifests",
+	"index_log",
+	"reorgs",
+	"utxo_snapshots",
+	"failed_items",
+	"transactions",
+	"transaction_outputs",
+	"transaction_inputs",
+	"inputs_addresses",
+	"accounts",
+	"account_transactions",
+	"tze_inputs",
+	"tze_outputs",
+	"verifiers",
+	"stark_proofs",
+	"ztarknet_facts",
+	"fact_segments",
+	"fact_anomalies",
+	"mempool_transactions",
+	"annotations",
+	"alert_rules",
+	"alerts",
+	"api_keys",
+	"api_usage",
+	"jobs",
+	"webhook_subscriptions",
+	"webhook_deliveries",
+	"webhook_delivery_attempts",
+	"webhook_digest_events",
+}
+
+var (
+	// pendingRows tracks rows touched per table since the last automatic ANALYZE
+	pendingRows = make(map[string]int64)
+	// pendingDeletes marks tables with bulk deletes since the last automatic run (VACUUM candidates)
+	pendingDeletes = make(map[string]bool)
+	pendingMu      sync.Mutex
+
+	// runMu serializes maintenance runs so automatic and admin runs never overlap
+	runMu sync.Mutex
+)
+
+func init() {
+	postgres.RegisterBulkChangeHook(onBulkChange)
+}
+
+// IsManagedTable reports whether a table name is one zindex may run maintenance on
+func IsManagedTable(table string) bool {
+	for _, t := range ManagedTables {
+		if t == table {
+			return true
+		}
+	}
+	return false
+}
+
+// onBulkChange accumulates touched rows and triggers ANALYZE (and VACUUM after rollbacks
+// when enabled) in the background once the configured threshold is crossed
+func onBulkChange(reason string, tables []string, rows int64) {
+	cfg := config.Conf.Database.Maintenance
+	if !cfg.AutoAnalyze || rows <= 0 {
+		return
+	}
+	if tables == nil {
+		tables = ManagedTables
+	}
+
+	pendingMu.Lock()
+	var due []string
+	vacuum := false
+	for _, table := range tables {
+		pendingRows[table] += rows
+		if reason == "rollback" {
+			pendingDeletes[table] = true
+		}
+		if pendingRows[table] >= cfg.BulkRowThreshold {
+			due = append(due, table)
+			vacuum = vacuum || (cfg.AutoVacuum && pendingDeletes[table])
+			delete(pendingRows, table)
+			delete(pendingDeletes, table)
+		}
+	}
+	pendingMu.Unlock()
+
+	if len(due) == 0 {
+		return
+	}
+
+	log.Printf("Bulk %s touched %d rows, scheduling maintenance on %d tables (vacuum: %t)", reason, rows, len(due), vacuum)
+	go func() {
+		for _, result := range Run(context.Background(), due, vacuum) {
+			if result.Error != "" {
+				log.Printf("Maintenance on %s failed: %s", result.Table, result.Error)
+			}
+		}
+	}()
+}
+
+// Run executes ANALYZE (preceded by VACUUM if requested) on the given tables
+// Tables that do not exist, e.g. because their module is disabled, are skipped
+func Run(ctx context.Context, tables []string, vacuum bool) []TableMaintenanceResult {
+	runMu.Lock()
+	defer runMu.Unlock()
+
+	results := make([]TableMaintenanceResult, 0, len(tables))
+
+	conn, err := postgres.DB.Acquire(ctx)
+	if err != nil {
+		for _, table := range tables {
+			results = append(results, TableMaintenanceResult{Table: table, Error: fmt.Sprintf("failed to acquire connection: %v", err)})
+		}
+		return results
+	}
+	defer conn.Release()
+
+	// Maintenance on large tables can exceed the configured statement timeout
+	resetTimeout, err := postgres.SetSessionStatementTimeout(ctx, conn, postgres.QueryAdminMaintenance)
+	if err != nil {
+		log.Printf("Failed to set statement timeout for maintenance: %v", err)
+	}
+	defer resetTimeout()
+
+	for _, table := range tables {
+		result := TableMaintenanceResult{Table: table}
+		start := time.Now()
+
+		var exists bool
+		if err := conn.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists); err != nil {
+			result.Error = fmt.Sprintf("failed to check table: %v", err)
+			results = append(results, result)
+			continue
+		}
+		if !exists {
+			result.Skipped = true
+			results = append(results, result)
+			continue
+		}
+
+		identifier := pgx.Identifier{table}.Sanitize()
+		if vacuum {
+			if _, err := conn.Exec(ctx, "VACUUM ANALYZE "+identifier); err != nil {
+				result.Error = fmt.Sprintf("failed to vacuum analyze: %v", err)
+			} else {
+				result.Vacuumed = true
+				result.Analyzed = true
+			}
+		} else {
+			if _, err := conn.Exec(ctx, "ANALYZE "+identifier); err != nil {
+				result.Error = fmt.Sprintf("failed to analyze: %v", err)
+			} else {
+				result.Analyzed = true
+			}
+		}
+
+		result.DurationMs = time.Since(start).Milliseconds()
+		results = append(results, result)
+	}
+
+	return results
+}
@@ -0,0 +1,36 @@
+package maintenance
+
+// TableMaintenanceResult reports the outcome of ANALYZE/VACUUM on a single table
+type TableMaintenanceResult struct {
+	Table      string `json:"table"`
+	Analyzed   bool   `json:"analyzed"`
+	Vacuumed   bool   `json:"vacuumed"`
+	Skipped    bool   `json:"skipped,omitempty"` // table does not exist (module disabled)
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// UnusedIndex is an index that has not been scanned since statistics were last reset
+type UnusedIndex struct {
+	Table     string `json:"table" db:"table_name"`
+	Index     string `json:"index" db:"index_name"`
+	Scans     int64  `json:"scans" db:"scans"`
+	SizeBytes int64  `json:"size_bytes" db:"size_bytes"`
+}
+
+// SlowQuery is a statement from pg_stat_statements ranked by mean execution time
+type SlowQuery struct {
+	Query       string  `json:"query" db:"query"`
+	Calls       int64   `json:"calls" db:"calls"`
+	MeanTimeMs  float64 `json:"mean_time_ms" db:"mean_time_ms"`
+	TotalTimeMs float64 `json:"total_time_ms" db:"total_time_ms"`
+	Rows        int64   `json:"rows" db:"rows"`
+}
+
+// IndexReport summarizes index usage and slow queries to guide index cleanup
+type IndexReport struct {
+	UnusedIndexes             []UnusedIndex `json:"unused_indexes"`
+	SlowQueries               []SlowQuery   `json:"slow_queries"`
+	PgStatStatementsAvailable bool          `json:"pg_stat_statements_available"`
+	Note                      string        `json:"note,omitempty"`
+}
@@ -0,0 +1,333 @@
+package mempool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/jobs"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/tx_graph"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/types"
+)
+
+// readDB serves mempool lookups; nil falls back to postgres.DB
+var readDB postgres.Querier
+
+// SetReadDB routes mempool lookups through q, e.g. a read replica
+// pool or a mock in tests. Passing nil restores the global postgres.DB
+func SetReadDB(q postgres.Querier) {
+	readDB = q
+}
+
+func init() {
+	// Register this module's schema initialization with the postgres package
+	postgres.RegisterModuleSchema("MEMPOOL", InitSchema)
+
+	// With the job queue, pruning runs as a periodic job instead of after every poll
+	jobs.RegisterPeriodic(KindPrune, pruneJobInterval, pruneJob)
+}
+
+// secondaryIndexes serve mempool listings and retention pruning
+var secondaryIndexes = []postgres.SecondaryIndex{
+	{
+		Name:       "idx_mempool_txs_status_first_seen",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_mempool_txs_status_first_seen ON mempool_transactions(status, first_seen_at DESC);`,
+	},
+	{
+		Name:       "idx_mempool_txs_confirmed_height",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_mempool_txs_confirmed_height ON mempool_transactions(confirmed_height);`,
+	},
+	{
+		Name:       "idx_mempool_txs_removed_at",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_mempool_txs_removed_at ON mempool_transactions(removed_at);`,
+	},
+}
+
+// InitSchema creates the mempool tables and indexes
+// Mempool state is not tied to indexed blocks, so it is not part of the block manifests or rollbacks
+func InitSchema() error {
+	schema := `
+		CREATE TABLE IF NOT EXISTS mempool_transactions (
+			txid VARCHAR(64) PRIMARY KEY,
+			type VARCHAR(10) NOT NULL,
+			tze_subtype VARCHAR(16),
+			size INTEGER NOT NULL DEFAULT 0,
+			input_count INTEGER NOT NULL DEFAULT 0,
+			output_count INTEGER NOT NULL DEFAULT 0,
+			total_output BIGINT NOT NULL DEFAULT 0,
+			status VARCHAR(10) NOT NULL DEFAULT 'pending',  -- pending, confirmed, evicted
+			first_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			confirmed_height BIGINT,
+			removed_at TIMESTAMP
+		);
+	`
+
+	_, err := postgres.DB.Exec(context.Background(), schema)
+	if err != nil {
+		return fmt.Errorf("failed to create mempool schema: %w", err)
+	}
+
+	if err := postgres.InitSecondaryIndexes(secondaryIndexes); err != nil {
+		return fmt.Errorf("failed to init mempool indexes: %w", err)
+	}
+
+	return nil
+}
+
+const mempoolTransactionColumns = `txid, type, tze_subtype, size, input_count, output_count, total_output,
+		 status, first_seen_at, last_seen_at, confirmed_height, removed_at`
+
+// GetMempoolTransaction retrieves a tracked transaction by txid, or nil if it was never seen
+// or has been pruned
+func GetMempoolTransaction(txid string) (*MempoolTransaction, error) {
+	tx, err := postgres.PostgresQueryOneCtx[MempoolTransaction](
+		context.Background(), readDB,
+		`SELECT `+mempoolTransactionColumns+`
+		 FROM mempool_transactions WHERE txid = $1`,
+		txid,
+	)
+
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mempool transaction %s: %w", txid, err)
+	}
+
+	return tx, nil
+}
+
+// GetMempoolTransactions retrieves tracked transactions, most recently seen first, with pagination
+// Empty status, txType or tzeSubtype match any value
+func GetMempoolTransactions(status, txType, tzeSubtype string, limit, offset int) ([]MempoolTransaction, error) {
+	txs, err := postgres.PostgresQueryCtx[MempoolTransaction](
+		context.Background(), readDB,
+		`SELECT `+mempoolTransactionColumns+`
+		 FROM mempool_transactions
+		 WHERE ($1 = '' OR status = $1)
+		   AND ($2 = '' OR type = $2)
+		   AND ($3 = '' OR tze_subtype = $3)
+		 ORDER BY first_seen_at DESC, txid
+		 LIMIT $4 OFFSET $5`,
+		status, txType, tzeSubtype, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mempool transactions: %w", err)
+	}
+
+	return txs, nil
+}
+
+// CountMempoolTransactions returns the number of tracked transactions with optional filters
+func CountMempoolTransactions(status, txType string) (int64, error) {
+	var count int64
+	err := postgres.ReadQuerier(readDB).QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM mempool_transactions
+		 WHERE ($1 = '' OR status = $1) AND ($2 = '' OR type = $2)`,
+		status, txType,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count mempool transactions: %w", err)
+	}
+
+	return count, nil
+}
+
+// GetMempoolSummary summarizes the pending transactions
+func GetMempoolSummary() (*MempoolSummary, error) {
+	ctx := context.Background()
+	q := postgres.ReadQuerier(readDB)
+
+	summary := &MempoolSummary{CountByType: map[string]int64{}}
+	err := q.QueryRow(ctx,
+		`SELECT COUNT(*), COALESCE(SUM(size), 0), MIN(first_seen_at),
+		        COUNT(*) FILTER (WHERE tze_subtype = 'stark_verify')
+		 FROM mempool_transactions WHERE status = 'pending'`,
+	).Scan(&summary.PendingCount, &summary.PendingBytes, &summary.OldestSeenAt, &summary.StarkVerifyCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize mempool: %w", err)
+	}
+
+	rows, err := q.Query(ctx,
+		`SELECT type, COUNT(*) FROM mempool_transactions WHERE status = 'pending' GROUP BY type`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count mempool transactions by type: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var txType string
+		var count int64
+		if err := rows.Scan(&txType, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan mempool type count: %w", err)
+		}
+		summary.CountByType[txType] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count mempool transactions by type: %w", err)
+	}
+
+	return summary, nil
+}
+
+// storeTransaction records a transaction seen in the mempool
+// A transaction that was confirmed or evicted and is back (after a reorg or a rebroadcast) is pending again
+func storeTransaction(tx *types.ZcashTransaction) error {
+	txType, tzeSubtype := tx_graph.ClassifyTransaction(tx)
+
+	totalOutput := int64(0)
+	for _, vout := range tx.Vout {
+		totalOutput += vout.ValueZat
+	}
+
+	_, err := postgres.DB.Exec(context.Background(),
+		`INSERT INTO mempool_transactions (txid, type, tze_subtype, size, input_count, output_count, total_output)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 ON CONFLICT (txid) DO UPDATE SET
+			status = 'pending',
+			last_seen_at = CURRENT_TIMESTAMP,
+			confirmed_height = NULL,
+			removed_at = NULL`,
+		tx.TxID, string(txType), tzeSubtype, tx.Size, len(tx.Vin), len(tx.Vout), totalOutput,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store mempool transaction %s: %w", tx.TxID, err)
+	}
+
+	return nil
+}
+
+// getPendingTxids returns the txids currently tracked as pending
+func getPendingTxids() (map[string]bool, error) {
+	rows, err := postgres.DB.Query(context.Background(),
+		`SELECT txid FROM mempool_transactions WHERE status = 'pending'`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending mempool transactions: %w", err)
+	}
+	defer rows.Close()
+
+	pending := make(map[string]bool)
+	for rows.Next() {
+		var txid string
+		if err := rows.Scan(&txid); err != nil {
+			return nil, fmt.Errorf("failed to scan pending mempool txid: %w", err)
+		}
+		pending[txid] = true
+	}
+
+	return pending, rows.Err()
+}
+
+// reconcile refreshes last_seen_at of the pending transactions still in the mempool and marks the
+// others evicted. Returns the number evicted
+// A transaction mined into a block the indexer has not reached yet is marked evicted until
+// ConfirmBlock sees it
+func reconcile(inMempool []string) (int64, error) {
+	ctx := context.Background()
+
+	_, err := postgres.DB.Exec(ctx,
+		`UPDATE mempool_transactions SET last_seen_at = CURRENT_TIMESTAMP
+		 WHERE status = 'pending' AND txid = ANY($1)`,
+		inMempool,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to refresh pending mempool transactions: %w", err)
+	}
+
+	tag, err := postgres.DB.Exec(ctx,
+		`UPDATE mempool_transactions SET status = 'evicted', removed_at = CURRENT_TIMESTAMP
+		 WHERE status = 'pending' AND NOT (txid = ANY($1))`,
+		inMempool,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to evict mempool transactions: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// KindPrune is the periodic job that prunes the mempool when jobs.enabled is set
+const KindPrune = "mempool_prune"
+
+// pruneEvery is how often the mempool_prune job runs; retention is in hours, so polls need not prune
+const pruneEvery = 10 * time.Minute
+
+func pruneJobInterval() time.Duration {
+	if !config.IsModuleEnabled("MEMPOOL") {
+		return 0
+	}
+	return pruneEvery
+}
+
+func pruneJob(_ context.Context, _ json.RawMessage) (any, error) {
+	pruned, err := prune()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]int64{"pruned": pruned}, nil
+}
+
+// prune deletes confirmed and evicted entries older than modules.mempool.retention
+func prune() (int64, error) {
+	cutoff := time.Now().Add(-time.Duration(config.Conf.Modules.Mempool.Retention) * time.Hour)
+	tag, err := postgres.DB.Exec(context.Background(),
+		`DELETE FROM mempool_transactions WHERE status <> 'pending' AND removed_at < $1`,
+		cutoff,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune mempool transactions: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// ConfirmBlock marks the tracked transactions included in an indexed block as confirmed
+// Transactions never seen in the mempool are not added
+func ConfirmBlock(block *types.ZcashBlock) error {
+	if !config.IsModuleEnabled("MEMPOOL") {
+		return nil
+	}
+
+	txids := make([]string, len(block.Tx))
+	for i := range block.Tx {
+		txids[i] = block.Tx[i].TxID
+	}
+
+	_, err := postgres.DB.Exec(context.Background(),
+		`UPDATE mempool_transactions
+		 SET status = 'confirmed', confirmed_height = $2, removed_at = CURRENT_TIMESTAMP
+		 WHERE txid = ANY($1)`,
+		txids, block.Height,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to confirm mempool transactions of block %d: %w", block.Height, err)
+	}
+
+	return nil
+}
+
+// RevertConfirmations returns transactions confirmed at or above height to pending after a reorg
+// The next poll evicts the ones that did not return to the node's mempool
+func RevertConfirmations(height int64) error {
+	if !config.IsModuleEnabled("MEMPOOL") {
+		return nil
+	}
+
+	_, err := postgres.DB.Exec(context.Background(),
+		`UPDATE mempool_transactions
+		 SET status = 'pending', confirmed_height = NULL, removed_at = NULL
+		 WHERE confirmed_height >= $1`,
+		height,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revert mempool confirmations from height %d: %w", height, err)
+	}
+
+	return nil
+}
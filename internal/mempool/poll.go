@@ -0,0 +1,126 @@
+package mempool
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/jobs"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/logging"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/tx_graph"
+)
+
+// RpcClient interface defines the methods required to read the node's mempool
+type RpcClient interface {
+	GetRawMempool() ([]string, error)
+	GetRawTransaction(txid string) (map[string]interface{}, error)
+}
+
+var (
+	stopChan chan struct{}
+	// pollDone is closed once the polling loop has returned
+	pollDone chan struct{}
+)
+
+// Start polls the node's mempool in the background until Stop is called
+// It does nothing unless the mempool module is enabled
+func Start(rpcClient RpcClient) {
+	if !config.IsModuleEnabled("MEMPOOL") {
+		return
+	}
+
+	stopChan = make(chan struct{})
+	pollDone = make(chan struct{})
+	go func() {
+		defer close(pollDone)
+		pollLoop(rpcClient, stopChan)
+	}()
+}
+
+// Stop ends the polling loop started by Start, waiting for a poll in progress to finish
+func Stop() {
+	if stopChan != nil {
+		close(stopChan)
+		<-pollDone
+		stopChan = nil
+	}
+}
+
+func pollLoop(rpcClient RpcClient, stop chan struct{}) {
+	interval := time.Duration(config.Conf.Modules.Mempool.PollInterval) * time.Second
+	logging.Infof(logging.ModuleMempool, "Polling the node mempool every %s", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		// A failed poll is retried on the next tick; the tracked state stays as it was
+		if err := poll(rpcClient); err != nil {
+			logging.Errorf(logging.ModuleMempool, "Mempool poll failed: %v", err)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll stores transactions new to the mempool, then reconciles the ones that left it
+// At most modules.mempool.max_transactions new transactions are fetched; the rest wait for the next poll
+func poll(rpcClient RpcClient) error {
+	txids, err := rpcClient.GetRawMempool()
+	if err != nil {
+		return fmt.Errorf("failed to get raw mempool: %w", err)
+	}
+
+	pending, err := getPendingTxids()
+	if err != nil {
+		return err
+	}
+
+	added := 0
+	for _, txid := range txids {
+		if pending[txid] {
+			continue
+		}
+		if added >= config.Conf.Modules.Mempool.MaxTransactions {
+			break
+		}
+
+		rawTx, err := rpcClient.GetRawTransaction(txid)
+		if err != nil {
+			// Mined or evicted since getrawmempool; reconcile handles it like any other departure
+			logging.Debugf(logging.ModuleMempool, "Skipping mempool transaction %s: %v", txid, err)
+			continue
+		}
+
+		tx, err := tx_graph.ParseRawTransaction(rawTx)
+		if err != nil {
+			logging.Warnf(logging.ModuleMempool, "Skipping mempool transaction %s: %v", txid, err)
+			continue
+		}
+
+		if err := storeTransaction(tx); err != nil {
+			return err
+		}
+		added++
+	}
+
+	evicted, err := reconcile(txids)
+	if err != nil {
+		return err
+	}
+
+	var pruned int64
+	if !jobs.Enabled() {
+		pruned, err = prune()
+		if err != nil {
+			return err
+		}
+	}
+
+	logging.Debugf(logging.ModuleMempool, "Mempool poll: %d in mempool, %d added, %d evicted, %d pruned",
+		len(txids), added, evicted, pruned)
+	return nil
+}
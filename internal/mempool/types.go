@@ -0,0 +1,36 @@
+package mempool
+
+import "time"
+
+// Status of a tracked mempool transaction
+const (
+	StatusPending   = "pending"   // in the node's mempool at the last poll
+	StatusConfirmed = "confirmed" // included in an indexed block
+	StatusEvicted   = "evicted"   // left the mempool without being seen in an indexed block
+)
+
+// MempoolTransaction is an unconfirmed transaction seen in the node's mempool
+// Confirmed and evicted entries are kept for modules.mempool.retention hours
+type MempoolTransaction struct {
+	TxID            string     `json:"txid" db:"txid"`
+	Type            string     `json:"type" db:"type"`                         // coinbase, tze, t2t, t2z, z2t, z2z
+	TzeSubtype      *string    `json:"tze_subtype,omitempty" db:"tze_subtype"` // demo, stark_verify, unknown (tze only)
+	Size            int        `json:"size" db:"size"`
+	InputCount      int        `json:"input_count" db:"input_count"`
+	OutputCount     int        `json:"output_count" db:"output_count"`
+	TotalOutput     int64      `json:"total_output" db:"total_output"`
+	Status          string     `json:"status" db:"status"`
+	FirstSeenAt     time.Time  `json:"first_seen_at" db:"first_seen_at"`
+	LastSeenAt      time.Time  `json:"last_seen_at" db:"last_seen_at"`
+	ConfirmedHeight *int64     `json:"confirmed_height" db:"confirmed_height"`
+	RemovedAt       *time.Time `json:"removed_at" db:"removed_at"` // when it was confirmed or evicted
+}
+
+// MempoolSummary describes the pending transactions
+type MempoolSummary struct {
+	PendingCount     int64            `json:"pending_count"`
+	PendingBytes     int64            `json:"pending_bytes"`
+	OldestSeenAt     *time.Time       `json:"oldest_seen_at"`
+	CountByType      map[string]int64 `json:"count_by_type"`
+	StarkVerifyCount int64            `json:"stark_verify_count"` // pending TZE transactions of the stark_verify extension
+}
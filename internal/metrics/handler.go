@@ -0,0 +1,28 @@
+package metrics
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Handler serves every registered metric, in OpenMetrics when the scraper accepts it (which
+// includes exemplars) and in the Prometheus text format otherwise
+func Handler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method not allowed, use GET", http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := FormatText
+	contentType := "text/plain; version=0.0.4; charset=utf-8"
+	if strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text") {
+		format = FormatOpenMetrics
+		contentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	if r.Method == http.MethodHead {
+		return
+	}
+	Write(w, format)
+}
@@ -0,0 +1,197 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the histogram upper bounds in seconds used for latencies
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Format is an exposition format metrics can be written in
+type Format int
+
+const (
+	// FormatText is the Prometheus text format (version 0.0.4)
+	FormatText Format = iota
+	// FormatOpenMetrics is OpenMetrics 1.0, the only format that carries exemplars
+	FormatOpenMetrics
+)
+
+// metric is a family of series that can be written in either exposition format
+type metric interface {
+	write(w io.Writer, format Format)
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]metric{}
+)
+
+// register adds a metric family, panicking on a duplicate name as that is a programming error
+func register(name string, m metric) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, exists := registry[name]; exists {
+		panic(fmt.Sprintf("metric %s registered twice", name))
+	}
+	registry[name] = m
+}
+
+// Write writes every registered metric, sorted by name, in the given format
+func Write(w io.Writer, format Format) {
+	registryMu.Lock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	families := make([]metric, 0, len(names))
+	sort.Strings(names)
+	for _, name := range names {
+		families = append(families, registry[name])
+	}
+	registryMu.Unlock()
+
+	for _, family := range families {
+		family.write(w, format)
+	}
+	if format == FormatOpenMetrics {
+		io.WriteString(w, "# EOF\n")
+	}
+}
+
+// writeHeader writes the HELP and TYPE lines of a family
+// OpenMetrics names a counter family without its _total suffix, which stays on the sample
+func writeHeader(w io.Writer, format Format, name, help, kind string) {
+	if format == FormatOpenMetrics && kind == "counter" {
+		name = strings.TrimSuffix(name, "_total")
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// family holds the series of one metric, keyed by their label values
+type family struct {
+	name   string
+	help   string
+	kind   string // counter, gauge or histogram
+	labels []string
+	bounds []float64 // histogram bucket upper bounds
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+	buckets     []uint64 // histograms only, per bucket (not cumulative)
+	sum         float64
+	count       uint64
+	// exemplars holds the latest traced observation of each bucket, the last one being +Inf
+	exemplars []*exemplar
+}
+
+// exemplar links an observation to the trace of the request that produced it
+type exemplar struct {
+	traceID string
+	value   float64
+	at      time.Time
+}
+
+func newFamily(name, help, kind string, labels []string, bounds []float64) *family {
+	f := &family{name: name, help: help, kind: kind, labels: labels, bounds: bounds, series: map[string]*series{}}
+	register(name, f)
+	return f
+}
+
+// get returns the series for a set of label values, creating it on first use (caller holds f.mu)
+func (f *family) get(labelValues []string) *series {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", f.name, len(f.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		if f.bounds != nil {
+			s.buckets = make([]uint64, len(f.bounds))
+			s.exemplars = make([]*exemplar, len(f.bounds)+1)
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+func (f *family) write(w io.Writer, format Format) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	writeHeader(w, format, f.name, f.help, f.kind)
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := f.series[key]
+		if f.bounds == nil {
+			fmt.Fprintf(w, "%s%s %s\n", f.name, formatLabels(f.labels, s.labelValues, ""), formatValue(s.value))
+			continue
+		}
+		cumulative := uint64(0)
+		for i, bound := range f.bounds {
+			cumulative += s.buckets[i]
+			fmt.Fprintf(w, "%s_bucket%s %d%s\n", f.name, formatLabels(f.labels, s.labelValues, formatValue(bound)),
+				cumulative, formatExemplar(format, s.exemplars[i]))
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d%s\n", f.name, formatLabels(f.labels, s.labelValues, "+Inf"),
+			s.count, formatExemplar(format, s.exemplars[len(f.bounds)]))
+		fmt.Fprintf(w, "%s_sum%s %s\n", f.name, formatLabels(f.labels, s.labelValues, ""), formatValue(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", f.name, formatLabels(f.labels, s.labelValues, ""), s.count)
+	}
+}
+
+// formatLabels renders {name="value",...}, adding le for histogram buckets
+func formatLabels(names, values []string, le string) string {
+	if len(names) == 0 && le == "" {
+		return ""
+	}
+	parts := make([]string, 0, len(names)+1)
+	for i, name := range names {
+		parts = append(parts, name+`="`+labelEscaper.Replace(values[i])+`"`)
+	}
+	if le != "" {
+		parts = append(parts, `le="`+le+`"`)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// formatExemplar renders an exemplar suffix for a bucket sample, only in OpenMetrics
+func formatExemplar(format Format, e *exemplar) string {
+	if format != FormatOpenMetrics || e == nil {
+		return ""
+	}
+	return fmt.Sprintf(` # {trace_id="%s"} %s %s`, labelEscaper.Replace(e.traceID), formatValue(e.value),
+		strconv.FormatFloat(float64(e.at.UnixMilli())/1000, 'f', 3, 64))
+}
+
+// labelEscaper escapes label values as the text exposition format requires
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
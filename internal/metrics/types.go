@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// Counter is a monotonically increasing value per label set
+type Counter struct{ f *family }
+
+// NewCounter registers a counter with the given label names
+func NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{f: newFamily(name, help, "counter", labels, nil)}
+}
+
+// Inc adds one to the series of the label values
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v, which must not be negative, to the series of the label values
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	c.f.mu.Lock()
+	c.f.get(labelValues).value += v
+	c.f.mu.Unlock()
+}
+
+// Gauge is a value per label set that can go up and down
+type Gauge struct{ f *family }
+
+// NewGauge registers a gauge with the given label names
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{f: newFamily(name, help, "gauge", labels, nil)}
+}
+
+// Set replaces the series of the label values
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.f.mu.Lock()
+	g.f.get(labelValues).value = v
+	g.f.mu.Unlock()
+}
+
+// Histogram counts observations into cumulative buckets per label set
+type Histogram struct{ f *family }
+
+// NewHistogram registers a histogram; buckets are upper bounds in increasing order,
+// DefaultBuckets when nil
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	if !sort.Float64sAreSorted(buckets) {
+		panic(fmt.Sprintf("metric %s buckets are not sorted", name))
+	}
+	return &Histogram{f: newFamily(name, help, "histogram", labels, buckets)}
+}
+
+// Observe adds one observation to the series of the label values
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.ObserveWithExemplar(v, "", labelValues...)
+}
+
+// ObserveSince observes the seconds elapsed since start
+func (h *Histogram) ObserveSince(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+// ObserveWithExemplar adds one observation and, with a trace id, keeps it as the exemplar of its
+// bucket so a latency spike can be followed to the request trace. An empty trace id records no exemplar
+func (h *Histogram) ObserveWithExemplar(v float64, traceID string, labelValues ...string) {
+	h.f.mu.Lock()
+	defer h.f.mu.Unlock()
+	s := h.f.get(labelValues)
+	bucket := len(h.f.bounds) // +Inf
+	for i, bound := range h.f.bounds {
+		if v <= bound {
+			bucket = i
+			s.buckets[i]++
+			break
+		}
+	}
+	if traceID != "" {
+		s.exemplars[bucket] = &exemplar{traceID: traceID, value: v, at: time.Now()}
+	}
+	s.sum += v
+	s.count++
+}
+
+// valueFunc is a counter or gauge without labels read when metrics are scraped
+type valueFunc struct {
+	name string
+	help string
+	kind string
+	fn   func() float64
+}
+
+// NewGaugeFunc registers a gauge whose value is read from fn on every scrape
+func NewGaugeFunc(name, help string, fn func() float64) {
+	register(name, &valueFunc{name: name, help: help, kind: "gauge", fn: fn})
+}
+
+// NewCounterFunc registers a counter whose value is read from fn on every scrape,
+// for totals that are already kept elsewhere such as connection pool statistics
+func NewCounterFunc(name, help string, fn func() float64) {
+	register(name, &valueFunc{name: name, help: help, kind: "counter", fn: fn})
+}
+
+func (v *valueFunc) write(w io.Writer, format Format) {
+	writeHeader(w, format, v.name, v.help, v.kind)
+	fmt.Fprintf(w, "%s %s\n", v.name, formatValue(v.fn()))
+}
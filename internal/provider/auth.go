@@ -0,0 +1,110 @@
+package provider
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+)
+
+var (
+	// cookie caches the user:password read from rpc.cookie_file until the node rejects it
+	cookieMu sync.Mutex
+	cookie   string
+)
+
+// newHTTPClient builds the RPC client with the TLS settings of rpc.tls
+// Timeouts are set per call (see callTimeout), so the client itself has none
+func newHTTPClient() (*http.Client, error) {
+	tlsConf := config.Conf.Rpc.TLS
+	if tlsConf == (config.RpcTLSConfig{}) {
+		return &http.Client{}, nil
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         tlsConf.ServerName,
+		InsecureSkipVerify: tlsConf.InsecureSkipVerify,
+	}
+	if tlsConf.CAFile != "" {
+		pem, err := os.ReadFile(tlsConf.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rpc.tls.ca_file: %w", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("rpc.tls.ca_file %s contains no PEM certificates", tlsConf.CAFile)
+		}
+		tlsConfig.RootCAs = roots
+	}
+	if tlsConf.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(tlsConf.CertFile, tlsConf.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load rpc.tls client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport}, nil
+}
+
+// callTimeout returns how long one attempt of an RPC method may take
+func callTimeout(method string) time.Duration {
+	if seconds, ok := config.Conf.Rpc.MethodTimeouts[method]; ok {
+		return time.Duration(seconds) * time.Second
+	}
+	return time.Duration(config.Conf.Rpc.Timeout) * time.Second
+}
+
+// setAuth adds the node credentials to a request: the cookie file when set, otherwise
+// rpc.username/password. Without either, credentials in rpc.url are sent as they are
+func setAuth(req *http.Request) error {
+	rpc := config.Conf.Rpc
+	if rpc.CookieFile != "" {
+		user, password, err := readCookie(rpc.CookieFile)
+		if err != nil {
+			return err
+		}
+		req.SetBasicAuth(user, password)
+		return nil
+	}
+	if rpc.Username != "" {
+		req.SetBasicAuth(rpc.Username, rpc.Password)
+	}
+	return nil
+}
+
+// readCookie returns the credentials of a zcashd/zebra cookie file, formatted as user:password
+func readCookie(path string) (string, string, error) {
+	cookieMu.Lock()
+	defer cookieMu.Unlock()
+
+	if cookie == "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read rpc.cookie_file: %w", err)
+		}
+		cookie = strings.TrimSpace(string(data))
+	}
+
+	user, password, ok := strings.Cut(cookie, ":")
+	if !ok {
+		cookie = ""
+		return "", "", fmt.Errorf("rpc.cookie_file %s is not in user:password format", path)
+	}
+	return user, password, nil
+}
+
+// resetCookie drops the cached cookie, so the next call reads the file a restarted node rewrote
+func resetCookie() {
+	cookieMu.Lock()
+	defer cookieMu.Unlock()
+	cookie = ""
+}
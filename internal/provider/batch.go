@@ -0,0 +1,158 @@
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+)
+
+// errBatchUnsupported is returned when the node answers a JSON-RPC batch with a single response
+var errBatchUnsupported = errors.New("node does not support JSON-RPC batch requests")
+
+var (
+	// batchUnsupported is set once the node rejects a batch; blocks are then fetched one by one
+	batchUnsupported atomic.Bool
+
+	// prefetched holds the hashes and blocks of the batch being indexed until IndexBlock takes them
+	prefetchMu       sync.Mutex
+	prefetchedHashes = map[int64]string{}
+	prefetchedBlocks = map[string]map[string]interface{}{}
+)
+
+// makeBatchRPCCall sends one call of method per params entry in a single JSON-RPC batch request
+// and returns the results in the same order. It fails if any call of the batch fails
+func makeBatchRPCCall(method string, paramsList [][]interface{}) ([]json.RawMessage, error) {
+	requests := make([]RPCRequest, len(paramsList))
+	for i, params := range paramsList {
+		requests[i] = RPCRequest{Jsonrpc: "2.0", Method: method, Params: params, ID: i}
+	}
+
+	jsonData, err := json.Marshal(requests)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal batch request: %w", err)
+	}
+
+	return withRetries(method+"_batch", func() ([]json.RawMessage, error) {
+		body, err := postRPC(method, jsonData)
+		if err != nil {
+			return nil, err
+		}
+
+		var responses []RPCResponse
+		if err := json.Unmarshal(body, &responses); err != nil {
+			// Nodes without batch support answer with one error object instead of an array
+			var single RPCResponse
+			if json.Unmarshal(body, &single) == nil {
+				return nil, errBatchUnsupported
+			}
+			return nil, fmt.Errorf("failed to unmarshal batch response: %w", err)
+		}
+
+		results := make([]json.RawMessage, len(requests))
+		for _, resp := range responses {
+			if resp.ID < 0 || resp.ID >= len(results) {
+				return nil, fmt.Errorf("batch response has unknown id %d", resp.ID)
+			}
+			if resp.Error != nil {
+				return nil, fmt.Errorf("RPC error in batch call %d: %s (code: %d)", resp.ID, resp.Error.Message, resp.Error.Code)
+			}
+			results[resp.ID] = resp.Result
+		}
+		for i, result := range results {
+			if result == nil {
+				return nil, fmt.Errorf("batch response is missing call %d", i)
+			}
+		}
+		return results, nil
+	})
+}
+
+// PrefetchBlocks fetches the hashes and then the blocks of a height range with one batch request
+// each, replacing whatever an earlier batch left unread. It does nothing without indexer.batch_rpc,
+// when blocks need a getrawtransaction per transaction, or once the node has rejected a batch
+// On failure the blocks are simply fetched one by one
+func PrefetchBlocks(fromHeight, toHeight int64) {
+	prefetchMu.Lock()
+	clear(prefetchedHashes)
+	clear(prefetchedBlocks)
+	prefetchMu.Unlock()
+
+	if !config.Conf.Indexer.BatchRpc || !verboseBlocksSupported || batchUnsupported.Load() || toHeight < fromHeight {
+		return
+	}
+
+	heights := make([][]interface{}, 0, toHeight-fromHeight+1)
+	for height := fromHeight; height <= toHeight; height++ {
+		heights = append(heights, []interface{}{height})
+	}
+	hashResults, err := makeBatchRPCCall("getblockhash", heights)
+	if err != nil {
+		prefetchFailed(err)
+		return
+	}
+
+	hashes := make([]string, len(hashResults))
+	blockParams := make([][]interface{}, len(hashResults))
+	for i, result := range hashResults {
+		if err := json.Unmarshal(result, &hashes[i]); err != nil {
+			prefetchFailed(fmt.Errorf("failed to unmarshal block hash: %w", err))
+			return
+		}
+		// Verbosity 2 to get full transaction details, as in GetBlock
+		blockParams[i] = []interface{}{hashes[i], 2}
+	}
+
+	blockResults, err := makeBatchRPCCall("getblock", blockParams)
+	if err != nil {
+		prefetchFailed(err)
+		return
+	}
+
+	blocks := make([]map[string]interface{}, len(blockResults))
+	for i, result := range blockResults {
+		if err := json.Unmarshal(result, &blocks[i]); err != nil {
+			prefetchFailed(fmt.Errorf("failed to unmarshal block %s: %w", hashes[i], err))
+			return
+		}
+		fillZatAmounts(blocks[i])
+	}
+
+	prefetchMu.Lock()
+	defer prefetchMu.Unlock()
+	for i, hash := range hashes {
+		prefetchedHashes[fromHeight+int64(i)] = hash
+		prefetchedBlocks[hash] = blocks[i]
+	}
+}
+
+func prefetchFailed(err error) {
+	if errors.Is(err, errBatchUnsupported) {
+		batchUnsupported.Store(true)
+		log.Printf("Node rejected a JSON-RPC batch request, fetching blocks one by one from now on")
+		return
+	}
+	log.Printf("Failed to prefetch blocks, fetching them one by one: %v", err)
+}
+
+// takePrefetchedHash returns and forgets the prefetched hash of the block at height
+func takePrefetchedHash(height int64) (string, bool) {
+	prefetchMu.Lock()
+	defer prefetchMu.Unlock()
+	hash, ok := prefetchedHashes[height]
+	delete(prefetchedHashes, height)
+	return hash, ok
+}
+
+// takePrefetchedBlock returns and forgets the prefetched block with the given hash
+func takePrefetchedBlock(hash string) (map[string]interface{}, bool) {
+	prefetchMu.Lock()
+	defer prefetchMu.Unlock()
+	block, ok := prefetchedBlocks[hash]
+	delete(prefetchedBlocks, hash)
+	return block, ok
+}
@@ -0,0 +1,129 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+)
+
+const zatsPerZec = 1e8
+
+// verboseBlocksSupported is set by detectCapabilities; when false, blocks are fetched with
+// verbosity 1 and each transaction is fetched with getrawtransaction
+var verboseBlocksSupported = true
+
+// detectCapabilities probes the node with a getblock of the genesis block at verbosity 2
+// Nodes that reject the verbosity or return only txids get the per-transaction fallback
+func detectCapabilities() {
+	hash, err := GetBlockHash(0)
+	if err != nil {
+		log.Printf("Failed to detect node capabilities, assuming getblock verbosity 2 is supported: %v", err)
+		return
+	}
+
+	block, err := getBlockWithVerbosity(hash, 2)
+	if err != nil || !hasVerboseTransactions(block) {
+		verboseBlocksSupported = false
+		log.Printf("Node does not return full transactions from getblock verbosity 2, falling back to getrawtransaction per txid (requires -txindex)")
+		return
+	}
+
+	log.Println("Node supports getblock verbosity 2")
+}
+
+// hasVerboseTransactions reports whether a getblock result carries transaction objects rather than txids
+func hasVerboseTransactions(block map[string]interface{}) bool {
+	txs, ok := block["tx"].([]interface{})
+	if !ok || len(txs) == 0 {
+		return false
+	}
+	_, ok = txs[0].(map[string]interface{})
+	return ok
+}
+
+func getBlockWithVerbosity(hash string, verbosity int) (map[string]interface{}, error) {
+	result, err := makeRPCCall("getblock", []interface{}{hash, verbosity})
+	if err != nil {
+		return nil, err
+	}
+
+	var block map[string]interface{}
+	if err := json.Unmarshal(result, &block); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal block: %w", err)
+	}
+
+	return block, nil
+}
+
+// getBlockWithRawTransactions fetches a block at verbosity 1 and replaces its txids with the
+// verbose transactions from getrawtransaction, matching the shape of a verbosity 2 result
+func getBlockWithRawTransactions(hash string) (map[string]interface{}, error) {
+	block, err := getBlockWithVerbosity(hash, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	txids, ok := block["tx"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("block %s has no tx list", hash)
+	}
+
+	txs := make([]interface{}, len(txids))
+	for i, rawTxid := range txids {
+		txid, ok := rawTxid.(string)
+		if !ok {
+			return nil, fmt.Errorf("block %s tx[%d] is not a txid", hash, i)
+		}
+
+		result, err := makeRPCCall("getrawtransaction", []interface{}{txid, 1})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get transaction %s: %w", txid, err)
+		}
+
+		var tx map[string]interface{}
+		if err := json.Unmarshal(result, &tx); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal transaction %s: %w", txid, err)
+		}
+		txs[i] = tx
+	}
+	block["tx"] = txs
+
+	return block, nil
+}
+
+// fillZatAmounts computes the zatoshi amounts that older nodes omit from their ZEC counterparts
+// Existing zatoshi amounts are left untouched
+func fillZatAmounts(block map[string]interface{}) {
+	txs, _ := block["tx"].([]interface{})
+	for _, rawTx := range txs {
+		tx, ok := rawTx.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		fillZat(tx, "valueBalance", "valueBalanceZat")
+		if orchard, ok := tx["orchard"].(map[string]interface{}); ok {
+			fillZat(orchard, "valueBalance", "valueBalanceZat")
+		}
+
+		vouts, _ := tx["vout"].([]interface{})
+		for _, rawVout := range vouts {
+			if vout, ok := rawVout.(map[string]interface{}); ok {
+				fillZat(vout, "value", "valueZat")
+			}
+		}
+	}
+}
+
+// fillZat sets obj[zatKey] from the ZEC amount in obj[zecKey] when only the latter is present
+func fillZat(obj map[string]interface{}, zecKey, zatKey string) {
+	if _, ok := obj[zatKey]; ok {
+		return
+	}
+	zec, ok := obj[zecKey].(float64)
+	if !ok {
+		return
+	}
+	obj[zatKey] = math.Round(zec * zatsPerZec)
+}
@@ -2,20 +2,35 @@ package provider
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"sync"
 	"time"
 
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/chaos"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/indexer"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/mempool"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/metrics"
 )
 
 var (
 	client       *http.Client
 	ErrorChannel chan error
+
+	// blockSources maps a fetched block hash to the endpoint that served it, until the indexer reads it
+	blockSources sync.Map
+
+	rpcCallDuration = metrics.NewHistogram("zindex_rpc_call_duration_seconds",
+		"Time to complete an RPC call, including retries", nil, "method")
+	rpcErrors = metrics.NewCounter("zindex_rpc_errors_total",
+		"Failed RPC attempts, including ones that a retry later recovered", "method")
 )
 
 type RPCRequest struct {
@@ -39,24 +54,73 @@ type RPCError struct {
 func InitProvider(startBlock int64) error {
 	log.Println("Initializing Zcash provider...")
 
-	client = &http.Client{
-		Timeout: time.Duration(config.Conf.Rpc.Timeout) * time.Second,
+	rpcClient, err := initClient()
+	if err != nil {
+		return err
 	}
 
-	// Create RPC client wrapper for the indexer
-	rpcClient := &rpcClientWrapper{}
-
 	// Start the indexer
 	_, ErrorChannel = indexer.Start(startBlock, rpcClient)
 
+	// Poll the node mempool (modules.mempool)
+	mempool.Start(rpcClient)
+
 	return nil
 }
 
+// InitClient connects the RPC client without starting the indexer or the mempool poller,
+// for tools that fetch blocks themselves
+func InitClient() (indexer.RpcClient, error) {
+	return initClient()
+}
+
+func initClient() (*rpcClientWrapper, error) {
+	var err error
+	client, err = newHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	// Pick how blocks are fetched before the indexer asks for any
+	detectCapabilities()
+
+	// Create RPC client wrapper for the indexer
+	return &rpcClientWrapper{}, nil
+}
+
 func CloseProvider() {
 	log.Println("Stopping provider...")
+	mempool.Stop()
 	indexer.Stop()
 }
 
+// currentEndpoint returns the RPC endpoint calls are sent to
+func currentEndpoint() string {
+	return config.Conf.Rpc.Url
+}
+
+// redactEndpoint strips credentials, query and fragment from an endpoint URL so it can be stored
+func redactEndpoint(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return ""
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
+
+// BlockSource returns the redacted endpoint that served the block with the given hash, or ""
+// when the block was not fetched through GetBlock. The entry is removed once read
+func BlockSource(hash string) string {
+	source, ok := blockSources.LoadAndDelete(hash)
+	if !ok {
+		return ""
+	}
+	return source.(string)
+}
+
 func makeRPCCall(method string, params []interface{}) (json.RawMessage, error) {
 	request := RPCRequest{
 		Jsonrpc: "2.0",
@@ -70,55 +134,96 @@ func makeRPCCall(method string, params []interface{}) (json.RawMessage, error) {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
+	return withRetries(method, func() (json.RawMessage, error) {
+		body, err := postRPC(method, jsonData)
+		if err != nil {
+			return nil, err
+		}
+
+		var rpcResp RPCResponse
+		if err := json.Unmarshal(body, &rpcResp); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		if rpcResp.Error != nil {
+			return nil, fmt.Errorf("RPC error: %s (code: %d)", rpcResp.Error.Message, rpcResp.Error.Code)
+		}
+		return rpcResp.Result, nil
+	})
+}
+
+// withRetries runs attempt up to rpc.retry_attempts times, waiting rpc.retry_delay between
+// attempts, and records the call's duration and failures under method
+func withRetries[T any](method string, attempt func() (T, error)) (T, error) {
+	start := time.Now()
+	defer rpcCallDuration.ObserveSince(start, method)
+
 	var lastErr error
 	maxAttempts := config.Conf.Rpc.RetryAttempts
 	if maxAttempts < 1 {
 		maxAttempts = 1
 	}
 
-	for attempt := 0; attempt < maxAttempts; attempt++ {
-		if attempt > 0 {
+	for i := 0; i < maxAttempts; i++ {
+		if i > 0 {
 			retryDelay := time.Duration(config.Conf.Rpc.RetryDelay) * time.Second
-			log.Printf("Retrying RPC call to %s (attempt %d/%d) after %v", method, attempt+1, maxAttempts, retryDelay)
+			rpcErrors.Inc(method)
+			log.Printf("Retrying RPC call to %s (attempt %d/%d) after %v", method, i+1, maxAttempts, retryDelay)
 			time.Sleep(retryDelay)
 		}
 
-		req, err := http.NewRequest("POST", config.Conf.Rpc.Url, bytes.NewBuffer(jsonData))
-		if err != nil {
-			lastErr = fmt.Errorf("failed to create request: %w", err)
-			continue
+		result, err := attempt()
+		if err == nil {
+			return result, nil
+		}
+		lastErr = err
+		// A node that does not understand batches will not on a retry either
+		if errors.Is(err, errBatchUnsupported) {
+			break
 		}
+	}
 
-		req.Header.Set("Content-Type", "application/json")
+	rpcErrors.Inc(method)
+	var zero T
+	return zero, fmt.Errorf("RPC call failed after %d attempts: %w", maxAttempts, lastErr)
+}
 
-		resp, err := client.Do(req)
-		if err != nil {
-			lastErr = fmt.Errorf("failed to execute request: %w", err)
-			continue
-		}
+// postRPC sends one JSON-RPC request body, bounded by the method's timeout, and returns the response body
+func postRPC(method string, jsonData []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout(method))
+	defer cancel()
 
-		body, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			lastErr = fmt.Errorf("failed to read response: %w", err)
-			continue
-		}
+	req, err := http.NewRequestWithContext(ctx, "POST", currentEndpoint(), bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
 
-		var rpcResp RPCResponse
-		if err := json.Unmarshal(body, &rpcResp); err != nil {
-			lastErr = fmt.Errorf("failed to unmarshal response: %w", err)
-			continue
-		}
+	req.Header.Set("Content-Type", "application/json")
+	if err := setAuth(req); err != nil {
+		return nil, err
+	}
 
-		if rpcResp.Error != nil {
-			lastErr = fmt.Errorf("RPC error: %s (code: %d)", rpcResp.Error.Message, rpcResp.Error.Code)
-			continue
-		}
+	if err := chaos.RPCTimeout(method); err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
 
-		return rpcResp.Result, nil
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	return nil, fmt.Errorf("RPC call failed after %d attempts: %w", maxAttempts, lastErr)
+	// Nodes answer bad credentials with an empty 401 (zcashd) or 403 rather than a JSON-RPC error
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		resetCookie()
+		return nil, fmt.Errorf("RPC authentication failed (HTTP %d), check rpc.username/password or rpc.cookie_file", resp.StatusCode)
+	}
+
+	return body, nil
 }
 
 func GetBlockCount() (int64, error) {
@@ -136,6 +241,10 @@ func GetBlockCount() (int64, error) {
 }
 
 func GetBlockHash(height int64) (string, error) {
+	if hash, ok := takePrefetchedHash(height); ok {
+		return hash, nil
+	}
+
 	result, err := makeRPCCall("getblockhash", []interface{}{height})
 	if err != nil {
 		return "", err
@@ -150,22 +259,104 @@ func GetBlockHash(height int64) (string, error) {
 }
 
 func GetBlock(hash string) (map[string]interface{}, error) {
-	// Use verbosity 2 to get full transaction details
-	result, err := makeRPCCall("getblock", []interface{}{hash, 2})
-	if err != nil {
-		return nil, err
+	endpoint := currentEndpoint()
+	if block, ok := takePrefetchedBlock(hash); ok {
+		blockSources.Store(hash, redactEndpoint(endpoint))
+		return block, nil
 	}
 
 	var block map[string]interface{}
-	if err := json.Unmarshal(result, &block); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal block: %w", err)
+	var err error
+	if verboseBlocksSupported {
+		// Use verbosity 2 to get full transaction details
+		block, err = getBlockWithVerbosity(hash, 2)
+	} else {
+		block, err = getBlockWithRawTransactions(hash)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	fillZatAmounts(block)
+	blockSources.Store(hash, redactEndpoint(endpoint))
 
 	return block, nil
 }
 
-// rpcClientWrapper implements the indexer.RpcClient interface
-// It wraps the provider's RPC functions for use by the indexer
+// GetRawMempool returns the txids in the node's mempool
+func GetRawMempool() ([]string, error) {
+	result, err := makeRPCCall("getrawmempool", []interface{}{})
+	if err != nil {
+		return nil, err
+	}
+
+	var txids []string
+	if err := json.Unmarshal(result, &txids); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal raw mempool: %w", err)
+	}
+
+	return txids, nil
+}
+
+// GetRawTransaction fetches a verbose transaction, with the zatoshi amounts older nodes omit filled in
+// Transactions in the mempool can be fetched without -txindex
+func GetRawTransaction(txid string) (map[string]interface{}, error) {
+	result, err := makeRPCCall("getrawtransaction", []interface{}{txid, 1})
+	if err != nil {
+		return nil, err
+	}
+
+	var tx map[string]interface{}
+	if err := json.Unmarshal(result, &tx); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal transaction %s: %w", txid, err)
+	}
+	fillZatAmounts(map[string]interface{}{"tx": []interface{}{tx}})
+
+	return tx, nil
+}
+
+// GetChain returns the network the node runs on (main, test or regtest)
+func GetChain() (string, error) {
+	result, err := makeRPCCall("getblockchaininfo", []interface{}{})
+	if err != nil {
+		return "", err
+	}
+
+	var info struct {
+		Chain string `json:"chain"`
+	}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return "", fmt.Errorf("failed to unmarshal blockchain info: %w", err)
+	}
+
+	return info.Chain, nil
+}
+
+// GenerateBlocks mines count blocks on a regtest node and returns their hashes
+// With an address the coinbase pays it through generatetoaddress; otherwise generate is used and
+// the node pays its configured miner address
+func GenerateBlocks(count int, address string) ([]string, error) {
+	var result json.RawMessage
+	var err error
+	if address != "" {
+		result, err = makeRPCCall("generatetoaddress", []interface{}{count, address})
+	} else {
+		result, err = makeRPCCall("generate", []interface{}{count})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var hashes []string
+	if err := json.Unmarshal(result, &hashes); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal generated block hashes: %w", err)
+	}
+
+	return hashes, nil
+}
+
+// rpcClientWrapper implements the indexer.RpcClient and mempool.RpcClient interfaces
+// It wraps the provider's RPC functions for use by the indexer and the mempool poller
 type rpcClientWrapper struct{}
 
 func (w *rpcClientWrapper) GetBlockHash(height int64) (string, error) {
@@ -179,3 +370,19 @@ func (w *rpcClientWrapper) GetBlock(hash string) (map[string]interface{}, error)
 func (w *rpcClientWrapper) GetBlockCount() (int64, error) {
 	return GetBlockCount()
 }
+
+func (w *rpcClientWrapper) BlockSource(hash string) string {
+	return BlockSource(hash)
+}
+
+func (w *rpcClientWrapper) PrefetchBlocks(fromHeight, toHeight int64) {
+	PrefetchBlocks(fromHeight, toHeight)
+}
+
+func (w *rpcClientWrapper) GetRawMempool() ([]string, error) {
+	return GetRawMempool()
+}
+
+func (w *rpcClientWrapper) GetRawTransaction(txid string) (map[string]interface{}, error) {
+	return GetRawTransaction(txid)
+}
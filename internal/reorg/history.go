@@ -0,0 +1,77 @@
+package reorg
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+)
+
+func init() {
+	// Register this as a core schema (always initialized)
+	postgres.RegisterCoreSchema("reorgs", InitSchema)
+}
+
+// InitSchema creates the reorgs table, the history of chain reorganizations the indexer handled
+// Rows survive the rollback they describe
+func InitSchema() error {
+	schema := `
+		CREATE TABLE IF NOT EXISTS reorgs (
+			id BIGSERIAL PRIMARY KEY,
+			old_tip_height BIGINT NOT NULL,  -- last height indexed before the reorg
+			old_tip_hash VARCHAR(64),  -- hash of the block orphaned at old_tip_height
+			common_ancestor BIGINT NOT NULL,  -- indexing restarted from the block after it
+			depth INTEGER NOT NULL,  -- blocks rolled back
+			detected_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_reorgs_detected_at ON reorgs(detected_at);
+	`
+
+	_, err := postgres.DB.Exec(context.Background(), schema)
+	if err != nil {
+		return fmt.Errorf("failed to create reorgs schema: %w", err)
+	}
+
+	return nil
+}
+
+// Reorg is a chain reorganization handled by the indexer
+type Reorg struct {
+	ID             int64     `json:"id" db:"id"`
+	OldTipHeight   int64     `json:"old_tip_height" db:"old_tip_height"`
+	OldTipHash     *string   `json:"old_tip_hash" db:"old_tip_hash"`
+	CommonAncestor int64     `json:"common_ancestor" db:"common_ancestor"`
+	Depth          int       `json:"depth" db:"depth"`
+	DetectedAt     time.Time `json:"detected_at" db:"detected_at"`
+}
+
+// recordReorg appends a handled reorg to the history
+// The rollback is already committed, so a failure is only logged
+func recordReorg(oldTipHeight int64, oldTipHash *string, commonAncestor int64, depth int) {
+	_, err := postgres.DB.Exec(context.Background(),
+		`INSERT INTO reorgs (old_tip_height, old_tip_hash, common_ancestor, depth) VALUES ($1, $2, $3, $4)`,
+		oldTipHeight, oldTipHash, commonAncestor, depth,
+	)
+	if err != nil {
+		log.Printf("Failed to record reorg at height %d: %v", oldTipHeight, err)
+	}
+}
+
+// GetRecentReorgs returns the most recently handled reorgs, newest first
+func GetRecentReorgs(limit int) ([]Reorg, error) {
+	reorgs, err := postgres.PostgresQueryCtx[Reorg](context.Background(), nil,
+		`SELECT id, old_tip_height, old_tip_hash, common_ancestor, depth, detected_at
+		 FROM reorgs
+		 ORDER BY detected_at DESC, id DESC
+		 LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent reorgs: %w", err)
+	}
+
+	return reorgs, nil
+}
@@ -9,9 +9,14 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/metrics"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/types"
 )
 
+// reorgDepthBlocks is the number of blocks rolled back by each handled reorg
+var reorgDepthBlocks = metrics.NewHistogram("zindex_reorg_depth_blocks",
+	"Blocks rolled back per handled reorg", []float64{1, 2, 3, 4, 6, 8, 12, 16, 32, 64, 100})
+
 // RpcClient interface defines the methods required for reorg detection
 type RpcClient interface {
 	GetBlockHash(height int64) (string, error)
@@ -140,11 +145,19 @@ func HandleReorg(currentHeight int64, rpcClient RpcClient) (*ReorgError, error)
 	reorgDepth := int(currentHeight - 1 - commonAncestor)
 	log.Printf("Reorg depth: %d blocks (from height %d to %d)", reorgDepth, currentHeight-1, commonAncestor)
 
+	// Keep the orphaned tip's hash for the reorg history before it is rolled back
+	var oldTipHash *string
+	if hash, err := postgres.GetBlockHashAtHeight(currentHeight - 1); err == nil {
+		oldTipHash = &hash
+	}
+
 	// Rollback the database
 	ctx := context.Background()
 	if err := postgres.RollbackToHeight(ctx, commonAncestor); err != nil {
 		return nil, fmt.Errorf("failed to rollback to height %d: %w", commonAncestor, err)
 	}
+	reorgDepthBlocks.Observe(float64(reorgDepth))
+	recordReorg(currentHeight-1, oldTipHash, commonAncestor, reorgDepth)
 
 	// Return the reorg error with the new start height
 	return &ReorgError{
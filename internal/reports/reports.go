@@ -0,0 +1,149 @@
+package reports
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+)
+
+// MaxStringParamLength bounds string and hex report parameters
+const MaxStringParamLength = 256
+
+var hexPattern = regexp.MustCompile(`^[0-9a-fA-F]*$`)
+
+// ListReports returns the configured reports sorted by name
+func ListReports() []Report {
+	reports := make([]Report, 0, len(config.Conf.Reports))
+	for name, report := range config.Conf.Reports {
+		params := make([]Param, 0, len(report.Params))
+		for _, param := range report.Params {
+			params = append(params, Param{Name: param.Name, Type: param.Type, Required: param.Required, Default: param.Default})
+		}
+		reports = append(reports, Report{Name: name, Description: report.Description, Params: params, MaxRows: report.MaxRows})
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Name < reports[j].Name })
+	return reports
+}
+
+// BindParams converts the request values of a report's declared parameters into query arguments
+// Values that are not declared parameters are rejected, as are values that do not parse as their type
+func BindParams(report config.ReportConfig, values map[string]string) ([]interface{}, error) {
+	declared := make(map[string]bool, len(report.Params))
+	for _, param := range report.Params {
+		declared[param.Name] = true
+	}
+	for name := range values {
+		if !declared[name] {
+			return nil, fmt.Errorf("unknown parameter: %s", name)
+		}
+	}
+
+	args := make([]interface{}, 0, len(report.Params))
+	for _, param := range report.Params {
+		raw, ok := values[param.Name]
+		if !ok || raw == "" {
+			if param.Required {
+				return nil, fmt.Errorf("missing required parameter: %s", param.Name)
+			}
+			if param.Default == "" {
+				args = append(args, nil)
+				continue
+			}
+			raw = param.Default
+		}
+
+		arg, err := parseParam(param.Type, raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameter %s: %w", param.Name, err)
+		}
+		args = append(args, arg)
+	}
+	return args, nil
+}
+
+func parseParam(paramType, raw string) (interface{}, error) {
+	switch paramType {
+	case "int":
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("must be an integer")
+		}
+		return value, nil
+	case "bool":
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("must be true or false")
+		}
+		return value, nil
+	case "hex":
+		if len(raw) > MaxStringParamLength || !hexPattern.MatchString(raw) {
+			return nil, fmt.Errorf("must be a hex string of at most %d characters", MaxStringParamLength)
+		}
+		return strings.ToLower(raw), nil
+	default:
+		if len(raw) > MaxStringParamLength {
+			return nil, fmt.Errorf("must be at most %d characters", MaxStringParamLength)
+		}
+		return raw, nil
+	}
+}
+
+// RunReport runs a report's query in a read-only transaction with the bound arguments
+// At most max_rows rows are returned; Truncated is set when the query matched more
+func RunReport(ctx context.Context, name string, report config.ReportConfig, args []interface{}) (*Result, error) {
+	tx, err := postgres.DB.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if report.Timeout > 0 {
+		if _, err := tx.Exec(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", report.Timeout*1000)); err != nil {
+			return nil, fmt.Errorf("failed to set report timeout: %w", err)
+		}
+	}
+
+	// Fetch one row past the limit to tell a full result from a truncated one
+	query := fmt.Sprintf("SELECT * FROM (%s) AS report LIMIT %d", report.Query, report.MaxRows+1)
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run report %s: %w", name, err)
+	}
+	defer rows.Close()
+
+	result := &Result{Name: name, Columns: []string{}, Rows: [][]interface{}{}}
+	for _, field := range rows.FieldDescriptions() {
+		result.Columns = append(result.Columns, field.Name)
+	}
+	for rows.Next() {
+		if len(result.Rows) == report.MaxRows {
+			result.Truncated = true
+			break
+		}
+		values, err := rows.Values()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read report %s row: %w", name, err)
+		}
+		for i, value := range values {
+			// bytea columns are returned as hex, matching how hashes are stored elsewhere
+			if b, ok := value.([]byte); ok {
+				values[i] = hex.EncodeToString(b)
+			}
+		}
+		result.Rows = append(result.Rows, values)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to run report %s: %w", name, err)
+	}
+
+	result.RowCount = len(result.Rows)
+	return result, nil
+}
@@ -0,0 +1,105 @@
+package reports
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+)
+
+func TestBindParams(t *testing.T) {
+	report := config.ReportConfig{
+		Params: []config.ReportParamConfig{
+			{Name: "height", Type: "int", Required: true},
+			{Name: "final", Type: "bool", Default: "false"},
+			{Name: "program", Type: "hex"},
+			{Name: "label", Type: "string", Default: "all"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		values  map[string]string
+		want    []interface{}
+		wantErr string
+	}{
+		{
+			name:   "defaults",
+			values: map[string]string{"height": "100"},
+			want:   []interface{}{int64(100), false, nil, "all"},
+		},
+		{
+			name:   "all values",
+			values: map[string]string{"height": "-5", "final": "true", "program": "ABcd01", "label": "x"},
+			want:   []interface{}{int64(-5), true, "abcd01", "x"},
+		},
+		{
+			name:   "empty value uses the default",
+			values: map[string]string{"height": "1", "label": ""},
+			want:   []interface{}{int64(1), false, nil, "all"},
+		},
+		{
+			name:   "empty hex",
+			values: map[string]string{"height": "1", "program": ""},
+			want:   []interface{}{int64(1), false, nil, "all"},
+		},
+		{
+			name:    "missing required",
+			values:  map[string]string{"final": "true"},
+			wantErr: "missing required parameter: height",
+		},
+		{
+			name:    "empty required",
+			values:  map[string]string{"height": ""},
+			wantErr: "missing required parameter: height",
+		},
+		{
+			name:    "unknown parameter",
+			values:  map[string]string{"height": "1", "limit": "10"},
+			wantErr: "unknown parameter: limit",
+		},
+		{
+			name:    "invalid int",
+			values:  map[string]string{"height": "1.5"},
+			wantErr: "invalid parameter height: must be an integer",
+		},
+		{
+			name:    "invalid bool",
+			values:  map[string]string{"height": "1", "final": "yes"},
+			wantErr: "invalid parameter final: must be true or false",
+		},
+		{
+			name:    "invalid hex",
+			values:  map[string]string{"height": "1", "program": "0xzz"},
+			wantErr: "invalid parameter program: must be a hex string",
+		},
+		{
+			name:    "hex too long",
+			values:  map[string]string{"height": "1", "program": strings.Repeat("a", MaxStringParamLength+1)},
+			wantErr: "invalid parameter program: must be a hex string",
+		},
+		{
+			name:    "string too long",
+			values:  map[string]string{"height": "1", "label": strings.Repeat("a", MaxStringParamLength+1)},
+			wantErr: "invalid parameter label: must be at most",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BindParams(report, tt.values)
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Fatalf("BindParams() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BindParams() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BindParams() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package reports
+
+// Report describes a configured report template for listings
+type Report struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	Params      []Param `json:"params"`
+	MaxRows     int     `json:"max_rows"`
+}
+
+// Param is a parameter a report accepts
+type Param struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Required bool   `json:"required"`
+	Default  string `json:"default,omitempty"`
+}
+
+// Result is the output of one report run
+// Rows hold the column values in the order of Columns
+type Result struct {
+	Name      string          `json:"name"`
+	Columns   []string        `json:"columns"`
+	Rows      [][]interface{} `json:"rows"`
+	RowCount  int             `json:"row_count"`
+	Truncated bool            `json:"truncated"` // more than max_rows rows matched
+}
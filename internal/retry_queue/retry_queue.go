@@ -0,0 +1,270 @@
+package retry_queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/changefeed"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/logging"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/types"
+)
+
+func init() {
+	// Register this as a core schema (always initialized)
+	postgres.RegisterCoreSchema("failed_items", InitSchema)
+}
+
+// InitSchema creates the failed_items table
+// One row per (module, txid); a transaction that fails again keeps its row and bumps attempts
+func InitSchema() error {
+	schema := `
+		CREATE TABLE IF NOT EXISTS failed_items (
+			id BIGSERIAL PRIMARY KEY,
+			module VARCHAR(20) NOT NULL,
+			txid VARCHAR(64) NOT NULL,
+			block_height BIGINT NOT NULL,
+			block_hash VARCHAR(64) NOT NULL,
+			block_time BIGINT NOT NULL,
+			error TEXT NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 1,
+			tx_data JSONB NOT NULL,
+			first_failed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			last_attempt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (module, txid)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_failed_items_block_height ON failed_items(block_height);
+	`
+
+	_, err := postgres.DB.Exec(context.Background(), schema)
+	if err != nil {
+		return fmt.Errorf("failed to create failed_items schema: %w", err)
+	}
+
+	return nil
+}
+
+// DBTX is an interface that both pgxpool.Pool and pgx.Tx implement
+type DBTX interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// RetryFunc re-indexes a single transaction for a module inside postgresTx
+// block carries the original height, hash and time; block.Tx is empty
+type RetryFunc func(postgresTx DBTX, block *types.ZcashBlock, tx *types.ZcashTransaction) error
+
+// Retrier is how a module retries its failed transactions
+type Retrier struct {
+	Index RetryFunc
+	// Scopes returns the counted rows a retry of tx can add, so the row counters stay exact
+	Scopes func(tx *types.ZcashTransaction) []postgres.RowScope
+	// Publish notifies event subscribers of the rows a retry of tx wrote, once it has committed
+	Publish func(block *types.ZcashBlock, tx *types.ZcashTransaction)
+}
+
+var retriers = make(map[string]Retrier)
+
+// RegisterRetrier registers how a module's failed transactions are retried
+// Modules call this from init(), the same way they register their schemas
+func RegisterRetrier(module string, retrier Retrier) {
+	retriers[module] = retrier
+}
+
+// IsLenient reports whether modules should queue unparseable transactions instead of failing the block
+func IsLenient() bool {
+	return config.Conf.Indexer.Lenient
+}
+
+// Record queues a transaction that failed to index
+// It runs in the module's block transaction so the entry is discarded if the block itself is rolled back
+func Record(postgresTx DBTX, module string, block *types.ZcashBlock, tx *types.ZcashTransaction, cause error) error {
+	txData, err := json.Marshal(tx)
+	if err != nil {
+		return fmt.Errorf("failed to encode failed transaction %s: %w", tx.TxID, err)
+	}
+
+	if postgresTx == nil {
+		postgresTx = postgres.DB
+	}
+
+	_, err = postgresTx.Exec(context.Background(), `
+		INSERT INTO failed_items (module, txid, block_height, block_hash, block_time, error, tx_data)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (module, txid) DO UPDATE SET
+			block_height = EXCLUDED.block_height,
+			block_hash = EXCLUDED.block_hash,
+			block_time = EXCLUDED.block_time,
+			error = EXCLUDED.error,
+			tx_data = EXCLUDED.tx_data,
+			attempts = failed_items.attempts + 1,
+			last_attempt_at = CURRENT_TIMESTAMP
+	`, module, tx.TxID, block.Height, block.Hash, block.Time, cause.Error(), txData)
+	if err != nil {
+		return fmt.Errorf("failed to record failed transaction %s: %w", tx.TxID, err)
+	}
+
+	logging.Warnf(module, "Queued transaction %s in block %d for retry: %v", tx.TxID, block.Height, cause)
+	return nil
+}
+
+// GetFailedItems retrieves queued items, optionally filtered by module, oldest block first
+func GetFailedItems(module string, limit, offset int) ([]FailedItem, error) {
+	items, err := postgres.PostgresQueryCtx[FailedItem](
+		context.Background(), nil,
+		`SELECT id, module, txid, block_height, block_hash, block_time, error, attempts, tx_data,
+		        first_failed_at, last_attempt_at
+		 FROM failed_items
+		 WHERE $1 = '' OR module = $1
+		 ORDER BY block_height, id
+		 LIMIT $2 OFFSET $3`,
+		module, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get failed items: %w", err)
+	}
+
+	return items, nil
+}
+
+// CountFailedItems returns the number of queued items, optionally filtered by module
+func CountFailedItems(module string) (int64, error) {
+	var count int64
+	err := postgres.DB.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM failed_items WHERE $1 = '' OR module = $1`, module,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count failed items: %w", err)
+	}
+	return count, nil
+}
+
+// Retry re-runs queued items through their module's RetryFunc, oldest block first
+// ids limits the run to specific items; when empty every queued item (optionally filtered by module) is retried
+// Each item is retried in its own transaction: success deletes the row, failure updates the error and attempts
+func Retry(module string, ids []int64) (*RetryResult, error) {
+	items, err := postgres.PostgresQueryCtx[FailedItem](
+		context.Background(), nil,
+		`SELECT id, module, txid, block_height, block_hash, block_time, error, attempts, tx_data,
+		        first_failed_at, last_attempt_at
+		 FROM failed_items
+		 WHERE ($1 = '' OR module = $1)
+		   AND (cardinality($2::BIGINT[]) = 0 OR id = ANY($2))
+		 ORDER BY block_height, id`,
+		module, ids,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get failed items: %w", err)
+	}
+
+	result := &RetryResult{Failed: []FailedItem{}}
+	for _, item := range items {
+		result.Attempted++
+		if err := retryItem(&item); err != nil {
+			item.Error = err.Error()
+			item.Attempts++
+			if _, err := postgres.DB.Exec(context.Background(), `
+				UPDATE failed_items
+				SET error = $2, attempts = attempts + 1, last_attempt_at = CURRENT_TIMESTAMP
+				WHERE id = $1
+			`, item.ID, item.Error); err != nil {
+				return nil, fmt.Errorf("failed to update failed item %d: %w", item.ID, err)
+			}
+			result.Failed = append(result.Failed, item)
+			continue
+		}
+		result.Succeeded++
+	}
+
+	return result, nil
+}
+
+// retryItem replays one queued transaction and removes it from the queue in the same transaction
+// The rows it writes are counted, added to the manifest of their block and, for Ztarknet facts,
+// announced in the change feed as if the block had indexed them
+func retryItem(item *FailedItem) error {
+	retrier, ok := retriers[item.Module]
+	if !ok {
+		return fmt.Errorf("no retry function registered for module %s", item.Module)
+	}
+	if !config.IsModuleEnabled(item.Module) {
+		return fmt.Errorf("module %s is disabled", item.Module)
+	}
+
+	var tx types.ZcashTransaction
+	if err := json.Unmarshal(item.TxData, &tx); err != nil {
+		return fmt.Errorf("failed to decode queued transaction: %w", err)
+	}
+	block := &types.ZcashBlock{Height: item.BlockHeight, Hash: item.BlockHash, Time: item.BlockTime}
+
+	ctx := context.Background()
+	postgresTx, err := postgres.BeginWithTimeout(ctx, postgres.QueryIndexerWrite)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer postgresTx.Rollback(ctx)
+
+	postgres.CollectManifest(postgresTx)
+	defer postgres.TakeManifest(postgresTx)
+
+	var scopes []postgres.RowScope
+	if retrier.Scopes != nil {
+		scopes = retrier.Scopes(&tx)
+	}
+	err = postgres.TrackRowCounts(ctx, postgresTx, scopes, func() error {
+		return retrier.Index(postgresTx, block, &tx)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := changefeed.RecordLateRows(postgresTx, item.BlockHeight); err != nil {
+		return err
+	}
+
+	if _, err := postgresTx.Exec(ctx, `DELETE FROM failed_items WHERE id = $1`, item.ID); err != nil {
+		return fmt.Errorf("failed to dequeue item: %w", err)
+	}
+
+	if err := postgresTx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit retry: %w", err)
+	}
+
+	if retrier.Publish != nil {
+		retrier.Publish(block, &tx)
+	}
+	return nil
+}
+
+// IndexOrQueue runs fn for one transaction inside a savepoint of the block transaction
+// If fn fails, its partial writes are rolled back and the transaction is queued instead,
+// so the rest of the block still commits
+// The manifest keys fn queues through the savepoint only reach the block manifest once it is released
+func IndexOrQueue(ctx context.Context, postgresTx pgx.Tx, module string, block *types.ZcashBlock, tx *types.ZcashTransaction, fn RetryFunc) error {
+	savepoint, err := postgresTx.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create savepoint: %w", err)
+	}
+	postgres.CollectManifest(savepoint)
+
+	if indexErr := fn(savepoint, block, tx); indexErr != nil {
+		postgres.TakeManifest(savepoint)
+		if err := savepoint.Rollback(ctx); err != nil {
+			return fmt.Errorf("failed to roll back savepoint: %w", err)
+		}
+		return Record(postgresTx, module, block, tx, indexErr)
+	}
+
+	if err := savepoint.Commit(ctx); err != nil {
+		postgres.TakeManifest(savepoint)
+		return fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	postgres.MergeManifest(savepoint, postgresTx)
+	return nil
+}
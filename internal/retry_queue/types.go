@@ -0,0 +1,28 @@
+package retry_queue
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// FailedItem is a transaction a module could not index in lenient mode
+type FailedItem struct {
+	ID            int64           `json:"id" db:"id"`
+	Module        string          `json:"module" db:"module"` // TZE_GRAPH, STARKS
+	TxID          string          `json:"txid" db:"txid"`
+	BlockHeight   int64           `json:"block_height" db:"block_height"`
+	BlockHash     string          `json:"block_hash" db:"block_hash"`
+	BlockTime     int64           `json:"block_time" db:"block_time"`
+	Error         string          `json:"error" db:"error"`
+	Attempts      int             `json:"attempts" db:"attempts"`
+	TxData        json.RawMessage `json:"-" db:"tx_data"` // transaction as received from RPC, replayed on retry
+	FirstFailedAt time.Time       `json:"first_failed_at" db:"first_failed_at"`
+	LastAttemptAt time.Time       `json:"last_attempt_at" db:"last_attempt_at"`
+}
+
+// RetryResult summarizes a retry run
+type RetryResult struct {
+	Attempted int          `json:"attempted"`
+	Succeeded int          `json:"succeeded"`
+	Failed    []FailedItem `json:"failed"` // items still queued, with their latest error
+}
@@ -0,0 +1,279 @@
+package snapshots
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+)
+
+func init() {
+	// Register this as a core schema (always initialized)
+	postgres.RegisterCoreSchema("utxo_snapshots", InitSchema)
+}
+
+// InitSchema creates the utxo_snapshots table
+func InitSchema() error {
+	schema := `
+		CREATE TABLE IF NOT EXISTS utxo_snapshots (
+			block_height BIGINT NOT NULL,
+			block_hash VARCHAR(64) NOT NULL,
+			set_type VARCHAR(20) NOT NULL,  -- transparent, tze
+			utxo_count BIGINT NOT NULL,
+			total_value BIGINT NOT NULL,
+			hash VARCHAR(64) NOT NULL,
+			computed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (block_height, set_type)
+		);
+	`
+
+	_, err := postgres.DB.Exec(context.Background(), schema)
+	if err != nil {
+		return fmt.Errorf("failed to create utxo_snapshots schema: %w", err)
+	}
+
+	return nil
+}
+
+// The snapshot hash is SHA-256 over one line per unspent output as of the checkpoint height,
+// ordered by txid (byte order) then vout, so any indexer holding the same UTXO set gets the same digest:
+//   - transparent: "<txid>:<vout>:<value>\n"
+//   - tze: "<txid>:<vout>:<value>:<tze_type>:<tze_mode>:<hex precondition>\n"
+//
+// An output is unspent at height h when its transaction is at or below h and it was not spent at or below h
+const (
+	transparentUtxoQuery = `
+		SELECT o.txid, o.vout, o.value
+		FROM transaction_outputs o
+		JOIN transactions t ON t.txid = o.txid
+		WHERE t.block_height <= $1
+		  AND (o.spent_at_height IS NULL OR o.spent_at_height > $1)
+		ORDER BY o.txid COLLATE "C", o.vout
+	`
+	tzeUtxoQuery = `
+		SELECT o.txid, o.vout, o.value, o.tze_type, o.tze_mode, COALESCE(o.precondition, ''::BYTEA)
+		FROM tze_outputs o
+		JOIN transactions t ON t.txid = o.txid
+		WHERE t.block_height <= $1
+		  AND (o.spent_at_height IS NULL OR o.spent_at_height > $1)
+		ORDER BY o.txid COLLATE "C", o.vout
+	`
+)
+
+// IsCheckpoint reports whether a snapshot should be taken at height per indexer.utxo_snapshot_interval
+func IsCheckpoint(height int64) bool {
+	interval := int64(config.Conf.Indexer.UtxoSnapshotInterval)
+	return interval > 0 && height > 0 && height%interval == 0
+}
+
+// snapshotRunning is set while a checkpoint snapshot is being computed in the background
+var snapshotRunning atomic.Bool
+
+// MaybeSnapshot computes UTXO snapshots in the background when height is a checkpoint
+// Hashing a large UTXO set takes a while, so it never blocks indexing; a checkpoint reached while
+// the previous one is still running is skipped and can be computed on demand
+// Snapshots are advisory, so failures are logged rather than stopping the indexer
+func MaybeSnapshot(height int64) {
+	if !IsCheckpoint(height) {
+		return
+	}
+	if !snapshotRunning.CompareAndSwap(false, true) {
+		log.Printf("Skipping UTXO snapshot at height %d, the previous snapshot is still running", height)
+		return
+	}
+
+	go func() {
+		defer snapshotRunning.Store(false)
+
+		snapshots, err := Compute(height)
+		if err != nil {
+			log.Printf("Failed to compute UTXO snapshot at height %d: %v", height, err)
+			return
+		}
+
+		for _, s := range snapshots {
+			log.Printf("UTXO snapshot at height %d (%s): %d utxos, total value %d, hash %s",
+				s.BlockHeight, s.SetType, s.UtxoCount, s.TotalValue, s.Hash)
+		}
+	}()
+}
+
+// Compute hashes the transparent UTXO set (TX_GRAPH) and the TZE UTXO set (TX_GRAPH + TZE_GRAPH)
+// as of height and stores the results, replacing any existing snapshot at that height
+func Compute(height int64) ([]UtxoSnapshot, error) {
+	if !config.IsModuleEnabled("TX_GRAPH") {
+		return nil, fmt.Errorf("UTXO snapshots require the TX_GRAPH module")
+	}
+
+	ctx := context.Background()
+
+	// Read both sets from one consistent view of the database
+	tx, err := postgres.DB.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var blockHash string
+	err = tx.QueryRow(ctx, `SELECT hash FROM blocks WHERE height = $1`, height).Scan(&blockHash)
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("block %d is not indexed", height)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block hash: %w", err)
+	}
+
+	snapshot, err := hashTransparentSet(ctx, tx, height)
+	if err != nil {
+		return nil, err
+	}
+	snapshots := []UtxoSnapshot{*snapshot}
+
+	if config.IsModuleEnabled("TZE_GRAPH") {
+		snapshot, err := hashTzeSet(ctx, tx, height)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, *snapshot)
+	}
+
+	for i := range snapshots {
+		snapshots[i].BlockHash = blockHash
+		err := tx.QueryRow(ctx, `
+			INSERT INTO utxo_snapshots (block_height, block_hash, set_type, utxo_count, total_value, hash)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (block_height, set_type) DO UPDATE SET
+				block_hash = EXCLUDED.block_hash,
+				utxo_count = EXCLUDED.utxo_count,
+				total_value = EXCLUDED.total_value,
+				hash = EXCLUDED.hash,
+				computed_at = CURRENT_TIMESTAMP
+			RETURNING computed_at
+		`, height, blockHash, string(snapshots[i].SetType), snapshots[i].UtxoCount, snapshots[i].TotalValue, snapshots[i].Hash,
+		).Scan(&snapshots[i].ComputedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store %s UTXO snapshot: %w", snapshots[i].SetType, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit UTXO snapshots: %w", err)
+	}
+
+	return snapshots, nil
+}
+
+// hashTransparentSet streams the transparent UTXO set at height into a SHA-256 digest
+func hashTransparentSet(ctx context.Context, tx pgx.Tx, height int64) (*UtxoSnapshot, error) {
+	rows, err := tx.Query(ctx, transparentUtxoQuery, height)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transparent UTXO set: %w", err)
+	}
+	defer rows.Close()
+
+	snapshot := &UtxoSnapshot{BlockHeight: height, SetType: SetTypeTransparent}
+	hasher := sha256.New()
+	for rows.Next() {
+		var txid string
+		var vout int
+		var value int64
+		if err := rows.Scan(&txid, &vout, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan transparent UTXO: %w", err)
+		}
+		fmt.Fprintf(hasher, "%s:%d:%d\n", txid, vout, value)
+		snapshot.UtxoCount++
+		snapshot.TotalValue += value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read transparent UTXO set: %w", err)
+	}
+
+	snapshot.Hash = hex.EncodeToString(hasher.Sum(nil))
+	return snapshot, nil
+}
+
+// hashTzeSet streams the TZE UTXO set at height into a SHA-256 digest
+func hashTzeSet(ctx context.Context, tx pgx.Tx, height int64) (*UtxoSnapshot, error) {
+	rows, err := tx.Query(ctx, tzeUtxoQuery, height)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query TZE UTXO set: %w", err)
+	}
+	defer rows.Close()
+
+	snapshot := &UtxoSnapshot{BlockHeight: height, SetType: SetTypeTZE}
+	hasher := sha256.New()
+	for rows.Next() {
+		var txid string
+		var vout, tzeType, tzeMode int
+		var value int64
+		var precondition []byte
+		if err := rows.Scan(&txid, &vout, &value, &tzeType, &tzeMode, &precondition); err != nil {
+			return nil, fmt.Errorf("failed to scan TZE UTXO: %w", err)
+		}
+		fmt.Fprintf(hasher, "%s:%d:%d:%d:%d:%s\n", txid, vout, value, tzeType, tzeMode, hex.EncodeToString(precondition))
+		snapshot.UtxoCount++
+		snapshot.TotalValue += value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read TZE UTXO set: %w", err)
+	}
+
+	snapshot.Hash = hex.EncodeToString(hasher.Sum(nil))
+	return snapshot, nil
+}
+
+// GetSnapshots retrieves stored snapshots, newest checkpoint first
+func GetSnapshots(limit, offset int) ([]UtxoSnapshot, error) {
+	snapshots, err := postgres.PostgresQueryCtx[UtxoSnapshot](
+		context.Background(), nil,
+		`SELECT block_height, block_hash, set_type, utxo_count, total_value, hash, computed_at
+		 FROM utxo_snapshots
+		 ORDER BY block_height DESC, set_type
+		 LIMIT $1 OFFSET $2`,
+		limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get UTXO snapshots: %w", err)
+	}
+
+	return snapshots, nil
+}
+
+// GetSnapshotsAtHeight retrieves the snapshots stored for a checkpoint height
+func GetSnapshotsAtHeight(height int64) ([]UtxoSnapshot, error) {
+	snapshots, err := postgres.PostgresQueryCtx[UtxoSnapshot](
+		context.Background(), nil,
+		`SELECT block_height, block_hash, set_type, utxo_count, total_value, hash, computed_at
+		 FROM utxo_snapshots
+		 WHERE block_height = $1
+		 ORDER BY set_type`,
+		height,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get UTXO snapshots at height %d: %w", height, err)
+	}
+
+	return snapshots, nil
+}
+
+// GetLatestSnapshots retrieves the snapshots of the most recent checkpoint
+func GetLatestSnapshots() ([]UtxoSnapshot, error) {
+	snapshots, err := postgres.PostgresQueryCtx[UtxoSnapshot](
+		context.Background(), nil,
+		`SELECT block_height, block_hash, set_type, utxo_count, total_value, hash, computed_at
+		 FROM utxo_snapshots
+		 WHERE block_height = (SELECT MAX(block_height) FROM utxo_snapshots)
+		 ORDER BY set_type`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest UTXO snapshots: %w", err)
+	}
+
+	return snapshots, nil
+}
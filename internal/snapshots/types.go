@@ -0,0 +1,22 @@
+package snapshots
+
+import "time"
+
+// SetType identifies which UTXO set a snapshot covers
+type SetType string
+
+const (
+	SetTypeTransparent SetType = "transparent" // transaction_outputs
+	SetTypeTZE         SetType = "tze"         // tze_outputs
+)
+
+// UtxoSnapshot is a deterministic digest of a UTXO set as of a checkpoint height
+type UtxoSnapshot struct {
+	BlockHeight int64     `json:"block_height" db:"block_height"`
+	BlockHash   string    `json:"block_hash" db:"block_hash"`
+	SetType     SetType   `json:"set_type" db:"set_type"`
+	UtxoCount   int64     `json:"utxo_count" db:"utxo_count"`
+	TotalValue  int64     `json:"total_value" db:"total_value"`
+	Hash        string    `json:"hash" db:"hash"` // hex SHA-256, see hashing notes in snapshots.go
+	ComputedAt  time.Time `json:"computed_at" db:"computed_at"`
+}
@@ -0,0 +1,107 @@
+package starks
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/logging"
+)
+
+// Kinds of fact anomalies
+const (
+	AnomalyUnexpectedProgramHash      = "unexpected_program_hash"       // program_hash differs from expected_program_hash
+	AnomalyUnexpectedInnerProgramHash = "unexpected_inner_program_hash" // inner_program_hash differs from expected_inner_program_hash
+)
+
+// IsAnomalyKind reports whether kind is a fact anomaly kind
+func IsAnomalyKind(kind string) bool {
+	return kind == AnomalyUnexpectedProgramHash || kind == AnomalyUnexpectedInnerProgramHash
+}
+
+// ExpectedProgramsConfigured reports whether facts are checked against expected program hashes
+func ExpectedProgramsConfigured() bool {
+	starks := config.Conf.Modules.Starks
+	return starks.ExpectedProgramHash != "" || starks.ExpectedInnerProgramHash != ""
+}
+
+// checkExpectedPrograms records an anomaly for each configured program hash a fact does not match
+// Anomalies from an earlier version of the fact are replaced, so an overwritten fact is judged afresh
+func checkExpectedPrograms(postgresTx DBTX, verifierID, txid string, blockHeight int64, programHash, innerProgramHash string) error {
+	if !ExpectedProgramsConfigured() {
+		return nil
+	}
+
+	ctx := context.Background()
+	if postgresTx == nil {
+		postgresTx = postgres.DB
+	}
+
+	_, err := postgresTx.Exec(ctx, `DELETE FROM fact_anomalies WHERE verifier_id = $1 AND txid = $2`, verifierID, txid)
+	if err != nil {
+		return fmt.Errorf("failed to clear fact anomalies for verifier %s, tx %s: %w", verifierID, txid, err)
+	}
+
+	starks := config.Conf.Modules.Starks
+	for _, check := range []struct {
+		kind     string
+		expected string
+		actual   string
+	}{
+		{AnomalyUnexpectedProgramHash, starks.ExpectedProgramHash, programHash},
+		{AnomalyUnexpectedInnerProgramHash, starks.ExpectedInnerProgramHash, innerProgramHash},
+	} {
+		if check.expected == "" || check.actual == check.expected {
+			continue
+		}
+
+		_, err := postgresTx.Exec(ctx,
+			`INSERT INTO fact_anomalies (verifier_id, txid, kind, block_height, expected, actual)
+			 VALUES ($1, $2, $3, $4, $5, $6)`,
+			verifierID, txid, check.kind, blockHeight, check.expected, check.actual,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to store fact anomaly for verifier %s, tx %s: %w", verifierID, txid, err)
+		}
+		logging.Warnf(logging.ModuleStarks, "Fact anomaly at block %d: verifier %s tx %s has %s %s, expected %s",
+			blockHeight, verifierID, txid, check.kind, check.actual, check.expected)
+	}
+
+	return nil
+}
+
+// GetFactAnomalies retrieves fact anomalies newest first, optionally filtered by verifier and kind
+func GetFactAnomalies(verifierID, kind string, limit, offset int) ([]FactAnomaly, error) {
+	anomalies, err := postgres.PostgresQueryCtx[FactAnomaly](
+		context.Background(), readDB,
+		`SELECT verifier_id, txid, kind, block_height, expected, actual, detected_at
+		 FROM fact_anomalies
+		 WHERE ($1 = '' OR verifier_id = $1) AND ($2 = '' OR kind = $2)
+		 ORDER BY block_height DESC, txid, kind
+		 LIMIT $3 OFFSET $4`,
+		verifierID, kind, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fact anomalies: %w", err)
+	}
+
+	return anomalies, nil
+}
+
+// GetFactAnomaliesByBlock retrieves the fact anomalies detected in a block
+func GetFactAnomaliesByBlock(blockHeight int64) ([]FactAnomaly, error) {
+	anomalies, err := postgres.PostgresQueryCtx[FactAnomaly](
+		context.Background(), readDB,
+		`SELECT verifier_id, txid, kind, block_height, expected, actual, detected_at
+		 FROM fact_anomalies
+		 WHERE block_height = $1
+		 ORDER BY txid, kind`,
+		blockHeight,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fact anomalies by block: %w", err)
+	}
+
+	return anomalies, nil
+}
@@ -0,0 +1,125 @@
+package starks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+)
+
+// Where the canonical verifier designation comes from
+const (
+	CanonicalSourceAdmin  = "admin"  // set through the admin API, stored on the verifier
+	CanonicalSourceConfig = "config" // modules.starks.canonical_verifier_id
+)
+
+// CanonicalVerifier returns the canonical Ztarknet verifier and where it was designated
+// An admin designation takes precedence over the configuration; both are empty when neither is set
+func CanonicalVerifier() (string, string, error) {
+	verifierID, err := postgres.PostgresQueryOneCtx[string](
+		context.Background(), readDB,
+		`SELECT verifier_id FROM verifiers WHERE canonical`,
+	)
+	if err == nil {
+		return *verifierID, CanonicalSourceAdmin, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return "", "", fmt.Errorf("failed to get canonical verifier: %w", err)
+	}
+
+	if configured := config.Conf.Modules.Starks.CanonicalVerifierID; configured != "" {
+		return configured, CanonicalSourceConfig, nil
+	}
+	return "", "", nil
+}
+
+// SetCanonicalVerifier designates a known verifier as canonical in place of any other
+// Returns false when the verifier does not exist
+func SetCanonicalVerifier(verifierID string) (bool, error) {
+	ctx := context.Background()
+
+	tx, err := postgres.DB.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Cleared first, so the unique index never sees two canonical verifiers
+	if _, err := tx.Exec(ctx, `UPDATE verifiers SET canonical = FALSE WHERE canonical AND verifier_id <> $1`, verifierID); err != nil {
+		return false, fmt.Errorf("failed to clear canonical verifier: %w", err)
+	}
+
+	result, err := tx.Exec(ctx, `UPDATE verifiers SET canonical = TRUE WHERE verifier_id = $1`, verifierID)
+	if err != nil {
+		return false, fmt.Errorf("failed to set canonical verifier %s: %w", verifierID, err)
+	}
+	if result.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit canonical verifier: %w", err)
+	}
+
+	return true, nil
+}
+
+// ClearCanonicalVerifier removes the admin designation, falling back to the configured verifier
+func ClearCanonicalVerifier() error {
+	_, err := postgres.DB.Exec(context.Background(), `UPDATE verifiers SET canonical = FALSE WHERE canonical`)
+	if err != nil {
+		return fmt.Errorf("failed to clear canonical verifier: %w", err)
+	}
+	return nil
+}
+
+// GetZtarknetState returns the latest state of the rollup proven by a verifier
+// Returns nil when the verifier has no facts yet
+func GetZtarknetState(verifierID string) (*ZtarknetState, error) {
+	state, err := postgres.PostgresQueryOneCtx[ZtarknetState](
+		context.Background(), readDB,
+		`SELECT verifier_id, new_state AS state_root, block_height, txid, program_hash, inner_program_hash,
+		        l2_block_number, (SELECT COUNT(*) FROM ztarknet_facts c WHERE c.verifier_id = f.verifier_id) AS fact_count
+		 FROM ztarknet_facts f
+		 WHERE verifier_id = $1
+		 ORDER BY block_height DESC, txid DESC
+		 LIMIT 1`,
+		verifierID,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ztarknet state of verifier %s: %w", verifierID, err)
+	}
+
+	finalizedHeight, err := postgres.GetFinalizedHeight()
+	if err != nil {
+		return nil, err
+	}
+	state.Final = state.BlockHeight <= finalizedHeight
+
+	return state, nil
+}
+
+// GetProofActivity aggregates the STARK proofs of every verifier in blocks timestamped at or after since
+func GetProofActivity(since int64) (*ProofActivity, error) {
+	activity, err := postgres.PostgresQueryOneCtx[ProofActivity](
+		context.Background(), readDB,
+		`SELECT COUNT(*) AS proof_count,
+		        COUNT(DISTINCT p.verifier_id) AS verifier_count,
+		        COALESCE(AVG(p.proof_size), 0)::DOUBLE PRECISION AS avg_proof_size
+		 FROM stark_proofs p
+		 JOIN blocks b ON b.height = p.block_height
+		 WHERE b.timestamp >= $1`,
+		since,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stark proof activity: %w", err)
+	}
+
+	return activity, nil
+}
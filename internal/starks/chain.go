@@ -0,0 +1,110 @@
+package starks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+)
+
+// unknownState is stored as the old_state of facts whose spent output has no known state,
+// such as outputs of verifiers indexed before their initial state was stored
+const unknownState = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// GetStateChain links the facts of a verifier into its state progression, oldest first
+// Each fact should start from the state the previous fact ended in; a fact that does not is a gap,
+// and facts starting from the same state as another fact of the verifier are forks
+// Returns nil when the verifier does not exist
+func GetStateChain(verifierID string) (*StateChain, error) {
+	initialState, err := postgres.PostgresQueryOneCtx[string](
+		context.Background(), readDB,
+		`SELECT COALESCE(initial_state, '') FROM verifiers WHERE verifier_id = $1`,
+		verifierID,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get initial state of verifier %s: %w", verifierID, err)
+	}
+
+	facts, err := postgres.PostgresQueryCtx[ZtarknetFacts](
+		context.Background(), readDB,
+		`SELECT verifier_id, txid, block_height, proof_size, old_state, new_state,
+		        program_hash, inner_program_hash, format_version, l2_block_number
+		 FROM ztarknet_facts
+		 WHERE verifier_id = $1
+		 ORDER BY block_height, txid`,
+		verifierID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state chain of verifier %s: %w", verifierID, err)
+	}
+
+	if err := markZtarknetFactsFinal(facts); err != nil {
+		return nil, err
+	}
+
+	return buildStateChain(verifierID, *initialState, facts), nil
+}
+
+// buildStateChain orders facts sorted by block height into a chain starting at initialState and flags
+// its gaps and forks; an empty initialState leaves the first fact unchecked
+func buildStateChain(verifierID, initialState string, facts []ZtarknetFacts) *StateChain {
+	successors := make(map[string]int, len(facts))
+	for _, fact := range facts {
+		successors[fact.OldState]++
+	}
+
+	chain := &StateChain{
+		VerifierID:   verifierID,
+		InitialState: initialState,
+		CurrentState: initialState,
+		Links:        make([]StateChainLink, 0, len(facts)),
+	}
+
+	tip := initialState
+	for start := 0; start < len(facts); {
+		end := start
+		for end < len(facts) && facts[end].BlockHeight == facts[start].BlockHeight {
+			end++
+		}
+
+		// Facts of one block are ordered by following the chain, since the block does not order them
+		block := facts[start:end]
+		for len(block) > 0 {
+			next := 0
+			for i, fact := range block {
+				if fact.OldState == tip {
+					next = i
+					break
+				}
+			}
+			fact := block[next]
+			block = append(block[:next:next], block[next+1:]...)
+
+			link := StateChainLink{
+				ZtarknetFacts: fact,
+				Position:      len(chain.Links),
+				ExpectedState: tip,
+				Gap:           fact.OldState == unknownState || (tip != "" && fact.OldState != tip),
+				Fork:          successors[fact.OldState] > 1,
+			}
+			if link.Gap {
+				chain.GapCount++
+			}
+			if link.Fork {
+				chain.ForkCount++
+			}
+			chain.Links = append(chain.Links, link)
+			tip = fact.NewState
+		}
+		start = end
+	}
+
+	chain.CurrentState = tip
+	chain.Valid = chain.GapCount == 0 && chain.ForkCount == 0
+	return chain
+}
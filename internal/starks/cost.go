@@ -0,0 +1,51 @@
+package starks
+
+import (
+	"math"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+)
+
+// CostModel estimates the work of verifying a stark_verify proof, in abstract cost units
+// Estimates are stored with each proof, so changing the model or its weights only affects proofs
+// indexed afterwards
+type CostModel interface {
+	ProofCost(witness *StarkWitnessData) int64
+}
+
+// costModels builds the cost model named by modules.starks.cost_model.model from its weights
+var costModels = map[string]func(config.CostModelConfig) CostModel{
+	"linear": func(c config.CostModelConfig) CostModel { return linearCostModel(c) },
+}
+
+// linearCostModel charges a fixed cost per proof, a cost per proof byte and a surcharge for
+// proofs verified with the Pedersen builtin
+type linearCostModel config.CostModelConfig
+
+func (m linearCostModel) ProofCost(witness *StarkWitnessData) int64 {
+	cost := m.Base + m.PerProofByte*float64(witness.ProofSize)
+	if witness.WithPedersen {
+		cost += m.Pedersen
+	}
+	return int64(math.Round(cost))
+}
+
+// activeCostModel returns the configured cost model, or nil when proofs are not priced
+func activeCostModel() CostModel {
+	c := config.Conf.Modules.Starks.CostModel
+	build, ok := costModels[c.Model]
+	if !ok {
+		return nil
+	}
+	return build(c)
+}
+
+// proofCost prices a proof with the configured cost model, or returns nil when none is configured
+func proofCost(witness *StarkWitnessData) *int64 {
+	model := activeCostModel()
+	if model == nil {
+		return nil
+	}
+	cost := model.ProofCost(witness)
+	return &cost
+}
@@ -0,0 +1,45 @@
+package starks
+
+import (
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/events"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/logging"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/types"
+)
+
+// publishTransactionEvents notifies event subscribers of the proofs and facts of a committed
+// transaction, for rows written outside block indexing such as a retried transaction
+// A failed read is logged and skipped, as for the events of an indexed block
+func publishTransactionEvents(block *types.ZcashBlock, tx *types.ZcashTransaction) {
+	if !events.HasSubscribers() {
+		return
+	}
+
+	proofs, err := GetStarkProofsByTransaction(tx.TxID)
+	if err != nil {
+		logging.Errorf(logging.ModuleStarks, "Failed to read STARK proofs of %s for events: %v", tx.TxID, err)
+	}
+	for _, proof := range proofs {
+		publishEvent(block, events.TopicStarkProofs, proof.VerifierID, proof)
+	}
+
+	if !ShouldIndexZtarknet() {
+		return
+	}
+	facts, err := GetZtarknetFactsByTransaction(tx.TxID)
+	if err != nil {
+		logging.Errorf(logging.ModuleStarks, "Failed to read Ztarknet facts of %s for events: %v", tx.TxID, err)
+	}
+	for _, fact := range facts {
+		publishEvent(block, events.TopicFacts, fact.VerifierID, fact)
+	}
+}
+
+func publishEvent(block *types.ZcashBlock, topic, verifierID string, data interface{}) {
+	events.Publish(events.Event{
+		Topic:       topic,
+		BlockHeight: block.Height,
+		BlockHash:   block.Hash,
+		VerifierID:  verifierID,
+		Data:        data,
+	})
+}
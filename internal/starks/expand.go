@@ -0,0 +1,67 @@
+package starks
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+)
+
+// ExpandFactsWithBlocks widens facts with the hash, timestamp and confirmations of their L1 block,
+// read in one query joining the facts' heights to blocks
+// A fact whose block is not in the blocks table, e.g. one created by a facts import, keeps empty block fields
+func ExpandFactsWithBlocks(facts []ZtarknetFacts) ([]ZtarknetFactWithBlock, error) {
+	expanded := make([]ZtarknetFactWithBlock, len(facts))
+	if len(facts) == 0 {
+		return expanded, nil
+	}
+
+	heights := make([]int64, 0, len(facts))
+	for _, fact := range facts {
+		heights = append(heights, fact.BlockHeight)
+	}
+
+	rows, err := postgres.ReadQuerier(readDB).Query(context.Background(),
+		`SELECT b.height, b.hash, COALESCE(b.timestamp, 0)
+		 FROM (SELECT DISTINCT unnest($1::bigint[]) AS height) AS f
+		 JOIN blocks b ON b.height = f.height`,
+		heights,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blocks of ztarknet facts: %w", err)
+	}
+	defer rows.Close()
+
+	type factBlock struct {
+		hash      string
+		timestamp int64
+	}
+	factBlocks := make(map[int64]factBlock)
+	for rows.Next() {
+		var height int64
+		var block factBlock
+		if err := rows.Scan(&height, &block.hash, &block.timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan block of ztarknet facts: %w", err)
+		}
+		factBlocks[height] = block
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get blocks of ztarknet facts: %w", err)
+	}
+
+	lastIndexed, err := postgres.GetLastIndexedBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, fact := range facts {
+		expanded[i].ZtarknetFacts = fact
+		if block, ok := factBlocks[fact.BlockHeight]; ok {
+			expanded[i].BlockHash = block.hash
+			expanded[i].BlockTimestamp = block.timestamp
+			expanded[i].Confirmations = lastIndexed - fact.BlockHeight + 1
+		}
+	}
+
+	return expanded, nil
+}
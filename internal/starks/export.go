@@ -0,0 +1,102 @@
+package starks
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+)
+
+// FactExportFormat identifies the layout of FactExportBundle; bump it on any field change
+const FactExportFormat = "zindex-fact-export/v1"
+
+// ExportVerifierFacts builds the signed export of every Ztarknet fact proven by a verifier
+//
+// The bundle is canonical: facts are ordered by (block_height, txid), fields are emitted in
+// struct order and nothing time-dependent is included, so two exports at the same indexed tip
+// are byte-identical. The signature and digest cover the bundle bytes exactly as returned.
+// Returns nil when the verifier does not exist
+func ExportVerifierFacts(verifierID string) (*SignedFactExport, error) {
+	ctx := context.Background()
+
+	// Read the tip and the facts from one snapshot so the bundle is self-consistent
+	tx, err := postgres.DB.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var exists bool
+	err = tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM verifiers WHERE verifier_id = $1)`, verifierID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check verifier: %w", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	bundle := FactExportBundle{Format: FactExportFormat, VerifierID: verifierID, Facts: []ExportedFact{}}
+	err = tx.QueryRow(ctx, `SELECT last_indexed_block, last_indexed_hash FROM indexer_state WHERE id = 1`).
+		Scan(&bundle.IndexedHeight, &bundle.IndexedHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get indexer state: %w", err)
+	}
+	bundle.FinalizedHeight = bundle.IndexedHeight - int64(config.Conf.Indexer.FinalityDepth)
+
+	facts, err := postgres.PostgresQueryCtx[ExportedFact](ctx, tx,
+		`SELECT f.block_height, b.hash AS block_hash, f.txid, f.proof_size, f.old_state, f.new_state,
+		        f.program_hash, f.inner_program_hash
+		 FROM ztarknet_facts f
+		 JOIN blocks b ON b.height = f.block_height
+		 WHERE f.verifier_id = $1
+		 ORDER BY f.block_height, f.txid`,
+		verifierID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ztarknet facts for export: %w", err)
+	}
+	for i := range facts {
+		facts[i].Final = facts[i].BlockHeight <= bundle.FinalizedHeight
+	}
+	if len(facts) > 0 {
+		bundle.Facts = facts
+	}
+	bundle.FactCount = len(bundle.Facts)
+
+	return signFactExport(&bundle)
+}
+
+// signFactExport serializes the bundle and signs it with modules.starks.export_signing_key if set
+func signFactExport(bundle *FactExportBundle) (*SignedFactExport, error) {
+	raw, err := json.Marshal(bundle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode fact export: %w", err)
+	}
+
+	digest := sha256.Sum256(raw)
+	export := &SignedFactExport{Bundle: raw, Sha256: hex.EncodeToString(digest[:])}
+
+	seedHex := config.Conf.Modules.Starks.ExportSigningKey
+	if seedHex == "" {
+		return export, nil
+	}
+
+	seed, err := hex.DecodeString(seedHex)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid export signing key")
+	}
+	key := ed25519.NewKeyFromSeed(seed)
+	export.Signature = &FactExportSignature{
+		Algorithm: "ed25519",
+		PublicKey: hex.EncodeToString(key.Public().(ed25519.PublicKey)),
+		Signature: hex.EncodeToString(ed25519.Sign(key, raw)),
+	}
+
+	return export, nil
+}
@@ -0,0 +1,56 @@
+package starks
+
+import (
+	"fmt"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+)
+
+// stark_verify format versions
+// A new precondition or witness layout gets a version here, parsers in starkFormats and an
+// activation height in modules.starks.format_activations, so blocks below it keep the old parsers
+const (
+	FormatV1 = 1 // 4-byte header + root + OS program hash + bootloader program hash; with_pedersen + proof_format + proof
+	FormatV2 = 2 // FormatV1 precondition + 8-byte big-endian L2 block number; FormatV1 witness
+	FormatV3 = 3 // FormatV2 precondition; FormatV1 witness with a 4-byte proof length, followed by DA blob and message segments
+)
+
+// starkFormat holds the parsers of one stark_verify format version
+type starkFormat struct {
+	version           int
+	parsePrecondition func(precondition []byte) (*StarkPreconditionData, error)
+	parseWitness      func(witness []byte) (*StarkWitnessData, error)
+	// The witness carries a 4-byte proof length after its two flag bytes
+	proofLengthPrefixed bool
+}
+
+var starkFormats = map[int]starkFormat{
+	FormatV1: {version: FormatV1, parsePrecondition: parseStarkVerifyPrecondition, parseWitness: parseStarkVerifyWitness},
+	FormatV2: {version: FormatV2, parsePrecondition: parseStarkVerifyPreconditionV2, parseWitness: parseStarkVerifyWitness},
+	FormatV3: {version: FormatV3, parsePrecondition: parseStarkVerifyPreconditionV2, parseWitness: parseStarkVerifyWitnessV3, proofLengthPrefixed: true},
+}
+
+// FormatVersionAt returns the stark_verify format version in effect at a block height:
+// the version with the highest activation height at or below it, or FormatV1 before any activation
+func FormatVersionAt(height int64) int {
+	version := FormatV1
+	activatedAt := int64(-1)
+	for v, h := range config.Conf.Modules.Starks.FormatActivations {
+		if h <= height && (h > activatedAt || (h == activatedAt && v > version)) {
+			version, activatedAt = v, h
+		}
+	}
+	return version
+}
+
+// formatAt returns the parsers for the stark_verify format in effect at a block height
+// An activated version without parsers is an error rather than a silent fallback, so a block
+// is never indexed with the wrong layout
+func formatAt(height int64) (starkFormat, error) {
+	version := FormatVersionAt(height)
+	format, ok := starkFormats[version]
+	if !ok {
+		return starkFormat{}, fmt.Errorf("stark_verify format version %d is active at block %d but has no parser in this build", version, height)
+	}
+	return format, nil
+}
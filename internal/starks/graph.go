@@ -0,0 +1,168 @@
+package starks
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+)
+
+// MaxStateGraphEdges caps the facts loaded into one state graph
+const MaxStateGraphEdges = 10000
+
+// GetStateGraph builds the graph of state roots (nodes) linked by the facts moving between them (edges),
+// across every verifier unless verifierID is empty, for facts between fromHeight and toHeight
+// A state with more than one outgoing fact is a fork; states and facts on a cycle are flagged too
+// Facts beyond MaxStateGraphEdges, oldest first, are left out and the graph is marked truncated
+func GetStateGraph(verifierID string, fromHeight, toHeight int64) (*StateGraph, error) {
+	facts, err := postgres.PostgresQueryCtx[ZtarknetFacts](
+		context.Background(), readDB,
+		`SELECT verifier_id, txid, block_height, proof_size, old_state, new_state,
+		        program_hash, inner_program_hash, format_version, l2_block_number
+		 FROM ztarknet_facts
+		 WHERE ($1 = '' OR verifier_id = $1) AND block_height BETWEEN $2 AND $3
+		 ORDER BY block_height, verifier_id, txid
+		 LIMIT $4`,
+		verifierID, fromHeight, toHeight, MaxStateGraphEdges+1,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ztarknet facts for state graph: %w", err)
+	}
+
+	truncated := len(facts) > MaxStateGraphEdges
+	if truncated {
+		facts = facts[:MaxStateGraphEdges]
+	}
+
+	graph := buildStateGraph(facts)
+	graph.Truncated = truncated
+	return graph, nil
+}
+
+// buildStateGraph links facts into a state graph and flags its forks and cycles
+func buildStateGraph(facts []ZtarknetFacts) *StateGraph {
+	nodes := make(map[string]*StateGraphNode)
+	node := func(state string) *StateGraphNode {
+		n, ok := nodes[state]
+		if !ok {
+			n = &StateGraphNode{State: state, Unknown: state == unknownState}
+			nodes[state] = n
+		}
+		return n
+	}
+
+	successors := make(map[string][]string)
+	graph := &StateGraph{Edges: make([]StateGraphEdge, 0, len(facts))}
+	for _, fact := range facts {
+		from, to := node(fact.OldState), node(fact.NewState)
+		from.OutDegree++
+		to.InDegree++
+		successors[fact.OldState] = append(successors[fact.OldState], fact.NewState)
+		graph.Edges = append(graph.Edges, StateGraphEdge{
+			VerifierID:  fact.VerifierID,
+			TxID:        fact.TxID,
+			BlockHeight: fact.BlockHeight,
+			From:        fact.OldState,
+			To:          fact.NewState,
+		})
+	}
+
+	// Facts whose old state is unknown all share one node, which is not a real fork
+	for _, n := range nodes {
+		n.Fork = n.OutDegree > 1 && !n.Unknown
+		if n.Fork {
+			graph.ForkCount++
+		}
+	}
+
+	// Each strongly connected component with more than one state, or a state with a fact back to
+	// itself, is a cycle
+	components := stronglyConnectedComponents(nodes, successors)
+	component := make(map[string]int, len(nodes))
+	for i, states := range components {
+		cyclic := len(states) > 1
+		if !cyclic {
+			for _, next := range successors[states[0]] {
+				if next == states[0] {
+					cyclic = true
+				}
+			}
+		}
+		if !cyclic {
+			continue
+		}
+		graph.CycleCount++
+		for _, state := range states {
+			nodes[state].InCycle = true
+			component[state] = i + 1
+		}
+	}
+
+	for i := range graph.Edges {
+		edge := &graph.Edges[i]
+		edge.Fork = nodes[edge.From].Fork
+		edge.InCycle = component[edge.From] != 0 && component[edge.From] == component[edge.To]
+	}
+
+	graph.Nodes = make([]StateGraphNode, 0, len(nodes))
+	for _, n := range nodes {
+		graph.Nodes = append(graph.Nodes, *n)
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool { return graph.Nodes[i].State < graph.Nodes[j].State })
+
+	return graph
+}
+
+// stronglyConnectedComponents returns the strongly connected components of the state graph (Tarjan)
+func stronglyConnectedComponents(nodes map[string]*StateGraphNode, successors map[string][]string) [][]string {
+	index := make(map[string]int, len(nodes))
+	lowlink := make(map[string]int, len(nodes))
+	onStack := make(map[string]bool, len(nodes))
+	var stack []string
+	var components [][]string
+
+	var visit func(state string)
+	visit = func(state string) {
+		index[state] = len(index)
+		lowlink[state] = index[state]
+		stack = append(stack, state)
+		onStack[state] = true
+
+		for _, next := range successors[state] {
+			if _, seen := index[next]; !seen {
+				visit(next)
+				lowlink[state] = min(lowlink[state], lowlink[next])
+			} else if onStack[next] {
+				lowlink[state] = min(lowlink[state], index[next])
+			}
+		}
+
+		if lowlink[state] == index[state] {
+			var component []string
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == state {
+					break
+				}
+			}
+			components = append(components, component)
+		}
+	}
+
+	states := make([]string, 0, len(nodes))
+	for state := range nodes {
+		states = append(states, state)
+	}
+	sort.Strings(states)
+	for _, state := range states {
+		if _, seen := index[state]; !seen {
+			visit(state)
+		}
+	}
+
+	return components
+}
@@ -0,0 +1,140 @@
+package starks
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildStateGraph(t *testing.T) {
+	fact := func(txid, oldState, newState string) ZtarknetFacts {
+		return ZtarknetFacts{VerifierID: "v", TxID: txid, OldState: oldState, NewState: newState}
+	}
+
+	tests := []struct {
+		name       string
+		facts      []ZtarknetFacts
+		wantNodes  []StateGraphNode
+		wantEdges  []StateGraphEdge // only From, To, Fork and InCycle are compared
+		wantForks  int
+		wantCycles int
+	}{
+		{
+			name:      "empty",
+			facts:     nil,
+			wantNodes: []StateGraphNode{},
+			wantEdges: []StateGraphEdge{},
+		},
+		{
+			name:  "chain",
+			facts: []ZtarknetFacts{fact("t1", "a", "b"), fact("t2", "b", "c")},
+			wantNodes: []StateGraphNode{
+				{State: "a", OutDegree: 1},
+				{State: "b", InDegree: 1, OutDegree: 1},
+				{State: "c", InDegree: 1},
+			},
+			wantEdges: []StateGraphEdge{{From: "a", To: "b"}, {From: "b", To: "c"}},
+		},
+		{
+			name:  "fork",
+			facts: []ZtarknetFacts{fact("t1", "a", "b"), fact("t2", "a", "c")},
+			wantNodes: []StateGraphNode{
+				{State: "a", OutDegree: 2, Fork: true},
+				{State: "b", InDegree: 1},
+				{State: "c", InDegree: 1},
+			},
+			wantEdges: []StateGraphEdge{{From: "a", To: "b", Fork: true}, {From: "a", To: "c", Fork: true}},
+			wantForks: 1,
+		},
+		{
+			name:  "unknown old state is not a fork",
+			facts: []ZtarknetFacts{fact("t1", unknownState, "b"), fact("t2", unknownState, "c")},
+			wantNodes: []StateGraphNode{
+				{State: unknownState, OutDegree: 2, Unknown: true},
+				{State: "b", InDegree: 1},
+				{State: "c", InDegree: 1},
+			},
+			wantEdges: []StateGraphEdge{{From: unknownState, To: "b"}, {From: unknownState, To: "c"}},
+		},
+		{
+			name:  "self loop",
+			facts: []ZtarknetFacts{fact("t1", "a", "a")},
+			wantNodes: []StateGraphNode{
+				{State: "a", InDegree: 1, OutDegree: 1, InCycle: true},
+			},
+			wantEdges:  []StateGraphEdge{{From: "a", To: "a", InCycle: true}},
+			wantCycles: 1,
+		},
+		{
+			name: "cycle with an exit",
+			facts: []ZtarknetFacts{
+				fact("t1", "a", "b"),
+				fact("t2", "b", "c"),
+				fact("t3", "c", "a"),
+				fact("t4", "c", "d"),
+			},
+			wantNodes: []StateGraphNode{
+				{State: "a", InDegree: 1, OutDegree: 1, InCycle: true},
+				{State: "b", InDegree: 1, OutDegree: 1, InCycle: true},
+				{State: "c", InDegree: 1, OutDegree: 2, Fork: true, InCycle: true},
+				{State: "d", InDegree: 1},
+			},
+			wantEdges: []StateGraphEdge{
+				{From: "a", To: "b", InCycle: true},
+				{From: "b", To: "c", InCycle: true},
+				{From: "c", To: "a", Fork: true, InCycle: true},
+				{From: "c", To: "d", Fork: true},
+			},
+			wantForks:  1,
+			wantCycles: 1,
+		},
+		{
+			name: "two cycles",
+			facts: []ZtarknetFacts{
+				fact("t1", "a", "b"),
+				fact("t2", "b", "a"),
+				fact("t3", "b", "c"),
+				fact("t4", "c", "d"),
+				fact("t5", "d", "c"),
+			},
+			wantNodes: []StateGraphNode{
+				{State: "a", InDegree: 1, OutDegree: 1, InCycle: true},
+				{State: "b", InDegree: 1, OutDegree: 2, Fork: true, InCycle: true},
+				{State: "c", InDegree: 2, OutDegree: 1, InCycle: true},
+				{State: "d", InDegree: 1, OutDegree: 1, InCycle: true},
+			},
+			wantEdges: []StateGraphEdge{
+				{From: "a", To: "b", InCycle: true},
+				{From: "b", To: "a", Fork: true, InCycle: true},
+				{From: "b", To: "c", Fork: true},
+				{From: "c", To: "d", InCycle: true},
+				{From: "d", To: "c", InCycle: true},
+			},
+			wantForks:  1,
+			wantCycles: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graph := buildStateGraph(tt.facts)
+
+			if !reflect.DeepEqual(graph.Nodes, tt.wantNodes) {
+				t.Errorf("nodes = %+v, want %+v", graph.Nodes, tt.wantNodes)
+			}
+
+			edges := make([]StateGraphEdge, len(graph.Edges))
+			for i, edge := range graph.Edges {
+				if edge.TxID != tt.facts[i].TxID {
+					t.Errorf("edge %d txid = %s, want %s", i, edge.TxID, tt.facts[i].TxID)
+				}
+				edges[i] = StateGraphEdge{From: edge.From, To: edge.To, Fork: edge.Fork, InCycle: edge.InCycle}
+			}
+			if !reflect.DeepEqual(edges, tt.wantEdges) {
+				t.Errorf("edges = %+v, want %+v", edges, tt.wantEdges)
+			}
+
+			if graph.ForkCount != tt.wantForks || graph.CycleCount != tt.wantCycles {
+				t.Errorf("forks, cycles = %d, %d, want %d, %d", graph.ForkCount, graph.CycleCount, tt.wantForks, tt.wantCycles)
+			}
+		})
+	}
+}
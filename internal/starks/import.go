@@ -0,0 +1,203 @@
+package starks
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/changefeed"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+)
+
+// ImportFacts stores facts exported by another system after checking each one against the
+// indexed chain: the verifier must be indexed, and the transaction must carry an indexed STARK
+// proof for it or, with TX_GRAPH enabled, be an indexed transaction (its proof is then stored too)
+// Facts that differ from an indexed fact are rejected unless overwrite is set
+// New facts and proofs join the manifest of their block, and new facts the change feed as fact_added
+// The import runs in a single transaction; with dryRun it is validated and rolled back
+func ImportFacts(facts []ImportedFact, overwrite, dryRun bool) (*FactImportResult, error) {
+	ctx := context.Background()
+
+	tx, err := postgres.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	postgres.CollectManifest(tx)
+	defer postgres.TakeManifest(tx)
+
+	result := &FactImportResult{DryRun: dryRun, Rejected: []FactImportRejection{}}
+	for i := range facts {
+		fact := normalizeImportedFact(facts[i])
+		reason, err := importFact(ctx, tx, &fact, overwrite, result)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			result.Rejected = append(result.Rejected, FactImportRejection{
+				Index:      i,
+				VerifierID: fact.VerifierID,
+				TxID:       fact.TxID,
+				Reason:     reason,
+			})
+		}
+	}
+
+	if dryRun {
+		return result, nil
+	}
+
+	err = postgres.AddRowCounts(ctx, tx, map[string]int64{
+		"ztarknet_facts": int64(result.Imported),
+		"stark_proofs":   int64(result.ProofsCreated),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit fact import: %w", err)
+	}
+
+	return result, nil
+}
+
+// importFact validates and stores one fact, returning a rejection reason for invalid facts
+func importFact(ctx context.Context, tx pgx.Tx, fact *ImportedFact, overwrite bool, result *FactImportResult) (string, error) {
+	if reason := validateImportedFact(fact); reason != "" {
+		return reason, nil
+	}
+
+	var verifierExists bool
+	err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM verifiers WHERE verifier_id = $1)`, fact.VerifierID).Scan(&verifierExists)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up verifier %s: %w", fact.VerifierID, err)
+	}
+	if !verifierExists {
+		return "verifier is not indexed", nil
+	}
+
+	// The block height comes from the indexed chain, never from the import
+	var blockHeight, proofSize int64
+	createProof := false
+	err = tx.QueryRow(ctx, `
+		SELECT block_height, proof_size FROM stark_proofs WHERE verifier_id = $1 AND txid = $2
+	`, fact.VerifierID, fact.TxID).Scan(&blockHeight, &proofSize)
+	switch {
+	case err == pgx.ErrNoRows:
+		if !config.IsModuleEnabled("TX_GRAPH") {
+			return "no indexed STARK proof for this verifier and transaction", nil
+		}
+		err = tx.QueryRow(ctx, `SELECT block_height FROM transactions WHERE txid = $1`, fact.TxID).Scan(&blockHeight)
+		if err == pgx.ErrNoRows {
+			return "transaction is not indexed", nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to look up transaction %s: %w", fact.TxID, err)
+		}
+		if fact.ProofSize <= 0 {
+			return "proof_size is required when the transaction has no indexed STARK proof", nil
+		}
+		createProof = true
+	case err != nil:
+		return "", fmt.Errorf("failed to look up STARK proof for verifier %s, tx %s: %w", fact.VerifierID, fact.TxID, err)
+	case fact.ProofSize == 0:
+		fact.ProofSize = proofSize
+	case fact.ProofSize != proofSize:
+		return fmt.Sprintf("proof_size %d does not match the indexed proof size %d", fact.ProofSize, proofSize), nil
+	}
+
+	var existing ZtarknetFacts
+	err = tx.QueryRow(ctx, `
+		SELECT proof_size, old_state, new_state, program_hash, inner_program_hash
+		FROM ztarknet_facts
+		WHERE verifier_id = $1 AND txid = $2
+	`, fact.VerifierID, fact.TxID).Scan(
+		&existing.ProofSize, &existing.OldState, &existing.NewState, &existing.ProgramHash, &existing.InnerProgramHash)
+	exists := err == nil
+	if err != nil && err != pgx.ErrNoRows {
+		return "", fmt.Errorf("failed to look up Ztarknet fact for verifier %s, tx %s: %w", fact.VerifierID, fact.TxID, err)
+	}
+	if exists {
+		if existing.ProofSize == fact.ProofSize && existing.OldState == fact.OldState && existing.NewState == fact.NewState &&
+			existing.ProgramHash == fact.ProgramHash && existing.InnerProgramHash == fact.InnerProgramHash {
+			result.Unchanged++
+			return "", nil
+		}
+		if !overwrite {
+			return "conflicts with the indexed fact for this verifier and transaction", nil
+		}
+	}
+
+	rowKey := fact.VerifierID + "/" + fact.TxID
+	if createProof {
+		if err := StoreStarkProof(tx, fact.VerifierID, fact.TxID, blockHeight, fact.ProofSize, nil, nil, nil); err != nil {
+			return "", err
+		}
+		postgres.QueueManifestKeys(tx, "STARKS", "stark_proofs", "insert", rowKey)
+		result.ProofsCreated++
+	}
+
+	err = StoreZtarknetFacts(tx, fact.VerifierID, fact.TxID, blockHeight, fact.ProofSize,
+		fact.OldState, fact.NewState, fact.ProgramHash, fact.InnerProgramHash, FormatVersionAt(blockHeight), nil)
+	if err != nil {
+		return "", err
+	}
+	if err := checkExpectedPrograms(tx, fact.VerifierID, fact.TxID, blockHeight, fact.ProgramHash, fact.InnerProgramHash); err != nil {
+		return "", err
+	}
+	postgres.QueueManifestKeys(tx, "STARKS", "ztarknet_facts", "insert", rowKey)
+	if err := changefeed.RecordLateRows(tx, blockHeight); err != nil {
+		return "", err
+	}
+
+	if exists {
+		result.Updated++
+	} else {
+		result.Imported++
+	}
+	return "", nil
+}
+
+// normalizeImportedFact trims whitespace and lowercases hex fields to match indexed values
+func normalizeImportedFact(fact ImportedFact) ImportedFact {
+	fact.VerifierID = strings.TrimSpace(fact.VerifierID)
+	fact.TxID = strings.ToLower(strings.TrimSpace(fact.TxID))
+	fact.OldState = strings.ToLower(strings.TrimSpace(fact.OldState))
+	fact.NewState = strings.ToLower(strings.TrimSpace(fact.NewState))
+	fact.ProgramHash = strings.ToLower(strings.TrimSpace(fact.ProgramHash))
+	fact.InnerProgramHash = strings.ToLower(strings.TrimSpace(fact.InnerProgramHash))
+	return fact
+}
+
+// validateImportedFact checks field formats, returning a rejection reason or ""
+func validateImportedFact(fact *ImportedFact) string {
+	if fact.VerifierID == "" {
+		return "verifier_id is required"
+	}
+	if fact.ProofSize < 0 {
+		return "proof_size must not be negative"
+	}
+	for _, field := range []struct {
+		name  string
+		value string
+	}{
+		{"txid", fact.TxID},
+		{"old_state", fact.OldState},
+		{"new_state", fact.NewState},
+		{"program_hash", fact.ProgramHash},
+		{"inner_program_hash", fact.InnerProgramHash},
+	} {
+		if len(field.value) != 64 {
+			return fmt.Sprintf("%s must be 64 hex characters", field.name)
+		}
+		if _, err := hex.DecodeString(field.value); err != nil {
+			return fmt.Sprintf("%s is not valid hex", field.name)
+		}
+	}
+	return ""
+}
@@ -2,12 +2,18 @@ package starks
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/binary"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"log"
+	"math"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/logging"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/retry_queue"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/types"
 )
 
@@ -20,8 +26,8 @@ const (
 
 // IndexStarks indexes STARK proof data and Ztarknet-specific data from a Zcash block
 // This function extracts and stores STARK proofs, verifier data, and Ztarknet facts
-// All STARK data in a block are indexed atomically in a single database transaction
-func IndexStarks(block *types.ZcashBlock) error {
+// STARK data is written through postgresTx, the block's transaction shared by every module
+func IndexStarks(postgresTx pgx.Tx, block *types.ZcashBlock) error {
 	// Check if starks module is enabled
 	if !config.IsModuleEnabled("STARKS") {
 		return nil
@@ -40,139 +46,109 @@ func IndexStarks(block *types.ZcashBlock) error {
 		return nil
 	}
 
-	log.Printf("Indexing STARK data for block %d (hash: %s, %d STARK transactions)",
+	logging.Blockf(logging.ModuleStarks, "Indexing STARK data for block %d (hash: %s, %d STARK transactions)",
 		block.Height, block.Hash, starkTransactionCount)
 
 	ctx := context.Background()
 
-	// Begin a database transaction for the entire block
-	postgresTx, err := postgres.DB.Begin(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to begin database transaction for block %d: %w", block.Height, err)
-	}
-	defer postgresTx.Rollback(ctx)
-
-	// Process each transaction in the block
-	for _, tx := range block.Tx {
-		// Only process TZE transactions with STARK verify
-		if !tx.IsTZETransaction() || !hasStarkVerifyTze(&tx) {
-			continue
-		}
-
-		if err := indexStarkTransaction(postgresTx, block, &tx); err != nil {
-			return fmt.Errorf("failed to index STARK transaction %s in block %d: %w",
-				tx.TxID, block.Height, err)
+	// Process each STARK transaction in the block, keeping the row counters in step
+	starkTxs := make([]*types.ZcashTransaction, 0, starkTransactionCount)
+	for i := range block.Tx {
+		if block.Tx[i].IsTZETransaction() && hasStarkVerifyTze(&block.Tx[i]) {
+			starkTxs = append(starkTxs, &block.Tx[i])
 		}
 	}
+	scopes := starkRowScopes(starkTxs)
+	err := postgres.TrackRowCounts(ctx, postgresTx, scopes, func() error {
+		for _, tx := range block.Tx {
+			// Only process TZE transactions with STARK verify
+			if !tx.IsTZETransaction() || !hasStarkVerifyTze(&tx) {
+				continue
+			}
 
-	// Commit the transaction
-	if err := postgresTx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit database transaction for block %d: %w", block.Height, err)
+			// In lenient mode a transaction that fails to index is queued for retry instead of failing the block
+			var err error
+			if retry_queue.IsLenient() {
+				err = retry_queue.IndexOrQueue(ctx, postgresTx, logging.ModuleStarks, block, &tx, retryStarkTransaction)
+			} else {
+				err = indexStarkTransaction(postgresTx, block, &tx)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to index STARK transaction %s in block %d: %w",
+					tx.TxID, block.Height, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	log.Printf("Successfully indexed %d STARK transactions for block %d", starkTransactionCount, block.Height)
+	logging.Blockf(logging.ModuleStarks, "Successfully indexed %d STARK transactions for block %d", starkTransactionCount, block.Height)
 	return nil
 }
 
-// hasStarkVerifyTze checks if a transaction has STARK verify TZE inputs or outputs
-func hasStarkVerifyTze(tx *types.ZcashTransaction) bool {
-	// Check outputs for STARK verify TZE
-	for _, vout := range tx.Vout {
-		if isStarkVerifyOutput(&vout) {
-			return true
+// starkRowScopes returns the counted rows indexing STARK transactions can add
+// New verifiers are keyed txid:vout, so every output of a STARK transaction is a candidate
+func starkRowScopes(txs []*types.ZcashTransaction) []postgres.RowScope {
+	txids := make([]string, 0, len(txs))
+	var verifierIDs []string
+	for _, tx := range txs {
+		txids = append(txids, tx.TxID)
+		for _, vout := range tx.Vout {
+			verifierIDs = append(verifierIDs, fmt.Sprintf("%s:%d", tx.TxID, vout.N))
 		}
 	}
-
-	// Check inputs for STARK verify TZE
-	for _, vin := range tx.Vin {
-		if isStarkVerifyInput(&vin) {
-			return true
-		}
+	return []postgres.RowScope{
+		{Table: "verifiers", Column: "verifier_id", Keys: verifierIDs},
+		{Table: "stark_proofs", Column: "txid", Keys: txids},
+		{Table: "ztarknet_facts", Column: "txid", Keys: txids},
 	}
-
-	return false
-}
-
-// isStarkVerifyOutput checks if an output is a STARK verify TZE output
-func isStarkVerifyOutput(vout *types.Vout) bool {
-	if vout.ScriptPubKey == nil || len(vout.ScriptPubKey.Hex) < 18 {
-		return false
-	}
-
-	// Check if it starts with "ff" (TZE marker)
-	if vout.ScriptPubKey.Hex[:2] != "ff" {
-		return false
-	}
-
-	// Decode and check if tze_type is 1 (STARK verify)
-	scriptBytes, err := hex.DecodeString(vout.ScriptPubKey.Hex)
-	if err != nil || len(scriptBytes) < 9 {
-		return false
-	}
-
-	// Parse tze_type (4 bytes, big-endian, at bytes 1-4)
-	tzeType := int32(uint32(scriptBytes[1])<<24 | uint32(scriptBytes[2])<<16 | uint32(scriptBytes[3])<<8 | uint32(scriptBytes[4]))
-
-	return tzeType == TzeTypeStarkVerify
 }
 
-// isStarkVerifyInput checks if an input is a STARK verify TZE input
-func isStarkVerifyInput(vin *types.Vin) bool {
-	if vin.ScriptSig == nil || len(vin.ScriptSig.Hex) < 18 {
-		return false
-	}
-
-	// Check if it starts with "ff" (TZE marker)
-	if vin.ScriptSig.Hex[:2] != "ff" {
-		return false
-	}
-
-	// Decode and check if tze_type is 1 (STARK verify)
-	scriptBytes, err := hex.DecodeString(vin.ScriptSig.Hex)
-	if err != nil || len(scriptBytes) < 9 {
-		return false
-	}
-
-	// Parse tze_type (4 bytes, big-endian, at bytes 1-4)
-	tzeType := int32(uint32(scriptBytes[1])<<24 | uint32(scriptBytes[2])<<16 | uint32(scriptBytes[3])<<8 | uint32(scriptBytes[4]))
-
-	return tzeType == TzeTypeStarkVerify
+// hasStarkVerifyTze checks if a transaction has STARK verify TZE inputs or outputs
+func hasStarkVerifyTze(tx *types.ZcashTransaction) bool {
+	return tx.Parsed().HasTzeType(TzeTypeStarkVerify)
 }
 
 // indexStarkTransaction processes a single STARK transaction and its data
 func indexStarkTransaction(postgresTx DBTX, block *types.ZcashBlock, tx *types.ZcashTransaction) error {
+	parsed := tx.Parsed()
+
 	// Check if this transaction has STARK verify inputs
-	hasStarkInput := false
-	for _, vin := range tx.Vin {
-		if isStarkVerifyInput(&vin) {
-			hasStarkInput = true
-			break
-		}
-	}
+	starkInputs := parsed.TzeInputsOfType(TzeTypeStarkVerify)
+	hasStarkInput := len(starkInputs) > 0
 
 	// Process STARK verify inputs first (verify mode - submits proofs)
-	for i, vin := range tx.Vin {
-		if isStarkVerifyInput(&vin) {
-			if err := indexStarkVerifyInput(postgresTx, block, tx, i, &vin); err != nil {
-				return fmt.Errorf("failed to index STARK verify input %d: %w", i, err)
-			}
+	for _, script := range starkInputs {
+		if err := indexStarkVerifyInput(postgresTx, block, tx, script.Index, &tx.Vin[script.Index]); err != nil {
+			return fmt.Errorf("failed to index STARK verify input %d: %w", script.Index, err)
 		}
 	}
 
 	// Process STARK verify outputs
 	// If hasStarkInput is false, this is initialize mode (creates new verifiers)
 	// If hasStarkInput is true, this is verify mode (updates existing verifier balance)
-	for _, vout := range tx.Vout {
-		if isStarkVerifyOutput(&vout) {
-			if err := indexStarkVerifyOutput(postgresTx, block, tx, &vout, hasStarkInput); err != nil {
-				return fmt.Errorf("failed to index STARK verify output %d: %w", vout.N, err)
-			}
+	for _, script := range parsed.TzeOutputsOfType(TzeTypeStarkVerify) {
+		vout := &tx.Vout[script.Index]
+		if err := indexStarkVerifyOutput(postgresTx, block, tx, vout, hasStarkInput); err != nil {
+			return fmt.Errorf("failed to index STARK verify output %d: %w", vout.N, err)
 		}
 	}
 
 	return nil
 }
 
+// retryStarkTransaction adapts indexStarkTransaction to the retry queue
+func retryStarkTransaction(postgresTx retry_queue.DBTX, block *types.ZcashBlock, tx *types.ZcashTransaction) error {
+	return indexStarkTransaction(postgresTx, block, tx)
+}
+
+// retryStarkScopes returns the counted rows a retry of a STARK transaction can add
+func retryStarkScopes(tx *types.ZcashTransaction) []postgres.RowScope {
+	return starkRowScopes([]*types.ZcashTransaction{tx})
+}
+
 // parseTzeData extracts TZE extension_id, mode, and data from a script byte array
 // Format: ff <extension_id> <mode> <data>
 // where extension_id and mode are 4 bytes each (big-endian)
@@ -207,9 +183,9 @@ func parseTzeData(scriptBytes []byte) (tzeType int32, tzeMode int32, data []byte
 // If hasStarkInput is true, this is verify mode (updates existing verifier balance)
 func indexStarkVerifyOutput(postgresTx DBTX, block *types.ZcashBlock, tx *types.ZcashTransaction, vout *types.Vout, hasStarkInput bool) error {
 	// Parse TZE data from scriptPubKey
-	scriptBytes, err := hex.DecodeString(vout.ScriptPubKey.Hex)
+	scriptBytes, err := vout.ScriptPubKey.Bytes()
 	if err != nil {
-		return fmt.Errorf("failed to decode scriptPubKey hex: %w", err)
+		return err
 	}
 
 	tzeType, _, precondition, err := parseTzeData(scriptBytes)
@@ -224,8 +200,12 @@ func indexStarkVerifyOutput(postgresTx DBTX, block *types.ZcashBlock, tx *types.
 
 	if !hasStarkInput {
 		// Initialize mode: Create a new verifier
-		// Parse the precondition to get initial state
-		starkPrecondition, err := parseStarkVerifyPrecondition(precondition)
+		// Parse the precondition to get initial state, with the format in effect at this block
+		format, err := formatAt(block.Height)
+		if err != nil {
+			return err
+		}
+		starkPrecondition, err := format.parsePrecondition(precondition)
 		if err != nil {
 			return fmt.Errorf("failed to parse STARK precondition: %w", err)
 		}
@@ -241,26 +221,24 @@ func indexStarkVerifyOutput(postgresTx DBTX, block *types.ZcashBlock, tx *types.
 		// Store the verifier
 		// TODO: Similar to accounts module, balance tracking will need to handle input values being 0
 		// See accounts indexing for the TODO note about this issue
-		err = StoreVerifier(postgresTx, verifierID, verifierName, verifierMetadata, vout.ValueZat)
+		err = StoreVerifier(postgresTx, verifierID, verifierName, verifierMetadata, vout.ValueZat, starkPrecondition.OldState)
 		if err != nil {
 			return fmt.Errorf("failed to store verifier: %w", err)
 		}
 
-		log.Printf("Created verifier %s (initial state: %s) in block %d", verifierID, starkPrecondition.OldState, block.Height)
+		logging.Infof(logging.ModuleStarks, "Created verifier %s (initial state: %s) in block %d", verifierID, starkPrecondition.OldState, block.Height)
 	} else {
 		// Verify mode: Update existing verifier balance
 		// We need to find the verifier ID from one of the inputs
 		var verifierID string
-		for _, vin := range tx.Vin {
-			if isStarkVerifyInput(&vin) {
-				// Look up the verifier ID from the input
-				foundVerifierID, err := getVerifierIDFromInput(postgresTx, &vin)
-				if err != nil {
-					return fmt.Errorf("failed to get verifier ID from input: %w", err)
-				}
-				verifierID = foundVerifierID
-				break
+		for _, script := range tx.Parsed().TzeInputsOfType(TzeTypeStarkVerify) {
+			// Look up the verifier ID from the input
+			foundVerifierID, err := getVerifierIDFromInput(postgresTx, &tx.Vin[script.Index])
+			if err != nil {
+				return fmt.Errorf("failed to get verifier ID from input: %w", err)
 			}
+			verifierID = foundVerifierID
+			break
 		}
 
 		if verifierID == "" {
@@ -273,7 +251,7 @@ func indexStarkVerifyOutput(postgresTx DBTX, block *types.ZcashBlock, tx *types.
 			return fmt.Errorf("failed to update verifier balance: %w", err)
 		}
 
-		log.Printf("Updated verifier %s balance to %d in block %d", verifierID, vout.ValueZat, block.Height)
+		logging.Blockf(logging.ModuleStarks, "Updated verifier %s balance to %d in block %d", verifierID, vout.ValueZat, block.Height)
 	}
 
 	return nil
@@ -282,27 +260,24 @@ func indexStarkVerifyOutput(postgresTx DBTX, block *types.ZcashBlock, tx *types.
 // indexStarkVerifyInput parses and stores a STARK verify input (verify mode)
 // This submits a proof to a verifier
 func indexStarkVerifyInput(postgresTx DBTX, block *types.ZcashBlock, tx *types.ZcashTransaction, vin int, input *types.Vin) error {
-	// Parse TZE data from scriptSig (witness)
-	scriptBytes, err := hex.DecodeString(input.ScriptSig.Hex)
-	if err != nil {
-		return fmt.Errorf("failed to decode scriptSig hex: %w", err)
-	}
-
-	tzeType, _, witness, err := parseTzeData(scriptBytes)
-	if err != nil {
-		return fmt.Errorf("failed to parse TZE input data: %w", err)
-	}
-
 	// Verify this is STARK verify type
 	// Note: We don't check the mode here because the mode field in inputs
 	// doesn't necessarily indicate verify vs initialize - that's determined
 	// by whether the transaction has STARK verify inputs (checked earlier)
+	tzeType, _, ok := types.ParseTzeHeader(input.ScriptSig.Hex)
+	if !ok {
+		return fmt.Errorf("failed to parse TZE input data: invalid TZE script header")
+	}
 	if tzeType != TzeTypeStarkVerify {
 		return fmt.Errorf("expected STARK verify type, got tzeType=%d", tzeType)
 	}
 
-	// Parse the witness to get proof size
-	witnessData, err := parseStarkVerifyWitness(witness)
+	// Parse the witness to get proof size, with the format in effect at this block
+	format, err := formatAt(block.Height)
+	if err != nil {
+		return err
+	}
+	witnessData, err := readWitness(format, tx.TxID, input.ScriptSig)
 	if err != nil {
 		return fmt.Errorf("failed to parse STARK witness: %w", err)
 	}
@@ -315,49 +290,50 @@ func indexStarkVerifyInput(postgresTx DBTX, block *types.ZcashBlock, tx *types.Z
 	}
 
 	// Store the STARK proof
-	err = StoreStarkProof(postgresTx, verifierID, tx.TxID, block.Height, witnessData.ProofSize)
+	err = StoreStarkProof(postgresTx, verifierID, tx.TxID, block.Height, witnessData.ProofSize, &witnessData.ProofHash,
+		&witnessData.WithPedersen, proofCost(witnessData))
 	if err != nil {
 		return fmt.Errorf("failed to store STARK proof: %w", err)
 	}
+	postgres.QueueManifestKeys(postgresTx, "STARKS", "stark_proofs", "insert", verifierID+"/"+tx.TxID)
 
 	// If Ztarknet indexing is enabled, parse and store Ztarknet facts
 	if ShouldIndexZtarknet() {
 		// We need to get the precondition from the TZE output to parse Ztarknet facts
 		// The precondition is in the output, and the witness is in the input
 		// We need to look up the previous output to get the precondition
-		if err := indexZtarknetFacts(postgresTx, block, tx, verifierID, input, witnessData.ProofSize); err != nil {
+		if err := indexZtarknetFacts(postgresTx, block, tx, format, verifierID, input, witnessData); err != nil {
 			return fmt.Errorf("failed to index Ztarknet facts: %w", err)
 		}
 	}
 
-	log.Printf("Stored STARK proof for verifier %s in tx %s (proof size: %d bytes)", verifierID, tx.TxID, witnessData.ProofSize)
+	logging.Blockf(logging.ModuleStarks, "Stored STARK proof for verifier %s in tx %s (proof size: %d bytes)", verifierID, tx.TxID, witnessData.ProofSize)
 
 	return nil
 }
 
 // indexZtarknetFacts parses and stores Ztarknet-specific facts from a STARK verify transaction
-func indexZtarknetFacts(postgresTx DBTX, block *types.ZcashBlock, tx *types.ZcashTransaction, verifierID string, input *types.Vin, proofSize int64) error {
+// witnessData is the witness of input as parsed by the caller
+func indexZtarknetFacts(postgresTx DBTX, block *types.ZcashBlock, tx *types.ZcashTransaction, format starkFormat, verifierID string, input *types.Vin, witnessData *StarkWitnessData) error {
 	// Find the corresponding TZE output in this transaction to get the new state
 	// The output will have the new state in its precondition
 	var newStatePrecondition []byte
 	found := false
 
-	for _, vout := range tx.Vout {
-		if isStarkVerifyOutput(&vout) {
-			scriptBytes, err := hex.DecodeString(vout.ScriptPubKey.Hex)
-			if err != nil {
-				continue
-			}
-
-			_, _, precondition, err := parseTzeData(scriptBytes)
-			if err != nil {
-				continue
-			}
+	for _, script := range tx.Parsed().TzeOutputsOfType(TzeTypeStarkVerify) {
+		scriptBytes, err := tx.Vout[script.Index].ScriptPubKey.Bytes()
+		if err != nil {
+			continue
+		}
 
-			newStatePrecondition = precondition
-			found = true
-			break
+		_, _, precondition, err := parseTzeData(scriptBytes)
+		if err != nil {
+			continue
 		}
+
+		newStatePrecondition = precondition
+		found = true
+		break
 	}
 
 	if !found {
@@ -365,32 +341,16 @@ func indexZtarknetFacts(postgresTx DBTX, block *types.ZcashBlock, tx *types.Zcas
 	}
 
 	// Parse the new state from the output precondition
-	newStateData, err := parseStarkVerifyPrecondition(newStatePrecondition)
+	newStateData, err := format.parsePrecondition(newStatePrecondition)
 	if err != nil {
 		return fmt.Errorf("failed to parse new state precondition: %w", err)
 	}
 
-	// Parse the old state from the input scriptSig
-	// The old state is encoded in the TZE input script
-	scriptBytes, err := hex.DecodeString(input.ScriptSig.Hex)
-	if err != nil {
-		return fmt.Errorf("failed to decode scriptSig hex: %w", err)
-	}
-
-	_, _, witness, err := parseTzeData(scriptBytes)
-	if err != nil {
-		return fmt.Errorf("failed to parse TZE input data for old state: %w", err)
-	}
-
-	// The old state needs to be retrieved from the previous output's precondition
-	// For now, we'll use a placeholder since we need to query the previous output
-	// TODO: Query the previous TZE output to get its precondition and parse old_state
-	oldState := "0000000000000000000000000000000000000000000000000000000000000000" // Placeholder
-
-	// Parse witness to ensure we have the proof data (already done in caller, but we need it here too)
-	_, err = parseStarkVerifyWitness(witness)
+	// The old state is the state held by the spent output: the new state of the fact that created it,
+	// or the initial state of the verifier when it spends the initialize output
+	oldState, err := resolveOldState(postgresTx, verifierID, input)
 	if err != nil {
-		return fmt.Errorf("failed to parse witness for Ztarknet facts: %w", err)
+		return err
 	}
 
 	// Store the Ztarknet facts
@@ -399,21 +359,58 @@ func indexZtarknetFacts(postgresTx DBTX, block *types.ZcashBlock, tx *types.Zcas
 		verifierID,
 		tx.TxID,
 		block.Height,
-		proofSize,
+		witnessData.ProofSize,
 		oldState,
 		newStateData.NewState,
 		newStateData.ProgramHash,
 		newStateData.InnerProgramHash,
+		format.version,
+		newStateData.L2BlockNumber,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to store Ztarknet facts: %w", err)
 	}
+	postgres.QueueManifestKeys(postgresTx, "STARKS", "ztarknet_facts", "insert", verifierID+"/"+tx.TxID)
 
-	log.Printf("Stored Ztarknet facts for verifier %s: %s -> %s", verifierID, oldState[:8], newStateData.NewState[:8])
+	err = checkExpectedPrograms(postgresTx, verifierID, tx.TxID, block.Height, newStateData.ProgramHash, newStateData.InnerProgramHash)
+	if err != nil {
+		return err
+	}
+
+	if len(witnessData.Segments) > 0 {
+		if err := StoreFactSegments(postgresTx, verifierID, tx.TxID, block.Height, witnessData.Segments); err != nil {
+			return err
+		}
+	}
+
+	logging.Blockf(logging.ModuleStarks, "Stored Ztarknet facts for verifier %s: %s -> %s", verifierID, oldState[:8], newStateData.NewState[:8])
 
 	return nil
 }
 
+// resolveOldState returns the state root held by the TZE output an input spends
+// Outputs indexed before states were tracked resolve to unknownState, which the state chain reports as a gap
+func resolveOldState(postgresTx DBTX, verifierID string, input *types.Vin) (string, error) {
+	ctx := context.Background()
+
+	var state *string
+	err := postgresTx.QueryRow(ctx,
+		`SELECT new_state FROM ztarknet_facts WHERE verifier_id = $1 AND txid = $2
+		 UNION ALL
+		 SELECT initial_state FROM verifiers WHERE verifier_id = $3
+		 LIMIT 1`,
+		verifierID, input.TxID, fmt.Sprintf("%s:%d", input.TxID, input.Vout),
+	).Scan(&state)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && state == nil) {
+		return unknownState, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve old state of input %s:%d: %w", input.TxID, input.Vout, err)
+	}
+
+	return *state, nil
+}
+
 // getVerifierIDFromInput traces back through the chain of verifications to find the original verifier ID
 // It queries the database to find either:
 // 1. A verifier with verifier_id matching the previous txid:vout (if this is the first verification)
@@ -456,6 +453,7 @@ type StarkPreconditionData struct {
 	NewState         string
 	ProgramHash      string // bootloader program hash
 	InnerProgramHash string // OS program hash
+	L2BlockNumber    *int64 // Ztarknet block number the OS output commits to, nil before FormatV2
 }
 
 // parseStarkVerifyPrecondition parses the precondition data from a STARK verify TZE output
@@ -497,12 +495,92 @@ func parseStarkVerifyPrecondition(precondition []byte) (*StarkPreconditionData,
 	}, nil
 }
 
+// parseStarkVerifyPreconditionV2 parses a FormatV2 precondition: the FormatV1 layout followed by
+// the 8-byte big-endian L2 block number of the Ztarknet OS output
+func parseStarkVerifyPreconditionV2(precondition []byte) (*StarkPreconditionData, error) {
+	const l2BlockOffset = 4 + 96
+	if len(precondition) < l2BlockOffset+8 {
+		return nil, fmt.Errorf("format 2 precondition too short for the L2 block number: %d bytes", len(precondition))
+	}
+
+	data, err := parseStarkVerifyPrecondition(precondition[:l2BlockOffset])
+	if err != nil {
+		return nil, err
+	}
+
+	l2BlockNumber := binary.BigEndian.Uint64(precondition[l2BlockOffset : l2BlockOffset+8])
+	if l2BlockNumber > math.MaxInt64 {
+		return nil, fmt.Errorf("L2 block number %d out of range", l2BlockNumber)
+	}
+	number := int64(l2BlockNumber)
+	data.L2BlockNumber = &number
+
+	return data, nil
+}
+
 // StarkWitnessData represents parsed STARK witness data
 type StarkWitnessData struct {
 	WithPedersen bool
 	ProofFormat  string
 	ProofData    []byte
 	ProofSize    int64
+	ProofHash    string           // hex SHA-256 of the whole witness, identical for replayed submissions
+	Segments     []WitnessSegment // DA blobs and messages carried after the proof, from FormatV3
+}
+
+// WitnessSegment is a data availability blob or L2 message carried in a FormatV3 witness
+type WitnessSegment struct {
+	Kind string
+	Data []byte
+}
+
+// Witness segment kinds, by their FormatV3 kind byte
+var witnessSegmentKinds = map[byte]string{
+	0: SegmentKindDABlob,
+	1: SegmentKindMessage,
+}
+
+// readWitness parses the witness of a STARK verify input with the given format
+// The scriptSig is decoded once and shared with the other modules; a witness above
+// indexer.max_witness_size is never decoded: its proof_hash is streamed from the hex and only
+// the flags and proof length are read, so FormatV3 DA segments are skipped
+func readWitness(format starkFormat, txid string, scriptSig *types.ScriptSig) (*StarkWitnessData, error) {
+	maxSize := config.Conf.Indexer.MaxWitnessSize
+	witnessSize := types.TzeDataSize(scriptSig.Hex)
+	if maxSize == 0 || witnessSize <= maxSize {
+		scriptBytes, err := scriptSig.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		_, _, witness, err := parseTzeData(scriptBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse TZE input data: %w", err)
+		}
+		return format.parseWitness(witness)
+	}
+
+	header, err := types.DecodeTzeDataPrefix(scriptSig.Hex, 6)
+	if err != nil {
+		return nil, err
+	}
+	data, err := parseStarkVerifyWitness(header[:min(len(header), 2)])
+	if err != nil {
+		return nil, err
+	}
+	data.ProofSize = int64(witnessSize - 2)
+	if format.proofLengthPrefixed {
+		if len(header) < 6 {
+			return nil, fmt.Errorf("format %d witness too short: %d bytes", format.version, witnessSize)
+		}
+		data.ProofSize = int64(binary.BigEndian.Uint32(header[2:]))
+	}
+	data.ProofHash, err = types.HashTzeData(scriptSig.Hex)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.Warnf(logging.ModuleStarks, "Witness of tx %s is %d bytes, above indexer.max_witness_size (%d): hashed without decoding, proof data and segments not parsed", txid, witnessSize, maxSize)
+	return data, nil
 }
 
 // parseStarkVerifyWitness parses the witness data from a STARK verify TZE input
@@ -533,5 +611,62 @@ func parseStarkVerifyWitness(witness []byte) (*StarkWitnessData, error) {
 		ProofFormat:  proofFormat,
 		ProofData:    proofData,
 		ProofSize:    int64(len(proofData)),
+		ProofHash:    fmt.Sprintf("%x", sha256.Sum256(witness)),
 	}, nil
 }
+
+// parseStarkVerifyWitnessV3 parses a FormatV3 witness:
+// - 1 byte with_pedersen
+// - 1 byte proof_format (0=JSON, 1=Binary)
+// - 4-byte big-endian proof length, then the proof
+// - 2-byte big-endian segment count
+// - per segment: 1 byte kind (0=DA blob, 1=message), 4-byte big-endian length, data
+func parseStarkVerifyWitnessV3(witness []byte) (*StarkWitnessData, error) {
+	if len(witness) < 6 {
+		return nil, fmt.Errorf("format 3 witness too short: %d bytes", len(witness))
+	}
+
+	offset := 2
+	proofLength := int(binary.BigEndian.Uint32(witness[offset:]))
+	offset += 4
+	if proofLength > len(witness)-offset {
+		return nil, fmt.Errorf("format 3 witness proof length %d exceeds the %d bytes left", proofLength, len(witness)-offset)
+	}
+	data, err := parseStarkVerifyWitness(witness[:2])
+	if err != nil {
+		return nil, err
+	}
+	data.ProofData = witness[offset : offset+proofLength]
+	data.ProofSize = int64(proofLength)
+	data.ProofHash = fmt.Sprintf("%x", sha256.Sum256(witness))
+	offset += proofLength
+
+	if len(witness)-offset < 2 {
+		return nil, fmt.Errorf("format 3 witness has no segment count")
+	}
+	count := int(binary.BigEndian.Uint16(witness[offset:]))
+	offset += 2
+
+	data.Segments = make([]WitnessSegment, 0, count)
+	for i := 0; i < count; i++ {
+		if len(witness)-offset < 5 {
+			return nil, fmt.Errorf("format 3 witness segment %d header truncated", i)
+		}
+		kind, ok := witnessSegmentKinds[witness[offset]]
+		if !ok {
+			return nil, fmt.Errorf("format 3 witness segment %d has unknown kind %d", i, witness[offset])
+		}
+		length := int(binary.BigEndian.Uint32(witness[offset+1:]))
+		offset += 5
+		if length > len(witness)-offset {
+			return nil, fmt.Errorf("format 3 witness segment %d length %d exceeds the %d bytes left", i, length, len(witness)-offset)
+		}
+		data.Segments = append(data.Segments, WitnessSegment{Kind: kind, Data: witness[offset : offset+length]})
+		offset += length
+	}
+	if offset != len(witness) {
+		return nil, fmt.Errorf("format 3 witness has %d trailing bytes", len(witness)-offset)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,61 @@
+package starks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+)
+
+// GetL2BlockAtHeight returns the latest L2 block a verifier had settled at an L1 height,
+// taken from its last fact with an L2 block number at or below the height
+// Returns nil when no such fact exists
+func GetL2BlockAtHeight(verifierID string, l1Height int64) (*L1L2Mapping, error) {
+	return getL1L2Mapping(
+		`SELECT verifier_id, block_height AS l1_height, l2_block_number, txid
+		 FROM ztarknet_facts
+		 WHERE verifier_id = $1 AND block_height <= $2 AND l2_block_number IS NOT NULL
+		 ORDER BY block_height DESC, l2_block_number DESC
+		 LIMIT 1`,
+		verifierID, l1Height,
+	)
+}
+
+// GetL1HeightOfL2Block returns the L1 height at which a verifier settled an L2 block: the first
+// fact proving that block or a later one, since each fact settles every L2 block up to its own
+// Returns nil while the L2 block is not settled
+func GetL1HeightOfL2Block(verifierID string, l2BlockNumber int64) (*L1L2Mapping, error) {
+	return getL1L2Mapping(
+		`SELECT verifier_id, block_height AS l1_height, l2_block_number, txid
+		 FROM ztarknet_facts
+		 WHERE verifier_id = $1 AND l2_block_number >= $2
+		 ORDER BY block_height, l2_block_number
+		 LIMIT 1`,
+		verifierID, l2BlockNumber,
+	)
+}
+
+func getL1L2Mapping(query string, verifierID string, height int64) (*L1L2Mapping, error) {
+	mapping, err := postgres.PostgresQueryOneCtx[L1L2Mapping](context.Background(), readDB, query, verifierID, height)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to map L1 and L2 heights for verifier %s: %w", verifierID, err)
+	}
+
+	lastIndexed, err := postgres.GetLastIndexedBlock()
+	if err != nil {
+		return nil, err
+	}
+	finalizedHeight, err := postgres.GetFinalizedHeight()
+	if err != nil {
+		return nil, err
+	}
+	mapping.Confirmations = lastIndexed - mapping.L1Height + 1
+	mapping.Final = mapping.L1Height <= finalizedHeight
+
+	return mapping, nil
+}
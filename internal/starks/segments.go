@@ -0,0 +1,86 @@
+package starks
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+)
+
+// Kinds of witness segments stored with a fact
+const (
+	SegmentKindDABlob  = "da_blob" // state diff data L2 nodes reconstruct state from
+	SegmentKindMessage = "message" // L2 to L1 message
+)
+
+// IsSegmentKind reports whether kind is a known witness segment kind
+func IsSegmentKind(kind string) bool {
+	return kind == SegmentKindDABlob || kind == SegmentKindMessage
+}
+
+// StoreFactSegments replaces the witness segments stored for a fact
+func StoreFactSegments(postgresTx DBTX, verifierID, txid string, blockHeight int64, segments []WitnessSegment) error {
+	ctx := context.Background()
+
+	if postgresTx == nil {
+		postgresTx = postgres.DB
+	}
+
+	_, err := postgresTx.Exec(ctx, `DELETE FROM fact_segments WHERE verifier_id = $1 AND txid = $2`, verifierID, txid)
+	if err != nil {
+		return fmt.Errorf("failed to clear segments of fact %s/%s: %w", verifierID, txid, err)
+	}
+
+	for seq, segment := range segments {
+		_, err := postgresTx.Exec(ctx,
+			`INSERT INTO fact_segments (verifier_id, txid, seq, kind, block_height, size, data_hash, data)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			verifierID, txid, seq, segment.Kind, blockHeight, len(segment.Data),
+			fmt.Sprintf("%x", sha256.Sum256(segment.Data)), segment.Data,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to store segment %d of fact %s/%s: %w", seq, verifierID, txid, err)
+		}
+	}
+
+	return nil
+}
+
+// GetFactSegments lists the witness segments of a fact in witness order, of one kind unless kind is empty
+// Segment data is only included with includeData, as blobs can be large
+func GetFactSegments(verifierID, txid, kind string, includeData bool) ([]FactSegment, error) {
+	segments, err := postgres.PostgresQueryCtx[FactSegment](
+		context.Background(), readDB,
+		`SELECT verifier_id, txid, seq, kind, block_height, size, data_hash,
+		        CASE WHEN $4 THEN encode(data, 'hex') END AS data
+		 FROM fact_segments
+		 WHERE verifier_id = $1 AND txid = $2 AND ($3 = '' OR kind = $3)
+		 ORDER BY seq`,
+		verifierID, txid, kind, includeData,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get segments of fact %s/%s: %w", verifierID, txid, err)
+	}
+
+	return segments, nil
+}
+
+// GetFactSegmentData returns the raw data of one witness segment, nil when it does not exist
+func GetFactSegmentData(verifierID, txid string, seq int) ([]byte, error) {
+	data, err := postgres.PostgresQueryOneCtx[[]byte](
+		context.Background(), readDB,
+		`SELECT data FROM fact_segments WHERE verifier_id = $1 AND txid = $2 AND seq = $3`,
+		verifierID, txid, seq,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get segment %d of fact %s/%s: %w", seq, verifierID, txid, err)
+	}
+
+	return *data, nil
+}
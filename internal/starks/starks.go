@@ -8,11 +8,101 @@ import (
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/retry_queue"
 )
 
 func init() {
 	// Register this module's schema initialization with the postgres package
 	postgres.RegisterModuleSchema("STARKS", InitSchema)
+	// Register how transactions queued in lenient mode are retried
+	retry_queue.RegisterRetrier("STARKS", retry_queue.Retrier{
+		Index:   retryStarkTransaction,
+		Scopes:  retryStarkScopes,
+		Publish: publishTransactionEvents,
+	})
+}
+
+// redundantIndexes are created by default but dropped when database.drop_redundant_indexes
+// is enabled, since verifier_id is the leading column of the (verifier_id, txid) primary keys
+var redundantIndexes = []postgres.RedundantIndex{
+	{
+		Name:       "idx_stark_proofs_verifier",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_stark_proofs_verifier ON stark_proofs(verifier_id);`,
+	},
+	{
+		Name:       "idx_ztarknet_facts_verifier",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_ztarknet_facts_verifier ON ztarknet_facts(verifier_id);`,
+	},
+}
+
+// secondaryIndexes serve verifier, proof and fact queries
+// The txid indexes are required: the indexer resolves verifiers and counts rows by txid
+var secondaryIndexes = []postgres.SecondaryIndex{
+	// Indexes for verifiers
+	{
+		Name:       "idx_verifiers_name",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_verifiers_name ON verifiers(verifier_name);`,
+	},
+	{
+		Name:       "idx_verifiers_first_seen",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_verifiers_first_seen ON verifiers(first_seen_at);`,
+	},
+	{
+		Name:       "idx_verifiers_balance",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_verifiers_balance ON verifiers(balance);`,
+	},
+	// Indexes for stark_proofs
+	{
+		Name:       "idx_stark_proofs_txid",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_stark_proofs_txid ON stark_proofs(txid);`,
+		Required:   true,
+	},
+	{
+		Name:       "idx_stark_proofs_block_height",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_stark_proofs_block_height ON stark_proofs(block_height);`,
+	},
+	{
+		Name:       "idx_stark_proofs_size",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_stark_proofs_size ON stark_proofs(proof_size);`,
+	},
+	{
+		Name:       "idx_stark_proofs_proof_hash",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_stark_proofs_proof_hash ON stark_proofs(proof_hash) WHERE proof_hash IS NOT NULL;`,
+	},
+	// Indexes for ztarknet_facts
+	{
+		Name:       "idx_ztarknet_facts_txid",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_ztarknet_facts_txid ON ztarknet_facts(txid);`,
+		Required:   true,
+	},
+	{
+		Name:       "idx_ztarknet_facts_block_height",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_ztarknet_facts_block_height ON ztarknet_facts(block_height);`,
+	},
+	{
+		Name:       "idx_ztarknet_facts_old_state",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_ztarknet_facts_old_state ON ztarknet_facts(old_state);`,
+	},
+	{
+		Name:       "idx_ztarknet_facts_new_state",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_ztarknet_facts_new_state ON ztarknet_facts(new_state);`,
+	},
+	{
+		Name:       "idx_ztarknet_facts_old_state_prefix",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_ztarknet_facts_old_state_prefix ON ztarknet_facts(old_state text_pattern_ops);`,
+	},
+	{
+		Name:       "idx_ztarknet_facts_new_state_prefix",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_ztarknet_facts_new_state_prefix ON ztarknet_facts(new_state text_pattern_ops);`,
+	},
+	{
+		Name:       "idx_ztarknet_facts_program_hash",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_ztarknet_facts_program_hash ON ztarknet_facts(program_hash);`,
+	},
+	{
+		Name:       "idx_ztarknet_facts_l2_block_number",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_ztarknet_facts_l2_block_number ON ztarknet_facts(l2_block_number) WHERE l2_block_number IS NOT NULL;`,
+	},
 }
 
 // InitSchema creates the starks module tables and indexes
@@ -24,19 +114,34 @@ func InitSchema() error {
 			verifier_name VARCHAR(255) NOT NULL,
 			verifier_metadata TEXT,
 			balance BIGINT NOT NULL DEFAULT 0,
+			label_curated BOOLEAN NOT NULL DEFAULT FALSE,  -- name/metadata set by an admin, kept on re-index
+			initial_state VARCHAR(64),  -- state root of the initialize output, NULL for verifiers indexed before it was stored
 			first_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 		);
 
+		-- Columns added after the initial schema
+		ALTER TABLE verifiers ADD COLUMN IF NOT EXISTS label_curated BOOLEAN NOT NULL DEFAULT FALSE;
+		ALTER TABLE verifiers ADD COLUMN IF NOT EXISTS canonical BOOLEAN NOT NULL DEFAULT FALSE;  -- designated by an admin
+		ALTER TABLE verifiers ADD COLUMN IF NOT EXISTS initial_state VARCHAR(64);
+
+		-- At most one verifier is the canonical Ztarknet verifier
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_verifiers_canonical ON verifiers(canonical) WHERE canonical;
+
 		-- STARK proofs table
 		CREATE TABLE IF NOT EXISTS stark_proofs (
 			verifier_id VARCHAR(80) NOT NULL,  -- matches verifiers.verifier_id
 			txid VARCHAR(64) NOT NULL,
 			block_height BIGINT NOT NULL,
 			proof_size BIGINT NOT NULL,
+			proof_hash VARCHAR(64),  -- SHA-256 of the witness, NULL for imported proofs
 			PRIMARY KEY (verifier_id, txid),
 			FOREIGN KEY (verifier_id) REFERENCES verifiers(verifier_id) ON DELETE CASCADE
 		);
 
+		ALTER TABLE stark_proofs ADD COLUMN IF NOT EXISTS proof_hash VARCHAR(64);
+		ALTER TABLE stark_proofs ADD COLUMN IF NOT EXISTS with_pedersen BOOLEAN;  -- NULL for imported proofs
+		ALTER TABLE stark_proofs ADD COLUMN IF NOT EXISTS verification_cost BIGINT;  -- NULL when no cost model was configured
+
 		-- Ztarknet facts table
 		CREATE TABLE IF NOT EXISTS ztarknet_facts (
 			verifier_id VARCHAR(80) NOT NULL,  -- matches verifiers.verifier_id
@@ -47,28 +152,44 @@ func InitSchema() error {
 			new_state VARCHAR(64) NOT NULL,
 			program_hash VARCHAR(64) NOT NULL,
 			inner_program_hash VARCHAR(64) NOT NULL,
+			format_version SMALLINT NOT NULL DEFAULT 1,  -- stark_verify format the fact was parsed with
+			l2_block_number BIGINT,  -- Ztarknet block number proven by the fact, NULL before format 2
 			PRIMARY KEY (verifier_id, txid),
 			FOREIGN KEY (verifier_id) REFERENCES verifiers(verifier_id) ON DELETE CASCADE
 		);
 
-		-- Indexes for verifiers
-		CREATE INDEX IF NOT EXISTS idx_verifiers_name ON verifiers(verifier_name);
-		CREATE INDEX IF NOT EXISTS idx_verifiers_first_seen ON verifiers(first_seen_at);
-		CREATE INDEX IF NOT EXISTS idx_verifiers_balance ON verifiers(balance);
-
-		-- Indexes for stark_proofs
-		CREATE INDEX IF NOT EXISTS idx_stark_proofs_txid ON stark_proofs(txid);
-		CREATE INDEX IF NOT EXISTS idx_stark_proofs_block_height ON stark_proofs(block_height);
-		CREATE INDEX IF NOT EXISTS idx_stark_proofs_verifier ON stark_proofs(verifier_id);
-		CREATE INDEX IF NOT EXISTS idx_stark_proofs_size ON stark_proofs(proof_size);
-
-		-- Indexes for ztarknet_facts
-		CREATE INDEX IF NOT EXISTS idx_ztarknet_facts_txid ON ztarknet_facts(txid);
-		CREATE INDEX IF NOT EXISTS idx_ztarknet_facts_block_height ON ztarknet_facts(block_height);
-		CREATE INDEX IF NOT EXISTS idx_ztarknet_facts_verifier ON ztarknet_facts(verifier_id);
-		CREATE INDEX IF NOT EXISTS idx_ztarknet_facts_old_state ON ztarknet_facts(old_state);
-		CREATE INDEX IF NOT EXISTS idx_ztarknet_facts_new_state ON ztarknet_facts(new_state);
-		CREATE INDEX IF NOT EXISTS idx_ztarknet_facts_program_hash ON ztarknet_facts(program_hash);
+		ALTER TABLE ztarknet_facts ADD COLUMN IF NOT EXISTS format_version SMALLINT NOT NULL DEFAULT 1;
+		ALTER TABLE ztarknet_facts ADD COLUMN IF NOT EXISTS l2_block_number BIGINT;
+
+		-- Facts that disagree with the expected programs of the canonical deployment
+		-- Removed with their fact on reorgs and re-indexing
+		CREATE TABLE IF NOT EXISTS fact_anomalies (
+			verifier_id VARCHAR(80) NOT NULL,
+			txid VARCHAR(64) NOT NULL,
+			kind VARCHAR(32) NOT NULL,  -- unexpected_program_hash or unexpected_inner_program_hash
+			block_height BIGINT NOT NULL,
+			expected VARCHAR(64) NOT NULL,
+			actual VARCHAR(64) NOT NULL,
+			detected_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (verifier_id, txid, kind),
+			FOREIGN KEY (verifier_id, txid) REFERENCES ztarknet_facts(verifier_id, txid) ON DELETE CASCADE
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_fact_anomalies_block_height ON fact_anomalies(block_height);
+
+		-- DA blobs and messages carried in fact witnesses, from stark_verify format 3
+		CREATE TABLE IF NOT EXISTS fact_segments (
+			verifier_id VARCHAR(80) NOT NULL,
+			txid VARCHAR(64) NOT NULL,
+			seq SMALLINT NOT NULL,  -- position in the witness
+			kind VARCHAR(16) NOT NULL,  -- da_blob or message
+			block_height BIGINT NOT NULL,
+			size INTEGER NOT NULL,
+			data_hash VARCHAR(64) NOT NULL,
+			data BYTEA NOT NULL,
+			PRIMARY KEY (verifier_id, txid, seq),
+			FOREIGN KEY (verifier_id, txid) REFERENCES ztarknet_facts(verifier_id, txid) ON DELETE CASCADE
+		);
 	`
 
 	_, err := postgres.DB.Exec(context.Background(), schema)
@@ -76,6 +197,14 @@ func InitSchema() error {
 		return fmt.Errorf("failed to create starks schema: %w", err)
 	}
 
+	if err := postgres.InitSecondaryIndexes(secondaryIndexes); err != nil {
+		return fmt.Errorf("failed to init starks indexes: %w", err)
+	}
+
+	if err := postgres.InitRedundantIndexes(redundantIndexes); err != nil {
+		return fmt.Errorf("failed to init starks redundant indexes: %w", err)
+	}
+
 	return nil
 }
 
@@ -90,7 +219,8 @@ func ShouldIndexZtarknet() bool {
 
 // GetVerifier retrieves a verifier by its ID
 func GetVerifier(verifierID string) (*Verifier, error) {
-	verifier, err := postgres.PostgresQueryOne[Verifier](
+	verifier, err := postgres.PostgresQueryOneCtx[Verifier](
+		context.Background(), readDB,
 		`SELECT verifier_id, verifier_name, verifier_metadata, balance, first_seen_at
 		 FROM verifiers WHERE verifier_id = $1`,
 		verifierID,
@@ -108,7 +238,8 @@ func GetVerifier(verifierID string) (*Verifier, error) {
 
 // GetVerifierByName retrieves a verifier by its name
 func GetVerifierByName(verifierName string) (*Verifier, error) {
-	verifier, err := postgres.PostgresQueryOne[Verifier](
+	verifier, err := postgres.PostgresQueryOneCtx[Verifier](
+		context.Background(), readDB,
 		`SELECT verifier_id, verifier_name, verifier_metadata, balance, first_seen_at
 		 FROM verifiers WHERE verifier_name = $1`,
 		verifierName,
@@ -126,7 +257,8 @@ func GetVerifierByName(verifierName string) (*Verifier, error) {
 
 // GetAllVerifiers retrieves all verifiers with pagination
 func GetAllVerifiers(limit, offset int) ([]Verifier, error) {
-	verifiers, err := postgres.PostgresQuery[Verifier](
+	verifiers, err := postgres.PostgresQueryCtx[Verifier](
+		context.Background(), readDB,
 		`SELECT verifier_id, verifier_name, verifier_metadata, balance, first_seen_at
 		 FROM verifiers
 		 ORDER BY first_seen_at DESC
@@ -142,7 +274,8 @@ func GetAllVerifiers(limit, offset int) ([]Verifier, error) {
 
 // GetVerifiersByBalance retrieves verifiers sorted by balance
 func GetVerifiersByBalance(limit, offset int) ([]Verifier, error) {
-	verifiers, err := postgres.PostgresQuery[Verifier](
+	verifiers, err := postgres.PostgresQueryCtx[Verifier](
+		context.Background(), readDB,
 		`SELECT verifier_id, verifier_name, verifier_metadata, balance, first_seen_at
 		 FROM verifiers
 		 ORDER BY balance DESC
@@ -160,10 +293,69 @@ func GetVerifiersByBalance(limit, offset int) ([]Verifier, error) {
 // StarkProof Query Functions
 // ============================================================================
 
+// verifierOutputCondition matches the stark_verify TZE outputs that have controlled verifier v:
+// the initialize output named by verifier_id, and the re-created output of each verify transaction
+// $1 is the stark_verify extension id
+const verifierOutputCondition = `
+	o.tze_type = $1
+	AND (
+		(o.txid = split_part(v.verifier_id, ':', 1) AND o.vout = split_part(v.verifier_id, ':', 2)::INT)
+		OR o.txid IN (SELECT p.txid FROM stark_proofs p WHERE p.verifier_id = v.verifier_id)
+	)`
+
+// GetActiveVerifiers retrieves verifiers whose controlling stark_verify UTXO is unspent
+// Requires the TZE graph module, which indexes tze_outputs
+func GetActiveVerifiers(limit, offset int) ([]Verifier, error) {
+	verifiers, err := postgres.PostgresQueryCtx[Verifier](
+		context.Background(), readDB,
+		`SELECT v.verifier_id, v.verifier_name, v.verifier_metadata, v.balance, v.first_seen_at
+		 FROM verifiers v
+		 WHERE EXISTS (
+			SELECT 1 FROM tze_outputs o
+			WHERE `+verifierOutputCondition+` AND o.spent_by_txid IS NULL
+		 )
+		 ORDER BY v.first_seen_at DESC, v.verifier_id
+		 LIMIT $2 OFFSET $3`,
+		TzeTypeStarkVerify, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active verifiers: %w", err)
+	}
+
+	return verifiers, nil
+}
+
+// GetTerminatedVerifiers retrieves verifiers whose controlling UTXO was spent without being re-created
+// Requires the TZE graph module, which indexes tze_outputs
+func GetTerminatedVerifiers(limit, offset int) ([]Verifier, error) {
+	verifiers, err := postgres.PostgresQueryCtx[Verifier](
+		context.Background(), readDB,
+		`SELECT v.verifier_id, v.verifier_name, v.verifier_metadata, v.balance, v.first_seen_at
+		 FROM verifiers v
+		 WHERE EXISTS (
+			SELECT 1 FROM tze_outputs o
+			WHERE `+verifierOutputCondition+` AND o.spent_by_txid IS NOT NULL
+		 )
+		 AND NOT EXISTS (
+			SELECT 1 FROM tze_outputs o
+			WHERE `+verifierOutputCondition+` AND o.spent_by_txid IS NULL
+		 )
+		 ORDER BY v.first_seen_at DESC, v.verifier_id
+		 LIMIT $2 OFFSET $3`,
+		TzeTypeStarkVerify, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get terminated verifiers: %w", err)
+	}
+
+	return verifiers, nil
+}
+
 // GetStarkProof retrieves a STARK proof by verifier ID and transaction ID
 func GetStarkProof(verifierID, txid string) (*StarkProof, error) {
-	proof, err := postgres.PostgresQueryOne[StarkProof](
-		`SELECT verifier_id, txid, block_height, proof_size
+	proof, err := postgres.PostgresQueryOneCtx[StarkProof](
+		context.Background(), readDB,
+		`SELECT verifier_id, txid, block_height, proof_size, proof_hash, with_pedersen, verification_cost
 		 FROM stark_proofs
 		 WHERE verifier_id = $1 AND txid = $2`,
 		verifierID, txid,
@@ -181,8 +373,9 @@ func GetStarkProof(verifierID, txid string) (*StarkProof, error) {
 
 // GetStarkProofsByVerifier retrieves all STARK proofs for a verifier
 func GetStarkProofsByVerifier(verifierID string, limit, offset int) ([]StarkProof, error) {
-	proofs, err := postgres.PostgresQuery[StarkProof](
-		`SELECT verifier_id, txid, block_height, proof_size
+	proofs, err := postgres.PostgresQueryCtx[StarkProof](
+		context.Background(), readDB,
+		`SELECT verifier_id, txid, block_height, proof_size, proof_hash, with_pedersen, verification_cost
 		 FROM stark_proofs
 		 WHERE verifier_id = $1
 		 ORDER BY block_height DESC
@@ -198,8 +391,9 @@ func GetStarkProofsByVerifier(verifierID string, limit, offset int) ([]StarkProo
 
 // GetStarkProofsByTransaction retrieves all STARK proofs for a transaction
 func GetStarkProofsByTransaction(txid string) ([]StarkProof, error) {
-	proofs, err := postgres.PostgresQuery[StarkProof](
-		`SELECT verifier_id, txid, block_height, proof_size
+	proofs, err := postgres.PostgresQueryCtx[StarkProof](
+		context.Background(), readDB,
+		`SELECT verifier_id, txid, block_height, proof_size, proof_hash, with_pedersen, verification_cost
 		 FROM stark_proofs
 		 WHERE txid = $1
 		 ORDER BY verifier_id`,
@@ -212,14 +406,15 @@ func GetStarkProofsByTransaction(txid string) ([]StarkProof, error) {
 	return proofs, nil
 }
 
-// GetStarkProofsByBlock retrieves all STARK proofs for a block
-func GetStarkProofsByBlock(blockHeight int64) ([]StarkProof, error) {
-	proofs, err := postgres.PostgresQuery[StarkProof](
-		`SELECT verifier_id, txid, block_height, proof_size
+// GetStarkProofsByBlock retrieves all STARK proofs for a block, of one verifier unless verifierID is empty
+func GetStarkProofsByBlock(blockHeight int64, verifierID string) ([]StarkProof, error) {
+	proofs, err := postgres.PostgresQueryCtx[StarkProof](
+		context.Background(), readDB,
+		`SELECT verifier_id, txid, block_height, proof_size, proof_hash, with_pedersen, verification_cost
 		 FROM stark_proofs
-		 WHERE block_height = $1
+		 WHERE block_height = $1 AND ($2 = '' OR verifier_id = $2)
 		 ORDER BY txid`,
-		blockHeight,
+		blockHeight, verifierID,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get stark proofs by block: %w", err)
@@ -228,14 +423,16 @@ func GetStarkProofsByBlock(blockHeight int64) ([]StarkProof, error) {
 	return proofs, nil
 }
 
-// GetRecentStarkProofs retrieves the most recent STARK proofs
-func GetRecentStarkProofs(limit, offset int) ([]StarkProof, error) {
-	proofs, err := postgres.PostgresQuery[StarkProof](
-		`SELECT verifier_id, txid, block_height, proof_size
+// GetRecentStarkProofs retrieves the most recent STARK proofs, of one verifier unless verifierID is empty
+func GetRecentStarkProofs(verifierID string, limit, offset int) ([]StarkProof, error) {
+	proofs, err := postgres.PostgresQueryCtx[StarkProof](
+		context.Background(), readDB,
+		`SELECT verifier_id, txid, block_height, proof_size, proof_hash, with_pedersen, verification_cost
 		 FROM stark_proofs
+		 WHERE $1 = '' OR verifier_id = $1
 		 ORDER BY block_height DESC, txid
-		 LIMIT $1 OFFSET $2`,
-		limit, offset,
+		 LIMIT $2 OFFSET $3`,
+		verifierID, limit, offset,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get recent stark proofs: %w", err)
@@ -246,8 +443,9 @@ func GetRecentStarkProofs(limit, offset int) ([]StarkProof, error) {
 
 // GetStarkProofsBySize retrieves STARK proofs filtered by size range
 func GetStarkProofsBySize(minSize, maxSize int64, limit, offset int) ([]StarkProof, error) {
-	proofs, err := postgres.PostgresQuery[StarkProof](
-		`SELECT verifier_id, txid, block_height, proof_size
+	proofs, err := postgres.PostgresQueryCtx[StarkProof](
+		context.Background(), readDB,
+		`SELECT verifier_id, txid, block_height, proof_size, proof_hash, with_pedersen, verification_cost
 		 FROM stark_proofs
 		 WHERE proof_size >= $1 AND proof_size <= $2
 		 ORDER BY proof_size DESC
@@ -261,15 +459,127 @@ func GetStarkProofsBySize(minSize, maxSize int64, limit, offset int) ([]StarkPro
 	return proofs, nil
 }
 
+// GetStarkProofsByTimeRange retrieves STARK proofs whose block timestamp falls within a range,
+// of one verifier unless verifierID is empty
+func GetStarkProofsByTimeRange(fromTime, toTime int64, verifierID string, limit, offset int) ([]StarkProof, error) {
+	proofs, err := postgres.PostgresQueryCtx[StarkProof](
+		context.Background(), readDB,
+		`SELECT p.verifier_id, p.txid, p.block_height, p.proof_size, p.proof_hash, p.with_pedersen, p.verification_cost
+		 FROM stark_proofs p
+		 JOIN blocks b ON b.height = p.block_height
+		 WHERE b.timestamp >= $1 AND b.timestamp <= $2 AND ($3 = '' OR p.verifier_id = $3)
+		 ORDER BY p.block_height DESC, p.txid
+		 LIMIT $4 OFFSET $5`,
+		fromTime, toTime, verifierID, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stark proofs by time range: %w", err)
+	}
+
+	return proofs, nil
+}
+
+// GetDailyStarkProofActivity aggregates STARK proofs into UTC day buckets within a time range
+func GetDailyStarkProofActivity(fromTime, toTime int64) ([]DailyStarkActivity, error) {
+	activity, err := postgres.PostgresQueryCtx[DailyStarkActivity](
+		context.Background(), readDB,
+		`SELECT to_char(to_timestamp(b.timestamp) AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS day,
+		        COUNT(*) AS count,
+		        COALESCE(SUM(p.proof_size), 0)::BIGINT AS total_proof_size
+		 FROM stark_proofs p
+		 JOIN blocks b ON b.height = p.block_height
+		 WHERE b.timestamp >= $1 AND b.timestamp <= $2
+		 GROUP BY day
+		 ORDER BY day`,
+		fromTime, toTime,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily stark proof activity: %w", err)
+	}
+
+	return activity, nil
+}
+
+// GetStarkProofsByHash retrieves every submission of the proof witness with the given hash
+func GetStarkProofsByHash(proofHash string) ([]StarkProof, error) {
+	proofs, err := postgres.PostgresQueryCtx[StarkProof](
+		context.Background(), readDB,
+		`SELECT verifier_id, txid, block_height, proof_size, proof_hash, with_pedersen, verification_cost
+		 FROM stark_proofs
+		 WHERE proof_hash = $1
+		 ORDER BY block_height, txid`,
+		proofHash,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stark proofs by hash: %w", err)
+	}
+
+	return proofs, nil
+}
+
+// GetDuplicateProofs retrieves proof witnesses submitted in more than one transaction, most
+// submitted first, each with its submissions ordered by block height
+func GetDuplicateProofs(limit, offset int) ([]DuplicateProof, error) {
+	ctx := context.Background()
+
+	duplicates, err := postgres.PostgresQueryCtx[DuplicateProof](ctx, readDB,
+		`SELECT proof_hash,
+		        COUNT(*) AS submission_count,
+		        MAX(proof_size) AS proof_size,
+		        MIN(block_height) AS first_block_height,
+		        MAX(block_height) AS last_block_height
+		 FROM stark_proofs
+		 WHERE proof_hash IS NOT NULL
+		 GROUP BY proof_hash
+		 HAVING COUNT(DISTINCT txid) > 1
+		 ORDER BY submission_count DESC, first_block_height DESC, proof_hash
+		 LIMIT $1 OFFSET $2`,
+		limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get duplicate stark proofs: %w", err)
+	}
+	if len(duplicates) == 0 {
+		return duplicates, nil
+	}
+
+	hashes := make([]string, len(duplicates))
+	for i, d := range duplicates {
+		hashes[i] = d.ProofHash
+	}
+	proofs, err := postgres.PostgresQueryCtx[StarkProof](ctx, readDB,
+		`SELECT verifier_id, txid, block_height, proof_size, proof_hash, with_pedersen, verification_cost
+		 FROM stark_proofs
+		 WHERE proof_hash = ANY($1)
+		 ORDER BY block_height, txid`,
+		hashes,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get duplicate stark proof submissions: %w", err)
+	}
+
+	byHash := make(map[string]int, len(duplicates))
+	for i, d := range duplicates {
+		byHash[d.ProofHash] = i
+	}
+	for _, proof := range proofs {
+		i := byHash[*proof.ProofHash]
+		duplicates[i].Submissions = append(duplicates[i].Submissions, proof)
+	}
+
+	return duplicates, nil
+}
+
 // ============================================================================
 // ZtarknetFacts Query Functions
 // ============================================================================
 
 // GetZtarknetFacts retrieves Ztarknet facts by verifier ID and transaction ID
 func GetZtarknetFacts(verifierID, txid string) (*ZtarknetFacts, error) {
-	facts, err := postgres.PostgresQueryOne[ZtarknetFacts](
+	facts, err := postgres.PostgresQueryOneCtx[ZtarknetFacts](
+		context.Background(), readDB,
 		`SELECT verifier_id, txid, block_height, proof_size, old_state, new_state,
-		        program_hash, inner_program_hash
+		        program_hash, inner_program_hash, format_version, l2_block_number
 		 FROM ztarknet_facts
 		 WHERE verifier_id = $1 AND txid = $2`,
 		verifierID, txid,
@@ -282,14 +592,19 @@ func GetZtarknetFacts(verifierID, txid string) (*ZtarknetFacts, error) {
 		return nil, fmt.Errorf("failed to get ztarknet facts: %w", err)
 	}
 
+	if err := markZtarknetFactFinal(facts); err != nil {
+		return nil, err
+	}
+
 	return facts, nil
 }
 
 // GetZtarknetFactsByVerifier retrieves all Ztarknet facts for a verifier
 func GetZtarknetFactsByVerifier(verifierID string, limit, offset int) ([]ZtarknetFacts, error) {
-	facts, err := postgres.PostgresQuery[ZtarknetFacts](
+	facts, err := postgres.PostgresQueryCtx[ZtarknetFacts](
+		context.Background(), readDB,
 		`SELECT verifier_id, txid, block_height, proof_size, old_state, new_state,
-		        program_hash, inner_program_hash
+		        program_hash, inner_program_hash, format_version, l2_block_number
 		 FROM ztarknet_facts
 		 WHERE verifier_id = $1
 		 ORDER BY block_height DESC
@@ -300,14 +615,19 @@ func GetZtarknetFactsByVerifier(verifierID string, limit, offset int) ([]Ztarkne
 		return nil, fmt.Errorf("failed to get ztarknet facts by verifier: %w", err)
 	}
 
+	if err := markZtarknetFactsFinal(facts); err != nil {
+		return nil, err
+	}
+
 	return facts, nil
 }
 
 // GetZtarknetFactsByTransaction retrieves all Ztarknet facts for a transaction
 func GetZtarknetFactsByTransaction(txid string) ([]ZtarknetFacts, error) {
-	facts, err := postgres.PostgresQuery[ZtarknetFacts](
+	facts, err := postgres.PostgresQueryCtx[ZtarknetFacts](
+		context.Background(), readDB,
 		`SELECT verifier_id, txid, block_height, proof_size, old_state, new_state,
-		        program_hash, inner_program_hash
+		        program_hash, inner_program_hash, format_version, l2_block_number
 		 FROM ztarknet_facts
 		 WHERE txid = $1
 		 ORDER BY verifier_id`,
@@ -317,99 +637,187 @@ func GetZtarknetFactsByTransaction(txid string) ([]ZtarknetFacts, error) {
 		return nil, fmt.Errorf("failed to get ztarknet facts by transaction: %w", err)
 	}
 
+	if err := markZtarknetFactsFinal(facts); err != nil {
+		return nil, err
+	}
+
 	return facts, nil
 }
 
-// GetZtarknetFactsByBlock retrieves all Ztarknet facts for a block
-func GetZtarknetFactsByBlock(blockHeight int64) ([]ZtarknetFacts, error) {
-	facts, err := postgres.PostgresQuery[ZtarknetFacts](
+// GetZtarknetFactsByBlock retrieves all Ztarknet facts for a block, of one verifier unless verifierID is empty
+func GetZtarknetFactsByBlock(blockHeight int64, verifierID string) ([]ZtarknetFacts, error) {
+	facts, err := postgres.PostgresQueryCtx[ZtarknetFacts](
+		context.Background(), readDB,
 		`SELECT verifier_id, txid, block_height, proof_size, old_state, new_state,
-		        program_hash, inner_program_hash
+		        program_hash, inner_program_hash, format_version, l2_block_number
 		 FROM ztarknet_facts
-		 WHERE block_height = $1
+		 WHERE block_height = $1 AND ($2 = '' OR verifier_id = $2)
 		 ORDER BY txid`,
-		blockHeight,
+		blockHeight, verifierID,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get ztarknet facts by block: %w", err)
 	}
 
+	if err := markZtarknetFactsFinal(facts); err != nil {
+		return nil, err
+	}
+
 	return facts, nil
 }
 
-// GetZtarknetFactsByState retrieves Ztarknet facts by state hash
-func GetZtarknetFactsByState(stateHash string) ([]ZtarknetFacts, error) {
-	facts, err := postgres.PostgresQuery[ZtarknetFacts](
+// GetZtarknetFactsByL2Block retrieves the Ztarknet facts proving an L2 block number,
+// of one verifier unless verifierID is empty
+// Only facts parsed with FormatV2 or later carry an L2 block number
+func GetZtarknetFactsByL2Block(l2BlockNumber int64, verifierID string) ([]ZtarknetFacts, error) {
+	facts, err := postgres.PostgresQueryCtx[ZtarknetFacts](
+		context.Background(), readDB,
 		`SELECT verifier_id, txid, block_height, proof_size, old_state, new_state,
-		        program_hash, inner_program_hash
+		        program_hash, inner_program_hash, format_version, l2_block_number
+		 FROM ztarknet_facts
+		 WHERE l2_block_number = $1 AND ($2 = '' OR verifier_id = $2)
+		 ORDER BY block_height, verifier_id, txid`,
+		l2BlockNumber, verifierID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ztarknet facts by L2 block: %w", err)
+	}
+
+	if err := markZtarknetFactsFinal(facts); err != nil {
+		return nil, err
+	}
+
+	return facts, nil
+}
+
+// GetZtarknetFactsByState retrieves Ztarknet facts whose old or new state matches a state hash, with pagination
+func GetZtarknetFactsByState(stateHash string, limit, offset int) ([]ZtarknetFacts, error) {
+	facts, err := postgres.PostgresQueryCtx[ZtarknetFacts](
+		context.Background(), readDB,
+		`SELECT verifier_id, txid, block_height, proof_size, old_state, new_state,
+		        program_hash, inner_program_hash, format_version, l2_block_number
 		 FROM ztarknet_facts
 		 WHERE old_state = $1 OR new_state = $1
-		 ORDER BY block_height DESC`,
-		stateHash,
+		 ORDER BY block_height DESC, txid
+		 LIMIT $2 OFFSET $3`,
+		stateHash, limit, offset,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get ztarknet facts by state: %w", err)
 	}
 
+	if err := markZtarknetFactsFinal(facts); err != nil {
+		return nil, err
+	}
+
 	return facts, nil
 }
 
-// GetZtarknetFactsByProgramHash retrieves Ztarknet facts by program hash
-func GetZtarknetFactsByProgramHash(programHash string) ([]ZtarknetFacts, error) {
-	facts, err := postgres.PostgresQuery[ZtarknetFacts](
+// GetZtarknetFactsByStatePrefix retrieves Ztarknet facts whose old_state or new_state starts with a prefix
+// The prefix must be lowercase hex without a 0x prefix, matching how states are stored
+func GetZtarknetFactsByStatePrefix(prefix string, limit, offset int) ([]ZtarknetFacts, error) {
+	// Each branch can use its own text_pattern_ops index
+	facts, err := postgres.PostgresQueryCtx[ZtarknetFacts](
+		context.Background(), readDB,
 		`SELECT verifier_id, txid, block_height, proof_size, old_state, new_state,
-		        program_hash, inner_program_hash
+		        program_hash, inner_program_hash, format_version, l2_block_number
+		 FROM ztarknet_facts
+		 WHERE old_state LIKE $1
+		 UNION
+		 SELECT verifier_id, txid, block_height, proof_size, old_state, new_state,
+		        program_hash, inner_program_hash, format_version, l2_block_number
+		 FROM ztarknet_facts
+		 WHERE new_state LIKE $1
+		 ORDER BY block_height DESC, txid
+		 LIMIT $2 OFFSET $3`,
+		prefix+"%", limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ztarknet facts by state prefix: %w", err)
+	}
+
+	if err := markZtarknetFactsFinal(facts); err != nil {
+		return nil, err
+	}
+
+	return facts, nil
+}
+
+// GetZtarknetFactsByProgramHash retrieves Ztarknet facts by program hash with pagination
+func GetZtarknetFactsByProgramHash(programHash string, limit, offset int) ([]ZtarknetFacts, error) {
+	facts, err := postgres.PostgresQueryCtx[ZtarknetFacts](
+		context.Background(), readDB,
+		`SELECT verifier_id, txid, block_height, proof_size, old_state, new_state,
+		        program_hash, inner_program_hash, format_version, l2_block_number
 		 FROM ztarknet_facts
 		 WHERE program_hash = $1
-		 ORDER BY block_height DESC`,
-		programHash,
+		 ORDER BY block_height DESC, txid
+		 LIMIT $2 OFFSET $3`,
+		programHash, limit, offset,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get ztarknet facts by program hash: %w", err)
 	}
 
+	if err := markZtarknetFactsFinal(facts); err != nil {
+		return nil, err
+	}
+
 	return facts, nil
 }
 
-// GetZtarknetFactsByInnerProgramHash retrieves Ztarknet facts by inner program hash
-func GetZtarknetFactsByInnerProgramHash(innerProgramHash string) ([]ZtarknetFacts, error) {
-	facts, err := postgres.PostgresQuery[ZtarknetFacts](
+// GetZtarknetFactsByInnerProgramHash retrieves Ztarknet facts by inner program hash with pagination
+func GetZtarknetFactsByInnerProgramHash(innerProgramHash string, limit, offset int) ([]ZtarknetFacts, error) {
+	facts, err := postgres.PostgresQueryCtx[ZtarknetFacts](
+		context.Background(), readDB,
 		`SELECT verifier_id, txid, block_height, proof_size, old_state, new_state,
-		        program_hash, inner_program_hash
+		        program_hash, inner_program_hash, format_version, l2_block_number
 		 FROM ztarknet_facts
 		 WHERE inner_program_hash = $1
-		 ORDER BY block_height DESC`,
-		innerProgramHash,
+		 ORDER BY block_height DESC, txid
+		 LIMIT $2 OFFSET $3`,
+		innerProgramHash, limit, offset,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get ztarknet facts by inner program hash: %w", err)
 	}
 
+	if err := markZtarknetFactsFinal(facts); err != nil {
+		return nil, err
+	}
+
 	return facts, nil
 }
 
-// GetRecentZtarknetFacts retrieves the most recent Ztarknet facts
-func GetRecentZtarknetFacts(limit, offset int) ([]ZtarknetFacts, error) {
-	facts, err := postgres.PostgresQuery[ZtarknetFacts](
+// GetRecentZtarknetFacts retrieves the most recent Ztarknet facts, of one verifier unless verifierID is empty
+func GetRecentZtarknetFacts(verifierID string, limit, offset int) ([]ZtarknetFacts, error) {
+	facts, err := postgres.PostgresQueryCtx[ZtarknetFacts](
+		context.Background(), readDB,
 		`SELECT verifier_id, txid, block_height, proof_size, old_state, new_state,
-		        program_hash, inner_program_hash
+		        program_hash, inner_program_hash, format_version, l2_block_number
 		 FROM ztarknet_facts
+		 WHERE $1 = '' OR verifier_id = $1
 		 ORDER BY block_height DESC, txid
-		 LIMIT $1 OFFSET $2`,
-		limit, offset,
+		 LIMIT $2 OFFSET $3`,
+		verifierID, limit, offset,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get recent ztarknet facts: %w", err)
 	}
 
+	if err := markZtarknetFactsFinal(facts); err != nil {
+		return nil, err
+	}
+
 	return facts, nil
 }
 
 // GetStateTransition retrieves the state transition from old_state to new_state
 func GetStateTransition(oldState, newState string) ([]ZtarknetFacts, error) {
-	facts, err := postgres.PostgresQuery[ZtarknetFacts](
+	facts, err := postgres.PostgresQueryCtx[ZtarknetFacts](
+		context.Background(), readDB,
 		`SELECT verifier_id, txid, block_height, proof_size, old_state, new_state,
-		        program_hash, inner_program_hash
+		        program_hash, inner_program_hash, format_version, l2_block_number
 		 FROM ztarknet_facts
 		 WHERE old_state = $1 AND new_state = $2
 		 ORDER BY block_height DESC`,
@@ -419,9 +827,115 @@ func GetStateTransition(oldState, newState string) ([]ZtarknetFacts, error) {
 		return nil, fmt.Errorf("failed to get state transition: %w", err)
 	}
 
+	if err := markZtarknetFactsFinal(facts); err != nil {
+		return nil, err
+	}
+
+	return facts, nil
+}
+
+// GetZtarknetFactsByTimeRange retrieves Ztarknet facts whose block timestamp falls within a range,
+// of one verifier unless verifierID is empty
+func GetZtarknetFactsByTimeRange(fromTime, toTime int64, verifierID string, limit, offset int) ([]ZtarknetFacts, error) {
+	facts, err := postgres.PostgresQueryCtx[ZtarknetFacts](
+		context.Background(), readDB,
+		`SELECT f.verifier_id, f.txid, f.block_height, f.proof_size, f.old_state, f.new_state,
+		        f.program_hash, f.inner_program_hash, f.format_version, f.l2_block_number
+		 FROM ztarknet_facts f
+		 JOIN blocks b ON b.height = f.block_height
+		 WHERE b.timestamp >= $1 AND b.timestamp <= $2 AND ($3 = '' OR f.verifier_id = $3)
+		 ORDER BY f.block_height DESC, f.txid
+		 LIMIT $4 OFFSET $5`,
+		fromTime, toTime, verifierID, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ztarknet facts by time range: %w", err)
+	}
+
+	if err := markZtarknetFactsFinal(facts); err != nil {
+		return nil, err
+	}
+
 	return facts, nil
 }
 
+// GetDailyZtarknetFactActivity aggregates Ztarknet facts into UTC day buckets within a time range
+func GetDailyZtarknetFactActivity(fromTime, toTime int64) ([]DailyStarkActivity, error) {
+	activity, err := postgres.PostgresQueryCtx[DailyStarkActivity](
+		context.Background(), readDB,
+		`SELECT to_char(to_timestamp(b.timestamp) AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS day,
+		        COUNT(*) AS count,
+		        COALESCE(SUM(f.proof_size), 0)::BIGINT AS total_proof_size
+		 FROM ztarknet_facts f
+		 JOIN blocks b ON b.height = f.block_height
+		 WHERE b.timestamp >= $1 AND b.timestamp <= $2
+		 GROUP BY day
+		 ORDER BY day`,
+		fromTime, toTime,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily ztarknet fact activity: %w", err)
+	}
+
+	return activity, nil
+}
+
+// GetTopPrograms aggregates Ztarknet facts by program hash, most active first
+// If inner is true, facts are grouped by inner_program_hash instead of program_hash
+func GetTopPrograms(inner bool, limit, offset int) ([]TopProgram, error) {
+	column := "program_hash"
+	if inner {
+		column = "inner_program_hash"
+	}
+
+	programs, err := postgres.PostgresQueryCtx[TopProgram](
+		context.Background(), readDB,
+		fmt.Sprintf(
+			`SELECT %[1]s AS program_hash,
+			        COUNT(*) AS fact_count,
+			        MIN(block_height) AS first_seen_height,
+			        MAX(block_height) AS last_seen_height,
+			        array_agg(DISTINCT verifier_id ORDER BY verifier_id) AS verifiers
+			 FROM ztarknet_facts
+			 WHERE %[1]s <> ''
+			 GROUP BY %[1]s
+			 ORDER BY fact_count DESC, last_seen_height DESC, %[1]s
+			 LIMIT $1 OFFSET $2`,
+			column,
+		),
+		limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top programs: %w", err)
+	}
+
+	return programs, nil
+}
+
+// markZtarknetFactsFinal sets the Final flag on facts proven at or below the finalized height
+func markZtarknetFactsFinal(facts []ZtarknetFacts) error {
+	finalizedHeight, err := postgres.GetFinalizedHeight()
+	if err != nil {
+		return err
+	}
+
+	for i := range facts {
+		facts[i].Final = facts[i].BlockHeight <= finalizedHeight
+	}
+	return nil
+}
+
+// markZtarknetFactFinal sets the Final flag on a single fact
+func markZtarknetFactFinal(fact *ZtarknetFacts) error {
+	finalizedHeight, err := postgres.GetFinalizedHeight()
+	if err != nil {
+		return err
+	}
+
+	fact.Final = fact.BlockHeight <= finalizedHeight
+	return nil
+}
+
 // ============================================================================
 // STORAGE FUNCTIONS
 // ============================================================================
@@ -434,25 +948,36 @@ type DBTX interface {
 	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
 }
 
+// readDB serves STARK/verifier lookups; nil falls back to postgres.DB
+var readDB postgres.Querier
+
+// SetReadDB routes STARK/verifier lookups through q, e.g. a read replica
+// pool or a mock in tests. Passing nil restores the global postgres.DB
+func SetReadDB(q postgres.Querier) {
+	readDB = q
+}
+
 // StoreVerifier inserts or updates a verifier in the database
+// Curated labels (see UpdateVerifierLabels) are preserved when an existing verifier is stored again
 // If postgresTx is provided, it will be used; otherwise a standalone query is executed
-func StoreVerifier(postgresTx DBTX, verifierID, verifierName, verifierMetadata string, balance int64) error {
+func StoreVerifier(postgresTx DBTX, verifierID, verifierName, verifierMetadata string, balance int64, initialState string) error {
 	ctx := context.Background()
 
 	query := `
-		INSERT INTO verifiers (verifier_id, verifier_name, verifier_metadata, balance)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO verifiers (verifier_id, verifier_name, verifier_metadata, balance, initial_state)
+		VALUES ($1, $2, $3, $4, $5)
 		ON CONFLICT (verifier_id) DO UPDATE SET
-			verifier_name = EXCLUDED.verifier_name,
-			verifier_metadata = EXCLUDED.verifier_metadata,
-			balance = EXCLUDED.balance
+			verifier_name = CASE WHEN verifiers.label_curated THEN verifiers.verifier_name ELSE EXCLUDED.verifier_name END,
+			verifier_metadata = CASE WHEN verifiers.label_curated THEN verifiers.verifier_metadata ELSE EXCLUDED.verifier_metadata END,
+			balance = EXCLUDED.balance,
+			initial_state = EXCLUDED.initial_state
 	`
 
 	if postgresTx == nil {
 		postgresTx = postgres.DB
 	}
 
-	_, err := postgresTx.Exec(ctx, query, verifierID, verifierName, verifierMetadata, balance)
+	_, err := postgresTx.Exec(ctx, query, verifierID, verifierName, verifierMetadata, balance, initialState)
 	if err != nil {
 		return fmt.Errorf("failed to store verifier %s: %w", verifierID, err)
 	}
@@ -483,24 +1008,68 @@ func UpdateVerifierBalance(postgresTx DBTX, verifierID string, balance int64) er
 	return nil
 }
 
+// UpdateVerifierLabels sets curated names and metadata on known verifiers in a single transaction
+// Nil fields are left unchanged. Returns the number of verifiers updated and the IDs that do not exist
+func UpdateVerifierLabels(labels []VerifierLabel) (int64, []string, error) {
+	ctx := context.Background()
+
+	tx, err := postgres.DB.Begin(ctx)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE verifiers
+		SET verifier_name = COALESCE($2, verifier_name),
+		    verifier_metadata = COALESCE($3, verifier_metadata),
+		    label_curated = TRUE
+		WHERE verifier_id = $1
+	`
+
+	var updated int64
+	unknown := []string{}
+	for _, label := range labels {
+		result, err := tx.Exec(ctx, query, label.VerifierID, label.VerifierName, label.VerifierMetadata)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to update verifier %s labels: %w", label.VerifierID, err)
+		}
+		if result.RowsAffected() == 0 {
+			unknown = append(unknown, label.VerifierID)
+			continue
+		}
+		updated++
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, nil, fmt.Errorf("failed to commit verifier labels: %w", err)
+	}
+
+	return updated, unknown, nil
+}
+
 // StoreStarkProof inserts or updates a STARK proof in the database
 // If postgresTx is provided, it will be used; otherwise a standalone query is executed
-func StoreStarkProof(postgresTx DBTX, verifierID, txid string, blockHeight, proofSize int64) error {
+func StoreStarkProof(postgresTx DBTX, verifierID, txid string, blockHeight, proofSize int64, proofHash *string,
+	withPedersen *bool, verificationCost *int64) error {
 	ctx := context.Background()
 
 	query := `
-		INSERT INTO stark_proofs (verifier_id, txid, block_height, proof_size)
-		VALUES ($1, $2, $3, $4)
-		ON CONFLICT (verifier_id, txid) DO UPDATE SET
+		INSERT INTO stark_proofs (verifier_id, txid, block_height, proof_size, proof_hash, with_pedersen, verification_cost)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (verifier_id, txid) ` + postgres.OnConflictImmutable(`
 			block_height = EXCLUDED.block_height,
-			proof_size = EXCLUDED.proof_size
-	`
+			proof_size = EXCLUDED.proof_size,
+			proof_hash = COALESCE(EXCLUDED.proof_hash, stark_proofs.proof_hash),
+			with_pedersen = COALESCE(EXCLUDED.with_pedersen, stark_proofs.with_pedersen),
+			verification_cost = COALESCE(EXCLUDED.verification_cost, stark_proofs.verification_cost)
+	`)
 
 	if postgresTx == nil {
 		postgresTx = postgres.DB
 	}
 
-	_, err := postgresTx.Exec(ctx, query, verifierID, txid, blockHeight, proofSize)
+	_, err := postgresTx.Exec(ctx, query, verifierID, txid, blockHeight, proofSize, proofHash, withPedersen, verificationCost)
 	if err != nil {
 		return fmt.Errorf("failed to store STARK proof for verifier %s, tx %s: %w", verifierID, txid, err)
 	}
@@ -511,20 +1080,22 @@ func StoreStarkProof(postgresTx DBTX, verifierID, txid string, blockHeight, proo
 // StoreZtarknetFacts inserts or updates Ztarknet facts in the database
 // If postgresTx is provided, it will be used; otherwise a standalone query is executed
 func StoreZtarknetFacts(postgresTx DBTX, verifierID, txid string, blockHeight, proofSize int64,
-	oldState, newState, programHash, innerProgramHash string) error {
+	oldState, newState, programHash, innerProgramHash string, formatVersion int, l2BlockNumber *int64) error {
 	ctx := context.Background()
 
 	query := `
 		INSERT INTO ztarknet_facts (verifier_id, txid, block_height, proof_size,
-		                            old_state, new_state, program_hash, inner_program_hash)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		                            old_state, new_state, program_hash, inner_program_hash, format_version, l2_block_number)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		ON CONFLICT (verifier_id, txid) DO UPDATE SET
 			block_height = EXCLUDED.block_height,
 			proof_size = EXCLUDED.proof_size,
 			old_state = EXCLUDED.old_state,
 			new_state = EXCLUDED.new_state,
 			program_hash = EXCLUDED.program_hash,
-			inner_program_hash = EXCLUDED.inner_program_hash
+			inner_program_hash = EXCLUDED.inner_program_hash,
+			format_version = EXCLUDED.format_version,
+			l2_block_number = COALESCE(EXCLUDED.l2_block_number, ztarknet_facts.l2_block_number)
 	`
 
 	if postgresTx == nil {
@@ -532,7 +1103,7 @@ func StoreZtarknetFacts(postgresTx DBTX, verifierID, txid string, blockHeight, p
 	}
 
 	_, err := postgresTx.Exec(ctx, query, verifierID, txid, blockHeight, proofSize,
-		oldState, newState, programHash, innerProgramHash)
+		oldState, newState, programHash, innerProgramHash, formatVersion, l2BlockNumber)
 	if err != nil {
 		return fmt.Errorf("failed to store Ztarknet facts for verifier %s, tx %s: %w", verifierID, txid, err)
 	}
@@ -546,12 +1117,7 @@ func StoreZtarknetFacts(postgresTx DBTX, verifierID, txid string, blockHeight, p
 
 // CountVerifiers returns the total count of verifiers with optional filters
 func CountVerifiers() (int64, error) {
-	var count int64
-	err := postgres.DB.QueryRow(context.Background(), `SELECT COUNT(*) FROM verifiers`).Scan(&count)
-	if err != nil {
-		return 0, fmt.Errorf("failed to count verifiers: %w", err)
-	}
-	return count, nil
+	return postgres.CountRows(context.Background(), readDB, "verifiers")
 }
 
 // CountStarkProofs returns the total count of stark proofs with optional filters
@@ -569,12 +1135,12 @@ func CountStarkProofs(verifierID string, blockHeight int64) (int64, error) {
 		query = `SELECT COUNT(*) FROM stark_proofs WHERE block_height = $1`
 		args = []interface{}{blockHeight}
 	} else {
-		query = `SELECT COUNT(*) FROM stark_proofs`
-		args = []interface{}{}
+		// Unfiltered counts come from the maintained row counter
+		return postgres.CountRows(context.Background(), readDB, "stark_proofs")
 	}
 
 	var count int64
-	err := postgres.DB.QueryRow(context.Background(), query, args...).Scan(&count)
+	err := postgres.ReadQuerier(readDB).QueryRow(context.Background(), query, args...).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count stark proofs: %w", err)
 	}
@@ -597,12 +1163,12 @@ func CountZtarknetFacts(verifierID string, blockHeight int64) (int64, error) {
 		query = `SELECT COUNT(*) FROM ztarknet_facts WHERE block_height = $1`
 		args = []interface{}{blockHeight}
 	} else {
-		query = `SELECT COUNT(*) FROM ztarknet_facts`
-		args = []interface{}{}
+		// Unfiltered counts come from the maintained row counter
+		return postgres.CountRows(context.Background(), readDB, "ztarknet_facts")
 	}
 
 	var count int64
-	err := postgres.DB.QueryRow(context.Background(), query, args...).Scan(&count)
+	err := postgres.ReadQuerier(readDB).QueryRow(context.Background(), query, args...).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count ztarknet facts: %w", err)
 	}
@@ -610,10 +1176,41 @@ func CountZtarknetFacts(verifierID string, blockHeight int64) (int64, error) {
 	return count, nil
 }
 
+// CountZtarknetFactsByState returns the number of facts whose old or new state matches a state hash
+func CountZtarknetFactsByState(stateHash string) (int64, error) {
+	var count int64
+	err := postgres.ReadQuerier(readDB).QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM ztarknet_facts WHERE old_state = $1 OR new_state = $1`,
+		stateHash,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count ztarknet facts by state: %w", err)
+	}
+
+	return count, nil
+}
+
+// CountZtarknetFactsByProgramHash returns the number of facts for a program hash,
+// matched against inner_program_hash when inner is set
+func CountZtarknetFactsByProgramHash(programHash string, inner bool) (int64, error) {
+	query := `SELECT COUNT(*) FROM ztarknet_facts WHERE program_hash = $1`
+	if inner {
+		query = `SELECT COUNT(*) FROM ztarknet_facts WHERE inner_program_hash = $1`
+	}
+
+	var count int64
+	err := postgres.ReadQuerier(readDB).QueryRow(context.Background(), query, programHash).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count ztarknet facts by program hash: %w", err)
+	}
+
+	return count, nil
+}
+
 // SumStarkProofSizesByVerifier returns the sum of all proof sizes for a given verifier
 func SumStarkProofSizesByVerifier(verifierID string) (int64, error) {
 	var sum int64
-	err := postgres.DB.QueryRow(context.Background(),
+	err := postgres.ReadQuerier(readDB).QueryRow(context.Background(),
 		`SELECT COALESCE(SUM(proof_size), 0) FROM stark_proofs WHERE verifier_id = $1`,
 		verifierID,
 	).Scan(&sum)
@@ -1,6 +1,9 @@
 package starks
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // Verifier represents a STARK proof verifier
 type Verifier struct {
@@ -11,12 +14,34 @@ type Verifier struct {
 	FirstSeenAt      time.Time `json:"first_seen_at" db:"first_seen_at"`
 }
 
+// VerifierLabel is a curated name and/or metadata for a verifier (nil fields are left unchanged)
+type VerifierLabel struct {
+	VerifierID       string  `json:"verifier_id"`
+	VerifierName     *string `json:"verifier_name,omitempty"`
+	VerifierMetadata *string `json:"verifier_metadata,omitempty"`
+}
+
 // StarkProof represents a STARK proof associated with a transaction
 type StarkProof struct {
-	VerifierID  string `json:"verifier_id" db:"verifier_id"`
-	TxID        string `json:"txid" db:"txid"`
-	BlockHeight int64  `json:"block_height" db:"block_height"`
-	ProofSize   int64  `json:"proof_size" db:"proof_size"`
+	VerifierID  string  `json:"verifier_id" db:"verifier_id"`
+	TxID        string  `json:"txid" db:"txid"`
+	BlockHeight int64   `json:"block_height" db:"block_height"`
+	ProofSize   int64   `json:"proof_size" db:"proof_size"`
+	ProofHash   *string `json:"proof_hash" db:"proof_hash"` // SHA-256 of the witness; null for imported proofs
+	// Whether the witness sets with_pedersen, and the cost model's estimate; null for imported proofs
+	// and, for the cost, proofs indexed without a cost model
+	WithPedersen     *bool  `json:"with_pedersen" db:"with_pedersen"`
+	VerificationCost *int64 `json:"verification_cost" db:"verification_cost"`
+}
+
+// DuplicateProof groups the submissions of one identical proof witness
+type DuplicateProof struct {
+	ProofHash        string       `json:"proof_hash" db:"proof_hash"`
+	SubmissionCount  int64        `json:"submission_count" db:"submission_count"`
+	ProofSize        int64        `json:"proof_size" db:"proof_size"`
+	FirstBlockHeight int64        `json:"first_block_height" db:"first_block_height"`
+	LastBlockHeight  int64        `json:"last_block_height" db:"last_block_height"`
+	Submissions      []StarkProof `json:"submissions" db:"-"`
 }
 
 // ZtarknetFacts represents Ztarknet-specific facts from STARK proofs
@@ -29,4 +54,202 @@ type ZtarknetFacts struct {
 	NewState         string `json:"new_state" db:"new_state"`
 	ProgramHash      string `json:"program_hash" db:"program_hash"`
 	InnerProgramHash string `json:"inner_program_hash" db:"inner_program_hash"`
+	FormatVersion    int    `json:"format_version" db:"format_version"`   // stark_verify format the fact was parsed with
+	L2BlockNumber    *int64 `json:"l2_block_number" db:"l2_block_number"` // nil for facts parsed before FormatV2
+	Final            bool   `json:"final" db:"-"`
+}
+
+// ZtarknetFactWithBlock is a fact with the metadata of its L1 block, returned with expand=block
+type ZtarknetFactWithBlock struct {
+	ZtarknetFacts
+	BlockHash      string `json:"block_hash"`
+	BlockTimestamp int64  `json:"block_timestamp"`
+	Confirmations  int64  `json:"confirmations"` // L1 blocks from the fact to the indexed tip, inclusive
+}
+
+// StateChain is the state progression of a verifier's facts, oldest first
+type StateChain struct {
+	VerifierID   string           `json:"verifier_id"`
+	InitialState string           `json:"initial_state"` // empty for verifiers indexed before it was stored
+	CurrentState string           `json:"current_state"`
+	Valid        bool             `json:"valid"` // no gaps and no forks
+	GapCount     int              `json:"gap_count"`
+	ForkCount    int              `json:"fork_count"`
+	Links        []StateChainLink `json:"links"`
+}
+
+// StateChainLink is a fact in a state chain with its validity flags
+type StateChainLink struct {
+	ZtarknetFacts
+	Position      int    `json:"position"`
+	ExpectedState string `json:"expected_state"` // new_state of the previous link, or the initial state
+	Gap           bool   `json:"gap"`            // old_state differs from expected_state or is unknown
+	Fork          bool   `json:"fork"`           // another fact of the verifier starts from the same old_state
+}
+
+// StateGraph links state roots (nodes) through the facts moving between them (edges)
+type StateGraph struct {
+	Nodes      []StateGraphNode `json:"nodes"`
+	Edges      []StateGraphEdge `json:"edges"`
+	ForkCount  int              `json:"fork_count"`  // states with more than one outgoing fact
+	CycleCount int              `json:"cycle_count"` // groups of states that lead back to themselves
+	Truncated  bool             `json:"truncated"`   // more facts matched than MaxStateGraphEdges
+}
+
+// StateGraphNode is a state root in a state graph
+type StateGraphNode struct {
+	State     string `json:"state"`
+	InDegree  int    `json:"in_degree"`
+	OutDegree int    `json:"out_degree"`
+	Fork      bool   `json:"fork"`
+	InCycle   bool   `json:"in_cycle"`
+	Unknown   bool   `json:"unknown"` // old state of facts whose spent output had no known state
+}
+
+// StateGraphEdge is a fact in a state graph
+type StateGraphEdge struct {
+	VerifierID  string `json:"verifier_id"`
+	TxID        string `json:"txid"`
+	BlockHeight int64  `json:"block_height"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Fork        bool   `json:"fork"`     // leaves a forked state
+	InCycle     bool   `json:"in_cycle"` // both ends are on the same cycle
+}
+
+// ZtarknetState is the latest state root proven for a rollup, taken from its verifier's newest fact
+type ZtarknetState struct {
+	VerifierID       string `json:"verifier_id" db:"verifier_id"`
+	CanonicalSource  string `json:"canonical_source" db:"-"` // admin or config
+	StateRoot        string `json:"state_root" db:"state_root"`
+	BlockHeight      int64  `json:"block_height" db:"block_height"`
+	TxID             string `json:"txid" db:"txid"`
+	ProgramHash      string `json:"program_hash" db:"program_hash"`
+	InnerProgramHash string `json:"inner_program_hash" db:"inner_program_hash"`
+	L2BlockNumber    *int64 `json:"l2_block_number" db:"l2_block_number"`
+	FactCount        int64  `json:"fact_count" db:"fact_count"`
+	Final            bool   `json:"final" db:"-"`
+}
+
+// ProofActivity summarizes the STARK proofs of all verifiers over a recent window
+type ProofActivity struct {
+	ProofCount    int64   `json:"proof_count" db:"proof_count"`
+	VerifierCount int64   `json:"verifier_count" db:"verifier_count"` // verifiers with at least one proof
+	AvgProofSize  float64 `json:"avg_proof_size" db:"avg_proof_size"`
+}
+
+// L1L2Mapping relates an L1 height to an L2 block number through the fact that settled the L2 block
+type L1L2Mapping struct {
+	VerifierID    string `json:"verifier_id" db:"verifier_id"`
+	L1Height      int64  `json:"l1_height" db:"l1_height"`             // height of the block holding the fact
+	L2BlockNumber int64  `json:"l2_block_number" db:"l2_block_number"` // L2 block proven by the fact
+	TxID          string `json:"txid" db:"txid"`
+	Confirmations int64  `json:"confirmations" db:"-"` // L1 blocks from the fact to the indexed tip, inclusive
+	Final         bool   `json:"final" db:"-"`
+}
+
+// FactSegment is a data availability blob or L2 message carried in the witness of a fact
+type FactSegment struct {
+	VerifierID  string  `json:"verifier_id" db:"verifier_id"`
+	TxID        string  `json:"txid" db:"txid"`
+	Seq         int     `json:"seq" db:"seq"` // position in the witness
+	Kind        string  `json:"kind" db:"kind"`
+	BlockHeight int64   `json:"block_height" db:"block_height"`
+	Size        int     `json:"size" db:"size"`
+	DataHash    string  `json:"data_hash" db:"data_hash"` // hex SHA-256 of the data
+	Data        *string `json:"data,omitempty" db:"data"` // hex, only when requested
+}
+
+// FactAnomaly flags a Ztarknet fact whose program hash differs from the expected one
+type FactAnomaly struct {
+	VerifierID  string    `json:"verifier_id" db:"verifier_id"`
+	TxID        string    `json:"txid" db:"txid"`
+	Kind        string    `json:"kind" db:"kind"`
+	BlockHeight int64     `json:"block_height" db:"block_height"`
+	Expected    string    `json:"expected" db:"expected"`
+	Actual      string    `json:"actual" db:"actual"`
+	DetectedAt  time.Time `json:"detected_at" db:"detected_at"`
+}
+
+// TopProgram aggregates Ztarknet facts proven for a single program hash
+type TopProgram struct {
+	ProgramHash     string   `json:"program_hash" db:"program_hash"` // program_hash or inner_program_hash depending on grouping
+	FactCount       int64    `json:"fact_count" db:"fact_count"`
+	FirstSeenHeight int64    `json:"first_seen_height" db:"first_seen_height"`
+	LastSeenHeight  int64    `json:"last_seen_height" db:"last_seen_height"`
+	Verifiers       []string `json:"verifiers" db:"verifiers"`
+}
+
+// DailyStarkActivity represents STARK proofs or facts aggregated into a UTC day bucket
+type DailyStarkActivity struct {
+	Day            string `json:"day" db:"day"` // YYYY-MM-DD (UTC, from block timestamp)
+	Count          int64  `json:"count" db:"count"`
+	TotalProofSize int64  `json:"total_proof_size" db:"total_proof_size"`
+}
+
+// ExportedFact is a Ztarknet fact as it appears in a fact export bundle
+type ExportedFact struct {
+	BlockHeight      int64  `json:"block_height" db:"block_height"`
+	BlockHash        string `json:"block_hash" db:"block_hash"`
+	TxID             string `json:"txid" db:"txid"`
+	ProofSize        int64  `json:"proof_size" db:"proof_size"`
+	OldState         string `json:"old_state" db:"old_state"`
+	NewState         string `json:"new_state" db:"new_state"`
+	ProgramHash      string `json:"program_hash" db:"program_hash"`
+	InnerProgramHash string `json:"inner_program_hash" db:"inner_program_hash"`
+	Final            bool   `json:"final" db:"-"`
+}
+
+// FactExportBundle is the complete fact history of a verifier at a given indexed height
+type FactExportBundle struct {
+	Format          string         `json:"format"`
+	VerifierID      string         `json:"verifier_id"`
+	IndexedHeight   int64          `json:"indexed_height"`
+	IndexedHash     string         `json:"indexed_hash"`
+	FinalizedHeight int64          `json:"finalized_height"`
+	FactCount       int            `json:"fact_count"`
+	Facts           []ExportedFact `json:"facts"` // oldest first
+}
+
+// FactExportSignature is an Ed25519 signature over the exact bundle bytes
+type FactExportSignature struct {
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"public_key"`
+	Signature string `json:"signature"`
+}
+
+// SignedFactExport pairs the canonical bundle bytes with their SHA-256 digest and optional signature
+type SignedFactExport struct {
+	Bundle    json.RawMessage      `json:"bundle"`
+	Sha256    string               `json:"sha256"`
+	Signature *FactExportSignature `json:"signature"` // null when no signing key is configured
+}
+
+// ImportedFact is a Ztarknet fact exported by an external fact-tracking service
+type ImportedFact struct {
+	VerifierID       string `json:"verifier_id"`
+	TxID             string `json:"txid"`
+	ProofSize        int64  `json:"proof_size"` // 0 takes the size of the indexed proof
+	OldState         string `json:"old_state"`
+	NewState         string `json:"new_state"`
+	ProgramHash      string `json:"program_hash"`
+	InnerProgramHash string `json:"inner_program_hash"`
+}
+
+// FactImportRejection explains why an imported fact was not stored
+type FactImportRejection struct {
+	Index      int    `json:"index"` // 0-based position in the import
+	VerifierID string `json:"verifier_id"`
+	TxID       string `json:"txid"`
+	Reason     string `json:"reason"`
+}
+
+// FactImportResult summarizes a fact import
+type FactImportResult struct {
+	DryRun        bool                  `json:"dry_run"`
+	Imported      int                   `json:"imported"`       // facts stored that were not indexed
+	Updated       int                   `json:"updated"`        // indexed facts replaced (overwrite only)
+	Unchanged     int                   `json:"unchanged"`      // facts already indexed with the same values
+	ProofsCreated int                   `json:"proofs_created"` // STARK proofs stored alongside imported facts
+	Rejected      []FactImportRejection `json:"rejected"`
 }
@@ -0,0 +1,180 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+)
+
+// GetChainDiff builds a digest of what changed in blocks fromHeight+1 through toHeight:
+// new verifiers, Ztarknet state transitions, newly proven programs, and addresses whose net
+// balance change is at least minBalanceChange in absolute value
+// Lists are capped at limit entries, largest or oldest first; the summary carries the full counts
+// Returns nil when either height is not indexed
+func GetChainDiff(fromHeight, toHeight, minBalanceChange int64, limit int) (*ChainDiff, error) {
+	ctx := context.Background()
+
+	// Read every section from one snapshot so the digest is self-consistent
+	tx, err := postgres.DB.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var fromHash, toHash *string
+	err = tx.QueryRow(ctx, `
+		SELECT (SELECT hash FROM blocks WHERE height = $1), (SELECT hash FROM blocks WHERE height = $2)
+	`, fromHeight, toHeight).Scan(&fromHash, &toHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block hashes: %w", err)
+	}
+	if fromHash == nil || toHash == nil {
+		return nil, nil
+	}
+
+	diff := &ChainDiff{FromHeight: fromHeight, FromHash: *fromHash, ToHeight: toHeight, ToHash: *toHash}
+
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(tx_count), 0)::BIGINT, COALESCE(SUM(tze_tx_count), 0)::BIGINT
+		FROM blocks
+		WHERE height > $1 AND height <= $2
+	`, fromHeight, toHeight).Scan(&diff.Summary.Blocks, &diff.Summary.Transactions, &diff.Summary.TzeTransactions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize blocks: %w", err)
+	}
+
+	if config.IsModuleEnabled("STARKS") {
+		if err := diffVerifiers(ctx, tx, diff, limit); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.Conf.Modules.Starks.Enabled && config.Conf.Modules.Starks.IndexZtarknet {
+		if err := diffFacts(ctx, tx, diff, limit); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.IsModuleEnabled("ACCOUNTS") {
+		if err := diffBalances(ctx, tx, diff, minBalanceChange, limit); err != nil {
+			return nil, err
+		}
+	}
+
+	return diff, nil
+}
+
+// diffVerifiers lists verifiers created in the range
+// A verifier is created by the output its id points at (txid:vout); its height comes from the
+// transaction graph when enabled, otherwise from the verifier's first proof
+func diffVerifiers(ctx context.Context, tx pgx.Tx, diff *ChainDiff, limit int) error {
+	createdAt := `SELECT MIN(sp.block_height) FROM stark_proofs sp WHERE sp.verifier_id = v.verifier_id`
+	if config.IsModuleEnabled("TX_GRAPH") {
+		createdAt = `SELECT t.block_height FROM transactions t WHERE t.txid = split_part(v.verifier_id, ':', 1)`
+	}
+
+	newVerifiers := fmt.Sprintf(`
+		SELECT v.verifier_id, v.verifier_name, c.block_height
+		FROM verifiers v
+		CROSS JOIN LATERAL (%s) AS c(block_height)
+		WHERE c.block_height > $1 AND c.block_height <= $2`, createdAt)
+
+	var count int64
+	err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM (`+newVerifiers+`) v`, diff.FromHeight, diff.ToHeight).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to count new verifiers: %w", err)
+	}
+	diff.Summary.NewVerifiers = &count
+
+	diff.NewVerifiers, err = postgres.PostgresQueryCtx[DiffVerifier](ctx, tx,
+		newVerifiers+` ORDER BY c.block_height, v.verifier_id LIMIT $3`,
+		diff.FromHeight, diff.ToHeight, limit,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to get new verifiers: %w", err)
+	}
+	return nil
+}
+
+// diffFacts lists the state transitions proven in the range and the programs first proven in it
+func diffFacts(ctx context.Context, tx pgx.Tx, diff *ChainDiff, limit int) error {
+	var transitions, programs int64
+	err := tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM ztarknet_facts WHERE block_height > $1 AND block_height <= $2
+	`, diff.FromHeight, diff.ToHeight).Scan(&transitions)
+	if err != nil {
+		return fmt.Errorf("failed to count state transitions: %w", err)
+	}
+	diff.Summary.StateTransitions = &transitions
+
+	diff.StateTransitions, err = postgres.PostgresQueryCtx[DiffTransition](ctx, tx,
+		`SELECT verifier_id, txid, block_height, old_state, new_state, program_hash
+		 FROM ztarknet_facts
+		 WHERE block_height > $1 AND block_height <= $2
+		 ORDER BY block_height, txid
+		 LIMIT $3`,
+		diff.FromHeight, diff.ToHeight, limit,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to get state transitions: %w", err)
+	}
+
+	newPrograms := `
+		SELECT program_hash, MIN(block_height) AS first_seen_height,
+		       COUNT(*) FILTER (WHERE block_height > $1) AS fact_count
+		FROM ztarknet_facts
+		WHERE block_height <= $2
+		GROUP BY program_hash
+		HAVING MIN(block_height) > $1`
+
+	err = tx.QueryRow(ctx, `SELECT COUNT(*) FROM (`+newPrograms+`) p`, diff.FromHeight, diff.ToHeight).Scan(&programs)
+	if err != nil {
+		return fmt.Errorf("failed to count new programs: %w", err)
+	}
+	diff.Summary.NewPrograms = &programs
+
+	diff.NewPrograms, err = postgres.PostgresQueryCtx[DiffProgram](ctx, tx,
+		newPrograms+` ORDER BY first_seen_height, program_hash LIMIT $3`,
+		diff.FromHeight, diff.ToHeight, limit,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to get new programs: %w", err)
+	}
+
+	return nil
+}
+
+// diffBalances lists addresses whose net balance change in the range meets the threshold, largest first
+func diffBalances(ctx context.Context, tx pgx.Tx, diff *ChainDiff, minBalanceChange int64, limit int) error {
+	movements := `
+		SELECT at.address, SUM(at.balance_change)::BIGINT AS net_change, COUNT(*) AS tx_count
+		FROM account_transactions at
+		WHERE at.block_height > $1 AND at.block_height <= $2
+		GROUP BY at.address
+		HAVING ABS(SUM(at.balance_change)) >= $3`
+
+	var count int64
+	err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM (`+movements+`) m`,
+		diff.FromHeight, diff.ToHeight, minBalanceChange).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to count balance movements: %w", err)
+	}
+	diff.Summary.BalanceMovements = &count
+
+	diff.BalanceMovements, err = postgres.PostgresQueryCtx[BalanceMovement](ctx, tx,
+		`SELECT m.address, m.net_change, m.tx_count, a.balance AS balance_now
+		 FROM (`+movements+`) m
+		 JOIN accounts a ON a.address = m.address
+		 ORDER BY ABS(m.net_change) DESC, m.address
+		 LIMIT $4`,
+		diff.FromHeight, diff.ToHeight, minBalanceChange, limit,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to get balance movements: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/chainparams"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+)
+
+// GetEmissionSchedule computes the subsidy at height and when it next halves
+// timestamp is the time of the block at height, from which the halving time is estimated
+func GetEmissionSchedule(params *chainparams.Params, height, timestamp int64) *EmissionSchedule {
+	schedule := &EmissionSchedule{
+		Params:   params,
+		Height:   height,
+		Halvings: params.Halving(height),
+		Subsidy:  params.BlockSubsidy(height),
+	}
+
+	next, ok := params.NextHalving(height)
+	if !ok {
+		return schedule
+	}
+	blocks := next - height
+	subsidy := params.BlockSubsidy(next)
+	estimate := timestamp + spacingUntil(params, height, next)
+	schedule.NextHalvingHeight = &next
+	schedule.BlocksUntilHalving = &blocks
+	schedule.NextSubsidy = &subsidy
+	schedule.EstimatedHalvingTime = &estimate
+	return schedule
+}
+
+// spacingUntil is the target time in seconds for the chain to grow from height to target,
+// switching to the post-Blossom spacing at the Blossom activation height
+func spacingUntil(params *chainparams.Params, height, target int64) int64 {
+	preBlossomBlocks := target - height
+	if params.BlossomHeight != nil {
+		preBlossomBlocks = min(max(*params.BlossomHeight-height, 0), target-height)
+	}
+	postBlossomBlocks := target - height - preBlossomBlocks
+	return preBlossomBlocks*chainparams.PreBlossomTargetSpacing + postBlossomBlocks*chainparams.PostBlossomTargetSpacing
+}
+
+// CheckEmission cross-checks the indexed coinbase values of a height range against the subsidy
+func CheckEmission(params *chainparams.Params, fromHeight, toHeight int64) (*EmissionCheck, error) {
+	discrepancies, blockCount, err := subsidyDiscrepancies(params, fromHeight, toHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	check := &EmissionCheck{
+		FromHeight:    fromHeight,
+		ToHeight:      toHeight,
+		BlockCount:    blockCount,
+		Discrepancies: int64(len(discrepancies)),
+	}
+	for _, discrepancy := range discrepancies {
+		if discrepancy.Kind == DiscrepancyOverpaid {
+			check.Overpaid++
+		} else {
+			check.Underpaid++
+		}
+	}
+	return check, nil
+}
+
+// GetSubsidyDiscrepancies returns the blocks of a height range whose coinbase does not match
+// the subsidy, newest first
+func GetSubsidyDiscrepancies(params *chainparams.Params, fromHeight, toHeight int64, limit, offset int) ([]SubsidyDiscrepancy, error) {
+	discrepancies, _, err := subsidyDiscrepancies(params, fromHeight, toHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	if offset >= len(discrepancies) {
+		return []SubsidyDiscrepancy{}, nil
+	}
+	return discrepancies[offset:min(offset+limit, len(discrepancies))], nil
+}
+
+// subsidyDiscrepancies compares each indexed coinbase in a height range with the subsidy and fees
+// of its block, less the lockbox share the coinbase does not pay out. A coinbase may claim less than
+// the fees, so only payouts above the subsidy plus fees or below the subsidy itself are reported.
+// Payouts are only known to be short when their shielded outputs were recorded, so older coinbases
+// can only be overpaid. Also returns the number of coinbases checked
+func subsidyDiscrepancies(params *chainparams.Params, fromHeight, toHeight int64) ([]SubsidyDiscrepancy, int64, error) {
+	payouts, err := postgres.PostgresQueryCtx[CoinbasePayout](
+		context.Background(), nil,
+		`SELECT b.height, b.hash AS block_hash, t.txid AS coinbase_txid,
+		        t.total_output + COALESCE(t.shielded_value, 0) AS coinbase_output,
+		        t.shielded_value AS shielded_output, b.total_fees AS fees
+		 FROM blocks b
+		 JOIN transactions t ON t.block_height = b.height AND t.type = 'coinbase'
+		 WHERE b.height BETWEEN $1 AND $2
+		 ORDER BY b.height DESC`,
+		fromHeight, toHeight,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get coinbase payouts: %w", err)
+	}
+
+	discrepancies := []SubsidyDiscrepancy{}
+	for _, payout := range payouts {
+		subsidy := params.BlockSubsidy(payout.Height)
+		lockbox := params.LockboxShare(payout.Height)
+		paid := subsidy - lockbox
+		var kind string
+		switch {
+		case payout.CoinbaseOutput > paid+payout.Fees:
+			kind = DiscrepancyOverpaid
+		case payout.ShieldedOutput != nil && payout.CoinbaseOutput < paid:
+			kind = DiscrepancyUnderpaid
+		default:
+			continue
+		}
+		discrepancies = append(discrepancies, SubsidyDiscrepancy{
+			CoinbasePayout: payout,
+			Subsidy:        subsidy,
+			Lockbox:        lockbox,
+			Difference:     payout.CoinbaseOutput - paid - payout.Fees,
+			Kind:           kind,
+		})
+	}
+
+	return discrepancies, int64(len(payouts)), nil
+}
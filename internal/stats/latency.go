@@ -0,0 +1,51 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+)
+
+// blockLatencies selects the block timestamp to indexed_at delay of the blocks in a height range
+// Blocks indexed before indexed_at was recorded are left out
+const blockLatencies = `
+	SELECT height, timestamp, indexed_at,
+	       EXTRACT(EPOCH FROM indexed_at)::DOUBLE PRECISION - timestamp AS latency
+	FROM blocks
+	WHERE height BETWEEN $1 AND $2 AND indexed_at IS NOT NULL`
+
+// GetIndexingLatency summarizes how long the blocks in a height range took to become queryable
+// after their block time. Percentiles are interpolated; aggregates are null when no block matches
+func GetIndexingLatency(fromHeight, toHeight int64) (*IndexingLatency, error) {
+	query := `SELECT COUNT(*) AS block_count,
+		        AVG(latency) AS avg_seconds,
+		        percentile_cont(0.5) WITHIN GROUP (ORDER BY latency) AS p50_seconds,
+		        percentile_cont(0.95) WITHIN GROUP (ORDER BY latency) AS p95_seconds,
+		        percentile_cont(0.99) WITHIN GROUP (ORDER BY latency) AS p99_seconds,
+		        MAX(latency) AS max_seconds
+		 FROM (` + blockLatencies + `) l`
+
+	latency, err := postgres.PostgresQueryOneCtx[IndexingLatency](context.Background(), nil, query, fromHeight, toHeight)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get indexing latency: %w", err)
+	}
+
+	latency.FromHeight = fromHeight
+	latency.ToHeight = toHeight
+	return latency, nil
+}
+
+// GetBlockLatencies returns the indexing latency of each block in a height range, newest first
+func GetBlockLatencies(fromHeight, toHeight int64, limit, offset int) ([]BlockLatency, error) {
+	query := blockLatencies + `
+	ORDER BY height DESC
+	LIMIT $3 OFFSET $4`
+
+	latencies, err := postgres.PostgresQueryCtx[BlockLatency](context.Background(), nil, query, fromHeight, toHeight, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block latencies: %w", err)
+	}
+
+	return latencies, nil
+}
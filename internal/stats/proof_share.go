@@ -0,0 +1,58 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+)
+
+// proofTotals sums the proof witnesses of block b, looked up per block so the stark_proofs
+// block_height index is used
+const proofTotals = `
+	LEFT JOIN LATERAL (
+		SELECT COUNT(*) AS proof_count, COALESCE(SUM(sp.proof_size), 0) AS proof_bytes
+		FROM stark_proofs sp WHERE sp.block_height = b.height
+	) p ON TRUE`
+
+// GetBlockProofShares returns, for each block in a height range, the share of its bytes
+// consumed by stark_verify proof witnesses. Requires the STARKS module
+// Blocks indexed before block sizes were recorded have a size of 0 and are left out
+func GetBlockProofShares(fromHeight, toHeight int64, limit, offset int) ([]BlockProofShare, error) {
+	query := `SELECT b.height, b.timestamp, b.size AS block_bytes,
+		        p.proof_count::BIGINT AS proof_count, p.proof_bytes::BIGINT AS proof_bytes,
+		        p.proof_bytes::DOUBLE PRECISION / b.size AS proof_share
+		 FROM blocks b` + proofTotals + `
+		 WHERE b.height BETWEEN $1 AND $2 AND b.size > 0
+		 ORDER BY b.height
+		 LIMIT $3 OFFSET $4`
+
+	shares, err := postgres.PostgresQueryCtx[BlockProofShare](context.Background(), nil, query, fromHeight, toHeight, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block proof shares: %w", err)
+	}
+
+	return shares, nil
+}
+
+// GetProofShareActivity aggregates the proof share of block space into UTC time buckets
+// Requires the STARKS module
+func GetProofShareActivity(granularity Granularity, fromTime, toTime int64) ([]ProofShareBucket, error) {
+	query := `SELECT EXTRACT(EPOCH FROM date_trunc($1, to_timestamp(b.timestamp) AT TIME ZONE 'UTC'))::BIGINT AS bucket_start,
+		        COUNT(*) AS block_count,
+		        SUM(b.size)::BIGINT AS block_bytes,
+		        SUM(p.proof_count)::BIGINT AS proof_count,
+		        SUM(p.proof_bytes)::BIGINT AS proof_bytes,
+		        SUM(p.proof_bytes)::DOUBLE PRECISION / SUM(b.size) AS proof_share
+		 FROM blocks b` + proofTotals + `
+		 WHERE b.timestamp >= $2 AND b.timestamp <= $3 AND b.size > 0
+		 GROUP BY bucket_start
+		 ORDER BY bucket_start`
+
+	buckets, err := postgres.PostgresQueryCtx[ProofShareBucket](context.Background(), nil, query, string(granularity), fromTime, toTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get proof share activity: %w", err)
+	}
+
+	return buckets, nil
+}
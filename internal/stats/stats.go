@@ -0,0 +1,41 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+)
+
+// GetActivity aggregates block, transaction, TZE and proof counts into UTC time buckets
+// Transaction counts come from the per-block summaries on the blocks table, so only
+// proof counts depend on an optional module
+func GetActivity(granularity Granularity, fromTime, toTime int64) ([]ActivityBucket, error) {
+	// Proof counts are looked up per block so the stark_proofs block_height index is used
+	proofCounts := `SELECT NULL::BIGINT AS proof_count`
+	if config.IsModuleEnabled("STARKS") {
+		proofCounts = `SELECT COUNT(*) AS proof_count FROM stark_proofs sp WHERE sp.block_height = b.height`
+	}
+
+	query := fmt.Sprintf(
+		`SELECT EXTRACT(EPOCH FROM date_trunc($1, to_timestamp(b.timestamp) AT TIME ZONE 'UTC'))::BIGINT AS bucket_start,
+		        COUNT(*) AS block_count,
+		        COALESCE(SUM(b.tx_count), 0)::BIGINT AS tx_count,
+		        COALESCE(SUM(b.tze_tx_count), 0)::BIGINT AS tze_tx_count,
+		        SUM(p.proof_count)::BIGINT AS proof_count
+		 FROM blocks b
+		 LEFT JOIN LATERAL (%s) p ON TRUE
+		 WHERE b.timestamp >= $2 AND b.timestamp <= $3
+		 GROUP BY bucket_start
+		 ORDER BY bucket_start`,
+		proofCounts,
+	)
+
+	buckets, err := postgres.PostgresQueryCtx[ActivityBucket](context.Background(), nil, query, string(granularity), fromTime, toTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain activity: %w", err)
+	}
+
+	return buckets, nil
+}
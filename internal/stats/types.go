@@ -0,0 +1,232 @@
+package stats
+
+import (
+	"time"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/chainparams"
+)
+
+// Granularity is the size of an activity time bucket
+type Granularity string
+
+const (
+	GranularityHour Granularity = "hour"
+	GranularityDay  Granularity = "day"
+)
+
+// ParseGranularity converts a string to a Granularity
+func ParseGranularity(s string) (Granularity, bool) {
+	switch Granularity(s) {
+	case GranularityHour, GranularityDay:
+		return Granularity(s), true
+	default:
+		return "", false
+	}
+}
+
+// Seconds returns the bucket width in seconds
+func (g Granularity) Seconds() int64 {
+	if g == GranularityHour {
+		return 3600
+	}
+	return 86400
+}
+
+// ActivityBucket aggregates chain activity within a UTC time bucket
+type ActivityBucket struct {
+	BucketStart int64  `json:"bucket_start" db:"bucket_start"` // Unix timestamp of the bucket start (UTC)
+	BlockCount  int64  `json:"block_count" db:"block_count"`
+	TxCount     int64  `json:"tx_count" db:"tx_count"`
+	TzeTxCount  int64  `json:"tze_tx_count" db:"tze_tx_count"`
+	ProofCount  *int64 `json:"proof_count" db:"proof_count"` // null when the STARKS module is disabled
+}
+
+// ChainDiff reports what changed on chain in the blocks after FromHeight up to and including ToHeight
+// Sections of disabled modules are omitted
+type ChainDiff struct {
+	FromHeight       int64             `json:"from_height"`
+	FromHash         string            `json:"from_hash"`
+	ToHeight         int64             `json:"to_height"`
+	ToHash           string            `json:"to_hash"`
+	Summary          ChainDiffSummary  `json:"summary"`
+	NewVerifiers     []DiffVerifier    `json:"new_verifiers,omitempty"`     // STARKS
+	StateTransitions []DiffTransition  `json:"state_transitions,omitempty"` // STARKS with index_ztarknet
+	NewPrograms      []DiffProgram     `json:"new_programs,omitempty"`      // STARKS with index_ztarknet
+	BalanceMovements []BalanceMovement `json:"balance_movements,omitempty"` // ACCOUNTS
+}
+
+// ChainDiffSummary holds the totals of a ChainDiff; the lists in the diff may be truncated, these are not
+type ChainDiffSummary struct {
+	Blocks           int64  `json:"blocks"`
+	Transactions     int64  `json:"transactions"`
+	TzeTransactions  int64  `json:"tze_transactions"`
+	NewVerifiers     *int64 `json:"new_verifiers,omitempty"`
+	StateTransitions *int64 `json:"state_transitions,omitempty"`
+	NewPrograms      *int64 `json:"new_programs,omitempty"`
+	BalanceMovements *int64 `json:"balance_movements,omitempty"` // addresses whose net change meets the threshold
+}
+
+// DiffVerifier is a verifier created within the diff range
+type DiffVerifier struct {
+	VerifierID   string `json:"verifier_id" db:"verifier_id"`
+	VerifierName string `json:"verifier_name" db:"verifier_name"`
+	BlockHeight  int64  `json:"block_height" db:"block_height"`
+}
+
+// DiffTransition is a Ztarknet state transition proven within the diff range
+type DiffTransition struct {
+	VerifierID  string `json:"verifier_id" db:"verifier_id"`
+	TxID        string `json:"txid" db:"txid"`
+	BlockHeight int64  `json:"block_height" db:"block_height"`
+	OldState    string `json:"old_state" db:"old_state"`
+	NewState    string `json:"new_state" db:"new_state"`
+	ProgramHash string `json:"program_hash" db:"program_hash"`
+}
+
+// DiffProgram is a program hash first proven within the diff range
+type DiffProgram struct {
+	ProgramHash     string `json:"program_hash" db:"program_hash"`
+	FirstSeenHeight int64  `json:"first_seen_height" db:"first_seen_height"`
+	FactCount       int64  `json:"fact_count" db:"fact_count"` // facts within the range
+}
+
+// BalanceMovement is an address's net balance change within the diff range
+type BalanceMovement struct {
+	Address    string `json:"address" db:"address"`
+	NetChange  int64  `json:"net_change" db:"net_change"`
+	TxCount    int64  `json:"tx_count" db:"tx_count"`
+	BalanceNow int64  `json:"balance_now" db:"balance_now"` // balance at the indexed tip, not at ToHeight
+}
+
+// BlockProofShare is the fraction of a block's bytes taken by stark_verify proof witnesses
+type BlockProofShare struct {
+	Height     int64   `json:"height" db:"height"`
+	Timestamp  int64   `json:"timestamp" db:"timestamp"`
+	BlockBytes int64   `json:"block_bytes" db:"block_bytes"`
+	ProofCount int64   `json:"proof_count" db:"proof_count"`
+	ProofBytes int64   `json:"proof_bytes" db:"proof_bytes"`
+	ProofShare float64 `json:"proof_share" db:"proof_share"` // proof_bytes / block_bytes
+}
+
+// ProofShareBucket aggregates the proof share of block space within a UTC time bucket
+type ProofShareBucket struct {
+	BucketStart int64   `json:"bucket_start" db:"bucket_start"` // Unix timestamp of the bucket start (UTC)
+	BlockCount  int64   `json:"block_count" db:"block_count"`
+	BlockBytes  int64   `json:"block_bytes" db:"block_bytes"`
+	ProofCount  int64   `json:"proof_count" db:"proof_count"`
+	ProofBytes  int64   `json:"proof_bytes" db:"proof_bytes"`
+	ProofShare  float64 `json:"proof_share" db:"proof_share"` // proof_bytes / block_bytes over the bucket
+}
+
+// IndexingLatency summarizes the delay between block timestamps and the time zindex finished indexing them
+// Latencies are in seconds; they include the catch-up delay of blocks indexed during an initial sync
+type IndexingLatency struct {
+	FromHeight int64    `json:"from_height" db:"-"`
+	ToHeight   int64    `json:"to_height" db:"-"`
+	BlockCount int64    `json:"block_count" db:"block_count"`
+	AvgSeconds *float64 `json:"avg_seconds" db:"avg_seconds"`
+	P50Seconds *float64 `json:"p50_seconds" db:"p50_seconds"`
+	P95Seconds *float64 `json:"p95_seconds" db:"p95_seconds"`
+	P99Seconds *float64 `json:"p99_seconds" db:"p99_seconds"`
+	MaxSeconds *float64 `json:"max_seconds" db:"max_seconds"`
+}
+
+// BlockLatency is the indexing latency of one block
+type BlockLatency struct {
+	Height    int64     `json:"height" db:"height"`
+	Timestamp int64     `json:"timestamp" db:"timestamp"`
+	IndexedAt time.Time `json:"indexed_at" db:"indexed_at"`
+	Latency   float64   `json:"latency_seconds" db:"latency"` // seconds from the block timestamp to indexed_at
+}
+
+// EmissionSchedule is the block subsidy schedule at a height, by default the indexed tip
+type EmissionSchedule struct {
+	Params             *chainparams.Params `json:"params"`
+	Height             int64               `json:"height"`
+	Halvings           int64               `json:"halvings"` // halvings that apply at Height
+	Subsidy            int64               `json:"subsidy"`  // zatoshis, including funding stream and lockbox shares
+	NextHalvingHeight  *int64              `json:"next_halving_height"`
+	BlocksUntilHalving *int64              `json:"blocks_until_halving"`
+	NextSubsidy        *int64              `json:"next_subsidy"`
+	// EstimatedHalvingTime is the Unix time of the next halving at the target block spacing
+	EstimatedHalvingTime *int64         `json:"estimated_halving_time"`
+	Check                *EmissionCheck `json:"check,omitempty"` // omitted when TX_GRAPH is disabled
+}
+
+// EmissionCheck summarizes the cross-check of indexed coinbase values against the expected subsidy
+type EmissionCheck struct {
+	FromHeight    int64 `json:"from_height"`
+	ToHeight      int64 `json:"to_height"`
+	BlockCount    int64 `json:"block_count"` // blocks with an indexed coinbase transaction
+	Discrepancies int64 `json:"discrepancies"`
+	Overpaid      int64 `json:"overpaid"`
+	Underpaid     int64 `json:"underpaid"`
+}
+
+// Subsidy discrepancy kinds
+const (
+	// DiscrepancyOverpaid is a coinbase paying more than the subsidy plus the block's fees
+	DiscrepancyOverpaid = "overpaid"
+	// DiscrepancyUnderpaid is a coinbase paying less than the subsidy net of the lockbox share
+	// Coinbases indexed before shielded values were recorded are never reported as underpaid
+	DiscrepancyUnderpaid = "underpaid"
+)
+
+// CoinbasePayout is the value a block's coinbase paid out
+type CoinbasePayout struct {
+	Height         int64  `json:"height" db:"height"`
+	BlockHash      string `json:"block_hash" db:"block_hash"`
+	CoinbaseTxID   string `json:"coinbase_txid" db:"coinbase_txid"`
+	CoinbaseOutput int64  `json:"coinbase_output" db:"coinbase_output"` // transparent and shielded outputs
+	// ShieldedOutput is the part of CoinbaseOutput paid to shielded outputs, null when the coinbase was
+	// indexed before shielded values were recorded, in which case CoinbaseOutput is only transparent
+	ShieldedOutput *int64 `json:"shielded_output" db:"shielded_output"`
+	Fees           int64  `json:"fees" db:"fees"`
+}
+
+// SubsidyDiscrepancy is a block whose coinbase does not match the expected subsidy
+type SubsidyDiscrepancy struct {
+	CoinbasePayout
+	Subsidy    int64  `json:"subsidy"`
+	Lockbox    int64  `json:"lockbox"`    // share of the subsidy deferred to the lockbox, not paid by the coinbase
+	Difference int64  `json:"difference"` // coinbase_output - (subsidy - lockbox) - fees
+	Kind       string `json:"kind"`
+}
+
+// CostModel is the stark_verify cost model in effect, as configured in modules.starks.cost_model
+type CostModel struct {
+	Model        string  `json:"model"` // empty when proofs are not priced
+	Base         float64 `json:"base"`
+	PerProofByte float64 `json:"per_proof_byte"`
+	Pedersen     float64 `json:"pedersen"`
+}
+
+// VerificationCostSummary aggregates the estimated verification cost of the stark_verify proofs of
+// a height range. Block averages and percentiles only count blocks with at least one priced proof
+type VerificationCostSummary struct {
+	FromHeight     int64     `json:"from_height" db:"-"`
+	ToHeight       int64     `json:"to_height" db:"-"`
+	CostModel      CostModel `json:"cost_model" db:"-"`
+	BlockCount     int64     `json:"block_count" db:"block_count"` // blocks with at least one proof
+	ProofCount     int64     `json:"proof_count" db:"proof_count"`
+	UnpricedProofs int64     `json:"unpriced_proofs" db:"unpriced_proofs"` // imported, or indexed without a cost model
+	TotalCost      int64     `json:"total_cost" db:"total_cost"`
+	AvgProofCost   float64   `json:"avg_proof_cost" db:"avg_proof_cost"`
+	MaxProofCost   int64     `json:"max_proof_cost" db:"max_proof_cost"`
+	AvgBlockCost   float64   `json:"avg_block_cost" db:"avg_block_cost"`
+	P95BlockCost   float64   `json:"p95_block_cost" db:"p95_block_cost"`
+	MaxBlockCost   int64     `json:"max_block_cost" db:"max_block_cost"`
+	MaxBlockHeight *int64    `json:"max_block_height" db:"max_block_height"` // most expensive block, null without priced proofs
+}
+
+// BlockVerificationCost is the estimated verification cost of the stark_verify proofs of one block
+type BlockVerificationCost struct {
+	Height         int64 `json:"height" db:"height"`
+	Timestamp      int64 `json:"timestamp" db:"timestamp"`
+	ProofCount     int64 `json:"proof_count" db:"proof_count"`
+	ProofBytes     int64 `json:"proof_bytes" db:"proof_bytes"`
+	PedersenProofs int64 `json:"pedersen_proofs" db:"pedersen_proofs"`
+	UnpricedProofs int64 `json:"unpriced_proofs" db:"unpriced_proofs"`
+	TotalCost      int64 `json:"total_cost" db:"total_cost"`
+	MaxProofCost   int64 `json:"max_proof_cost" db:"max_proof_cost"`
+}
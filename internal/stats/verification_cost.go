@@ -0,0 +1,85 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+)
+
+// currentCostModel returns the configured stark_verify cost model and its weights
+func currentCostModel() CostModel {
+	c := config.Conf.Modules.Starks.CostModel
+	return CostModel{Model: c.Model, Base: c.Base, PerProofByte: c.PerProofByte, Pedersen: c.Pedersen}
+}
+
+// GetVerificationCostSummary aggregates the verification cost estimated for each stark_verify proof
+// of a height range, per proof and per block. Requires the STARKS module
+// Costs were computed with the cost model in effect when each proof was indexed
+func GetVerificationCostSummary(fromHeight, toHeight int64) (*VerificationCostSummary, error) {
+	summary, err := postgres.PostgresQueryOneCtx[VerificationCostSummary](context.Background(), nil,
+		`WITH per_block AS (
+			SELECT block_height,
+			       COUNT(*) AS proof_count,
+			       COUNT(*) FILTER (WHERE verification_cost IS NULL) AS unpriced_proofs,
+			       SUM(verification_cost) AS total_cost,
+			       MAX(verification_cost) AS max_proof_cost
+			FROM stark_proofs
+			WHERE block_height BETWEEN $1 AND $2
+			GROUP BY block_height
+		)
+		SELECT COUNT(*) AS block_count,
+		       COALESCE(SUM(proof_count), 0)::BIGINT AS proof_count,
+		       COALESCE(SUM(unpriced_proofs), 0)::BIGINT AS unpriced_proofs,
+		       COALESCE(SUM(total_cost), 0)::BIGINT AS total_cost,
+		       COALESCE(SUM(total_cost)::DOUBLE PRECISION / NULLIF(SUM(proof_count - unpriced_proofs), 0), 0) AS avg_proof_cost,
+		       COALESCE(MAX(max_proof_cost), 0)::BIGINT AS max_proof_cost,
+		       COALESCE(AVG(total_cost), 0)::DOUBLE PRECISION AS avg_block_cost,
+		       COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY total_cost), 0)::DOUBLE PRECISION AS p95_block_cost,
+		       COALESCE(MAX(total_cost), 0)::BIGINT AS max_block_cost,
+		       (SELECT block_height FROM per_block WHERE total_cost IS NOT NULL
+		        ORDER BY total_cost DESC, block_height LIMIT 1) AS max_block_height
+		FROM per_block`,
+		fromHeight, toHeight,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get verification cost summary: %w", err)
+	}
+
+	summary.FromHeight = fromHeight
+	summary.ToHeight = toHeight
+	summary.CostModel = currentCostModel()
+	return summary, nil
+}
+
+// GetBlockVerificationCosts returns the verification cost of each block of a height range that has
+// stark_verify proofs, by height or most expensive first. Requires the STARKS module
+func GetBlockVerificationCosts(fromHeight, toHeight int64, byCost bool, limit, offset int) ([]BlockVerificationCost, error) {
+	order := "sp.block_height"
+	if byCost {
+		order = "total_cost DESC, sp.block_height"
+	}
+
+	costs, err := postgres.PostgresQueryCtx[BlockVerificationCost](context.Background(), nil,
+		`SELECT sp.block_height AS height, b.timestamp,
+		        COUNT(*) AS proof_count,
+		        SUM(sp.proof_size)::BIGINT AS proof_bytes,
+		        COUNT(*) FILTER (WHERE sp.with_pedersen) AS pedersen_proofs,
+		        COUNT(*) FILTER (WHERE sp.verification_cost IS NULL) AS unpriced_proofs,
+		        COALESCE(SUM(sp.verification_cost), 0)::BIGINT AS total_cost,
+		        COALESCE(MAX(sp.verification_cost), 0)::BIGINT AS max_proof_cost
+		 FROM stark_proofs sp
+		 JOIN blocks b ON b.height = sp.block_height
+		 WHERE sp.block_height BETWEEN $1 AND $2
+		 GROUP BY sp.block_height, b.timestamp
+		 ORDER BY `+order+`
+		 LIMIT $3 OFFSET $4`,
+		fromHeight, toHeight, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block verification costs: %w", err)
+	}
+
+	return costs, nil
+}
@@ -0,0 +1,151 @@
+package tx_graph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/blocks"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/jobs"
+)
+
+// KindBackfillFees is the job kind that backfills fees over a height range, chunk by chunk
+const KindBackfillFees = "backfill_fees"
+
+// defaultFeeBackfillChunk is the blocks backfilled per transaction when a job sets no chunk_size
+const defaultFeeBackfillChunk = 1000
+
+// FeeBackfillResult counts the rows a fee backfill changed in a range of blocks
+type FeeBackfillResult struct {
+	InputsResolved int64 `json:"inputs_resolved"` // inputs whose value was set from the output they spend
+	FeesUpdated    int64 `json:"fees_updated"`    // transactions whose total_fee changed
+	BlocksUpdated  int64 `json:"blocks_updated"`  // blocks whose total_fees changed
+	// ShieldedSkipped counts shielded transactions still without a fee: their shielded value
+	// balances are not stored, so their blocks must be re-indexed instead
+	ShieldedSkipped int64 `json:"shielded_skipped"`
+}
+
+// FeeBackfillJob is the payload of a backfill_fees job
+type FeeBackfillJob struct {
+	FromHeight int64  `json:"from_height"`
+	ToHeight   *int64 `json:"to_height"`  // omitted = last indexed block when the job runs
+	ChunkSize  int64  `json:"chunk_size"` // blocks per database transaction (default 1000)
+}
+
+// runFeeBackfillJob backfills a job's range one chunk per transaction, like cmd/backfill-fees
+// A retried job starts over; chunks already backfilled change no rows the second time
+func runFeeBackfillJob(ctx context.Context, payload json.RawMessage) (any, error) {
+	if !config.IsModuleEnabled("TX_GRAPH") {
+		return nil, jobs.Permanent(fmt.Errorf("the tx_graph module is disabled"))
+	}
+
+	var job FeeBackfillJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return nil, jobs.Permanent(fmt.Errorf("invalid backfill_fees payload: %w", err))
+	}
+	if job.ChunkSize == 0 {
+		job.ChunkSize = defaultFeeBackfillChunk
+	}
+	if job.ChunkSize < 0 || job.FromHeight < 0 {
+		return nil, jobs.Permanent(fmt.Errorf("from_height and chunk_size must not be negative"))
+	}
+
+	var toHeight int64
+	if job.ToHeight != nil {
+		toHeight = *job.ToHeight
+	} else {
+		lastBlock, err := postgres.GetLastIndexedBlock()
+		if err != nil {
+			return nil, err
+		}
+		toHeight = lastBlock
+	}
+	if toHeight < job.FromHeight {
+		return nil, jobs.Permanent(fmt.Errorf("to_height %d is below from_height %d", toHeight, job.FromHeight))
+	}
+
+	total := &FeeBackfillResult{}
+	for start := job.FromHeight; start <= toHeight; start += job.ChunkSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result, err := BackfillFees(ctx, start, min(start+job.ChunkSize-1, toHeight))
+		if err != nil {
+			return nil, err
+		}
+		total.InputsResolved += result.InputsResolved
+		total.FeesUpdated += result.FeesUpdated
+		total.BlocksUpdated += result.BlocksUpdated
+		total.ShieldedSkipped += result.ShieldedSkipped
+	}
+	return total, nil
+}
+
+// BackfillFees resolves input values and transaction fees for blocks indexed before they were
+// computed, between fromHeight and toHeight in one transaction
+// Fees are only derived for transactions without shielded components, whose fee is their
+// transparent inputs minus their transparent outputs
+func BackfillFees(ctx context.Context, fromHeight, toHeight int64) (*FeeBackfillResult, error) {
+	postgresTx, err := postgres.BeginWithTimeout(ctx, postgres.QueryAdminMaintenance)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin fee backfill transaction: %w", err)
+	}
+	defer postgresTx.Rollback(ctx)
+
+	result := &FeeBackfillResult{}
+
+	tag, err := postgresTx.Exec(ctx, `
+		UPDATE transaction_inputs ti
+		SET value = o.value
+		FROM transactions t, transaction_outputs o
+		WHERE t.txid = ti.txid AND t.block_height BETWEEN $1 AND $2
+		  AND o.txid = ti.prev_txid AND o.vout = ti.prev_vout
+		  AND ti.value <> o.value
+	`, fromHeight, toHeight)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve input values for blocks %d-%d: %w", fromHeight, toHeight, err)
+	}
+	result.InputsResolved = tag.RowsAffected()
+
+	tag, err = postgresTx.Exec(ctx, `
+		UPDATE transactions t
+		SET total_fee = i.total_input - t.total_output
+		FROM (
+			SELECT ti.txid, SUM(ti.value) AS total_input, bool_and(o.txid IS NOT NULL) AS resolved
+			FROM transaction_inputs ti
+			JOIN transactions tt ON tt.txid = ti.txid
+			LEFT JOIN transaction_outputs o ON o.txid = ti.prev_txid AND o.vout = ti.prev_vout
+			WHERE tt.block_height BETWEEN $1 AND $2
+			GROUP BY ti.txid
+		) i
+		WHERE t.txid = i.txid AND i.resolved
+		  AND t.type IN ('t2t', 'tze') AND t.shielded_proof_bytes = 0
+		  AND t.total_fee <> i.total_input - t.total_output
+	`, fromHeight, toHeight)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute fees for blocks %d-%d: %w", fromHeight, toHeight, err)
+	}
+	result.FeesUpdated = tag.RowsAffected()
+
+	err = postgresTx.QueryRow(ctx, `
+		SELECT COUNT(*)
+		FROM transactions
+		WHERE block_height BETWEEN $1 AND $2 AND total_fee = 0
+		  AND (type IN ('t2z', 'z2t', 'z2z') OR (type = 'tze' AND shielded_proof_bytes > 0))
+	`, fromHeight, toHeight).Scan(&result.ShieldedSkipped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count shielded transactions for blocks %d-%d: %w", fromHeight, toHeight, err)
+	}
+
+	if result.BlocksUpdated, err = blocks.UpdateBlockTotalFeesRange(postgresTx, fromHeight, toHeight); err != nil {
+		return nil, err
+	}
+
+	if err := postgresTx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit fee backfill for blocks %d-%d: %w", fromHeight, toHeight, err)
+	}
+
+	return result, nil
+}
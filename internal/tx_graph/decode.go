@@ -0,0 +1,93 @@
+package tx_graph
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/types"
+)
+
+// ParseRawTransaction decodes a verbose getrawtransaction result into a ZcashTransaction
+func ParseRawTransaction(rawTx map[string]interface{}) (*types.ZcashTransaction, error) {
+	jsonData, err := json.Marshal(rawTx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal transaction data: %w", err)
+	}
+
+	var tx types.ZcashTransaction
+	if err := json.Unmarshal(jsonData, &tx); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal transaction data: %w", err)
+	}
+	if tx.TxID == "" {
+		return nil, fmt.Errorf("transaction has no txid")
+	}
+
+	return &tx, nil
+}
+
+// DecodeTransaction merges a live decode of a transaction with what is stored for it
+// stored, inputs and outputs are nil/empty for transactions that are not indexed
+func DecodeTransaction(tx *types.ZcashTransaction, stored *Transaction, inputs []TransactionInput, outputs []TransactionOutput) *DecodedTransaction {
+	decoded := &DecodedTransaction{
+		TxID:           tx.TxID,
+		Transaction:    stored,
+		Version:        tx.Version,
+		VersionGroupID: tx.VersionGroupID,
+		Locktime:       tx.LockTime,
+		ExpiryHeight:   tx.ExpiryHeight,
+		Confirmations:  tx.Confirmations,
+		Inputs:         make([]DecodedInput, len(tx.Vin)),
+		Outputs:        make([]DecodedOutput, len(tx.Vout)),
+		Shielded: ShieldedCounts{
+			SaplingSpends:       len(tx.VShieldedSpend),
+			SaplingOutputs:      len(tx.VShieldedOutput),
+			JoinSplits:          len(tx.VJoinSplit),
+			SaplingValueBalance: tx.ValueBalanceZat,
+		},
+	}
+	if tx.Orchard != nil {
+		decoded.Shielded.OrchardActions = len(tx.Orchard.Actions)
+		decoded.Shielded.OrchardValueBalance = tx.Orchard.ValueBalanceZat
+	}
+
+	storedInputs := make(map[int]TransactionInput, len(inputs))
+	for _, input := range inputs {
+		storedInputs[input.Vin] = input
+	}
+	for i, vin := range tx.Vin {
+		input := DecodedInput{Vin: i, Coinbase: vin.Coinbase, Sequence: vin.Sequence}
+		if vin.Coinbase == "" {
+			prevVout := vin.Vout
+			input.PrevTxID = vin.TxID
+			input.PrevVout = &prevVout
+		}
+		if vin.ScriptSig != nil {
+			input.ScriptSigAsm = vin.ScriptSig.Asm
+		}
+		if storedInput, ok := storedInputs[i]; ok {
+			value := storedInput.Value
+			input.Value = &value
+		}
+		decoded.Inputs[i] = input
+	}
+
+	storedOutputs := make(map[int]TransactionOutput, len(outputs))
+	for _, output := range outputs {
+		storedOutputs[output.Vout] = output
+	}
+	for i, vout := range tx.Vout {
+		output := DecodedOutput{Vout: vout.N, Value: vout.ValueZat}
+		if vout.ScriptPubKey != nil {
+			output.ScriptType = vout.ScriptPubKey.Type
+			output.ScriptAsm = vout.ScriptPubKey.Asm
+			output.Addresses = vout.ScriptPubKey.AddressList()
+		}
+		if storedOutput, ok := storedOutputs[int(vout.N)]; ok {
+			output.SpentByTxID = storedOutput.SpentByTxID
+			output.SpentAtHeight = storedOutput.SpentAtHeight
+		}
+		decoded.Outputs[i] = output
+	}
+
+	return decoded
+}
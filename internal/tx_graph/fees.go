@@ -0,0 +1,123 @@
+package tx_graph
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/types"
+)
+
+// outpoint identifies a transparent output by its transaction and index
+type outpoint struct {
+	txid string
+	vout int
+}
+
+// prevout is the value and script addresses of a spent output
+// addresses is empty for scripts without an address and for outputs stored before addresses were
+type prevout struct {
+	value     int64
+	addresses []string
+}
+
+// resolvePrevouts returns the value and addresses of each previous output spent by the block's transactions
+// Outputs created earlier in the same block are taken from the block itself and the rest are read
+// from transaction_outputs in one query. Outputs that were never indexed, e.g. below
+// indexer.start_block, are left out
+func resolvePrevouts(ctx context.Context, postgresTx DBTX, block *types.ZcashBlock) (map[outpoint]prevout, error) {
+	values := make(map[outpoint]prevout)
+	created := make(map[outpoint]prevout)
+	var txids []string
+	var vouts []int32
+	for _, tx := range block.Tx {
+		for _, vin := range tx.Vin {
+			if vin.Coinbase != "" {
+				continue
+			}
+			prev := outpoint{txid: vin.TxID, vout: int(vin.Vout)}
+			if output, ok := created[prev]; ok {
+				values[prev] = output
+				continue
+			}
+			txids = append(txids, prev.txid)
+			vouts = append(vouts, int32(prev.vout))
+		}
+		// A transaction cannot spend its own outputs, so they are added after its inputs
+		outputAddresses := tx.Parsed().OutputAddresses
+		for i, vout := range tx.Vout {
+			created[outpoint{txid: tx.TxID, vout: int(vout.N)}] = prevout{
+				value:     vout.ValueZat,
+				addresses: outputAddresses[i],
+			}
+		}
+	}
+	if len(txids) == 0 {
+		return values, nil
+	}
+
+	rows, err := postgresTx.Query(ctx, `
+		SELECT o.txid, o.vout, o.value, o.addresses
+		FROM transaction_outputs o
+		JOIN unnest($1::varchar[], $2::int[]) AS p(txid, vout) ON o.txid = p.txid AND o.vout = p.vout
+	`, txids, vouts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve previous outputs of block %d: %w", block.Height, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var prev outpoint
+		var output prevout
+		if err := rows.Scan(&prev.txid, &prev.vout, &output.value, &output.addresses); err != nil {
+			return nil, fmt.Errorf("failed to scan previous output of block %d: %w", block.Height, err)
+		}
+		values[prev] = output
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to resolve previous outputs of block %d: %w", block.Height, err)
+	}
+
+	return values, nil
+}
+
+// inputValues returns the value of each of a transaction's inputs (0 for coinbase inputs) and
+// whether every spent previous output was resolved
+func inputValues(tx *types.ZcashTransaction, prevouts map[outpoint]prevout) ([]int64, bool) {
+	values := make([]int64, len(tx.Vin))
+	resolved := true
+	for i, vin := range tx.Vin {
+		if vin.Coinbase != "" {
+			continue
+		}
+		output, ok := prevouts[outpoint{txid: vin.TxID, vout: int(vin.Vout)}]
+		if !ok {
+			resolved = false
+			continue
+		}
+		values[i] = output.value
+	}
+	return values, resolved
+}
+
+// calculateFee returns the fee paid by a transaction: its transparent inputs plus the value
+// leaving the shielded pools, minus its transparent outputs
+func calculateFee(tx *types.ZcashTransaction, totalInput, totalOutput int64) int64 {
+	if tx.IsCoinbase() {
+		return 0
+	}
+	return totalInput + shieldedValueBalance(tx) - totalOutput
+}
+
+// shieldedValueBalance returns the net value in zatoshis a transaction takes out of the
+// Sprout, Sapling and Orchard pools (negative when it puts value in)
+func shieldedValueBalance(tx *types.ZcashTransaction) int64 {
+	balance := tx.ValueBalanceZat
+	if tx.Orchard != nil {
+		balance += tx.Orchard.ValueBalanceZat
+	}
+	for _, js := range tx.VJoinSplit {
+		balance += int64(math.Round(js.VPubNew*1e8)) - int64(math.Round(js.VPubOld*1e8))
+	}
+	return balance
+}
@@ -3,95 +3,123 @@ package tx_graph
 import (
 	"context"
 	"fmt"
-	"log"
+	"math"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/blocks"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/logging"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/types"
 )
 
 // IndexTxGraph indexes transaction graph data from a Zcash block
 // This function builds the UTXO graph by tracking transaction inputs and outputs
-// All transactions in a block are indexed atomically in a single database transaction
-func IndexTxGraph(block *types.ZcashBlock) error {
+// Rows are written through postgresTx, the block's transaction shared by every module
+func IndexTxGraph(postgresTx pgx.Tx, block *types.ZcashBlock) error {
 	// Check if tx_graph module is enabled
 	if !config.IsModuleEnabled("TX_GRAPH") {
 		return nil
 	}
 
-	log.Printf("Indexing transaction graph for block %d (hash: %s, %d transactions)",
+	logging.Blockf(logging.ModuleTxGraph, "Indexing transaction graph for block %d (hash: %s, %d transactions)",
 		block.Height, block.Hash, len(block.Tx))
 
 	ctx := context.Background()
 
-	// Begin a database transaction for the entire block
-	postgresTx, err := postgres.DB.Begin(ctx)
+	// Process each transaction in the block, keeping the row counters in step
+	txids := make([]string, len(block.Tx))
+	for i, tx := range block.Tx {
+		txids[i] = tx.TxID
+	}
+	scopes := []postgres.RowScope{
+		{Table: "transactions", Column: "txid", Keys: txids},
+		{Table: "transaction_outputs", Column: "txid", Keys: txids},
+		{Table: "transaction_inputs", Column: "txid", Keys: txids},
+	}
+	// Input values and sender addresses come from the outputs they spend, so fees can be computed
+	prevouts, err := resolvePrevouts(ctx, postgresTx, block)
 	if err != nil {
-		return fmt.Errorf("failed to begin database transaction for block %d: %w", block.Height, err)
+		return err
 	}
-	defer postgresTx.Rollback(ctx)
 
-	// Process each transaction in the block
+	// Every row of the block is queued and written in one round-trip
+	var batch postgres.WriteBatch
 	for _, tx := range block.Tx {
-		if err := indexTransaction(postgresTx, block, &tx); err != nil {
-			return fmt.Errorf("failed to index transaction %s in block %d: %w",
-				tx.TxID, block.Height, err)
+		indexTransaction(postgresTx, &batch, block, &tx, prevouts)
+	}
+	err = postgres.TrackRowCounts(ctx, postgresTx, scopes, func() error {
+		if err := batch.Send(ctx, postgresTx); err != nil {
+			return fmt.Errorf("failed to store transactions of block %d: %w", block.Height, err)
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	// Commit the transaction
-	if err := postgresTx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit database transaction for block %d: %w", block.Height, err)
+	// Roll the transaction fees up onto the block summary
+	if err := blocks.UpdateBlockTotalFees(postgresTx, block.Height); err != nil {
+		return err
 	}
 
-	log.Printf("Successfully indexed %d transactions for block %d", len(block.Tx), block.Height)
+	logging.Blockf(logging.ModuleTxGraph, "Successfully indexed %d transactions for block %d", len(block.Tx), block.Height)
 	return nil
 }
 
-// indexTransaction processes a single transaction and its inputs/outputs
-func indexTransaction(postgresTx DBTX, block *types.ZcashBlock, tx *types.ZcashTransaction) error {
-	// Determine transaction type
-	txType := determineTransactionType(tx)
+// indexTransaction queues the rows of a single transaction and its inputs/outputs, and their keys
+// for the block manifest of postgresTx
+// prevouts holds the outputs spent in the block; a transaction with an input whose previous output
+// is unknown is stored with a total_fee of 0 and no sender address for that input
+func indexTransaction(postgresTx pgx.Tx, batch *postgres.WriteBatch, block *types.ZcashBlock, tx *types.ZcashTransaction, prevouts map[outpoint]prevout) {
+	// Determine transaction type and, for TZE transactions, the extension used
+	txType, tzeSubtype := ClassifyTransaction(tx)
 
 	// Calculate total output value
 	totalOutput := calculateTotalOutput(tx)
 
-	// TODO: Calculate input values and fees by querying previous outputs from the database
-	// This requires fetching the value of each previous output referenced by inputs.
-	// Fields affected:
-	//   - transactions.total_fee (set to 0 for now)
-	//   - transaction_inputs.value (set to 0 for now)
+	// Calculate input values and the fee from the previous outputs
+	values, resolved := inputValues(tx, prevouts)
+	totalFee := int64(0)
+	if resolved {
+		totalInput := int64(0)
+		for _, value := range values {
+			totalInput += value
+		}
+		totalFee = calculateFee(tx, totalInput, totalOutput)
+	}
 
 	// Store the transaction
-	err := StoreTransaction(
-		postgresTx,
+	QueueTransaction(
+		batch,
 		tx.TxID,
 		block.Height,
 		block.Hash,
 		tx.Version,
 		int64(tx.LockTime),
 		string(txType),
+		tzeSubtype,
 		totalOutput,
-		0, // TODO: totalFee - requires calculating total_input - total_output
+		totalFee,
+		calculateShieldedValue(tx),
 		tx.Size,
+		calculateSizeBreakdown(tx),
 		len(tx.Vin),  // input_count
 		len(tx.Vout), // output_count
 	)
-	if err != nil {
-		return fmt.Errorf("failed to store transaction: %w", err)
-	}
+	postgres.QueueManifestKeys(postgresTx, "TX_GRAPH", "transactions", "insert", tx.TxID)
 
 	// Store transaction outputs
-	for _, vout := range tx.Vout {
-		err := StoreTransactionOutput(
-			postgresTx,
+	outputAddresses := tx.Parsed().OutputAddresses
+	for i, vout := range tx.Vout {
+		QueueTransactionOutput(
+			batch,
 			tx.TxID,
 			int(vout.N),
 			vout.ValueZat,
+			outputAddresses[i],
 		)
-		if err != nil {
-			return fmt.Errorf("failed to store output %d: %w", vout.N, err)
-		}
+		postgres.QueueManifestKeys(postgresTx, "TX_GRAPH", "transaction_outputs", "insert", fmt.Sprintf("%s:%d", tx.TxID, vout.N))
 	}
 
 	// Store transaction inputs (skip for coinbase transactions)
@@ -102,27 +130,42 @@ func indexTransaction(postgresTx DBTX, block *types.ZcashBlock, tx *types.ZcashT
 				continue
 			}
 
-			// TODO: Query the previous output to get its value
-			// This requires: SELECT value FROM transaction_outputs WHERE txid = vin.TxID AND vout = vin.Vout
-			value := int64(0)
-
-			err := StoreTransactionInput(
-				postgresTx,
+			QueueTransactionInput(
+				batch,
 				tx.TxID,
 				i,
-				value,
+				values[i],
 				vin.TxID,
 				int(vin.Vout),
 				int64(vin.Sequence),
 				block.Height,
 			)
-			if err != nil {
-				return fmt.Errorf("failed to store input %d: %w", i, err)
+			postgres.QueueManifestKeys(postgresTx, "TX_GRAPH", "transaction_inputs", "insert", fmt.Sprintf("%s:%d", tx.TxID, i))
+
+			// Only an output that is already stored is marked spent
+			prev, ok := prevouts[outpoint{txid: vin.TxID, vout: int(vin.Vout)}]
+			if ok {
+				postgres.QueueManifestKeys(postgresTx, "TX_GRAPH", "transaction_outputs", "spend", fmt.Sprintf("%s:%d", vin.TxID, vin.Vout))
+			}
+
+			// Record the sender addresses for "sent-from" lookups and the accounts module
+			for _, address := range prev.addresses {
+				QueueInputAddress(batch, tx.TxID, i, address, values[i])
+				postgres.QueueManifestKeys(postgresTx, "TX_GRAPH", "inputs_addresses", "insert", fmt.Sprintf("%s:%d:%s", tx.TxID, i, address))
 			}
 		}
 	}
+}
 
-	return nil
+// ClassifyTransaction returns a transaction's type and, for TZE transactions, its TZE subtype
+// It is shared with the mempool module so unconfirmed transactions are typed the same way
+func ClassifyTransaction(tx *types.ZcashTransaction) (TransactionType, *string) {
+	txType := determineTransactionType(tx)
+	if txType != TxTypeTZE {
+		return txType, nil
+	}
+	subtype := string(determineTzeSubtype(tx))
+	return txType, &subtype
 }
 
 // determineTransactionType determines the type of a transaction based on its properties
@@ -160,6 +203,23 @@ func determineTransactionType(tx *types.ZcashTransaction) TransactionType {
 	return TxTypeT2T
 }
 
+// determineTzeSubtype resolves the TZE extension used by a TZE transaction from its TZE script
+func determineTzeSubtype(tx *types.ZcashTransaction) TzeSubtype {
+	extensionID, ok := tx.TZEExtensionID()
+	if !ok {
+		return TzeSubtypeUnknown
+	}
+
+	switch extensionID {
+	case 0:
+		return TzeSubtypeDemo
+	case 1:
+		return TzeSubtypeStarkVerify
+	default:
+		return TzeSubtypeUnknown
+	}
+}
+
 // calculateTotalOutput sums up all transparent outputs in a transaction
 func calculateTotalOutput(tx *types.ZcashTransaction) int64 {
 	total := int64(0)
@@ -168,3 +228,20 @@ func calculateTotalOutput(tx *types.ZcashTransaction) int64 {
 	}
 	return total
 }
+
+// calculateShieldedValue returns the net value in zatoshis a transaction moves into the Sprout,
+// Sapling and Orchard pools; for a coinbase, this is the value of its shielded outputs
+func calculateShieldedValue(tx *types.ZcashTransaction) int64 {
+	// vpub_old enters the Sprout pool, vpub_new leaves it
+	value := int64(0)
+	for _, js := range tx.VJoinSplit {
+		value += int64(math.Round(js.VPubOld*1e8)) - int64(math.Round(js.VPubNew*1e8))
+	}
+
+	// A positive value balance is value leaving the pool into the transparent part of the transaction
+	value -= tx.ValueBalanceZat
+	if tx.Orchard != nil {
+		value -= tx.Orchard.ValueBalanceZat
+	}
+	return value
+}
@@ -0,0 +1,147 @@
+package tx_graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/types"
+)
+
+// Fixed serialized sizes of transparent input and output fields
+const (
+	outpointBytes = 36 // previous txid + output index
+	sequenceBytes = 4
+	valueBytes    = 8
+)
+
+// calculateSizeBreakdown attributes the serialized bytes of a transaction to transparent data,
+// shielded proofs and TZE scripts, from the hex fields returned by getblock verbosity 2
+// TZE scripts are counted whole, including their extension and mode header
+func calculateSizeBreakdown(tx *types.ZcashTransaction) SizeBreakdown {
+	var sizes SizeBreakdown
+
+	for _, vin := range tx.Vin {
+		if vin.Coinbase != "" {
+			sizes.TransparentBytes += outpointBytes + scriptBytes(vin.Coinbase) + sequenceBytes
+			continue
+		}
+		if vin.ScriptSig != nil && isTzeScript(vin.ScriptSig.Hex) {
+			sizes.TzeWitnessBytes += hexLen(vin.ScriptSig.Hex)
+			continue
+		}
+		sizes.TransparentBytes += outpointBytes + sequenceBytes
+		if vin.ScriptSig != nil {
+			sizes.TransparentBytes += scriptBytes(vin.ScriptSig.Hex)
+		}
+	}
+
+	for _, vout := range tx.Vout {
+		if vout.ScriptPubKey != nil && isTzeScript(vout.ScriptPubKey.Hex) {
+			sizes.TzePreconditionBytes += hexLen(vout.ScriptPubKey.Hex)
+			continue
+		}
+		sizes.TransparentBytes += valueBytes
+		if vout.ScriptPubKey != nil {
+			sizes.TransparentBytes += scriptBytes(vout.ScriptPubKey.Hex)
+		}
+	}
+
+	for _, spend := range tx.VShieldedSpend {
+		sizes.ShieldedProofBytes += hexLen(spend.Proof)
+	}
+	for _, output := range tx.VShieldedOutput {
+		sizes.ShieldedProofBytes += hexLen(output.Proof)
+	}
+	for _, joinSplit := range tx.VJoinSplit {
+		sizes.ShieldedProofBytes += hexLen(joinSplit.Proof)
+	}
+	if tx.Orchard != nil {
+		sizes.ShieldedProofBytes += hexLen(tx.Orchard.Proof)
+	}
+
+	return sizes
+}
+
+func isTzeScript(scriptHex string) bool {
+	return len(scriptHex) >= 2 && scriptHex[:2] == "ff"
+}
+
+func hexLen(s string) int {
+	return len(s) / 2
+}
+
+// scriptBytes is the serialized size of a script: its compactSize length prefix plus the script
+func scriptBytes(scriptHex string) int {
+	n := hexLen(scriptHex)
+	return compactSizeLen(n) + n
+}
+
+func compactSizeLen(n int) int {
+	switch {
+	case n < 0xfd:
+		return 1
+	case n <= 0xffff:
+		return 3
+	case n <= 0xffffffff:
+		return 5
+	default:
+		return 9
+	}
+}
+
+// blockSizeBreakdownQuery aggregates the transaction size breakdowns of each block in a range
+const blockSizeBreakdownQuery = `
+	SELECT block_height,
+	       COUNT(*)::INT AS tx_count,
+	       COALESCE(SUM(size), 0)::BIGINT AS total_bytes,
+	       COALESCE(SUM(transparent_bytes), 0)::BIGINT AS transparent_bytes,
+	       COALESCE(SUM(shielded_proof_bytes), 0)::BIGINT AS shielded_proof_bytes,
+	       COALESCE(SUM(tze_witness_bytes), 0)::BIGINT AS tze_witness_bytes,
+	       COALESCE(SUM(tze_precondition_bytes), 0)::BIGINT AS tze_precondition_bytes,
+	       GREATEST(COALESCE(SUM(size - transparent_bytes - shielded_proof_bytes - tze_witness_bytes - tze_precondition_bytes), 0), 0)::BIGINT AS other_bytes
+	FROM transactions
+	WHERE block_height BETWEEN $1 AND $2
+	GROUP BY block_height
+	ORDER BY block_height
+`
+
+// GetBlockSizeBreakdown returns the size composition of a block and of each of its transactions
+// Returns nil if no transactions are indexed at that height
+func GetBlockSizeBreakdown(blockHeight int64) (*BlockSizeBreakdown, []TransactionSize, error) {
+	ctx := context.Background()
+
+	block, err := postgres.PostgresQueryOneCtx[BlockSizeBreakdown](ctx, readDB, blockSizeBreakdownQuery, blockHeight, blockHeight)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get block size breakdown: %w", err)
+	}
+
+	txs, err := postgres.PostgresQueryCtx[TransactionSize](ctx, readDB,
+		`SELECT txid, type, size, transparent_bytes, shielded_proof_bytes, tze_witness_bytes, tze_precondition_bytes
+		 FROM transactions WHERE block_height = $1
+		 ORDER BY size DESC, txid`,
+		blockHeight,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get transaction size breakdowns: %w", err)
+	}
+
+	return block, txs, nil
+}
+
+// GetBlockSizeBreakdowns returns the size composition of the indexed blocks in a height range with pagination
+func GetBlockSizeBreakdowns(fromHeight, toHeight int64, limit, offset int) ([]BlockSizeBreakdown, error) {
+	blocks, err := postgres.PostgresQueryCtx[BlockSizeBreakdown](context.Background(), readDB,
+		blockSizeBreakdownQuery+` LIMIT $3 OFFSET $4`,
+		fromHeight, toHeight, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block size breakdowns: %w", err)
+	}
+	return blocks, nil
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/jobs"
 )
 
 // DBTX is an interface that both pgxpool.Pool and pgx.Tx implement
@@ -17,9 +18,81 @@ type DBTX interface {
 	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
 }
 
+// readDB serves transaction graph lookups; nil falls back to postgres.DB
+var readDB postgres.Querier
+
+// SetReadDB routes transaction graph lookups through q, e.g. a read replica
+// pool or a mock in tests. Passing nil restores the global postgres.DB
+func SetReadDB(q postgres.Querier) {
+	readDB = q
+}
+
 func init() {
 	// Register this module's schema initialization with the postgres package
 	postgres.RegisterModuleSchema("TX_GRAPH", InitSchema)
+
+	jobs.Register(KindBackfillFees, runFeeBackfillJob)
+}
+
+// redundantIndexes are created by default but dropped when database.drop_redundant_indexes
+// is enabled, since txid is the leading column of the (txid, vout) and (txid, vin) primary keys
+var redundantIndexes = []postgres.RedundantIndex{
+	{
+		Name:       "idx_tx_outputs_txid",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_tx_outputs_txid ON transaction_outputs(txid);`,
+	},
+	{
+		Name:       "idx_tx_inputs_txid",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_tx_inputs_txid ON transaction_inputs(txid);`,
+	},
+}
+
+// secondaryIndexes serve transaction graph queries; none are used while indexing, so all can be deferred
+var secondaryIndexes = []postgres.SecondaryIndex{
+	// Indexes for transactions
+	{
+		Name:       "idx_transactions_block_height",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_transactions_block_height ON transactions(block_height);`,
+	},
+	{
+		Name:       "idx_transactions_block_hash",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_transactions_block_hash ON transactions(block_hash);`,
+	},
+	{
+		Name:       "idx_transactions_type",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_transactions_type ON transactions(type);`,
+	},
+	{
+		Name:       "idx_transactions_tze_subtype",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_transactions_tze_subtype ON transactions(tze_subtype) WHERE tze_subtype IS NOT NULL;`,
+	},
+	{
+		Name:       "idx_transactions_created_at",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_transactions_created_at ON transactions(created_at);`,
+	},
+	// Indexes for transaction outputs
+	{
+		Name:       "idx_tx_outputs_spent_by",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_tx_outputs_spent_by ON transaction_outputs(spent_by_txid) WHERE spent_by_txid IS NOT NULL;`,
+	},
+	{
+		Name:       "idx_tx_outputs_unspent",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_tx_outputs_unspent ON transaction_outputs(txid, vout) WHERE spent_by_txid IS NULL;`,
+	},
+	{
+		Name:       "idx_tx_outputs_value",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_tx_outputs_value ON transaction_outputs(value);`,
+	},
+	// Indexes for transaction inputs
+	{
+		Name:       "idx_tx_inputs_prev",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_tx_inputs_prev ON transaction_inputs(prev_txid, prev_vout);`,
+	},
+	// Indexes for input addresses
+	{
+		Name:       "idx_inputs_addresses_address",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_inputs_addresses_address ON inputs_addresses(address);`,
+	},
 }
 
 // InitSchema creates the transaction graph tables and indexes
@@ -33,6 +106,7 @@ func InitSchema() error {
 			version INT NOT NULL,
 			locktime BIGINT NOT NULL,
 			type VARCHAR(20) NOT NULL,
+			tze_subtype VARCHAR(20),
 			total_output BIGINT NOT NULL,
 			total_fee BIGINT NOT NULL,
 			size INT NOT NULL,
@@ -41,6 +115,15 @@ func InitSchema() error {
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 		);
 
+		-- Columns added after the initial schema
+		ALTER TABLE transactions ADD COLUMN IF NOT EXISTS tze_subtype VARCHAR(20);
+		ALTER TABLE transactions ADD COLUMN IF NOT EXISTS transparent_bytes INT NOT NULL DEFAULT 0;
+		ALTER TABLE transactions ADD COLUMN IF NOT EXISTS shielded_proof_bytes INT NOT NULL DEFAULT 0;
+		ALTER TABLE transactions ADD COLUMN IF NOT EXISTS tze_witness_bytes INT NOT NULL DEFAULT 0;
+		ALTER TABLE transactions ADD COLUMN IF NOT EXISTS tze_precondition_bytes INT NOT NULL DEFAULT 0;
+		-- Net value moved into the shielded pools, NULL for transactions indexed before it was recorded
+		ALTER TABLE transactions ADD COLUMN IF NOT EXISTS shielded_value BIGINT;
+
 		-- Transaction outputs table
 		CREATE TABLE IF NOT EXISTS transaction_outputs (
 			txid VARCHAR(64) NOT NULL,
@@ -53,6 +136,10 @@ func InitSchema() error {
 			FOREIGN KEY (txid) REFERENCES transactions(txid) ON DELETE CASCADE
 		);
 
+		-- Addresses of the output script, so spends can be attributed to their sender
+		-- NULL for outputs indexed before the column existed
+		ALTER TABLE transaction_outputs ADD COLUMN IF NOT EXISTS addresses TEXT[];
+
 		-- Transaction inputs table
 		CREATE TABLE IF NOT EXISTS transaction_inputs (
 			txid VARCHAR(64) NOT NULL,
@@ -65,21 +152,15 @@ func InitSchema() error {
 			FOREIGN KEY (txid) REFERENCES transactions(txid) ON DELETE CASCADE
 		);
 
-		-- Indexes for transactions
-		CREATE INDEX IF NOT EXISTS idx_transactions_block_height ON transactions(block_height);
-		CREATE INDEX IF NOT EXISTS idx_transactions_block_hash ON transactions(block_hash);
-		CREATE INDEX IF NOT EXISTS idx_transactions_type ON transactions(type);
-		CREATE INDEX IF NOT EXISTS idx_transactions_created_at ON transactions(created_at);
-
-		-- Indexes for transaction outputs
-		CREATE INDEX IF NOT EXISTS idx_tx_outputs_txid ON transaction_outputs(txid);
-		CREATE INDEX IF NOT EXISTS idx_tx_outputs_spent_by ON transaction_outputs(spent_by_txid) WHERE spent_by_txid IS NOT NULL;
-		CREATE INDEX IF NOT EXISTS idx_tx_outputs_unspent ON transaction_outputs(txid, vout) WHERE spent_by_txid IS NULL;
-		CREATE INDEX IF NOT EXISTS idx_tx_outputs_value ON transaction_outputs(value);
-
-		-- Indexes for transaction inputs
-		CREATE INDEX IF NOT EXISTS idx_tx_inputs_txid ON transaction_inputs(txid);
-		CREATE INDEX IF NOT EXISTS idx_tx_inputs_prev ON transaction_inputs(prev_txid, prev_vout);
+		-- Input addresses table (sender addresses resolved from the spent previous outputs)
+		CREATE TABLE IF NOT EXISTS inputs_addresses (
+			txid VARCHAR(64) NOT NULL,
+			vin INT NOT NULL,
+			address VARCHAR(255) NOT NULL,
+			value BIGINT NOT NULL,
+			PRIMARY KEY (txid, vin, address),
+			FOREIGN KEY (txid) REFERENCES transactions(txid) ON DELETE CASCADE
+		);
 	`
 
 	_, err := postgres.DB.Exec(context.Background(), schema)
@@ -87,14 +168,24 @@ func InitSchema() error {
 		return fmt.Errorf("failed to create tx_graph schema: %w", err)
 	}
 
+	if err := postgres.InitSecondaryIndexes(secondaryIndexes); err != nil {
+		return fmt.Errorf("failed to init tx_graph indexes: %w", err)
+	}
+
+	if err := postgres.InitRedundantIndexes(redundantIndexes); err != nil {
+		return fmt.Errorf("failed to init tx_graph redundant indexes: %w", err)
+	}
+
 	return nil
 }
 
 // GetTransaction retrieves a transaction by its txid
 func GetTransaction(txid string) (*Transaction, error) {
-	tx, err := postgres.PostgresQueryOne[Transaction](
-		`SELECT txid, block_height, block_hash, version, locktime, type,
-		        total_output, total_fee, size, input_count, output_count, created_at
+	tx, err := postgres.PostgresQueryOneCtx[Transaction](
+		context.Background(), readDB,
+		`SELECT txid, block_height, block_hash, version, locktime, type, tze_subtype,
+		        total_output, total_fee, size, input_count, output_count, created_at,
+		        transparent_bytes, shielded_proof_bytes, tze_witness_bytes, tze_precondition_bytes
 		 FROM transactions WHERE txid = $1`,
 		txid,
 	)
@@ -106,14 +197,20 @@ func GetTransaction(txid string) (*Transaction, error) {
 		return nil, fmt.Errorf("failed to get transaction: %w", err)
 	}
 
+	if err := markTransactionFinal(tx); err != nil {
+		return nil, err
+	}
+
 	return tx, nil
 }
 
 // GetTransactionsByBlock retrieves all transactions in a block
 func GetTransactionsByBlock(blockHeight int64) ([]Transaction, error) {
-	txs, err := postgres.PostgresQuery[Transaction](
-		`SELECT txid, block_height, block_hash, version, locktime, type,
-		        total_output, total_fee, size, input_count, output_count, created_at
+	txs, err := postgres.PostgresQueryCtx[Transaction](
+		context.Background(), readDB,
+		`SELECT txid, block_height, block_hash, version, locktime, type, tze_subtype,
+		        total_output, total_fee, size, input_count, output_count, created_at,
+		        transparent_bytes, shielded_proof_bytes, tze_witness_bytes, tze_precondition_bytes
 		 FROM transactions WHERE block_height = $1
 		 ORDER BY txid`,
 		blockHeight,
@@ -122,6 +219,10 @@ func GetTransactionsByBlock(blockHeight int64) ([]Transaction, error) {
 		return nil, fmt.Errorf("failed to get transactions by block: %w", err)
 	}
 
+	if err := markTransactionsFinal(txs); err != nil {
+		return nil, err
+	}
+
 	return txs, nil
 }
 
@@ -133,30 +234,49 @@ func GetTransactionsByType(txType string, limit, offset int) ([]Transaction, err
 
 // GetTransactionsByTypes retrieves transactions by multiple types with pagination
 func GetTransactionsByTypes(txTypes []string, limit, offset int) ([]Transaction, error) {
+	return GetTransactionsByTypesAndTzeSubtypes(txTypes, nil, limit, offset)
+}
+
+// GetTransactionsByTypesAndTzeSubtypes retrieves transactions by multiple types with pagination
+// If tzeSubtypes is non-empty, only transactions whose tze_subtype is in the list are returned
+func GetTransactionsByTypesAndTzeSubtypes(txTypes []string, tzeSubtypes []string, limit, offset int) ([]Transaction, error) {
 	if len(txTypes) == 0 {
 		return []Transaction{}, nil
 	}
 
-	txs, err := postgres.PostgresQuery[Transaction](
-		`SELECT txid, block_height, block_hash, version, locktime, type,
-		        total_output, total_fee, size, input_count, output_count, created_at
-		 FROM transactions WHERE type = ANY($1)
+	if len(tzeSubtypes) == 0 {
+		tzeSubtypes = nil
+	}
+
+	txs, err := postgres.PostgresQueryCtx[Transaction](
+		context.Background(), readDB,
+		`SELECT txid, block_height, block_hash, version, locktime, type, tze_subtype,
+		        total_output, total_fee, size, input_count, output_count, created_at,
+		        transparent_bytes, shielded_proof_bytes, tze_witness_bytes, tze_precondition_bytes
+		 FROM transactions
+		 WHERE type = ANY($1) AND ($2::VARCHAR[] IS NULL OR tze_subtype = ANY($2))
 		 ORDER BY block_height DESC, txid
-		 LIMIT $2 OFFSET $3`,
-		txTypes, limit, offset,
+		 LIMIT $3 OFFSET $4`,
+		txTypes, tzeSubtypes, limit, offset,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get transactions by types: %w", err)
 	}
 
+	if err := markTransactionsFinal(txs); err != nil {
+		return nil, err
+	}
+
 	return txs, nil
 }
 
 // GetRecentTransactions retrieves the most recent transactions
 func GetRecentTransactions(limit, offset int) ([]Transaction, error) {
-	txs, err := postgres.PostgresQuery[Transaction](
-		`SELECT txid, block_height, block_hash, version, locktime, type,
-		        total_output, total_fee, size, input_count, output_count, created_at
+	txs, err := postgres.PostgresQueryCtx[Transaction](
+		context.Background(), readDB,
+		`SELECT txid, block_height, block_hash, version, locktime, type, tze_subtype,
+		        total_output, total_fee, size, input_count, output_count, created_at,
+		        transparent_bytes, shielded_proof_bytes, tze_witness_bytes, tze_precondition_bytes
 		 FROM transactions
 		 ORDER BY block_height DESC, created_at DESC
 		 LIMIT $1 OFFSET $2`,
@@ -166,17 +286,23 @@ func GetRecentTransactions(limit, offset int) ([]Transaction, error) {
 		return nil, fmt.Errorf("failed to get recent transactions: %w", err)
 	}
 
+	if err := markTransactionsFinal(txs); err != nil {
+		return nil, err
+	}
+
 	return txs, nil
 }
 
-// GetTransactionOutputs retrieves all outputs for a transaction
-func GetTransactionOutputs(txid string) ([]TransactionOutput, error) {
-	outputs, err := postgres.PostgresQuery[TransactionOutput](
+// GetTransactionOutputs retrieves the outputs of a transaction
+// Zero-value outputs, such as TZE markers and data carriers, are left out unless includeZero is set
+func GetTransactionOutputs(txid string, includeZero bool) ([]TransactionOutput, error) {
+	outputs, err := postgres.PostgresQueryCtx[TransactionOutput](
+		context.Background(), readDB,
 		`SELECT txid, vout, value, spent_by_txid, spent_by_vin, spent_at_height
 		 FROM transaction_outputs
-		 WHERE txid = $1
+		 WHERE txid = $1 AND ($2 OR value <> 0)
 		 ORDER BY vout`,
-		txid,
+		txid, includeZero,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get transaction outputs: %w", err)
@@ -187,7 +313,8 @@ func GetTransactionOutputs(txid string) ([]TransactionOutput, error) {
 
 // GetTransactionOutput retrieves a specific output
 func GetTransactionOutput(txid string, vout int) (*TransactionOutput, error) {
-	output, err := postgres.PostgresQueryOne[TransactionOutput](
+	output, err := postgres.PostgresQueryOneCtx[TransactionOutput](
+		context.Background(), readDB,
 		`SELECT txid, vout, value, spent_by_txid, spent_by_vin, spent_at_height
 		 FROM transaction_outputs
 		 WHERE txid = $1 AND vout = $2`,
@@ -204,14 +331,16 @@ func GetTransactionOutput(txid string, vout int) (*TransactionOutput, error) {
 	return output, nil
 }
 
-// GetUnspentOutputs retrieves all unspent outputs for a transaction
-func GetUnspentOutputs(txid string) ([]TransactionOutput, error) {
-	outputs, err := postgres.PostgresQuery[TransactionOutput](
+// GetUnspentOutputs retrieves the unspent outputs of a transaction, with zero-value outputs
+// only when includeZero is set
+func GetUnspentOutputs(txid string, includeZero bool) ([]TransactionOutput, error) {
+	outputs, err := postgres.PostgresQueryCtx[TransactionOutput](
+		context.Background(), readDB,
 		`SELECT txid, vout, value, spent_by_txid, spent_by_vin, spent_at_height
 		 FROM transaction_outputs
-		 WHERE txid = $1 AND spent_by_txid IS NULL
+		 WHERE txid = $1 AND spent_by_txid IS NULL AND ($2 OR value <> 0)
 		 ORDER BY vout`,
-		txid,
+		txid, includeZero,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get unspent outputs: %w", err)
@@ -222,7 +351,8 @@ func GetUnspentOutputs(txid string) ([]TransactionOutput, error) {
 
 // GetTransactionInputs retrieves all inputs for a transaction
 func GetTransactionInputs(txid string) ([]TransactionInput, error) {
-	inputs, err := postgres.PostgresQuery[TransactionInput](
+	inputs, err := postgres.PostgresQueryCtx[TransactionInput](
+		context.Background(), readDB,
 		`SELECT txid, vin, value, prev_txid, prev_vout, sequence
 		 FROM transaction_inputs
 		 WHERE txid = $1
@@ -238,7 +368,8 @@ func GetTransactionInputs(txid string) ([]TransactionInput, error) {
 
 // GetTransactionInput retrieves a specific input
 func GetTransactionInput(txid string, vin int) (*TransactionInput, error) {
-	input, err := postgres.PostgresQueryOne[TransactionInput](
+	input, err := postgres.PostgresQueryOneCtx[TransactionInput](
+		context.Background(), readDB,
 		`SELECT txid, vin, value, prev_txid, prev_vout, sequence
 		 FROM transaction_inputs
 		 WHERE txid = $1 AND vin = $2`,
@@ -255,9 +386,47 @@ func GetTransactionInput(txid string, vin int) (*TransactionInput, error) {
 	return input, nil
 }
 
+// GetInputAddresses retrieves the resolved sender addresses of a transaction's inputs
+func GetInputAddresses(txid string) ([]InputAddress, error) {
+	addresses, err := postgres.PostgresQueryCtx[InputAddress](
+		context.Background(), readDB,
+		`SELECT txid, vin, address, value
+		 FROM inputs_addresses
+		 WHERE txid = $1
+		 ORDER BY vin, address`,
+		txid,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get input addresses: %w", err)
+	}
+
+	return addresses, nil
+}
+
+// GetInputsByAddress retrieves the inputs that spent funds from an address ("sent-from" history),
+// newest first
+func GetInputsByAddress(address string, limit, offset int) ([]InputAddress, error) {
+	inputs, err := postgres.PostgresQueryCtx[InputAddress](
+		context.Background(), readDB,
+		`SELECT ia.txid, ia.vin, ia.address, ia.value
+		 FROM inputs_addresses ia
+		 JOIN transactions t ON t.txid = ia.txid
+		 WHERE ia.address = $1
+		 ORDER BY t.block_height DESC, ia.txid, ia.vin
+		 LIMIT $2 OFFSET $3`,
+		address, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inputs by address: %w", err)
+	}
+
+	return inputs, nil
+}
+
 // GetOutputSpenders retrieves all transactions that spent outputs from a given transaction
 func GetOutputSpenders(txid string) ([]TransactionOutput, error) {
-	outputs, err := postgres.PostgresQuery[TransactionOutput](
+	outputs, err := postgres.PostgresQueryCtx[TransactionOutput](
+		context.Background(), readDB,
 		`SELECT txid, vout, value, spent_by_txid, spent_by_vin, spent_at_height
 		 FROM transaction_outputs
 		 WHERE txid = $1 AND spent_by_txid IS NOT NULL
@@ -273,7 +442,8 @@ func GetOutputSpenders(txid string) ([]TransactionOutput, error) {
 
 // GetInputSources retrieves all transactions that provided inputs to a given transaction
 func GetInputSources(txid string) ([]TransactionInput, error) {
-	inputs, err := postgres.PostgresQuery[TransactionInput](
+	inputs, err := postgres.PostgresQueryCtx[TransactionInput](
+		context.Background(), readDB,
 		`SELECT txid, vin, value, prev_txid, prev_vout, sequence
 		 FROM transaction_inputs
 		 WHERE txid = $1
@@ -322,7 +492,7 @@ func GetTransactionGraph(txid string, depth int) ([]string, error) {
 		TxID string `db:"txid"`
 	}
 
-	results, err := postgres.PostgresQuery[result](query, txid, depth)
+	results, err := postgres.PostgresQueryCtx[result](context.Background(), readDB, query, txid, depth)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query transaction graph: %w", err)
 	}
@@ -337,86 +507,90 @@ func GetTransactionGraph(txid string, depth int) ([]string, error) {
 
 // StoreTransaction inserts or updates a transaction in the database
 // If postgresTx is provided, it will be used; otherwise a standalone query is executed
-func StoreTransaction(postgresTx DBTX, txid string, blockHeight int64, blockHash string, version int, locktime int64, txType string, totalOutput int64, totalFee int64, size int, inputCount int, outputCount int) error {
-	ctx := context.Background()
+// tzeSubtype is only set for "tze" transactions; pass nil otherwise
+// shieldedValue is the net value the transaction moves into the shielded pools, negative when it moves value out
+func StoreTransaction(postgresTx DBTX, txid string, blockHeight int64, blockHash string, version int, locktime int64, txType string, tzeSubtype *string, totalOutput int64, totalFee int64, shieldedValue int64, size int, sizes SizeBreakdown, inputCount int, outputCount int) error {
+	var batch postgres.WriteBatch
+	QueueTransaction(&batch, txid, blockHeight, blockHash, version, locktime, txType, tzeSubtype, totalOutput, totalFee, shieldedValue, size, sizes, inputCount, outputCount)
+	return sendBatch(postgresTx, &batch)
+}
 
+// QueueTransaction queues the upsert of a transaction into batch, see StoreTransaction
+func QueueTransaction(batch *postgres.WriteBatch, txid string, blockHeight int64, blockHash string, version int, locktime int64, txType string, tzeSubtype *string, totalOutput int64, totalFee int64, shieldedValue int64, size int, sizes SizeBreakdown, inputCount int, outputCount int) {
 	query := `
-		INSERT INTO transactions (txid, block_height, block_hash, version, locktime, type, total_output, total_fee, size, input_count, output_count)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-		ON CONFLICT (txid) DO UPDATE SET
+		INSERT INTO transactions (txid, block_height, block_hash, version, locktime, type, tze_subtype, total_output, total_fee, size, input_count, output_count,
+		                          transparent_bytes, shielded_proof_bytes, tze_witness_bytes, tze_precondition_bytes, shielded_value)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+		ON CONFLICT (txid) ` + postgres.OnConflictImmutable(`
 			block_height = EXCLUDED.block_height,
 			block_hash = EXCLUDED.block_hash,
 			version = EXCLUDED.version,
 			locktime = EXCLUDED.locktime,
 			type = EXCLUDED.type,
+			tze_subtype = EXCLUDED.tze_subtype,
 			total_output = EXCLUDED.total_output,
 			total_fee = EXCLUDED.total_fee,
 			size = EXCLUDED.size,
 			input_count = EXCLUDED.input_count,
-			output_count = EXCLUDED.output_count
-	`
-
-	if postgresTx == nil {
-		postgresTx = postgres.DB
-	}
-
-	_, err := postgresTx.Exec(ctx, query, txid, blockHeight, blockHash, version, locktime, txType, totalOutput, totalFee, size, inputCount, outputCount)
-	if err != nil {
-		return fmt.Errorf("failed to store transaction %s: %w", txid, err)
-	}
-
-	return nil
+			output_count = EXCLUDED.output_count,
+			transparent_bytes = EXCLUDED.transparent_bytes,
+			shielded_proof_bytes = EXCLUDED.shielded_proof_bytes,
+			tze_witness_bytes = EXCLUDED.tze_witness_bytes,
+			tze_precondition_bytes = EXCLUDED.tze_precondition_bytes,
+			shielded_value = EXCLUDED.shielded_value
+	`)
+
+	batch.Queue(fmt.Sprintf("store transaction %s", txid), query,
+		txid, blockHeight, blockHash, version, locktime, txType, tzeSubtype, totalOutput, totalFee, size, inputCount, outputCount,
+		sizes.TransparentBytes, sizes.ShieldedProofBytes, sizes.TzeWitnessBytes, sizes.TzePreconditionBytes, shieldedValue)
 }
 
 // StoreTransactionOutput inserts or updates a transaction output in the database
 // If postgresTx is provided, it will be used; otherwise a standalone query is executed
-func StoreTransactionOutput(postgresTx DBTX, txid string, vout int, value int64) error {
-	ctx := context.Background()
+func StoreTransactionOutput(postgresTx DBTX, txid string, vout int, value int64, addresses []string) error {
+	var batch postgres.WriteBatch
+	QueueTransactionOutput(&batch, txid, vout, value, addresses)
+	return sendBatch(postgresTx, &batch)
+}
 
+// QueueTransactionOutput queues the upsert of a transaction output into batch
+func QueueTransactionOutput(batch *postgres.WriteBatch, txid string, vout int, value int64, addresses []string) {
 	query := `
-		INSERT INTO transaction_outputs (txid, vout, value)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (txid, vout) DO UPDATE SET
-			value = EXCLUDED.value
-	`
-
-	if postgresTx == nil {
-		postgresTx = postgres.DB
-	}
-
-	_, err := postgresTx.Exec(ctx, query, txid, vout, value)
-	if err != nil {
-		return fmt.Errorf("failed to store transaction output %s:%d: %w", txid, vout, err)
-	}
+		INSERT INTO transaction_outputs (txid, vout, value, addresses)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (txid, vout) ` + postgres.OnConflictImmutable(`
+			value = EXCLUDED.value,
+			addresses = EXCLUDED.addresses
+	`)
 
-	return nil
+	batch.Queue(fmt.Sprintf("store transaction output %s:%d", txid, vout), query, txid, vout, value, addresses)
 }
 
 // StoreTransactionInput inserts or updates a transaction input in the database
 // and marks the corresponding output as spent
 // If postgresTx is provided, it will be used; otherwise a standalone query is executed
 func StoreTransactionInput(postgresTx DBTX, txid string, vin int, value int64, prevTxid string, prevVout int, sequence int64, blockHeight int64) error {
-	ctx := context.Background()
-
-	if postgresTx == nil {
-		postgresTx = postgres.DB
-	}
+	var batch postgres.WriteBatch
+	QueueTransactionInput(&batch, txid, vin, value, prevTxid, prevVout, sequence, blockHeight)
+	return sendBatch(postgresTx, &batch)
+}
 
+// QueueTransactionInput queues the upsert of a transaction input and the update marking the
+// output it spends; the output must be queued earlier or already stored
+func QueueTransactionInput(batch *postgres.WriteBatch, txid string, vin int, value int64, prevTxid string, prevVout int, sequence int64, blockHeight int64) {
 	// Insert the input
 	inputQuery := `
 		INSERT INTO transaction_inputs (txid, vin, value, prev_txid, prev_vout, sequence)
 		VALUES ($1, $2, $3, $4, $5, $6)
-		ON CONFLICT (txid, vin) DO UPDATE SET
+		ON CONFLICT (txid, vin) ` + postgres.OnConflictImmutable(`
 			value = EXCLUDED.value,
 			prev_txid = EXCLUDED.prev_txid,
 			prev_vout = EXCLUDED.prev_vout,
 			sequence = EXCLUDED.sequence
-	`
+	`)
 
-	_, err := postgresTx.Exec(ctx, inputQuery, txid, vin, value, prevTxid, prevVout, sequence)
-	if err != nil {
-		return fmt.Errorf("failed to store transaction input %s:%d: %w", txid, vin, err)
-	}
+	batch.Queue(fmt.Sprintf("store transaction input %s:%d", txid, vin), inputQuery,
+		txid, vin, value, prevTxid, prevVout, sequence)
 
 	// Mark the previous output as spent
 	outputQuery := `
@@ -427,12 +601,35 @@ func StoreTransactionInput(postgresTx DBTX, txid string, vin int, value int64, p
 		WHERE txid = $4 AND vout = $5
 	`
 
-	_, err = postgresTx.Exec(ctx, outputQuery, txid, vin, blockHeight, prevTxid, prevVout)
-	if err != nil {
-		return fmt.Errorf("failed to mark output %s:%d as spent: %w", prevTxid, prevVout, err)
+	batch.Queue(fmt.Sprintf("mark output %s:%d as spent", prevTxid, prevVout), outputQuery,
+		txid, vin, blockHeight, prevTxid, prevVout)
+}
+
+// sendBatch writes batch through postgresTx, or the global pool when it is nil
+func sendBatch(postgresTx DBTX, batch *postgres.WriteBatch) error {
+	if postgresTx == nil {
+		postgresTx = postgres.DB
 	}
+	return batch.Send(context.Background(), postgresTx)
+}
 
-	return nil
+// StoreInputAddress records the address and value of the previous output spent by an input
+func StoreInputAddress(postgresTx DBTX, txid string, vin int, address string, value int64) error {
+	var batch postgres.WriteBatch
+	QueueInputAddress(&batch, txid, vin, address, value)
+	return sendBatch(postgresTx, &batch)
+}
+
+// QueueInputAddress queues the upsert of an input's sender address into batch
+func QueueInputAddress(batch *postgres.WriteBatch, txid string, vin int, address string, value int64) {
+	query := `
+		INSERT INTO inputs_addresses (txid, vin, address, value)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (txid, vin, address) ` + postgres.OnConflictImmutable(`
+			value = EXCLUDED.value
+	`)
+
+	batch.Queue(fmt.Sprintf("store input address %s:%d (%s)", txid, vin, address), query, txid, vin, address, value)
 }
 
 // CountTransactions returns the total count of transactions with optional filters
@@ -450,12 +647,12 @@ func CountTransactions(txType string, blockHeight int64) (int64, error) {
 		query = `SELECT COUNT(*) FROM transactions WHERE block_height = $1`
 		args = []interface{}{blockHeight}
 	} else {
-		query = `SELECT COUNT(*) FROM transactions`
-		args = []interface{}{}
+		// Unfiltered counts come from the maintained row counter
+		return postgres.CountRows(context.Background(), readDB, "transactions")
 	}
 
 	var count int64
-	err := postgres.DB.QueryRow(context.Background(), query, args...).Scan(&count)
+	err := postgres.ReadQuerier(readDB).QueryRow(context.Background(), query, args...).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count transactions: %w", err)
 	}
@@ -478,12 +675,12 @@ func CountTransactionOutputs(txid string, spent bool) (int64, error) {
 		query = `SELECT COUNT(*) FROM transaction_outputs WHERE spent_by_txid IS NOT NULL`
 		args = []interface{}{}
 	} else {
-		query = `SELECT COUNT(*) FROM transaction_outputs`
-		args = []interface{}{}
+		// Unfiltered counts come from the maintained row counter
+		return postgres.CountRows(context.Background(), readDB, "transaction_outputs")
 	}
 
 	var count int64
-	err := postgres.DB.QueryRow(context.Background(), query, args...).Scan(&count)
+	err := postgres.ReadQuerier(readDB).QueryRow(context.Background(), query, args...).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count transaction outputs: %w", err)
 	}
@@ -500,15 +697,39 @@ func CountTransactionInputs(txid string) (int64, error) {
 		query = `SELECT COUNT(*) FROM transaction_inputs WHERE txid = $1`
 		args = []interface{}{txid}
 	} else {
-		query = `SELECT COUNT(*) FROM transaction_inputs`
-		args = []interface{}{}
+		// Unfiltered counts come from the maintained row counter
+		return postgres.CountRows(context.Background(), readDB, "transaction_inputs")
 	}
 
 	var count int64
-	err := postgres.DB.QueryRow(context.Background(), query, args...).Scan(&count)
+	err := postgres.ReadQuerier(readDB).QueryRow(context.Background(), query, args...).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count transaction inputs: %w", err)
 	}
 
 	return count, nil
 }
+
+// markTransactionsFinal sets the Final flag on transactions mined at or below the finalized height
+func markTransactionsFinal(txs []Transaction) error {
+	finalizedHeight, err := postgres.GetFinalizedHeight()
+	if err != nil {
+		return err
+	}
+
+	for i := range txs {
+		txs[i].Final = txs[i].BlockHeight <= finalizedHeight
+	}
+	return nil
+}
+
+// markTransactionFinal sets the Final flag on a single transaction
+func markTransactionFinal(tx *Transaction) error {
+	finalizedHeight, err := postgres.GetFinalizedHeight()
+	if err != nil {
+		return err
+	}
+
+	tx.Final = tx.BlockHeight <= finalizedHeight
+	return nil
+}
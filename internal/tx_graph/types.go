@@ -9,13 +9,51 @@ type Transaction struct {
 	BlockHash   string    `json:"block_hash" db:"block_hash"`
 	Version     int       `json:"version" db:"version"`
 	Locktime    int64     `json:"locktime" db:"locktime"`
-	Type        string    `json:"type" db:"type"` // coinbase, tze, t2t, t2z, z2t, z2z
+	Type        string    `json:"type" db:"type"`                         // coinbase, tze, t2t, t2z, z2t, z2z
+	TzeSubtype  *string   `json:"tze_subtype,omitempty" db:"tze_subtype"` // demo, stark_verify, unknown (tze only)
 	TotalOutput int64     `json:"total_output" db:"total_output"`
 	TotalFee    int64     `json:"total_fee" db:"total_fee"`
 	Size        int       `json:"size" db:"size"`
 	InputCount  int       `json:"input_count" db:"input_count"`
 	OutputCount int       `json:"output_count" db:"output_count"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	Final       bool      `json:"final" db:"-"`
+	SizeBreakdown
+}
+
+// SizeBreakdown splits a transaction's serialized size by what the bytes carry
+// Bytes not attributed to a category (header, signatures, note ciphertexts, ...) make up the
+// difference to the transaction size
+type SizeBreakdown struct {
+	TransparentBytes     int `json:"transparent_bytes" db:"transparent_bytes"`           // non-TZE inputs and outputs
+	ShieldedProofBytes   int `json:"shielded_proof_bytes" db:"shielded_proof_bytes"`     // Sprout, Sapling and Orchard zk proofs
+	TzeWitnessBytes      int `json:"tze_witness_bytes" db:"tze_witness_bytes"`           // TZE input witnesses
+	TzePreconditionBytes int `json:"tze_precondition_bytes" db:"tze_precondition_bytes"` // TZE output preconditions
+}
+
+// BlockSizeBreakdown aggregates the size breakdown of every transaction in a block
+type BlockSizeBreakdown struct {
+	BlockHeight int64 `json:"block_height" db:"block_height"`
+	TxCount     int   `json:"tx_count" db:"tx_count"`
+	TotalBytes  int64 `json:"total_bytes" db:"total_bytes"` // sum of transaction sizes
+	SizeTotals
+	OtherBytes int64 `json:"other_bytes" db:"other_bytes"`
+}
+
+// SizeTotals are SizeBreakdown sums over several transactions
+type SizeTotals struct {
+	TransparentBytes     int64 `json:"transparent_bytes" db:"transparent_bytes"`
+	ShieldedProofBytes   int64 `json:"shielded_proof_bytes" db:"shielded_proof_bytes"`
+	TzeWitnessBytes      int64 `json:"tze_witness_bytes" db:"tze_witness_bytes"`
+	TzePreconditionBytes int64 `json:"tze_precondition_bytes" db:"tze_precondition_bytes"`
+}
+
+// TransactionSize is the size breakdown of one transaction within a block
+type TransactionSize struct {
+	TxID string `json:"txid" db:"txid"`
+	Type string `json:"type" db:"type"`
+	Size int    `json:"size" db:"size"`
+	SizeBreakdown
 }
 
 // TransactionOutput represents an output of a transaction
@@ -38,6 +76,61 @@ type TransactionInput struct {
 	Sequence int64  `json:"sequence" db:"sequence"`
 }
 
+// InputAddress records the address (and value) of the previous output spent by an input
+type InputAddress struct {
+	TxID    string `json:"txid" db:"txid"`
+	Vin     int    `json:"vin" db:"vin"`
+	Address string `json:"address" db:"address"`
+	Value   int64  `json:"value" db:"value"`
+}
+
+// DecodedTransaction merges a stored transaction with a live getrawtransaction decode from the node
+// Transaction is nil when the transaction is not indexed, e.g. while it is still in the mempool
+type DecodedTransaction struct {
+	TxID           string          `json:"txid"`
+	Transaction    *Transaction    `json:"transaction"`
+	Version        int             `json:"version"`
+	VersionGroupID string          `json:"version_group_id,omitempty"`
+	Locktime       uint32          `json:"locktime"`
+	ExpiryHeight   int64           `json:"expiry_height,omitempty"`
+	Confirmations  int64           `json:"confirmations"`
+	Inputs         []DecodedInput  `json:"inputs"`
+	Outputs        []DecodedOutput `json:"outputs"`
+	Shielded       ShieldedCounts  `json:"shielded"`
+}
+
+// DecodedInput is a transparent input as decoded by the node
+type DecodedInput struct {
+	Vin          int     `json:"vin"`
+	Coinbase     string  `json:"coinbase,omitempty"`
+	PrevTxID     string  `json:"prev_txid,omitempty"`
+	PrevVout     *uint32 `json:"prev_vout,omitempty"` // nil for coinbase inputs
+	ScriptSigAsm string  `json:"script_sig_asm,omitempty"`
+	Sequence     uint32  `json:"sequence"`
+	Value        *int64  `json:"value,omitempty"` // stored value of the spent output, indexed transactions only
+}
+
+// DecodedOutput is a transparent output as decoded by the node, with its stored spend status
+type DecodedOutput struct {
+	Vout          uint32   `json:"vout"`
+	Value         int64    `json:"value"` // zatoshis
+	ScriptType    string   `json:"script_type"`
+	ScriptAsm     string   `json:"script_asm"`
+	Addresses     []string `json:"addresses,omitempty"`
+	SpentByTxID   *string  `json:"spent_by_txid,omitempty"`
+	SpentAtHeight *int64   `json:"spent_at_height,omitempty"`
+}
+
+// ShieldedCounts counts a transaction's shielded components and their net value balances
+type ShieldedCounts struct {
+	SaplingSpends       int   `json:"sapling_spends"`
+	SaplingOutputs      int   `json:"sapling_outputs"`
+	JoinSplits          int   `json:"joinsplits"`
+	OrchardActions      int   `json:"orchard_actions"`
+	SaplingValueBalance int64 `json:"sapling_value_balance"` // zatoshis leaving (positive) or entering (negative) the Sapling pool
+	OrchardValueBalance int64 `json:"orchard_value_balance"` // zatoshis leaving (positive) or entering (negative) the Orchard pool
+}
+
 // TransactionType represents the type of transaction
 type TransactionType string
 
@@ -49,3 +142,12 @@ const (
 	TxTypeZ2T      TransactionType = "z2t" // shielded to transparent
 	TxTypeZ2Z      TransactionType = "z2z" // shielded to shielded
 )
+
+// TzeSubtype represents the TZE extension used by a "tze" transaction
+type TzeSubtype string
+
+const (
+	TzeSubtypeDemo        TzeSubtype = "demo"         // extension_id 0
+	TzeSubtypeStarkVerify TzeSubtype = "stark_verify" // extension_id 1
+	TzeSubtypeUnknown     TzeSubtype = "unknown"      // any other extension_id
+)
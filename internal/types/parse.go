@@ -0,0 +1,94 @@
+package types
+
+// TzeScript is a TZE input or output of a transaction, classified by its script header
+type TzeScript struct {
+	Index  int   // position in Vin or Vout
+	Type   int32 // extension_id
+	Mode   int32
+	Header bool // false when the script starts with the TZE marker but is too short for the header
+}
+
+// ParsedTransaction is what the indexing modules read from a transaction's scripts, worked out once
+type ParsedTransaction struct {
+	TzeInputs       []TzeScript
+	TzeOutputs      []TzeScript
+	OutputAddresses [][]string // by position in Vout
+}
+
+// Parse runs the preprocessing stage of a block before its modules index it: the scripts of every
+// transaction are classified once, and the result is shared by all modules through Parsed
+// Modules index a block one after another, so the block must not be indexed concurrently
+func (b *ZcashBlock) Parse() {
+	for i := range b.Tx {
+		b.Tx[i].Parsed()
+	}
+}
+
+// Parsed returns the classified scripts of the transaction, parsing them on first use
+// Range loops copy transactions, so a transaction of a block that was not Parse'd first is parsed
+// again for every copy
+func (tx *ZcashTransaction) Parsed() *ParsedTransaction {
+	if tx.parsed == nil {
+		tx.parsed = parseTransaction(tx)
+	}
+	return tx.parsed
+}
+
+func parseTransaction(tx *ZcashTransaction) *ParsedTransaction {
+	parsed := &ParsedTransaction{OutputAddresses: make([][]string, len(tx.Vout))}
+
+	for i, vin := range tx.Vin {
+		if vin.ScriptSig != nil && isTzeScript(vin.ScriptSig.Hex) {
+			parsed.TzeInputs = append(parsed.TzeInputs, classifyTzeScript(i, vin.ScriptSig.Hex))
+		}
+	}
+
+	for i, vout := range tx.Vout {
+		if vout.ScriptPubKey != nil && isTzeScript(vout.ScriptPubKey.Hex) {
+			parsed.TzeOutputs = append(parsed.TzeOutputs, classifyTzeScript(i, vout.ScriptPubKey.Hex))
+		}
+		parsed.OutputAddresses[i] = vout.ScriptPubKey.AddressList()
+	}
+
+	return parsed
+}
+
+// isTzeScript reports whether a script starts with the 0xff TZE marker
+func isTzeScript(scriptHex string) bool {
+	return len(scriptHex) >= 2 && scriptHex[:2] == "ff"
+}
+
+func classifyTzeScript(index int, scriptHex string) TzeScript {
+	tzeType, tzeMode, ok := ParseTzeHeader(scriptHex)
+	return TzeScript{Index: index, Type: tzeType, Mode: tzeMode, Header: ok}
+}
+
+// IsTZE reports whether the transaction has any TZE input or output
+func (p *ParsedTransaction) IsTZE() bool {
+	return len(p.TzeInputs) > 0 || len(p.TzeOutputs) > 0
+}
+
+// TzeInputsOfType returns the TZE inputs of one extension
+func (p *ParsedTransaction) TzeInputsOfType(tzeType int32) []TzeScript {
+	return tzeScriptsOfType(p.TzeInputs, tzeType)
+}
+
+// TzeOutputsOfType returns the TZE outputs of one extension
+func (p *ParsedTransaction) TzeOutputsOfType(tzeType int32) []TzeScript {
+	return tzeScriptsOfType(p.TzeOutputs, tzeType)
+}
+
+// HasTzeType reports whether any TZE input or output uses the extension
+func (p *ParsedTransaction) HasTzeType(tzeType int32) bool {
+	return len(p.TzeInputsOfType(tzeType)) > 0 || len(p.TzeOutputsOfType(tzeType)) > 0
+}
+
+func tzeScriptsOfType(scripts []TzeScript, tzeType int32) []TzeScript {
+	var matching []TzeScript
+	for _, script := range scripts {
+		if script.Header && script.Type == tzeType {
+			matching = append(matching, script)
+		}
+	}
+	return matching
+}
@@ -0,0 +1,96 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// TzeHeaderSize is the size of a TZE script header: the 0xff marker, the 4-byte extension id
+// and the 4-byte mode, followed by the precondition or witness
+const TzeHeaderSize = 9
+
+// Bytes returns the decoded script, hex-decoding it on first use only so the modules indexing
+// a block share one copy. Not safe for concurrent use; modules index a block one after another
+func (s *ScriptSig) Bytes() ([]byte, error) {
+	if s.decoded == nil {
+		decoded, err := hex.DecodeString(s.Hex)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode scriptSig hex: %w", err)
+		}
+		s.decoded = decoded
+	}
+	return s.decoded, nil
+}
+
+// Bytes returns the decoded script, hex-decoding it on first use only (see ScriptSig.Bytes)
+func (s *ScriptPubKey) Bytes() ([]byte, error) {
+	if s.decoded == nil {
+		decoded, err := hex.DecodeString(s.Hex)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode scriptPubKey hex: %w", err)
+		}
+		s.decoded = decoded
+	}
+	return s.decoded, nil
+}
+
+// ParseTzeHeader decodes the extension id and mode of a TZE script from its hex, without decoding the rest
+func ParseTzeHeader(scriptHex string) (tzeType int32, tzeMode int32, ok bool) {
+	if len(scriptHex) < 2*TzeHeaderSize || scriptHex[:2] != "ff" {
+		return 0, 0, false
+	}
+
+	header, err := hex.DecodeString(scriptHex[:2*TzeHeaderSize])
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return int32(binary.BigEndian.Uint32(header[1:5])), int32(binary.BigEndian.Uint32(header[5:9])), true
+}
+
+// TzeDataSize returns the size of the precondition or witness of a TZE script from its hex length
+func TzeDataSize(scriptHex string) int {
+	size := len(scriptHex)/2 - TzeHeaderSize
+	if size < 0 {
+		return 0
+	}
+	return size
+}
+
+// DecodeTzeDataPrefix decodes the first n bytes of the precondition or witness of a TZE script,
+// fewer when the data is shorter
+func DecodeTzeDataPrefix(scriptHex string, n int) ([]byte, error) {
+	start := 2 * TzeHeaderSize
+	end := start + 2*n
+	if end > len(scriptHex) {
+		end = len(scriptHex)
+	}
+	if start > end {
+		return []byte{}, nil
+	}
+
+	prefix, err := hex.DecodeString(scriptHex[start:end])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode TZE data hex: %w", err)
+	}
+	return prefix, nil
+}
+
+// HashTzeData returns the hex SHA-256 of the precondition or witness of a TZE script, streaming the
+// hex through the hash so the decoded data is never held in memory
+func HashTzeData(scriptHex string) (string, error) {
+	data := ""
+	if len(scriptHex) > 2*TzeHeaderSize {
+		data = scriptHex[2*TzeHeaderSize:]
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, hex.NewDecoder(strings.NewReader(data))); err != nil {
+		return "", fmt.Errorf("failed to hash TZE data: %w", err)
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
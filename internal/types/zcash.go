@@ -102,6 +102,8 @@ type ZcashTransaction struct {
 	Time          int64  `json:"time,omitempty"`
 	BlockHash     string `json:"blockhash,omitempty"`
 	BlockTime     int64  `json:"blocktime,omitempty"`
+
+	parsed *ParsedTransaction // cached by Parsed
 }
 
 // Vin represents a transaction input
@@ -122,6 +124,8 @@ type Vin struct {
 type ScriptSig struct {
 	Asm string `json:"asm"`
 	Hex string `json:"hex"`
+
+	decoded []byte // cached by Bytes
 }
 
 // Vout represents a transaction output
@@ -138,7 +142,24 @@ type ScriptPubKey struct {
 	Hex       string   `json:"hex"`
 	ReqSigs   int      `json:"reqSigs,omitempty"`
 	Type      string   `json:"type"`
-	Addresses []string `json:"addresses,omitempty"`
+	Addresses []string `json:"addresses,omitempty"` // older zcashd
+	Address   string   `json:"address,omitempty"`   // newer zcashd, single-address scripts only
+
+	decoded []byte // cached by Bytes
+}
+
+// AddressList returns the output's addresses, whichever of addresses or address the node returned
+func (s *ScriptPubKey) AddressList() []string {
+	if s == nil {
+		return nil
+	}
+	if len(s.Addresses) > 0 {
+		return s.Addresses
+	}
+	if s.Address != "" {
+		return []string{s.Address}
+	}
+	return nil
 }
 
 // ShieldedSpend represents a Sapling spend
@@ -200,23 +221,7 @@ type OrchardAction struct {
 // IsTZETransaction checks if a transaction is a TZE transaction
 // TZE transactions are identified by scriptSig or scriptPubKey hex starting with "ff"
 func (tx *ZcashTransaction) IsTZETransaction() bool {
-	// Check inputs for TZE (scriptSig hex starts with "ff")
-	for _, vin := range tx.Vin {
-		if vin.ScriptSig != nil && len(vin.ScriptSig.Hex) >= 2 &&
-			vin.ScriptSig.Hex[:2] == "ff" {
-			return true
-		}
-	}
-
-	// Check outputs for TZE (scriptPubKey hex starts with "ff")
-	for _, vout := range tx.Vout {
-		if vout.ScriptPubKey != nil && len(vout.ScriptPubKey.Hex) >= 2 &&
-			vout.ScriptPubKey.Hex[:2] == "ff" {
-			return true
-		}
-	}
-
-	return false
+	return tx.Parsed().IsTZE()
 }
 
 // IsCoinbase checks if a transaction is a coinbase transaction
@@ -227,11 +232,20 @@ func (tx *ZcashTransaction) IsCoinbase() bool {
 // HasTZEOutputs checks if any of the transaction outputs are TZE outputs
 // TZE outputs are identified by scriptPubKey hex starting with "ff"
 func (tx *ZcashTransaction) HasTZEOutputs() bool {
-	for _, vout := range tx.Vout {
-		if vout.ScriptPubKey != nil && len(vout.ScriptPubKey.Hex) >= 2 &&
-			vout.ScriptPubKey.Hex[:2] == "ff" {
-			return true
+	return len(tx.Parsed().TzeOutputs) > 0
+}
+
+// TZEExtensionID returns the extension_id of the first TZE script found in the transaction
+// Inputs are checked before outputs; the second return value is false if no valid TZE script exists
+// Format: ff <extension_id (4 bytes, big-endian)> <mode (4 bytes)> <data>
+func (tx *ZcashTransaction) TZEExtensionID() (int32, bool) {
+	parsed := tx.Parsed()
+	for _, scripts := range [][]TzeScript{parsed.TzeInputs, parsed.TzeOutputs} {
+		for _, script := range scripts {
+			if script.Header {
+				return script.Type, true
+			}
 		}
 	}
-	return false
+	return 0, false
 }
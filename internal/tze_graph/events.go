@@ -0,0 +1,29 @@
+package tze_graph
+
+import (
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/events"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/logging"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/types"
+)
+
+// publishTransactionEvents notifies event subscribers of the TZE outputs of a committed
+// transaction, for rows written outside block indexing such as a retried transaction
+func publishTransactionEvents(block *types.ZcashBlock, tx *types.ZcashTransaction) {
+	if !events.HasSubscribers() || !tx.HasTZEOutputs() {
+		return
+	}
+
+	outputs, err := GetTzeOutputs(tx.TxID)
+	if err != nil {
+		logging.Errorf(logging.ModuleTzeGraph, "Failed to read TZE outputs of %s for events: %v", tx.TxID, err)
+		return
+	}
+	for _, output := range outputs {
+		events.Publish(events.Event{
+			Topic:       events.TopicTzeOutputs,
+			BlockHeight: block.Height,
+			BlockHash:   block.Hash,
+			Data:        output,
+		})
+	}
+}
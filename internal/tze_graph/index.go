@@ -2,19 +2,20 @@ package tze_graph
 
 import (
 	"context"
-	"encoding/hex"
 	"fmt"
-	"log"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/logging"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/retry_queue"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/types"
 )
 
 // IndexTzeGraph indexes TZE (Transparent Zcash Extension) graph data from a Zcash block
 // This function tracks TZE inputs, outputs, and their relationships
-// All TZE transactions in a block are indexed atomically in a single database transaction
-func IndexTzeGraph(block *types.ZcashBlock) error {
+// TZE rows are written through postgresTx, the block's transaction shared by every module
+func IndexTzeGraph(postgresTx pgx.Tx, block *types.ZcashBlock) error {
 	// Check if tze_graph module is enabled
 	if !config.IsModuleEnabled("TZE_GRAPH") {
 		return nil
@@ -33,73 +34,101 @@ func IndexTzeGraph(block *types.ZcashBlock) error {
 		return nil
 	}
 
-	log.Printf("Indexing TZE graph for block %d (hash: %s, %d TZE transactions)",
+	logging.Blockf(logging.ModuleTzeGraph, "Indexing TZE graph for block %d (hash: %s, %d TZE transactions)",
 		block.Height, block.Hash, tzeTransactionCount)
 
 	ctx := context.Background()
 
-	// Begin a database transaction for the entire block
-	postgresTx, err := postgres.DB.Begin(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to begin database transaction for block %d: %w", block.Height, err)
-	}
-	defer postgresTx.Rollback(ctx)
-
-	// Process each transaction in the block
+	// Process each TZE transaction in the block, keeping the row counters in step
+	txids := make([]string, 0, tzeTransactionCount)
 	for _, tx := range block.Tx {
-		// Only process TZE transactions
-		if !tx.IsTZETransaction() {
-			continue
-		}
-
-		if err := indexTzeTransaction(postgresTx, block, &tx); err != nil {
-			return fmt.Errorf("failed to index TZE transaction %s in block %d: %w",
-				tx.TxID, block.Height, err)
+		if tx.IsTZETransaction() {
+			txids = append(txids, tx.TxID)
 		}
 	}
+	scopes := tzeRowScopes(txids)
+	err := postgres.TrackRowCounts(ctx, postgresTx, scopes, func() error {
+		// Rows of the whole block are queued and written in one round-trip, except in lenient
+		// mode where each transaction is written in its own savepoint so it can be queued for retry
+		var batch postgres.WriteBatch
+		for _, tx := range block.Tx {
+			// Only process TZE transactions
+			if !tx.IsTZETransaction() {
+				continue
+			}
 
-	// Commit the transaction
-	if err := postgresTx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit database transaction for block %d: %w", block.Height, err)
+			// In lenient mode a transaction that fails to index is queued for retry instead of failing the block
+			var err error
+			if retry_queue.IsLenient() {
+				err = retry_queue.IndexOrQueue(ctx, postgresTx, logging.ModuleTzeGraph, block, &tx, retryTzeTransaction)
+			} else {
+				err = indexTzeTransaction(postgresTx, &batch, block, &tx)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to index TZE transaction %s in block %d: %w",
+					tx.TxID, block.Height, err)
+			}
+		}
+		if err := batch.Send(ctx, postgresTx); err != nil {
+			return fmt.Errorf("failed to store TZE transactions of block %d: %w", block.Height, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	log.Printf("Successfully indexed %d TZE transactions for block %d", tzeTransactionCount, block.Height)
+	logging.Blockf(logging.ModuleTzeGraph, "Successfully indexed %d TZE transactions for block %d", tzeTransactionCount, block.Height)
 	return nil
 }
 
-// indexTzeTransaction processes a single TZE transaction and its inputs/outputs
-func indexTzeTransaction(postgresTx DBTX, block *types.ZcashBlock, tx *types.ZcashTransaction) error {
+// indexTzeTransaction parses a single TZE transaction and queues its inputs/outputs, and their keys
+// for the block manifest of postgresTx
+func indexTzeTransaction(postgresTx postgres.Execer, batch *postgres.WriteBatch, block *types.ZcashBlock, tx *types.ZcashTransaction) error {
+	parsed := tx.Parsed()
+
 	// Process TZE outputs first
-	for _, vout := range tx.Vout {
-		if isTzeOutput(&vout) {
-			if err := indexTzeOutput(postgresTx, tx.TxID, &vout); err != nil {
-				return fmt.Errorf("failed to index TZE output %d: %w", vout.N, err)
-			}
+	for _, script := range parsed.TzeOutputs {
+		vout := &tx.Vout[script.Index]
+		if err := indexTzeOutput(batch, tx.TxID, vout); err != nil {
+			return fmt.Errorf("failed to index TZE output %d: %w", vout.N, err)
 		}
+		postgres.QueueManifestKeys(postgresTx, "TZE_GRAPH", "tze_outputs", "insert", fmt.Sprintf("%s:%d", tx.TxID, vout.N))
 	}
 
 	// Process TZE inputs
-	for i, vin := range tx.Vin {
-		if isTzeInput(&vin) {
-			if err := indexTzeInput(postgresTx, tx.TxID, i, &vin, block.Height); err != nil {
-				return fmt.Errorf("failed to index TZE input %d: %w", i, err)
-			}
+	for _, script := range parsed.TzeInputs {
+		input := &tx.Vin[script.Index]
+		if err := indexTzeInput(batch, tx.TxID, script, input, block.Height); err != nil {
+			return fmt.Errorf("failed to index TZE input %d: %w", script.Index, err)
 		}
+		postgres.QueueManifestKeys(postgresTx, "TZE_GRAPH", "tze_inputs", "insert", fmt.Sprintf("%s:%d", tx.TxID, script.Index))
+		postgres.QueueManifestKeys(postgresTx, "TZE_GRAPH", "tze_outputs", "spend", fmt.Sprintf("%s:%d", input.TxID, input.Vout))
 	}
 
 	return nil
 }
 
-// isTzeOutput checks if an output is a TZE output
-func isTzeOutput(vout *types.Vout) bool {
-	return vout.ScriptPubKey != nil && len(vout.ScriptPubKey.Hex) >= 2 &&
-		vout.ScriptPubKey.Hex[:2] == "ff"
+// tzeRowScopes returns the counted rows indexing the TZE transactions txids can add
+func tzeRowScopes(txids []string) []postgres.RowScope {
+	return []postgres.RowScope{
+		{Table: "tze_inputs", Column: "txid", Keys: txids},
+		{Table: "tze_outputs", Column: "txid", Keys: txids},
+	}
+}
+
+// retryTzeScopes returns the counted rows a retry of a TZE transaction can add
+func retryTzeScopes(tx *types.ZcashTransaction) []postgres.RowScope {
+	return tzeRowScopes([]string{tx.TxID})
 }
 
-// isTzeInput checks if an input is a TZE input
-func isTzeInput(vin *types.Vin) bool {
-	return vin.ScriptSig != nil && len(vin.ScriptSig.Hex) >= 2 &&
-		vin.ScriptSig.Hex[:2] == "ff"
+// retryTzeTransaction adapts indexTzeTransaction to the retry queue, writing the transaction's rows at once
+func retryTzeTransaction(postgresTx retry_queue.DBTX, block *types.ZcashBlock, tx *types.ZcashTransaction) error {
+	var batch postgres.WriteBatch
+	if err := indexTzeTransaction(postgresTx, &batch, block, tx); err != nil {
+		return err
+	}
+	return batch.Send(context.Background(), postgresTx)
 }
 
 // parseTzeData extracts TZE extension_id, mode, and data from a script byte array
@@ -131,15 +160,12 @@ func parseTzeData(scriptBytes []byte) (tzeType int32, tzeMode int32, data []byte
 	return tzeType, tzeMode, data, nil
 }
 
-// indexTzeOutput parses and stores a TZE output
-func indexTzeOutput(postgresTx DBTX, txid string, vout *types.Vout) error {
-	// Parse TZE data from scriptPubKey
-	scriptHex := vout.ScriptPubKey.Hex
-
-	// Decode the hex string
-	scriptBytes, err := hex.DecodeString(scriptHex)
+// indexTzeOutput parses and queues a TZE output
+func indexTzeOutput(batch *postgres.WriteBatch, txid string, vout *types.Vout) error {
+	// Decode the scriptPubKey (shared with the other modules indexing this block)
+	scriptBytes, err := vout.ScriptPubKey.Bytes()
 	if err != nil {
-		return fmt.Errorf("failed to decode scriptPubKey hex: %w", err)
+		return err
 	}
 
 	// Parse TZE fields
@@ -149,8 +175,8 @@ func indexTzeOutput(postgresTx DBTX, txid string, vout *types.Vout) error {
 	}
 
 	// Store the TZE output
-	err = StoreTzeOutput(
-		postgresTx,
+	QueueTzeOutput(
+		batch,
 		txid,
 		int(vout.N),
 		vout.ValueZat,
@@ -158,26 +184,18 @@ func indexTzeOutput(postgresTx DBTX, txid string, vout *types.Vout) error {
 		tzeMode,
 		precondition,
 	)
-	if err != nil {
-		return fmt.Errorf("failed to store TZE output: %w", err)
-	}
 
 	return nil
 }
 
-// indexTzeInput parses and stores a TZE input
-func indexTzeInput(postgresTx DBTX, txid string, vin int, input *types.Vin, blockHeight int64) error {
-	// Parse TZE data from scriptSig
-	scriptHex := input.ScriptSig.Hex
-
-	// Decode the hex string
-	scriptBytes, err := hex.DecodeString(scriptHex)
-	if err != nil {
-		return fmt.Errorf("failed to decode scriptSig hex: %w", err)
+// indexTzeInput queues a TZE input, whose header was classified by the block's parse stage
+// Only the witness hash is stored, so the witness is hashed straight from the hex without decoding it
+func indexTzeInput(batch *postgres.WriteBatch, txid string, script types.TzeScript, input *types.Vin, blockHeight int64) error {
+	if !script.Header {
+		return fmt.Errorf("failed to parse TZE input data: invalid TZE script header")
 	}
 
-	// Parse TZE fields
-	tzeType, tzeMode, _, err := parseTzeData(scriptBytes)
+	witnessHash, err := types.HashTzeData(input.ScriptSig.Hex)
 	if err != nil {
 		return fmt.Errorf("failed to parse TZE input data: %w", err)
 	}
@@ -191,20 +209,18 @@ func indexTzeInput(postgresTx DBTX, txid string, vin int, input *types.Vin, bloc
 	value := int64(0)
 
 	// Store the TZE input
-	err = StoreTzeInput(
-		postgresTx,
+	QueueTzeInput(
+		batch,
 		txid,
-		vin,
+		script.Index,
 		value,
 		prevTxid,
 		prevVout,
-		tzeType,
-		tzeMode,
+		script.Type,
+		script.Mode,
+		witnessHash,
 		blockHeight,
 	)
-	if err != nil {
-		return fmt.Errorf("failed to store TZE input: %w", err)
-	}
 
 	return nil
 }
@@ -1,27 +1,78 @@
 package tze_graph
 
+import "encoding/json"
+
 // TzeInput represents a TZE input in a transaction
 type TzeInput struct {
-	TxID     string `json:"txid" db:"txid"`
-	Vin      int    `json:"vin" db:"vin"`
-	Value    int64  `json:"value" db:"value"`
-	PrevTxID string `json:"prev_txid" db:"prev_txid"`
-	PrevVout int    `json:"prev_vout" db:"prev_vout"`
-	TzeType  int32  `json:"tze_type" db:"tze_type"` // 4-byte extension_id (0=demo, 1=stark_verify)
-	TzeMode  int32  `json:"tze_mode" db:"tze_mode"` // 4-byte mode (demo: 0=open, 1=close; stark_verify: 0=initialize, 1=verify)
+	TxID        string  `json:"txid" db:"txid"`
+	Vin         int     `json:"vin" db:"vin"`
+	Value       int64   `json:"value" db:"value"`
+	PrevTxID    string  `json:"prev_txid" db:"prev_txid"`
+	PrevVout    int     `json:"prev_vout" db:"prev_vout"`
+	TzeType     int32   `json:"tze_type" db:"tze_type"`         // 4-byte extension_id (0=demo, 1=stark_verify)
+	TzeMode     int32   `json:"tze_mode" db:"tze_mode"`         // 4-byte mode (demo: 0=open, 1=close; stark_verify: 0=initialize, 1=verify)
+	WitnessHash *string `json:"witness_hash" db:"witness_hash"` // SHA-256 of the witness data; null for inputs indexed before it was recorded
 }
 
 // TzeOutput represents a TZE output in a transaction
 type TzeOutput struct {
-	TxID          string  `json:"txid" db:"txid"`
-	Vout          int     `json:"vout" db:"vout"`
-	Value         int64   `json:"value" db:"value"`
-	SpentByTxID   *string `json:"spent_by_txid,omitempty" db:"spent_by_txid"`
-	SpentByVin    *int    `json:"spent_by_vin,omitempty" db:"spent_by_vin"`
-	SpentAtHeight *int64  `json:"spent_at_height,omitempty" db:"spent_at_height"`
-	TzeType       int32   `json:"tze_type" db:"tze_type"`         // 4-byte extension_id (0=demo, 1=stark_verify)
-	TzeMode       int32   `json:"tze_mode" db:"tze_mode"`         // 4-byte mode (demo: 0=open, 1=close; stark_verify: 0=initialize, 1=verify)
-	Precondition  []byte  `json:"precondition" db:"precondition"` // TZE precondition data
+	TxID             string  `json:"txid" db:"txid"`
+	Vout             int     `json:"vout" db:"vout"`
+	Value            int64   `json:"value" db:"value"`
+	SpentByTxID      *string `json:"spent_by_txid,omitempty" db:"spent_by_txid"`
+	SpentByVin       *int    `json:"spent_by_vin,omitempty" db:"spent_by_vin"`
+	SpentAtHeight    *int64  `json:"spent_at_height,omitempty" db:"spent_at_height"`
+	TzeType          int32   `json:"tze_type" db:"tze_type"`                   // 4-byte extension_id (0=demo, 1=stark_verify)
+	TzeMode          int32   `json:"tze_mode" db:"tze_mode"`                   // 4-byte mode (demo: 0=open, 1=close; stark_verify: 0=initialize, 1=verify)
+	Precondition     []byte  `json:"precondition" db:"precondition"`           // TZE precondition data
+	PreconditionHash *string `json:"precondition_hash" db:"precondition_hash"` // SHA-256 of the precondition data; null for outputs indexed before it was recorded
+}
+
+// MarshalJSON adds tze_type_name and tze_mode_name next to the raw values so clients don't need the mapping
+func (i TzeInput) MarshalJSON() ([]byte, error) {
+	type tzeInput TzeInput
+	return json.Marshal(struct {
+		tzeInput
+		TzeTypeName string `json:"tze_type_name"`
+		TzeModeName string `json:"tze_mode_name"`
+	}{
+		tzeInput:    tzeInput(i),
+		TzeTypeName: TzeType(i.TzeType).String(),
+		TzeModeName: TzeMode(i.TzeMode).String(TzeType(i.TzeType)),
+	})
+}
+
+// MarshalJSON adds tze_type_name and tze_mode_name next to the raw values
+func (o TzeOutput) MarshalJSON() ([]byte, error) {
+	type tzeOutput TzeOutput
+	return json.Marshal(struct {
+		tzeOutput
+		TzeTypeName string `json:"tze_type_name"`
+		TzeModeName string `json:"tze_mode_name"`
+	}{
+		tzeOutput:   tzeOutput(o),
+		TzeTypeName: TzeType(o.TzeType).String(),
+		TzeModeName: TzeMode(o.TzeMode).String(TzeType(o.TzeType)),
+	})
+}
+
+// TzeExtension summarizes how often a TZE extension id has been observed
+type TzeExtension struct {
+	TzeType     int32  `json:"tze_type" db:"tze_type"`         // raw 4-byte extension_id
+	Name        string `json:"name" db:"-"`                    // demo, stark_verify, or unknown
+	OutputCount int64  `json:"output_count" db:"output_count"` // number of TZE outputs using this extension
+	InputCount  int64  `json:"input_count" db:"input_count"`   // number of TZE inputs using this extension
+}
+
+// DemoChannelStats aggregates demo extension activity
+// A channel is a demo TZE output; it is open while unspent and closed once a close-mode input spends it
+type DemoChannelStats struct {
+	OpenChannels         int64    `json:"open_channels" db:"open_channels"`                   // unspent demo outputs
+	ValueLocked          int64    `json:"value_locked" db:"value_locked"`                     // zatoshis held in unspent demo outputs
+	OpenEvents           int64    `json:"open_events" db:"open_events"`                       // open-mode demo outputs ever created
+	CloseEvents          int64    `json:"close_events" db:"close_events"`                     // close-mode demo inputs
+	AvgLifetimeBlocks    *float64 `json:"avg_lifetime_blocks" db:"avg_lifetime_blocks"`       // mean blocks between creation and spend; null without TX_GRAPH
+	ClosedChannelsSample int64    `json:"closed_channels_sample" db:"closed_channels_sample"` // spent demo outputs the average is taken over
 }
 
 // TzeType represents the type of TZE transaction (4-byte extension_id)
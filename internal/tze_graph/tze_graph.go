@@ -2,18 +2,93 @@ package tze_graph
 
 import (
 	"context"
+	"crypto/sha256"
 	"fmt"
-	"log"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/logging"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/retry_queue"
 )
 
 func init() {
 	// Register this module's schema initialization with the postgres package
 	postgres.RegisterModuleSchema("TZE_GRAPH", InitSchema)
+	// Register how transactions queued in lenient mode are retried
+	retry_queue.RegisterRetrier("TZE_GRAPH", retry_queue.Retrier{
+		Index:   retryTzeTransaction,
+		Scopes:  retryTzeScopes,
+		Publish: publishTransactionEvents,
+	})
+}
+
+// redundantIndexes are created by default but dropped when database.drop_redundant_indexes
+// is enabled, since txid leads the primary keys and tze_type leads the (tze_type, tze_mode) indexes
+var redundantIndexes = []postgres.RedundantIndex{
+	{
+		Name:       "idx_tze_inputs_txid",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_tze_inputs_txid ON tze_inputs(txid);`,
+	},
+	{
+		Name:       "idx_tze_inputs_type",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_tze_inputs_type ON tze_inputs(tze_type);`,
+	},
+	{
+		Name:       "idx_tze_outputs_txid",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_tze_outputs_txid ON tze_outputs(txid);`,
+	},
+	{
+		Name:       "idx_tze_outputs_type",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_tze_outputs_type ON tze_outputs(tze_type);`,
+	},
+}
+
+// secondaryIndexes serve TZE input and output queries and can all be deferred during initial sync
+var secondaryIndexes = []postgres.SecondaryIndex{
+	// Indexes for tze_inputs
+	{
+		Name:       "idx_tze_inputs_prev",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_tze_inputs_prev ON tze_inputs(prev_txid, prev_vout);`,
+	},
+	{
+		Name:       "idx_tze_inputs_mode",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_tze_inputs_mode ON tze_inputs(tze_mode);`,
+	},
+	{
+		Name:       "idx_tze_inputs_type_mode",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_tze_inputs_type_mode ON tze_inputs(tze_type, tze_mode);`,
+	},
+	{
+		Name:       "idx_tze_inputs_witness_hash",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_tze_inputs_witness_hash ON tze_inputs(witness_hash) WHERE witness_hash IS NOT NULL;`,
+	},
+	// Indexes for tze_outputs
+	{
+		Name:       "idx_tze_outputs_spent_by",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_tze_outputs_spent_by ON tze_outputs(spent_by_txid) WHERE spent_by_txid IS NOT NULL;`,
+	},
+	{
+		Name:       "idx_tze_outputs_unspent",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_tze_outputs_unspent ON tze_outputs(txid, vout) WHERE spent_by_txid IS NULL;`,
+	},
+	{
+		Name:       "idx_tze_outputs_mode",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_tze_outputs_mode ON tze_outputs(tze_mode);`,
+	},
+	{
+		Name:       "idx_tze_outputs_type_mode",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_tze_outputs_type_mode ON tze_outputs(tze_type, tze_mode);`,
+	},
+	{
+		Name:       "idx_tze_outputs_value",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_tze_outputs_value ON tze_outputs(value);`,
+	},
+	{
+		Name:       "idx_tze_outputs_precondition_hash",
+		Definition: `CREATE INDEX IF NOT EXISTS idx_tze_outputs_precondition_hash ON tze_outputs(precondition_hash) WHERE precondition_hash IS NOT NULL;`,
+	},
 }
 
 func InitSchema() error {
@@ -27,16 +102,10 @@ func InitSchema() error {
 			prev_vout INT NOT NULL,
 			tze_type INT NOT NULL,  -- 4-byte extension_id (0=demo, 1=stark_verify)
 			tze_mode INT NOT NULL,  -- 4-byte mode (demo: 0=open, 1=close; stark_verify: 0=initialize, 1=verify)
+			witness_hash VARCHAR(64),  -- SHA-256 of the witness data
 			PRIMARY KEY (txid, vin)
 		);
 
-		-- Indexes for tze_inputs
-		CREATE INDEX IF NOT EXISTS idx_tze_inputs_txid ON tze_inputs(txid);
-		CREATE INDEX IF NOT EXISTS idx_tze_inputs_prev ON tze_inputs(prev_txid, prev_vout);
-		CREATE INDEX IF NOT EXISTS idx_tze_inputs_type ON tze_inputs(tze_type);
-		CREATE INDEX IF NOT EXISTS idx_tze_inputs_mode ON tze_inputs(tze_mode);
-		CREATE INDEX IF NOT EXISTS idx_tze_inputs_type_mode ON tze_inputs(tze_type, tze_mode);
-
 		-- TZE Outputs table
 		CREATE TABLE IF NOT EXISTS tze_outputs (
 			txid VARCHAR(64) NOT NULL,
@@ -48,19 +117,13 @@ func InitSchema() error {
 			tze_type INT NOT NULL,  -- 4-byte extension_id (0=demo, 1=stark_verify)
 			tze_mode INT NOT NULL,  -- 4-byte mode (demo: 0=open, 1=close; stark_verify: 0=initialize, 1=verify)
 			precondition BYTEA,
+			precondition_hash VARCHAR(64),  -- SHA-256 of the precondition data, kept even when an oversized precondition is dropped
 			PRIMARY KEY (txid, vout)
 		);
 
-		-- Indexes for tze_outputs
-		CREATE INDEX IF NOT EXISTS idx_tze_outputs_txid ON tze_outputs(txid);
-		CREATE INDEX IF NOT EXISTS idx_tze_outputs_spent_by ON tze_outputs(spent_by_txid)
-			WHERE spent_by_txid IS NOT NULL;
-		CREATE INDEX IF NOT EXISTS idx_tze_outputs_unspent ON tze_outputs(txid, vout)
-			WHERE spent_by_txid IS NULL;
-		CREATE INDEX IF NOT EXISTS idx_tze_outputs_type ON tze_outputs(tze_type);
-		CREATE INDEX IF NOT EXISTS idx_tze_outputs_mode ON tze_outputs(tze_mode);
-		CREATE INDEX IF NOT EXISTS idx_tze_outputs_type_mode ON tze_outputs(tze_type, tze_mode);
-		CREATE INDEX IF NOT EXISTS idx_tze_outputs_value ON tze_outputs(value);
+		-- Columns added after the initial schema
+		ALTER TABLE tze_inputs ADD COLUMN IF NOT EXISTS witness_hash VARCHAR(64);
+		ALTER TABLE tze_outputs ADD COLUMN IF NOT EXISTS precondition_hash VARCHAR(64);
 	`
 
 	_, err := postgres.DB.Exec(context.Background(), schema)
@@ -68,6 +131,14 @@ func InitSchema() error {
 		return fmt.Errorf("failed to create tze_graph schema: %w", err)
 	}
 
+	if err := postgres.InitSecondaryIndexes(secondaryIndexes); err != nil {
+		return fmt.Errorf("failed to init tze_graph indexes: %w", err)
+	}
+
+	if err := postgres.InitRedundantIndexes(redundantIndexes); err != nil {
+		return fmt.Errorf("failed to init tze_graph redundant indexes: %w", err)
+	}
+
 	return nil
 }
 
@@ -87,8 +158,9 @@ func ValidatePreconditionSize(precondition []byte) error {
 
 // GetTzeInputs retrieves all inputs for a transaction
 func GetTzeInputs(txid string) ([]TzeInput, error) {
-	inputs, err := postgres.PostgresQuery[TzeInput](
-		`SELECT txid, vin, value, prev_txid, prev_vout, tze_type, tze_mode
+	inputs, err := postgres.PostgresQueryCtx[TzeInput](
+		context.Background(), readDB,
+		`SELECT txid, vin, value, prev_txid, prev_vout, tze_type, tze_mode, witness_hash
 		 FROM tze_inputs
 		 WHERE txid = $1
 		 ORDER BY vin`,
@@ -103,8 +175,9 @@ func GetTzeInputs(txid string) ([]TzeInput, error) {
 
 // GetTzeInput retrieves a specific input by txid and vin
 func GetTzeInput(txid string, vin int) (*TzeInput, error) {
-	input, err := postgres.PostgresQueryOne[TzeInput](
-		`SELECT txid, vin, value, prev_txid, prev_vout, tze_type, tze_mode
+	input, err := postgres.PostgresQueryOneCtx[TzeInput](
+		context.Background(), readDB,
+		`SELECT txid, vin, value, prev_txid, prev_vout, tze_type, tze_mode, witness_hash
 		 FROM tze_inputs
 		 WHERE txid = $1 AND vin = $2`,
 		txid, vin,
@@ -122,8 +195,9 @@ func GetTzeInput(txid string, vin int) (*TzeInput, error) {
 
 // GetTzeInputsByType retrieves all inputs of a specific TZE type with pagination
 func GetTzeInputsByType(tzeType TzeType, limit, offset int) ([]TzeInput, error) {
-	inputs, err := postgres.PostgresQuery[TzeInput](
-		`SELECT txid, vin, value, prev_txid, prev_vout, tze_type, tze_mode
+	inputs, err := postgres.PostgresQueryCtx[TzeInput](
+		context.Background(), readDB,
+		`SELECT txid, vin, value, prev_txid, prev_vout, tze_type, tze_mode, witness_hash
 		 FROM tze_inputs
 		 WHERE tze_type = $1
 		 ORDER BY txid, vin
@@ -139,8 +213,9 @@ func GetTzeInputsByType(tzeType TzeType, limit, offset int) ([]TzeInput, error)
 
 // GetTzeInputsByMode retrieves all inputs of a specific TZE mode with pagination
 func GetTzeInputsByMode(tzeMode TzeMode, limit, offset int) ([]TzeInput, error) {
-	inputs, err := postgres.PostgresQuery[TzeInput](
-		`SELECT txid, vin, value, prev_txid, prev_vout, tze_type, tze_mode
+	inputs, err := postgres.PostgresQueryCtx[TzeInput](
+		context.Background(), readDB,
+		`SELECT txid, vin, value, prev_txid, prev_vout, tze_type, tze_mode, witness_hash
 		 FROM tze_inputs
 		 WHERE tze_mode = $1
 		 ORDER BY txid, vin
@@ -156,8 +231,9 @@ func GetTzeInputsByMode(tzeMode TzeMode, limit, offset int) ([]TzeInput, error)
 
 // GetTzeInputsByTypeAndMode retrieves all inputs matching both type and mode with pagination
 func GetTzeInputsByTypeAndMode(tzeType TzeType, tzeMode TzeMode, limit, offset int) ([]TzeInput, error) {
-	inputs, err := postgres.PostgresQuery[TzeInput](
-		`SELECT txid, vin, value, prev_txid, prev_vout, tze_type, tze_mode
+	inputs, err := postgres.PostgresQueryCtx[TzeInput](
+		context.Background(), readDB,
+		`SELECT txid, vin, value, prev_txid, prev_vout, tze_type, tze_mode, witness_hash
 		 FROM tze_inputs
 		 WHERE tze_type = $1 AND tze_mode = $2
 		 ORDER BY txid, vin
@@ -173,8 +249,9 @@ func GetTzeInputsByTypeAndMode(tzeType TzeType, tzeMode TzeMode, limit, offset i
 
 // GetTzeInputsByPrevOutput retrieves all inputs spending a specific previous output
 func GetTzeInputsByPrevOutput(prevTxid string, prevVout int) ([]TzeInput, error) {
-	inputs, err := postgres.PostgresQuery[TzeInput](
-		`SELECT txid, vin, value, prev_txid, prev_vout, tze_type, tze_mode
+	inputs, err := postgres.PostgresQueryCtx[TzeInput](
+		context.Background(), readDB,
+		`SELECT txid, vin, value, prev_txid, prev_vout, tze_type, tze_mode, witness_hash
 		 FROM tze_inputs
 		 WHERE prev_txid = $1 AND prev_vout = $2
 		 ORDER BY txid, vin`,
@@ -187,15 +264,34 @@ func GetTzeInputsByPrevOutput(prevTxid string, prevVout int) ([]TzeInput, error)
 	return inputs, nil
 }
 
+// GetTzeInputsByWitnessHash retrieves all inputs whose witness data has the given SHA-256 hash with pagination
+func GetTzeInputsByWitnessHash(witnessHash string, limit, offset int) ([]TzeInput, error) {
+	inputs, err := postgres.PostgresQueryCtx[TzeInput](
+		context.Background(), readDB,
+		`SELECT txid, vin, value, prev_txid, prev_vout, tze_type, tze_mode, witness_hash
+		 FROM tze_inputs
+		 WHERE witness_hash = $1
+		 ORDER BY txid, vin
+		 LIMIT $2 OFFSET $3`,
+		witnessHash, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tze inputs by witness hash: %w", err)
+	}
+
+	return inputs, nil
+}
+
 // ============================================================================
 // TZE OUTPUT QUERIES
 // ============================================================================
 
 // GetTzeOutputs retrieves all outputs for a transaction
 func GetTzeOutputs(txid string) ([]TzeOutput, error) {
-	outputs, err := postgres.PostgresQuery[TzeOutput](
+	outputs, err := postgres.PostgresQueryCtx[TzeOutput](
+		context.Background(), readDB,
 		`SELECT txid, vout, value, spent_by_txid, spent_by_vin, spent_at_height,
-		        tze_type, tze_mode, precondition
+		        tze_type, tze_mode, precondition, precondition_hash
 		 FROM tze_outputs
 		 WHERE txid = $1
 		 ORDER BY vout`,
@@ -210,9 +306,10 @@ func GetTzeOutputs(txid string) ([]TzeOutput, error) {
 
 // GetTzeOutput retrieves a specific output by txid and vout
 func GetTzeOutput(txid string, vout int) (*TzeOutput, error) {
-	output, err := postgres.PostgresQueryOne[TzeOutput](
+	output, err := postgres.PostgresQueryOneCtx[TzeOutput](
+		context.Background(), readDB,
 		`SELECT txid, vout, value, spent_by_txid, spent_by_vin, spent_at_height,
-		        tze_type, tze_mode, precondition
+		        tze_type, tze_mode, precondition, precondition_hash
 		 FROM tze_outputs
 		 WHERE txid = $1 AND vout = $2`,
 		txid, vout,
@@ -230,9 +327,10 @@ func GetTzeOutput(txid string, vout int) (*TzeOutput, error) {
 
 // GetUnspentTzeOutputs retrieves all unspent outputs for a transaction
 func GetUnspentTzeOutputs(txid string) ([]TzeOutput, error) {
-	outputs, err := postgres.PostgresQuery[TzeOutput](
+	outputs, err := postgres.PostgresQueryCtx[TzeOutput](
+		context.Background(), readDB,
 		`SELECT txid, vout, value, spent_by_txid, spent_by_vin, spent_at_height,
-		        tze_type, tze_mode, precondition
+		        tze_type, tze_mode, precondition, precondition_hash
 		 FROM tze_outputs
 		 WHERE txid = $1 AND spent_by_txid IS NULL
 		 ORDER BY vout`,
@@ -247,9 +345,10 @@ func GetUnspentTzeOutputs(txid string) ([]TzeOutput, error) {
 
 // GetAllUnspentTzeOutputs retrieves all unspent TZE outputs with pagination
 func GetAllUnspentTzeOutputs(limit, offset int) ([]TzeOutput, error) {
-	outputs, err := postgres.PostgresQuery[TzeOutput](
+	outputs, err := postgres.PostgresQueryCtx[TzeOutput](
+		context.Background(), readDB,
 		`SELECT txid, vout, value, spent_by_txid, spent_by_vin, spent_at_height,
-		        tze_type, tze_mode, precondition
+		        tze_type, tze_mode, precondition, precondition_hash
 		 FROM tze_outputs
 		 WHERE spent_by_txid IS NULL
 		 ORDER BY txid, vout
@@ -265,9 +364,10 @@ func GetAllUnspentTzeOutputs(limit, offset int) ([]TzeOutput, error) {
 
 // GetTzeOutputsByType retrieves all outputs of a specific TZE type with pagination
 func GetTzeOutputsByType(tzeType TzeType, limit, offset int) ([]TzeOutput, error) {
-	outputs, err := postgres.PostgresQuery[TzeOutput](
+	outputs, err := postgres.PostgresQueryCtx[TzeOutput](
+		context.Background(), readDB,
 		`SELECT txid, vout, value, spent_by_txid, spent_by_vin, spent_at_height,
-		        tze_type, tze_mode, precondition
+		        tze_type, tze_mode, precondition, precondition_hash
 		 FROM tze_outputs
 		 WHERE tze_type = $1
 		 ORDER BY txid, vout
@@ -283,9 +383,10 @@ func GetTzeOutputsByType(tzeType TzeType, limit, offset int) ([]TzeOutput, error
 
 // GetTzeOutputsByMode retrieves all outputs of a specific TZE mode with pagination
 func GetTzeOutputsByMode(tzeMode TzeMode, limit, offset int) ([]TzeOutput, error) {
-	outputs, err := postgres.PostgresQuery[TzeOutput](
+	outputs, err := postgres.PostgresQueryCtx[TzeOutput](
+		context.Background(), readDB,
 		`SELECT txid, vout, value, spent_by_txid, spent_by_vin, spent_at_height,
-		        tze_type, tze_mode, precondition
+		        tze_type, tze_mode, precondition, precondition_hash
 		 FROM tze_outputs
 		 WHERE tze_mode = $1
 		 ORDER BY txid, vout
@@ -301,9 +402,10 @@ func GetTzeOutputsByMode(tzeMode TzeMode, limit, offset int) ([]TzeOutput, error
 
 // GetTzeOutputsByTypeAndMode retrieves all outputs matching both type and mode with pagination
 func GetTzeOutputsByTypeAndMode(tzeType TzeType, tzeMode TzeMode, limit, offset int) ([]TzeOutput, error) {
-	outputs, err := postgres.PostgresQuery[TzeOutput](
+	outputs, err := postgres.PostgresQueryCtx[TzeOutput](
+		context.Background(), readDB,
 		`SELECT txid, vout, value, spent_by_txid, spent_by_vin, spent_at_height,
-		        tze_type, tze_mode, precondition
+		        tze_type, tze_mode, precondition, precondition_hash
 		 FROM tze_outputs
 		 WHERE tze_type = $1 AND tze_mode = $2
 		 ORDER BY txid, vout
@@ -319,9 +421,10 @@ func GetTzeOutputsByTypeAndMode(tzeType TzeType, tzeMode TzeMode, limit, offset
 
 // GetUnspentTzeOutputsByType retrieves all unspent outputs of a specific type with pagination
 func GetUnspentTzeOutputsByType(tzeType TzeType, limit, offset int) ([]TzeOutput, error) {
-	outputs, err := postgres.PostgresQuery[TzeOutput](
+	outputs, err := postgres.PostgresQueryCtx[TzeOutput](
+		context.Background(), readDB,
 		`SELECT txid, vout, value, spent_by_txid, spent_by_vin, spent_at_height,
-		        tze_type, tze_mode, precondition
+		        tze_type, tze_mode, precondition, precondition_hash
 		 FROM tze_outputs
 		 WHERE tze_type = $1 AND spent_by_txid IS NULL
 		 ORDER BY txid, vout
@@ -337,9 +440,10 @@ func GetUnspentTzeOutputsByType(tzeType TzeType, limit, offset int) ([]TzeOutput
 
 // GetUnspentTzeOutputsByTypeAndMode retrieves all unspent outputs matching type and mode
 func GetUnspentTzeOutputsByTypeAndMode(tzeType TzeType, tzeMode TzeMode, limit, offset int) ([]TzeOutput, error) {
-	outputs, err := postgres.PostgresQuery[TzeOutput](
+	outputs, err := postgres.PostgresQueryCtx[TzeOutput](
+		context.Background(), readDB,
 		`SELECT txid, vout, value, spent_by_txid, spent_by_vin, spent_at_height,
-		        tze_type, tze_mode, precondition
+		        tze_type, tze_mode, precondition, precondition_hash
 		 FROM tze_outputs
 		 WHERE tze_type = $1 AND tze_mode = $2 AND spent_by_txid IS NULL
 		 ORDER BY txid, vout
@@ -355,9 +459,10 @@ func GetUnspentTzeOutputsByTypeAndMode(tzeType TzeType, tzeMode TzeMode, limit,
 
 // GetSpentTzeOutputs retrieves all spent outputs with pagination
 func GetSpentTzeOutputs(limit, offset int) ([]TzeOutput, error) {
-	outputs, err := postgres.PostgresQuery[TzeOutput](
+	outputs, err := postgres.PostgresQueryCtx[TzeOutput](
+		context.Background(), readDB,
 		`SELECT txid, vout, value, spent_by_txid, spent_by_vin, spent_at_height,
-		        tze_type, tze_mode, precondition
+		        tze_type, tze_mode, precondition, precondition_hash
 		 FROM tze_outputs
 		 WHERE spent_by_txid IS NOT NULL
 		 ORDER BY spent_at_height DESC, txid, vout
@@ -373,9 +478,10 @@ func GetSpentTzeOutputs(limit, offset int) ([]TzeOutput, error) {
 
 // GetTzeOutputsByValue retrieves outputs with value greater than or equal to minimum value
 func GetTzeOutputsByValue(minValue int64, limit, offset int) ([]TzeOutput, error) {
-	outputs, err := postgres.PostgresQuery[TzeOutput](
+	outputs, err := postgres.PostgresQueryCtx[TzeOutput](
+		context.Background(), readDB,
 		`SELECT txid, vout, value, spent_by_txid, spent_by_vin, spent_at_height,
-		        tze_type, tze_mode, precondition
+		        tze_type, tze_mode, precondition, precondition_hash
 		 FROM tze_outputs
 		 WHERE value >= $1
 		 ORDER BY value DESC, txid, vout
@@ -389,6 +495,54 @@ func GetTzeOutputsByValue(minValue int64, limit, offset int) ([]TzeOutput, error
 	return outputs, nil
 }
 
+// GetTzeOutputsByPreconditionHash retrieves all outputs whose precondition data has the given SHA-256 hash with pagination
+func GetTzeOutputsByPreconditionHash(preconditionHash string, limit, offset int) ([]TzeOutput, error) {
+	outputs, err := postgres.PostgresQueryCtx[TzeOutput](
+		context.Background(), readDB,
+		`SELECT txid, vout, value, spent_by_txid, spent_by_vin, spent_at_height,
+		        tze_type, tze_mode, precondition, precondition_hash
+		 FROM tze_outputs
+		 WHERE precondition_hash = $1
+		 ORDER BY txid, vout
+		 LIMIT $2 OFFSET $3`,
+		preconditionHash, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tze outputs by precondition hash: %w", err)
+	}
+
+	return outputs, nil
+}
+
+// ============================================================================
+// TZE EXTENSION QUERIES
+// ============================================================================
+
+// GetTzeExtensions returns every extension id observed in TZE inputs or outputs with counts
+// This includes extension ids that ParseTzeType does not recognize
+func GetTzeExtensions() ([]TzeExtension, error) {
+	extensions, err := postgres.PostgresQueryCtx[TzeExtension](
+		context.Background(), readDB,
+		`SELECT tze_type, SUM(output_count)::BIGINT AS output_count, SUM(input_count)::BIGINT AS input_count
+		 FROM (
+			SELECT tze_type, COUNT(*) AS output_count, 0 AS input_count FROM tze_outputs GROUP BY tze_type
+			UNION ALL
+			SELECT tze_type, 0 AS output_count, COUNT(*) AS input_count FROM tze_inputs GROUP BY tze_type
+		 ) counts
+		 GROUP BY tze_type
+		 ORDER BY tze_type`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tze extensions: %w", err)
+	}
+
+	for i := range extensions {
+		extensions[i].Name = TzeType(extensions[i].TzeType).String()
+	}
+
+	return extensions, nil
+}
+
 // ============================================================================
 // TZE STORAGE FUNCTIONS
 // ============================================================================
@@ -401,66 +555,169 @@ type DBTX interface {
 	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
 }
 
+// GetTzeGraph returns the txids reachable from txid within depth hops, following TZE links only:
+// forward through tze_outputs spent_by_txid and backward through tze_inputs prev_txid
+func GetTzeGraph(txid string, depth int) ([]string, error) {
+	query := `
+		WITH RECURSIVE tze_graph AS (
+			-- Non-recursive term: Start with the given transaction
+			SELECT $1::VARCHAR AS txid, 0 AS depth
+
+			UNION
+
+			-- Recursive term: Follow TZE spends in both directions
+			SELECT DISTINCT connected_tx AS txid, g.depth + 1 AS depth
+			FROM tze_graph g
+			CROSS JOIN LATERAL (
+				-- Transactions that spent TZE outputs from current level (forward traversal)
+				SELECT o.spent_by_txid AS connected_tx
+				FROM tze_outputs o
+				WHERE o.txid = g.txid AND o.spent_by_txid IS NOT NULL
+
+				UNION
+
+				-- Transactions whose TZE outputs were spent by current level (backward traversal)
+				SELECT i.prev_txid AS connected_tx
+				FROM tze_inputs i
+				WHERE i.txid = g.txid
+			) AS connections
+			WHERE g.depth < $2 AND connected_tx IS NOT NULL
+		)
+		SELECT DISTINCT txid FROM tze_graph WHERE txid IS NOT NULL ORDER BY txid
+	`
+
+	type result struct {
+		TxID string `db:"txid"`
+	}
+
+	results, err := postgres.PostgresQueryCtx[result](context.Background(), readDB, query, txid, depth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query TZE graph: %w", err)
+	}
+
+	txids := make([]string, len(results))
+	for i, r := range results {
+		txids[i] = r.TxID
+	}
+
+	return txids, nil
+}
+
+// GetOpenDemoChannels retrieves unspent demo TZE outputs
+func GetOpenDemoChannels(limit, offset int) ([]TzeOutput, error) {
+	return GetUnspentTzeOutputsByType(TzeTypeDemo, limit, offset)
+}
+
+// GetDemoCloseEvents retrieves demo TZE inputs spent in close mode
+func GetDemoCloseEvents(limit, offset int) ([]TzeInput, error) {
+	return GetTzeInputsByTypeAndMode(TzeTypeDemo, TzeModeClose, limit, offset)
+}
+
+// GetDemoChannelStats aggregates open channels, value locked, close events and average channel lifetime
+// Lifetime needs the creation height of each output, which comes from the tx_graph transactions table
+func GetDemoChannelStats() (*DemoChannelStats, error) {
+	stats, err := postgres.PostgresQueryOneCtx[DemoChannelStats](
+		context.Background(), readDB,
+		`SELECT
+			(SELECT COUNT(*) FROM tze_outputs WHERE tze_type = $1 AND spent_by_txid IS NULL) AS open_channels,
+			(SELECT COALESCE(SUM(value), 0)::BIGINT FROM tze_outputs WHERE tze_type = $1 AND spent_by_txid IS NULL) AS value_locked,
+			(SELECT COUNT(*) FROM tze_outputs WHERE tze_type = $1 AND tze_mode = $2) AS open_events,
+			(SELECT COUNT(*) FROM tze_inputs WHERE tze_type = $1 AND tze_mode = $3) AS close_events,
+			NULL::FLOAT8 AS avg_lifetime_blocks,
+			0::BIGINT AS closed_channels_sample`,
+		TzeTypeDemo, TzeModeOpen, TzeModeClose,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get demo channel stats: %w", err)
+	}
+
+	if config.IsModuleEnabled("TX_GRAPH") {
+		err := postgres.ReadQuerier(readDB).QueryRow(context.Background(),
+			`SELECT AVG(o.spent_at_height - t.block_height)::FLOAT8, COUNT(*)
+			 FROM tze_outputs o
+			 JOIN transactions t ON t.txid = o.txid
+			 WHERE o.tze_type = $1 AND o.spent_at_height IS NOT NULL`,
+			TzeTypeDemo,
+		).Scan(&stats.AvgLifetimeBlocks, &stats.ClosedChannelsSample)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get demo channel lifetime: %w", err)
+		}
+	}
+
+	return stats, nil
+}
+
+// readDB serves TZE graph lookups; nil falls back to postgres.DB
+var readDB postgres.Querier
+
+// SetReadDB routes TZE graph lookups through q, e.g. a read replica
+// pool or a mock in tests. Passing nil restores the global postgres.DB
+func SetReadDB(q postgres.Querier) {
+	readDB = q
+}
+
 // StoreTzeOutput inserts or updates a TZE output in the database
 // If postgresTx is provided, it will be used; otherwise a standalone query is executed
 // If the precondition exceeds the maximum size, it will be stored as an empty byte array
+// The precondition hash is computed before that check, so oversized preconditions stay searchable
 func StoreTzeOutput(postgresTx DBTX, txid string, vout int, value int64, tzeType int32, tzeMode int32, precondition []byte) error {
-	ctx := context.Background()
+	var batch postgres.WriteBatch
+	QueueTzeOutput(&batch, txid, vout, value, tzeType, tzeMode, precondition)
+	return sendBatch(postgresTx, &batch)
+}
+
+// QueueTzeOutput queues the upsert of a TZE output into batch, see StoreTzeOutput
+func QueueTzeOutput(batch *postgres.WriteBatch, txid string, vout int, value int64, tzeType int32, tzeMode int32, precondition []byte) {
+	preconditionHash := contentHash(precondition)
 
 	// Validate precondition size - if it exceeds max size, store empty byte array instead
 	if err := ValidatePreconditionSize(precondition); err != nil {
-		log.Printf("Warning: Precondition for output %s:%d exceeds maximum size, storing empty precondition: %v", txid, vout, err)
+		logging.Warnf(logging.ModuleTzeGraph, "Warning: Precondition for output %s:%d exceeds maximum size, storing empty precondition: %v", txid, vout, err)
 		precondition = []byte{}
 	}
 
 	query := `
-		INSERT INTO tze_outputs (txid, vout, value, tze_type, tze_mode, precondition)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		ON CONFLICT (txid, vout) DO UPDATE SET
+		INSERT INTO tze_outputs (txid, vout, value, tze_type, tze_mode, precondition, precondition_hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (txid, vout) ` + postgres.OnConflictImmutable(`
 			value = EXCLUDED.value,
 			tze_type = EXCLUDED.tze_type,
 			tze_mode = EXCLUDED.tze_mode,
-			precondition = EXCLUDED.precondition
-	`
-
-	if postgresTx == nil {
-		postgresTx = postgres.DB
-	}
-
-	_, err := postgresTx.Exec(ctx, query, txid, vout, value, tzeType, tzeMode, precondition)
-	if err != nil {
-		return fmt.Errorf("failed to store tze output %s:%d: %w", txid, vout, err)
-	}
+			precondition = EXCLUDED.precondition,
+			precondition_hash = EXCLUDED.precondition_hash
+	`)
 
-	return nil
+	batch.Queue(fmt.Sprintf("store tze output %s:%d", txid, vout), query,
+		txid, vout, value, tzeType, tzeMode, precondition, preconditionHash)
 }
 
 // StoreTzeInput inserts or updates a TZE input in the database
 // and marks the corresponding TZE output as spent
 // If postgresTx is provided, it will be used; otherwise a standalone query is executed
-func StoreTzeInput(postgresTx DBTX, txid string, vin int, value int64, prevTxid string, prevVout int, tzeType int32, tzeMode int32, blockHeight int64) error {
-	ctx := context.Background()
-
-	if postgresTx == nil {
-		postgresTx = postgres.DB
-	}
+func StoreTzeInput(postgresTx DBTX, txid string, vin int, value int64, prevTxid string, prevVout int, tzeType int32, tzeMode int32, witness []byte, blockHeight int64) error {
+	var batch postgres.WriteBatch
+	QueueTzeInput(&batch, txid, vin, value, prevTxid, prevVout, tzeType, tzeMode, contentHash(witness), blockHeight)
+	return sendBatch(postgresTx, &batch)
+}
 
+// QueueTzeInput queues the upsert of a TZE input and the update marking the TZE output it spends
+// It takes the hex SHA-256 of the witness rather than the witness, so large witnesses can be hashed without decoding
+func QueueTzeInput(batch *postgres.WriteBatch, txid string, vin int, value int64, prevTxid string, prevVout int, tzeType int32, tzeMode int32, witnessHash string, blockHeight int64) {
 	// Insert the TZE input
 	inputQuery := `
-		INSERT INTO tze_inputs (txid, vin, value, prev_txid, prev_vout, tze_type, tze_mode)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		ON CONFLICT (txid, vin) DO UPDATE SET
+		INSERT INTO tze_inputs (txid, vin, value, prev_txid, prev_vout, tze_type, tze_mode, witness_hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (txid, vin) ` + postgres.OnConflictImmutable(`
 			value = EXCLUDED.value,
 			prev_txid = EXCLUDED.prev_txid,
 			prev_vout = EXCLUDED.prev_vout,
 			tze_type = EXCLUDED.tze_type,
-			tze_mode = EXCLUDED.tze_mode
-	`
+			tze_mode = EXCLUDED.tze_mode,
+			witness_hash = EXCLUDED.witness_hash
+	`)
 
-	_, err := postgresTx.Exec(ctx, inputQuery, txid, vin, value, prevTxid, prevVout, tzeType, tzeMode)
-	if err != nil {
-		return fmt.Errorf("failed to store tze input %s:%d: %w", txid, vin, err)
-	}
+	batch.Queue(fmt.Sprintf("store tze input %s:%d", txid, vin), inputQuery,
+		txid, vin, value, prevTxid, prevVout, tzeType, tzeMode, witnessHash)
 
 	// Mark the previous TZE output as spent
 	outputQuery := `
@@ -471,10 +728,19 @@ func StoreTzeInput(postgresTx DBTX, txid string, vin int, value int64, prevTxid
 		WHERE txid = $4 AND vout = $5
 	`
 
-	_, err = postgresTx.Exec(ctx, outputQuery, txid, vin, blockHeight, prevTxid, prevVout)
-	if err != nil {
-		return fmt.Errorf("failed to mark tze output %s:%d as spent: %w", prevTxid, prevVout, err)
+	batch.Queue(fmt.Sprintf("mark tze output %s:%d as spent", prevTxid, prevVout), outputQuery,
+		txid, vin, blockHeight, prevTxid, prevVout)
+}
+
+// sendBatch writes batch through postgresTx, or the global pool when it is nil
+func sendBatch(postgresTx DBTX, batch *postgres.WriteBatch) error {
+	if postgresTx == nil {
+		postgresTx = postgres.DB
 	}
+	return batch.Send(context.Background(), postgresTx)
+}
 
-	return nil
+// contentHash returns the hex SHA-256 of TZE precondition or witness data
+func contentHash(data []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(data))
 }
@@ -0,0 +1,21 @@
+package usage
+
+// KeyUsage is the traffic of one API key over a date range
+type KeyUsage struct {
+	KeyID        string `json:"key_id" db:"key_id"` // AnonymousKeyID for requests without a key
+	Requests     int64  `json:"requests" db:"requests"`
+	BytesOut     int64  `json:"bytes_out" db:"bytes_out"` // response body bytes
+	ClientErrors int64  `json:"client_errors" db:"client_errors"`
+	ServerErrors int64  `json:"server_errors" db:"server_errors"`
+	FirstDay     string `json:"first_day" db:"first_day"` // YYYY-MM-DD (UTC)
+	LastDay      string `json:"last_day" db:"last_day"`
+}
+
+// DailyUsage is the traffic of one API key on one UTC day
+type DailyUsage struct {
+	Day          string `json:"day" db:"day"` // YYYY-MM-DD (UTC)
+	Requests     int64  `json:"requests" db:"requests"`
+	BytesOut     int64  `json:"bytes_out" db:"bytes_out"`
+	ClientErrors int64  `json:"client_errors" db:"client_errors"`
+	ServerErrors int64  `json:"server_errors" db:"server_errors"`
+}
@@ -0,0 +1,226 @@
+package usage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+)
+
+// AnonymousKeyID is the key id that requests without an API key are counted under
+const AnonymousKeyID = "anonymous"
+
+func init() {
+	// Register this as a core schema (always initialized)
+	postgres.RegisterCoreSchema("api_usage", InitSchema)
+}
+
+// InitSchema creates the api_usage table
+// Keys are stored as KeyID digests, never in the clear
+func InitSchema() error {
+	schema := `
+		CREATE TABLE IF NOT EXISTS api_usage (
+			key_id VARCHAR(64) NOT NULL,
+			day DATE NOT NULL,
+			requests BIGINT NOT NULL DEFAULT 0,
+			bytes_out BIGINT NOT NULL DEFAULT 0,
+			client_errors BIGINT NOT NULL DEFAULT 0,
+			server_errors BIGINT NOT NULL DEFAULT 0,
+			PRIMARY KEY (key_id, day)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_api_usage_day ON api_usage(day);
+	`
+
+	_, err := postgres.DB.Exec(context.Background(), schema)
+	if err != nil {
+		return fmt.Errorf("failed to create api_usage schema: %w", err)
+	}
+
+	return nil
+}
+
+// KeyID derives the identifier usage is recorded under from a raw API key
+// It is the first 16 hex characters of the key's SHA-256, enough to tell keys apart in reports
+// without keeping a usable secret in the database
+func KeyID(apiKey string) string {
+	if apiKey == "" {
+		return AnonymousKeyID
+	}
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:8])
+}
+
+type counterKey struct {
+	keyID string
+	day   string
+}
+
+type counter struct {
+	requests     int64
+	bytesOut     int64
+	clientErrors int64
+	serverErrors int64
+}
+
+var (
+	pendingMu sync.Mutex
+	pending   = map[counterKey]*counter{}
+)
+
+// Enabled reports whether API usage is tracked
+func Enabled() bool {
+	return config.Conf.Api.Usage.Enabled
+}
+
+// Record counts one request in memory; counts are written to the database by Flush
+func Record(keyID string, status int, bytesOut int64) {
+	key := counterKey{keyID: keyID, day: time.Now().UTC().Format(time.DateOnly)}
+
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+
+	c, ok := pending[key]
+	if !ok {
+		c = &counter{}
+		pending[key] = c
+	}
+	c.requests++
+	c.bytesOut += bytesOut
+	switch {
+	case status >= 500:
+		c.serverErrors++
+	case status >= 400:
+		c.clientErrors++
+	}
+}
+
+// Flush adds the counts recorded since the last flush to the api_usage table
+// Counts that fail to be written are kept for the next flush
+func Flush(ctx context.Context) error {
+	pendingMu.Lock()
+	batch := pending
+	pending = map[counterKey]*counter{}
+	pendingMu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	if err := writeCounts(ctx, batch); err != nil {
+		requeue(batch)
+		return fmt.Errorf("failed to flush API usage: %w", err)
+	}
+
+	return nil
+}
+
+// writeCounts adds a batch of counts to api_usage in one transaction
+func writeCounts(ctx context.Context, batch map[counterKey]*counter) error {
+	tx, err := postgres.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for key, c := range batch {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO api_usage (key_id, day, requests, bytes_out, client_errors, server_errors)
+			VALUES ($1, $2::DATE, $3, $4, $5, $6)
+			ON CONFLICT (key_id, day) DO UPDATE SET
+				requests = api_usage.requests + EXCLUDED.requests,
+				bytes_out = api_usage.bytes_out + EXCLUDED.bytes_out,
+				client_errors = api_usage.client_errors + EXCLUDED.client_errors,
+				server_errors = api_usage.server_errors + EXCLUDED.server_errors
+		`, key.keyID, key.day, c.requests, c.bytesOut, c.clientErrors, c.serverErrors)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// requeue merges counts that failed to flush back into the pending counters
+func requeue(batch map[counterKey]*counter) {
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+
+	for key, c := range batch {
+		existing, ok := pending[key]
+		if !ok {
+			pending[key] = c
+			continue
+		}
+		existing.requests += c.requests
+		existing.bytesOut += c.bytesOut
+		existing.clientErrors += c.clientErrors
+		existing.serverErrors += c.serverErrors
+	}
+}
+
+// StartFlusher writes recorded usage every api.usage.flush_interval seconds
+func StartFlusher() {
+	if !Enabled() {
+		return
+	}
+
+	interval := time.Duration(config.Conf.Api.Usage.FlushInterval) * time.Second
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := Flush(context.Background()); err != nil {
+				log.Printf("%v", err)
+			}
+		}
+	}()
+}
+
+// GetUsage returns the traffic of every key seen between two UTC days (inclusive), busiest first
+func GetUsage(fromDay, toDay string, limit, offset int) ([]KeyUsage, error) {
+	usage, err := postgres.PostgresQueryCtx[KeyUsage](
+		context.Background(), nil,
+		`SELECT key_id,
+		        SUM(requests)::BIGINT AS requests,
+		        SUM(bytes_out)::BIGINT AS bytes_out,
+		        SUM(client_errors)::BIGINT AS client_errors,
+		        SUM(server_errors)::BIGINT AS server_errors,
+		        MIN(day)::TEXT AS first_day,
+		        MAX(day)::TEXT AS last_day
+		 FROM api_usage
+		 WHERE day BETWEEN $1::DATE AND $2::DATE
+		 GROUP BY key_id
+		 ORDER BY requests DESC, key_id
+		 LIMIT $3 OFFSET $4`,
+		fromDay, toDay, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API usage: %w", err)
+	}
+
+	return usage, nil
+}
+
+// GetKeyUsage returns the daily traffic of one key between two UTC days (inclusive)
+func GetKeyUsage(keyID, fromDay, toDay string) ([]DailyUsage, error) {
+	usage, err := postgres.PostgresQueryCtx[DailyUsage](
+		context.Background(), nil,
+		`SELECT day::TEXT AS day, requests, bytes_out, client_errors, server_errors
+		 FROM api_usage
+		 WHERE key_id = $1 AND day BETWEEN $2::DATE AND $3::DATE
+		 ORDER BY day`,
+		keyID, fromDay, toDay,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API usage for key %s: %w", keyID, err)
+	}
+
+	return usage, nil
+}
@@ -0,0 +1,308 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/bluegreen"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/events"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/jobs"
+)
+
+const (
+	// eventBuffer is how many events may wait to be queued before the event bus drops the webhook
+	// subscriber; it resubscribes, and events published in between get no deliveries
+	eventBuffer = 4096
+	// deliveryBatch is how many due deliveries are claimed at a time
+	deliveryBatch = 50
+	// deliveryPollInterval is how often due retries are looked for when no new event arrives
+	deliveryPollInterval = time.Second
+	// maxBackoffDoublings caps the retry delay at retry_delay * 2^maxBackoffDoublings
+	maxBackoffDoublings = 10
+	// maxErrorLength bounds the response body or error kept per attempt
+	maxErrorLength = 512
+)
+
+var (
+	stopChan chan struct{}
+	// loopsDone is closed once the queueing and delivery loops have returned
+	loopsDone chan struct{}
+	// wakeChan prompts the delivery loop to look for due deliveries right away
+	wakeChan = make(chan struct{}, 1)
+)
+
+// Start queues a delivery of every published event for each subscription to its topic, or an
+// hourly or daily digest of them, and delivers them in the background until Stop is called
+// It does nothing unless api.webhooks is enabled
+func Start() {
+	if !Enabled() {
+		return
+	}
+
+	stopChan = make(chan struct{})
+	loopsDone = make(chan struct{})
+	queued := make(chan struct{})
+	digested := make(chan struct{})
+	go func() {
+		defer close(queued)
+		queueLoop(stopChan)
+	}()
+	go func() {
+		defer close(digested)
+		// With the job queue, the webhook_digests job queues digests instead
+		if !jobs.Enabled() {
+			digestLoop(stopChan)
+		}
+	}()
+	go func() {
+		defer close(loopsDone)
+		deliverLoop(stopChan)
+		<-queued
+		<-digested
+	}()
+}
+
+// Stop ends delivery, waiting for a request in flight; unfinished deliveries and held digest
+// events resume on the next start
+func Stop() {
+	if stopChan != nil {
+		close(stopChan)
+		<-loopsDone
+		stopChan = nil
+	}
+}
+
+func wake() {
+	select {
+	case wakeChan <- struct{}{}:
+	default:
+	}
+}
+
+// queueLoop persists a delivery per subscriber of every event, so none is lost to a restart
+func queueLoop(stop chan struct{}) {
+	sub := events.Subscribe(eventBuffer)
+	for {
+		select {
+		case <-stop:
+			events.Unsubscribe(sub)
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				log.Printf("Webhook event subscriber fell behind, resubscribing; events published meanwhile get no deliveries")
+				sub = events.Subscribe(eventBuffer)
+				continue
+			}
+			// A standby blue/green deployment indexes the same blocks as the live one, which alone delivers them
+			if !bluegreen.Serving() {
+				continue
+			}
+			queued, err := enqueue(context.Background(), event)
+			if err != nil {
+				log.Printf("%v", err)
+				continue
+			}
+			if queued > 0 {
+				wake()
+			}
+		}
+	}
+}
+
+func deliverLoop(stop chan struct{}) {
+	ticker := time.NewTicker(deliveryPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		case <-wakeChan:
+		}
+		if !bluegreen.Serving() {
+			continue
+		}
+
+		// Keep claiming until nothing is due, so a burst of events is not paced by the ticker
+		for {
+			delivered, err := deliverDue(stop)
+			if err != nil {
+				log.Printf("Webhook delivery failed: %v", err)
+			}
+			if err != nil || delivered < deliveryBatch {
+				break
+			}
+		}
+	}
+}
+
+// dueDelivery is a claimed delivery with what is needed to send it
+type dueDelivery struct {
+	ID       int64  `db:"id"`
+	Topic    string `db:"topic"`
+	Payload  string `db:"payload"`
+	Attempts int    `db:"attempts"`
+	Url      string `db:"url"`
+	Secret   string `db:"secret"`
+}
+
+// deliverDue claims up to deliveryBatch due deliveries and sends them in order
+// Claiming pushes next_attempt_at past the time the whole batch can take to send, one request
+// timeout per delivery plus one spare, so a delivery interrupted by a crash is retried later
+// rather than lost, and other instances skip it meanwhile
+func deliverDue(stop chan struct{}) (int, error) {
+	ctx := context.Background()
+	timeout := time.Duration(config.Conf.Api.Webhooks.Timeout) * time.Second
+
+	due, err := postgres.PostgresQueryCtx[dueDelivery](ctx, nil,
+		`WITH due AS (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		), claimed AS (
+			UPDATE webhook_deliveries d
+			SET next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $2)
+			FROM due
+			WHERE d.id = due.id
+			RETURNING d.id, d.subscription_id, d.topic, d.payload::TEXT AS payload, d.attempts
+		)
+		SELECT c.id, c.topic, c.payload, c.attempts, s.url, s.secret
+		FROM claimed c
+		JOIN webhook_subscriptions s ON s.id = c.subscription_id
+		ORDER BY c.id`,
+		deliveryBatch, float64(deliveryBatch+1)*timeout.Seconds(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim due webhook deliveries: %w", err)
+	}
+
+	client := &http.Client{Timeout: timeout}
+	for i := range due {
+		select {
+		case <-stop:
+			return i, nil
+		default:
+		}
+		if err := attempt(ctx, client, &due[i]); err != nil {
+			return i, err
+		}
+	}
+	return len(due), nil
+}
+
+// Sign returns the signature header value of a payload sent at timestamp (unix seconds):
+// sha256= followed by the hex HMAC-SHA256 of "<timestamp>.<payload>" keyed with the secret
+// Receivers recompute it to check authenticity and reject stale timestamps to stop replays
+func Sign(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// attempt sends one delivery and records the outcome
+func attempt(ctx context.Context, client *http.Client, d *dueDelivery) error {
+	payload := []byte(d.Payload)
+	timestamp := time.Now().Unix()
+
+	var statusCode *int
+	var errMsg *string
+	started := time.Now()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.Url, bytes.NewReader(payload))
+	if err == nil {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "zindex-webhooks")
+		req.Header.Set(HeaderDelivery, strconv.FormatInt(d.ID, 10))
+		req.Header.Set(HeaderEvent, d.Topic)
+		req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+		req.Header.Set(HeaderSignature, Sign(d.Secret, timestamp, payload))
+
+		var resp *http.Response
+		resp, err = client.Do(req)
+		if err == nil {
+			code := resp.StatusCode
+			statusCode = &code
+			if code < 200 || code > 299 {
+				body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorLength))
+				err = fmt.Errorf("endpoint answered %d: %s", code, bytes.TrimSpace(body))
+			}
+			resp.Body.Close()
+		}
+	}
+	if err != nil {
+		message := err.Error()
+		if len(message) > maxErrorLength {
+			message = message[:maxErrorLength]
+		}
+		errMsg = &message
+	}
+
+	return recordAttempt(ctx, d, statusCode, errMsg, time.Since(started))
+}
+
+// recordAttempt logs an attempt and moves the delivery to delivered, failed, or its next retry
+func recordAttempt(ctx context.Context, d *dueDelivery, statusCode *int, errMsg *string, duration time.Duration) error {
+	webhooks := config.Conf.Api.Webhooks
+	attempts := d.Attempts + 1
+
+	status := StatusPending
+	switch {
+	case errMsg == nil:
+		status = StatusDelivered
+	case attempts >= webhooks.MaxAttempts:
+		status = StatusFailed
+	}
+	backoff := webhooks.RetryDelay << min(d.Attempts, maxBackoffDoublings)
+
+	tx, err := postgres.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Attempts are numbered across redeliveries, while the delivery's attempts count restarts
+	_, err = tx.Exec(ctx,
+		`INSERT INTO webhook_delivery_attempts (delivery_id, attempt, status_code, error, duration_ms)
+		 SELECT $1, COUNT(*) + 1, $2, $3, $4 FROM webhook_delivery_attempts WHERE delivery_id = $1`,
+		d.ID, statusCode, errMsg, duration.Milliseconds(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record attempt of webhook delivery %d: %w", d.ID, err)
+	}
+
+	_, err = tx.Exec(ctx,
+		`UPDATE webhook_deliveries
+		 SET status = $2, attempts = $3, last_status_code = $4, last_error = $5,
+		     next_attempt_at = CASE WHEN $2 = 'pending' THEN CURRENT_TIMESTAMP + make_interval(secs => $6) END,
+		     delivered_at = CASE WHEN $2 = 'delivered' THEN CURRENT_TIMESTAMP END
+		 WHERE id = $1`,
+		d.ID, status, attempts, statusCode, errMsg, backoff,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery %d: %w", d.ID, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit webhook delivery %d: %w", d.ID, err)
+	}
+
+	if status == StatusFailed {
+		log.Printf("Webhook delivery %d to %s failed after %d attempts: %s", d.ID, d.Url, attempts, *errMsg)
+	}
+	return nil
+}
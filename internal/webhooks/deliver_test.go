@@ -0,0 +1,63 @@
+package webhooks
+
+import "testing"
+
+func TestSign(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		payload   string
+		want      string
+	}{
+		{
+			name:      "event",
+			secret:    "secret",
+			timestamp: 1700000000,
+			payload:   `{"topic":"blocks"}`,
+			want:      "sha256=581be0a8538242080d883bf45e98aac0970125af0cdafdbce73bf170c1495ca5",
+		},
+		{
+			name:      "empty",
+			secret:    "",
+			timestamp: 0,
+			payload:   "",
+			want:      "sha256=b849d5a581847b281957065739df36df2463d1977ea8d6e1e4e6cf33fadc68c3",
+		},
+		{
+			name:      "empty object",
+			secret:    "whsec_abc",
+			timestamp: 1760520000,
+			payload:   `{}`,
+			want:      "sha256=53759b33a68c0c16780d89d6a3d222166881d49e87e283e9f52dad34d1fa4c2a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sign(tt.secret, tt.timestamp, []byte(tt.payload)); got != tt.want {
+				t.Errorf("Sign() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignDependsOnEveryInput(t *testing.T) {
+	base := Sign("secret", 1700000000, []byte(`{}`))
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		payload   string
+	}{
+		{"secret", "other", 1700000000, `{}`},
+		{"timestamp", "secret", 1700000001, `{}`},
+		{"payload", "secret", 1700000000, `{ }`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if Sign(tt.secret, tt.timestamp, []byte(tt.payload)) == base {
+				t.Errorf("changing the %s did not change the signature", tt.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/bluegreen"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/jobs"
+)
+
+// KindFlushDigests is the periodic job that queues due digests when jobs.enabled is set
+const KindFlushDigests = "webhook_digests"
+
+func init() {
+	jobs.RegisterPeriodic(KindFlushDigests, digestJobInterval, flushDigestsJob)
+}
+
+func digestJobInterval() time.Duration {
+	if !Enabled() {
+		return 0
+	}
+	return digestCheckInterval
+}
+
+func flushDigestsJob(ctx context.Context, _ json.RawMessage) (any, error) {
+	queued, err := flushDigests(ctx, time.Now())
+	if queued > 0 {
+		wake()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return map[string]int{"queued": queued}, nil
+}
+
+// digestCheckInterval is how often digest subscriptions are checked for a finished period
+const digestCheckInterval = time.Minute
+
+// heldEvent is an event waiting for the digest of its subscription
+type heldEvent struct {
+	ID          int64     `db:"id"`
+	Topic       string    `db:"topic"`
+	BlockHeight int64     `db:"block_height"`
+	Payload     string    `db:"payload"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+// digestLoop queues a digest delivery for each digest subscription once its hour or day is over
+func digestLoop(stop chan struct{}) {
+	ticker := time.NewTicker(digestCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		if !bluegreen.Serving() {
+			continue
+		}
+
+		queued, err := flushDigests(context.Background(), time.Now())
+		if err != nil {
+			log.Printf("Webhook digest failed: %v", err)
+		}
+		if queued > 0 {
+			wake()
+		}
+	}
+}
+
+// periodStart returns the start of the UTC hour or day that contains t
+func periodStart(digest string, t time.Time) time.Time {
+	t = t.UTC()
+	if digest == DigestHourly {
+		return t.Truncate(time.Hour)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func periodEnd(digest string, start time.Time) time.Time {
+	if digest == DigestHourly {
+		return start.Add(time.Hour)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// flushDigests queues the digests of every subscription holding events from a period that ended
+// before now. Returns the number of digests queued
+func flushDigests(ctx context.Context, now time.Time) (int, error) {
+	subscriptions, err := postgres.PostgresQueryCtx[Subscription](ctx, nil,
+		`SELECT s.id, s.url, s.topics, s.description, s.digest, '' AS secret, s.created_at
+		 FROM webhook_subscriptions s
+		 WHERE s.digest <> ''
+		   AND EXISTS (SELECT 1 FROM webhook_digest_events e WHERE e.subscription_id = s.id)`,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get webhook digest subscriptions: %w", err)
+	}
+
+	queued := 0
+	for _, subscription := range subscriptions {
+		digests, err := flushDigest(ctx, subscription.ID, subscription.Digest, periodStart(subscription.Digest, now))
+		if err != nil {
+			return queued, err
+		}
+		queued += digests
+	}
+	return queued, nil
+}
+
+// flushDigest turns a subscription's events held from before until into one delivery per period
+// The events are locked and removed in the same transaction that queues the deliveries, so no
+// event is sent twice, and other instances skip them meanwhile
+func flushDigest(ctx context.Context, subscriptionID int64, digest string, until time.Time) (int, error) {
+	tx, err := postgres.DB.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	held, err := postgres.PostgresQueryCtx[heldEvent](ctx, tx,
+		`SELECT id, topic, block_height, payload::TEXT AS payload, created_at
+		 FROM webhook_digest_events
+		 WHERE subscription_id = $1 AND created_at < $2
+		 ORDER BY id
+		 FOR UPDATE SKIP LOCKED`,
+		subscriptionID, until,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get held events of webhook subscription %d: %w", subscriptionID, err)
+	}
+	if len(held) == 0 {
+		return 0, nil
+	}
+
+	maxEvents := config.Conf.Api.Webhooks.MaxDigestEvents
+	var digests []*Digest
+	var current *Digest
+	ids := make([]int64, 0, len(held))
+	for _, event := range held {
+		ids = append(ids, event.ID)
+		start := periodStart(digest, event.CreatedAt)
+		if current == nil || !current.PeriodStart.Equal(start) {
+			current = &Digest{
+				Topic:       TopicDigest,
+				Period:      digest,
+				PeriodStart: start,
+				PeriodEnd:   periodEnd(digest, start),
+				FromHeight:  event.BlockHeight,
+				ToHeight:    event.BlockHeight,
+				TopicCounts: map[string]int{},
+				Events:      []json.RawMessage{},
+			}
+			digests = append(digests, current)
+		}
+
+		current.EventCount++
+		current.TopicCounts[event.Topic]++
+		current.FromHeight = min(current.FromHeight, event.BlockHeight)
+		current.ToHeight = max(current.ToHeight, event.BlockHeight)
+		if len(current.Events) < maxEvents {
+			current.Events = append(current.Events, json.RawMessage(event.Payload))
+		} else {
+			current.Truncated = true
+		}
+	}
+
+	for _, d := range digests {
+		payload, err := json.Marshal(d)
+		if err != nil {
+			return 0, fmt.Errorf("failed to encode webhook digest: %w", err)
+		}
+		_, err = tx.Exec(ctx,
+			`INSERT INTO webhook_deliveries (subscription_id, topic, block_height, payload)
+			 VALUES ($1, $2, $3, $4)`,
+			subscriptionID, TopicDigest, d.ToHeight, payload,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to queue webhook digest of subscription %d: %w", subscriptionID, err)
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM webhook_digest_events WHERE id = ANY($1)`, ids); err != nil {
+		return 0, fmt.Errorf("failed to remove digested events of webhook subscription %d: %w", subscriptionID, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit webhook digest of subscription %d: %w", subscriptionID, err)
+	}
+
+	return len(digests), nil
+}
@@ -0,0 +1,88 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Delivery statuses
+const (
+	StatusPending   = "pending"   // waiting for its next attempt
+	StatusDelivered = "delivered" // the endpoint answered 2xx
+	StatusFailed    = "failed"    // max_attempts reached; can be redelivered
+)
+
+// Digest periods of a subscription; without one, every event is delivered on its own
+const (
+	DigestHourly = "hourly"
+	DigestDaily  = "daily"
+)
+
+// TopicDigest is the topic of a digest delivery
+const TopicDigest = "digest"
+
+// Signature headers sent with every delivery
+const (
+	HeaderDelivery  = "X-Zindex-Delivery"  // delivery id, stable across retries
+	HeaderEvent     = "X-Zindex-Event"     // event topic
+	HeaderTimestamp = "X-Zindex-Timestamp" // unix seconds of the attempt
+	HeaderSignature = "X-Zindex-Signature" // sha256=<hex HMAC-SHA256 of "<timestamp>.<body>">
+)
+
+// Subscription is a URL receiving the events of some topics
+// The secret is only returned when the subscription is created
+type Subscription struct {
+	ID          int64     `json:"id" db:"id"`
+	Url         string    `json:"url" db:"url"`
+	Topics      []string  `json:"topics" db:"topics"`
+	Description string    `json:"description" db:"description"`
+	Digest      string    `json:"digest" db:"digest"` // hourly, daily or empty
+	Secret      string    `json:"secret,omitempty" db:"secret"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// Delivery is one event queued for one subscription
+type Delivery struct {
+	ID             int64      `json:"id" db:"id"`
+	SubscriptionID int64      `json:"subscription_id" db:"subscription_id"`
+	Topic          string     `json:"topic" db:"topic"`
+	BlockHeight    int64      `json:"block_height" db:"block_height"`
+	Status         string     `json:"status" db:"status"`
+	Attempts       int        `json:"attempts" db:"attempts"`
+	LastStatusCode *int       `json:"last_status_code" db:"last_status_code"`
+	LastError      *string    `json:"last_error" db:"last_error"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at" db:"next_attempt_at"` // pending deliveries only
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at" db:"delivered_at"`
+}
+
+// Attempt is the outcome of one delivery request
+type Attempt struct {
+	Attempt     int       `json:"attempt" db:"attempt"`
+	StatusCode  *int      `json:"status_code" db:"status_code"` // nil when no response was received
+	Error       *string   `json:"error" db:"error"`
+	DurationMs  int64     `json:"duration_ms" db:"duration_ms"`
+	AttemptedAt time.Time `json:"attempted_at" db:"attempted_at"`
+}
+
+// DeliveryDetail is a delivery with its payload and every attempt made
+type DeliveryDetail struct {
+	Delivery
+	Payload    json.RawMessage `json:"payload" db:"payload"`
+	AttemptLog []Attempt       `json:"attempt_log" db:"-"`
+}
+
+// Digest is the payload of a digest delivery: the events held for a subscription over one UTC
+// hour or day, oldest first
+type Digest struct {
+	Topic       string            `json:"topic"`  // always digest
+	Period      string            `json:"period"` // hourly or daily
+	PeriodStart time.Time         `json:"period_start"`
+	PeriodEnd   time.Time         `json:"period_end"`
+	FromHeight  int64             `json:"from_height"`
+	ToHeight    int64             `json:"to_height"`
+	EventCount  int               `json:"event_count"`
+	TopicCounts map[string]int    `json:"topic_counts"`
+	Events      []json.RawMessage `json:"events"`
+	Truncated   bool              `json:"truncated"` // more than api.webhooks.max_digest_events events
+}
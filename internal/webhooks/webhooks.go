@@ -0,0 +1,269 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/events"
+)
+
+func init() {
+	// Register this as a core schema (always initialized)
+	postgres.RegisterCoreSchema("webhooks", InitSchema)
+}
+
+// InitSchema creates the webhook subscription, delivery and attempt tables
+// Deliveries keep their payload so failed or missed events can be redelivered as they were first sent
+func InitSchema() error {
+	schema := `
+		CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+			id BIGSERIAL PRIMARY KEY,
+			url TEXT NOT NULL,
+			topics TEXT[] NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			secret VARCHAR(64) NOT NULL,  -- HMAC-SHA256 key
+			created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+
+		-- Columns added after the initial schema
+		ALTER TABLE webhook_subscriptions ADD COLUMN IF NOT EXISTS digest VARCHAR(8) NOT NULL DEFAULT '';  -- hourly, daily or empty
+
+		CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id BIGSERIAL PRIMARY KEY,
+			subscription_id BIGINT NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+			topic VARCHAR(32) NOT NULL,
+			block_height BIGINT NOT NULL,
+			payload JSONB NOT NULL,
+			status VARCHAR(16) NOT NULL DEFAULT 'pending',  -- pending, delivered or failed
+			attempts INT NOT NULL DEFAULT 0,
+			last_status_code INT,
+			last_error TEXT,
+			next_attempt_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,  -- NULL once delivered or failed
+			created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			delivered_at TIMESTAMPTZ
+		);
+
+		CREATE TABLE IF NOT EXISTS webhook_delivery_attempts (
+			delivery_id BIGINT NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+			attempt INT NOT NULL,
+			status_code INT,
+			error TEXT,
+			duration_ms BIGINT NOT NULL,
+			attempted_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (delivery_id, attempt)
+		);
+
+		-- Events held for digest subscriptions until their period ends
+		CREATE TABLE IF NOT EXISTS webhook_digest_events (
+			id BIGSERIAL PRIMARY KEY,
+			subscription_id BIGINT NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+			topic VARCHAR(32) NOT NULL,
+			block_height BIGINT NOT NULL,
+			payload JSONB NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+		CREATE INDEX IF NOT EXISTS idx_webhook_digest_events_subscription ON webhook_digest_events(subscription_id, id);
+		CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription ON webhook_deliveries(subscription_id, id);
+	`
+
+	_, err := postgres.DB.Exec(context.Background(), schema)
+	if err != nil {
+		return fmt.Errorf("failed to create webhooks schema: %w", err)
+	}
+
+	return nil
+}
+
+// Enabled reports whether webhooks are delivered
+func Enabled() bool {
+	return config.Conf.Api.Webhooks.Enabled
+}
+
+// IsStatus reports whether status is a delivery status
+func IsStatus(status string) bool {
+	return status == StatusPending || status == StatusDelivered || status == StatusFailed
+}
+
+// ValidateSubscription checks a subscription's URL, topics and digest period
+func ValidateSubscription(s *Subscription) error {
+	parsed, err := url.Parse(s.Url)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+	if len(s.Topics) == 0 {
+		return fmt.Errorf("topics must list at least one of %v", events.Topics)
+	}
+	for _, topic := range s.Topics {
+		if !slices.Contains(events.Topics, topic) {
+			return fmt.Errorf("unknown topic %q, must be one of %v", topic, events.Topics)
+		}
+	}
+	if s.Digest != "" && s.Digest != DigestHourly && s.Digest != DigestDaily {
+		return fmt.Errorf("digest must be %s, %s or empty", DigestHourly, DigestDaily)
+	}
+	return nil
+}
+
+// CreateSubscription stores a subscription with a newly generated secret, which is returned
+// with it this one time
+func CreateSubscription(s Subscription) (*Subscription, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	subscription, err := postgres.PostgresQueryOneCtx[Subscription](
+		context.Background(), nil,
+		`INSERT INTO webhook_subscriptions (url, topics, description, digest, secret)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING id, url, topics, description, digest, secret, created_at`,
+		s.Url, s.Topics, s.Description, s.Digest, hex.EncodeToString(secret),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+
+	return subscription, nil
+}
+
+// GetSubscriptions lists every subscription without its secret, oldest first
+func GetSubscriptions() ([]Subscription, error) {
+	subscriptions, err := postgres.PostgresQueryCtx[Subscription](
+		context.Background(), nil,
+		`SELECT id, url, topics, description, digest, '' AS secret, created_at
+		 FROM webhook_subscriptions
+		 ORDER BY id`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook subscriptions: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+// DeleteSubscription removes a subscription and its delivery history
+// Returns false when no subscription has the id
+func DeleteSubscription(id int64) (bool, error) {
+	tag, err := postgres.DB.Exec(context.Background(), `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete webhook subscription %d: %w", id, err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// enqueue records a delivery of the event for every subscription to its topic, or holds it for
+// the next digest of digest subscriptions. Returns the number of deliveries queued
+func enqueue(ctx context.Context, event events.Event) (int64, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode %s event: %w", event.Topic, err)
+	}
+
+	tag, err := postgres.DB.Exec(ctx,
+		`INSERT INTO webhook_deliveries (subscription_id, topic, block_height, payload)
+		 SELECT id, $1, $2, $3
+		 FROM webhook_subscriptions
+		 WHERE $1 = ANY(topics) AND digest = ''`,
+		event.Topic, event.BlockHeight, payload,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to queue %s webhook deliveries: %w", event.Topic, err)
+	}
+
+	_, err = postgres.DB.Exec(ctx,
+		`INSERT INTO webhook_digest_events (subscription_id, topic, block_height, payload)
+		 SELECT id, $1, $2, $3
+		 FROM webhook_subscriptions
+		 WHERE $1 = ANY(topics) AND digest <> ''`,
+		event.Topic, event.BlockHeight, payload,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to hold %s event for webhook digests: %w", event.Topic, err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+const deliveryColumns = `id, subscription_id, topic, block_height, status, attempts, last_status_code, last_error,
+	next_attempt_at, created_at, delivered_at`
+
+// GetDeliveries lists deliveries newest first, optionally of one subscription (0 for all) and status
+func GetDeliveries(subscriptionID int64, status string, limit, offset int) ([]Delivery, error) {
+	deliveries, err := postgres.PostgresQueryCtx[Delivery](
+		context.Background(), nil,
+		`SELECT `+deliveryColumns+`
+		 FROM webhook_deliveries
+		 WHERE ($1 = 0 OR subscription_id = $1) AND ($2 = '' OR status = $2)
+		 ORDER BY id DESC
+		 LIMIT $3 OFFSET $4`,
+		subscriptionID, status, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// GetDelivery returns a delivery with its payload and attempts, or nil when it does not exist
+func GetDelivery(id int64) (*DeliveryDetail, error) {
+	ctx := context.Background()
+
+	deliveries, err := postgres.PostgresQueryCtx[DeliveryDetail](
+		ctx, nil,
+		`SELECT `+deliveryColumns+`, payload
+		 FROM webhook_deliveries
+		 WHERE id = $1`,
+		id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery %d: %w", id, err)
+	}
+	if len(deliveries) == 0 {
+		return nil, nil
+	}
+	delivery := &deliveries[0]
+
+	delivery.AttemptLog, err = postgres.PostgresQueryCtx[Attempt](
+		ctx, nil,
+		`SELECT attempt, status_code, error, duration_ms, attempted_at
+		 FROM webhook_delivery_attempts
+		 WHERE delivery_id = $1
+		 ORDER BY attempt`,
+		id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attempts of webhook delivery %d: %w", id, err)
+	}
+
+	return delivery, nil
+}
+
+// Redeliver queues deliveries again with a fresh attempt budget, keeping their original payload
+// With deliveryID set only that delivery is queued; otherwise every failed delivery of the
+// subscription at or above fromHeight is. Returns the number of deliveries queued
+func Redeliver(deliveryID, subscriptionID, fromHeight int64) (int64, error) {
+	tag, err := postgres.DB.Exec(context.Background(),
+		`UPDATE webhook_deliveries
+		 SET status = 'pending', attempts = 0, next_attempt_at = CURRENT_TIMESTAMP
+		 WHERE CASE WHEN $1 > 0 THEN id = $1
+		            ELSE subscription_id = $2 AND status = 'failed' AND block_height >= $3 END`,
+		deliveryID, subscriptionID, fromHeight,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to redeliver webhook deliveries: %w", err)
+	}
+	wake()
+
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,213 @@
+// Package zmq implements the subscriber side of ZeroMQ PUB/SUB over TCP, enough to receive the
+// notifications zcashd publishes with -zmqpubhashblock and -zmqpubrawblock
+//
+// It speaks ZMTP 3.0 with the NULL security mechanism, which libzmq 4.x publishers accept, so no
+// libzmq binding (and no cgo) is needed
+package zmq
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	// greetingSize is the fixed size of the ZMTP 3.0 greeting
+	greetingSize = 64
+	// MaxFrameSize bounds a received frame; zcash blocks are at most 2 MB, rawblock included
+	MaxFrameSize = 32 << 20
+
+	flagMore    = 0x01
+	flagLong    = 0x02
+	flagCommand = 0x04
+)
+
+// ErrFrameTooLarge is returned when the publisher sends a frame above MaxFrameSize
+var ErrFrameTooLarge = errors.New("zmq frame exceeds the maximum size")
+
+// Subscriber is a SUB socket connected to one publisher
+type Subscriber struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// Dial connects to a publisher at endpoint (tcp://host:port) and subscribes to topics
+// An empty topic subscribes to every message
+func Dial(endpoint string, timeout time.Duration, topics ...string) (*Subscriber, error) {
+	address, ok := strings.CutPrefix(endpoint, "tcp://")
+	if !ok {
+		return nil, fmt.Errorf("unsupported zmq endpoint %q, only tcp:// is supported", endpoint)
+	}
+
+	dialer := net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}
+	conn, err := dialer.Dial("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", endpoint, err)
+	}
+
+	s := &Subscriber{conn: conn, r: bufio.NewReader(conn)}
+	// The handshake must finish within timeout; afterwards reads block until a message arrives
+	conn.SetDeadline(time.Now().Add(timeout))
+	if err := s.handshake(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("zmq handshake with %s failed: %w", endpoint, err)
+	}
+	for _, topic := range topics {
+		// ZMTP 3.0 subscriptions are messages whose body is 0x01 followed by the topic prefix
+		if err := s.writeFrame(0, append([]byte{0x01}, topic...)); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to subscribe to %q: %w", topic, err)
+		}
+	}
+	conn.SetDeadline(time.Time{})
+
+	return s, nil
+}
+
+// handshake exchanges greetings and READY commands, announcing a SUB socket
+func (s *Subscriber) handshake() error {
+	greeting := make([]byte, greetingSize)
+	greeting[0] = 0xFF
+	greeting[9] = 0x7F
+	greeting[10] = 3 // version 3.0
+	greeting[11] = 0
+	copy(greeting[12:32], "NULL")
+	if _, err := s.conn.Write(greeting); err != nil {
+		return err
+	}
+
+	peer := make([]byte, greetingSize)
+	if _, err := io.ReadFull(s.r, peer); err != nil {
+		return fmt.Errorf("failed to read greeting: %w", err)
+	}
+	if peer[0] != 0xFF || peer[9] != 0x7F {
+		return fmt.Errorf("peer is not a ZMTP endpoint")
+	}
+	if peer[10] < 3 {
+		return fmt.Errorf("peer speaks ZMTP %d.%d, 3.0 or later is required", peer[10], peer[11])
+	}
+	if mechanism := string(bytes.TrimRight(peer[12:32], "\x00")); mechanism != "NULL" {
+		return fmt.Errorf("peer requires the %s security mechanism, only NULL is supported", mechanism)
+	}
+
+	ready := []byte("\x05READY")
+	ready = appendProperty(ready, "Socket-Type", "SUB")
+	if err := s.writeFrame(flagCommand, ready); err != nil {
+		return err
+	}
+
+	flags, body, err := s.readFrame()
+	if err != nil {
+		return fmt.Errorf("failed to read READY: %w", err)
+	}
+	if flags&flagCommand == 0 || !bytes.HasPrefix(body, []byte("\x05READY")) {
+		return fmt.Errorf("expected a READY command")
+	}
+	if socketType, ok := readProperty(body[6:], "Socket-Type"); ok && socketType != "PUB" && socketType != "XPUB" {
+		return fmt.Errorf("peer is a %s socket, not a publisher", socketType)
+	}
+	return nil
+}
+
+// appendProperty appends a ZMTP metadata property: 1-byte name length, name, 4-byte value length, value
+func appendProperty(b []byte, name, value string) []byte {
+	b = append(b, byte(len(name)))
+	b = append(b, name...)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(value)))
+	return append(b, value...)
+}
+
+// readProperty finds a metadata property, matching names case-insensitively as ZMTP requires
+func readProperty(b []byte, name string) (string, bool) {
+	for len(b) > 0 {
+		nameLen := int(b[0])
+		if len(b) < 1+nameLen+4 {
+			return "", false
+		}
+		key := string(b[1 : 1+nameLen])
+		valueLen := int(binary.BigEndian.Uint32(b[1+nameLen:]))
+		b = b[1+nameLen+4:]
+		if len(b) < valueLen {
+			return "", false
+		}
+		if strings.EqualFold(key, name) {
+			return string(b[:valueLen]), true
+		}
+		b = b[valueLen:]
+	}
+	return "", false
+}
+
+func (s *Subscriber) writeFrame(flags byte, body []byte) error {
+	header := []byte{flags}
+	if len(body) > 255 {
+		header[0] |= flagLong
+		header = binary.BigEndian.AppendUint64(header, uint64(len(body)))
+	} else {
+		header = append(header, byte(len(body)))
+	}
+	_, err := s.conn.Write(append(header, body...))
+	return err
+}
+
+func (s *Subscriber) readFrame() (byte, []byte, error) {
+	flags, err := s.r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var size uint64
+	if flags&flagLong != 0 {
+		var b [8]byte
+		if _, err := io.ReadFull(s.r, b[:]); err != nil {
+			return 0, nil, err
+		}
+		size = binary.BigEndian.Uint64(b[:])
+	} else {
+		b, err := s.r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		size = uint64(b)
+	}
+	if size > MaxFrameSize {
+		return 0, nil, ErrFrameTooLarge
+	}
+
+	body := make([]byte, size)
+	if _, err := io.ReadFull(s.r, body); err != nil {
+		return 0, nil, err
+	}
+	return flags, body, nil
+}
+
+// Receive blocks until the next message and returns its frames
+// zcashd messages are [topic, body, 4-byte little-endian sequence number]
+func (s *Subscriber) Receive() ([][]byte, error) {
+	var frames [][]byte
+	for {
+		flags, body, err := s.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		// Commands carry no message data; a 3.0 peer sends none after READY, but skip them anyway
+		if flags&flagCommand != 0 {
+			continue
+		}
+		frames = append(frames, body)
+		if flags&flagMore == 0 {
+			return frames, nil
+		}
+	}
+}
+
+// Close closes the connection, unblocking a pending Receive
+func (s *Subscriber) Close() error {
+	return s.conn.Close()
+}
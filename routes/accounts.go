@@ -1,9 +1,11 @@
 package routes
 
 import (
+	"math"
 	"net/http"
 
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/accounts"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/annotations"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
 	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
 )
@@ -11,7 +13,7 @@ import (
 // GetAccount retrieves a single account by address
 func GetAccount(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("ACCOUNTS") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Accounts module is disabled")
+		utils.WriteModuleDisabledJson(w, "ACCOUNTS", "Accounts module is disabled")
 		return
 	}
 
@@ -32,13 +34,13 @@ func GetAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	utils.WriteDataJson(w, account)
+	writeEntityJson(w, r, annotations.EntityAddress, account.Address, account)
 }
 
 // GetAccounts retrieves all accounts with pagination
 func GetAccounts(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("ACCOUNTS") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Accounts module is disabled")
+		utils.WriteModuleDisabledJson(w, "ACCOUNTS", "Accounts module is disabled")
 		return
 	}
 
@@ -52,13 +54,13 @@ func GetAccounts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	utils.WriteDataJson(w, accountList)
+	utils.WritePageJson(w, r, accountList, limit, offset, accounts.CountAccounts)
 }
 
 // GetAccountsByBalanceRange retrieves accounts within a specified balance range
 func GetAccountsByBalanceRange(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("ACCOUNTS") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Accounts module is disabled")
+		utils.WriteModuleDisabledJson(w, "ACCOUNTS", "Accounts module is disabled")
 		return
 	}
 
@@ -95,7 +97,7 @@ func GetAccountsByBalanceRange(w http.ResponseWriter, r *http.Request) {
 // GetTopAccountsByBalance retrieves accounts with the highest balances
 func GetTopAccountsByBalance(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("ACCOUNTS") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Accounts module is disabled")
+		utils.WriteModuleDisabledJson(w, "ACCOUNTS", "Accounts module is disabled")
 		return
 	}
 
@@ -111,10 +113,22 @@ func GetTopAccountsByBalance(w http.ResponseWriter, r *http.Request) {
 	utils.WriteDataJson(w, accountList)
 }
 
+// parseChangeRange reads the optional min_change and max_change filters on the signed
+// balance_change of account transactions, writing a 400 response when the range is empty
+func parseChangeRange(w http.ResponseWriter, r *http.Request) (int64, int64, bool) {
+	minChange := int64(utils.ParseQueryParamInt(r, "min_change", math.MinInt64))
+	maxChange := int64(utils.ParseQueryParamInt(r, "max_change", math.MaxInt64))
+	if minChange > maxChange {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "min_change must be less than or equal to max_change")
+		return 0, 0, false
+	}
+	return minChange, maxChange, true
+}
+
 // GetAccountTransactions retrieves all transactions for a specific account
 func GetAccountTransactions(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("ACCOUNTS") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Accounts module is disabled")
+		utils.WriteModuleDisabledJson(w, "ACCOUNTS", "Accounts module is disabled")
 		return
 	}
 
@@ -124,11 +138,16 @@ func GetAccountTransactions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	minChange, maxChange, ok := parseChangeRange(w, r)
+	if !ok {
+		return
+	}
+
 	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
 	offset := utils.ParseQueryParamInt(r, "offset", 0)
 	limit, offset = utils.NormalizePagination(limit, offset)
 
-	txs, err := accounts.GetAccountTransactions(address, limit, offset)
+	txs, err := accounts.GetAccountTransactions(address, utils.IncludeZeroValue(r), minChange, maxChange, limit, offset)
 	if err != nil {
 		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
 		return
@@ -140,7 +159,7 @@ func GetAccountTransactions(w http.ResponseWriter, r *http.Request) {
 // GetAccountTransactionsByType retrieves transactions for an account filtered by type
 func GetAccountTransactionsByType(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("ACCOUNTS") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Accounts module is disabled")
+		utils.WriteModuleDisabledJson(w, "ACCOUNTS", "Accounts module is disabled")
 		return
 	}
 
@@ -158,11 +177,17 @@ func GetAccountTransactionsByType(w http.ResponseWriter, r *http.Request) {
 
 	// Validate transaction type
 	validTypes := map[string]bool{
-		string(accounts.TxTypeReceive): true,
-		string(accounts.TxTypeSend):    true,
+		string(accounts.TxTypeReceive):        true,
+		string(accounts.TxTypeSend):           true,
+		string(accounts.TxTypeCoinbaseReward): true,
 	}
 	if !validTypes[txType] {
-		utils.WriteErrorJson(w, http.StatusBadRequest, "Invalid transaction type. Must be one of: receive, send")
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Invalid transaction type. Must be one of: receive, send, coinbase_reward")
+		return
+	}
+
+	minChange, maxChange, ok := parseChangeRange(w, r)
+	if !ok {
 		return
 	}
 
@@ -170,7 +195,7 @@ func GetAccountTransactionsByType(w http.ResponseWriter, r *http.Request) {
 	offset := utils.ParseQueryParamInt(r, "offset", 0)
 	limit, offset = utils.NormalizePagination(limit, offset)
 
-	txs, err := accounts.GetAccountTransactionsByType(address, txType, limit, offset)
+	txs, err := accounts.GetAccountTransactionsByType(address, txType, utils.IncludeZeroValue(r), minChange, maxChange, limit, offset)
 	if err != nil {
 		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
 		return
@@ -182,7 +207,7 @@ func GetAccountTransactionsByType(w http.ResponseWriter, r *http.Request) {
 // GetAccountReceivingTransactions retrieves receiving transactions for an account
 func GetAccountReceivingTransactions(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("ACCOUNTS") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Accounts module is disabled")
+		utils.WriteModuleDisabledJson(w, "ACCOUNTS", "Accounts module is disabled")
 		return
 	}
 
@@ -192,11 +217,16 @@ func GetAccountReceivingTransactions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	minChange, maxChange, ok := parseChangeRange(w, r)
+	if !ok {
+		return
+	}
+
 	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
 	offset := utils.ParseQueryParamInt(r, "offset", 0)
 	limit, offset = utils.NormalizePagination(limit, offset)
 
-	txs, err := accounts.GetAccountReceivingTransactions(address, limit, offset)
+	txs, err := accounts.GetAccountReceivingTransactions(address, utils.IncludeZeroValue(r), minChange, maxChange, limit, offset)
 	if err != nil {
 		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
 		return
@@ -208,7 +238,7 @@ func GetAccountReceivingTransactions(w http.ResponseWriter, r *http.Request) {
 // GetAccountSendingTransactions retrieves sending transactions for an account
 func GetAccountSendingTransactions(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("ACCOUNTS") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Accounts module is disabled")
+		utils.WriteModuleDisabledJson(w, "ACCOUNTS", "Accounts module is disabled")
 		return
 	}
 
@@ -218,11 +248,16 @@ func GetAccountSendingTransactions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	minChange, maxChange, ok := parseChangeRange(w, r)
+	if !ok {
+		return
+	}
+
 	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
 	offset := utils.ParseQueryParamInt(r, "offset", 0)
 	limit, offset = utils.NormalizePagination(limit, offset)
 
-	txs, err := accounts.GetAccountSendingTransactions(address, limit, offset)
+	txs, err := accounts.GetAccountSendingTransactions(address, utils.IncludeZeroValue(r), minChange, maxChange, limit, offset)
 	if err != nil {
 		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
 		return
@@ -234,7 +269,7 @@ func GetAccountSendingTransactions(w http.ResponseWriter, r *http.Request) {
 // GetAccountTransactionsByBlockRange retrieves transactions for an account within a block range
 func GetAccountTransactionsByBlockRange(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("ACCOUNTS") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Accounts module is disabled")
+		utils.WriteModuleDisabledJson(w, "ACCOUNTS", "Accounts module is disabled")
 		return
 	}
 
@@ -261,11 +296,16 @@ func GetAccountTransactionsByBlockRange(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	minChange, maxChange, ok := parseChangeRange(w, r)
+	if !ok {
+		return
+	}
+
 	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
 	offset := utils.ParseQueryParamInt(r, "offset", 0)
 	limit, offset = utils.NormalizePagination(limit, offset)
 
-	txs, err := accounts.GetAccountTransactionsByBlockRange(address, fromBlock, toBlock, limit, offset)
+	txs, err := accounts.GetAccountTransactionsByBlockRange(address, fromBlock, toBlock, utils.IncludeZeroValue(r), minChange, maxChange, limit, offset)
 	if err != nil {
 		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
 		return
@@ -277,7 +317,7 @@ func GetAccountTransactionsByBlockRange(w http.ResponseWriter, r *http.Request)
 // GetAccountTransactionCount returns the total number of transactions for an account
 func GetAccountTransactionCount(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("ACCOUNTS") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Accounts module is disabled")
+		utils.WriteModuleDisabledJson(w, "ACCOUNTS", "Accounts module is disabled")
 		return
 	}
 
@@ -299,7 +339,7 @@ func GetAccountTransactionCount(w http.ResponseWriter, r *http.Request) {
 // GetAccountTransaction retrieves a specific transaction for an account
 func GetAccountTransaction(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("ACCOUNTS") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Accounts module is disabled")
+		utils.WriteModuleDisabledJson(w, "ACCOUNTS", "Accounts module is disabled")
 		return
 	}
 
@@ -332,7 +372,7 @@ func GetAccountTransaction(w http.ResponseWriter, r *http.Request) {
 // GetTransactionAccounts retrieves all accounts associated with a transaction
 func GetTransactionAccounts(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("ACCOUNTS") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Accounts module is disabled")
+		utils.WriteModuleDisabledJson(w, "ACCOUNTS", "Accounts module is disabled")
 		return
 	}
 
@@ -354,7 +394,7 @@ func GetTransactionAccounts(w http.ResponseWriter, r *http.Request) {
 // GetRecentActiveAccounts retrieves accounts with recent transaction activity
 func GetRecentActiveAccounts(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("ACCOUNTS") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Accounts module is disabled")
+		utils.WriteModuleDisabledJson(w, "ACCOUNTS", "Accounts module is disabled")
 		return
 	}
 
@@ -373,7 +413,7 @@ func GetRecentActiveAccounts(w http.ResponseWriter, r *http.Request) {
 // CountAccounts returns the total count of accounts
 func CountAccounts(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("ACCOUNTS") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Accounts module is disabled")
+		utils.WriteModuleDisabledJson(w, "ACCOUNTS", "Accounts module is disabled")
 		return
 	}
 
@@ -389,7 +429,7 @@ func CountAccounts(w http.ResponseWriter, r *http.Request) {
 // CountAccountTransactions returns the total count of account transactions with optional filters
 func CountAccountTransactions(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("ACCOUNTS") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Accounts module is disabled")
+		utils.WriteModuleDisabledJson(w, "ACCOUNTS", "Accounts module is disabled")
 		return
 	}
 
@@ -0,0 +1,326 @@
+package routes
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/maintenance"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/starks"
+	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
+)
+
+// MaintenanceRequest is the body of an admin maintenance request
+type MaintenanceRequest struct {
+	Tables []string `json:"tables"` // empty means all managed tables
+	Vacuum bool     `json:"vacuum"`
+}
+
+// RunMaintenance runs ANALYZE (and optionally VACUUM) on zindex tables
+func RunMaintenance(w http.ResponseWriter, r *http.Request) {
+	if utils.AdminMiddleware(w, r) {
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		utils.WriteErrorJson(w, http.StatusMethodNotAllowed, "Method not allowed, use POST")
+		return
+	}
+
+	body, err := utils.ReadJsonBody[MaintenanceRequest](r)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tables := body.Tables
+	if len(tables) == 0 {
+		tables = maintenance.ManagedTables
+	}
+	for _, table := range tables {
+		if !maintenance.IsManagedTable(table) {
+			utils.WriteErrorJson(w, http.StatusBadRequest, fmt.Sprintf("Invalid table: %s", table))
+			return
+		}
+	}
+
+	results := maintenance.Run(r.Context(), tables, body.Vacuum)
+	utils.WriteDataJson(w, results)
+}
+
+// GetIndexReport reports unused indexes and the slowest queries
+func GetIndexReport(w http.ResponseWriter, r *http.Request) {
+	if utils.AdminMiddleware(w, r) {
+		return
+	}
+
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	limit, _ = utils.NormalizePagination(limit, 0)
+
+	report, err := maintenance.GetIndexReport(limit)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, report)
+}
+
+// maxVerifierLabelBatch caps the number of verifiers labeled per request
+const maxVerifierLabelBatch = 1000
+
+// VerifierLabelsRequest is the body of an admin verifier label update
+type VerifierLabelsRequest struct {
+	Verifiers []starks.VerifierLabel `json:"verifiers"`
+}
+
+// UpdateVerifierLabels sets curated names and metadata on known verifiers in bulk
+func UpdateVerifierLabels(w http.ResponseWriter, r *http.Request) {
+	if utils.AdminMiddleware(w, r) {
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		utils.WriteErrorJson(w, http.StatusMethodNotAllowed, "Method not allowed, use POST")
+		return
+	}
+
+	if !config.IsModuleEnabled("STARKS") {
+		utils.WriteModuleDisabledJson(w, "STARKS", "STARKS module is disabled")
+		return
+	}
+
+	body, err := utils.ReadJsonBody[VerifierLabelsRequest](r)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if len(body.Verifiers) == 0 {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing required field: verifiers")
+		return
+	}
+	if len(body.Verifiers) > maxVerifierLabelBatch {
+		utils.WriteErrorJson(w, http.StatusBadRequest, fmt.Sprintf("Too many verifiers, at most %d per request", maxVerifierLabelBatch))
+		return
+	}
+	for _, label := range body.Verifiers {
+		if label.VerifierID == "" {
+			utils.WriteErrorJson(w, http.StatusBadRequest, "Each entry requires a verifier_id")
+			return
+		}
+		if label.VerifierName == nil && label.VerifierMetadata == nil {
+			utils.WriteErrorJson(w, http.StatusBadRequest, fmt.Sprintf("Verifier %s: set verifier_name and/or verifier_metadata", label.VerifierID))
+			return
+		}
+		if label.VerifierName != nil && (*label.VerifierName == "" || len(*label.VerifierName) > 255) {
+			utils.WriteErrorJson(w, http.StatusBadRequest, fmt.Sprintf("Verifier %s: verifier_name must be 1-255 characters", label.VerifierID))
+			return
+		}
+	}
+
+	updated, unknown, err := starks.UpdateVerifierLabels(body.Verifiers)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, map[string]interface{}{
+		"updated": updated,
+		"unknown": unknown,
+	})
+}
+
+// CanonicalVerifierRequest is the body of an admin canonical verifier designation
+type CanonicalVerifierRequest struct {
+	VerifierID string `json:"verifier_id"`
+}
+
+// ManageCanonicalVerifier shows (GET), sets (POST) or clears (DELETE) the canonical Ztarknet verifier
+// Clearing only removes the admin designation; modules.starks.canonical_verifier_id then applies again
+func ManageCanonicalVerifier(w http.ResponseWriter, r *http.Request) {
+	if utils.AdminMiddleware(w, r) {
+		return
+	}
+
+	if !config.IsModuleEnabled("STARKS") {
+		utils.WriteModuleDisabledJson(w, "STARKS", "STARKS module is disabled")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		body, err := utils.ReadJsonBody[CanonicalVerifierRequest](r)
+		if err != nil {
+			utils.WriteErrorJson(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if body.VerifierID == "" {
+			utils.WriteErrorJson(w, http.StatusBadRequest, "Missing required field: verifier_id")
+			return
+		}
+
+		found, err := starks.SetCanonicalVerifier(body.VerifierID)
+		if err != nil {
+			utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !found {
+			utils.WriteErrorJson(w, http.StatusNotFound, "Verifier not found")
+			return
+		}
+	case http.MethodDelete:
+		if err := starks.ClearCanonicalVerifier(); err != nil {
+			utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	default:
+		utils.WriteErrorJson(w, http.StatusMethodNotAllowed, "Method not allowed, use GET, POST or DELETE")
+		return
+	}
+
+	verifierID, source, err := starks.CanonicalVerifier()
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, map[string]interface{}{
+		"verifier_id": verifierID,
+		"source":      source,
+	})
+}
+
+// maxFactImportBatch caps the number of facts imported per request
+const maxFactImportBatch = 10000
+
+// maxFactImportBytes caps the size of a fact import body, read before the batch size is checked
+// It leaves room for maxFactImportBatch facts with their hex fields and JSON or CSV overhead
+const maxFactImportBytes = 16 << 20
+
+// FactImportRequest is the JSON body of an admin fact import
+type FactImportRequest struct {
+	Facts     []starks.ImportedFact `json:"facts"`
+	Overwrite bool                  `json:"overwrite"`
+	DryRun    bool                  `json:"dry_run"`
+}
+
+// ImportZtarknetFacts ingests facts exported by a legacy fact-tracking service
+// The body is either JSON (FactImportRequest) or CSV with a header row of ImportedFact field
+// names, selected by Content-Type; for CSV, overwrite and dry_run are query parameters
+func ImportZtarknetFacts(w http.ResponseWriter, r *http.Request) {
+	if utils.AdminMiddleware(w, r) {
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		utils.WriteErrorJson(w, http.StatusMethodNotAllowed, "Method not allowed, use POST")
+		return
+	}
+
+	if !starks.ShouldIndexZtarknet() {
+		utils.WriteModuleDisabledJson(w, "STARKS", "Ztarknet indexing is disabled")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxFactImportBytes)
+	var body *FactImportRequest
+	var err error
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") {
+		body = &FactImportRequest{
+			Overwrite: utils.ParseQueryParam(r, "overwrite", "false") == "true",
+			DryRun:    utils.ParseQueryParam(r, "dry_run", "false") == "true",
+		}
+		body.Facts, err = parseFactsCsv(r.Body)
+	} else {
+		body, err = utils.ReadJsonBody[FactImportRequest](r)
+	}
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if len(body.Facts) == 0 {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing required field: facts")
+		return
+	}
+	if len(body.Facts) > maxFactImportBatch {
+		utils.WriteErrorJson(w, http.StatusBadRequest, fmt.Sprintf("Too many facts, at most %d per request", maxFactImportBatch))
+		return
+	}
+
+	result, err := starks.ImportFacts(body.Facts, body.Overwrite, body.DryRun)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, result)
+}
+
+// parseFactsCsv reads facts from CSV whose header row names the columns
+// verifier_id, txid, old_state, new_state, program_hash and inner_program_hash are required;
+// proof_size is optional and other columns are ignored
+func parseFactsCsv(body io.Reader) ([]starks.ImportedFact, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"verifier_id", "txid", "old_state", "new_state", "program_hash", "inner_program_hash"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("CSV is missing required column: %s", name)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	facts := []starks.ImportedFact{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		if len(facts) == maxFactImportBatch {
+			return nil, fmt.Errorf("too many facts, at most %d per request", maxFactImportBatch)
+		}
+
+		fact := starks.ImportedFact{
+			VerifierID:       field(record, "verifier_id"),
+			TxID:             field(record, "txid"),
+			OldState:         field(record, "old_state"),
+			NewState:         field(record, "new_state"),
+			ProgramHash:      field(record, "program_hash"),
+			InnerProgramHash: field(record, "inner_program_hash"),
+		}
+		if size := strings.TrimSpace(field(record, "proof_size")); size != "" {
+			fact.ProofSize, err = strconv.ParseInt(size, 10, 64)
+			if err != nil {
+				line, _ := reader.FieldPos(0)
+				return nil, fmt.Errorf("invalid proof_size on line %d: %s", line, size)
+			}
+		}
+		facts = append(facts, fact)
+	}
+
+	return facts, nil
+}
@@ -0,0 +1,123 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/alerts"
+	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
+)
+
+// AlertRuleRequest is the body of an admin alert rule creation
+type AlertRuleRequest struct {
+	Name        string `json:"name"`
+	Kind        string `json:"kind"`
+	Threshold   int64  `json:"threshold"`
+	VerifierID  string `json:"verifier_id"`
+	ProgramHash string `json:"program_hash"`
+	Blocks      int64  `json:"blocks"`
+}
+
+// ManageAlertRules lists (GET), adds (POST) or removes (DELETE) alert rules
+// Rules from the configuration are listed but can only be changed there
+func ManageAlertRules(w http.ResponseWriter, r *http.Request) {
+	if utils.AdminMiddleware(w, r) {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		rules, err := alerts.GetRules(r.Context())
+		if err != nil {
+			utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		utils.WriteDataJson(w, rules)
+	case http.MethodPost:
+		createAlertRule(w, r)
+	case http.MethodDelete:
+		deleteAlertRule(w, r)
+	default:
+		utils.WriteErrorJson(w, http.StatusMethodNotAllowed, "Method not allowed, use GET, POST or DELETE")
+	}
+}
+
+func createAlertRule(w http.ResponseWriter, r *http.Request) {
+	body, err := utils.ReadJsonBody[AlertRuleRequest](r)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rule := alerts.Rule{
+		Name:        body.Name,
+		Kind:        body.Kind,
+		Threshold:   body.Threshold,
+		VerifierID:  body.VerifierID,
+		ProgramHash: body.ProgramHash,
+		Blocks:      body.Blocks,
+	}
+	if err := alerts.ValidateRule(&rule); err != nil {
+		utils.WriteErrorJson(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	created, err := alerts.CreateRule(rule)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if created == nil {
+		utils.WriteErrorJson(w, http.StatusConflict, "Alert rule already exists")
+		return
+	}
+
+	utils.WriteDataJson(w, created)
+}
+
+func deleteAlertRule(w http.ResponseWriter, r *http.Request) {
+	name := utils.ParseQueryParam(r, "name", "")
+	if name == "" {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing required parameter: name")
+		return
+	}
+
+	deleted, err := alerts.DeleteRule(name)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !deleted {
+		utils.WriteErrorJson(w, http.StatusNotFound, "Alert rule not found (rules from the configuration cannot be deleted)")
+		return
+	}
+
+	utils.WriteDataJson(w, map[string]interface{}{
+		"deleted": name,
+	})
+}
+
+// GetAlerts lists fired alerts newest first, optionally filtered by rule and status
+func GetAlerts(w http.ResponseWriter, r *http.Request) {
+	if utils.AdminMiddleware(w, r) {
+		return
+	}
+
+	rule := utils.ParseQueryParam(r, "rule", "")
+	status := utils.ParseQueryParam(r, "status", "")
+	if status != "" && !alerts.IsStatus(status) {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Invalid status parameter. Must be one of: firing, resolved, triggered")
+		return
+	}
+
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	offset := utils.ParseQueryParamInt(r, "offset", 0)
+	limit, offset = utils.NormalizePagination(limit, offset)
+
+	firedAlerts, err := alerts.GetAlerts(rule, status, limit, offset)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, firedAlerts)
+}
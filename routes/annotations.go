@@ -0,0 +1,144 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/annotations"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/usage"
+	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
+)
+
+// writeEntityJson writes an entity response, adding its annotations next to the data when
+// the request asks for them with annotations=true
+func writeEntityJson(w http.ResponseWriter, r *http.Request, entityType, entityID string, data interface{}) {
+	if utils.ParseQueryParam(r, "annotations", "") != "true" {
+		utils.WriteDataJson(w, data)
+		return
+	}
+
+	entityAnnotations, err := annotations.GetAnnotations(entityType, entityID)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteAnnotatedDataJson(w, data, entityAnnotations)
+}
+
+// GetAnnotations retrieves the annotations of one entity, or with key set, the annotations
+// with that key across every entity of the type
+func GetAnnotations(w http.ResponseWriter, r *http.Request) {
+	entityType := utils.ParseQueryParam(r, "entity_type", "")
+	if !annotations.IsEntityType(entityType) {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing or invalid required parameter: entity_type (block, tx, verifier or address)")
+		return
+	}
+
+	entityID := utils.ParseQueryParam(r, "entity_id", "")
+	key := utils.ParseQueryParam(r, "key", "")
+
+	if entityID != "" {
+		entityAnnotations, err := annotations.GetAnnotations(entityType, entityID)
+		if err != nil {
+			utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		utils.WriteDataJson(w, entityAnnotations)
+		return
+	}
+
+	if key == "" {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing required parameter: entity_id or key")
+		return
+	}
+
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	offset := utils.ParseQueryParamInt(r, "offset", 0)
+	limit, offset = utils.NormalizePagination(limit, offset)
+
+	keyAnnotations, err := annotations.GetAnnotationsByKey(entityType, key, limit, offset)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, keyAnnotations)
+}
+
+// AnnotationRequest is the body of an admin annotation update
+type AnnotationRequest struct {
+	EntityType string `json:"entity_type"`
+	EntityID   string `json:"entity_id"`
+	Key        string `json:"key"`
+	Value      string `json:"value"`
+}
+
+// ManageAnnotations sets an annotation (POST) or removes annotations (DELETE)
+// The author of a set annotation is the usage key id of the caller's API key
+func ManageAnnotations(w http.ResponseWriter, r *http.Request) {
+	if utils.AdminMiddleware(w, r) {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		setAnnotation(w, r)
+	case http.MethodDelete:
+		deleteAnnotations(w, r)
+	default:
+		utils.WriteErrorJson(w, http.StatusMethodNotAllowed, "Method not allowed, use POST or DELETE")
+	}
+}
+
+func setAnnotation(w http.ResponseWriter, r *http.Request) {
+	body, err := utils.ReadJsonBody[AnnotationRequest](r)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	annotation := annotations.Annotation{
+		EntityType: body.EntityType,
+		EntityID:   body.EntityID,
+		Key:        body.Key,
+		Value:      body.Value,
+		Author:     usage.KeyID(utils.RequestApiKey(r)),
+	}
+	if err := annotations.Validate(&annotation); err != nil {
+		utils.WriteErrorJson(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	stored, err := annotations.SetAnnotation(annotation)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, stored)
+}
+
+// deleteAnnotations removes the annotation named by key, or every annotation of the entity without key
+func deleteAnnotations(w http.ResponseWriter, r *http.Request) {
+	entityType := utils.ParseQueryParam(r, "entity_type", "")
+	if !annotations.IsEntityType(entityType) {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing or invalid required parameter: entity_type (block, tx, verifier or address)")
+		return
+	}
+
+	entityID := utils.ParseQueryParam(r, "entity_id", "")
+	if entityID == "" {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing required parameter: entity_id")
+		return
+	}
+
+	deleted, err := annotations.DeleteAnnotation(entityType, entityID, utils.ParseQueryParam(r, "key", ""))
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, map[string]interface{}{
+		"deleted": deleted,
+	})
+}
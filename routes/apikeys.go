@@ -0,0 +1,98 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/apikeys"
+	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
+)
+
+// authMiddleware applies utils.AuthMiddleware to every request before it reaches the rate limiter
+func authMiddleware(next http.Handler) http.Handler {
+	if !apikeys.Enabled() {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if utils.AuthMiddleware(w, r) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ApiKeyRequest is the body of an admin API key creation
+type ApiKeyRequest struct {
+	Name              string  `json:"name"`
+	Scope             string  `json:"scope"`
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	Burst             int     `json:"burst"`
+}
+
+// ManageApiKeys lists (GET), creates (POST) or revokes (DELETE) API keys
+func ManageApiKeys(w http.ResponseWriter, r *http.Request) {
+	if utils.AdminMiddleware(w, r) {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		keys, err := apikeys.GetKeys(utils.ParseQueryParam(r, "include_revoked", "") == "true")
+		if err != nil {
+			utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		utils.WriteDataJson(w, keys)
+	case http.MethodPost:
+		createApiKey(w, r)
+	case http.MethodDelete:
+		revokeApiKey(w, r)
+	default:
+		utils.WriteErrorJson(w, http.StatusMethodNotAllowed, "Method not allowed, use GET, POST or DELETE")
+	}
+}
+
+// createApiKey stores a key and returns it with the key itself, shown only this once
+func createApiKey(w http.ResponseWriter, r *http.Request) {
+	body, err := utils.ReadJsonBody[ApiKeyRequest](r)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	key := apikeys.Key{Name: body.Name, Scope: body.Scope, RequestsPerSecond: body.RequestsPerSecond, Burst: body.Burst}
+	if err := apikeys.Validate(&key); err != nil {
+		utils.WriteErrorJson(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	created, err := apikeys.CreateKey(key)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, created)
+}
+
+func revokeApiKey(w http.ResponseWriter, r *http.Request) {
+	keyID := utils.ParseQueryParam(r, "key_id", "")
+	if keyID == "" {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing required parameter: key_id")
+		return
+	}
+
+	revoked, err := apikeys.RevokeKey(keyID)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !revoked {
+		utils.WriteErrorJson(w, http.StatusNotFound, "API key not found or already revoked")
+		return
+	}
+
+	utils.WriteDataJson(w, map[string]interface{}{
+		"revoked": keyID,
+	})
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"net/http"
 
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/annotations"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/blocks"
 	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
 )
@@ -26,7 +27,7 @@ func GetBlock(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	utils.WriteDataJson(w, block)
+	writeEntityJson(w, r, annotations.EntityBlock, block.Hash, block)
 }
 
 // GetBlockByHash retrieves a single block by hash
@@ -48,7 +49,7 @@ func GetBlockByHash(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	utils.WriteDataJson(w, block)
+	writeEntityJson(w, r, annotations.EntityBlock, block.Hash, block)
 }
 
 // GetBlocks retrieves blocks with pagination
@@ -63,7 +64,7 @@ func GetBlocks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	utils.WriteDataJson(w, blockList)
+	utils.WritePageJson(w, r, blockList, limit, offset, blocks.GetBlockCount)
 }
 
 // GetBlocksByRange retrieves blocks within a height range
@@ -170,3 +171,19 @@ func GetLatestBlock(w http.ResponseWriter, r *http.Request) {
 
 	utils.WriteDataJson(w, block)
 }
+
+// GetLatestFinalizedBlock retrieves the most recent block that has reached the configured finality depth
+func GetLatestFinalizedBlock(w http.ResponseWriter, r *http.Request) {
+	block, err := blocks.GetLatestFinalizedBlock()
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if block == nil {
+		utils.WriteErrorJson(w, http.StatusNotFound, "No finalized blocks found")
+		return
+	}
+
+	utils.WriteDataJson(w, block)
+}
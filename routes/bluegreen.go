@@ -0,0 +1,82 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/bluegreen"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
+)
+
+// SchemaHeader names the schema a request is meant for; a standby deployment serves requests
+// naming its own schema, so it can be checked before the switch
+const SchemaHeader = "X-ZIndex-Schema"
+
+// standbyMiddleware answers API requests with 503 while this deployment's schema is not the
+// active one under api.blue_green. Admin routes stay available so the switch can be made from here
+func standbyMiddleware(next http.Handler) http.Handler {
+	if !bluegreen.Enabled() {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(SchemaHeader, postgres.Schema())
+		if bluegreen.Serving() ||
+			!strings.HasPrefix(r.URL.Path, "/api/v1/") ||
+			strings.HasPrefix(r.URL.Path, "/api/v1/admin/") ||
+			r.Header.Get(SchemaHeader) == postgres.Schema() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		status := bluegreen.GetStatus()
+		utils.WriteErrorJson(w, http.StatusServiceUnavailable,
+			"This deployment (schema "+status.Schema+") is on standby, schema "+status.ActiveSchema+" is active")
+	})
+}
+
+// Ready reports whether this deployment serves the API, for load balancer readiness checks:
+// 503 while it is on standby under api.blue_green
+func Ready(w http.ResponseWriter, r *http.Request) {
+	if !bluegreen.Serving() {
+		utils.WriteErrorJson(w, http.StatusServiceUnavailable, "standby")
+		return
+	}
+	utils.WriteResultJson(w, "ready")
+}
+
+// ManageActiveSchema reports the blue/green state (GET) or switches the active schema (POST)
+func ManageActiveSchema(w http.ResponseWriter, r *http.Request) {
+	if utils.AdminMiddleware(w, r) {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		utils.WriteDataJson(w, bluegreen.GetStatus())
+	case http.MethodPost:
+		if !bluegreen.Enabled() {
+			utils.WriteErrorJson(w, http.StatusNotImplemented, "Blue/green deployment is disabled (api.blue_green.enabled)")
+			return
+		}
+		schema := utils.ParseQueryParam(r, "schema", "")
+		if !config.SchemaNamePattern.MatchString(schema) {
+			utils.WriteErrorJson(w, http.StatusBadRequest, "Missing or invalid required parameter: schema")
+			return
+		}
+		if err := bluegreen.Activate(r.Context(), schema); err != nil {
+			if errors.Is(err, bluegreen.ErrNotIndexed) {
+				utils.WriteErrorJson(w, http.StatusBadRequest, "Schema "+schema+" has no zindex tables")
+				return
+			}
+			utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		utils.WriteDataJson(w, bluegreen.GetStatus())
+	default:
+		utils.WriteErrorJson(w, http.StatusMethodNotAllowed, "Method not allowed, use GET or POST")
+	}
+}
@@ -0,0 +1,54 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/indexer"
+	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
+)
+
+// LagHeader carries the indexer's sync lag in blocks while it exceeds api.degraded_mode.lag_threshold
+const LagHeader = "X-ZIndex-Lag"
+
+// staleLag returns the current sync lag and whether it exceeds the configured threshold
+func staleLag() (int64, bool) {
+	threshold := config.Conf.Api.DegradedMode.LagThreshold
+	if threshold <= 0 {
+		return 0, false
+	}
+
+	lag, ok := indexer.SyncLag()
+	if !ok || lag <= threshold {
+		return lag, false
+	}
+	return lag, true
+}
+
+// degradedModeMiddleware adds the lag header to every response while the indexer is lagging
+func degradedModeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if lag, stale := staleLag(); stale {
+			w.Header().Set(LagHeader, strconv.FormatInt(lag, 10))
+			w.Header().Set("Access-Control-Expose-Headers", LagHeader)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireFresh flags an endpoint as requiring fresh data: while the indexer is lagging
+// and api.degraded_mode.reject_stale is enabled, it returns 503 instead of stale state
+func requireFresh(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if config.Conf.Api.DegradedMode.RejectStale {
+			if lag, stale := staleLag(); stale {
+				utils.WriteErrorJson(w, http.StatusServiceUnavailable,
+					fmt.Sprintf("Indexer is %d blocks behind the node tip, data may be stale", lag))
+				return
+			}
+		}
+		handler(w, r)
+	}
+}
@@ -0,0 +1,118 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/provider"
+	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
+)
+
+const (
+	// maxGenerateBlocks caps the number of blocks mined per request
+	maxGenerateBlocks = 1000
+	// maxGenerateWait caps how long a request waits for the indexer to reach the mined blocks
+	maxGenerateWait = 60 * time.Second
+	// generateWaitPoll is how often the indexed height is checked while waiting
+	generateWaitPoll = 250 * time.Millisecond
+)
+
+// GenerateRequest is the body of a dev mode block generation request
+type GenerateRequest struct {
+	Blocks      int    `json:"blocks"`
+	Address     string `json:"address"`      // coinbase recipient; empty uses the node's miner address
+	WaitSeconds int    `json:"wait_seconds"` // wait up to this long for the blocks to be indexed
+}
+
+// GenerateResponse lists the mined blocks and, when waited for, whether they were indexed
+type GenerateResponse struct {
+	Hashes        []string `json:"hashes"`
+	Height        int64    `json:"height"` // node height after mining
+	IndexedHeight int64    `json:"indexed_height"`
+	Indexed       bool     `json:"indexed"`
+	WaitedSeconds float64  `json:"waited_seconds"`
+}
+
+// GenerateBlocks mines blocks on a regtest node (rpc.dev_mode), optionally waiting until zindex
+// has indexed them so end-to-end tests can query the results right away
+func GenerateBlocks(w http.ResponseWriter, r *http.Request) {
+	if utils.AdminMiddleware(w, r) {
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		utils.WriteErrorJson(w, http.StatusMethodNotAllowed, "Method not allowed, use POST")
+		return
+	}
+
+	body, err := utils.ReadJsonBody[GenerateRequest](r)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if body.Blocks == 0 {
+		body.Blocks = 1
+	}
+	if body.Blocks < 0 || body.Blocks > maxGenerateBlocks {
+		utils.WriteErrorJson(w, http.StatusBadRequest, fmt.Sprintf("blocks must be between 1 and %d", maxGenerateBlocks))
+		return
+	}
+	wait := time.Duration(body.WaitSeconds) * time.Second
+	if wait < 0 || wait > maxGenerateWait {
+		utils.WriteErrorJson(w, http.StatusBadRequest, fmt.Sprintf("wait_seconds must be between 0 and %d", int(maxGenerateWait.Seconds())))
+		return
+	}
+	// Leave time to write the response before api.write_timeout closes the connection
+	if writeTimeout := time.Duration(config.Conf.Api.WriteTimeout) * time.Second; writeTimeout > 0 && wait > writeTimeout-time.Second {
+		wait = writeTimeout - time.Second
+	}
+
+	// Refuse to mine against anything but a regtest node, whatever the config says
+	chain, err := provider.GetChain()
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	if chain != "regtest" {
+		utils.WriteErrorJson(w, http.StatusConflict, fmt.Sprintf("Node is on %s, dev mode only mines on regtest", chain))
+		return
+	}
+
+	hashes, err := provider.GenerateBlocks(body.Blocks, body.Address)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	height, err := provider.GetBlockCount()
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	response := GenerateResponse{Hashes: hashes, Height: height}
+	started := time.Now()
+	for {
+		response.IndexedHeight, err = postgres.GetLastIndexedBlock()
+		if err != nil {
+			utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		response.Indexed = response.IndexedHeight >= height
+		if response.Indexed || time.Since(started) >= wait {
+			break
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(generateWaitPoll):
+		}
+	}
+	response.WaitedSeconds = time.Since(started).Seconds()
+
+	utils.WriteDataJson(w, response)
+}
@@ -0,0 +1,40 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/index_log"
+	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
+)
+
+// IndexLogResponse is a page of the index log with its total size
+type IndexLogResponse struct {
+	Total   int64             `json:"total"`
+	Entries []index_log.Entry `json:"entries"`
+}
+
+// GetIndexLog lists block payloads the indexer rejected, newest first
+func GetIndexLog(w http.ResponseWriter, r *http.Request) {
+	if utils.AdminMiddleware(w, r) {
+		return
+	}
+
+	stage := utils.ParseQueryParam(r, "stage", "")
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	offset := utils.ParseQueryParamInt(r, "offset", 0)
+	limit, offset = utils.NormalizePagination(limit, offset)
+
+	total, err := index_log.CountEntries(stage)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	entries, err := index_log.GetEntries(stage, limit, offset)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, IndexLogResponse{Total: total, Entries: entries})
+}
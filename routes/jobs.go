@@ -0,0 +1,177 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/jobs"
+	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
+)
+
+// EnqueueJobRequest is the body of an admin job request
+type EnqueueJobRequest struct {
+	Kind    string          `json:"kind"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// ManageJobs lists jobs newest first (GET), optionally filtered by kind and status, or enqueues one (POST)
+func ManageJobs(w http.ResponseWriter, r *http.Request) {
+	if utils.AdminMiddleware(w, r) {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		listJobs(w, r)
+	case http.MethodPost:
+		enqueueJob(w, r)
+	default:
+		utils.WriteErrorJson(w, http.StatusMethodNotAllowed, "Method not allowed, use GET or POST")
+	}
+}
+
+func listJobs(w http.ResponseWriter, r *http.Request) {
+	kind := utils.ParseQueryParam(r, "kind", "")
+	status := utils.ParseQueryParam(r, "status", "")
+	if status != "" && !jobs.IsStatus(status) {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Invalid status parameter. Must be one of: queued, running, succeeded, failed")
+		return
+	}
+
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	offset := utils.ParseQueryParamInt(r, "offset", 0)
+	limit, offset = utils.NormalizePagination(limit, offset)
+
+	list, err := jobs.GetJobs(kind, status, limit, offset)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, list)
+}
+
+// enqueueJob queues a job of a registered kind; periodic kinds can be run ahead of their schedule
+func enqueueJob(w http.ResponseWriter, r *http.Request) {
+	body, err := utils.ReadJsonBody[EnqueueJobRequest](r)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if body.Kind == "" {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing required field: kind")
+		return
+	}
+	if !jobs.IsKind(body.Kind) {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Unknown job kind: "+body.Kind)
+		return
+	}
+
+	var payload any
+	if len(body.Payload) > 0 && string(body.Payload) != "null" {
+		payload = body.Payload
+	}
+
+	job, err := jobs.Enqueue(r.Context(), body.Kind, payload)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, job)
+}
+
+// GetJobKinds lists the registered job kinds with the number of jobs of each kind per status
+func GetJobKinds(w http.ResponseWriter, r *http.Request) {
+	if utils.AdminMiddleware(w, r) {
+		return
+	}
+
+	counts, err := jobs.CountJobs()
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, map[string]interface{}{
+		"kinds":  jobs.Kinds(),
+		"counts": counts,
+	})
+}
+
+// GetJob returns one job with its payload, result and last error
+func GetJob(w http.ResponseWriter, r *http.Request) {
+	if utils.AdminMiddleware(w, r) {
+		return
+	}
+
+	id, ok := parseJobID(w, r)
+	if !ok {
+		return
+	}
+
+	job, err := jobs.GetJob(id)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if job == nil {
+		utils.WriteErrorJson(w, http.StatusNotFound, "Job not found")
+		return
+	}
+
+	utils.WriteDataJson(w, job)
+}
+
+// RetryJob queues a failed job again with a fresh attempt budget
+func RetryJob(w http.ResponseWriter, r *http.Request) {
+	if utils.AdminMiddleware(w, r) {
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		utils.WriteErrorJson(w, http.StatusMethodNotAllowed, "Method not allowed, use POST")
+		return
+	}
+
+	id, ok := parseJobID(w, r)
+	if !ok {
+		return
+	}
+
+	job, err := jobs.Retry(id)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if job != nil {
+		utils.WriteDataJson(w, job)
+		return
+	}
+
+	// Nothing was queued: tell a missing job from one that cannot be retried
+	existing, err := jobs.GetJob(id)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if existing == nil {
+		utils.WriteErrorJson(w, http.StatusNotFound, "Job not found")
+		return
+	}
+	if existing.Status != jobs.StatusFailed {
+		utils.WriteErrorJson(w, http.StatusConflict, "Only failed jobs can be retried; job is "+existing.Status)
+		return
+	}
+	utils.WriteErrorJson(w, http.StatusConflict, "Another run of this periodic job is already queued")
+}
+
+func parseJobID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Invalid job id")
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,115 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/mempool"
+	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
+)
+
+// validMempoolStatus reports whether status is empty or a mempool entry status
+func validMempoolStatus(status string) bool {
+	switch status {
+	case "", mempool.StatusPending, mempool.StatusConfirmed, mempool.StatusEvicted:
+		return true
+	}
+	return false
+}
+
+// GetMempoolTransaction retrieves a tracked mempool transaction by txid
+func GetMempoolTransaction(w http.ResponseWriter, r *http.Request) {
+	if !config.IsModuleEnabled("MEMPOOL") {
+		utils.WriteModuleDisabledJson(w, "MEMPOOL", "Mempool module is disabled")
+		return
+	}
+
+	txid := utils.ParseQueryParam(r, "txid", "")
+	if txid == "" {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing required parameter: txid")
+		return
+	}
+
+	tx, err := mempool.GetMempoolTransaction(txid)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if tx == nil {
+		utils.WriteErrorJson(w, http.StatusNotFound, "Mempool transaction not found")
+		return
+	}
+
+	utils.WriteDataJson(w, tx)
+}
+
+// GetMempoolTransactions retrieves tracked mempool transactions, pending ones by default
+func GetMempoolTransactions(w http.ResponseWriter, r *http.Request) {
+	if !config.IsModuleEnabled("MEMPOOL") {
+		utils.WriteModuleDisabledJson(w, "MEMPOOL", "Mempool module is disabled")
+		return
+	}
+
+	status := utils.ParseQueryParam(r, "status", mempool.StatusPending)
+	if status == "all" {
+		status = ""
+	}
+	if !validMempoolStatus(status) {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Invalid status. Must be one of: pending, confirmed, evicted, all")
+		return
+	}
+
+	txType := utils.ParseQueryParam(r, "type", "")
+	tzeSubtype := utils.ParseQueryParam(r, "tze_subtype", "")
+
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	offset := utils.ParseQueryParamInt(r, "offset", 0)
+	limit, offset = utils.NormalizePagination(limit, offset)
+
+	txs, err := mempool.GetMempoolTransactions(status, txType, tzeSubtype, limit, offset)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, txs)
+}
+
+// CountMempoolTransactions returns the number of tracked mempool transactions with optional filters
+func CountMempoolTransactions(w http.ResponseWriter, r *http.Request) {
+	if !config.IsModuleEnabled("MEMPOOL") {
+		utils.WriteModuleDisabledJson(w, "MEMPOOL", "Mempool module is disabled")
+		return
+	}
+
+	status := utils.ParseQueryParam(r, "status", "")
+	if !validMempoolStatus(status) {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Invalid status. Must be one of: pending, confirmed, evicted")
+		return
+	}
+
+	count, err := mempool.CountMempoolTransactions(status, utils.ParseQueryParam(r, "type", ""))
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, map[string]int64{"count": count})
+}
+
+// GetMempoolSummary summarizes the pending mempool transactions
+func GetMempoolSummary(w http.ResponseWriter, r *http.Request) {
+	if !config.IsModuleEnabled("MEMPOOL") {
+		utils.WriteModuleDisabledJson(w, "MEMPOOL", "Mempool module is disabled")
+		return
+	}
+
+	summary, err := mempool.GetMempoolSummary()
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, summary)
+}
@@ -0,0 +1,79 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/metrics"
+)
+
+var (
+	httpRequestsTotal = metrics.NewCounter("zindex_http_requests_total",
+		"API requests by route and response status", "method", "route", "status")
+	httpRequestDuration = metrics.NewHistogram("zindex_http_request_duration_seconds",
+		"Time to serve an API request", nil, "method", "route")
+)
+
+// EnableMetricsRoutes serves Prometheus metrics at /metrics (api.metrics)
+func EnableMetricsRoutes(mux *http.ServeMux) {
+	if !config.Conf.Api.Metrics {
+		return
+	}
+	mux.HandleFunc("/metrics", metrics.Handler)
+}
+
+// metricsMiddleware counts and times every request by its registered route pattern, so paths
+// with ids in them do not create a series per id
+func metricsMiddleware(next http.Handler) http.Handler {
+	if !config.Conf.Api.Metrics {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &usageRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		httpRequestsTotal.Inc(r.Method, route, strconv.Itoa(status))
+		httpRequestDuration.ObserveWithExemplar(time.Since(start).Seconds(), traceID(r), r.Method, route)
+	})
+}
+
+// traceID returns the trace id of a W3C traceparent header (version-traceid-parentid-flags),
+// or "" when the request carries none or a malformed one
+// It becomes the exemplar of the latency bucket, linking a slow request to its trace
+func traceID(r *http.Request) string {
+	parts := strings.Split(r.Header.Get("traceparent"), "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return ""
+	}
+	for _, part := range parts {
+		if !isLowerHex(part) {
+			return ""
+		}
+	}
+	if strings.Trim(parts[1], "0") == "" {
+		return ""
+	}
+	return parts[1]
+}
+
+func isLowerHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
+)
+
+// ModuleInfo describes one indexing module of this deployment and the limits its endpoints apply
+type ModuleInfo struct {
+	Name     string                 `json:"name"`
+	Enabled  bool                   `json:"enabled"`
+	Settings map[string]interface{} `json:"settings"`
+}
+
+// GetModules lists every module with its enabled status and client-relevant settings
+// Secrets such as the fact export signing key are reported only as whether they are set
+func GetModules(w http.ResponseWriter, r *http.Request) {
+	modules := config.Conf.Modules
+
+	utils.WriteDataJson(w, []ModuleInfo{
+		{
+			Name:     "BLOCKS",
+			Enabled:  true,
+			Settings: map[string]interface{}{},
+		},
+		{
+			Name:    "TX_GRAPH",
+			Enabled: modules.TxGraph.Enabled,
+			Settings: map[string]interface{}{
+				"max_graph_depth": modules.TxGraph.MaxGraphDepth,
+			},
+		},
+		{
+			Name:    "TZE_GRAPH",
+			Enabled: modules.TzeGraph.Enabled,
+			Settings: map[string]interface{}{
+				"max_precondition_size": modules.TzeGraph.MaxPreconditionSize,
+				"max_graph_depth":       modules.TzeGraph.MaxGraphDepth,
+			},
+		},
+		{
+			Name:    "STARKS",
+			Enabled: modules.Starks.Enabled,
+			Settings: map[string]interface{}{
+				"index_ztarknet":      modules.Starks.IndexZtarknet,
+				"signed_fact_exports": modules.Starks.ExportSigningKey != "",
+			},
+		},
+		{
+			Name:    "ACCOUNTS",
+			Enabled: modules.Accounts.Enabled,
+			Settings: map[string]interface{}{
+				"track_shielded": modules.Accounts.TrackShielded,
+			},
+		},
+		{
+			Name:    "MEMPOOL",
+			Enabled: modules.Mempool.Enabled,
+			Settings: map[string]interface{}{
+				"poll_interval":   modules.Mempool.PollInterval,
+				"retention_hours": modules.Mempool.Retention,
+			},
+		},
+	})
+}
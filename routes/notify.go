@@ -0,0 +1,36 @@
+package routes
+
+import (
+	"encoding/hex"
+	"net/http"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/indexer"
+	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
+)
+
+// NotifyBlock wakes the indexer when the node announces a block, e.g. from zcashd's
+// -blocknotify='curl -s -X POST http://zindex:8080/api/v1/admin/notify/block?hash=%s'
+// The hash is only recorded in /status; the indexer reads new blocks from RPC as usual
+func NotifyBlock(w http.ResponseWriter, r *http.Request) {
+	if utils.AdminMiddleware(w, r) {
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		utils.WriteErrorJson(w, http.StatusMethodNotAllowed, "Method not allowed, use POST")
+		return
+	}
+
+	hash := utils.ParseQueryParam(r, "hash", "")
+	if hash != "" {
+		if _, err := hex.DecodeString(hash); err != nil || len(hash) != 64 {
+			utils.WriteErrorJson(w, http.StatusBadRequest, "Invalid hash parameter. Must be a 64 character hex block hash")
+			return
+		}
+	}
+
+	indexer.NotifyNewBlock(hash, "http")
+	utils.WriteDataJson(w, map[string]interface{}{
+		"notified": true,
+	})
+}
@@ -0,0 +1,101 @@
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/indexer"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/reorg"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/starks"
+	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
+)
+
+const (
+	// overviewWindow is the window of the proof activity reported by the overview
+	overviewWindow = 24 * time.Hour
+	// overviewReorgs is the number of recent reorgs listed by the overview
+	overviewReorgs = 5
+)
+
+// OverviewSync reports how far the indexer trails the node
+// Both are only known on an instance running the indexer, and null elsewhere
+type OverviewSync struct {
+	ChainHeight *int64 `json:"chain_height"`
+	Lag         *int64 `json:"lag"`
+}
+
+// ZtarknetOverview gathers what a rollup status page shows in one response
+type ZtarknetOverview struct {
+	// CanonicalVerifier is the latest state of the canonical verifier, null when none is
+	// designated, it has no facts yet or Ztarknet indexing is disabled
+	CanonicalVerifier *starks.ZtarknetState `json:"canonical_verifier"`
+	L1Height          int64                 `json:"l1_height"`       // last indexed block
+	L2BlockNumber     *int64                `json:"l2_block_number"` // latest L2 block proven by the canonical verifier
+	Proofs24h         *starks.ProofActivity `json:"proofs_24h"`      // every verifier, by block timestamp
+	Sync              OverviewSync          `json:"sync"`
+	RecentReorgs      []reorg.Reorg         `json:"recent_reorgs"`
+}
+
+// GetZtarknetOverview returns the canonical verifier state, the proof activity of all verifiers
+// over the last 24 hours, the L1 and L2 heights, the sync lag and the most recent reorgs
+func GetZtarknetOverview(w http.ResponseWriter, r *http.Request) {
+	if !config.IsModuleEnabled("STARKS") {
+		utils.WriteModuleDisabledJson(w, "STARKS", "STARKS module is disabled")
+		return
+	}
+
+	var overview ZtarknetOverview
+
+	if starks.ShouldIndexZtarknet() {
+		verifierID, source, err := starks.CanonicalVerifier()
+		if err != nil {
+			utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if verifierID != "" {
+			state, err := starks.GetZtarknetState(verifierID)
+			if err != nil {
+				utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			if state != nil {
+				state.CanonicalSource = source
+				overview.CanonicalVerifier = state
+				overview.L2BlockNumber = state.L2BlockNumber
+			}
+		}
+	}
+
+	activity, err := starks.GetProofActivity(time.Now().Add(-overviewWindow).Unix())
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	overview.Proofs24h = activity
+
+	lastBlock, err := postgres.GetLastIndexedBlock()
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	overview.L1Height = lastBlock
+	if lag, ok := indexer.SyncLag(); ok {
+		chainHeight := indexer.GetLoopStatus().ChainHeight
+		overview.Sync.ChainHeight = &chainHeight
+		overview.Sync.Lag = &lag
+	}
+
+	reorgs, err := reorg.GetRecentReorgs(overviewReorgs)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if reorgs == nil {
+		reorgs = []reorg.Reorg{}
+	}
+	overview.RecentReorgs = reorgs
+
+	utils.WriteDataJson(w, overview)
+}
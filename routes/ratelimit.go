@@ -0,0 +1,175 @@
+package routes
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/metrics"
+	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
+)
+
+// rateLimitSweepInterval is how often buckets that have refilled completely are dropped
+const rateLimitSweepInterval = time.Minute
+
+var httpRequestsRateLimited = metrics.NewCounter("zindex_http_requests_rate_limited_total",
+	"API requests rejected because their client IP was over its rate limit", "route")
+
+// tokenBucket holds the requests one client may still make on one route
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	limit  config.RateLimitRouteConfig
+}
+
+// bucketKey is a client on a rate-limited route; route is "" for the default limit
+// The client is the API key id for requests with a key and the remote IP otherwise
+type bucketKey struct {
+	route  string
+	client string
+}
+
+// rateLimiter keeps a token bucket per client and route
+type rateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[bucketKey]*tokenBucket
+	sweptAt   time.Time
+	defaults  config.RateLimitRouteConfig
+	overrides map[string]config.RateLimitRouteConfig
+}
+
+func newRateLimiter(conf config.RateLimitConfig) *rateLimiter {
+	return &rateLimiter{
+		buckets:   make(map[bucketKey]*tokenBucket),
+		sweptAt:   time.Now(),
+		defaults:  config.RateLimitRouteConfig{RequestsPerSecond: conf.RequestsPerSecond, Burst: conf.Burst},
+		overrides: conf.Routes,
+	}
+}
+
+// route returns the longest api.rate_limit.routes prefix matching path, or "" for the default limit
+func (l *rateLimiter) route(path string) (string, config.RateLimitRouteConfig) {
+	matched := ""
+	for prefix := range l.overrides {
+		if strings.HasPrefix(path, prefix) && len(prefix) > len(matched) {
+			matched = prefix
+		}
+	}
+	if matched == "" {
+		return "", l.defaults
+	}
+	return matched, l.overrides[matched]
+}
+
+// allow takes a token from the bucket of client on route
+// When the bucket is empty it returns false and how long until the next token
+func (l *rateLimiter) allow(key bucketKey, limit config.RateLimitRouteConfig, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.sweptAt) > rateLimitSweepInterval {
+		l.sweep(now)
+	}
+
+	bucket, ok := l.buckets[key]
+	if !ok || bucket.limit != limit {
+		bucket = &tokenBucket{tokens: float64(limit.Burst), last: now, limit: limit}
+		l.buckets[key] = bucket
+	}
+
+	bucket.tokens = math.Min(float64(limit.Burst), bucket.tokens+now.Sub(bucket.last).Seconds()*limit.RequestsPerSecond)
+	bucket.last = now
+	if bucket.tokens < 1 {
+		wait := time.Duration((1 - bucket.tokens) / limit.RequestsPerSecond * float64(time.Second))
+		return false, wait
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// sweep drops the buckets that would be full by now, which a new bucket recreates identically
+// (caller must hold the lock)
+func (l *rateLimiter) sweep(now time.Time) {
+	for key, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*bucket.limit.RequestsPerSecond >= float64(bucket.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+	l.sweptAt = now
+}
+
+// clientIP returns the IP of the connection's remote address
+// Forwarding headers are ignored since clients can set them freely
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// rateLimit picks the bucket and rate of a request, or returns false when it is not limited
+// A key with its own rate gets one bucket across every route; other requests follow api.rate_limit,
+// keyed by API key id when they have one so clients sharing an IP keep separate buckets
+func (l *rateLimiter) rateLimit(r *http.Request) (bucketKey, config.RateLimitRouteConfig, bool) {
+	key := utils.RequestKey(r)
+	if key != nil && key.HasRateLimit() {
+		limit := config.RateLimitRouteConfig{RequestsPerSecond: key.RequestsPerSecond, Burst: key.Burst}
+		return bucketKey{route: "key", client: key.KeyID}, limit, true
+	}
+	if !config.Conf.Api.RateLimit.Enabled {
+		return bucketKey{}, config.RateLimitRouteConfig{}, false
+	}
+
+	route, limit := l.route(r.URL.Path)
+	client := clientIP(r)
+	if key != nil {
+		client = key.KeyID
+	}
+	return bucketKey{route: route, client: client}, limit, true
+}
+
+// rateLimitMiddleware answers API requests of a client over its token bucket with 429 and
+// Retry-After, so scrapers of a public deployment cannot monopolise the database pool
+// It also enforces the rate limits of API keys under api.auth
+// Admin routes, /health, /status and /metrics are never limited
+func rateLimitMiddleware(next http.Handler) http.Handler {
+	if !config.Conf.Api.RateLimit.Enabled && !config.Conf.Api.Auth.Enabled {
+		return next
+	}
+	limiter := newRateLimiter(config.Conf.Api.RateLimit)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/v1/") || strings.HasPrefix(r.URL.Path, "/api/v1/admin/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		bucket, limit, limited := limiter.rateLimit(r)
+		if !limited {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowed, wait := limiter.allow(bucket, limit, time.Now())
+		if !allowed {
+			route := bucket.route
+			if route == "" {
+				route = "default"
+			}
+			httpRequestsRateLimited.Inc(route)
+
+			retryAfter := strconv.Itoa(int(math.Ceil(wait.Seconds())))
+			w.Header().Set("Retry-After", retryAfter)
+			utils.WriteErrorJson(w, http.StatusTooManyRequests, "Rate limit exceeded, retry in "+retryAfter+" seconds")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,121 @@
+package routes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	limit := config.RateLimitRouteConfig{RequestsPerSecond: 2, Burst: 3}
+	start := time.Unix(1700000000, 0)
+
+	type request struct {
+		after       time.Duration // since start
+		wantAllowed bool
+		wantWait    time.Duration
+	}
+	tests := []struct {
+		name     string
+		requests []request
+	}{
+		{
+			name: "burst then empty",
+			requests: []request{
+				{0, true, 0},
+				{0, true, 0},
+				{0, true, 0},
+				{0, false, 500 * time.Millisecond},
+			},
+		},
+		{
+			name: "refills at the rate",
+			requests: []request{
+				{0, true, 0},
+				{0, true, 0},
+				{0, true, 0},
+				{250 * time.Millisecond, false, 250 * time.Millisecond},
+				{500 * time.Millisecond, true, 0},
+				{500 * time.Millisecond, false, 500 * time.Millisecond},
+			},
+		},
+		{
+			name: "refill is capped at the burst",
+			requests: []request{
+				{0, true, 0},
+				{time.Hour, true, 0},
+				{time.Hour, true, 0},
+				{time.Hour, true, 0},
+				{time.Hour, false, 500 * time.Millisecond},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := newRateLimiter(config.RateLimitConfig{})
+			key := bucketKey{client: "192.0.2.1"}
+			for i, req := range tt.requests {
+				allowed, wait := limiter.allow(key, limit, start.Add(req.after))
+				if allowed != req.wantAllowed || wait != req.wantWait {
+					t.Fatalf("request %d: allow() = %v, %v, want %v, %v", i, allowed, wait, req.wantAllowed, req.wantWait)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterSeparateBuckets(t *testing.T) {
+	limit := config.RateLimitRouteConfig{RequestsPerSecond: 1, Burst: 1}
+	now := time.Unix(1700000000, 0)
+	limiter := newRateLimiter(config.RateLimitConfig{})
+
+	tests := []struct {
+		name        string
+		key         bucketKey
+		limit       config.RateLimitRouteConfig
+		wantAllowed bool
+	}{
+		{"first client", bucketKey{client: "a"}, limit, true},
+		{"first client again", bucketKey{client: "a"}, limit, false},
+		{"other client", bucketKey{client: "b"}, limit, true},
+		{"other route", bucketKey{route: "/api/v1/stats", client: "a"}, limit, true},
+		{"changed limit resets the bucket", bucketKey{client: "a"}, config.RateLimitRouteConfig{RequestsPerSecond: 1, Burst: 2}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if allowed, _ := limiter.allow(tt.key, tt.limit, now); allowed != tt.wantAllowed {
+				t.Errorf("allow() = %v, want %v", allowed, tt.wantAllowed)
+			}
+		})
+	}
+}
+
+func TestRateLimiterRoute(t *testing.T) {
+	limiter := newRateLimiter(config.RateLimitConfig{
+		RequestsPerSecond: 10,
+		Burst:             20,
+		Routes: map[string]config.RateLimitRouteConfig{
+			"/api/v1/stats":       {RequestsPerSecond: 1, Burst: 2},
+			"/api/v1/stats/chain": {RequestsPerSecond: 3, Burst: 4},
+		},
+	})
+
+	tests := []struct {
+		path      string
+		wantRoute string
+		wantLimit config.RateLimitRouteConfig
+	}{
+		{"/api/v1/blocks", "", config.RateLimitRouteConfig{RequestsPerSecond: 10, Burst: 20}},
+		{"/api/v1/stats/emission", "/api/v1/stats", config.RateLimitRouteConfig{RequestsPerSecond: 1, Burst: 2}},
+		{"/api/v1/stats/chain/diff", "/api/v1/stats/chain", config.RateLimitRouteConfig{RequestsPerSecond: 3, Burst: 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			route, limit := limiter.route(tt.path)
+			if route != tt.wantRoute || limit != tt.wantLimit {
+				t.Errorf("route(%q) = %q, %+v, want %q, %+v", tt.path, route, limit, tt.wantRoute, tt.wantLimit)
+			}
+		})
+	}
+}
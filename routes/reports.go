@@ -0,0 +1,113 @@
+package routes
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/reports"
+	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
+)
+
+// EnableReportRoutes registers the report routes when any report is configured
+func EnableReportRoutes(mux *http.ServeMux) {
+	if len(config.Conf.Reports) == 0 {
+		return
+	}
+	log.Println("Registering Report routes")
+
+	mux.HandleFunc("/api/v1/reports", GetReports)
+	mux.HandleFunc("/api/v1/reports/{name}", GetReport)
+}
+
+// GetReports lists the configured reports and the parameters they accept
+func GetReports(w http.ResponseWriter, r *http.Request) {
+	utils.WriteDataJson(w, reports.ListReports())
+}
+
+// GetReport runs a configured report with the declared parameters from the query string
+// format=csv returns the rows as a CSV attachment instead of JSON
+func GetReport(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	report, ok := config.Conf.Reports[name]
+	if !ok {
+		utils.WriteErrorJson(w, http.StatusNotFound, "Report not found: "+name)
+		return
+	}
+
+	format := utils.ParseQueryParam(r, "format", "json")
+	if format != "json" && format != "csv" {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Invalid format parameter. Must be one of: json, csv")
+		return
+	}
+
+	values := map[string]string{}
+	for key, value := range r.URL.Query() {
+		if key != "format" {
+			values[key] = value[0]
+		}
+	}
+	args, err := reports.BindParams(report, values)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := reports.RunReport(r.Context(), name, report, args)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if format == "csv" {
+		writeReportCsv(w, result)
+		return
+	}
+	utils.WriteDataJson(w, result)
+}
+
+// writeReportCsv writes a report as CSV with a header row of column names
+// Truncation is reported in the X-Report-Truncated header since CSV has no place for it
+func writeReportCsv(w http.ResponseWriter, result *reports.Result) {
+	utils.SetCorsHeaders(w)
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+result.Name+`.csv"`)
+	if result.Truncated {
+		w.Header().Set("X-Report-Truncated", "true")
+	}
+	w.WriteHeader(http.StatusOK)
+
+	writer := csv.NewWriter(w)
+	writer.Write(result.Columns)
+	record := make([]string, len(result.Columns))
+	for _, row := range result.Rows {
+		for i, value := range row {
+			record[i] = formatCsvValue(value)
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+}
+
+func formatCsvValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		return v.String()
+	}
+	// Numerics, JSON and arrays are written as their JSON encoding
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
@@ -0,0 +1,72 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/retry_queue"
+	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
+)
+
+// FailedItemsResponse is a page of the retry queue with its total size
+type FailedItemsResponse struct {
+	Total int64                    `json:"total"`
+	Items []retry_queue.FailedItem `json:"items"`
+}
+
+// GetFailedItems lists transactions queued for retry in lenient mode
+func GetFailedItems(w http.ResponseWriter, r *http.Request) {
+	if utils.AdminMiddleware(w, r) {
+		return
+	}
+
+	module := utils.ParseQueryParam(r, "module", "")
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	offset := utils.ParseQueryParamInt(r, "offset", 0)
+	limit, offset = utils.NormalizePagination(limit, offset)
+
+	total, err := retry_queue.CountFailedItems(module)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	items, err := retry_queue.GetFailedItems(module, limit, offset)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, FailedItemsResponse{Total: total, Items: items})
+}
+
+// RetryFailedItemsRequest is the body of an admin retry request
+type RetryFailedItemsRequest struct {
+	Module string  `json:"module"` // empty means all modules
+	IDs    []int64 `json:"ids"`    // empty means every queued item
+}
+
+// RetryFailedItems re-indexes queued transactions, typically after deploying a parser fix
+func RetryFailedItems(w http.ResponseWriter, r *http.Request) {
+	if utils.AdminMiddleware(w, r) {
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		utils.WriteErrorJson(w, http.StatusMethodNotAllowed, "Method not allowed, use POST")
+		return
+	}
+
+	body, err := utils.ReadJsonBody[RetryFailedItemsRequest](r)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := retry_queue.Retry(body.Module, body.IDs)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, result)
+}
@@ -0,0 +1,131 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/indexer"
+	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
+)
+
+// RollbackIndex removes every indexed block above height, after which the indexer indexes them again
+// With dry_run=true it only reports how many rows each table would lose
+func RollbackIndex(w http.ResponseWriter, r *http.Request) {
+	if utils.AdminMiddleware(w, r) {
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		utils.WriteErrorJson(w, http.StatusMethodNotAllowed, "Method not allowed, use POST")
+		return
+	}
+
+	lastIndexed, err := postgres.GetLastIndexedBlock()
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	minHeight := minRollbackHeight(lastIndexed)
+	height := int64(utils.ParseQueryParamInt(r, "height", -1))
+	if height < minHeight || height >= lastIndexed {
+		utils.WriteErrorJson(w, http.StatusBadRequest, fmt.Sprintf("Missing or invalid required parameter: height (%d to %d)", minHeight, lastIndexed-1))
+		return
+	}
+
+	writeRollback(w, r, height, lastIndexed, func() error {
+		return indexer.Rewind(height)
+	}, nil)
+}
+
+// ReindexBlocks indexes blocks from..to again by rolling back below from
+// to defaults to the last indexed block; blocks above it are removed too and re-indexed as the indexer catches up
+// GET returns the progress of the last re-index
+func ReindexBlocks(w http.ResponseWriter, r *http.Request) {
+	if utils.AdminMiddleware(w, r) {
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		utils.WriteDataJson(w, indexer.GetLoopStatus().Reindex)
+		return
+	}
+	if r.Method != http.MethodPost {
+		utils.WriteErrorJson(w, http.StatusMethodNotAllowed, "Method not allowed, use GET or POST")
+		return
+	}
+
+	lastIndexed, err := postgres.GetLastIndexedBlock()
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	minFrom := minRollbackHeight(lastIndexed) + 1
+	from := int64(utils.ParseQueryParamInt(r, "from", -1))
+	if from < minFrom || from > lastIndexed {
+		utils.WriteErrorJson(w, http.StatusBadRequest, fmt.Sprintf("Missing or invalid required parameter: from (%d to %d)", minFrom, lastIndexed))
+		return
+	}
+	to := int64(utils.ParseQueryParamInt(r, "to", int(lastIndexed)))
+	if to < from || to > lastIndexed {
+		utils.WriteErrorJson(w, http.StatusBadRequest, fmt.Sprintf("Invalid to parameter, must be between from and %d", lastIndexed))
+		return
+	}
+
+	writeRollback(w, r, from-1, lastIndexed, func() error {
+		return indexer.Reindex(from, to)
+	}, map[string]interface{}{
+		"from": from,
+		"to":   to,
+	})
+}
+
+// minRollbackHeight returns the lowest height a rollback may keep
+// Manifests of blocks at or below lastIndexed - manifest_retention are pruned, and removals need them
+func minRollbackHeight(lastIndexed int64) int64 {
+	retention := int64(config.Conf.Indexer.ManifestRetention)
+	if retention <= 0 || lastIndexed-retention < 0 {
+		return 0
+	}
+	return lastIndexed - retention
+}
+
+// writeRollback runs a rollback to height, or with dry_run=true counts the rows it would delete,
+// and writes the outcome merged with extra
+func writeRollback(w http.ResponseWriter, r *http.Request, height, lastIndexed int64, rollback func() error, extra map[string]interface{}) {
+	response := map[string]interface{}{
+		"rollback_height": height,
+		"removed_blocks":  lastIndexed - height,
+	}
+	for key, value := range extra {
+		response[key] = value
+	}
+
+	if utils.ParseQueryParam(r, "dry_run", "false") == "true" {
+		rows, err := postgres.CountRollbackRows(r.Context(), height)
+		if err != nil {
+			utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		var total int64
+		for _, count := range rows {
+			total += count
+		}
+		response["dry_run"] = true
+		response["rows"] = rows
+		response["total_rows"] = total
+		utils.WriteDataJson(w, response)
+		return
+	}
+
+	if err := rollback(); err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response["dry_run"] = false
+	utils.WriteDataJson(w, response)
+}
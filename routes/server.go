@@ -6,11 +6,20 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/alerts"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/db/postgres"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/indexer"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/jobs"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/usage"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/webhooks"
 	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
+	"github.com/keep-starknet-strange/ztarknet/zindex/routes/ws"
 )
 
-func StartServer(host, port string) {
+// NewServer registers the routes of every enabled module and returns the configured API server
+// Serving is left to the caller, which restarts the listener if it fails
+func NewServer(host, port string) *http.Server {
 	mux := http.NewServeMux()
 
 	// Enable base routes (always enabled)
@@ -19,11 +28,44 @@ func StartServer(host, port string) {
 	// Enable block routes (always enabled)
 	EnableBlockRoutes(mux)
 
+	// Enable change feed routes (always enabled)
+	EnableSyncRoutes(mux)
+
+	// Enable stats routes (always enabled)
+	EnableStatsRoutes(mux)
+
+	// Enable UTXO snapshot routes (always enabled)
+	EnableSnapshotRoutes(mux)
+
+	// Enable cross-module transaction routes (always enabled, sections follow module config)
+	EnableTxRoutes(mux)
+
+	// Enable annotation routes (always enabled, writes are admin-only)
+	EnableAnnotationRoutes(mux)
+
+	// Enable custom report routes (when reports are configured)
+	EnableReportRoutes(mux)
+
+	// Enable WebSocket event subscriptions (api.websocket)
+	if config.Conf.Api.Websocket.Enabled {
+		ws.RegisterRoutes(mux)
+	}
+
+	// Enable Prometheus metrics (api.metrics)
+	EnableMetricsRoutes(mux)
+
+	// Enable admin routes (guarded by api.admin)
+	EnableAdminRoutes(mux)
+
 	// Enable module-specific routes based on configuration
 	EnableAccountsRoutes(mux)
 	EnableTxGraphRoutes(mux)
 	EnableTzeGraphRoutes(mux)
 	EnableStarksRoutes(mux)
+	EnableMempoolRoutes(mux)
+
+	// Write per-key request counts in the background (api.usage)
+	usage.StartFlusher()
 
 	addr := fmt.Sprintf("%s:%s", host, port)
 	log.Printf("API server listening on %s", addr)
@@ -31,28 +73,45 @@ func StartServer(host, port string) {
 	// Configure server with timeouts and limits from config
 	server := &http.Server{
 		Addr:           addr,
-		Handler:        mux,
+		Handler:        metricsMiddleware(standbyMiddleware(authMiddleware(rateLimitMiddleware(degradedModeMiddleware(usageMiddleware(loadSheddingMiddleware(mux))))))),
 		ReadTimeout:    time.Duration(config.Conf.Api.ReadTimeout) * time.Second,
 		WriteTimeout:   time.Duration(config.Conf.Api.WriteTimeout) * time.Second,
 		IdleTimeout:    time.Duration(config.Conf.Api.IdleTimeout) * time.Second,
 		MaxHeaderBytes: config.Conf.Api.MaxHeaderBytes,
 	}
 
+	// Shutdown drains in-flight requests but does not see hijacked WebSocket connections
+	if config.Conf.Api.Websocket.Enabled {
+		server.RegisterOnShutdown(ws.Shutdown)
+	}
+
 	log.Printf("Server configured with ReadTimeout: %ds, WriteTimeout: %ds, IdleTimeout: %ds, MaxHeaderBytes: %d",
 		config.Conf.Api.ReadTimeout,
 		config.Conf.Api.WriteTimeout,
 		config.Conf.Api.IdleTimeout,
 		config.Conf.Api.MaxHeaderBytes)
 
-	if err := server.ListenAndServe(); err != nil {
-		log.Fatalf("Failed to start API server: %v", err)
-	}
+	return server
 }
 
 func HealthCheck(w http.ResponseWriter, r *http.Request) {
 	utils.WriteResultJson(w, "healthy")
 }
 
+// Status reports the internal state of the indexing loop and the maintained table row counts
+func Status(w http.ResponseWriter, r *http.Request) {
+	status := map[string]interface{}{
+		"indexer": indexer.GetLoopStatus(),
+	}
+
+	// Row counts are informational, so a database error must not fail the status report
+	if counts, err := postgres.GetRowCounts(r.Context(), nil); err == nil {
+		status["row_counts"] = counts
+	}
+
+	utils.WriteDataJson(w, status)
+}
+
 // EnableBaseRoutes registers base routes that are always available
 func EnableBaseRoutes(mux *http.ServeMux) {
 	log.Println("Registering base routes")
@@ -66,12 +125,32 @@ func EnableBaseRoutes(mux *http.ServeMux) {
 
 	// Health check endpoint
 	mux.HandleFunc("/health", HealthCheck)
+
+	// Indexer loop status endpoint
+	mux.HandleFunc("/status", Status)
+
+	// Readiness endpoint (503 while on standby under api.blue_green)
+	mux.HandleFunc("/ready", Ready)
+
+	// Module configuration endpoint
+	mux.HandleFunc("/api/v1/modules", GetModules)
+}
+
+// registerDisabledModule answers every path under a disabled module's prefix with 501, so
+// clients can tell the module is off rather than getting the root handler's empty response
+func registerDisabledModule(mux *http.ServeMux, module, prefix, errorMsg string) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		utils.WriteModuleDisabledJson(w, module, errorMsg)
+	}
+	mux.HandleFunc(prefix, handler)
+	mux.HandleFunc(prefix+"/", handler)
 }
 
 // EnableAccountsRoutes registers all accounts module routes if the module is enabled
 func EnableAccountsRoutes(mux *http.ServeMux) {
 	if !config.IsModuleEnabled("ACCOUNTS") {
 		log.Println("Accounts module is disabled, skipping route registration")
+		registerDisabledModule(mux, "ACCOUNTS", "/api/v1/accounts", "Accounts module is disabled")
 		return
 	}
 
@@ -79,7 +158,7 @@ func EnableAccountsRoutes(mux *http.ServeMux) {
 
 	// Account routes
 	mux.HandleFunc("/api/v1/accounts", GetAccounts)
-	mux.HandleFunc("/api/v1/accounts/account", GetAccount)
+	mux.HandleFunc("/api/v1/accounts/account", requireFresh(GetAccount))
 	mux.HandleFunc("/api/v1/accounts/balance-range", GetAccountsByBalanceRange)
 	mux.HandleFunc("/api/v1/accounts/top-balances", GetTopAccountsByBalance)
 	mux.HandleFunc("/api/v1/accounts/recent-active", GetRecentActiveAccounts)
@@ -103,6 +182,7 @@ func EnableAccountsRoutes(mux *http.ServeMux) {
 func EnableTxGraphRoutes(mux *http.ServeMux) {
 	if !config.IsModuleEnabled("TX_GRAPH") {
 		log.Println("Transaction graph module is disabled, skipping route registration")
+		registerDisabledModule(mux, "TX_GRAPH", "/api/v1/tx-graph", "Transaction graph module is disabled")
 		return
 	}
 
@@ -110,6 +190,7 @@ func EnableTxGraphRoutes(mux *http.ServeMux) {
 
 	// Transaction routes
 	mux.HandleFunc("/api/v1/tx-graph/transaction", GetTransaction)
+	mux.HandleFunc("/api/v1/tx-graph/transaction/decoded", GetDecodedTransaction)
 	mux.HandleFunc("/api/v1/tx-graph/transactions/by-block", GetTransactionsByBlock)
 	mux.HandleFunc("/api/v1/tx-graph/transactions/by-type", GetTransactionsByType)
 	mux.HandleFunc("/api/v1/tx-graph/transactions/recent", GetRecentTransactions)
@@ -117,17 +198,22 @@ func EnableTxGraphRoutes(mux *http.ServeMux) {
 	// Transaction output routes
 	mux.HandleFunc("/api/v1/tx-graph/outputs", GetTransactionOutputs)
 	mux.HandleFunc("/api/v1/tx-graph/outputs/output", GetTransactionOutput)
-	mux.HandleFunc("/api/v1/tx-graph/outputs/unspent", GetUnspentOutputs)
+	mux.HandleFunc("/api/v1/tx-graph/outputs/unspent", requireFresh(GetUnspentOutputs))
 	mux.HandleFunc("/api/v1/tx-graph/outputs/spenders", GetOutputSpenders)
 
 	// Transaction input routes
 	mux.HandleFunc("/api/v1/tx-graph/inputs", GetTransactionInputs)
 	mux.HandleFunc("/api/v1/tx-graph/inputs/input", GetTransactionInput)
 	mux.HandleFunc("/api/v1/tx-graph/inputs/sources", GetInputSources)
+	mux.HandleFunc("/api/v1/tx-graph/inputs/by-address", GetInputsByAddress)
 
 	// Transaction graph routes
 	mux.HandleFunc("/api/v1/tx-graph/graph", GetTransactionGraph)
 
+	// Block space routes
+	mux.HandleFunc("/api/v1/tx-graph/blocks/size-breakdown", GetBlockSizeBreakdown)
+	mux.HandleFunc("/api/v1/tx-graph/blocks/size-breakdown/range", GetBlockSizeBreakdownsByRange)
+
 	// Count routes
 	mux.HandleFunc("/api/v1/tx-graph/transactions/count", CountTransactions)
 	mux.HandleFunc("/api/v1/tx-graph/outputs/count", CountTransactionOutputs)
@@ -138,6 +224,7 @@ func EnableTxGraphRoutes(mux *http.ServeMux) {
 func EnableTzeGraphRoutes(mux *http.ServeMux) {
 	if !config.IsModuleEnabled("TZE_GRAPH") {
 		log.Println("TZE graph module is disabled, skipping route registration")
+		registerDisabledModule(mux, "TZE_GRAPH", "/api/v1/tze-graph", "TZE graph module is disabled")
 		return
 	}
 
@@ -150,25 +237,39 @@ func EnableTzeGraphRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/v1/tze-graph/inputs/by-mode", GetTzeInputsByMode)
 	mux.HandleFunc("/api/v1/tze-graph/inputs/by-type-mode", GetTzeInputsByTypeAndMode)
 	mux.HandleFunc("/api/v1/tze-graph/inputs/by-prev-output", GetTzeInputsByPrevOutput)
+	mux.HandleFunc("/api/v1/tze-graph/inputs/by-witness-hash", GetTzeInputsByWitnessHash)
 
 	// TZE output routes
 	mux.HandleFunc("/api/v1/tze-graph/outputs", GetTzeOutputs)
 	mux.HandleFunc("/api/v1/tze-graph/outputs/output", GetTzeOutput)
-	mux.HandleFunc("/api/v1/tze-graph/outputs/unspent", GetUnspentTzeOutputs)
+	mux.HandleFunc("/api/v1/tze-graph/outputs/unspent", requireFresh(GetUnspentTzeOutputs))
 	mux.HandleFunc("/api/v1/tze-graph/outputs/all-unspent", GetAllUnspentTzeOutputs)
 	mux.HandleFunc("/api/v1/tze-graph/outputs/by-type", GetTzeOutputsByType)
 	mux.HandleFunc("/api/v1/tze-graph/outputs/by-mode", GetTzeOutputsByMode)
 	mux.HandleFunc("/api/v1/tze-graph/outputs/by-type-mode", GetTzeOutputsByTypeAndMode)
-	mux.HandleFunc("/api/v1/tze-graph/outputs/unspent-by-type", GetUnspentTzeOutputsByType)
-	mux.HandleFunc("/api/v1/tze-graph/outputs/unspent-by-type-mode", GetUnspentTzeOutputsByTypeAndMode)
+	mux.HandleFunc("/api/v1/tze-graph/outputs/unspent-by-type", requireFresh(GetUnspentTzeOutputsByType))
+	mux.HandleFunc("/api/v1/tze-graph/outputs/unspent-by-type-mode", requireFresh(GetUnspentTzeOutputsByTypeAndMode))
 	mux.HandleFunc("/api/v1/tze-graph/outputs/spent", GetSpentTzeOutputs)
 	mux.HandleFunc("/api/v1/tze-graph/outputs/by-value", GetTzeOutputsByValue)
+	mux.HandleFunc("/api/v1/tze-graph/outputs/by-precondition-hash", GetTzeOutputsByPreconditionHash)
+
+	// TZE extension routes
+	mux.HandleFunc("/api/v1/tze-graph/extensions", GetTzeExtensions)
+
+	// TZE graph traversal
+	mux.HandleFunc("/api/v1/tze-graph/graph", GetTzeGraph)
+
+	// Demo extension channel routes
+	mux.HandleFunc("/api/v1/tze-graph/demo/stats", GetDemoChannelStats)
+	mux.HandleFunc("/api/v1/tze-graph/demo/channels/open", GetOpenDemoChannels)
+	mux.HandleFunc("/api/v1/tze-graph/demo/closes", GetDemoCloseEvents)
 }
 
 // EnableStarksRoutes registers all STARK module routes if the module is enabled
 func EnableStarksRoutes(mux *http.ServeMux) {
 	if !config.IsModuleEnabled("STARKS") {
 		log.Println("STARKS module is disabled, skipping route registration")
+		registerDisabledModule(mux, "STARKS", "/api/v1/starks", "STARKS module is disabled")
 		return
 	}
 
@@ -179,6 +280,8 @@ func EnableStarksRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/v1/starks/verifiers/by-name", GetVerifierByName)
 	mux.HandleFunc("/api/v1/starks/verifiers", GetAllVerifiers)
 	mux.HandleFunc("/api/v1/starks/verifiers/by-balance", GetVerifiersByBalance)
+	mux.HandleFunc("/api/v1/starks/verifiers/active", GetActiveVerifiers)
+	mux.HandleFunc("/api/v1/starks/verifiers/terminated", GetTerminatedVerifiers)
 
 	// STARK proof routes
 	mux.HandleFunc("/api/v1/starks/proofs/proof", GetStarkProof)
@@ -187,25 +290,66 @@ func EnableStarksRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/v1/starks/proofs/by-block", GetStarkProofsByBlock)
 	mux.HandleFunc("/api/v1/starks/proofs/recent", GetRecentStarkProofs)
 	mux.HandleFunc("/api/v1/starks/proofs/by-size", GetStarkProofsBySize)
+	mux.HandleFunc("/api/v1/starks/proofs/by-time", GetStarkProofsByTimeRange)
+	mux.HandleFunc("/api/v1/starks/proofs/daily", GetDailyStarkProofActivity)
+	mux.HandleFunc("/api/v1/starks/proofs/by-hash", GetStarkProofsByHash)
+	mux.HandleFunc("/api/v1/starks/proofs/duplicates", GetDuplicateStarkProofs)
 
 	// Ztarknet facts routes
 	mux.HandleFunc("/api/v1/starks/facts/facts", GetZtarknetFacts)
 	mux.HandleFunc("/api/v1/starks/facts/by-verifier", GetZtarknetFactsByVerifier)
 	mux.HandleFunc("/api/v1/starks/facts/by-transaction", GetZtarknetFactsByTransaction)
 	mux.HandleFunc("/api/v1/starks/facts/by-block", GetZtarknetFactsByBlock)
+	mux.HandleFunc("/api/v1/starks/facts/by-l2-block", GetZtarknetFactsByL2Block)
 	mux.HandleFunc("/api/v1/starks/facts/by-state", GetZtarknetFactsByState)
 	mux.HandleFunc("/api/v1/starks/facts/by-program-hash", GetZtarknetFactsByProgramHash)
 	mux.HandleFunc("/api/v1/starks/facts/by-inner-program-hash", GetZtarknetFactsByInnerProgramHash)
 	mux.HandleFunc("/api/v1/starks/facts/recent", GetRecentZtarknetFacts)
 	mux.HandleFunc("/api/v1/starks/facts/state-transition", GetStateTransition)
+	mux.HandleFunc("/api/v1/starks/facts/state-chain", GetZtarknetStateChain)
+	mux.HandleFunc("/api/v1/starks/facts/graph", GetZtarknetStateGraph)
+	mux.HandleFunc("/api/v1/starks/facts/by-state-prefix", GetZtarknetFactsByStatePrefix)
+	mux.HandleFunc("/api/v1/starks/facts/by-time", GetZtarknetFactsByTimeRange)
+	mux.HandleFunc("/api/v1/starks/facts/daily", GetDailyZtarknetFactActivity)
+	mux.HandleFunc("/api/v1/starks/facts/export", ExportZtarknetFacts)
+	mux.HandleFunc("/api/v1/starks/facts/anomalies", GetFactAnomalies)
+	mux.HandleFunc("/api/v1/starks/facts/segments", GetFactSegments)
+	mux.HandleFunc("/api/v1/starks/facts/segments/data", GetFactSegmentData)
+
+	// Rollup-level views of the canonical verifier
+	mux.HandleFunc("/api/v1/ztarknet/state", GetZtarknetState)
+	mux.HandleFunc("/api/v1/ztarknet/mapping", GetZtarknetMapping)
+	mux.HandleFunc("/api/v1/ztarknet/overview", GetZtarknetOverview)
 
 	// Count routes
 	mux.HandleFunc("/api/v1/starks/verifiers/count", CountVerifiers)
 	mux.HandleFunc("/api/v1/starks/proofs/count", CountStarkProofs)
 	mux.HandleFunc("/api/v1/starks/facts/count", CountZtarknetFacts)
+	mux.HandleFunc("/api/v1/starks/facts/by-state/count", CountZtarknetFactsByState)
+	mux.HandleFunc("/api/v1/starks/facts/by-program-hash/count", CountZtarknetFactsByProgramHash)
+	mux.HandleFunc("/api/v1/starks/facts/by-inner-program-hash/count", CountZtarknetFactsByInnerProgramHash)
 
 	// Aggregation routes
 	mux.HandleFunc("/api/v1/starks/verifier/sum-proof-sizes", GetSumProofSizesByVerifier)
+
+	// Stats routes
+	mux.HandleFunc("/api/v1/starks/stats/top-programs", GetTopPrograms)
+}
+
+// EnableMempoolRoutes registers all mempool module routes if the module is enabled
+func EnableMempoolRoutes(mux *http.ServeMux) {
+	if !config.IsModuleEnabled("MEMPOOL") {
+		log.Println("MEMPOOL module is disabled, skipping route registration")
+		registerDisabledModule(mux, "MEMPOOL", "/api/v1/mempool", "Mempool module is disabled")
+		return
+	}
+
+	log.Println("Registering MEMPOOL module routes")
+
+	mux.HandleFunc("/api/v1/mempool/transaction", GetMempoolTransaction)
+	mux.HandleFunc("/api/v1/mempool/transactions", GetMempoolTransactions)
+	mux.HandleFunc("/api/v1/mempool/transactions/count", CountMempoolTransactions)
+	mux.HandleFunc("/api/v1/mempool/summary", GetMempoolSummary)
 }
 
 // EnableBlockRoutes registers all block routes (always enabled)
@@ -220,5 +364,108 @@ func EnableBlockRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/v1/blocks/timestamp-range", GetBlocksByTimestampRange)
 	mux.HandleFunc("/api/v1/blocks/recent", GetRecentBlocks)
 	mux.HandleFunc("/api/v1/blocks/count", GetBlockCount)
-	mux.HandleFunc("/api/v1/blocks/latest", GetLatestBlock)
+	mux.HandleFunc("/api/v1/blocks/latest", requireFresh(GetLatestBlock))
+
+	// Finality routes
+	mux.HandleFunc("/api/v1/finalized/latest", GetLatestFinalizedBlock)
+}
+
+// EnableSyncRoutes registers the consumer change feed routes (always enabled)
+func EnableSyncRoutes(mux *http.ServeMux) {
+	log.Println("Registering Sync routes")
+
+	mux.HandleFunc("/api/v1/sync/changes", GetChanges)
+	mux.HandleFunc("/api/v1/sync/manifest", GetBlockManifest)
+}
+
+// EnableAdminRoutes registers admin routes (each handler is guarded by AdminMiddleware)
+func EnableAdminRoutes(mux *http.ServeMux) {
+	log.Println("Registering Admin routes")
+
+	mux.HandleFunc("/api/v1/admin/maintenance/analyze", RunMaintenance)
+	mux.HandleFunc("/api/v1/admin/maintenance/index-report", GetIndexReport)
+	mux.HandleFunc("/api/v1/admin/snapshots/utxo", ComputeUtxoSnapshot)
+	mux.HandleFunc("/api/v1/admin/starks/verifiers/labels", UpdateVerifierLabels)
+	mux.HandleFunc("/api/v1/admin/starks/verifiers/canonical", ManageCanonicalVerifier)
+	mux.HandleFunc("/api/v1/admin/starks/facts/import", ImportZtarknetFacts)
+	mux.HandleFunc("/api/v1/admin/failed-items", GetFailedItems)
+	mux.HandleFunc("/api/v1/admin/failed-items/retry", RetryFailedItems)
+	mux.HandleFunc("/api/v1/admin/index-log", GetIndexLog)
+	mux.HandleFunc("/api/v1/admin/rollback", RollbackIndex)
+	mux.HandleFunc("/api/v1/admin/reindex", ReindexBlocks)
+	mux.HandleFunc("/api/v1/admin/usage", GetApiUsage)
+	mux.HandleFunc("/api/v1/admin/annotations", ManageAnnotations)
+	mux.HandleFunc("/api/v1/admin/keys", ManageApiKeys)
+	mux.HandleFunc("/api/v1/admin/schema", ManageActiveSchema)
+
+	// Alert rules and history are only registered when alerts are evaluated (alerts.enabled)
+	if alerts.Enabled() {
+		mux.HandleFunc("/api/v1/admin/alerts", GetAlerts)
+		mux.HandleFunc("/api/v1/admin/alerts/rules", ManageAlertRules)
+	}
+
+	// Webhook management is only registered when deliveries are enabled (api.webhooks)
+	if webhooks.Enabled() {
+		mux.HandleFunc("/api/v1/admin/webhooks", ManageWebhooks)
+		mux.HandleFunc("/api/v1/admin/webhooks/deliveries", GetWebhookDeliveries)
+		mux.HandleFunc("/api/v1/admin/webhooks/deliveries/{id}", GetWebhookDelivery)
+		mux.HandleFunc("/api/v1/admin/webhooks/redeliver", RedeliverWebhooks)
+	}
+
+	// Block pushes are only accepted when configured (indexer.notify.http)
+	if config.Conf.Indexer.Notify.Http {
+		mux.HandleFunc("/api/v1/admin/notify/block", NotifyBlock)
+	}
+
+	// The job queue is only inspectable when it runs (jobs.enabled)
+	if jobs.Enabled() {
+		mux.HandleFunc("/api/v1/admin/jobs", ManageJobs)
+		mux.HandleFunc("/api/v1/admin/jobs/kinds", GetJobKinds)
+		mux.HandleFunc("/api/v1/admin/jobs/{id}", GetJob)
+		mux.HandleFunc("/api/v1/admin/jobs/{id}/retry", RetryJob)
+	}
+
+	// Mining on demand is only registered for regtest development setups
+	if config.Conf.Rpc.DevMode {
+		log.Println("Registering dev mode routes (rpc.dev_mode)")
+		mux.HandleFunc("/api/v1/admin/dev/generate", GenerateBlocks)
+	}
+}
+
+// EnableStatsRoutes registers chain statistics routes (always enabled)
+func EnableStatsRoutes(mux *http.ServeMux) {
+	log.Println("Registering Stats routes")
+
+	mux.HandleFunc("/api/v1/stats/activity", GetActivity)
+	mux.HandleFunc("/api/v1/stats/diff", GetChainDiff)
+	mux.HandleFunc("/api/v1/stats/proof-share", GetBlockProofShares)
+	mux.HandleFunc("/api/v1/stats/proof-share/activity", GetProofShareActivity)
+	mux.HandleFunc("/api/v1/stats/verification-cost", GetVerificationCost)
+	mux.HandleFunc("/api/v1/stats/verification-cost/blocks", GetBlockVerificationCosts)
+	mux.HandleFunc("/api/v1/stats/indexing-latency", GetIndexingLatency)
+	mux.HandleFunc("/api/v1/stats/indexing-latency/blocks", GetBlockLatencies)
+	mux.HandleFunc("/api/v1/stats/emission", GetEmissionSchedule)
+	mux.HandleFunc("/api/v1/stats/emission/discrepancies", GetSubsidyDiscrepancies)
+}
+
+// EnableSnapshotRoutes registers UTXO snapshot routes (always enabled)
+func EnableSnapshotRoutes(mux *http.ServeMux) {
+	log.Println("Registering Snapshot routes")
+
+	mux.HandleFunc("/api/v1/snapshots/utxo", GetUtxoSnapshots)
+	mux.HandleFunc("/api/v1/snapshots/utxo/latest", GetLatestUtxoSnapshots)
+}
+
+// EnableTxRoutes registers cross-module transaction routes
+func EnableTxRoutes(mux *http.ServeMux) {
+	log.Println("Registering Tx routes")
+
+	mux.HandleFunc("/api/v1/tx/{txid}/full", GetTransactionFull)
+}
+
+// EnableAnnotationRoutes registers the public annotation read route
+func EnableAnnotationRoutes(mux *http.ServeMux) {
+	log.Println("Registering annotation routes")
+
+	mux.HandleFunc("/api/v1/annotations", GetAnnotations)
 }
@@ -0,0 +1,196 @@
+package routes
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/metrics"
+	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
+)
+
+// latencyBoundsMs are the upper bounds of the latency buckets the rolling p95 is read from
+var latencyBoundsMs = []int64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000}
+
+var httpRequestsShed = metrics.NewCounter("zindex_http_requests_shed_total",
+	"API requests rejected because their endpoint group was over its latency budget", "group")
+
+// latencySlot counts the requests of one second by latency bucket
+type latencySlot struct {
+	second int64
+	counts []uint64 // one per latencyBoundsMs entry, plus one past the last bound
+}
+
+// groupLatency is the rolling latency of one endpoint group over api.load_shedding.window seconds
+type groupLatency struct {
+	name     string
+	budgetMs int64
+
+	mu        sync.Mutex
+	slots     []latencySlot
+	checkedAt int64 // second the shedding state was last evaluated
+	shedding  bool
+}
+
+var (
+	latencyGroupsMu sync.Mutex
+	latencyGroups   = map[string]*groupLatency{}
+)
+
+// endpointGroup returns the group of an API path: the segment after /api/v1/, or "" for paths
+// that are never shed (health, status, metrics and admin routes)
+func endpointGroup(path string) string {
+	rest, ok := strings.CutPrefix(path, "/api/v1/")
+	if !ok {
+		return ""
+	}
+	group, _, _ := strings.Cut(rest, "/")
+	if group == "admin" {
+		return ""
+	}
+	return group
+}
+
+// latencyGroup returns the tracker of a group with a configured budget, or nil
+func latencyGroup(name string) *groupLatency {
+	budget, ok := config.Conf.Api.LoadShedding.Budgets[name]
+	if !ok {
+		return nil
+	}
+
+	latencyGroupsMu.Lock()
+	defer latencyGroupsMu.Unlock()
+	group, ok := latencyGroups[name]
+	if !ok {
+		group = &groupLatency{
+			name:     name,
+			budgetMs: int64(budget),
+			slots:    make([]latencySlot, config.Conf.Api.LoadShedding.Window),
+		}
+		latencyGroups[name] = group
+	}
+	return group
+}
+
+// observe records the latency of one request served by the group
+func (g *groupLatency) observe(now time.Time, latency time.Duration) {
+	bucket := len(latencyBoundsMs)
+	for i, bound := range latencyBoundsMs {
+		if latency.Milliseconds() <= bound {
+			bucket = i
+			break
+		}
+	}
+
+	second := now.Unix()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	slot := &g.slots[second%int64(len(g.slots))]
+	if slot.second != second {
+		slot.second = second
+		slot.counts = make([]uint64, len(latencyBoundsMs)+1)
+	}
+	slot.counts[bucket]++
+}
+
+// isShedding reports whether the group's p95 is over budget, re-evaluating it at most once a second
+// The p95 is the upper bound of the bucket holding the 95th percentile request of the window
+func (g *groupLatency) isShedding(now time.Time) bool {
+	second := now.Unix()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.checkedAt == second {
+		return g.shedding
+	}
+	g.checkedAt = second
+
+	counts := make([]uint64, len(latencyBoundsMs)+1)
+	total := uint64(0)
+	for _, slot := range g.slots {
+		if slot.counts == nil || second-slot.second >= int64(len(g.slots)) {
+			continue
+		}
+		for i, count := range slot.counts {
+			counts[i] += count
+			total += count
+		}
+	}
+
+	p95 := int64(0)
+	if total > 0 && total >= uint64(config.Conf.Api.LoadShedding.MinSamples) {
+		rank := (total*95 + 99) / 100
+		seen := uint64(0)
+		for i, count := range counts {
+			seen += count
+			if seen >= rank {
+				if i < len(latencyBoundsMs) {
+					p95 = latencyBoundsMs[i]
+				} else {
+					p95 = latencyBoundsMs[len(latencyBoundsMs)-1] + 1
+				}
+				break
+			}
+		}
+	}
+
+	shedding := p95 > g.budgetMs
+	if shedding != g.shedding {
+		if shedding {
+			log.Printf("Load shedding: %s p95 latency is in the %dms bucket, over its %dms budget; shedding low-priority requests", g.name, p95, g.budgetMs)
+		} else {
+			log.Printf("Load shedding: %s p95 latency back within its %dms budget", g.name, g.budgetMs)
+		}
+		g.shedding = shedding
+	}
+	return g.shedding
+}
+
+// isLowPriority reports whether a request may be shed: it matches api.load_shedding.low_priority,
+// or the list is empty and every request of a group over budget is
+func isLowPriority(path string) bool {
+	prefixes := config.Conf.Api.LoadShedding.LowPriority
+	if len(prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadSheddingMiddleware tracks the rolling p95 latency of each budgeted endpoint group and, while
+// a group is over budget, answers its low-priority requests with 503 and Retry-After before they
+// reach the database, leaving the pool to the requests that still run
+func loadSheddingMiddleware(next http.Handler) http.Handler {
+	if !config.Conf.Api.LoadShedding.Enabled {
+		return next
+	}
+	retryAfter := strconv.Itoa(config.Conf.Api.LoadShedding.RetryAfter)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		group := latencyGroup(endpointGroup(r.URL.Path))
+		if group == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		if group.isShedding(start) && isLowPriority(r.URL.Path) {
+			httpRequestsShed.Inc(group.name)
+			w.Header().Set("Retry-After", retryAfter)
+			utils.WriteErrorJson(w, http.StatusServiceUnavailable,
+				fmt.Sprintf("The %s endpoints are over their latency budget, retry in %s seconds", group.name, retryAfter))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+		group.observe(time.Now(), time.Since(start))
+	})
+}
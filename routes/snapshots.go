@@ -0,0 +1,95 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/snapshots"
+	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
+)
+
+// GetUtxoSnapshots retrieves stored UTXO snapshots, either for one checkpoint height or paginated newest first
+func GetUtxoSnapshots(w http.ResponseWriter, r *http.Request) {
+	height := int64(utils.ParseQueryParamInt(r, "height", -1))
+	if height >= 0 {
+		result, err := snapshots.GetSnapshotsAtHeight(height)
+		if err != nil {
+			utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if len(result) == 0 {
+			utils.WriteErrorJson(w, http.StatusNotFound, "No UTXO snapshot at this height")
+			return
+		}
+
+		utils.WriteDataJson(w, result)
+		return
+	}
+
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	offset := utils.ParseQueryParamInt(r, "offset", 0)
+	limit, offset = utils.NormalizePagination(limit, offset)
+
+	result, err := snapshots.GetSnapshots(limit, offset)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, result)
+}
+
+// GetLatestUtxoSnapshots retrieves the UTXO snapshots of the most recent checkpoint
+func GetLatestUtxoSnapshots(w http.ResponseWriter, r *http.Request) {
+	result, err := snapshots.GetLatestSnapshots()
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(result) == 0 {
+		utils.WriteErrorJson(w, http.StatusNotFound, "No UTXO snapshots recorded yet")
+		return
+	}
+
+	utils.WriteDataJson(w, result)
+}
+
+// UtxoSnapshotRequest is the body of an admin UTXO snapshot request
+type UtxoSnapshotRequest struct {
+	Height int64 `json:"height"`
+}
+
+// ComputeUtxoSnapshot computes (or recomputes) the UTXO snapshots at an indexed height
+func ComputeUtxoSnapshot(w http.ResponseWriter, r *http.Request) {
+	if utils.AdminMiddleware(w, r) {
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		utils.WriteErrorJson(w, http.StatusMethodNotAllowed, "Method not allowed, use POST")
+		return
+	}
+
+	if !config.IsModuleEnabled("TX_GRAPH") {
+		utils.WriteModuleDisabledJson(w, "TX_GRAPH", "Transaction graph module is disabled")
+		return
+	}
+
+	body, err := utils.ReadJsonBody[UtxoSnapshotRequest](r)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if body.Height <= 0 {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Invalid parameter: height must be positive")
+		return
+	}
+
+	result, err := snapshots.Compute(body.Height)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, result)
+}
@@ -1,13 +1,44 @@
 package routes
 
 import (
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/annotations"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/starks"
 	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
 )
 
+// minStatePrefixLength is the shortest state root prefix accepted, to keep prefix scans selective
+const minStatePrefixLength = 6
+
+// parseTimeRange parses the required from_time and to_time Unix timestamp parameters
+// On failure it writes a 400 response and returns false
+func parseTimeRange(w http.ResponseWriter, r *http.Request) (int64, int64, bool) {
+	fromTime := int64(utils.ParseQueryParamInt(r, "from_time", -1))
+	if fromTime < 0 {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing or invalid required parameter: from_time")
+		return 0, 0, false
+	}
+
+	toTime := int64(utils.ParseQueryParamInt(r, "to_time", -1))
+	if toTime < 0 {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing or invalid required parameter: to_time")
+		return 0, 0, false
+	}
+
+	if fromTime > toTime {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "from_time must be less than or equal to to_time")
+		return 0, 0, false
+	}
+
+	return fromTime, toTime, true
+}
+
 // ============================================================================
 // Verifier Routes
 // ============================================================================
@@ -15,7 +46,7 @@ import (
 // GetVerifier retrieves a single verifier by its ID
 func GetVerifier(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("STARKS") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "STARKS module is disabled")
+		utils.WriteModuleDisabledJson(w, "STARKS", "STARKS module is disabled")
 		return
 	}
 
@@ -36,13 +67,13 @@ func GetVerifier(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	utils.WriteDataJson(w, verifier)
+	writeEntityJson(w, r, annotations.EntityVerifier, verifier.VerifierID, verifier)
 }
 
 // GetVerifierByName retrieves a verifier by its name
 func GetVerifierByName(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("STARKS") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "STARKS module is disabled")
+		utils.WriteModuleDisabledJson(w, "STARKS", "STARKS module is disabled")
 		return
 	}
 
@@ -63,13 +94,13 @@ func GetVerifierByName(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	utils.WriteDataJson(w, verifier)
+	writeEntityJson(w, r, annotations.EntityVerifier, verifier.VerifierID, verifier)
 }
 
 // GetAllVerifiers retrieves all verifiers with pagination
 func GetAllVerifiers(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("STARKS") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "STARKS module is disabled")
+		utils.WriteModuleDisabledJson(w, "STARKS", "STARKS module is disabled")
 		return
 	}
 
@@ -83,13 +114,13 @@ func GetAllVerifiers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	utils.WriteDataJson(w, verifiers)
+	utils.WritePageJson(w, r, verifiers, limit, offset, starks.CountVerifiers)
 }
 
 // GetVerifiersByBalance retrieves verifiers sorted by balance with pagination
 func GetVerifiersByBalance(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("STARKS") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "STARKS module is disabled")
+		utils.WriteModuleDisabledJson(w, "STARKS", "STARKS module is disabled")
 		return
 	}
 
@@ -106,6 +137,40 @@ func GetVerifiersByBalance(w http.ResponseWriter, r *http.Request) {
 	utils.WriteDataJson(w, verifiers)
 }
 
+// GetActiveVerifiers retrieves verifiers whose controlling UTXO is unspent
+func GetActiveVerifiers(w http.ResponseWriter, r *http.Request) {
+	getVerifiersByStatus(w, r, starks.GetActiveVerifiers)
+}
+
+// GetTerminatedVerifiers retrieves verifiers whose controlling UTXO was spent and not re-created
+func GetTerminatedVerifiers(w http.ResponseWriter, r *http.Request) {
+	getVerifiersByStatus(w, r, starks.GetTerminatedVerifiers)
+}
+
+// getVerifiersByStatus serves a paginated verifier status query, which joins tze_outputs
+func getVerifiersByStatus(w http.ResponseWriter, r *http.Request, query func(limit, offset int) ([]starks.Verifier, error)) {
+	if !config.IsModuleEnabled("STARKS") {
+		utils.WriteModuleDisabledJson(w, "STARKS", "STARKS module is disabled")
+		return
+	}
+	if !config.IsModuleEnabled("TZE_GRAPH") {
+		utils.WriteModuleDisabledJson(w, "TZE_GRAPH", "TZE graph module is disabled")
+		return
+	}
+
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	offset := utils.ParseQueryParamInt(r, "offset", 0)
+	limit, offset = utils.NormalizePagination(limit, offset)
+
+	verifiers, err := query(limit, offset)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, verifiers)
+}
+
 // ============================================================================
 // StarkProof Routes
 // ============================================================================
@@ -113,7 +178,7 @@ func GetVerifiersByBalance(w http.ResponseWriter, r *http.Request) {
 // GetStarkProof retrieves a STARK proof by verifier ID and transaction ID
 func GetStarkProof(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("STARKS") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "STARKS module is disabled")
+		utils.WriteModuleDisabledJson(w, "STARKS", "STARKS module is disabled")
 		return
 	}
 
@@ -146,7 +211,7 @@ func GetStarkProof(w http.ResponseWriter, r *http.Request) {
 // GetStarkProofsByVerifier retrieves all STARK proofs for a verifier with pagination
 func GetStarkProofsByVerifier(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("STARKS") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "STARKS module is disabled")
+		utils.WriteModuleDisabledJson(w, "STARKS", "STARKS module is disabled")
 		return
 	}
 
@@ -166,13 +231,15 @@ func GetStarkProofsByVerifier(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	utils.WriteDataJson(w, proofs)
+	utils.WritePageJson(w, r, proofs, limit, offset, func() (int64, error) {
+		return starks.CountStarkProofs(verifierID, 0)
+	})
 }
 
 // GetStarkProofsByTransaction retrieves all STARK proofs for a transaction
 func GetStarkProofsByTransaction(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("STARKS") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "STARKS module is disabled")
+		utils.WriteModuleDisabledJson(w, "STARKS", "STARKS module is disabled")
 		return
 	}
 
@@ -191,10 +258,67 @@ func GetStarkProofsByTransaction(w http.ResponseWriter, r *http.Request) {
 	utils.WriteDataJson(w, proofs)
 }
 
+// expandBlock reports whether the request asks for facts widened with their L1 block (expand=block)
+// It writes a 400 response for any other expand value
+func expandBlock(w http.ResponseWriter, r *http.Request) (bool, bool) {
+	switch utils.ParseQueryParam(r, "expand", "") {
+	case "":
+		return false, true
+	case "block":
+		return true, true
+	default:
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Invalid parameter: expand (supported: block)")
+		return false, false
+	}
+}
+
+// factsData returns the response data of a list of facts, with their block hash, timestamp and
+// confirmations when expand is set; it writes a 500 response when the blocks cannot be read
+func factsData(w http.ResponseWriter, facts []starks.ZtarknetFacts, expand bool) (interface{}, bool) {
+	if !expand {
+		return facts, true
+	}
+
+	expanded, err := starks.ExpandFactsWithBlocks(facts)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	return expanded, true
+}
+
+// factData is factsData for a single fact
+func factData(w http.ResponseWriter, fact *starks.ZtarknetFacts, expand bool) (interface{}, bool) {
+	data, ok := factsData(w, []starks.ZtarknetFacts{*fact}, expand)
+	if !ok || !expand {
+		return fact, ok
+	}
+	return &data.([]starks.ZtarknetFactWithBlock)[0], true
+}
+
+// canonicalVerifierFilter returns the canonical Ztarknet verifier when the request sets canonical=true,
+// or "" to keep every verifier; it writes a 404 response when no verifier is designated canonical
+func canonicalVerifierFilter(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if utils.ParseQueryParam(r, "canonical", "") != "true" {
+		return "", true
+	}
+
+	verifierID, _, err := starks.CanonicalVerifier()
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return "", false
+	}
+	if verifierID == "" {
+		utils.WriteErrorJson(w, http.StatusNotFound, "No canonical verifier is designated")
+		return "", false
+	}
+	return verifierID, true
+}
+
 // GetStarkProofsByBlock retrieves all STARK proofs for a specific block
 func GetStarkProofsByBlock(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("STARKS") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "STARKS module is disabled")
+		utils.WriteModuleDisabledJson(w, "STARKS", "STARKS module is disabled")
 		return
 	}
 
@@ -204,7 +328,12 @@ func GetStarkProofsByBlock(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	proofs, err := starks.GetStarkProofsByBlock(blockHeight)
+	verifierID, ok := canonicalVerifierFilter(w, r)
+	if !ok {
+		return
+	}
+
+	proofs, err := starks.GetStarkProofsByBlock(blockHeight, verifierID)
 	if err != nil {
 		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
 		return
@@ -216,7 +345,12 @@ func GetStarkProofsByBlock(w http.ResponseWriter, r *http.Request) {
 // GetRecentStarkProofs retrieves the most recent STARK proofs with pagination
 func GetRecentStarkProofs(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("STARKS") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "STARKS module is disabled")
+		utils.WriteModuleDisabledJson(w, "STARKS", "STARKS module is disabled")
+		return
+	}
+
+	verifierID, ok := canonicalVerifierFilter(w, r)
+	if !ok {
 		return
 	}
 
@@ -224,19 +358,21 @@ func GetRecentStarkProofs(w http.ResponseWriter, r *http.Request) {
 	offset := utils.ParseQueryParamInt(r, "offset", 0)
 	limit, offset = utils.NormalizePagination(limit, offset)
 
-	proofs, err := starks.GetRecentStarkProofs(limit, offset)
+	proofs, err := starks.GetRecentStarkProofs(verifierID, limit, offset)
 	if err != nil {
 		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	utils.WriteDataJson(w, proofs)
+	utils.WritePageJson(w, r, proofs, limit, offset, func() (int64, error) {
+		return starks.CountStarkProofs(verifierID, 0)
+	})
 }
 
 // GetStarkProofsBySize retrieves STARK proofs filtered by size range with pagination
 func GetStarkProofsBySize(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("STARKS") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "STARKS module is disabled")
+		utils.WriteModuleDisabledJson(w, "STARKS", "STARKS module is disabled")
 		return
 	}
 
@@ -270,14 +406,138 @@ func GetStarkProofsBySize(w http.ResponseWriter, r *http.Request) {
 	utils.WriteDataJson(w, proofs)
 }
 
+// GetStarkProofsByTimeRange retrieves STARK proofs within a block timestamp range with pagination
+func GetStarkProofsByTimeRange(w http.ResponseWriter, r *http.Request) {
+	if !config.IsModuleEnabled("STARKS") {
+		utils.WriteModuleDisabledJson(w, "STARKS", "STARKS module is disabled")
+		return
+	}
+
+	fromTime, toTime, ok := parseTimeRange(w, r)
+	if !ok {
+		return
+	}
+
+	verifierID, ok := canonicalVerifierFilter(w, r)
+	if !ok {
+		return
+	}
+
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	offset := utils.ParseQueryParamInt(r, "offset", 0)
+	limit, offset = utils.NormalizePagination(limit, offset)
+
+	proofs, err := starks.GetStarkProofsByTimeRange(fromTime, toTime, verifierID, limit, offset)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, proofs)
+}
+
+// GetDailyStarkProofActivity returns STARK proof counts and sizes bucketed by UTC day
+func GetDailyStarkProofActivity(w http.ResponseWriter, r *http.Request) {
+	if !config.IsModuleEnabled("STARKS") {
+		utils.WriteModuleDisabledJson(w, "STARKS", "STARKS module is disabled")
+		return
+	}
+
+	fromTime, toTime, ok := parseTimeRange(w, r)
+	if !ok {
+		return
+	}
+
+	activity, err := starks.GetDailyStarkProofActivity(fromTime, toTime)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, activity)
+}
+
+// GetStarkProofsByHash retrieves every submission of a proof witness by its hash
+func GetStarkProofsByHash(w http.ResponseWriter, r *http.Request) {
+	if !config.IsModuleEnabled("STARKS") {
+		utils.WriteModuleDisabledJson(w, "STARKS", "STARKS module is disabled")
+		return
+	}
+
+	proofHash := strings.ToLower(utils.ParseQueryParam(r, "proof_hash", ""))
+	if proofHash == "" {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing required parameter: proof_hash")
+		return
+	}
+
+	proofs, err := starks.GetStarkProofsByHash(proofHash)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, proofs)
+}
+
+// GetDuplicateStarkProofs lists proof witnesses submitted in more than one transaction with pagination
+func GetDuplicateStarkProofs(w http.ResponseWriter, r *http.Request) {
+	if !config.IsModuleEnabled("STARKS") {
+		utils.WriteModuleDisabledJson(w, "STARKS", "STARKS module is disabled")
+		return
+	}
+
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	offset := utils.ParseQueryParamInt(r, "offset", 0)
+	limit, offset = utils.NormalizePagination(limit, offset)
+
+	duplicates, err := starks.GetDuplicateProofs(limit, offset)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, duplicates)
+}
+
 // ============================================================================
 // ZtarknetFacts Routes
 // ============================================================================
 
+// ExportZtarknetFacts returns a verifier's full fact history as a canonical, optionally signed bundle
+func ExportZtarknetFacts(w http.ResponseWriter, r *http.Request) {
+	if !starks.ShouldIndexZtarknet() {
+		utils.WriteModuleDisabledJson(w, "STARKS", "Ztarknet indexing is disabled")
+		return
+	}
+
+	verifierID := utils.ParseQueryParam(r, "verifier_id", "")
+	if verifierID == "" {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing required parameter: verifier_id")
+		return
+	}
+
+	export, err := starks.ExportVerifierFacts(verifierID)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if export == nil {
+		utils.WriteErrorJson(w, http.StatusNotFound, "Verifier not found")
+		return
+	}
+
+	utils.WriteAttachmentJson(w, "ztarknet-facts-"+verifierID+".json", export)
+}
+
 // GetZtarknetFacts retrieves Ztarknet facts by verifier ID and transaction ID
 func GetZtarknetFacts(w http.ResponseWriter, r *http.Request) {
 	if !starks.ShouldIndexZtarknet() {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Ztarknet indexing is disabled")
+		utils.WriteModuleDisabledJson(w, "STARKS", "Ztarknet indexing is disabled")
+		return
+	}
+
+	expand, ok := expandBlock(w, r)
+	if !ok {
 		return
 	}
 
@@ -304,13 +564,22 @@ func GetZtarknetFacts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	utils.WriteDataJson(w, facts)
+	data, ok := factData(w, facts, expand)
+	if !ok {
+		return
+	}
+	utils.WriteDataJson(w, data)
 }
 
 // GetZtarknetFactsByVerifier retrieves all Ztarknet facts for a verifier with pagination
 func GetZtarknetFactsByVerifier(w http.ResponseWriter, r *http.Request) {
 	if !starks.ShouldIndexZtarknet() {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Ztarknet indexing is disabled")
+		utils.WriteModuleDisabledJson(w, "STARKS", "Ztarknet indexing is disabled")
+		return
+	}
+
+	expand, ok := expandBlock(w, r)
+	if !ok {
 		return
 	}
 
@@ -330,13 +599,24 @@ func GetZtarknetFactsByVerifier(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	utils.WriteDataJson(w, facts)
+	data, ok := factsData(w, facts, expand)
+	if !ok {
+		return
+	}
+	utils.WritePageJson(w, r, data, limit, offset, func() (int64, error) {
+		return starks.CountZtarknetFacts(verifierID, 0)
+	})
 }
 
 // GetZtarknetFactsByTransaction retrieves all Ztarknet facts for a transaction
 func GetZtarknetFactsByTransaction(w http.ResponseWriter, r *http.Request) {
 	if !starks.ShouldIndexZtarknet() {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Ztarknet indexing is disabled")
+		utils.WriteModuleDisabledJson(w, "STARKS", "Ztarknet indexing is disabled")
+		return
+	}
+
+	expand, ok := expandBlock(w, r)
+	if !ok {
 		return
 	}
 
@@ -352,13 +632,22 @@ func GetZtarknetFactsByTransaction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	utils.WriteDataJson(w, facts)
+	data, ok := factsData(w, facts, expand)
+	if !ok {
+		return
+	}
+	utils.WriteDataJson(w, data)
 }
 
 // GetZtarknetFactsByBlock retrieves all Ztarknet facts for a specific block
 func GetZtarknetFactsByBlock(w http.ResponseWriter, r *http.Request) {
 	if !starks.ShouldIndexZtarknet() {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Ztarknet indexing is disabled")
+		utils.WriteModuleDisabledJson(w, "STARKS", "Ztarknet indexing is disabled")
+		return
+	}
+
+	expand, ok := expandBlock(w, r)
+	if !ok {
 		return
 	}
 
@@ -368,19 +657,72 @@ func GetZtarknetFactsByBlock(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	facts, err := starks.GetZtarknetFactsByBlock(blockHeight)
+	verifierID, ok := canonicalVerifierFilter(w, r)
+	if !ok {
+		return
+	}
+
+	facts, err := starks.GetZtarknetFactsByBlock(blockHeight, verifierID)
 	if err != nil {
 		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	utils.WriteDataJson(w, facts)
+	data, ok := factsData(w, facts, expand)
+	if !ok {
+		return
+	}
+	utils.WriteDataJson(w, data)
+}
+
+// GetZtarknetFactsByL2Block retrieves the Ztarknet facts proving an L2 block number
+func GetZtarknetFactsByL2Block(w http.ResponseWriter, r *http.Request) {
+	if !starks.ShouldIndexZtarknet() {
+		utils.WriteModuleDisabledJson(w, "STARKS", "Ztarknet indexing is disabled")
+		return
+	}
+
+	expand, ok := expandBlock(w, r)
+	if !ok {
+		return
+	}
+
+	l2BlockNumber := int64(utils.ParseQueryParamInt(r, "l2_block_number", -1))
+	if l2BlockNumber < 0 {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing or invalid required parameter: l2_block_number")
+		return
+	}
+
+	verifierID, ok := canonicalVerifierFilter(w, r)
+	if !ok {
+		return
+	}
+	if verifierID == "" {
+		verifierID = utils.ParseQueryParam(r, "verifier_id", "")
+	}
+
+	facts, err := starks.GetZtarknetFactsByL2Block(l2BlockNumber, verifierID)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	data, ok := factsData(w, facts, expand)
+	if !ok {
+		return
+	}
+	utils.WriteDataJson(w, data)
 }
 
 // GetZtarknetFactsByState retrieves Ztarknet facts by state hash
 func GetZtarknetFactsByState(w http.ResponseWriter, r *http.Request) {
 	if !starks.ShouldIndexZtarknet() {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Ztarknet indexing is disabled")
+		utils.WriteModuleDisabledJson(w, "STARKS", "Ztarknet indexing is disabled")
+		return
+	}
+
+	expand, ok := expandBlock(w, r)
+	if !ok {
 		return
 	}
 
@@ -390,19 +732,75 @@ func GetZtarknetFactsByState(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	facts, err := starks.GetZtarknetFactsByState(stateHash)
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	offset := utils.ParseQueryParamInt(r, "offset", 0)
+	limit, offset = utils.NormalizePagination(limit, offset)
+
+	facts, err := starks.GetZtarknetFactsByState(stateHash, limit, offset)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	data, ok := factsData(w, facts, expand)
+	if !ok {
+		return
+	}
+	utils.WritePageJson(w, r, data, limit, offset, func() (int64, error) {
+		return starks.CountZtarknetFactsByState(stateHash)
+	})
+}
+
+// GetZtarknetFactsByStatePrefix retrieves Ztarknet facts whose old or new state root starts with a prefix
+func GetZtarknetFactsByStatePrefix(w http.ResponseWriter, r *http.Request) {
+	if !starks.ShouldIndexZtarknet() {
+		utils.WriteModuleDisabledJson(w, "STARKS", "Ztarknet indexing is disabled")
+		return
+	}
+
+	expand, ok := expandBlock(w, r)
+	if !ok {
+		return
+	}
+
+	// States are stored as lowercase hex without 0x, so normalize truncated roots copied from elsewhere
+	prefix := strings.ToLower(strings.TrimPrefix(utils.ParseQueryParam(r, "prefix", ""), "0x"))
+	if len(prefix) < minStatePrefixLength {
+		utils.WriteErrorJson(w, http.StatusBadRequest,
+			fmt.Sprintf("Missing or too short parameter: prefix (at least %d hex characters)", minStatePrefixLength))
+		return
+	}
+	if strings.Trim(prefix, "0123456789abcdef") != "" {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Invalid parameter: prefix must be hexadecimal")
+		return
+	}
+
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	offset := utils.ParseQueryParamInt(r, "offset", 0)
+	limit, offset = utils.NormalizePagination(limit, offset)
+
+	facts, err := starks.GetZtarknetFactsByStatePrefix(prefix, limit, offset)
 	if err != nil {
 		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	utils.WriteDataJson(w, facts)
+	data, ok := factsData(w, facts, expand)
+	if !ok {
+		return
+	}
+	utils.WriteDataJson(w, data)
 }
 
 // GetZtarknetFactsByProgramHash retrieves Ztarknet facts by program hash
 func GetZtarknetFactsByProgramHash(w http.ResponseWriter, r *http.Request) {
 	if !starks.ShouldIndexZtarknet() {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Ztarknet indexing is disabled")
+		utils.WriteModuleDisabledJson(w, "STARKS", "Ztarknet indexing is disabled")
+		return
+	}
+
+	expand, ok := expandBlock(w, r)
+	if !ok {
 		return
 	}
 
@@ -412,19 +810,34 @@ func GetZtarknetFactsByProgramHash(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	facts, err := starks.GetZtarknetFactsByProgramHash(programHash)
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	offset := utils.ParseQueryParamInt(r, "offset", 0)
+	limit, offset = utils.NormalizePagination(limit, offset)
+
+	facts, err := starks.GetZtarknetFactsByProgramHash(programHash, limit, offset)
 	if err != nil {
 		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	utils.WriteDataJson(w, facts)
+	data, ok := factsData(w, facts, expand)
+	if !ok {
+		return
+	}
+	utils.WritePageJson(w, r, data, limit, offset, func() (int64, error) {
+		return starks.CountZtarknetFactsByProgramHash(programHash, false)
+	})
 }
 
 // GetZtarknetFactsByInnerProgramHash retrieves Ztarknet facts by inner program hash
 func GetZtarknetFactsByInnerProgramHash(w http.ResponseWriter, r *http.Request) {
 	if !starks.ShouldIndexZtarknet() {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Ztarknet indexing is disabled")
+		utils.WriteModuleDisabledJson(w, "STARKS", "Ztarknet indexing is disabled")
+		return
+	}
+
+	expand, ok := expandBlock(w, r)
+	if !ok {
 		return
 	}
 
@@ -434,19 +847,264 @@ func GetZtarknetFactsByInnerProgramHash(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	facts, err := starks.GetZtarknetFactsByInnerProgramHash(innerProgramHash)
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	offset := utils.ParseQueryParamInt(r, "offset", 0)
+	limit, offset = utils.NormalizePagination(limit, offset)
+
+	facts, err := starks.GetZtarknetFactsByInnerProgramHash(innerProgramHash, limit, offset)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	data, ok := factsData(w, facts, expand)
+	if !ok {
+		return
+	}
+	utils.WritePageJson(w, r, data, limit, offset, func() (int64, error) {
+		return starks.CountZtarknetFactsByProgramHash(innerProgramHash, true)
+	})
+}
+
+// GetZtarknetStateChain retrieves a verifier's facts linked into its state progression, with gap and fork flags
+func GetZtarknetStateChain(w http.ResponseWriter, r *http.Request) {
+	if !starks.ShouldIndexZtarknet() {
+		utils.WriteModuleDisabledJson(w, "STARKS", "Ztarknet indexing is disabled")
+		return
+	}
+
+	verifierID := utils.ParseQueryParam(r, "verifier_id", "")
+	if verifierID == "" {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing required parameter: verifier_id")
+		return
+	}
+
+	chain, err := starks.GetStateChain(verifierID)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if chain == nil {
+		utils.WriteErrorJson(w, http.StatusNotFound, "Verifier not found")
+		return
+	}
+
+	utils.WriteDataJson(w, chain)
+}
+
+// GetZtarknetStateGraph retrieves the graph of state roots linked by facts, across verifiers unless
+// verifier_id is given, with fork and cycle flags
+func GetZtarknetStateGraph(w http.ResponseWriter, r *http.Request) {
+	if !starks.ShouldIndexZtarknet() {
+		utils.WriteModuleDisabledJson(w, "STARKS", "Ztarknet indexing is disabled")
+		return
+	}
+
+	fromHeight := int64(utils.ParseQueryParamInt(r, "from_height", 0))
+	toHeight := int64(utils.ParseQueryParamInt(r, "to_height", math.MaxInt))
+	if fromHeight < 0 || toHeight < fromHeight {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Invalid from_height or to_height")
+		return
+	}
+
+	graph, err := starks.GetStateGraph(utils.ParseQueryParam(r, "verifier_id", ""), fromHeight, toHeight)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, graph)
+}
+
+// GetZtarknetState returns the latest state root and height proven for the canonical Ztarknet rollup
+func GetZtarknetState(w http.ResponseWriter, r *http.Request) {
+	if !starks.ShouldIndexZtarknet() {
+		utils.WriteModuleDisabledJson(w, "STARKS", "Ztarknet indexing is disabled")
+		return
+	}
+
+	verifierID, source, err := starks.CanonicalVerifier()
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if verifierID == "" {
+		utils.WriteErrorJson(w, http.StatusNotFound, "No canonical verifier is designated")
+		return
+	}
+
+	state, err := starks.GetZtarknetState(verifierID)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if state == nil {
+		utils.WriteErrorJson(w, http.StatusNotFound, "Canonical verifier has no indexed facts")
+		return
+	}
+	state.CanonicalSource = source
+
+	utils.WriteDataJson(w, state)
+}
+
+// GetZtarknetMapping translates between L1 heights and L2 block numbers of a verifier,
+// the canonical one unless verifier_id is given
+// With l1_height it returns the latest L2 block settled at that height; with l2_block, the L1 height that settled it
+func GetZtarknetMapping(w http.ResponseWriter, r *http.Request) {
+	if !starks.ShouldIndexZtarknet() {
+		utils.WriteModuleDisabledJson(w, "STARKS", "Ztarknet indexing is disabled")
+		return
+	}
+
+	l1Height := int64(utils.ParseQueryParamInt(r, "l1_height", -1))
+	l2Block := int64(utils.ParseQueryParamInt(r, "l2_block", -1))
+	if (l1Height < 0) == (l2Block < 0) {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing or invalid parameter: set exactly one of l1_height or l2_block")
+		return
+	}
+
+	verifierID := utils.ParseQueryParam(r, "verifier_id", "")
+	if verifierID == "" {
+		canonicalID, _, err := starks.CanonicalVerifier()
+		if err != nil {
+			utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if canonicalID == "" {
+			utils.WriteErrorJson(w, http.StatusNotFound, "No canonical verifier is designated, set verifier_id")
+			return
+		}
+		verifierID = canonicalID
+	}
+
+	var mapping *starks.L1L2Mapping
+	var err error
+	if l1Height >= 0 {
+		mapping, err = starks.GetL2BlockAtHeight(verifierID, l1Height)
+	} else {
+		mapping, err = starks.GetL1HeightOfL2Block(verifierID, l2Block)
+	}
 	if err != nil {
 		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	if mapping == nil {
+		if l1Height >= 0 {
+			utils.WriteErrorJson(w, http.StatusNotFound, "No L2 block settled at or below this height")
+		} else {
+			utils.WriteErrorJson(w, http.StatusNotFound, "L2 block not settled yet")
+		}
+		return
+	}
 
-	utils.WriteDataJson(w, facts)
+	utils.WriteDataJson(w, mapping)
+}
+
+// GetFactSegments lists the DA blobs and messages carried in the witness of a fact
+// include_data=true adds each segment's data as hex
+func GetFactSegments(w http.ResponseWriter, r *http.Request) {
+	if !starks.ShouldIndexZtarknet() {
+		utils.WriteModuleDisabledJson(w, "STARKS", "Ztarknet indexing is disabled")
+		return
+	}
+
+	verifierID := utils.ParseQueryParam(r, "verifier_id", "")
+	txid := utils.ParseQueryParam(r, "txid", "")
+	if verifierID == "" || txid == "" {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing required parameters: verifier_id and txid")
+		return
+	}
+
+	kind := utils.ParseQueryParam(r, "kind", "")
+	if kind != "" && !starks.IsSegmentKind(kind) {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Invalid kind parameter. Must be one of: da_blob, message")
+		return
+	}
+
+	includeData := utils.ParseQueryParam(r, "include_data", "false") == "true"
+	segments, err := starks.GetFactSegments(verifierID, txid, kind, includeData)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, segments)
+}
+
+// GetFactSegmentData returns the raw bytes of one witness segment of a fact
+func GetFactSegmentData(w http.ResponseWriter, r *http.Request) {
+	if !starks.ShouldIndexZtarknet() {
+		utils.WriteModuleDisabledJson(w, "STARKS", "Ztarknet indexing is disabled")
+		return
+	}
+
+	verifierID := utils.ParseQueryParam(r, "verifier_id", "")
+	txid := utils.ParseQueryParam(r, "txid", "")
+	seq := utils.ParseQueryParamInt(r, "seq", -1)
+	if verifierID == "" || txid == "" || seq < 0 {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing or invalid required parameters: verifier_id, txid and seq")
+		return
+	}
+
+	data, err := starks.GetFactSegmentData(verifierID, txid, seq)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if data == nil {
+		utils.WriteErrorJson(w, http.StatusNotFound, "Segment not found")
+		return
+	}
+
+	utils.SetCorsHeaders(w)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// GetFactAnomalies retrieves facts flagged for not matching the expected program hashes,
+// optionally filtered by verifier and anomaly kind
+func GetFactAnomalies(w http.ResponseWriter, r *http.Request) {
+	if !starks.ShouldIndexZtarknet() {
+		utils.WriteModuleDisabledJson(w, "STARKS", "Ztarknet indexing is disabled")
+		return
+	}
+
+	verifierID := utils.ParseQueryParam(r, "verifier_id", "")
+	kind := utils.ParseQueryParam(r, "kind", "")
+	if kind != "" && !starks.IsAnomalyKind(kind) {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Invalid kind parameter. Must be one of: unexpected_program_hash, unexpected_inner_program_hash")
+		return
+	}
+
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	offset := utils.ParseQueryParamInt(r, "offset", 0)
+	limit, offset = utils.NormalizePagination(limit, offset)
+
+	anomalies, err := starks.GetFactAnomalies(verifierID, kind, limit, offset)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, anomalies)
 }
 
 // GetRecentZtarknetFacts retrieves the most recent Ztarknet facts with pagination
 func GetRecentZtarknetFacts(w http.ResponseWriter, r *http.Request) {
 	if !starks.ShouldIndexZtarknet() {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Ztarknet indexing is disabled")
+		utils.WriteModuleDisabledJson(w, "STARKS", "Ztarknet indexing is disabled")
+		return
+	}
+
+	expand, ok := expandBlock(w, r)
+	if !ok {
+		return
+	}
+
+	verifierID, ok := canonicalVerifierFilter(w, r)
+	if !ok {
 		return
 	}
 
@@ -454,19 +1112,30 @@ func GetRecentZtarknetFacts(w http.ResponseWriter, r *http.Request) {
 	offset := utils.ParseQueryParamInt(r, "offset", 0)
 	limit, offset = utils.NormalizePagination(limit, offset)
 
-	facts, err := starks.GetRecentZtarknetFacts(limit, offset)
+	facts, err := starks.GetRecentZtarknetFacts(verifierID, limit, offset)
 	if err != nil {
 		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	utils.WriteDataJson(w, facts)
+	data, ok := factsData(w, facts, expand)
+	if !ok {
+		return
+	}
+	utils.WritePageJson(w, r, data, limit, offset, func() (int64, error) {
+		return starks.CountZtarknetFacts(verifierID, 0)
+	})
 }
 
 // GetStateTransition retrieves the state transition from old_state to new_state
 func GetStateTransition(w http.ResponseWriter, r *http.Request) {
 	if !starks.ShouldIndexZtarknet() {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Ztarknet indexing is disabled")
+		utils.WriteModuleDisabledJson(w, "STARKS", "Ztarknet indexing is disabled")
+		return
+	}
+
+	expand, ok := expandBlock(w, r)
+	if !ok {
 		return
 	}
 
@@ -488,13 +1157,103 @@ func GetStateTransition(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	utils.WriteDataJson(w, facts)
+	data, ok := factsData(w, facts, expand)
+	if !ok {
+		return
+	}
+	utils.WriteDataJson(w, data)
+}
+
+// GetZtarknetFactsByTimeRange retrieves Ztarknet facts within a block timestamp range with pagination
+func GetZtarknetFactsByTimeRange(w http.ResponseWriter, r *http.Request) {
+	if !starks.ShouldIndexZtarknet() {
+		utils.WriteModuleDisabledJson(w, "STARKS", "Ztarknet indexing is disabled")
+		return
+	}
+
+	expand, ok := expandBlock(w, r)
+	if !ok {
+		return
+	}
+
+	fromTime, toTime, ok := parseTimeRange(w, r)
+	if !ok {
+		return
+	}
+
+	verifierID, ok := canonicalVerifierFilter(w, r)
+	if !ok {
+		return
+	}
+
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	offset := utils.ParseQueryParamInt(r, "offset", 0)
+	limit, offset = utils.NormalizePagination(limit, offset)
+
+	facts, err := starks.GetZtarknetFactsByTimeRange(fromTime, toTime, verifierID, limit, offset)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	data, ok := factsData(w, facts, expand)
+	if !ok {
+		return
+	}
+	utils.WriteDataJson(w, data)
+}
+
+// GetDailyZtarknetFactActivity returns Ztarknet fact counts and proof sizes bucketed by UTC day
+func GetDailyZtarknetFactActivity(w http.ResponseWriter, r *http.Request) {
+	if !starks.ShouldIndexZtarknet() {
+		utils.WriteModuleDisabledJson(w, "STARKS", "Ztarknet indexing is disabled")
+		return
+	}
+
+	fromTime, toTime, ok := parseTimeRange(w, r)
+	if !ok {
+		return
+	}
+
+	activity, err := starks.GetDailyZtarknetFactActivity(fromTime, toTime)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, activity)
+}
+
+// GetTopPrograms returns the most active Cairo programs by number of proven facts
+func GetTopPrograms(w http.ResponseWriter, r *http.Request) {
+	if !starks.ShouldIndexZtarknet() {
+		utils.WriteModuleDisabledJson(w, "STARKS", "Ztarknet indexing is disabled")
+		return
+	}
+
+	groupBy := utils.ParseQueryParam(r, "group_by", "program_hash")
+	if groupBy != "program_hash" && groupBy != "inner_program_hash" {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Invalid group_by. Must be one of: program_hash, inner_program_hash")
+		return
+	}
+
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	offset := utils.ParseQueryParamInt(r, "offset", 0)
+	limit, offset = utils.NormalizePagination(limit, offset)
+
+	programs, err := starks.GetTopPrograms(groupBy == "inner_program_hash", limit, offset)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, programs)
 }
 
 // CountVerifiers returns the total count of verifiers
 func CountVerifiers(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("STARKS") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "STARKS module is disabled")
+		utils.WriteModuleDisabledJson(w, "STARKS", "STARKS module is disabled")
 		return
 	}
 
@@ -510,7 +1269,7 @@ func CountVerifiers(w http.ResponseWriter, r *http.Request) {
 // CountStarkProofs returns the total count of stark proofs with optional filters
 func CountStarkProofs(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("STARKS") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "STARKS module is disabled")
+		utils.WriteModuleDisabledJson(w, "STARKS", "STARKS module is disabled")
 		return
 	}
 
@@ -529,7 +1288,7 @@ func CountStarkProofs(w http.ResponseWriter, r *http.Request) {
 // CountZtarknetFacts returns the total count of ztarknet facts with optional filters
 func CountZtarknetFacts(w http.ResponseWriter, r *http.Request) {
 	if !starks.ShouldIndexZtarknet() {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Ztarknet indexing is disabled")
+		utils.WriteModuleDisabledJson(w, "STARKS", "Ztarknet indexing is disabled")
 		return
 	}
 
@@ -545,10 +1304,63 @@ func CountZtarknetFacts(w http.ResponseWriter, r *http.Request) {
 	utils.WriteDataJson(w, map[string]int64{"count": count})
 }
 
+// CountZtarknetFactsByState returns the number of facts matching a state hash
+func CountZtarknetFactsByState(w http.ResponseWriter, r *http.Request) {
+	if !starks.ShouldIndexZtarknet() {
+		utils.WriteModuleDisabledJson(w, "STARKS", "Ztarknet indexing is disabled")
+		return
+	}
+
+	stateHash := utils.ParseQueryParam(r, "state_hash", "")
+	if stateHash == "" {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing required parameter: state_hash")
+		return
+	}
+
+	count, err := starks.CountZtarknetFactsByState(stateHash)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, map[string]int64{"count": count})
+}
+
+// CountZtarknetFactsByProgramHash returns the number of facts for a program hash
+func CountZtarknetFactsByProgramHash(w http.ResponseWriter, r *http.Request) {
+	countZtarknetFactsByProgramHash(w, r, "program_hash", false)
+}
+
+// CountZtarknetFactsByInnerProgramHash returns the number of facts for an inner program hash
+func CountZtarknetFactsByInnerProgramHash(w http.ResponseWriter, r *http.Request) {
+	countZtarknetFactsByProgramHash(w, r, "inner_program_hash", true)
+}
+
+func countZtarknetFactsByProgramHash(w http.ResponseWriter, r *http.Request, param string, inner bool) {
+	if !starks.ShouldIndexZtarknet() {
+		utils.WriteModuleDisabledJson(w, "STARKS", "Ztarknet indexing is disabled")
+		return
+	}
+
+	programHash := utils.ParseQueryParam(r, param, "")
+	if programHash == "" {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing required parameter: "+param)
+		return
+	}
+
+	count, err := starks.CountZtarknetFactsByProgramHash(programHash, inner)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, map[string]int64{"count": count})
+}
+
 // GetSumProofSizesByVerifier returns the sum of all proof sizes for a given verifier
 func GetSumProofSizesByVerifier(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("STARKS") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "STARKS module is disabled")
+		utils.WriteModuleDisabledJson(w, "STARKS", "STARKS module is disabled")
 		return
 	}
 
@@ -0,0 +1,363 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/blocks"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/chainparams"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/stats"
+	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
+)
+
+const (
+	// maxActivityBuckets bounds the number of buckets a single activity request can span
+	maxActivityBuckets = 2000
+	// defaultActivityBuckets is the number of buckets returned when from_time is omitted
+	defaultActivityBuckets = 30
+	// defaultLatencyWindow is the number of blocks measured when from_height is omitted
+	defaultLatencyWindow = 1000
+	// maxEmissionCheckRange bounds the blocks a coinbase cross-check can span, as the subsidy
+	// comparison runs over every block of the range
+	maxEmissionCheckRange = 10000
+	// maxChainDiffRange bounds the blocks a chain diff can span, as each section aggregates the
+	// whole range in one snapshot
+	maxChainDiffRange = 10000
+)
+
+// parseActivityRange reads the granularity, from_time and to_time parameters shared by the
+// bucketed stats endpoints, writing a 400 response and returning false when they are invalid
+func parseActivityRange(w http.ResponseWriter, r *http.Request) (stats.Granularity, int64, int64, bool) {
+	granularity, ok := stats.ParseGranularity(utils.ParseQueryParam(r, "granularity", string(stats.GranularityDay)))
+	if !ok {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Invalid granularity. Must be one of: hour, day")
+		return "", 0, 0, false
+	}
+
+	toTime := int64(utils.ParseQueryParamInt(r, "to_time", int(time.Now().Unix())))
+	fromTime := int64(utils.ParseQueryParamInt(r, "from_time", int(toTime-defaultActivityBuckets*granularity.Seconds())))
+	if fromTime < 0 || toTime < 0 {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "from_time and to_time must be non-negative")
+		return "", 0, 0, false
+	}
+	if fromTime > toTime {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "from_time must be less than or equal to to_time")
+		return "", 0, 0, false
+	}
+	if (toTime-fromTime)/granularity.Seconds() > maxActivityBuckets {
+		utils.WriteErrorJson(w, http.StatusBadRequest,
+			fmt.Sprintf("Time range too large: at most %d %s buckets per request", maxActivityBuckets, granularity))
+		return "", 0, 0, false
+	}
+
+	return granularity, fromTime, toTime, true
+}
+
+// GetActivity returns chain activity bucketed by hour or day
+func GetActivity(w http.ResponseWriter, r *http.Request) {
+	granularity, fromTime, toTime, ok := parseActivityRange(w, r)
+	if !ok {
+		return
+	}
+
+	buckets, err := stats.GetActivity(granularity, fromTime, toTime)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, buckets)
+}
+
+// GetChainDiff returns a digest of what changed between two indexed heights
+func GetChainDiff(w http.ResponseWriter, r *http.Request) {
+	fromHeight := int64(utils.ParseQueryParamInt(r, "from_height", -1))
+	toHeight := int64(utils.ParseQueryParamInt(r, "to_height", -1))
+	if fromHeight < 0 || toHeight < 0 {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing or invalid required parameters: from_height, to_height")
+		return
+	}
+	if fromHeight >= toHeight {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "from_height must be less than to_height")
+		return
+	}
+	if toHeight-fromHeight > maxChainDiffRange {
+		utils.WriteErrorJson(w, http.StatusBadRequest, fmt.Sprintf("Height range spans more than %d blocks", maxChainDiffRange))
+		return
+	}
+
+	minBalanceChange := int64(utils.ParseQueryParamInt(r, "min_balance_change", 0))
+	if minBalanceChange < 0 {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "min_balance_change must be non-negative")
+		return
+	}
+
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	limit, _ = utils.NormalizePagination(limit, 0)
+
+	diff, err := stats.GetChainDiff(fromHeight, toHeight, minBalanceChange, limit)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if diff == nil {
+		utils.WriteErrorJson(w, http.StatusNotFound, "from_height or to_height is not indexed")
+		return
+	}
+
+	utils.WriteDataJson(w, diff)
+}
+
+// GetBlockProofShares returns the share of each block's bytes consumed by STARK proof witnesses
+func GetBlockProofShares(w http.ResponseWriter, r *http.Request) {
+	if !config.IsModuleEnabled("STARKS") {
+		utils.WriteModuleDisabledJson(w, "STARKS", "STARKS module is disabled")
+		return
+	}
+
+	fromHeight := int64(utils.ParseQueryParamInt(r, "from_height", -1))
+	toHeight := int64(utils.ParseQueryParamInt(r, "to_height", -1))
+	if fromHeight < 0 || toHeight < 0 {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing or invalid required parameters: from_height, to_height")
+		return
+	}
+	if fromHeight > toHeight {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "from_height must be less than or equal to to_height")
+		return
+	}
+
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	offset := utils.ParseQueryParamInt(r, "offset", 0)
+	limit, offset = utils.NormalizePagination(limit, offset)
+
+	shares, err := stats.GetBlockProofShares(fromHeight, toHeight, limit, offset)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, shares)
+}
+
+// GetProofShareActivity returns the STARK proof share of block space bucketed by hour or day
+func GetProofShareActivity(w http.ResponseWriter, r *http.Request) {
+	if !config.IsModuleEnabled("STARKS") {
+		utils.WriteModuleDisabledJson(w, "STARKS", "STARKS module is disabled")
+		return
+	}
+
+	granularity, fromTime, toTime, ok := parseActivityRange(w, r)
+	if !ok {
+		return
+	}
+
+	buckets, err := stats.GetProofShareActivity(granularity, fromTime, toTime)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, buckets)
+}
+
+// GetVerificationCost summarizes the estimated stark_verify verification cost per proof and per
+// block over a height range, with the cost model in effect
+func GetVerificationCost(w http.ResponseWriter, r *http.Request) {
+	if !config.IsModuleEnabled("STARKS") {
+		utils.WriteModuleDisabledJson(w, "STARKS", "STARKS module is disabled")
+		return
+	}
+
+	fromHeight, toHeight, ok := parseLatencyRange(w, r)
+	if !ok {
+		return
+	}
+
+	summary, err := stats.GetVerificationCostSummary(fromHeight, toHeight)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, summary)
+}
+
+// GetBlockVerificationCosts returns the estimated verification cost of each block with stark_verify
+// proofs over a height range, ordered by height or with sort=cost most expensive first
+func GetBlockVerificationCosts(w http.ResponseWriter, r *http.Request) {
+	if !config.IsModuleEnabled("STARKS") {
+		utils.WriteModuleDisabledJson(w, "STARKS", "STARKS module is disabled")
+		return
+	}
+
+	fromHeight, toHeight, ok := parseLatencyRange(w, r)
+	if !ok {
+		return
+	}
+
+	sort := utils.ParseQueryParam(r, "sort", "height")
+	if sort != "height" && sort != "cost" {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Invalid sort parameter. Must be one of: height, cost")
+		return
+	}
+
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	offset := utils.ParseQueryParamInt(r, "offset", 0)
+	limit, offset = utils.NormalizePagination(limit, offset)
+
+	costs, err := stats.GetBlockVerificationCosts(fromHeight, toHeight, sort == "cost", limit, offset)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, costs)
+}
+
+// parseLatencyRange reads the from_height and to_height parameters of the indexing latency, emission and
+// verification cost endpoints
+// to_height defaults to the latest indexed block and from_height to the defaultLatencyWindow blocks before it
+func parseLatencyRange(w http.ResponseWriter, r *http.Request) (int64, int64, bool) {
+	toHeight := int64(utils.ParseQueryParamInt(r, "to_height", -1))
+	if toHeight < 0 {
+		latest, err := blocks.GetLatestBlock()
+		if err != nil {
+			utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+			return 0, 0, false
+		}
+		toHeight = 0
+		if latest != nil {
+			toHeight = latest.Height
+		}
+	}
+
+	fromHeight := int64(utils.ParseQueryParamInt(r, "from_height", int(toHeight-defaultLatencyWindow+1)))
+	if fromHeight < 0 {
+		fromHeight = 0
+	}
+	if fromHeight > toHeight {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "from_height must be less than or equal to to_height")
+		return 0, 0, false
+	}
+
+	return fromHeight, toHeight, true
+}
+
+// GetIndexingLatency returns p50/p95/p99 latency from block time to indexed time over a height range
+func GetIndexingLatency(w http.ResponseWriter, r *http.Request) {
+	fromHeight, toHeight, ok := parseLatencyRange(w, r)
+	if !ok {
+		return
+	}
+
+	latency, err := stats.GetIndexingLatency(fromHeight, toHeight)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, latency)
+}
+
+// GetBlockLatencies returns the indexing latency of each block in a height range
+func GetBlockLatencies(w http.ResponseWriter, r *http.Request) {
+	fromHeight, toHeight, ok := parseLatencyRange(w, r)
+	if !ok {
+		return
+	}
+
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	offset := utils.ParseQueryParamInt(r, "offset", 0)
+	limit, offset = utils.NormalizePagination(limit, offset)
+
+	latencies, err := stats.GetBlockLatencies(fromHeight, toHeight, limit, offset)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, latencies)
+}
+
+// parseEmissionCheckRange reads the height range of a coinbase cross-check, rejecting ranges
+// over maxEmissionCheckRange blocks
+func parseEmissionCheckRange(w http.ResponseWriter, r *http.Request) (int64, int64, bool) {
+	fromHeight, toHeight, ok := parseLatencyRange(w, r)
+	if !ok {
+		return 0, 0, false
+	}
+	if toHeight-fromHeight+1 > maxEmissionCheckRange {
+		utils.WriteErrorJson(w, http.StatusBadRequest, fmt.Sprintf("Height range spans more than %d blocks", maxEmissionCheckRange))
+		return 0, 0, false
+	}
+	return fromHeight, toHeight, true
+}
+
+// GetEmissionSchedule returns the current block subsidy and next halving at the indexed tip, with
+// a cross-check of recent coinbase values when the transaction graph is indexed
+func GetEmissionSchedule(w http.ResponseWriter, r *http.Request) {
+	params, err := chainparams.Get()
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+
+	latest, err := blocks.GetLatestBlock()
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	height, timestamp := int64(0), time.Now().Unix()
+	if latest != nil {
+		height, timestamp = latest.Height, latest.Timestamp
+	}
+
+	schedule := stats.GetEmissionSchedule(params, height, timestamp)
+	if config.IsModuleEnabled("TX_GRAPH") {
+		fromHeight, toHeight, ok := parseEmissionCheckRange(w, r)
+		if !ok {
+			return
+		}
+		schedule.Check, err = stats.CheckEmission(params, fromHeight, toHeight)
+		if err != nil {
+			utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	utils.WriteDataJson(w, schedule)
+}
+
+// GetSubsidyDiscrepancies returns the blocks whose indexed coinbase pays more than the subsidy
+// plus fees, or less than the subsidy
+func GetSubsidyDiscrepancies(w http.ResponseWriter, r *http.Request) {
+	if !config.IsModuleEnabled("TX_GRAPH") {
+		utils.WriteModuleDisabledJson(w, "TX_GRAPH", "Transaction graph module is disabled")
+		return
+	}
+
+	params, err := chainparams.Get()
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+
+	fromHeight, toHeight, ok := parseEmissionCheckRange(w, r)
+	if !ok {
+		return
+	}
+
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	offset := utils.ParseQueryParamInt(r, "offset", 0)
+	limit, offset = utils.NormalizePagination(limit, offset)
+
+	discrepancies, err := stats.GetSubsidyDiscrepancies(params, fromHeight, toHeight, limit, offset)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, discrepancies)
+}
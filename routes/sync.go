@@ -0,0 +1,45 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/changefeed"
+	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
+)
+
+// GetChanges retrieves the ordered change feed after the given cursor
+func GetChanges(w http.ResponseWriter, r *http.Request) {
+	cursor := int64(utils.ParseQueryParamInt(r, "cursor", 0))
+	if cursor < 0 {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Invalid parameter: cursor must be non-negative")
+		return
+	}
+
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	limit, _ = utils.NormalizePagination(limit, 0)
+
+	page, err := changefeed.GetChanges(cursor, limit)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, page)
+}
+
+// GetBlockManifest retrieves the per-module row keys recorded while indexing a block
+func GetBlockManifest(w http.ResponseWriter, r *http.Request) {
+	height := int64(utils.ParseQueryParamInt(r, "height", -1))
+	if height < 0 {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing or invalid required parameter: height")
+		return
+	}
+
+	entries, err := changefeed.GetBlockManifest(height)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, entries)
+}
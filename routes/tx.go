@@ -0,0 +1,103 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/accounts"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/annotations"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/starks"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/tx_graph"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/tze_graph"
+	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
+)
+
+// TransactionView is a composite view of a transaction across all enabled modules
+// Sections of disabled modules are omitted
+type TransactionView struct {
+	TxID                string                        `json:"txid"`
+	Transaction         *tx_graph.Transaction         `json:"transaction,omitempty"`
+	Outputs             []tx_graph.TransactionOutput  `json:"outputs,omitempty"`
+	Inputs              []tx_graph.TransactionInput   `json:"inputs,omitempty"`
+	TzeInputs           []tze_graph.TzeInput          `json:"tze_inputs,omitempty"`
+	TzeOutputs          []tze_graph.TzeOutput         `json:"tze_outputs,omitempty"`
+	StarkProofs         []starks.StarkProof           `json:"stark_proofs,omitempty"`
+	ZtarknetFacts       []starks.ZtarknetFacts        `json:"ztarknet_facts,omitempty"`
+	AccountTransactions []accounts.AccountTransaction `json:"account_transactions,omitempty"`
+}
+
+// isEmpty reports whether no module returned data for the transaction
+func (v *TransactionView) isEmpty() bool {
+	return v.Transaction == nil && len(v.Outputs) == 0 && len(v.Inputs) == 0 &&
+		len(v.TzeInputs) == 0 && len(v.TzeOutputs) == 0 && len(v.StarkProofs) == 0 &&
+		len(v.ZtarknetFacts) == 0 && len(v.AccountTransactions) == 0
+}
+
+// GetTransactionFull returns a transaction together with its views in every enabled module
+func GetTransactionFull(w http.ResponseWriter, r *http.Request) {
+	txid := r.PathValue("txid")
+	if txid == "" {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing required path parameter: txid")
+		return
+	}
+
+	view, err := buildTransactionView(txid)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if view.isEmpty() {
+		utils.WriteErrorJson(w, http.StatusNotFound, "Transaction not found")
+		return
+	}
+
+	writeEntityJson(w, r, annotations.EntityTx, txid, view)
+}
+
+// buildTransactionView assembles the composite transaction view from the per-module queries
+func buildTransactionView(txid string) (*TransactionView, error) {
+	view := &TransactionView{TxID: txid}
+	var err error
+
+	if config.IsModuleEnabled("TX_GRAPH") {
+		if view.Transaction, err = tx_graph.GetTransaction(txid); err != nil {
+			return nil, err
+		}
+		if view.Outputs, err = tx_graph.GetTransactionOutputs(txid, true); err != nil {
+			return nil, err
+		}
+		if view.Inputs, err = tx_graph.GetTransactionInputs(txid); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.IsModuleEnabled("TZE_GRAPH") {
+		if view.TzeInputs, err = tze_graph.GetTzeInputs(txid); err != nil {
+			return nil, err
+		}
+		if view.TzeOutputs, err = tze_graph.GetTzeOutputs(txid); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.IsModuleEnabled("STARKS") {
+		if view.StarkProofs, err = starks.GetStarkProofsByTransaction(txid); err != nil {
+			return nil, err
+		}
+	}
+
+	if starks.ShouldIndexZtarknet() {
+		if view.ZtarknetFacts, err = starks.GetZtarknetFactsByTransaction(txid); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.IsModuleEnabled("ACCOUNTS") {
+		if view.AccountTransactions, err = accounts.GetTransactionAccounts(txid); err != nil {
+			return nil, err
+		}
+	}
+
+	return view, nil
+}
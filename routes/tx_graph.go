@@ -3,7 +3,9 @@ package routes
 import (
 	"net/http"
 
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/annotations"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/provider"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/tx_graph"
 	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
 )
@@ -11,7 +13,7 @@ import (
 // GetTransaction retrieves a single transaction by txid
 func GetTransaction(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TX_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Transaction graph module is disabled")
+		utils.WriteModuleDisabledJson(w, "TX_GRAPH", "Transaction graph module is disabled")
 		return
 	}
 
@@ -32,13 +34,61 @@ func GetTransaction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	utils.WriteDataJson(w, tx)
+	writeEntityJson(w, r, annotations.EntityTx, tx.TxID, tx)
+}
+
+// GetDecodedTransaction merges a stored transaction with a live getrawtransaction decode
+// (script asm, addresses, shielded component counts) for explorer detail pages
+// Transactions the indexer has not stored yet are decoded with a null transaction
+func GetDecodedTransaction(w http.ResponseWriter, r *http.Request) {
+	if !config.IsModuleEnabled("TX_GRAPH") {
+		utils.WriteModuleDisabledJson(w, "TX_GRAPH", "Transaction graph module is disabled")
+		return
+	}
+
+	txid := utils.ParseQueryParam(r, "txid", "")
+	if txid == "" {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing required parameter: txid")
+		return
+	}
+
+	rawTx, err := provider.GetRawTransaction(txid)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusBadGateway, "Failed to decode transaction from node: "+err.Error())
+		return
+	}
+	tx, err := tx_graph.ParseRawTransaction(rawTx)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusBadGateway, "Failed to decode transaction from node: "+err.Error())
+		return
+	}
+
+	stored, err := tx_graph.GetTransaction(txid)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var inputs []tx_graph.TransactionInput
+	var outputs []tx_graph.TransactionOutput
+	if stored != nil {
+		if inputs, err = tx_graph.GetTransactionInputs(txid); err != nil {
+			utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if outputs, err = tx_graph.GetTransactionOutputs(txid, true); err != nil {
+			utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	writeEntityJson(w, r, annotations.EntityTx, txid, tx_graph.DecodeTransaction(tx, stored, inputs, outputs))
 }
 
 // GetTransactionsByBlock retrieves all transactions in a specific block
 func GetTransactionsByBlock(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TX_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Transaction graph module is disabled")
+		utils.WriteModuleDisabledJson(w, "TX_GRAPH", "Transaction graph module is disabled")
 		return
 	}
 
@@ -58,10 +108,10 @@ func GetTransactionsByBlock(w http.ResponseWriter, r *http.Request) {
 }
 
 // GetTransactionsByType retrieves transactions filtered by type(s) with pagination
-// Accepts comma-separated types (e.g., "tze,t2t,t2z")
+// Accepts comma-separated types (e.g., "tze,t2t,t2z") and optional comma-separated TZE subtypes
 func GetTransactionsByType(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TX_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Transaction graph module is disabled")
+		utils.WriteModuleDisabledJson(w, "TX_GRAPH", "Transaction graph module is disabled")
 		return
 	}
 
@@ -94,11 +144,25 @@ func GetTransactionsByType(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Parse optional comma-separated TZE subtypes (only meaningful for tze transactions)
+	tzeSubtypes := utils.ParseCommaSeparated(utils.ParseQueryParam(r, "tze_subtype", ""))
+	validSubtypes := map[string]bool{
+		string(tx_graph.TzeSubtypeDemo):        true,
+		string(tx_graph.TzeSubtypeStarkVerify): true,
+		string(tx_graph.TzeSubtypeUnknown):     true,
+	}
+	for _, s := range tzeSubtypes {
+		if !validSubtypes[s] {
+			utils.WriteErrorJson(w, http.StatusBadRequest, "Invalid TZE subtype. Must be one of: demo, stark_verify, unknown")
+			return
+		}
+	}
+
 	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
 	offset := utils.ParseQueryParamInt(r, "offset", 0)
 	limit, offset = utils.NormalizePagination(limit, offset)
 
-	txs, err := tx_graph.GetTransactionsByTypes(txTypes, limit, offset)
+	txs, err := tx_graph.GetTransactionsByTypesAndTzeSubtypes(txTypes, tzeSubtypes, limit, offset)
 	if err != nil {
 		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
 		return
@@ -110,7 +174,7 @@ func GetTransactionsByType(w http.ResponseWriter, r *http.Request) {
 // GetRecentTransactions retrieves the most recent transactions with pagination
 func GetRecentTransactions(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TX_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Transaction graph module is disabled")
+		utils.WriteModuleDisabledJson(w, "TX_GRAPH", "Transaction graph module is disabled")
 		return
 	}
 
@@ -124,13 +188,15 @@ func GetRecentTransactions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	utils.WriteDataJson(w, txs)
+	utils.WritePageJson(w, r, txs, limit, offset, func() (int64, error) {
+		return tx_graph.CountTransactions("", 0)
+	})
 }
 
 // GetTransactionOutputs retrieves all outputs for a transaction
 func GetTransactionOutputs(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TX_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Transaction graph module is disabled")
+		utils.WriteModuleDisabledJson(w, "TX_GRAPH", "Transaction graph module is disabled")
 		return
 	}
 
@@ -140,7 +206,7 @@ func GetTransactionOutputs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	outputs, err := tx_graph.GetTransactionOutputs(txid)
+	outputs, err := tx_graph.GetTransactionOutputs(txid, utils.IncludeZeroValue(r))
 	if err != nil {
 		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
 		return
@@ -152,7 +218,7 @@ func GetTransactionOutputs(w http.ResponseWriter, r *http.Request) {
 // GetTransactionOutput retrieves a specific output by txid and vout
 func GetTransactionOutput(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TX_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Transaction graph module is disabled")
+		utils.WriteModuleDisabledJson(w, "TX_GRAPH", "Transaction graph module is disabled")
 		return
 	}
 
@@ -185,7 +251,7 @@ func GetTransactionOutput(w http.ResponseWriter, r *http.Request) {
 // GetUnspentOutputs retrieves all unspent outputs for a transaction
 func GetUnspentOutputs(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TX_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Transaction graph module is disabled")
+		utils.WriteModuleDisabledJson(w, "TX_GRAPH", "Transaction graph module is disabled")
 		return
 	}
 
@@ -195,7 +261,7 @@ func GetUnspentOutputs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	outputs, err := tx_graph.GetUnspentOutputs(txid)
+	outputs, err := tx_graph.GetUnspentOutputs(txid, utils.IncludeZeroValue(r))
 	if err != nil {
 		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
 		return
@@ -207,7 +273,7 @@ func GetUnspentOutputs(w http.ResponseWriter, r *http.Request) {
 // GetTransactionInputs retrieves all inputs for a transaction
 func GetTransactionInputs(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TX_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Transaction graph module is disabled")
+		utils.WriteModuleDisabledJson(w, "TX_GRAPH", "Transaction graph module is disabled")
 		return
 	}
 
@@ -229,7 +295,7 @@ func GetTransactionInputs(w http.ResponseWriter, r *http.Request) {
 // GetTransactionInput retrieves a specific input by txid and vin
 func GetTransactionInput(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TX_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Transaction graph module is disabled")
+		utils.WriteModuleDisabledJson(w, "TX_GRAPH", "Transaction graph module is disabled")
 		return
 	}
 
@@ -262,7 +328,7 @@ func GetTransactionInput(w http.ResponseWriter, r *http.Request) {
 // GetOutputSpenders retrieves all transactions that spent outputs from a given transaction
 func GetOutputSpenders(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TX_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Transaction graph module is disabled")
+		utils.WriteModuleDisabledJson(w, "TX_GRAPH", "Transaction graph module is disabled")
 		return
 	}
 
@@ -284,7 +350,7 @@ func GetOutputSpenders(w http.ResponseWriter, r *http.Request) {
 // GetInputSources retrieves all transactions that provided inputs to a given transaction
 func GetInputSources(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TX_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Transaction graph module is disabled")
+		utils.WriteModuleDisabledJson(w, "TX_GRAPH", "Transaction graph module is disabled")
 		return
 	}
 
@@ -303,10 +369,36 @@ func GetInputSources(w http.ResponseWriter, r *http.Request) {
 	utils.WriteDataJson(w, inputs)
 }
 
+// GetInputsByAddress retrieves the inputs that spent funds from an address
+func GetInputsByAddress(w http.ResponseWriter, r *http.Request) {
+	if !config.IsModuleEnabled("TX_GRAPH") {
+		utils.WriteModuleDisabledJson(w, "TX_GRAPH", "Transaction graph module is disabled")
+		return
+	}
+
+	address := utils.ParseQueryParam(r, "address", "")
+	if address == "" {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing required parameter: address")
+		return
+	}
+
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	offset := utils.ParseQueryParamInt(r, "offset", 0)
+	limit, offset = utils.NormalizePagination(limit, offset)
+
+	inputs, err := tx_graph.GetInputsByAddress(address, limit, offset)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, inputs)
+}
+
 // GetTransactionGraph builds a graph of connected transactions up to a specified depth
 func GetTransactionGraph(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TX_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Transaction graph module is disabled")
+		utils.WriteModuleDisabledJson(w, "TX_GRAPH", "Transaction graph module is disabled")
 		return
 	}
 
@@ -340,7 +432,7 @@ func GetTransactionGraph(w http.ResponseWriter, r *http.Request) {
 // CountTransactions returns the total count of transactions with optional filters
 func CountTransactions(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TX_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Transaction graph module is disabled")
+		utils.WriteModuleDisabledJson(w, "TX_GRAPH", "Transaction graph module is disabled")
 		return
 	}
 
@@ -359,7 +451,7 @@ func CountTransactions(w http.ResponseWriter, r *http.Request) {
 // CountTransactionOutputs returns the total count of transaction outputs with optional filters
 func CountTransactionOutputs(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TX_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Transaction graph module is disabled")
+		utils.WriteModuleDisabledJson(w, "TX_GRAPH", "Transaction graph module is disabled")
 		return
 	}
 
@@ -378,7 +470,7 @@ func CountTransactionOutputs(w http.ResponseWriter, r *http.Request) {
 // CountTransactionInputs returns the total count of transaction inputs with optional filters
 func CountTransactionInputs(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TX_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "Transaction graph module is disabled")
+		utils.WriteModuleDisabledJson(w, "TX_GRAPH", "Transaction graph module is disabled")
 		return
 	}
 
@@ -392,3 +484,74 @@ func CountTransactionInputs(w http.ResponseWriter, r *http.Request) {
 
 	utils.WriteDataJson(w, map[string]int64{"count": count})
 }
+
+// BlockSizeBreakdownResponse is the size composition of a block and of each of its transactions
+type BlockSizeBreakdownResponse struct {
+	Block        *tx_graph.BlockSizeBreakdown `json:"block"`
+	Transactions []tx_graph.TransactionSize   `json:"transactions"`
+}
+
+// GetBlockSizeBreakdown retrieves how the bytes of a block split between transparent data,
+// shielded proofs and TZE witnesses and preconditions
+func GetBlockSizeBreakdown(w http.ResponseWriter, r *http.Request) {
+	if !config.IsModuleEnabled("TX_GRAPH") {
+		utils.WriteModuleDisabledJson(w, "TX_GRAPH", "Transaction graph module is disabled")
+		return
+	}
+
+	blockHeight := int64(utils.ParseQueryParamInt(r, "block_height", -1))
+	if blockHeight < 0 {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing or invalid required parameter: block_height")
+		return
+	}
+
+	block, txs, err := tx_graph.GetBlockSizeBreakdown(blockHeight)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if block == nil {
+		utils.WriteErrorJson(w, http.StatusNotFound, "Block not found")
+		return
+	}
+
+	utils.WriteDataJson(w, BlockSizeBreakdownResponse{Block: block, Transactions: txs})
+}
+
+// GetBlockSizeBreakdownsByRange retrieves the size composition of each block in a height range
+func GetBlockSizeBreakdownsByRange(w http.ResponseWriter, r *http.Request) {
+	if !config.IsModuleEnabled("TX_GRAPH") {
+		utils.WriteModuleDisabledJson(w, "TX_GRAPH", "Transaction graph module is disabled")
+		return
+	}
+
+	fromHeight := int64(utils.ParseQueryParamInt(r, "from_height", -1))
+	if fromHeight < 0 {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing or invalid required parameter: from_height")
+		return
+	}
+
+	toHeight := int64(utils.ParseQueryParamInt(r, "to_height", -1))
+	if toHeight < 0 {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing or invalid required parameter: to_height")
+		return
+	}
+
+	if fromHeight > toHeight {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "from_height must be less than or equal to to_height")
+		return
+	}
+
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	offset := utils.ParseQueryParamInt(r, "offset", 0)
+	limit, offset = utils.NormalizePagination(limit, offset)
+
+	breakdowns, err := tx_graph.GetBlockSizeBreakdowns(fromHeight, toHeight, limit, offset)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, breakdowns)
+}
@@ -2,12 +2,44 @@ package routes
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/tze_graph"
 	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
 )
 
+// parseTzeTypeFilter resolves a TZE type from either a named type (type=demo) or a raw
+// numeric extension id (type_id=7), so extensions without a name remain queryable
+// On failure it writes a 400 response and returns false
+func parseTzeTypeFilter(w http.ResponseWriter, r *http.Request) (tze_graph.TzeType, bool) {
+	if typeIDStr := utils.ParseQueryParam(r, "type_id", ""); typeIDStr != "" {
+		// Extension ids are 4-byte unsigned values stored as INT
+		typeID, err := strconv.ParseUint(typeIDStr, 10, 32)
+		if err != nil {
+			utils.WriteErrorJson(w, http.StatusBadRequest, "Invalid parameter: type_id must be an unsigned 32-bit integer")
+			return 0, false
+		}
+		return tze_graph.TzeType(int32(uint32(typeID))), true
+	}
+
+	tzeTypeStr := utils.ParseQueryParam(r, "type", "")
+	if tzeTypeStr == "" {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing required parameter: type or type_id")
+		return 0, false
+	}
+
+	// Parse and validate TZE type
+	tzeType, ok := tze_graph.ParseTzeType(tzeTypeStr)
+	if !ok {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Invalid TZE type. Must be one of: demo, stark_verify (use type_id for other extensions)")
+		return 0, false
+	}
+
+	return tzeType, true
+}
+
 // ============================================================================
 // TZE INPUT ROUTES
 // ============================================================================
@@ -15,7 +47,7 @@ import (
 // GetTzeInputs retrieves all inputs for a transaction
 func GetTzeInputs(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TZE_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "TZE graph module is disabled")
+		utils.WriteModuleDisabledJson(w, "TZE_GRAPH", "TZE graph module is disabled")
 		return
 	}
 
@@ -37,7 +69,7 @@ func GetTzeInputs(w http.ResponseWriter, r *http.Request) {
 // GetTzeInput retrieves a specific input by txid and vin
 func GetTzeInput(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TZE_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "TZE graph module is disabled")
+		utils.WriteModuleDisabledJson(w, "TZE_GRAPH", "TZE graph module is disabled")
 		return
 	}
 
@@ -70,20 +102,12 @@ func GetTzeInput(w http.ResponseWriter, r *http.Request) {
 // GetTzeInputsByType retrieves all inputs of a specific TZE type with pagination
 func GetTzeInputsByType(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TZE_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "TZE graph module is disabled")
-		return
-	}
-
-	tzeTypeStr := utils.ParseQueryParam(r, "type", "")
-	if tzeTypeStr == "" {
-		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing required parameter: type")
+		utils.WriteModuleDisabledJson(w, "TZE_GRAPH", "TZE graph module is disabled")
 		return
 	}
 
-	// Parse and validate TZE type
-	tzeType, ok := tze_graph.ParseTzeType(tzeTypeStr)
+	tzeType, ok := parseTzeTypeFilter(w, r)
 	if !ok {
-		utils.WriteErrorJson(w, http.StatusBadRequest, "Invalid TZE type. Must be one of: demo, stark_verify")
 		return
 	}
 
@@ -104,7 +128,7 @@ func GetTzeInputsByType(w http.ResponseWriter, r *http.Request) {
 // Note: mode values have different meanings depending on type context
 func GetTzeInputsByMode(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TZE_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "TZE graph module is disabled")
+		utils.WriteModuleDisabledJson(w, "TZE_GRAPH", "TZE graph module is disabled")
 		return
 	}
 
@@ -138,7 +162,7 @@ func GetTzeInputsByMode(w http.ResponseWriter, r *http.Request) {
 // GetTzeInputsByTypeAndMode retrieves all inputs matching both type and mode with pagination
 func GetTzeInputsByTypeAndMode(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TZE_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "TZE graph module is disabled")
+		utils.WriteModuleDisabledJson(w, "TZE_GRAPH", "TZE graph module is disabled")
 		return
 	}
 
@@ -190,7 +214,7 @@ func GetTzeInputsByTypeAndMode(w http.ResponseWriter, r *http.Request) {
 // GetTzeInputsByPrevOutput retrieves all inputs spending a specific previous output
 func GetTzeInputsByPrevOutput(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TZE_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "TZE graph module is disabled")
+		utils.WriteModuleDisabledJson(w, "TZE_GRAPH", "TZE graph module is disabled")
 		return
 	}
 
@@ -215,6 +239,32 @@ func GetTzeInputsByPrevOutput(w http.ResponseWriter, r *http.Request) {
 	utils.WriteDataJson(w, inputs)
 }
 
+// GetTzeInputsByWitnessHash retrieves all inputs whose witness data has the given SHA-256 hash
+func GetTzeInputsByWitnessHash(w http.ResponseWriter, r *http.Request) {
+	if !config.IsModuleEnabled("TZE_GRAPH") {
+		utils.WriteModuleDisabledJson(w, "TZE_GRAPH", "TZE graph module is disabled")
+		return
+	}
+
+	hash := strings.ToLower(utils.ParseQueryParam(r, "hash", ""))
+	if hash == "" {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing required parameter: hash")
+		return
+	}
+
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	offset := utils.ParseQueryParamInt(r, "offset", 0)
+	limit, offset = utils.NormalizePagination(limit, offset)
+
+	inputs, err := tze_graph.GetTzeInputsByWitnessHash(hash, limit, offset)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, inputs)
+}
+
 // ============================================================================
 // TZE OUTPUT ROUTES
 // ============================================================================
@@ -222,7 +272,7 @@ func GetTzeInputsByPrevOutput(w http.ResponseWriter, r *http.Request) {
 // GetTzeOutputs retrieves all outputs for a transaction
 func GetTzeOutputs(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TZE_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "TZE graph module is disabled")
+		utils.WriteModuleDisabledJson(w, "TZE_GRAPH", "TZE graph module is disabled")
 		return
 	}
 
@@ -244,7 +294,7 @@ func GetTzeOutputs(w http.ResponseWriter, r *http.Request) {
 // GetTzeOutput retrieves a specific output by txid and vout
 func GetTzeOutput(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TZE_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "TZE graph module is disabled")
+		utils.WriteModuleDisabledJson(w, "TZE_GRAPH", "TZE graph module is disabled")
 		return
 	}
 
@@ -277,7 +327,7 @@ func GetTzeOutput(w http.ResponseWriter, r *http.Request) {
 // GetUnspentTzeOutputs retrieves all unspent outputs for a transaction
 func GetUnspentTzeOutputs(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TZE_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "TZE graph module is disabled")
+		utils.WriteModuleDisabledJson(w, "TZE_GRAPH", "TZE graph module is disabled")
 		return
 	}
 
@@ -299,7 +349,7 @@ func GetUnspentTzeOutputs(w http.ResponseWriter, r *http.Request) {
 // GetAllUnspentTzeOutputs retrieves all unspent TZE outputs with pagination
 func GetAllUnspentTzeOutputs(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TZE_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "TZE graph module is disabled")
+		utils.WriteModuleDisabledJson(w, "TZE_GRAPH", "TZE graph module is disabled")
 		return
 	}
 
@@ -319,20 +369,12 @@ func GetAllUnspentTzeOutputs(w http.ResponseWriter, r *http.Request) {
 // GetTzeOutputsByType retrieves all outputs of a specific TZE type with pagination
 func GetTzeOutputsByType(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TZE_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "TZE graph module is disabled")
-		return
-	}
-
-	tzeTypeStr := utils.ParseQueryParam(r, "type", "")
-	if tzeTypeStr == "" {
-		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing required parameter: type")
+		utils.WriteModuleDisabledJson(w, "TZE_GRAPH", "TZE graph module is disabled")
 		return
 	}
 
-	// Parse and validate TZE type
-	tzeType, ok := tze_graph.ParseTzeType(tzeTypeStr)
+	tzeType, ok := parseTzeTypeFilter(w, r)
 	if !ok {
-		utils.WriteErrorJson(w, http.StatusBadRequest, "Invalid TZE type. Must be one of: demo, stark_verify")
 		return
 	}
 
@@ -353,7 +395,7 @@ func GetTzeOutputsByType(w http.ResponseWriter, r *http.Request) {
 // Note: mode values have different meanings depending on type context
 func GetTzeOutputsByMode(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TZE_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "TZE graph module is disabled")
+		utils.WriteModuleDisabledJson(w, "TZE_GRAPH", "TZE graph module is disabled")
 		return
 	}
 
@@ -387,7 +429,7 @@ func GetTzeOutputsByMode(w http.ResponseWriter, r *http.Request) {
 // GetTzeOutputsByTypeAndMode retrieves all outputs matching both type and mode with pagination
 func GetTzeOutputsByTypeAndMode(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TZE_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "TZE graph module is disabled")
+		utils.WriteModuleDisabledJson(w, "TZE_GRAPH", "TZE graph module is disabled")
 		return
 	}
 
@@ -439,20 +481,12 @@ func GetTzeOutputsByTypeAndMode(w http.ResponseWriter, r *http.Request) {
 // GetUnspentTzeOutputsByType retrieves all unspent outputs of a specific type with pagination
 func GetUnspentTzeOutputsByType(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TZE_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "TZE graph module is disabled")
-		return
-	}
-
-	tzeTypeStr := utils.ParseQueryParam(r, "type", "")
-	if tzeTypeStr == "" {
-		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing required parameter: type")
+		utils.WriteModuleDisabledJson(w, "TZE_GRAPH", "TZE graph module is disabled")
 		return
 	}
 
-	// Parse and validate TZE type
-	tzeType, ok := tze_graph.ParseTzeType(tzeTypeStr)
+	tzeType, ok := parseTzeTypeFilter(w, r)
 	if !ok {
-		utils.WriteErrorJson(w, http.StatusBadRequest, "Invalid TZE type. Must be one of: demo, stark_verify")
 		return
 	}
 
@@ -472,7 +506,7 @@ func GetUnspentTzeOutputsByType(w http.ResponseWriter, r *http.Request) {
 // GetUnspentTzeOutputsByTypeAndMode retrieves all unspent outputs matching type and mode
 func GetUnspentTzeOutputsByTypeAndMode(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TZE_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "TZE graph module is disabled")
+		utils.WriteModuleDisabledJson(w, "TZE_GRAPH", "TZE graph module is disabled")
 		return
 	}
 
@@ -524,7 +558,7 @@ func GetUnspentTzeOutputsByTypeAndMode(w http.ResponseWriter, r *http.Request) {
 // GetSpentTzeOutputs retrieves all spent outputs with pagination
 func GetSpentTzeOutputs(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TZE_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "TZE graph module is disabled")
+		utils.WriteModuleDisabledJson(w, "TZE_GRAPH", "TZE graph module is disabled")
 		return
 	}
 
@@ -544,7 +578,7 @@ func GetSpentTzeOutputs(w http.ResponseWriter, r *http.Request) {
 // GetTzeOutputsByValue retrieves outputs with value greater than or equal to minimum value
 func GetTzeOutputsByValue(w http.ResponseWriter, r *http.Request) {
 	if !config.IsModuleEnabled("TZE_GRAPH") {
-		utils.WriteErrorJson(w, http.StatusNotFound, "TZE graph module is disabled")
+		utils.WriteModuleDisabledJson(w, "TZE_GRAPH", "TZE graph module is disabled")
 		return
 	}
 
@@ -566,3 +600,143 @@ func GetTzeOutputsByValue(w http.ResponseWriter, r *http.Request) {
 
 	utils.WriteDataJson(w, outputs)
 }
+
+// GetTzeOutputsByPreconditionHash retrieves all outputs whose precondition data has the given SHA-256 hash
+func GetTzeOutputsByPreconditionHash(w http.ResponseWriter, r *http.Request) {
+	if !config.IsModuleEnabled("TZE_GRAPH") {
+		utils.WriteModuleDisabledJson(w, "TZE_GRAPH", "TZE graph module is disabled")
+		return
+	}
+
+	hash := strings.ToLower(utils.ParseQueryParam(r, "hash", ""))
+	if hash == "" {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing required parameter: hash")
+		return
+	}
+
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	offset := utils.ParseQueryParamInt(r, "offset", 0)
+	limit, offset = utils.NormalizePagination(limit, offset)
+
+	outputs, err := tze_graph.GetTzeOutputsByPreconditionHash(hash, limit, offset)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, outputs)
+}
+
+// ============================================================================
+// TZE EXTENSION ROUTES
+// ============================================================================
+
+// GetTzeExtensions lists every TZE extension id observed in inputs or outputs with counts
+func GetTzeExtensions(w http.ResponseWriter, r *http.Request) {
+	if !config.IsModuleEnabled("TZE_GRAPH") {
+		utils.WriteModuleDisabledJson(w, "TZE_GRAPH", "TZE graph module is disabled")
+		return
+	}
+
+	extensions, err := tze_graph.GetTzeExtensions()
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, extensions)
+}
+
+// GetTzeGraph builds a graph of transactions connected through TZE inputs/outputs up to a specified depth
+func GetTzeGraph(w http.ResponseWriter, r *http.Request) {
+	if !config.IsModuleEnabled("TZE_GRAPH") {
+		utils.WriteModuleDisabledJson(w, "TZE_GRAPH", "TZE graph module is disabled")
+		return
+	}
+
+	txid := utils.ParseQueryParam(r, "txid", "")
+	if txid == "" {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing required parameter: txid")
+		return
+	}
+
+	depth := utils.ParseQueryParamInt(r, "depth", 3)
+
+	// Validate depth range
+	if depth < 1 {
+		depth = 1
+	}
+	// Cap depth at configured max_graph_depth to prevent excessive recursion
+	maxDepth := config.Conf.Modules.TzeGraph.MaxGraphDepth
+	if depth > maxDepth {
+		depth = maxDepth
+	}
+
+	txids, err := tze_graph.GetTzeGraph(txid, depth)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, txids)
+}
+
+// ============================================================================
+// DEMO CHANNEL ROUTES
+// ============================================================================
+
+// GetDemoChannelStats returns open channels, value locked, close events and average lifetime for the demo extension
+func GetDemoChannelStats(w http.ResponseWriter, r *http.Request) {
+	if !config.IsModuleEnabled("TZE_GRAPH") {
+		utils.WriteModuleDisabledJson(w, "TZE_GRAPH", "TZE graph module is disabled")
+		return
+	}
+
+	stats, err := tze_graph.GetDemoChannelStats()
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, stats)
+}
+
+// GetOpenDemoChannels lists unspent demo TZE outputs
+func GetOpenDemoChannels(w http.ResponseWriter, r *http.Request) {
+	if !config.IsModuleEnabled("TZE_GRAPH") {
+		utils.WriteModuleDisabledJson(w, "TZE_GRAPH", "TZE graph module is disabled")
+		return
+	}
+
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	offset := utils.ParseQueryParamInt(r, "offset", 0)
+	limit, offset = utils.NormalizePagination(limit, offset)
+
+	outputs, err := tze_graph.GetOpenDemoChannels(limit, offset)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, outputs)
+}
+
+// GetDemoCloseEvents lists demo TZE inputs spent in close mode
+func GetDemoCloseEvents(w http.ResponseWriter, r *http.Request) {
+	if !config.IsModuleEnabled("TZE_GRAPH") {
+		utils.WriteModuleDisabledJson(w, "TZE_GRAPH", "TZE graph module is disabled")
+		return
+	}
+
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	offset := utils.ParseQueryParamInt(r, "offset", 0)
+	limit, offset = utils.NormalizePagination(limit, offset)
+
+	inputs, err := tze_graph.GetDemoCloseEvents(limit, offset)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, inputs)
+}
@@ -0,0 +1,140 @@
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/usage"
+	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
+)
+
+// defaultUsageDays is the report window when from_date is omitted
+const defaultUsageDays = 30
+
+// usageRecorder captures the status and body size of a response
+type usageRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (u *usageRecorder) WriteHeader(status int) {
+	if u.status == 0 {
+		u.status = status
+	}
+	u.ResponseWriter.WriteHeader(status)
+}
+
+func (u *usageRecorder) Write(b []byte) (int, error) {
+	if u.status == 0 {
+		u.status = http.StatusOK
+	}
+	n, err := u.ResponseWriter.Write(b)
+	u.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (u *usageRecorder) Unwrap() http.ResponseWriter {
+	return u.ResponseWriter
+}
+
+// usageMiddleware counts every request and its response bytes against the caller's API key
+// Only keys that authenticate are counted as themselves; requests with no key, an unknown key or
+// API keys disabled are all counted as anonymous, so clients cannot mint report rows by rotating headers
+func usageMiddleware(next http.Handler) http.Handler {
+	if !usage.Enabled() {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &usageRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		keyID := usage.AnonymousKeyID
+		if key := utils.RequestKey(r); key != nil {
+			keyID = key.KeyID
+		}
+		usage.Record(keyID, status, recorder.bytes)
+	})
+}
+
+// parseUsageDates reads the from_date and to_date parameters (YYYY-MM-DD, UTC), defaulting to
+// the last 30 days
+func parseUsageDates(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	today := time.Now().UTC()
+	toDate := utils.ParseQueryParam(r, "to_date", today.Format(time.DateOnly))
+	fromDate := utils.ParseQueryParam(r, "from_date", today.AddDate(0, 0, -(defaultUsageDays-1)).Format(time.DateOnly))
+
+	to, err := time.Parse(time.DateOnly, toDate)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Invalid to_date, expected YYYY-MM-DD")
+		return "", "", false
+	}
+	from, err := time.Parse(time.DateOnly, fromDate)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Invalid from_date, expected YYYY-MM-DD")
+		return "", "", false
+	}
+	if from.After(to) {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "from_date must be on or before to_date")
+		return "", "", false
+	}
+
+	return fromDate, toDate, true
+}
+
+// GetApiUsage reports request counts and response bytes per API key
+// With key_id or key, it returns the daily usage of that key instead
+func GetApiUsage(w http.ResponseWriter, r *http.Request) {
+	if utils.AdminMiddleware(w, r) {
+		return
+	}
+
+	if !usage.Enabled() {
+		utils.WriteErrorJson(w, http.StatusNotImplemented, "API usage tracking is disabled")
+		return
+	}
+
+	fromDate, toDate, ok := parseUsageDates(w, r)
+	if !ok {
+		return
+	}
+
+	keyID := utils.ParseQueryParam(r, "key_id", "")
+	if key := utils.ParseQueryParam(r, "key", ""); key != "" {
+		keyID = usage.KeyID(key)
+	}
+
+	// Include counts not yet flushed so the report is current
+	if err := usage.Flush(r.Context()); err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if keyID != "" {
+		daily, err := usage.GetKeyUsage(keyID, fromDate, toDate)
+		if err != nil {
+			utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		utils.WriteDataJson(w, daily)
+		return
+	}
+
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	offset := utils.ParseQueryParamInt(r, "offset", 0)
+	limit, offset = utils.NormalizePagination(limit, offset)
+
+	keys, err := usage.GetUsage(fromDate, toDate, limit, offset)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, keys)
+}
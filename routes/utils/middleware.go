@@ -1,9 +1,22 @@
 package utils
 
 import (
+	"log"
+	"net"
 	"net/http"
+	"net/netip"
+	"strings"
 
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/apikeys"
 	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/metrics"
+)
+
+var (
+	apiKeyRequests = metrics.NewCounter("zindex_api_key_requests_total",
+		"API requests authenticated with each API key", "key_id", "scope")
+	apiAuthFailures = metrics.NewCounter("zindex_api_auth_failures_total",
+		"API requests rejected by API key authentication", "reason")
 )
 
 func NonProductionMiddleware(w http.ResponseWriter, r *http.Request) bool {
@@ -14,14 +27,105 @@ func NonProductionMiddleware(w http.ResponseWriter, r *http.Request) bool {
 	return false
 }
 
+// AuthMiddleware rejects /api/v1/ requests with an unknown or revoked API key, and with
+// api.auth.require_key, requests without one
+// Other paths such as /health, /status and /metrics are never checked
 func AuthMiddleware(w http.ResponseWriter, r *http.Request) bool {
+	if !apikeys.Enabled() || !strings.HasPrefix(r.URL.Path, "/api/v1/") {
+		return false
+	}
+
+	apiKey := RequestApiKey(r)
+	if apiKey == "" {
+		if config.Conf.Api.Auth.RequireKey {
+			apiAuthFailures.Inc("missing")
+			WriteErrorJson(w, http.StatusUnauthorized, "API key required")
+			return true
+		}
+		return false
+	}
+
+	key, err := apikeys.Authenticate(r.Context(), apiKey)
+	if err != nil {
+		log.Printf("%v", err)
+		WriteErrorJson(w, http.StatusServiceUnavailable, "API keys cannot be checked right now")
+		return true
+	}
+	if key == nil {
+		apiAuthFailures.Inc("invalid")
+		WriteErrorJson(w, http.StatusUnauthorized, "Invalid or revoked API key")
+		return true
+	}
+
+	apiKeyRequests.Inc(key.KeyID, key.Scope)
 	return false
 }
 
+// RequestKey returns the API key authenticated for a request, or nil without api.auth or a valid key
+// AuthMiddleware has already looked the key up, so this is answered from the lookup cache
+func RequestKey(r *http.Request) *apikeys.Key {
+	if !apikeys.Enabled() {
+		return nil
+	}
+	apiKey := RequestApiKey(r)
+	if apiKey == "" {
+		return nil
+	}
+	key, err := apikeys.Authenticate(r.Context(), apiKey)
+	if err != nil {
+		return nil
+	}
+	return key
+}
+
 func AdminMiddleware(w http.ResponseWriter, r *http.Request) bool {
 	if !config.Conf.Api.Admin {
 		WriteErrorJson(w, http.StatusUnauthorized, "Admin access required")
 		return true
 	}
+	if !adminAddressAllowed(r) {
+		WriteErrorJson(w, http.StatusForbidden, "Admin access is not allowed from this address")
+		return true
+	}
+	if apikeys.Enabled() {
+		key := RequestKey(r)
+		if key == nil {
+			apiAuthFailures.Inc("missing")
+			WriteErrorJson(w, http.StatusUnauthorized, "Admin API key required")
+			return true
+		}
+		if !key.HasScope(apikeys.ScopeAdmin) {
+			apiAuthFailures.Inc("scope")
+			WriteErrorJson(w, http.StatusForbidden, "API key does not have the admin scope")
+			return true
+		}
+	}
+	return false
+}
+
+// adminAddressAllowed reports whether the connection's remote address is inside one of
+// api.admin_allowed_cidrs; an empty list allows every address
+// Forwarding headers are ignored since clients can set them freely
+func adminAddressAllowed(r *http.Request) bool {
+	if len(config.Conf.Api.AdminCidrs) == 0 {
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, cidr := range config.Conf.Api.AdminCidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err == nil && prefix.Contains(addr) {
+			return true
+		}
+	}
 	return false
 }
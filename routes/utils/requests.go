@@ -48,6 +48,23 @@ func ParseQueryParamInt(r *http.Request, param string, defaultValue int) int {
 	return intValue
 }
 
+// IncludeZeroValue reports whether a listing should include zero-value outputs: the include_zero
+// parameter when it is true or false, otherwise the inverse of api.exclude_zero_value_outputs
+func IncludeZeroValue(r *http.Request) bool {
+	switch r.URL.Query().Get("include_zero") {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return !config.Conf.Api.ExcludeZeroValueOutputs
+}
+
+// IncludeTotal reports whether a list request asked for its pagination with include_total=true
+func IncludeTotal(r *http.Request) bool {
+	return r.URL.Query().Get("include_total") == "true"
+}
+
 // GetDefaultPaginationLimit returns the default pagination limit from config
 func GetDefaultPaginationLimit() int {
 	return config.Conf.Api.Pagination.DefaultLimit
@@ -120,3 +137,15 @@ func ParseCommaSeparated(value string) []string {
 
 	return result
 }
+
+// RequestApiKey returns the API key sent with a request in the X-API-Key header or as an
+// Authorization bearer token, or "" when there is none
+func RequestApiKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	return ""
+}
@@ -11,6 +11,27 @@ type DataResponse struct {
 	Data interface{} `json:"data"`
 }
 
+// AnnotatedDataResponse is a data response with the annotations of the returned entity alongside it
+type AnnotatedDataResponse struct {
+	Data        interface{} `json:"data"`
+	Annotations interface{} `json:"annotations"`
+}
+
+// PaginatedDataResponse is a page of a list endpoint with its position in the full list
+type PaginatedDataResponse struct {
+	Data       interface{} `json:"data"`
+	Pagination Pagination  `json:"pagination"`
+}
+
+// Pagination describes a page: total matching rows, the page bounds and where the next page starts
+type Pagination struct {
+	Total      int64 `json:"total"`
+	Limit      int   `json:"limit"`
+	Offset     int   `json:"offset"`
+	HasMore    bool  `json:"has_more"`
+	NextOffset *int  `json:"next_offset"` // null on the last page
+}
+
 type ResultResponse struct {
 	Result string `json:"result"`
 }
@@ -28,6 +49,44 @@ func WriteDataJson(w http.ResponseWriter, data interface{}) {
 	json.NewEncoder(w).Encode(response)
 }
 
+func WriteAnnotatedDataJson(w http.ResponseWriter, data interface{}, annotations interface{}) {
+	SetCorsHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := AnnotatedDataResponse{Data: data, Annotations: annotations}
+	json.NewEncoder(w).Encode(response)
+}
+
+// WritePageJson writes a page of a list endpoint
+// With include_total=true the list is wrapped with its pagination, counting the total with count;
+// otherwise the bare list is written as before and count is not run
+func WritePageJson(w http.ResponseWriter, r *http.Request, data interface{}, limit, offset int, count func() (int64, error)) {
+	if !IncludeTotal(r) {
+		WriteDataJson(w, data)
+		return
+	}
+
+	total, err := count()
+	if err != nil {
+		WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	pagination := Pagination{Total: total, Limit: limit, Offset: offset}
+	if next := offset + limit; int64(next) < total {
+		pagination.HasMore = true
+		pagination.NextOffset = &next
+	}
+
+	SetCorsHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := PaginatedDataResponse{Data: data, Pagination: pagination}
+	json.NewEncoder(w).Encode(response)
+}
+
 func WriteResultJson(w http.ResponseWriter, result string) {
 	SetCorsHeaders(w)
 	w.Header().Set("Content-Type", "application/json")
@@ -37,6 +96,16 @@ func WriteResultJson(w http.ResponseWriter, result string) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// WriteAttachmentJson writes data without the data envelope, as a downloadable file
+func WriteAttachmentJson(w http.ResponseWriter, filename string, data interface{}) {
+	SetCorsHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+
+	json.NewEncoder(w).Encode(data)
+}
+
 func WriteErrorJson(w http.ResponseWriter, statusCode int, errorMsg string) {
 	SetCorsHeaders(w)
 	w.Header().Set("Content-Type", "application/json")
@@ -46,6 +115,24 @@ func WriteErrorJson(w http.ResponseWriter, statusCode int, errorMsg string) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// ModuleDisabledResponse is the error body of an endpoint whose module is disabled in this deployment
+// The code lets clients tell a disabled feature apart from missing data
+type ModuleDisabledResponse struct {
+	Error  string `json:"error"`
+	Code   string `json:"code"`
+	Module string `json:"module"`
+}
+
+// WriteModuleDisabledJson responds 501 Not Implemented for an endpoint of a disabled module
+func WriteModuleDisabledJson(w http.ResponseWriter, module string, errorMsg string) {
+	SetCorsHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotImplemented)
+
+	response := ModuleDisabledResponse{Error: errorMsg, Code: "module_disabled", Module: module}
+	json.NewEncoder(w).Encode(response)
+}
+
 func BasicErrorJson(errorMsg string) ErrorResponse {
 	return ErrorResponse{Error: errorMsg}
 }
@@ -0,0 +1,176 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/webhooks"
+	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
+)
+
+// WebhookSubscriptionRequest is the body of an admin webhook subscription
+type WebhookSubscriptionRequest struct {
+	Url         string   `json:"url"`
+	Topics      []string `json:"topics"`
+	Description string   `json:"description"`
+	Digest      string   `json:"digest"`
+}
+
+// ManageWebhooks lists (GET), creates (POST) or deletes (DELETE) webhook subscriptions
+func ManageWebhooks(w http.ResponseWriter, r *http.Request) {
+	if utils.AdminMiddleware(w, r) {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		subscriptions, err := webhooks.GetSubscriptions()
+		if err != nil {
+			utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		utils.WriteDataJson(w, subscriptions)
+	case http.MethodPost:
+		createWebhook(w, r)
+	case http.MethodDelete:
+		deleteWebhook(w, r)
+	default:
+		utils.WriteErrorJson(w, http.StatusMethodNotAllowed, "Method not allowed, use GET, POST or DELETE")
+	}
+}
+
+// createWebhook stores a subscription and returns it with its signing secret, shown only this once
+func createWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := utils.ReadJsonBody[WebhookSubscriptionRequest](r)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	subscription := webhooks.Subscription{Url: body.Url, Topics: body.Topics, Description: body.Description, Digest: body.Digest}
+	if err := webhooks.ValidateSubscription(&subscription); err != nil {
+		utils.WriteErrorJson(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	created, err := webhooks.CreateSubscription(subscription)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, created)
+}
+
+func deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id := int64(utils.ParseQueryParamInt(r, "id", 0))
+	if id <= 0 {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing or invalid required parameter: id")
+		return
+	}
+
+	deleted, err := webhooks.DeleteSubscription(id)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !deleted {
+		utils.WriteErrorJson(w, http.StatusNotFound, "Webhook subscription not found")
+		return
+	}
+
+	utils.WriteDataJson(w, map[string]interface{}{
+		"deleted": id,
+	})
+}
+
+// GetWebhookDeliveries lists webhook deliveries newest first, optionally filtered by subscription and status
+func GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	if utils.AdminMiddleware(w, r) {
+		return
+	}
+
+	subscriptionID := int64(utils.ParseQueryParamInt(r, "subscription_id", 0))
+	status := utils.ParseQueryParam(r, "status", "")
+	if status != "" && !webhooks.IsStatus(status) {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Invalid status parameter. Must be one of: pending, delivered, failed")
+		return
+	}
+
+	limit := utils.ParseQueryParamInt(r, "limit", utils.GetDefaultPaginationLimit())
+	offset := utils.ParseQueryParamInt(r, "offset", 0)
+	limit, offset = utils.NormalizePagination(limit, offset)
+
+	deliveries, err := webhooks.GetDeliveries(subscriptionID, status, limit, offset)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, deliveries)
+}
+
+// GetWebhookDelivery returns one delivery with its payload and the outcome of every attempt
+func GetWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	if utils.AdminMiddleware(w, r) {
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Invalid delivery id")
+		return
+	}
+
+	delivery, err := webhooks.GetDelivery(id)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if delivery == nil {
+		utils.WriteErrorJson(w, http.StatusNotFound, "Webhook delivery not found")
+		return
+	}
+
+	utils.WriteDataJson(w, delivery)
+}
+
+// RedeliverWebhooksRequest is the body of an admin redelivery request
+// Either delivery_id, or subscription_id to redeliver its failed deliveries from from_height on
+type RedeliverWebhooksRequest struct {
+	DeliveryID     int64 `json:"delivery_id"`
+	SubscriptionID int64 `json:"subscription_id"`
+	FromHeight     int64 `json:"from_height"`
+}
+
+// RedeliverWebhooks queues deliveries again with their original payload and a fresh attempt budget
+func RedeliverWebhooks(w http.ResponseWriter, r *http.Request) {
+	if utils.AdminMiddleware(w, r) {
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		utils.WriteErrorJson(w, http.StatusMethodNotAllowed, "Method not allowed, use POST")
+		return
+	}
+
+	body, err := utils.ReadJsonBody[RedeliverWebhooksRequest](r)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if body.DeliveryID <= 0 && body.SubscriptionID <= 0 {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing required field: delivery_id or subscription_id")
+		return
+	}
+
+	queued, err := webhooks.Redeliver(body.DeliveryID, body.SubscriptionID, body.FromHeight)
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteDataJson(w, map[string]interface{}{
+		"queued": queued,
+	})
+}
@@ -0,0 +1,220 @@
+package ws
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
+)
+
+// websocketGUID is appended to the client key to derive Sec-WebSocket-Accept (RFC 6455 section 1.3)
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Frame opcodes (RFC 6455 section 5.2)
+const (
+	opText   = 0x1
+	opBinary = 0x2
+	opClose  = 0x8
+	opPing   = 0x9
+	opPong   = 0xA
+)
+
+// Close status codes (RFC 6455 section 7.4.1)
+const (
+	closeGoingAway     = 1001
+	closeProtocolError = 1002
+	closePolicy        = 1008
+	closeTooLarge      = 1009
+)
+
+const (
+	// maxMessageSize bounds the client messages read; clients only send subscription changes
+	maxMessageSize = 4096
+	// writeWait is how long a frame write may block before the connection is dropped
+	writeWait = 10 * time.Second
+)
+
+// errClosed is returned by readMessage once the client has sent a close frame
+var errClosed = errors.New("websocket closed by client")
+
+// conn is a server-side WebSocket connection. Reads happen on one goroutine;
+// writes may come from several and are serialized
+type conn struct {
+	netConn net.Conn
+	reader  *bufio.Reader
+	writeMu sync.Mutex
+}
+
+// upgrade performs the opening handshake, writing an error response and returning false when
+// the request is not a valid WebSocket upgrade
+func upgrade(w http.ResponseWriter, r *http.Request) (*conn, bool) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorJson(w, http.StatusMethodNotAllowed, "Method not allowed, use GET")
+		return nil, false
+	}
+	if !headerHasToken(r.Header, "Connection", "upgrade") || !headerHasToken(r.Header, "Upgrade", "websocket") {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Expected a WebSocket upgrade request")
+		return nil, false
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		utils.WriteErrorJson(w, http.StatusUpgradeRequired, "Unsupported WebSocket version, use 13")
+		return nil, false
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		utils.WriteErrorJson(w, http.StatusBadRequest, "Missing Sec-WebSocket-Key header")
+		return nil, false
+	}
+
+	netConn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		utils.WriteErrorJson(w, http.StatusInternalServerError, fmt.Sprintf("Failed to upgrade connection: %v", err))
+		return nil, false
+	}
+	// The server's read and write timeouts are meant for single responses, not a long-lived stream
+	netConn.SetDeadline(time.Time{})
+
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n"
+	netConn.SetWriteDeadline(time.Now().Add(writeWait))
+	if _, err := rw.WriteString(response); err != nil {
+		netConn.Close()
+		return nil, false
+	}
+	if err := rw.Flush(); err != nil {
+		netConn.Close()
+		return nil, false
+	}
+
+	return &conn{netConn: netConn, reader: rw.Reader}, true
+}
+
+// headerHasToken reports whether a comma-separated header contains a token, ignoring case
+func headerHasToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// readMessage returns the next text or binary message, answering pings along the way
+// Fragmented messages are not supported, as clients only send small subscription messages
+func (c *conn) readMessage() (int, []byte, error) {
+	for {
+		var header [2]byte
+		if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+			return 0, nil, err
+		}
+		fin := header[0]&0x80 != 0
+		opcode := int(header[0] & 0x0F)
+		masked := header[1]&0x80 != 0
+		length := int64(header[1] & 0x7F)
+
+		switch length {
+		case 126:
+			var ext [2]byte
+			if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+				return 0, nil, err
+			}
+			length = int64(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+				return 0, nil, err
+			}
+			length = int64(binary.BigEndian.Uint64(ext[:]))
+		}
+
+		if !masked {
+			c.close(closeProtocolError, "client frames must be masked")
+			return 0, nil, errors.New("unmasked client frame")
+		}
+		if length < 0 || length > maxMessageSize {
+			c.close(closeTooLarge, "message too large")
+			return 0, nil, fmt.Errorf("client frame of %d bytes exceeds %d", length, maxMessageSize)
+		}
+
+		var mask [4]byte
+		if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+			return 0, nil, err
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(c.reader, payload); err != nil {
+			return 0, nil, err
+		}
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+
+		switch opcode {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return 0, nil, err
+			}
+		case opPong:
+		case opClose:
+			c.writeFrame(opClose, payload)
+			return 0, nil, errClosed
+		case opText, opBinary:
+			if !fin {
+				c.close(closeTooLarge, "fragmented messages are not supported")
+				return 0, nil, errors.New("fragmented client message")
+			}
+			return opcode, payload, nil
+		default:
+			c.close(closeProtocolError, "unexpected opcode")
+			return 0, nil, fmt.Errorf("unexpected opcode %d", opcode)
+		}
+	}
+}
+
+// writeFrame writes a single unmasked frame
+func (c *conn) writeFrame(opcode int, payload []byte) error {
+	header := []byte{0x80 | byte(opcode)}
+	switch {
+	case len(payload) < 126:
+		header = append(header, byte(len(payload)))
+	case len(payload) <= 0xFFFF:
+		header = append(header, 126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(len(payload)))
+	default:
+		header = append(header, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(len(payload)))
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.netConn.SetWriteDeadline(time.Now().Add(writeWait))
+	if _, err := c.netConn.Write(header); err != nil {
+		return err
+	}
+	_, err := c.netConn.Write(payload)
+	return err
+}
+
+// close sends a close frame with a status code and reason, then closes the connection
+func (c *conn) close(code int, reason string) {
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, reason...)
+	c.writeFrame(opClose, payload)
+	c.netConn.Close()
+}
@@ -0,0 +1,225 @@
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/config"
+	"github.com/keep-starknet-strange/ztarknet/zindex/internal/events"
+	"github.com/keep-starknet-strange/ztarknet/zindex/routes/utils"
+)
+
+const (
+	// subscriberBuffer is how many events a connection may have pending before it is dropped
+	subscriberBuffer = 256
+	// pingInterval is how often idle connections are pinged to keep proxies from closing them
+	pingInterval = 30 * time.Second
+)
+
+// connections counts open WebSocket connections against api.websocket.max_connections
+var connections atomic.Int64
+
+// shuttingDown is closed by Shutdown to end every open connection
+var (
+	shuttingDown = make(chan struct{})
+	shutdownOnce sync.Once
+)
+
+// Shutdown closes every open connection with a going-away status
+// http.Server.Shutdown does not track hijacked connections, so it is registered with RegisterOnShutdown
+func Shutdown() {
+	shutdownOnce.Do(func() {
+		close(shuttingDown)
+	})
+}
+
+// RegisterRoutes registers the WebSocket endpoint
+func RegisterRoutes(mux *http.ServeMux) {
+	log.Println("Registering WebSocket routes")
+
+	mux.HandleFunc("/api/v1/ws", Subscribe)
+}
+
+// SubscriptionRequest is a message a client sends to change its subscription
+type SubscriptionRequest struct {
+	Action     string   `json:"action"` // subscribe or unsubscribe
+	Topics     []string `json:"topics"`
+	VerifierID *string  `json:"verifier_id"` // set to filter stark_proofs, facts and anomalies, "" to clear
+}
+
+// SubscriptionState is sent after every subscription change
+type SubscriptionState struct {
+	Topics     []string `json:"topics"`
+	VerifierID string   `json:"verifier_id,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// subscription is the topics and filter of one connection, changed by its reader goroutine
+type subscription struct {
+	mu         sync.Mutex
+	topics     map[string]bool
+	verifierID string
+}
+
+func (s *subscription) wants(event events.Event) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.topics[event.Topic] {
+		return false
+	}
+	return s.verifierID == "" || event.VerifierID == "" || event.VerifierID == s.verifierID
+}
+
+func (s *subscription) state() SubscriptionState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := SubscriptionState{Topics: []string{}, VerifierID: s.verifierID}
+	for _, topic := range events.Topics {
+		if s.topics[topic] {
+			state.Topics = append(state.Topics, topic)
+		}
+	}
+	return state
+}
+
+// apply changes the subscription as a client message asks
+func (s *subscription) apply(request SubscriptionRequest) error {
+	if request.Action != "subscribe" && request.Action != "unsubscribe" {
+		return fmt.Errorf("action must be subscribe or unsubscribe")
+	}
+	if err := validateTopics(request.Topics); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, topic := range request.Topics {
+		s.topics[topic] = request.Action == "subscribe"
+	}
+	if request.VerifierID != nil {
+		s.verifierID = *request.VerifierID
+	}
+	return nil
+}
+
+func validateTopics(topics []string) error {
+	for _, topic := range topics {
+		if !slices.Contains(events.Topics, topic) {
+			return fmt.Errorf("unknown topic %q, must be one of: %s", topic, strings.Join(events.Topics, ", "))
+		}
+	}
+	return nil
+}
+
+// Subscribe upgrades the request to a WebSocket and streams the events of the subscribed topics
+// Initial topics and verifier filter come from the topics and verifier_id query parameters;
+// clients change them afterwards with SubscriptionRequest messages
+func Subscribe(w http.ResponseWriter, r *http.Request) {
+	var topics []string
+	if param := utils.ParseQueryParam(r, "topics", ""); param != "" {
+		topics = strings.Split(param, ",")
+	}
+	if err := validateTopics(topics); err != nil {
+		utils.WriteErrorJson(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if connections.Add(1) > int64(config.Conf.Api.Websocket.MaxConnections) {
+		connections.Add(-1)
+		utils.WriteErrorJson(w, http.StatusServiceUnavailable, "Too many WebSocket connections, try again later")
+		return
+	}
+	defer connections.Add(-1)
+
+	c, ok := upgrade(w, r)
+	if !ok {
+		return
+	}
+
+	sub := &subscription{topics: map[string]bool{}, verifierID: utils.ParseQueryParam(r, "verifier_id", "")}
+	for _, topic := range topics {
+		sub.topics[topic] = true
+	}
+
+	feed := events.Subscribe(subscriberBuffer)
+	defer events.Unsubscribe(feed)
+
+	if err := c.writeJson(sub.state()); err != nil {
+		c.netConn.Close()
+		return
+	}
+
+	// The reader applies subscription changes until the client goes away
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			_, message, err := c.readMessage()
+			if err != nil {
+				return
+			}
+			var request SubscriptionRequest
+			err = json.Unmarshal(message, &request)
+			if err == nil {
+				err = sub.apply(request)
+			}
+			state := sub.state()
+			if err != nil {
+				state.Error = err.Error()
+			}
+			if err := c.writeJson(state); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-done:
+			c.netConn.Close()
+			return
+		case <-shuttingDown:
+			c.close(closeGoingAway, "server shutting down")
+			<-done
+			return
+		case event, ok := <-feed.C:
+			if !ok {
+				c.close(closePolicy, "subscriber fell behind, reconnect and catch up with /api/v1/sync/changes")
+				<-done
+				return
+			}
+			if !sub.wants(event) {
+				continue
+			}
+			if err := c.writeJson(event); err != nil {
+				c.netConn.Close()
+				<-done
+				return
+			}
+		case <-ping.C:
+			if err := c.writeFrame(opPing, nil); err != nil {
+				c.netConn.Close()
+				<-done
+				return
+			}
+		}
+	}
+}
+
+// writeJson sends a value as a text message
+func (c *conn) writeJson(v interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.writeFrame(opText, payload)
+}